@@ -7,6 +7,7 @@ import (
 	"os"
 
 	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/pathutil"
 )
 
 type CatAgent struct {
@@ -48,9 +49,10 @@ func (a *CatAgent) Process(ctx context.Context, input interfaces.AgentInput) (in
 	return interfaces.AgentOutput{
 		Success: true,
 		Data: map[string]interface{}{
-			"content": string(content),
-			"path":    path,
-			"size":    len(content),
+			"content":       string(content),
+			"path":          path,
+			"resolved_path": pathutil.Resolve(path),
+			"size":          len(content),
 		},
 	}, nil
 }
@@ -64,5 +66,10 @@ func (a *CatAgent) Shutdown() error {
 	return nil
 }
 
+// Classification declares that the agent only reads files
+func (a *CatAgent) Classification() interfaces.Classification {
+	return interfaces.Classification{ReadOnly: true, Idempotent: true}
+}
+
 // Export the agent for plugin loading
 var Agent interfaces.Agent = NewCatAgent()
@@ -27,21 +27,23 @@ func (a *ChatAgent) Initialize(config map[string]interface{}) error {
 
 func (a *ChatAgent) Process(ctx context.Context, input interfaces.AgentInput) (interfaces.AgentOutput, error) {
 	// Extract message from input
-	message, ok := input["message"].(string)
+	message, ok := input.Payload["message"].(string)
 	if !ok {
 		return interfaces.AgentOutput{
-			Text:     "Error: message parameter is required",
-			Finished: true,
-		}, fmt.Errorf("message parameter is required")
+			Success: false,
+			Error:   "Error: message parameter is required",
+		}, nil
 	}
 
 	// For chat agent, we'll just return the message as-is
 	// In a real implementation, this might send to a chat service
 	output := fmt.Sprintf("Message received: %s", message)
-	
+
 	return interfaces.AgentOutput{
-		Text:     output,
-		Finished: true,
+		Success: true,
+		Data: map[string]interface{}{
+			"response": output,
+		},
 	}, nil
 }
 
@@ -54,5 +56,10 @@ func (a *ChatAgent) Shutdown() error {
 	return nil
 }
 
+// Classification declares that the agent only echoes the message back
+func (a *ChatAgent) Classification() interfaces.Classification {
+	return interfaces.Classification{ReadOnly: true, Idempotent: true}
+}
+
 // Export the agent for plugin loading
 var Agent interfaces.Agent = NewChatAgent()
@@ -0,0 +1,774 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/gitignore"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/tokenizer"
+)
+
+// Prioritization strategies for deciding which files consume the token budget first
+const (
+	StrategySmallestFirst = "smallest_first"
+	StrategyLargestFirst  = "largest_first"
+	StrategyRecentFirst   = "recent_first"
+	StrategyRelevance     = "relevance"
+	// StrategyPathMatch puts files matching the patterns first, in pattern
+	// order, then the rest smallest first
+	StrategyPathMatch = "path_match"
+)
+
+// strategyAliases are other names accepted for the strategies
+var strategyAliases = map[string]string{
+	"size_asc":  StrategySmallestFirst,
+	"size_desc": StrategyLargestFirst,
+	"modtime":   StrategyRecentFirst,
+}
+
+type ContextManagerAgent struct {
+	name             string
+	defaultMaxTokens int
+	maxFileSize      int64
+	skipDirs         []string
+	workers          int
+	tokenizer        tokenizer.Tokenizer
+	// strategy and patterns apply when a request sets none
+	strategy string
+	patterns []string
+	// excludeExtensions are never read; includeExtensions are read even when
+	// their content does not look like UTF-8 text. Both are lowercase with a
+	// leading dot.
+	excludeExtensions map[string]bool
+	includeExtensions map[string]bool
+	// extraIgnore holds gitignore-style patterns skipped on every walk, on
+	// top of the .gitignore files found when respectGitignore is set
+	extraIgnore      []string
+	respectGitignore bool
+}
+
+// defaultExcludeExtensions are binary formats skipped without reading them
+var defaultExcludeExtensions = []string{
+	".png", ".jpg", ".jpeg", ".gif", ".bmp", ".ico", ".webp", ".pdf",
+	".zip", ".gz", ".tgz", ".tar", ".bz2", ".xz", ".7z", ".jar", ".war",
+	".exe", ".dll", ".so", ".dylib", ".o", ".a", ".class", ".pyc", ".wasm",
+	".woff", ".woff2", ".ttf", ".otf", ".mp3", ".mp4", ".mov", ".avi",
+}
+
+// ignoreRules decide which paths a walk skips
+type ignoreRules struct {
+	extra *gitignore.Matcher
+	// gitignore collects the .gitignore files met on the way down; nil when
+	// they are not respected
+	gitignore *gitignore.Matcher
+}
+
+// candidateFile is a text file discovered during the walk, read and counted
+// but not yet assigned any of the token budget
+type candidateFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+	content string
+	tokens  int
+	matches int
+	// pattern is the index of the first path_match pattern the file
+	// matches, or -1
+	pattern int
+}
+
+// FileContext describes how a single file contributed to the assembled context
+type FileContext struct {
+	Path      string `json:"path"`
+	Size      int64  `json:"size"`
+	Tokens    int    `json:"tokens"`
+	Truncated bool   `json:"truncated"`
+	Matches   int    `json:"matches,omitempty"`
+	Content   string `json:"content"`
+}
+
+// OrderEntry explains where one file came in the priority order and whether
+// it made the token budget
+type OrderEntry struct {
+	Rank   int    `json:"rank"`
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+	// Status is "included", "truncated", or "skipped"
+	Status string `json:"status"`
+}
+
+func NewContextManagerAgent() *ContextManagerAgent {
+	return &ContextManagerAgent{
+		name:              "context-manager",
+		defaultMaxTokens:  8000,
+		maxFileSize:       1024 * 1024, // 1MB
+		skipDirs:          []string{".git", "node_modules", "vendor", ".idea", ".vscode"},
+		workers:           runtime.NumCPU(),
+		tokenizer:         tokenizer.NewHeuristic(),
+		strategy:          StrategySmallestFirst,
+		respectGitignore:  true,
+		excludeExtensions: extensionSet(defaultExcludeExtensions),
+		includeExtensions: map[string]bool{},
+	}
+}
+
+func (a *ContextManagerAgent) Name() string {
+	return a.name
+}
+
+func (a *ContextManagerAgent) Initialize(config map[string]interface{}) error {
+	if maxTokens, ok := getInt(config, "default_max_tokens"); ok && maxTokens > 0 {
+		a.defaultMaxTokens = maxTokens
+	}
+
+	// max_file_bytes replaces max_file_size, which is still read
+	if maxFileSize, ok := getInt(config, "max_file_size"); ok && maxFileSize > 0 {
+		a.maxFileSize = int64(maxFileSize)
+	}
+	if maxFileBytes, ok := getInt(config, "max_file_bytes"); ok && maxFileBytes > 0 {
+		a.maxFileSize = int64(maxFileBytes)
+	}
+
+	// Excludes win over includes, which win over content sniffing
+	if extensions, ok := getStrings(config, "include_extensions"); ok {
+		for ext := range extensionSet(extensions) {
+			a.includeExtensions[ext] = true
+			delete(a.excludeExtensions, ext)
+		}
+	}
+	if extensions, ok := getStrings(config, "exclude_extensions"); ok {
+		for ext := range extensionSet(extensions) {
+			a.excludeExtensions[ext] = true
+			delete(a.includeExtensions, ext)
+		}
+	}
+
+	if workers, ok := getInt(config, "workers"); ok && workers > 0 {
+		a.workers = workers
+	}
+
+	if respect, ok := config["respect_gitignore"].(bool); ok {
+		a.respectGitignore = respect
+	}
+	if patterns, ok := getStrings(config, "extra_ignore"); ok {
+		a.extraIgnore = patterns
+	}
+
+	if priority, ok := config["priority"].(string); ok && priority != "" {
+		strategy, ok := normalizeStrategy(priority)
+		if !ok {
+			return fmt.Errorf("unknown priority: %s", priority)
+		}
+		a.strategy = strategy
+	}
+	if patterns, ok := getStrings(config, "patterns"); ok {
+		if err := validatePatterns(patterns); err != nil {
+			return err
+		}
+		a.patterns = patterns
+	}
+
+	// A missing or broken vocab costs accuracy, not the agent
+	tok, err := tokenizer.FromConfig(config)
+	if err != nil {
+		log.Printf("Failed to load tokenizer for %s agent, falling back to %s: %v", a.name, tokenizer.HeuristicName, err)
+		tok = tokenizer.NewHeuristic()
+	}
+	a.tokenizer = tok
+
+	log.Printf("Initializing %s agent: max_tokens=%d, max_file_bytes=%d, workers=%d, tokenizer=%s, priority=%s, respect_gitignore=%v",
+		a.name, a.defaultMaxTokens, a.maxFileSize, a.workers, a.tokenizer.Name(), a.strategy, a.respectGitignore)
+	return nil
+}
+
+func (a *ContextManagerAgent) Process(ctx context.Context, input interfaces.AgentInput) (interfaces.AgentOutput, error) {
+	switch input.Type {
+	case "analyze", "execute", "":
+		return a.analyzeAndReportContext(ctx, input)
+	default:
+		return interfaces.AgentOutput{
+			Success: false,
+			Error:   fmt.Sprintf("unknown operation: %s", input.Type),
+		}, nil
+	}
+}
+
+// analyzeAndReportContext walks a directory and assembles as many text files as
+// fit in the token budget, in the order given by the requested strategy
+func (a *ContextManagerAgent) analyzeAndReportContext(ctx context.Context, input interfaces.AgentInput) (interfaces.AgentOutput, error) {
+	root, _ := input.Payload["path"].(string)
+	if root == "" {
+		root = "."
+	}
+
+	// priority is accepted as another name for strategy
+	requested, _ := input.Payload["strategy"].(string)
+	if requested == "" {
+		requested, _ = input.Payload["priority"].(string)
+	}
+	strategy := a.strategy
+	if requested != "" {
+		var ok bool
+		if strategy, ok = normalizeStrategy(requested); !ok {
+			return interfaces.AgentOutput{
+				Success: false,
+				Error:   fmt.Sprintf("unknown strategy: %s", requested),
+			}, nil
+		}
+	}
+
+	patterns := a.patterns
+	if requested, ok := getStrings(input.Payload, "patterns"); ok {
+		patterns = requested
+	}
+	if err := validatePatterns(patterns); err != nil {
+		return interfaces.AgentOutput{Success: false, Error: err.Error()}, nil
+	}
+	if strategy == StrategyPathMatch && len(patterns) == 0 {
+		return interfaces.AgentOutput{
+			Success: false,
+			Error:   "Error: patterns parameter is required for path_match strategy",
+		}, nil
+	}
+
+	query, _ := input.Payload["query"].(string)
+	if strategy == StrategyRelevance && strings.TrimSpace(query) == "" {
+		return interfaces.AgentOutput{
+			Success: false,
+			Error:   "Error: query parameter is required for relevance strategy",
+		}, nil
+	}
+
+	maxTokens := a.defaultMaxTokens
+	if requested, ok := getInt(input.Payload, "max_tokens"); ok && requested > 0 {
+		maxTokens = requested
+	}
+
+	// Matches only influence the relevance ordering, so skip counting otherwise
+	matchQuery := ""
+	if strategy == StrategyRelevance {
+		matchQuery = query
+	}
+
+	// A request may turn .gitignore files off and add its own patterns
+	rules := ignoreRules{extra: gitignore.New()}
+	rules.extra.AddPatterns("", a.extraIgnore)
+	if patterns, ok := getStrings(input.Payload, "extra_ignore"); ok {
+		rules.extra.AddPatterns("", patterns)
+	}
+	respectGitignore := a.respectGitignore
+	if respect, ok := input.Payload["respect_gitignore"].(bool); ok {
+		respectGitignore = respect
+	}
+	if respectGitignore {
+		rules.gitignore = gitignore.New()
+	}
+
+	// A cancelled walk still reports whatever was read before cancellation
+	candidates, err := a.collectFiles(ctx, root, matchQuery, rules)
+	cancelled := err != nil && ctx.Err() != nil
+	if err != nil && !cancelled {
+		return interfaces.AgentOutput{
+			Success: false,
+			Error:   fmt.Sprintf("Error walking %s: %v", root, err),
+		}, nil
+	}
+
+	if strategy == StrategyPathMatch {
+		matchPatterns(candidates, root, patterns)
+	}
+	prioritizeFiles(candidates, strategy)
+
+	// Fill the budget in priority order; once it is exhausted the remaining
+	// files are reported so the caller can request them in a follow-up. This
+	// runs after every worker has finished, so the accounting does not depend
+	// on the order in which files were read.
+	var files []FileContext
+	var skipped []string
+	ordering := make([]OrderEntry, 0, len(candidates))
+	totalTokens := 0
+	truncated := 0
+
+	for i := range candidates {
+		candidate := &candidates[i]
+		entry := OrderEntry{
+			Rank:   i + 1,
+			Path:   candidate.path,
+			Reason: priorityReason(candidate, strategy, patterns),
+		}
+
+		remaining := maxTokens - totalTokens
+		if remaining <= 0 {
+			skipped = append(skipped, candidate.path)
+			entry.Status = "skipped"
+			ordering = append(ordering, entry)
+			continue
+		}
+
+		fileContext := a.processFile(candidate, remaining)
+		totalTokens += fileContext.Tokens
+		files = append(files, fileContext)
+
+		entry.Status = "included"
+		if fileContext.Truncated {
+			entry.Status = "truncated"
+			truncated++
+		}
+		ordering = append(ordering, entry)
+	}
+
+	if files == nil {
+		files = []FileContext{}
+	}
+	if skipped == nil {
+		skipped = []string{}
+	}
+
+	var warnings []string
+	if truncated > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d files truncated to fit max_tokens", truncated))
+	}
+	if len(skipped) > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d files skipped to fit max_tokens", len(skipped)))
+	}
+
+	return interfaces.AgentOutput{
+		Success:  true,
+		Warnings: warnings,
+		Data: map[string]interface{}{
+			"path":          root,
+			"strategy":      strategy,
+			"query":         query,
+			"patterns":      patterns,
+			"ordering":      ordering,
+			"max_tokens":    maxTokens,
+			"total_tokens":  totalTokens,
+			"tokenizer":     a.tokenizer.Name(),
+			"files":         files,
+			"file_count":    len(files),
+			"skipped_files": skipped,
+			"cancelled":     cancelled,
+			"summary":       buildSummary(files, skipped, totalTokens, maxTokens, cancelled),
+		},
+	}, nil
+}
+
+// collectFiles walks root and reads every text file small enough to consider.
+// The walk only discovers paths and hands them to a bounded pool of workers that
+// open, sniff, read, and count each file once. Candidates are returned in walk
+// order regardless of which worker finished first. If ctx is cancelled the walk
+// stops promptly and the files read so far are returned along with ctx.Err().
+func (a *ContextManagerAgent) collectFiles(ctx context.Context, root, query string, rules ignoreRules) ([]candidateFile, error) {
+	type job struct {
+		index     int
+		candidate candidateFile
+	}
+
+	workers := a.workers
+	if workers <= 0 {
+		workers = 1
+	}
+
+	jobs := make(chan job)
+	var mu sync.Mutex
+	read := make(map[int]candidateFile)
+	var wg sync.WaitGroup
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+				if a.readFile(&j.candidate, query) {
+					mu.Lock()
+					read[j.index] = j.candidate
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	discovered := 0
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return nil
+		}
+
+		if info.IsDir() {
+			if path != root && (a.shouldSkipDir(info.Name()) || rules.ignored(rel, true)) {
+				return filepath.SkipDir
+			}
+			// Rules of a nested .gitignore apply below it, after its parents'
+			if rules.gitignore != nil {
+				rules.gitignore.LoadDir(root, rel)
+			}
+			return nil
+		}
+
+		if rules.ignored(rel, false) || a.excludeExtensions[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+		if !info.Mode().IsRegular() || info.Size() == 0 || info.Size() > a.maxFileSize {
+			return nil
+		}
+
+		candidate := candidateFile{
+			path:    path,
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case jobs <- job{index: discovered, candidate: candidate}:
+			discovered++
+		}
+		return nil
+	})
+
+	close(jobs)
+	wg.Wait()
+
+	indexes := make([]int, 0, len(read))
+	for index := range read {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	candidates := make([]candidateFile, len(indexes))
+	for i, index := range indexes {
+		candidates[i] = read[index]
+	}
+
+	if err == nil {
+		err = ctx.Err()
+	}
+	return candidates, err
+}
+
+// readFile opens a file once, sniffing the first KB for binary or non-UTF-8
+// content and reading the rest into the candidate. It reports whether the file
+// is text that should be considered.
+func (a *ContextManagerAgent) readFile(candidate *candidateFile, query string) bool {
+	data, err := os.ReadFile(candidate.path)
+	if err != nil {
+		log.Printf("%s: skipping %s: %v", a.name, candidate.path, err)
+		return false
+	}
+
+	sample := data
+	if len(sample) > sniffSize {
+		sample = sample[:sniffSize]
+	}
+	// A NUL byte rules a file out even when its extension is included
+	if isBinary(sample) {
+		return false
+	}
+	if !a.includeExtensions[strings.ToLower(filepath.Ext(candidate.path))] && !isText(sample) {
+		return false
+	}
+
+	candidate.content = string(data)
+	candidate.tokens = a.tokenizer.CountTokens(candidate.content)
+	if query != "" {
+		candidate.matches = countMatches(candidate.content, query)
+	}
+	return true
+}
+
+// processFile truncates a candidate to the remaining budget
+func (a *ContextManagerAgent) processFile(candidate *candidateFile, remainingTokens int) FileContext {
+	content := candidate.content
+	tokens := candidate.tokens
+	truncated := false
+
+	if tokens > remainingTokens {
+		content = a.truncateToTokens(content, remainingTokens)
+		tokens = a.tokenizer.CountTokens(content)
+		truncated = true
+	}
+
+	return FileContext{
+		Path:      candidate.path,
+		Size:      candidate.size,
+		Tokens:    tokens,
+		Truncated: truncated,
+		Matches:   candidate.matches,
+		Content:   content,
+	}
+}
+
+// ignored reports whether the path rel, relative to the walk's root, is
+// skipped by the extra patterns or a .gitignore
+func (r ignoreRules) ignored(rel string, isDir bool) bool {
+	if r.extra.Ignored(rel, isDir) {
+		return true
+	}
+	return r.gitignore != nil && r.gitignore.Ignored(rel, isDir)
+}
+
+func (a *ContextManagerAgent) shouldSkipDir(name string) bool {
+	for _, dir := range a.skipDirs {
+		if name == dir {
+			return true
+		}
+	}
+	return false
+}
+
+// prioritizeFiles orders candidates in place according to strategy. Ties are
+// broken by path so the result is deterministic regardless of walk order.
+func prioritizeFiles(candidates []candidateFile, strategy string) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		ci, cj := candidates[i], candidates[j]
+
+		switch strategy {
+		case StrategyLargestFirst:
+			if ci.size != cj.size {
+				return ci.size > cj.size
+			}
+		case StrategyRecentFirst:
+			if !ci.modTime.Equal(cj.modTime) {
+				return ci.modTime.After(cj.modTime)
+			}
+		case StrategyRelevance:
+			if ci.matches != cj.matches {
+				return ci.matches > cj.matches
+			}
+			if ci.size != cj.size {
+				return ci.size < cj.size
+			}
+		case StrategyPathMatch:
+			// Unmatched files, with pattern -1, go after every match
+			if (ci.pattern < 0) != (cj.pattern < 0) {
+				return ci.pattern >= 0
+			}
+			if ci.pattern != cj.pattern {
+				return ci.pattern < cj.pattern
+			}
+			if ci.size != cj.size {
+				return ci.size < cj.size
+			}
+		default:
+			if ci.size != cj.size {
+				return ci.size < cj.size
+			}
+		}
+
+		return ci.path < cj.path
+	})
+}
+
+// normalizeStrategy resolves a strategy or one of its aliases
+func normalizeStrategy(name string) (string, bool) {
+	if alias, ok := strategyAliases[name]; ok {
+		return alias, true
+	}
+	switch name {
+	case StrategySmallestFirst, StrategyLargestFirst, StrategyRecentFirst, StrategyRelevance, StrategyPathMatch:
+		return name, true
+	}
+	return "", false
+}
+
+// validatePatterns checks path_match patterns for glob syntax errors
+func validatePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %v", pattern, err)
+		}
+	}
+	return nil
+}
+
+// matchPatterns records the first pattern each candidate matches. Patterns
+// with a slash match the path relative to root; others match the file name.
+func matchPatterns(candidates []candidateFile, root string, patterns []string) {
+	for i := range candidates {
+		candidate := &candidates[i]
+		candidate.pattern = -1
+
+		rel, err := filepath.Rel(root, candidate.path)
+		if err != nil {
+			rel = candidate.path
+		}
+		rel = filepath.ToSlash(rel)
+
+		for j, pattern := range patterns {
+			target := filepath.Base(rel)
+			if strings.Contains(pattern, "/") {
+				target = rel
+			}
+			if matched, _ := filepath.Match(pattern, target); matched {
+				candidate.pattern = j
+				break
+			}
+		}
+	}
+}
+
+// priorityReason explains a candidate's place in the order of strategy
+func priorityReason(candidate *candidateFile, strategy string, patterns []string) string {
+	switch strategy {
+	case StrategyRecentFirst:
+		return "modified " + candidate.modTime.Format(time.RFC3339)
+	case StrategyRelevance:
+		return fmt.Sprintf("%d query matches, %d bytes", candidate.matches, candidate.size)
+	case StrategyPathMatch:
+		if candidate.pattern >= 0 {
+			return fmt.Sprintf("matches pattern %s", patterns[candidate.pattern])
+		}
+		return fmt.Sprintf("no pattern match, %d bytes", candidate.size)
+	default:
+		return fmt.Sprintf("%d bytes", candidate.size)
+	}
+}
+
+// countMatches counts case-insensitive occurrences of each query term in content
+func countMatches(content, query string) int {
+	lowerContent := strings.ToLower(content)
+	count := 0
+	for _, term := range strings.Fields(strings.ToLower(query)) {
+		count += strings.Count(lowerContent, term)
+	}
+	return count
+}
+
+// sniffSize is how much of a file is inspected to decide whether it is text
+const sniffSize = 1024
+
+// extensionSet normalizes extensions such as "TOML" or ".toml" to ".toml"
+func extensionSet(extensions []string) map[string]bool {
+	set := make(map[string]bool, len(extensions))
+	for _, ext := range extensions {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		set[ext] = true
+	}
+	return set
+}
+
+// isBinary reports whether a sample contains a NUL byte
+func isBinary(sample []byte) bool {
+	for _, b := range sample {
+		if b == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// isText reports whether a sample is valid UTF-8
+func isText(sample []byte) bool {
+	// The sample may end in the middle of a multi-byte rune
+	for i := 0; i < utf8.UTFMax && len(sample) > 0 && !utf8.Valid(sample); i++ {
+		sample = sample[:len(sample)-1]
+	}
+	return utf8.Valid(sample)
+}
+
+// truncateToTokens cuts text to the token budget, backing off to the last
+// complete line when there is one
+func (a *ContextManagerAgent) truncateToTokens(text string, maxTokens int) string {
+	truncated := a.tokenizer.Truncate(text, maxTokens)
+	if len(truncated) == len(text) {
+		return text
+	}
+
+	if lastNewline := strings.LastIndex(truncated, "\n"); lastNewline > 0 {
+		truncated = truncated[:lastNewline]
+	}
+	return truncated
+}
+
+func buildSummary(files []FileContext, skipped []string, totalTokens, maxTokens int, cancelled bool) string {
+	truncatedCount := 0
+	for _, file := range files {
+		if file.Truncated {
+			truncatedCount++
+		}
+	}
+
+	summary := fmt.Sprintf("Included %d files (%d truncated) using %d/%d tokens", len(files), truncatedCount, totalTokens, maxTokens)
+	if len(skipped) > 0 {
+		summary += fmt.Sprintf("; %d files skipped after the budget ran out", len(skipped))
+	}
+	if cancelled {
+		summary += "; cancelled before the walk finished, results are partial"
+	}
+	return summary
+}
+
+// getStrings reads a list of strings decoded from YAML or JSON
+func getStrings(values map[string]interface{}, key string) ([]string, bool) {
+	switch v := values[key].(type) {
+	case []string:
+		return v, true
+	case []interface{}:
+		strs := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				strs = append(strs, s)
+			}
+		}
+		return strs, true
+	}
+	return nil, false
+}
+
+// getInt reads an integer value that may have been decoded from YAML (int) or JSON (float64)
+func getInt(values map[string]interface{}, key string) (int, bool) {
+	switch v := values[key].(type) {
+	case int:
+		return v, true
+	case int64:
+		return int(v), true
+	case float64:
+		return int(v), true
+	}
+	return 0, false
+}
+
+func (a *ContextManagerAgent) HealthCheck() error {
+	return nil
+}
+
+func (a *ContextManagerAgent) Shutdown() error {
+	log.Printf("Shutting down %s agent", a.name)
+	return nil
+}
+
+// Classification declares that the agent only reads the files it gathers
+func (a *ContextManagerAgent) Classification() interfaces.Classification {
+	return interfaces.Classification{ReadOnly: true, Idempotent: true}
+}
+
+// Export the agent for plugin loading
+var Agent interfaces.Agent = NewContextManagerAgent()
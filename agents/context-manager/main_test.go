@@ -0,0 +1,511 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+func writeTestFile(t *testing.T, dir, name, content string, modTime time.Time) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("Failed to set times on %s: %v", name, err)
+	}
+	return path
+}
+
+func analyze(t *testing.T, payload map[string]interface{}) map[string]interface{} {
+	t.Helper()
+
+	agent := NewContextManagerAgent()
+	output, err := agent.Process(t.Context(), interfaces.AgentInput{Type: "analyze", Payload: payload})
+	if err != nil {
+		t.Fatalf("Process returned error: %v", err)
+	}
+	if !output.Success {
+		t.Fatalf("Expected success, got error: %s", output.Error)
+	}
+	return output.Data
+}
+
+func filePaths(data map[string]interface{}) []string {
+	var paths []string
+	for _, file := range data["files"].([]FileContext) {
+		paths = append(paths, filepath.Base(file.Path))
+	}
+	return paths
+}
+
+func TestContextManager_Strategies(t *testing.T) {
+	tmpDir := t.TempDir()
+	base := time.Now().Add(-time.Hour)
+
+	writeTestFile(t, tmpDir, "small.txt", "tiny", base)
+	writeTestFile(t, tmpDir, "medium.txt", strings.Repeat("medium ", 10), base.Add(2*time.Minute))
+	writeTestFile(t, tmpDir, "large.txt", strings.Repeat("large content ", 30), base.Add(time.Minute))
+
+	testCases := []struct {
+		strategy string
+		query    string
+		expected []string
+	}{
+		{StrategySmallestFirst, "", []string{"small.txt", "medium.txt", "large.txt"}},
+		{StrategyLargestFirst, "", []string{"large.txt", "medium.txt", "small.txt"}},
+		{StrategyRecentFirst, "", []string{"medium.txt", "large.txt", "small.txt"}},
+		{StrategyRelevance, "tiny", []string{"small.txt", "medium.txt", "large.txt"}},
+		{"size_asc", "", []string{"small.txt", "medium.txt", "large.txt"}},
+		{"size_desc", "", []string{"large.txt", "medium.txt", "small.txt"}},
+		{"modtime", "", []string{"medium.txt", "large.txt", "small.txt"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.strategy, func(t *testing.T) {
+			data := analyze(t, map[string]interface{}{
+				"path":     tmpDir,
+				"strategy": tc.strategy,
+				"query":    tc.query,
+			})
+
+			got := filePaths(data)
+			if strings.Join(got, ",") != strings.Join(tc.expected, ",") {
+				t.Errorf("Expected order %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestContextManager_BudgetReportsSkippedFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	now := time.Now()
+
+	writeTestFile(t, tmpDir, "a.txt", strings.Repeat("a", 40), now)
+	writeTestFile(t, tmpDir, "b.txt", strings.Repeat("b", 80), now)
+	writeTestFile(t, tmpDir, "c.txt", strings.Repeat("c", 200), now)
+
+	data := analyze(t, map[string]interface{}{
+		"path":       tmpDir,
+		"max_tokens": float64(20),
+	})
+
+	files := data["files"].([]FileContext)
+	if len(files) != 2 {
+		t.Fatalf("Expected 2 included files, got %d", len(files))
+	}
+	if files[0].Tokens != 10 || files[0].Truncated {
+		t.Errorf("Expected a.txt to contribute 10 untruncated tokens, got %d (truncated=%v)", files[0].Tokens, files[0].Truncated)
+	}
+	if !files[1].Truncated {
+		t.Error("Expected b.txt to be truncated to fit the budget")
+	}
+
+	if total := data["total_tokens"].(int); total > 20 {
+		t.Errorf("Expected total tokens within budget, got %d", total)
+	}
+
+	skipped := data["skipped_files"].([]string)
+	if len(skipped) != 1 || filepath.Base(skipped[0]) != "c.txt" {
+		t.Errorf("Expected c.txt to be skipped, got %v", skipped)
+	}
+}
+
+func TestContextManager_BudgetWarnings(t *testing.T) {
+	tmpDir := t.TempDir()
+	now := time.Now()
+
+	writeTestFile(t, tmpDir, "a.txt", strings.Repeat("a", 40), now)
+	writeTestFile(t, tmpDir, "b.txt", strings.Repeat("b", 80), now)
+	writeTestFile(t, tmpDir, "c.txt", strings.Repeat("c", 200), now)
+
+	agent := NewContextManagerAgent()
+	output, _ := agent.Process(t.Context(), interfaces.AgentInput{
+		Type:    "analyze",
+		Payload: map[string]interface{}{"path": tmpDir, "max_tokens": float64(20)},
+	})
+	expected := []string{"1 files truncated to fit max_tokens", "1 files skipped to fit max_tokens"}
+	if strings.Join(output.Warnings, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected warnings %v, got %v", expected, output.Warnings)
+	}
+
+	output, _ = agent.Process(t.Context(), interfaces.AgentInput{
+		Type:    "analyze",
+		Payload: map[string]interface{}{"path": tmpDir},
+	})
+	if len(output.Warnings) != 0 {
+		t.Errorf("Expected no warnings within the budget, got %v", output.Warnings)
+	}
+}
+
+func TestContextManager_PathMatch(t *testing.T) {
+	tmpDir := t.TempDir()
+	now := time.Now()
+
+	if err := os.Mkdir(filepath.Join(tmpDir, "docs"), 0755); err != nil {
+		t.Fatalf("Failed to create docs: %v", err)
+	}
+	writeTestFile(t, tmpDir, "notes.txt", "n", now)
+	writeTestFile(t, tmpDir, "main.go", strings.Repeat("go ", 20), now)
+	writeTestFile(t, tmpDir, "util.go", "go", now)
+	writeTestFile(t, tmpDir, "docs/guide.md", strings.Repeat("doc ", 10), now)
+	writeTestFile(t, tmpDir, "readme.md", "r", now)
+
+	data := analyze(t, map[string]interface{}{
+		"path":       tmpDir,
+		"priority":   StrategyPathMatch,
+		"patterns":   []interface{}{"docs/*.md", "*.go"},
+		"max_tokens": float64(23),
+	})
+
+	// Matches come in pattern order, then smallest first; readme.md does not
+	// match docs/*.md
+	expected := []string{"guide.md", "util.go", "main.go", "notes.txt", "readme.md"}
+	ordering := data["ordering"].([]OrderEntry)
+	if len(ordering) != len(expected) {
+		t.Fatalf("Expected %d ordering entries, got %+v", len(expected), ordering)
+	}
+	for i, entry := range ordering {
+		if filepath.Base(entry.Path) != expected[i] || entry.Rank != i+1 {
+			t.Errorf("Rank %d: expected %s, got %+v", i+1, expected[i], entry)
+		}
+	}
+
+	if ordering[0].Reason != "matches pattern docs/*.md" || ordering[3].Reason != "no pattern match, 1 bytes" {
+		t.Errorf("Unexpected reasons: %q, %q", ordering[0].Reason, ordering[3].Reason)
+	}
+	if ordering[0].Status != "included" || ordering[2].Status != "truncated" || ordering[4].Status != "skipped" {
+		t.Errorf("Unexpected statuses: %+v", ordering)
+	}
+}
+
+func TestContextManager_ConfiguredPriority(t *testing.T) {
+	tmpDir := t.TempDir()
+	now := time.Now()
+
+	writeTestFile(t, tmpDir, "a.txt", "a", now)
+	writeTestFile(t, tmpDir, "b.go", strings.Repeat("b", 40), now)
+
+	agent := NewContextManagerAgent()
+	if err := agent.Initialize(map[string]interface{}{"priority": "path_match", "patterns": []interface{}{"*.go"}}); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	output, _ := agent.Process(t.Context(), interfaces.AgentInput{Type: "analyze", Payload: map[string]interface{}{"path": tmpDir}})
+	if !output.Success {
+		t.Fatalf("Expected success, got error: %s", output.Error)
+	}
+	if got := filePaths(output.Data); strings.Join(got, ",") != "b.go,a.txt" {
+		t.Errorf("Expected the configured priority to put b.go first, got %v", got)
+	}
+
+	// A request's strategy overrides the configured one
+	output, _ = agent.Process(t.Context(), interfaces.AgentInput{Type: "analyze", Payload: map[string]interface{}{"path": tmpDir, "strategy": "size_asc"}})
+	if got := filePaths(output.Data); strings.Join(got, ",") != "a.txt,b.go" {
+		t.Errorf("Expected size_asc to put a.txt first, got %v", got)
+	}
+
+	for _, config := range []map[string]interface{}{
+		{"priority": "alphabetical"},
+		{"patterns": []interface{}{"[*.go"}},
+	} {
+		if err := NewContextManagerAgent().Initialize(config); err == nil {
+			t.Errorf("Expected Initialize to reject %v", config)
+		}
+	}
+}
+
+func TestContextManager_Gitignore(t *testing.T) {
+	tmpDir := t.TempDir()
+	now := time.Now()
+
+	for _, dir := range []string{"build", "src", "src/gen"} {
+		if err := os.MkdirAll(filepath.Join(tmpDir, dir), 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", dir, err)
+		}
+	}
+	writeTestFile(t, tmpDir, ".gitignore", "build/\n*.log\n", now)
+	writeTestFile(t, tmpDir, "main.go", "package main", now)
+	writeTestFile(t, tmpDir, "debug.log", "log line", now)
+	writeTestFile(t, tmpDir, "build/out.txt", "artifact", now)
+	writeTestFile(t, tmpDir, "src/.gitignore", "gen/\n!keep.log\n", now)
+	writeTestFile(t, tmpDir, "src/lib.go", "package src", now)
+	writeTestFile(t, tmpDir, "src/keep.log", "kept by the nested negation", now)
+	writeTestFile(t, tmpDir, "src/gen/code.go", "package gen", now)
+	writeTestFile(t, tmpDir, "notes.tmp", "scratch", now)
+
+	relPaths := func(data map[string]interface{}) string {
+		var paths []string
+		for _, file := range data["files"].([]FileContext) {
+			rel, _ := filepath.Rel(tmpDir, file.Path)
+			paths = append(paths, filepath.ToSlash(rel))
+		}
+		sort.Strings(paths)
+		return strings.Join(paths, ",")
+	}
+
+	data := analyze(t, map[string]interface{}{"path": tmpDir, "extra_ignore": []interface{}{"*.tmp"}})
+	if got, expected := relPaths(data), ".gitignore,main.go,src/.gitignore,src/keep.log,src/lib.go"; got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+
+	data = analyze(t, map[string]interface{}{"path": tmpDir, "respect_gitignore": false})
+	if got := relPaths(data); !strings.Contains(got, "build/out.txt") || !strings.Contains(got, "src/gen/code.go") || !strings.Contains(got, "notes.tmp") {
+		t.Errorf("Expected every file without .gitignore, got %s", got)
+	}
+}
+
+func TestContextManager_InvalidStrategy(t *testing.T) {
+	agent := NewContextManagerAgent()
+
+	output, err := agent.Process(t.Context(), interfaces.AgentInput{
+		Type:    "analyze",
+		Payload: map[string]interface{}{"path": t.TempDir(), "strategy": "alphabetical"},
+	})
+	if err != nil {
+		t.Fatalf("Process returned error: %v", err)
+	}
+	if output.Success {
+		t.Error("Expected failure for unknown strategy")
+	}
+
+	output, _ = agent.Process(t.Context(), interfaces.AgentInput{
+		Type:    "analyze",
+		Payload: map[string]interface{}{"path": t.TempDir(), "strategy": StrategyRelevance},
+	})
+	if output.Success {
+		t.Error("Expected failure for relevance strategy without a query")
+	}
+
+	output, _ = agent.Process(t.Context(), interfaces.AgentInput{
+		Type:    "analyze",
+		Payload: map[string]interface{}{"path": t.TempDir(), "strategy": StrategyPathMatch},
+	})
+	if output.Success {
+		t.Error("Expected failure for path_match strategy without patterns")
+	}
+}
+
+func TestContextManager_SkipsBinaryFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	now := time.Now()
+
+	writeTestFile(t, tmpDir, "text.txt", "plain text", now)
+	writeTestFile(t, tmpDir, "image.bin", "PNG\x00\x01\x02", now)
+	writeTestFile(t, tmpDir, "latin1.txt", "caf\xe9 au lait", now)
+
+	data := analyze(t, map[string]interface{}{"path": tmpDir})
+
+	got := filePaths(data)
+	if len(got) != 1 || got[0] != "text.txt" {
+		t.Errorf("Expected only text.txt, got %v", got)
+	}
+}
+
+func TestContextManager_ExtensionOverrides(t *testing.T) {
+	tmpDir := t.TempDir()
+	now := time.Now()
+
+	writeTestFile(t, tmpDir, "text.txt", "plain text", now)
+	writeTestFile(t, tmpDir, "logo.png", "not really an image", now)
+	writeTestFile(t, tmpDir, "legacy.dat", "caf\xe9 au lait", now)
+	writeTestFile(t, tmpDir, "blob.dat2", "nul\x00byte", now)
+	writeTestFile(t, tmpDir, "huge.txt", strings.Repeat("x", 64), now)
+
+	process := func(config map[string]interface{}) []string {
+		t.Helper()
+		agent := NewContextManagerAgent()
+		if err := agent.Initialize(config); err != nil {
+			t.Fatalf("Initialize failed: %v", err)
+		}
+		output, _ := agent.Process(t.Context(), interfaces.AgentInput{Type: "analyze", Payload: map[string]interface{}{"path": tmpDir}})
+		if !output.Success {
+			t.Fatalf("Expected success, got error: %s", output.Error)
+		}
+		got := filePaths(output.Data)
+		sort.Strings(got)
+		return got
+	}
+
+	tests := []struct {
+		name     string
+		config   map[string]interface{}
+		expected string
+	}{
+		{"defaults skip known binary extensions", map[string]interface{}{}, "huge.txt,text.txt"},
+		{"include bypasses the UTF-8 check but not the NUL check", map[string]interface{}{"include_extensions": []interface{}{"DAT", ".dat2"}}, "huge.txt,legacy.dat,text.txt"},
+		{"include overrides a built-in exclude", map[string]interface{}{"include_extensions": []interface{}{"png"}}, "huge.txt,logo.png,text.txt"},
+		{"exclude wins over include", map[string]interface{}{"include_extensions": []interface{}{"txt"}, "exclude_extensions": []interface{}{".txt"}}, ""},
+		{"max_file_bytes", map[string]interface{}{"max_file_bytes": 32}, "text.txt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := strings.Join(process(tt.config), ","); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestContextManager_WorkersPreserveOrder(t *testing.T) {
+	tmpDir := t.TempDir()
+	generateTree(t, tmpDir, 200)
+
+	agent := NewContextManagerAgent()
+	var expected []string
+	for _, workers := range []int{1, 8} {
+		agent.workers = workers
+		output, err := agent.Process(t.Context(), interfaces.AgentInput{
+			Type:    "analyze",
+			Payload: map[string]interface{}{"path": tmpDir, "max_tokens": float64(2000)},
+		})
+		if err != nil || !output.Success {
+			t.Fatalf("Process failed: %v %s", err, output.Error)
+		}
+
+		got := filePaths(output.Data)
+		got = append(got, output.Data["skipped_files"].([]string)...)
+		if expected == nil {
+			expected = got
+			continue
+		}
+		if strings.Join(got, ",") != strings.Join(expected, ",") {
+			t.Errorf("Expected %d workers to produce the same order as 1 worker", workers)
+		}
+		if total := output.Data["total_tokens"].(int); total > 2000 {
+			t.Errorf("Expected total tokens within budget, got %d", total)
+		}
+	}
+}
+
+func TestContextManager_CancelledReturnsPartialResult(t *testing.T) {
+	tmpDir := t.TempDir()
+	generateTree(t, tmpDir, 10000)
+
+	agent := NewContextManagerAgent()
+	agent.workers = 2
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	// Cancel once the walk is underway
+	var cancelledAt time.Time
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancelledAt = time.Now()
+		cancel()
+	}()
+
+	output, err := agent.Process(ctx, interfaces.AgentInput{
+		Type:    "analyze",
+		Payload: map[string]interface{}{"path": tmpDir, "max_tokens": float64(1000000)},
+	})
+	returnedAt := time.Now()
+	if err != nil {
+		t.Fatalf("Process returned error: %v", err)
+	}
+	if !output.Success {
+		t.Fatalf("Expected partial success, got error: %s", output.Error)
+	}
+
+	if cancelled, _ := output.Data["cancelled"].(bool); !cancelled {
+		t.Fatal("Expected result to be marked cancelled")
+	}
+	if elapsed := returnedAt.Sub(cancelledAt); elapsed > 100*time.Millisecond {
+		t.Errorf("Expected Process to return within 100ms of cancellation, took %v", elapsed)
+	}
+
+	count := output.Data["file_count"].(int)
+	if count == 0 || count >= 10000 {
+		t.Errorf("Expected a partial set of files, got %d", count)
+	}
+	if summary := output.Data["summary"].(string); !strings.Contains(summary, "partial") {
+		t.Errorf("Expected summary to mention partial results, got %q", summary)
+	}
+}
+
+// generateTree writes count source-like files spread over nested directories
+func generateTree(t testing.TB, root string, count int) {
+	t.Helper()
+
+	line := "func handler(w http.ResponseWriter, r *http.Request) { process(r.Context()) }\n"
+	for i := 0; i < count; i++ {
+		dir := filepath.Join(root, fmt.Sprintf("pkg%02d", i%20), fmt.Sprintf("sub%d", i%3))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", dir, err)
+		}
+
+		path := filepath.Join(dir, fmt.Sprintf("file%05d.go", i))
+		if err := os.WriteFile(path, []byte(strings.Repeat(line, 1+i%40)), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+}
+
+func BenchmarkContextManager_Analyze(b *testing.B) {
+	tmpDir := b.TempDir()
+	generateTree(b, tmpDir, 5000)
+
+	for _, workers := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			agent := NewContextManagerAgent()
+			agent.workers = workers
+			input := interfaces.AgentInput{
+				Type:    "analyze",
+				Payload: map[string]interface{}{"path": tmpDir, "max_tokens": float64(100000)},
+			}
+
+			for i := 0; i < b.N; i++ {
+				if output, _ := agent.Process(context.Background(), input); !output.Success {
+					b.Fatalf("Analyze failed: %s", output.Error)
+				}
+			}
+		})
+	}
+}
+
+func TestContextManager_Tokenizer(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTestFile(t, tmpDir, "main.go", "func main() {}\n", time.Now())
+
+	if data := analyze(t, map[string]interface{}{"path": tmpDir}); data["tokenizer"] != "heuristic" {
+		t.Errorf("Expected the heuristic by default, got %v", data["tokenizer"])
+	}
+
+	// A vocab of single bytes makes every byte one token
+	var sb strings.Builder
+	for i := 0; i < 256; i++ {
+		fmt.Fprintf(&sb, "%s %d\n", base64.StdEncoding.EncodeToString([]byte{byte(i)}), i)
+	}
+	vocab := filepath.Join(t.TempDir(), "bytes.tiktoken")
+	if err := os.WriteFile(vocab, []byte(sb.String()), 0644); err != nil {
+		t.Fatalf("Failed to write vocab: %v", err)
+	}
+
+	agent := NewContextManagerAgent()
+	if err := agent.Initialize(map[string]interface{}{"tokenizer": "bytes", "tokenizer_path": vocab}); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	output, _ := agent.Process(t.Context(), interfaces.AgentInput{Type: "analyze", Payload: map[string]interface{}{"path": tmpDir}})
+	if output.Data["tokenizer"] != "bytes" || output.Data["total_tokens"] != len("func main() {}\n") {
+		t.Errorf("Expected a token per byte from the bytes vocab, got %v with %v tokens", output.Data["tokenizer"], output.Data["total_tokens"])
+	}
+
+	// A vocab that cannot be found falls back to the heuristic
+	agent = NewContextManagerAgent()
+	if err := agent.Initialize(map[string]interface{}{"tokenizer": "missing", "tokenizer_path": filepath.Join(tmpDir, "missing.tiktoken")}); err != nil {
+		t.Fatalf("Expected a fallback instead of an error, got %v", err)
+	}
+	if name := agent.tokenizer.Name(); name != "heuristic" {
+		t.Errorf("Expected the heuristic fallback, got %s", name)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 
 	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/pathutil"
 )
 
 type CpAgent struct {
@@ -93,6 +94,8 @@ func (a *CpAgent) Process(ctx context.Context, input interfaces.AgentInput) (int
 		Data: map[string]interface{}{
 			"source":               source,
 			"destination":          destination,
+			"resolved_source":      pathutil.Resolve(source),
+			"resolved_destination": pathutil.Resolve(destination),
 			"absolute_source":      absSource,
 			"absolute_destination": absDestination,
 			"type":                 map[bool]string{true: "directory", false: "file"}[sourceInfo.IsDir()],
@@ -182,5 +185,10 @@ func (a *CpAgent) Shutdown() error {
 	return nil
 }
 
+// Classification declares that the agent may overwrite the destination
+func (a *CpAgent) Classification() interfaces.Classification {
+	return interfaces.Classification{Idempotent: true, Destructive: true}
+}
+
 // Export the agent for plugin loading
 var Agent interfaces.Agent = NewCpAgent()
@@ -1,7 +1,10 @@
 module github.com/AgentForgeEngine/AgentForgeEngine/agents/df
 
-go 1.24
+go 1.24.0
 
 replace github.com/AgentForgeEngine/AgentForgeEngine => ../..
 
-require github.com/AgentForgeEngine/AgentForgeEngine v0.0.0-00010101000000-000000000000
+require (
+	github.com/AgentForgeEngine/AgentForgeEngine v0.0.0-00010101000000-000000000000
+	golang.org/x/sys v0.40.0
+)
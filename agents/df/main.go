@@ -1,18 +1,32 @@
 package main
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"log"
-	"os/exec
+	"strconv"
+	"strings"
 
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/execrun"
 	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+	"golang.org/x/sys/unix"
 )
 
 type DfAgent struct {
 	name string
 }
 
+// filesystemUsage holds the numeric usage of one mounted filesystem in bytes
+type filesystemUsage struct {
+	filesystem string
+	mountedOn  string
+	size       uint64
+	used       uint64
+	available  uint64
+}
+
 func NewDfAgent() *DfAgent {
 	return &DfAgent{name: "df"}
 }
@@ -27,22 +41,175 @@ func (a *DfAgent) Initialize(config map[string]interface{}) error {
 }
 
 func (a *DfAgent) Process(ctx context.Context, input interfaces.AgentInput) (interfaces.AgentOutput, error) {
-	// Build df command
-	cmd := exec.CommandContext(ctx, "df", "-h")
-	output, err := cmd.Output()
+	path, _ := input.Payload["path"].(string)
+	rawBytes, _ := input.Payload["bytes"].(bool)
+
+	// The human-readable report is kept as raw output for existing callers
+	human, err := runDf(ctx, path, "-h")
+	if err != nil {
+		return interfaces.AgentOutput{
+			Success: false,
+			Error:   fmt.Sprintf("Error executing df: %v", err),
+			Data:    human.Fields(),
+		}, nil
+	}
+
+	// POSIX output in 1K blocks has a stable layout to parse
+	portable, err := runDf(ctx, path, "-P", "-k")
 	if err != nil {
 		return interfaces.AgentOutput{
-			Text:     fmt.Sprintf("Error executing df: %v", err),
-			Finished: true,
-		}, err
+			Success: false,
+			Error:   fmt.Sprintf("Error executing df: %v", err),
+			Data:    portable.Fields(),
+		}, nil
+	}
+
+	usages := parseDfOutput(portable.Stdout)
+	filesystems := make([]map[string]interface{}, 0, len(usages))
+	for _, usage := range usages {
+		// statfs gives exact byte counts; the parsed values are the fallback
+		refineWithStatfs(&usage)
+		filesystems = append(filesystems, usage.record(rawBytes))
+	}
+
+	data := human.Fields()
+	data["filesystems"] = filesystems
+	data["count"] = len(filesystems)
+	data["bytes"] = rawBytes
+	data["raw"] = human.Stdout
+	if path != "" {
+		data["path"] = path
 	}
 
 	return interfaces.AgentOutput{
-		Text:     string(output),
-		Finished: true,
+		Success: true,
+		Data:    data,
 	}, nil
 }
 
+// runDf runs df; a non-zero exit is an error that carries df's stderr
+func runDf(ctx context.Context, path string, flags ...string) (execrun.Result, error) {
+	args := flags
+	if path != "" {
+		args = append(args, "--", path)
+	}
+
+	result, err := execrun.Run(ctx, "df", args...)
+	if err == nil && result.ExitCode != 0 {
+		err = errors.New(result.Error())
+	}
+	return result, err
+}
+
+// parseDfOutput parses `df -P -k` output. Filesystem names may contain
+// spaces, so columns are read from the right, where the layout is fixed.
+func parseDfOutput(output string) []filesystemUsage {
+	var usages []filesystemUsage
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	header := true
+	for scanner.Scan() {
+		if header {
+			header = false
+			continue
+		}
+
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 {
+			continue
+		}
+
+		// Mount points may contain spaces too; the capacity column ends in %
+		capacity := -1
+		for i := 4; i < len(fields); i++ {
+			if strings.HasSuffix(fields[i], "%") {
+				capacity = i
+				break
+			}
+		}
+		if capacity < 4 || capacity == len(fields)-1 {
+			continue
+		}
+
+		size, _ := strconv.ParseUint(fields[capacity-3], 10, 64)
+		used, _ := strconv.ParseUint(fields[capacity-2], 10, 64)
+		available, _ := strconv.ParseUint(fields[capacity-1], 10, 64)
+
+		usages = append(usages, filesystemUsage{
+			filesystem: strings.Join(fields[:capacity-3], " "),
+			mountedOn:  strings.Join(fields[capacity+1:], " "),
+			size:       size * 1024,
+			used:       used * 1024,
+			available:  available * 1024,
+		})
+	}
+
+	return usages
+}
+
+func refineWithStatfs(usage *filesystemUsage) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(usage.mountedOn, &stat); err != nil {
+		return
+	}
+
+	blockSize := uint64(stat.Bsize)
+	usage.size = uint64(stat.Blocks) * blockSize
+	usage.used = (uint64(stat.Blocks) - uint64(stat.Bfree)) * blockSize
+	usage.available = uint64(stat.Bavail) * blockSize
+}
+
+// record converts usage to the structured output, in bytes or human units
+func (u filesystemUsage) record(rawBytes bool) map[string]interface{} {
+	record := map[string]interface{}{
+		"filesystem":  u.filesystem,
+		"mounted_on":  u.mountedOn,
+		"use_percent": usePercent(u.used, u.available),
+	}
+
+	if rawBytes {
+		record["size"] = u.size
+		record["used"] = u.used
+		record["available"] = u.available
+	} else {
+		record["size"] = humanSize(u.size)
+		record["used"] = humanSize(u.used)
+		record["available"] = humanSize(u.available)
+	}
+
+	return record
+}
+
+// usePercent matches df, which rounds up and measures against the space
+// available to unprivileged users
+func usePercent(used, available uint64) int {
+	total := used + available
+	if total == 0 {
+		return 0
+	}
+	return int((used*100 + total - 1) / total)
+}
+
+// humanSize formats bytes in powers of 1024 like `df -h`
+func humanSize(bytes uint64) string {
+	const units = "KMGTPE"
+	if bytes < 1024 {
+		return fmt.Sprintf("%dB", bytes)
+	}
+
+	value := float64(bytes)
+	unit := -1
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+
+	if value < 10 {
+		return fmt.Sprintf("%.1f%c", value, units[unit])
+	}
+	return fmt.Sprintf("%.0f%c", value, units[unit])
+}
+
 func (a *DfAgent) HealthCheck() error {
 	return nil
 }
@@ -52,5 +219,10 @@ func (a *DfAgent) Shutdown() error {
 	return nil
 }
 
+// Classification declares that the agent only reports disk usage
+func (a *DfAgent) Classification() interfaces.Classification {
+	return interfaces.Classification{ReadOnly: true, Idempotent: true}
+}
+
 // Export the agent for plugin loading
 var Agent interfaces.Agent = NewDfAgent()
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+const sampleDf = `Filesystem     1024-blocks      Used Available Capacity Mounted on
+/dev/sda1         41152736  20576368  18462728      53% /
+tmpfs              8192000         0   8192000       0% /dev/shm
+//nas/share name  1048576    524288    524288      50% /mnt/My Share
+`
+
+func TestParseDfOutput(t *testing.T) {
+	usages := parseDfOutput(sampleDf)
+	if len(usages) != 3 {
+		t.Fatalf("Expected 3 filesystems, got %d", len(usages))
+	}
+
+	root := usages[0]
+	if root.filesystem != "/dev/sda1" || root.mountedOn != "/" || root.size != 41152736*1024 {
+		t.Errorf("Unexpected root record: %+v", root)
+	}
+
+	share := usages[2]
+	if share.filesystem != "//nas/share name" || share.mountedOn != "/mnt/My Share" {
+		t.Errorf("Expected names with spaces to be preserved, got %+v", share)
+	}
+
+	record := share.record(false)
+	if record["size"] != "1.0G" || record["use_percent"] != 50 {
+		t.Errorf("Unexpected human record: %v", record)
+	}
+	if share.record(true)["used"] != uint64(524288*1024) {
+		t.Errorf("Expected raw byte counts, got %v", share.record(true))
+	}
+}
+
+func TestDfAgent_Path(t *testing.T) {
+	output, err := NewDfAgent().Process(t.Context(), interfaces.AgentInput{
+		Type:    "df",
+		Payload: map[string]interface{}{"path": t.TempDir(), "bytes": true},
+	})
+	if err != nil || !output.Success {
+		t.Skipf("df unavailable: %v %s", err, output.Error)
+	}
+
+	filesystems := output.Data["filesystems"].([]map[string]interface{})
+	if len(filesystems) != 1 {
+		t.Fatalf("Expected only the filesystem containing the path, got %d", len(filesystems))
+	}
+	if size, ok := filesystems[0]["size"].(uint64); !ok || size == 0 {
+		t.Errorf("Expected a byte count for size, got %v", filesystems[0]["size"])
+	}
+	if output.Data["raw"] == "" || output.Data["exit_code"] != 0 {
+		t.Errorf("Expected raw df output and exit code 0, got %v", output.Data["exit_code"])
+	}
+}
+
+func TestDfAgent_MissingPath(t *testing.T) {
+	if _, err := exec.LookPath("df"); err != nil {
+		t.Skip("df unavailable")
+	}
+
+	output, err := NewDfAgent().Process(t.Context(), interfaces.AgentInput{
+		Type:    "df",
+		Payload: map[string]interface{}{"path": filepath.Join(t.TempDir(), "missing")},
+	})
+	if err != nil {
+		t.Fatalf("Process returned error: %v", err)
+	}
+	if output.Success {
+		t.Fatal("Expected a missing path to fail")
+	}
+	if code, _ := output.Data["exit_code"].(int); code <= 0 || output.Data["stderr"] == "" {
+		t.Errorf("Expected df's exit code and stderr, got %v", output.Data)
+	}
+}
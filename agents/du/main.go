@@ -4,7 +4,10 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"os/exec
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
 )
@@ -13,6 +16,12 @@ type DuAgent struct {
 	name string
 }
 
+// diskUsage is the total size of the files below one directory
+type diskUsage struct {
+	path  string
+	bytes int64
+}
+
 func NewDuAgent() *DuAgent {
 	return &DuAgent{name: "du"}
 }
@@ -27,32 +36,195 @@ func (a *DuAgent) Initialize(config map[string]interface{}) error {
 }
 
 func (a *DuAgent) Process(ctx context.Context, input interfaces.AgentInput) (interfaces.AgentOutput, error) {
-	// Extract path from input
-	path, _ := input["path"].(string)
-
-	// Build du command
-	args := []string{"-h"}
-	if path != "" {
-		args = append(args, path)
-	} else {
-		args = append(args, ".")
+	path, _ := input.Payload["path"].(string)
+	if path == "" {
+		path = "."
+	}
+
+	// A negative depth reports every directory, like du without --max-depth
+	maxDepth := -1
+	switch v := input.Payload["max_depth"].(type) {
+	case int:
+		maxDepth = v
+	case float64:
+		maxDepth = int(v)
+	}
+
+	var exclude []string
+	if patterns, ok := input.Payload["exclude"].([]interface{}); ok {
+		for _, pattern := range patterns {
+			patternStr, ok := pattern.(string)
+			if !ok {
+				continue
+			}
+			if _, err := filepath.Match(patternStr, ""); err != nil {
+				return interfaces.AgentOutput{
+					Success: false,
+					Error:   fmt.Sprintf("invalid exclude pattern %q: %v", patternStr, err),
+				}, nil
+			}
+			exclude = append(exclude, patternStr)
+		}
 	}
 
-	cmd := exec.CommandContext(ctx, "du", args...)
-	output, err := cmd.Output()
+	usages, totalBytes, walkErrors, err := diskUsageOf(ctx, path, maxDepth, exclude)
 	if err != nil {
 		return interfaces.AgentOutput{
-			Text:     fmt.Sprintf("Error executing du: %v", err),
-			Finished: true,
-		}, err
+			Success: false,
+			Error:   fmt.Sprintf("Error computing disk usage: %v", err),
+		}, nil
+	}
+
+	entries := make([]map[string]interface{}, 0, len(usages))
+	var formatted strings.Builder
+	for _, usage := range usages {
+		entries = append(entries, map[string]interface{}{
+			"path":  usage.path,
+			"bytes": usage.bytes,
+			"human": humanSize(usage.bytes),
+		})
+		fmt.Fprintf(&formatted, "%s\t%s\n", humanSize(usage.bytes), usage.path)
 	}
 
 	return interfaces.AgentOutput{
-		Text:     string(output),
-		Finished: true,
+		Success: true,
+		Data: map[string]interface{}{
+			"path":      path,
+			"max_depth": maxDepth,
+			"entries":   entries,
+			"count":     len(entries),
+			"total": map[string]interface{}{
+				"bytes": totalBytes,
+				"human": humanSize(totalBytes),
+			},
+			"errors":    walkErrors,
+			"formatted": formatted.String(),
+		},
 	}, nil
 }
 
+// diskUsageOf walks root summing file sizes into every directory at most
+// maxDepth levels below it, skipping anything matching an exclude pattern,
+// and returns them with the grand total. Unreadable entries are reported and
+// skipped. The result is sorted by size, largest first, then by path.
+func diskUsageOf(ctx context.Context, root string, maxDepth int, exclude []string) ([]diskUsage, int64, []string, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	if !info.IsDir() {
+		return []diskUsage{{path: root, bytes: info.Size()}}, info.Size(), []string{}, nil
+	}
+
+	totals := map[string]int64{}
+	walkErrors := []string{}
+
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			walkErrors = append(walkErrors, err.Error())
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		if rel != "." && isExcluded(rel, exclude) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if info.IsDir() {
+			// Empty directories still get an entry
+			if _, seen := totals[rel]; !seen && (maxDepth < 0 || pathDepth(rel) <= maxDepth) {
+				totals[rel] = 0
+			}
+			return nil
+		}
+
+		// Charge the file to each ancestor directory within the depth limit
+		dir := filepath.Dir(rel)
+		components := []string{}
+		if dir != "." {
+			components = strings.Split(dir, string(filepath.Separator))
+		}
+		limit := len(components)
+		if maxDepth >= 0 && limit > maxDepth {
+			limit = maxDepth
+		}
+		totals["."] += info.Size()
+		for i := 1; i <= limit; i++ {
+			totals[filepath.Join(components[:i]...)] += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	usages := make([]diskUsage, 0, len(totals))
+	for rel, bytes := range totals {
+		usages = append(usages, diskUsage{path: filepath.Join(root, rel), bytes: bytes})
+	}
+	sort.Slice(usages, func(i, j int) bool {
+		if usages[i].bytes != usages[j].bytes {
+			return usages[i].bytes > usages[j].bytes
+		}
+		return usages[i].path < usages[j].path
+	})
+
+	return usages, totals["."], walkErrors, nil
+}
+
+// isExcluded reports whether a relative path matches an exclude pattern,
+// either by its base name or as a whole
+func isExcluded(rel string, exclude []string) bool {
+	base := filepath.Base(rel)
+	for _, pattern := range exclude {
+		if matched, _ := filepath.Match(pattern, base); matched {
+			return true
+		}
+		if matched, _ := filepath.Match(pattern, rel); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// pathDepth counts the directory levels in a path relative to the walk root
+func pathDepth(rel string) int {
+	if rel == "." {
+		return 0
+	}
+	return strings.Count(rel, string(filepath.Separator)) + 1
+}
+
+// humanSize formats a byte count like du -h
+func humanSize(bytes int64) string {
+	const units = "KMGTPE"
+	if bytes < 1024 {
+		return fmt.Sprintf("%dB", bytes)
+	}
+
+	value := float64(bytes)
+	unit := -1
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+
+	if value < 10 {
+		return fmt.Sprintf("%.1f%c", value, units[unit])
+	}
+	return fmt.Sprintf("%.0f%c", value, units[unit])
+}
+
 func (a *DuAgent) HealthCheck() error {
 	return nil
 }
@@ -62,5 +234,10 @@ func (a *DuAgent) Shutdown() error {
 	return nil
 }
 
+// Classification declares that the agent only reports disk usage
+func (a *DuAgent) Classification() interfaces.Classification {
+	return interfaces.Classification{ReadOnly: true, Idempotent: true}
+}
+
 // Export the agent for plugin loading
 var Agent interfaces.Agent = NewDuAgent()
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+// writeTree creates files of the given sizes below root
+func writeTree(t *testing.T, root string, files map[string]int) {
+	t.Helper()
+	for name, size := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+}
+
+func runDu(t *testing.T, payload map[string]interface{}) map[string]interface{} {
+	t.Helper()
+	output, err := NewDuAgent().Process(context.Background(), interfaces.AgentInput{Payload: payload})
+	if err != nil || !output.Success {
+		t.Fatalf("Expected success, got err=%v error=%s", err, output.Error)
+	}
+	return output.Data
+}
+
+func entryBytes(data map[string]interface{}) map[string]int64 {
+	sizes := map[string]int64{}
+	for _, entry := range data["entries"].([]map[string]interface{}) {
+		sizes[entry["path"].(string)] = entry["bytes"].(int64)
+	}
+	return sizes
+}
+
+func TestDuAgent_MaxDepthAggregates(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]int{
+		"top.txt":           100,
+		"src/main.go":       200,
+		"src/pkg/util.go":   300,
+		"src/pkg/deep/x.go": 400,
+		"docs/readme.md":    50,
+	})
+	os.MkdirAll(filepath.Join(root, "empty"), 0755)
+
+	data := runDu(t, map[string]interface{}{"path": root, "max_depth": float64(1)})
+	sizes := entryBytes(data)
+
+	expected := map[string]int64{
+		root:                         1050,
+		filepath.Join(root, "src"):   900,
+		filepath.Join(root, "docs"):  50,
+		filepath.Join(root, "empty"): 0,
+	}
+	if len(sizes) != len(expected) {
+		t.Fatalf("Expected %d entries, got %v", len(expected), sizes)
+	}
+	for path, bytes := range expected {
+		if sizes[path] != bytes {
+			t.Errorf("Expected %s to be %d bytes, got %d", path, bytes, sizes[path])
+		}
+	}
+
+	total := data["total"].(map[string]interface{})
+	if total["bytes"] != int64(1050) || total["human"] != "1.0K" {
+		t.Errorf("Unexpected total: %v", total)
+	}
+
+	// Entries are sorted largest first
+	entries := data["entries"].([]map[string]interface{})
+	if entries[0]["path"] != root || entries[len(entries)-1]["path"] != filepath.Join(root, "empty") {
+		t.Errorf("Expected entries sorted by size, got %v", entries)
+	}
+}
+
+func TestDuAgent_UnlimitedDepthAndExclude(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]int{
+		"app.js":                     10,
+		"node_modules/lib/index.js":  5000,
+		".git/objects/pack":          7000,
+		"src/pkg/deep/module.js":     20,
+		"src/pkg/deep/module.js.map": 30,
+	})
+
+	data := runDu(t, map[string]interface{}{
+		"path":    root,
+		"exclude": []interface{}{"node_modules", ".git", "*.map"},
+	})
+	sizes := entryBytes(data)
+
+	if _, ok := sizes[filepath.Join(root, "node_modules")]; ok {
+		t.Error("Expected node_modules to be excluded")
+	}
+	if sizes[filepath.Join(root, "src/pkg/deep")] != 20 {
+		t.Errorf("Expected nested directories to be reported, got %v", sizes)
+	}
+	if total := data["total"].(map[string]interface{}); total["bytes"] != int64(30) {
+		t.Errorf("Expected excluded sizes to be left out of the total, got %v", total)
+	}
+}
+
+func TestDuAgent_InvalidInput(t *testing.T) {
+	agent := NewDuAgent()
+
+	output, _ := agent.Process(context.Background(), interfaces.AgentInput{
+		Payload: map[string]interface{}{"path": filepath.Join(t.TempDir(), "missing")},
+	})
+	if output.Success {
+		t.Error("Expected a missing path to fail")
+	}
+
+	output, _ = agent.Process(context.Background(), interfaces.AgentInput{
+		Payload: map[string]interface{}{"exclude": []interface{}{"["}},
+	})
+	if output.Success {
+		t.Error("Expected an invalid exclude pattern to fail")
+	}
+}
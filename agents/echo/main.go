@@ -30,35 +30,101 @@ func (a *EchoAgent) Process(ctx context.Context, input interfaces.AgentInput) (i
 	// Extract message and file from input payload
 	message, _ := input.Payload["message"].(string)
 	file, _ := input.Payload["file"].(string)
+	appendToFile, _ := input.Payload["append"].(bool)
+	stream, _ := input.Payload["stream"].(string)
 
-	var output string
-	var err error
+	// Like the shell builtin, a trailing newline is written unless disabled
+	newline := true
+	if value, ok := input.Payload["newline"].(bool); ok {
+		newline = value
+	}
+
+	content := message
+	if newline {
+		content += "\n"
+	}
+
+	data := map[string]interface{}{
+		"message": message,
+		"file":    file,
+	}
 
 	if file != "" {
 		// Echo to file
-		err = os.WriteFile(file, []byte(message), 0644)
+		written, err := writeToFile(file, content, appendToFile)
 		if err != nil {
 			return interfaces.AgentOutput{
 				Success: false,
 				Error:   fmt.Sprintf("Error writing to file %s: %v", file, err),
 			}, nil
 		}
-		output = fmt.Sprintf("Message written to file: %s", file)
+
+		if appendToFile {
+			data["output"] = fmt.Sprintf("Message appended to file: %s", file)
+		} else {
+			data["output"] = fmt.Sprintf("Message written to file: %s", file)
+		}
+		data["append"] = appendToFile
+		data["bytes_written"] = written
+	} else if stream != "" {
+		// Echo to the requested descriptor
+		var target *os.File
+		switch stream {
+		case "stdout":
+			target = os.Stdout
+		case "stderr":
+			target = os.Stderr
+		default:
+			return interfaces.AgentOutput{
+				Success: false,
+				Error:   fmt.Sprintf("Error: invalid stream %q (expected stdout or stderr)", stream),
+			}, nil
+		}
+
+		written, err := target.WriteString(content)
+		if err != nil {
+			return interfaces.AgentOutput{
+				Success: false,
+				Error:   fmt.Sprintf("Error writing to %s: %v", stream, err),
+			}, nil
+		}
+
+		data["output"] = message
+		data["stream"] = stream
+		data["bytes_written"] = written
 	} else {
-		// Echo to stdout
-		output = message
+		// Return the message to the caller
+		data["output"] = message
 	}
 
 	return interfaces.AgentOutput{
 		Success: true,
-		Data: map[string]interface{}{
-			"message": message,
-			"file":    file,
-			"output":  output,
-		},
+		Data:    data,
 	}, nil
 }
 
+// writeToFile writes content to file, appending instead of truncating when
+// requested, and returns the number of bytes written
+func writeToFile(file, content string, appendToFile bool) (int, error) {
+	flags := os.O_WRONLY | os.O_CREATE
+	if appendToFile {
+		flags |= os.O_APPEND
+	} else {
+		flags |= os.O_TRUNC
+	}
+
+	f, err := os.OpenFile(file, flags, 0644)
+	if err != nil {
+		return 0, err
+	}
+
+	written, err := f.WriteString(content)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return written, err
+}
+
 func (a *EchoAgent) HealthCheck() error {
 	return nil
 }
@@ -68,5 +134,10 @@ func (a *EchoAgent) Shutdown() error {
 	return nil
 }
 
+// Classification declares that the agent may overwrite or append to a file
+func (a *EchoAgent) Classification() interfaces.Classification {
+	return interfaces.Classification{Destructive: true}
+}
+
 // Export the agent for plugin loading
 var Agent interfaces.Agent = NewEchoAgent()
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+func TestEchoAgent_AppendAndNewline(t *testing.T) {
+	agent := NewEchoAgent()
+	file := filepath.Join(t.TempDir(), "out.txt")
+
+	payloads := []map[string]interface{}{
+		{"message": "first", "file": file},
+		{"message": "second", "file": file, "append": true},
+		{"message": "third", "file": file, "append": true, "newline": false},
+	}
+
+	for _, payload := range payloads {
+		output, err := agent.Process(t.Context(), interfaces.AgentInput{Type: "echo", Payload: payload})
+		if err != nil || !output.Success {
+			t.Fatalf("Echo failed: err=%v, output=%s", err, output.Error)
+		}
+	}
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if string(content) != "first\nsecond\nthird" {
+		t.Errorf("Unexpected file content %q", string(content))
+	}
+}
+
+func TestEchoAgent_Stream(t *testing.T) {
+	agent := NewEchoAgent()
+
+	output, _ := agent.Process(t.Context(), interfaces.AgentInput{
+		Type:    "echo",
+		Payload: map[string]interface{}{"message": "hello", "stream": "stderr"},
+	})
+	if !output.Success {
+		t.Fatalf("Echo failed: %s", output.Error)
+	}
+	if written := output.Data["bytes_written"].(int); written != len("hello\n") {
+		t.Errorf("Expected %d bytes written, got %d", len("hello\n"), written)
+	}
+
+	output, _ = agent.Process(t.Context(), interfaces.AgentInput{
+		Type:    "echo",
+		Payload: map[string]interface{}{"message": "hello", "stream": "stdin"},
+	})
+	if output.Success {
+		t.Error("Expected failure for invalid stream")
+	}
+}
@@ -4,8 +4,9 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"os/exec
+	"strings"
 
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/execrun"
 	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
 )
 
@@ -28,8 +29,8 @@ func (a *FindAgent) Initialize(config map[string]interface{}) error {
 
 func (a *FindAgent) Process(ctx context.Context, input interfaces.AgentInput) (interfaces.AgentOutput, error) {
 	// Extract path and name from input
-	path, _ := input["path"].(string)
-	name, _ := input["name"].(string)
+	path, _ := input.Payload["path"].(string)
+	name, _ := input.Payload["name"].(string)
 
 	// Build find command
 	args := []string{}
@@ -43,18 +44,48 @@ func (a *FindAgent) Process(ctx context.Context, input interfaces.AgentInput) (i
 		args = append(args, "-name", name)
 	}
 
-	cmd := exec.CommandContext(ctx, "find", args...)
-	output, err := cmd.Output()
+	result, err := execrun.Run(ctx, "find", args...)
 	if err != nil {
 		return interfaces.AgentOutput{
-			Text:     fmt.Sprintf("Error executing find: %v", err),
-			Finished: true,
-		}, err
+			Success: false,
+			Error:   fmt.Sprintf("Error executing find: %v", err),
+			Data:    result.Fields(),
+		}, nil
 	}
 
+	files := []string{}
+	for _, line := range strings.Split(result.Stdout, "\n") {
+		if line != "" {
+			files = append(files, line)
+		}
+	}
+
+	// find exits 1 when any directory could not be read; what it found
+	// elsewhere is still a result, with the errors as warnings
+	var warnings []string
+	if result.ExitCode != 0 {
+		if len(files) == 0 {
+			return interfaces.AgentOutput{
+				Success: false,
+				Error:   fmt.Sprintf("Error executing find: %s", result.Error()),
+				Data:    result.Fields(),
+			}, nil
+		}
+		for _, line := range strings.Split(strings.TrimSpace(result.Stderr), "\n") {
+			if line != "" {
+				warnings = append(warnings, line)
+			}
+		}
+	}
+
+	data := result.Fields()
+	data["files"] = files
+	data["count"] = len(files)
+
 	return interfaces.AgentOutput{
-		Text:     string(output),
-		Finished: true,
+		Success:  true,
+		Data:     data,
+		Warnings: warnings,
 	}, nil
 }
 
@@ -67,5 +98,10 @@ func (a *FindAgent) Shutdown() error {
 	return nil
 }
 
+// Classification declares that the agent only searches the file tree
+func (a *FindAgent) Classification() interfaces.Classification {
+	return interfaces.Classification{ReadOnly: true, Idempotent: true}
+}
+
 // Export the agent for plugin loading
 var Agent interfaces.Agent = NewFindAgent()
@@ -1,19 +1,85 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"log"
-	"os/exec"
+	"os"
+	"path/filepath"
+	"regexp"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
 )
 
+const (
+	// defaultMaxMatches bounds the matches returned by one search
+	defaultMaxMatches = 1000
+	// maxLineLength is the longest line that is searched; files with longer
+	// lines are reported as errors
+	maxLineLength = 1024 * 1024
+	// binarySniffSize is how much of a file is checked for NUL bytes
+	binarySniffSize = 8000
+)
+
+// Exit codes follow grep(1), so callers can tell a search that found nothing
+// from one that failed
+const (
+	exitMatched = 0
+	exitNoMatch = 1
+	exitError   = 2
+)
+
+// skippedDirs are version control directories never searched
+var skippedDirs = map[string]bool{
+	".git": true,
+	".hg":  true,
+	".svn": true,
+}
+
 type GrepAgent struct {
 	name string
 }
 
+// searchOptions control a search below one path
+type searchOptions struct {
+	pattern       *regexp.Regexp
+	contextBefore int
+	contextAfter  int
+	filesOnly     bool
+	maxMatches    int
+	include       []string
+	exclude       []string
+}
+
+// lineMatch is one matching line with its surrounding context
+type lineMatch struct {
+	LineNumber    int      `json:"line_number"`
+	Column        int      `json:"column"`
+	Line          string   `json:"line"`
+	ContextBefore []string `json:"context_before"`
+	ContextAfter  []string `json:"context_after"`
+}
+
+// fileMatches groups the matches found in one file
+type fileMatches struct {
+	File    string      `json:"file"`
+	Matches []lineMatch `json:"matches"`
+}
+
+// searchResult is the outcome of a search over every file below a path
+type searchResult struct {
+	files         []fileMatches
+	filesSearched int
+	totalMatches  int
+	truncated     bool
+	errors        []string
+}
+
 func NewGrepAgent() *GrepAgent {
 	return &GrepAgent{name: "grep"}
 }
@@ -22,6 +88,22 @@ func (a *GrepAgent) Name() string {
 	return a.name
 }
 
+// InputSchema declares the scalar options, so a model that writes "true" for
+// ignore_case or 5.0 for context gets what it asked for
+func (a *GrepAgent) InputSchema() interfaces.InputSchema {
+	return interfaces.InputSchema{
+		"pattern":        interfaces.ParamString,
+		"path":           interfaces.ParamString,
+		"fixed_strings":  interfaces.ParamBool,
+		"ignore_case":    interfaces.ParamBool,
+		"files_only":     interfaces.ParamBool,
+		"context":        interfaces.ParamInt,
+		"before_context": interfaces.ParamInt,
+		"after_context":  interfaces.ParamInt,
+		"max_matches":    interfaces.ParamInt,
+	}
+}
+
 func (a *GrepAgent) Initialize(config map[string]interface{}) error {
 	log.Printf("Initializing %s agent", a.name)
 	return nil
@@ -29,46 +111,313 @@ func (a *GrepAgent) Initialize(config map[string]interface{}) error {
 
 func (a *GrepAgent) Process(ctx context.Context, input interfaces.AgentInput) (interfaces.AgentOutput, error) {
 	// Extract pattern and path from input
-	pattern, ok := input["pattern"].(string)
+	pattern, ok := input.Payload["pattern"].(string)
 	if !ok || pattern == "" {
-		return interfaces.AgentOutput{
-			Text:     "Error: pattern parameter is required",
-			Finished: true,
-		}, fmt.Errorf("pattern parameter is required")
+		return failure("Error: pattern parameter is required"), nil
 	}
 
-	path, ok := input["path"].(string)
+	path, ok := input.Payload["path"].(string)
 	if !ok || path == "" {
-		return interfaces.AgentOutput{
-			Text:     "Error: path parameter is required",
-			Finished: true,
-		}, fmt.Errorf("path parameter is required")
+		return failure("Error: path parameter is required"), nil
 	}
 
-	// Build grep command
-	cmd := exec.CommandContext(ctx, "grep", "-n", pattern, path)
-	output, err := cmd.Output()
+	opts, err := parseOptions(pattern, input.Payload)
 	if err != nil {
-		// grep returns non-zero exit code when no matches found, which is not an error
-		if strings.Contains(string(output), "No such file or directory") {
-			return interfaces.AgentOutput{
-				Text:     fmt.Sprintf("Error: %s", string(output)),
-				Finished: true,
-			}, err
+		return failure(fmt.Sprintf("Error: %v", err)), nil
+	}
+
+	result, err := search(ctx, path, opts)
+	if err != nil {
+		return failure(fmt.Sprintf("Error: %v", err)), nil
+	}
+
+	// Files that could not be read are listed in errors, and do not turn a
+	// search that otherwise ran into a failure
+	exitCode := exitMatched
+	if len(result.files) == 0 {
+		exitCode = exitNoMatch
+	}
+
+	data := map[string]interface{}{
+		"exit_code":      exitCode,
+		"pattern":        pattern,
+		"path":           path,
+		"files_matched":  len(result.files),
+		"files_searched": result.filesSearched,
+		"truncated":      result.truncated,
+		"errors":         result.errors,
+	}
+
+	if opts.filesOnly {
+		files := make([]string, 0, len(result.files))
+		for _, file := range result.files {
+			files = append(files, file.File)
 		}
-		// If no matches, just return empty output
-		return interfaces.AgentOutput{
-			Text:     "",
-			Finished: true,
-		}, nil
+		data["files"] = files
+	} else {
+		data["results"] = result.files
+		data["total_matches"] = result.totalMatches
+		data["formatted"] = formatMatches(result.files)
 	}
 
 	return interfaces.AgentOutput{
-		Text:     string(output),
-		Finished: true,
+		Success: true,
+		Data:    data,
 	}, nil
 }
 
+// failure reports a search that could not run
+func failure(message string) interfaces.AgentOutput {
+	return interfaces.AgentOutput{
+		Success: false,
+		Error:   message,
+		Data:    map[string]interface{}{"exit_code": exitError},
+	}
+}
+
+// parseOptions reads the search options from the payload. pattern is a Go
+// regular expression unless fixed_strings is set.
+func parseOptions(pattern string, payload map[string]interface{}) (searchOptions, error) {
+	opts := searchOptions{maxMatches: defaultMaxMatches}
+
+	if fixed, _ := payload["fixed_strings"].(bool); fixed {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if ignoreCase, _ := payload["ignore_case"].(bool); ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return opts, fmt.Errorf("invalid pattern: %v", err)
+	}
+	opts.pattern = re
+
+	// context sets both sides; before_context and after_context override it
+	if n, ok := getInt(payload, "context"); ok {
+		opts.contextBefore, opts.contextAfter = n, n
+	}
+	if n, ok := getInt(payload, "before_context"); ok {
+		opts.contextBefore = n
+	}
+	if n, ok := getInt(payload, "after_context"); ok {
+		opts.contextAfter = n
+	}
+	if opts.contextBefore < 0 || opts.contextAfter < 0 {
+		return opts, fmt.Errorf("context must not be negative")
+	}
+
+	if n, ok := getInt(payload, "max_matches"); ok && n > 0 {
+		opts.maxMatches = n
+	}
+	opts.filesOnly, _ = payload["files_only"].(bool)
+
+	for _, key := range []string{"include", "exclude"} {
+		patterns, ok := payload[key].([]interface{})
+		if !ok {
+			continue
+		}
+		for _, p := range patterns {
+			patternStr, ok := p.(string)
+			if !ok {
+				continue
+			}
+			if _, err := filepath.Match(patternStr, ""); err != nil {
+				return opts, fmt.Errorf("invalid %s pattern %q: %v", key, patternStr, err)
+			}
+			if key == "include" {
+				opts.include = append(opts.include, patternStr)
+			} else {
+				opts.exclude = append(opts.exclude, patternStr)
+			}
+		}
+	}
+
+	return opts, nil
+}
+
+// search looks for opts.pattern in root, or in every file below it when it
+// is a directory, in lexical order. Version control directories, binary
+// files, and excluded paths are skipped; unreadable files are reported and
+// skipped.
+func search(ctx context.Context, root string, opts searchOptions) (*searchResult, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &searchResult{files: []fileMatches{}, errors: []string{}}
+
+	if !info.IsDir() {
+		searchFile(root, opts, result)
+		return result, nil
+	}
+
+	err = filepath.WalkDir(root, func(path string, entry os.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			result.errors = append(result.errors, err.Error())
+			return nil
+		}
+		if result.truncated {
+			return filepath.SkipAll
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			if rel != "." && (skippedDirs[entry.Name()] || matchesAny(rel, opts.exclude)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() || matchesAny(rel, opts.exclude) {
+			return nil
+		}
+		if len(opts.include) > 0 && !matchesAny(rel, opts.include) {
+			return nil
+		}
+
+		searchFile(path, opts, result)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// searchFile adds the matches in one file to result
+func searchFile(path string, opts searchOptions, result *searchResult) {
+	file, err := os.Open(path)
+	if err != nil {
+		result.errors = append(result.errors, err.Error())
+		return
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	if head, _ := reader.Peek(binarySniffSize); bytes.IndexByte(head, 0) >= 0 {
+		return
+	}
+	result.filesSearched++
+
+	matches, err := scanLines(reader, opts, opts.maxMatches-result.totalMatches)
+	if err != nil {
+		result.errors = append(result.errors, fmt.Sprintf("%s: %v", path, err))
+	}
+	if len(matches) == 0 {
+		return
+	}
+
+	result.files = append(result.files, fileMatches{File: path, Matches: matches})
+	if opts.filesOnly {
+		return
+	}
+
+	result.totalMatches += len(matches)
+	if result.totalMatches >= opts.maxMatches {
+		result.truncated = true
+	}
+}
+
+// scanLines returns up to limit matching lines from r with their context. In
+// files_only mode it stops at the first match.
+func scanLines(r io.Reader, opts searchOptions, limit int) ([]lineMatch, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineLength)
+
+	var matches []lineMatch
+	var before []string
+	// pending are the indexes of matches still collecting context_after
+	var pending []int
+
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+
+		for len(pending) > 0 && len(matches[pending[0]].ContextAfter) >= opts.contextAfter {
+			pending = pending[1:]
+		}
+		for _, i := range pending {
+			matches[i].ContextAfter = append(matches[i].ContextAfter, line)
+		}
+
+		if loc := opts.pattern.FindStringIndex(line); loc != nil && len(matches) < limit {
+			matches = append(matches, lineMatch{
+				LineNumber:    lineNumber,
+				Column:        utf8.RuneCountInString(line[:loc[0]]) + 1,
+				Line:          line,
+				ContextBefore: append([]string{}, before...),
+				ContextAfter:  []string{},
+			})
+			pending = append(pending, len(matches)-1)
+
+			if opts.filesOnly {
+				return matches, nil
+			}
+		}
+
+		if len(matches) >= limit && len(pending) == 0 {
+			break
+		}
+
+		if opts.contextBefore > 0 {
+			before = append(before, line)
+			if len(before) > opts.contextBefore {
+				before = before[1:]
+			}
+		}
+	}
+
+	return matches, scanner.Err()
+}
+
+// matchesAny reports whether a relative path matches one of patterns, either
+// by its base name or as a whole
+func matchesAny(rel string, patterns []string) bool {
+	base := filepath.Base(rel)
+	for _, pattern := range patterns {
+		if matched, _ := filepath.Match(pattern, base); matched {
+			return true
+		}
+		if matched, _ := filepath.Match(pattern, rel); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// formatMatches renders matches like grep -n over several files
+func formatMatches(files []fileMatches) string {
+	var sb strings.Builder
+	for _, file := range files {
+		for _, match := range file.Matches {
+			fmt.Fprintf(&sb, "%s:%d:%s\n", file.File, match.LineNumber, match.Line)
+		}
+	}
+	return sb.String()
+}
+
+// getInt reads a number that may arrive as an int or as a JSON float64
+func getInt(values map[string]interface{}, key string) (int, bool) {
+	switch v := values[key].(type) {
+	case int:
+		return v, true
+	case int64:
+		return int(v), true
+	case float64:
+		return int(v), true
+	}
+	return 0, false
+}
+
 func (a *GrepAgent) HealthCheck() error {
 	return nil
 }
@@ -78,5 +427,10 @@ func (a *GrepAgent) Shutdown() error {
 	return nil
 }
 
+// Classification declares that the agent only reads files
+func (a *GrepAgent) Classification() interfaces.Classification {
+	return interfaces.Classification{ReadOnly: true, Idempotent: true}
+}
+
 // Export the agent for plugin loading
 var Agent interfaces.Agent = NewGrepAgent()
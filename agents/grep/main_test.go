@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+// writeTree creates files below a temporary directory and returns it
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	return root
+}
+
+func grep(t *testing.T, payload map[string]interface{}) interfaces.AgentOutput {
+	t.Helper()
+	output, err := NewGrepAgent().Process(context.Background(), interfaces.AgentInput{Payload: payload})
+	if err != nil {
+		t.Fatalf("Process returned error: %v", err)
+	}
+	return output
+}
+
+var searchTree = map[string]string{
+	"main.go":          "package main\n\n// TODO: parse flags\nfunc main() {\n\trun() // TODO handle error\n}\n",
+	"lib/util.go":      "package lib\n\nfunc helper() {}\n\n// TODO: remove\n",
+	"lib/util_test.go": "package lib\n",
+	"README.md":        "See the ToDo list.\n",
+	"docs/notes.txt":   "line 1\nline 2\nTODO: write docs\nline 4\nline 5\n",
+	".git/HEAD":        "TODO: not searched\n",
+	"data.bin":         "TODO\x00\x01\x02",
+}
+
+func TestGrep_GroupsMatchesByFile(t *testing.T) {
+	root := writeTree(t, searchTree)
+
+	output := grep(t, map[string]interface{}{"pattern": "TODO", "path": root})
+	if !output.Success {
+		t.Fatalf("Expected search to succeed, got: %s", output.Error)
+	}
+
+	results := output.Data["results"].([]fileMatches)
+	var files []string
+	for _, result := range results {
+		rel, _ := filepath.Rel(root, result.File)
+		files = append(files, rel)
+	}
+	expected := []string{"docs/notes.txt", "lib/util.go", "main.go"}
+	if !reflect.DeepEqual(files, expected) {
+		t.Fatalf("Expected matches in %v, got %v", expected, files)
+	}
+
+	main := results[2].Matches
+	if len(main) != 2 {
+		t.Fatalf("Expected 2 matches in main.go, got %v", main)
+	}
+	if main[0].LineNumber != 3 || main[0].Column != 4 || main[0].Line != "// TODO: parse flags" {
+		t.Errorf("Unexpected first match: %+v", main[0])
+	}
+	if main[1].LineNumber != 5 || main[1].Column != 11 {
+		t.Errorf("Unexpected second match: %+v", main[1])
+	}
+
+	if output.Data["files_matched"] != 3 || output.Data["total_matches"] != 4 {
+		t.Errorf("Expected 3 files and 4 matches, got files_matched=%v total_matches=%v",
+			output.Data["files_matched"], output.Data["total_matches"])
+	}
+	// The binary file and .git are skipped
+	if output.Data["files_searched"] != 5 {
+		t.Errorf("Expected 5 files searched, got %v", output.Data["files_searched"])
+	}
+}
+
+func TestGrep_Context(t *testing.T) {
+	root := writeTree(t, searchTree)
+
+	output := grep(t, map[string]interface{}{
+		"pattern": "TODO",
+		"path":    filepath.Join(root, "docs/notes.txt"),
+		"context": 1,
+	})
+	match := output.Data["results"].([]fileMatches)[0].Matches[0]
+	if !reflect.DeepEqual(match.ContextBefore, []string{"line 2"}) || !reflect.DeepEqual(match.ContextAfter, []string{"line 4"}) {
+		t.Errorf("Expected one line of context each side, got %+v", match)
+	}
+
+	// Context is clipped at the start and end of the file
+	output = grep(t, map[string]interface{}{
+		"pattern":        "line [15]",
+		"path":           filepath.Join(root, "docs/notes.txt"),
+		"before_context": 3,
+		"after_context":  float64(3),
+	})
+	matches := output.Data["results"].([]fileMatches)[0].Matches
+	if len(matches[0].ContextBefore) != 0 || len(matches[0].ContextAfter) != 3 {
+		t.Errorf("Unexpected context for first line: %+v", matches[0])
+	}
+	if len(matches[1].ContextBefore) != 3 || len(matches[1].ContextAfter) != 0 {
+		t.Errorf("Unexpected context for last line: %+v", matches[1])
+	}
+}
+
+func TestGrep_FilesOnly(t *testing.T) {
+	root := writeTree(t, searchTree)
+
+	output := grep(t, map[string]interface{}{
+		"pattern":     "todo",
+		"path":        root,
+		"files_only":  true,
+		"ignore_case": true,
+		"include":     []interface{}{"*.go", "*.md"},
+	})
+	if !output.Success {
+		t.Fatalf("Expected search to succeed, got: %s", output.Error)
+	}
+
+	expected := []string{filepath.Join(root, "README.md"), filepath.Join(root, "lib/util.go"), filepath.Join(root, "main.go")}
+	if !reflect.DeepEqual(output.Data["files"], expected) || output.Data["files_matched"] != 3 {
+		t.Errorf("Expected files %v, got %v", expected, output.Data["files"])
+	}
+	if _, ok := output.Data["results"]; ok {
+		t.Error("Expected files_only output to omit results")
+	}
+}
+
+func TestGrep_MaxMatchesAndErrors(t *testing.T) {
+	root := writeTree(t, searchTree)
+
+	output := grep(t, map[string]interface{}{"pattern": "TODO", "path": root, "max_matches": 2})
+	if output.Data["total_matches"] != 2 || output.Data["truncated"] != true {
+		t.Errorf("Expected 2 matches and truncation, got total_matches=%v truncated=%v",
+			output.Data["total_matches"], output.Data["truncated"])
+	}
+
+	if output := grep(t, map[string]interface{}{"pattern": "(", "path": root}); output.Success {
+		t.Error("Expected an invalid pattern to fail")
+	}
+	if output := grep(t, map[string]interface{}{"pattern": "x", "path": filepath.Join(root, "missing")}); output.Success {
+		t.Error("Expected a missing path to fail")
+	}
+	if output := grep(t, map[string]interface{}{"pattern": "x"}); output.Success {
+		t.Error("Expected a missing path parameter to fail")
+	}
+}
+
+func TestGrep_ExitCodes(t *testing.T) {
+	root := writeTree(t, searchTree)
+
+	tests := []struct {
+		name     string
+		payload  map[string]interface{}
+		success  bool
+		exitCode int
+	}{
+		{"matches", map[string]interface{}{"pattern": "TODO", "path": root}, true, exitMatched},
+		{"no matches", map[string]interface{}{"pattern": "FIXME", "path": root}, true, exitNoMatch},
+		{"no matches in files_only mode", map[string]interface{}{"pattern": "FIXME", "path": root, "files_only": true}, true, exitNoMatch},
+		{"invalid pattern", map[string]interface{}{"pattern": "(", "path": root}, false, exitError},
+		{"missing path", map[string]interface{}{"pattern": "TODO", "path": filepath.Join(root, "missing")}, false, exitError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := grep(t, tt.payload)
+			if output.Success != tt.success || output.Data["exit_code"] != tt.exitCode {
+				t.Errorf("Expected success=%v exit_code=%d, got success=%v exit_code=%v (%s)",
+					tt.success, tt.exitCode, output.Success, output.Data["exit_code"], output.Error)
+			}
+		})
+	}
+
+	// No matches is an empty result, not a missing one
+	output := grep(t, map[string]interface{}{"pattern": "FIXME", "path": root})
+	if results, ok := output.Data["results"].([]fileMatches); !ok || len(results) != 0 {
+		t.Errorf("Expected empty results, got %#v", output.Data["results"])
+	}
+}
@@ -4,9 +4,9 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"os/exec"
 	"strings"
 
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/execrun"
 	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
 )
 
@@ -43,17 +43,25 @@ func (a *LsAgent) Process(ctx context.Context, input interfaces.AgentInput) (int
 		args = append(args, ".")
 	}
 
-	cmd := exec.CommandContext(ctx, "ls", args...)
-	output, err := cmd.Output()
+	result, err := execrun.Run(ctx, "ls", args...)
 	if err != nil {
 		return interfaces.AgentOutput{
 			Success: false,
 			Error:   fmt.Sprintf("Error executing ls: %v", err),
+			Data:    result.Fields(),
 		}, nil
 	}
+	if result.ExitCode != 0 {
+		return interfaces.AgentOutput{
+			Success: false,
+			Error:   fmt.Sprintf("Error executing ls: %s", result.Error()),
+			Data:    result.Fields(),
+		}, nil
+	}
+	output := result.Stdout
 
 	// Parse the output and return structured data
-	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	lines := strings.Split(strings.TrimSpace(output), "\n")
 	var files []interface{}
 	var dirs []interface{}
 
@@ -63,15 +71,16 @@ func (a *LsAgent) Process(ctx context.Context, input interfaces.AgentInput) (int
 		}
 	}
 
+	data := result.Fields()
+	data["output"] = output
+	data["files"] = files
+	data["dirs"] = dirs
+	data["path"] = path
+	data["flags"] = flags
+
 	return interfaces.AgentOutput{
 		Success: true,
-		Data: map[string]interface{}{
-			"output": string(output),
-			"files":  files,
-			"dirs":   dirs,
-			"path":   path,
-			"flags":  flags,
-		},
+		Data:    data,
 	}, nil
 }
 
@@ -84,5 +93,10 @@ func (a *LsAgent) Shutdown() error {
 	return nil
 }
 
+// Classification declares that the agent only lists directories
+func (a *LsAgent) Classification() interfaces.Classification {
+	return interfaces.Classification{ReadOnly: true, Idempotent: true}
+}
+
 // Export the agent for plugin loading
 var Agent interfaces.Agent = NewLsAgent()
@@ -5,9 +5,12 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"strconv"
 	"time"
 
 	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/pathutil"
 )
 
 type MkdirAgent struct {
@@ -37,8 +40,38 @@ func (a *MkdirAgent) Process(ctx context.Context, input interfaces.AgentInput) (
 		}, nil
 	}
 
-	// Create directory with default permissions
-	err := os.MkdirAll(path, 0755)
+	// Parse optional octal mode, defaulting to 0755
+	mode := os.FileMode(0755)
+	if modeStr, ok := input.Payload["mode"].(string); ok && modeStr != "" {
+		parsed, err := strconv.ParseInt(modeStr, 8, 32)
+		if err != nil || parsed < 0 || parsed > 0777 {
+			return interfaces.AgentOutput{
+				Success: false,
+				Error:   fmt.Sprintf("Error: invalid mode %q, expected an octal string like \"0755\"", modeStr),
+			}, nil
+		}
+		mode = os.FileMode(parsed)
+	}
+
+	// Check whether the path already exists before creating it
+	alreadyExisted := false
+	if existing, err := os.Stat(path); err == nil {
+		if !existing.IsDir() {
+			return interfaces.AgentOutput{
+				Success: false,
+				Error:   fmt.Sprintf("Error: %s exists and is not a directory", path),
+			}, nil
+		}
+		alreadyExisted = true
+	}
+
+	// Count the missing ancestors first; MkdirAll does not say what it made
+	parentsCreated := 0
+	if !alreadyExisted {
+		parentsCreated = missingParents(path)
+	}
+
+	err := os.MkdirAll(path, mode)
 	if err != nil {
 		return interfaces.AgentOutput{
 			Success: false,
@@ -58,14 +91,31 @@ func (a *MkdirAgent) Process(ctx context.Context, input interfaces.AgentInput) (
 	return interfaces.AgentOutput{
 		Success: true,
 		Data: map[string]interface{}{
-			"path":     path,
-			"created":  true,
-			"mode":     dirInfo.Mode(),
-			"modified": dirInfo.ModTime().Format(time.RFC3339),
+			"path":            path,
+			"resolved_path":   pathutil.Resolve(path),
+			"created":         !alreadyExisted,
+			"already_existed": alreadyExisted,
+			"parents_created": parentsCreated,
+			"mode":            dirInfo.Mode(),
+			"modified":        dirInfo.ModTime().Format(time.RFC3339),
 		},
 	}, nil
 }
 
+// missingParents counts the ancestors of path that do not exist yet
+func missingParents(path string) int {
+	missing := 0
+	for dir := filepath.Dir(filepath.Clean(path)); ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(dir); err == nil {
+			return missing
+		}
+		missing++
+		if parent := filepath.Dir(dir); parent == dir {
+			return missing
+		}
+	}
+}
+
 func (a *MkdirAgent) HealthCheck() error {
 	return nil
 }
@@ -75,5 +125,10 @@ func (a *MkdirAgent) Shutdown() error {
 	return nil
 }
 
+// Classification declares that the agent creates directories; creating one that exists changes nothing
+func (a *MkdirAgent) Classification() interfaces.Classification {
+	return interfaces.Classification{Idempotent: true}
+}
+
 // Export the agent for plugin loading
 var Agent interfaces.Agent = NewMkdirAgent()
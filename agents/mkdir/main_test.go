@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+func mkdir(t *testing.T, path string) interfaces.AgentOutput {
+	t.Helper()
+	output, err := NewMkdirAgent().Process(t.Context(), interfaces.AgentInput{
+		Type:    "mkdir",
+		Payload: map[string]interface{}{"path": path},
+	})
+	if err != nil {
+		t.Fatalf("Process returned error: %v", err)
+	}
+	return output
+}
+
+func TestMkdirAgent_CreatesWithParents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a", "b", "c")
+
+	output := mkdir(t, path)
+	if !output.Success {
+		t.Fatalf("Expected success, got error: %s", output.Error)
+	}
+	if output.Data["created"] != true || output.Data["already_existed"] != false {
+		t.Errorf("Expected a fresh create, got %v", output.Data)
+	}
+	if output.Data["parents_created"] != 2 {
+		t.Errorf("Expected 2 parents created, got %v", output.Data["parents_created"])
+	}
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		t.Errorf("Expected %s to be a directory: %v", path, err)
+	}
+}
+
+func TestMkdirAgent_ExistingDirectory(t *testing.T) {
+	path := t.TempDir()
+
+	output := mkdir(t, path)
+	if !output.Success {
+		t.Fatalf("Expected an existing directory to succeed, got error: %s", output.Error)
+	}
+	if output.Data["created"] != false || output.Data["already_existed"] != true || output.Data["parents_created"] != 0 {
+		t.Errorf("Expected the directory to be reported as already existing, got %v", output.Data)
+	}
+}
+
+func TestMkdirAgent_PathIsAFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file.txt")
+	if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	output := mkdir(t, path)
+	if output.Success || !strings.Contains(output.Error, "is not a directory") {
+		t.Errorf("Expected a not-a-directory error, got %+v", output)
+	}
+	if content, _ := os.ReadFile(path); string(content) != "data" {
+		t.Error("Expected the file to be left alone")
+	}
+}
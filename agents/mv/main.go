@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 
 	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/pathutil"
 )
 
 type MvAgent struct {
@@ -60,6 +61,9 @@ func (a *MvAgent) Process(ctx context.Context, input interfaces.AgentInput) (int
 		}, nil
 	}
 
+	// Resolve the source while it still exists
+	resolvedSource := pathutil.Resolve(source)
+
 	// Perform the move operation
 	err = os.Rename(source, destination)
 	if err != nil {
@@ -87,6 +91,8 @@ func (a *MvAgent) Process(ctx context.Context, input interfaces.AgentInput) (int
 		Data: map[string]interface{}{
 			"source":               source,
 			"destination":          destination,
+			"resolved_source":      resolvedSource,
+			"resolved_destination": pathutil.Resolve(destination),
 			"absolute_source":      absSource,
 			"absolute_destination": absDestination,
 			"type":                 map[bool]string{true: "directory", false: "file"}[sourceInfo.IsDir()],
@@ -107,5 +113,10 @@ func (a *MvAgent) Shutdown() error {
 	return nil
 }
 
+// Classification declares that the agent moves files and may overwrite the destination
+func (a *MvAgent) Classification() interfaces.Classification {
+	return interfaces.Classification{Destructive: true}
+}
+
 // Export the agent for plugin loading
 var Agent interfaces.Agent = NewMvAgent()
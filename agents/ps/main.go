@@ -1,14 +1,23 @@
 package main
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"log"
-	"os/exec
+	"sort"
+	"strconv"
+	"strings"
 
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/execrun"
 	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
 )
 
+// psColumns is the number of columns in `ps aux` output; COMMAND is last and
+// may itself contain spaces
+const psColumns = 11
+
 type PsAgent struct {
 	name string
 }
@@ -27,22 +36,129 @@ func (a *PsAgent) Initialize(config map[string]interface{}) error {
 }
 
 func (a *PsAgent) Process(ctx context.Context, input interfaces.AgentInput) (interfaces.AgentOutput, error) {
+	filter, _ := input.Payload["filter"].(string)
+	sortBy, _ := input.Payload["sort_by"].(string)
+	if sortBy != "" && sortBy != "cpu" && sortBy != "mem" {
+		return interfaces.AgentOutput{
+			Success: false,
+			Error:   fmt.Sprintf("Error: invalid sort_by %q (expected cpu or mem)", sortBy),
+		}, nil
+	}
+
 	// Build ps command
-	cmd := exec.CommandContext(ctx, "ps", "aux")
-	output, err := cmd.Output()
+	result, err := execrun.Run(ctx, "ps", "aux")
+	if err == nil && result.ExitCode != 0 {
+		err = errors.New(result.Error())
+	}
 	if err != nil {
 		return interfaces.AgentOutput{
-			Text:     fmt.Sprintf("Error executing ps: %v", err),
-			Finished: true,
-		}, err
+			Success: false,
+			Error:   fmt.Sprintf("Error executing ps: %v", err),
+			Data:    result.Fields(),
+		}, nil
+	}
+
+	processes := parsePsOutput(result.Stdout)
+	if filter != "" {
+		processes = filterProcesses(processes, filter)
+	}
+	if sortBy != "" {
+		sortProcesses(processes, sortBy)
 	}
 
+	data := result.Fields()
+	data["processes"] = processes
+	data["count"] = len(processes)
+	data["raw"] = result.Stdout
+
 	return interfaces.AgentOutput{
-		Text:     string(output),
-		Finished: true,
+		Success: true,
+		Data:    data,
 	}, nil
 }
 
+// parsePsOutput converts `ps aux` output into one record per process, skipping
+// the header and any line with too few columns
+func parsePsOutput(output string) []map[string]interface{} {
+	processes := []map[string]interface{}{}
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	header := true
+	for scanner.Scan() {
+		if header {
+			header = false
+			continue
+		}
+
+		fields := splitColumns(scanner.Text(), psColumns)
+		if len(fields) < psColumns {
+			continue
+		}
+
+		pid, _ := strconv.Atoi(fields[1])
+		cpu, _ := strconv.ParseFloat(fields[2], 64)
+		mem, _ := strconv.ParseFloat(fields[3], 64)
+		vsz, _ := strconv.ParseInt(fields[4], 10, 64)
+		rss, _ := strconv.ParseInt(fields[5], 10, 64)
+
+		processes = append(processes, map[string]interface{}{
+			"user":    fields[0],
+			"pid":     pid,
+			"cpu":     cpu,
+			"mem":     mem,
+			"vsz":     vsz,
+			"rss":     rss,
+			"tty":     fields[6],
+			"stat":    fields[7],
+			"start":   fields[8],
+			"time":    fields[9],
+			"command": fields[10],
+		})
+	}
+
+	return processes
+}
+
+// splitColumns splits line on runs of whitespace into at most n fields, the
+// last of which keeps the remainder of the line
+func splitColumns(line string, n int) []string {
+	var fields []string
+	rest := strings.TrimSpace(line)
+
+	for len(fields) < n-1 && rest != "" {
+		end := strings.IndexAny(rest, " \t")
+		if end < 0 {
+			break
+		}
+		fields = append(fields, rest[:end])
+		rest = strings.TrimLeft(rest[end:], " \t")
+	}
+
+	if rest != "" {
+		fields = append(fields, rest)
+	}
+	return fields
+}
+
+// filterProcesses keeps processes whose command contains filter, ignoring case
+func filterProcesses(processes []map[string]interface{}, filter string) []map[string]interface{} {
+	filter = strings.ToLower(filter)
+	filtered := []map[string]interface{}{}
+	for _, process := range processes {
+		if strings.Contains(strings.ToLower(process["command"].(string)), filter) {
+			filtered = append(filtered, process)
+		}
+	}
+	return filtered
+}
+
+// sortProcesses orders processes by descending cpu or mem usage
+func sortProcesses(processes []map[string]interface{}, sortBy string) {
+	sort.SliceStable(processes, func(i, j int) bool {
+		return processes[i][sortBy].(float64) > processes[j][sortBy].(float64)
+	})
+}
+
 func (a *PsAgent) HealthCheck() error {
 	return nil
 }
@@ -52,5 +168,10 @@ func (a *PsAgent) Shutdown() error {
 	return nil
 }
 
+// Classification declares that the agent only lists processes, which change between calls
+func (a *PsAgent) Classification() interfaces.Classification {
+	return interfaces.Classification{ReadOnly: true}
+}
+
 // Export the agent for plugin loading
 var Agent interfaces.Agent = NewPsAgent()
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+)
+
+const samplePs = `USER         PID %CPU %MEM    VSZ   RSS TTY      STAT START   TIME COMMAND
+root           1  0.0  0.1 167744 11904 ?        Ss   Oct16   0:03 /sbin/init splash
+postgres     812  2.5 12.4 1024000 512000 ?      Ssl  Oct16  12:01 postgres: writer process
+dev         4242 35.1  3.2 2048000 131072 pts/0  Rl+  09:15   1:42 go test ./... -run TestPs
+`
+
+func TestParsePsOutput(t *testing.T) {
+	processes := parsePsOutput(samplePs)
+	if len(processes) != 3 {
+		t.Fatalf("Expected 3 processes, got %d", len(processes))
+	}
+
+	first := processes[0]
+	if first["user"] != "root" || first["pid"] != 1 || first["stat"] != "Ss" || first["tty"] != "?" {
+		t.Errorf("Unexpected first record: %v", first)
+	}
+	if first["command"] != "/sbin/init splash" {
+		t.Errorf("Expected command with arguments preserved, got %q", first["command"])
+	}
+	if processes[1]["mem"] != 12.4 || processes[1]["rss"] != int64(512000) {
+		t.Errorf("Unexpected numeric fields: %v", processes[1])
+	}
+}
+
+func TestFilterAndSortProcesses(t *testing.T) {
+	processes := parsePsOutput(samplePs)
+
+	sortProcesses(processes, "cpu")
+	if processes[0]["pid"] != 4242 {
+		t.Errorf("Expected highest CPU first, got %v", processes[0])
+	}
+
+	sortProcesses(processes, "mem")
+	if processes[0]["pid"] != 812 {
+		t.Errorf("Expected highest memory first, got %v", processes[0])
+	}
+
+	filtered := filterProcesses(processes, "POSTGRES")
+	if len(filtered) != 1 || filtered[0]["pid"] != 812 {
+		t.Errorf("Expected case-insensitive command filter, got %v", filtered)
+	}
+}
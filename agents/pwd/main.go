@@ -4,7 +4,8 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"os/exec
+	"os/exec"
+	"strings"
 
 	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
 )
@@ -32,14 +33,16 @@ func (a *PwdAgent) Process(ctx context.Context, input interfaces.AgentInput) (in
 	output, err := cmd.Output()
 	if err != nil {
 		return interfaces.AgentOutput{
-			Text:     fmt.Sprintf("Error executing pwd: %v", err),
-			Finished: true,
-		}, err
+			Success: false,
+			Error:   fmt.Sprintf("Error executing pwd: %v", err),
+		}, nil
 	}
 
 	return interfaces.AgentOutput{
-		Text:     string(output),
-		Finished: true,
+		Success: true,
+		Data: map[string]interface{}{
+			"output": strings.TrimSpace(string(output)),
+		},
 	}, nil
 }
 
@@ -52,5 +55,10 @@ func (a *PwdAgent) Shutdown() error {
 	return nil
 }
 
+// Classification declares that the agent only reports the working directory
+func (a *PwdAgent) Classification() interfaces.Classification {
+	return interfaces.Classification{ReadOnly: true, Idempotent: true}
+}
+
 // Export the agent for plugin loading
 var Agent interfaces.Agent = NewPwdAgent()
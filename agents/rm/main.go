@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 
 	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/pathutil"
 )
 
 type RmAgent struct {
@@ -52,6 +53,9 @@ func (a *RmAgent) Process(ctx context.Context, input interfaces.AgentInput) (int
 		}, nil
 	}
 
+	// Resolve the path while it still exists
+	resolvedPath := pathutil.Resolve(path)
+
 	// Determine if it's a directory or file
 	isDir := fileInfo.IsDir()
 	var removedItems []string
@@ -85,6 +89,7 @@ func (a *RmAgent) Process(ctx context.Context, input interfaces.AgentInput) (int
 		Success: true,
 		Data: map[string]interface{}{
 			"path":          path,
+			"resolved_path": resolvedPath,
 			"absolute_path": absPath,
 			"type":          map[bool]string{true: "directory", false: "file"}[isDir],
 			"removed":       removedItems,
@@ -102,5 +107,10 @@ func (a *RmAgent) Shutdown() error {
 	return nil
 }
 
+// Classification declares that the agent deletes files
+func (a *RmAgent) Classification() interfaces.Classification {
+	return interfaces.Classification{Destructive: true}
+}
+
 // Export the agent for plugin loading
 var Agent interfaces.Agent = NewRmAgent()
@@ -4,9 +4,13 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"os
+	"os"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/pathutil"
 )
 
 type StatAgent struct {
@@ -27,36 +31,98 @@ func (a *StatAgent) Initialize(config map[string]interface{}) error {
 }
 
 func (a *StatAgent) Process(ctx context.Context, input interfaces.AgentInput) (interfaces.AgentOutput, error) {
-	// Extract path from input
-	path, ok := input["path"].(string)
-	if !ok || path == "" {
-		return interfaces.AgentOutput{
-			Text:     "Error: path parameter is required",
-			Finished: true,
-		}, fmt.Errorf("path parameter is required")
+	// Accept a single path or a paths array
+	var paths []string
+	if path, ok := input.Payload["path"].(string); ok && path != "" {
+		paths = append(paths, path)
+	}
+	if list, ok := input.Payload["paths"].([]interface{}); ok {
+		for _, item := range list {
+			if path, ok := item.(string); ok && path != "" {
+				paths = append(paths, path)
+			}
+		}
 	}
 
-	// Get file stats
-	stat, err := os.Stat(path)
-	if err != nil {
+	if len(paths) == 0 {
 		return interfaces.AgentOutput{
-			Text:     fmt.Sprintf("Error getting stats for %s: %v", path, err),
-			Finished: true,
-		}, err
+			Success: false,
+			Error:   "Error: path or paths parameter is required",
+		}, nil
 	}
 
-	output := fmt.Sprintf("File stats for %s:\n", path)
-	output += fmt.Sprintf("  Size: %d bytes\n", stat.Size())
-	output += fmt.Sprintf("  Mode: %s\n", stat.Mode())
-	output += fmt.Sprintf("  ModTime: %s\n", stat.ModTime())
-	output += fmt.Sprintf("  IsDir: %t\n", stat.IsDir())
+	files := make([]map[string]interface{}, 0, len(paths))
+	var formatted []string
+	failed := 0
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			failed++
+			files = append(files, map[string]interface{}{
+				"path":          path,
+				"resolved_path": pathutil.Resolve(path),
+				"error":         err.Error(),
+			})
+			formatted = append(formatted, fmt.Sprintf("Error getting stats for %s: %v\n", path, err))
+			continue
+		}
+
+		files = append(files, statFields(path, info))
+		formatted = append(formatted, formatStat(path, info))
+	}
+
+	if failed == len(paths) {
+		return interfaces.AgentOutput{
+			Success: false,
+			Error:   strings.TrimSpace(strings.Join(formatted, "")),
+		}, nil
+	}
 
 	return interfaces.AgentOutput{
-		Text:     output,
-		Finished: true,
+		Success: true,
+		Data: map[string]interface{}{
+			"files":     files,
+			"count":     len(files),
+			"errors":    failed,
+			"formatted": strings.Join(formatted, "\n"),
+		},
 	}, nil
 }
 
+// statFields converts file info into structured fields, adding ownership and
+// inode details when the platform provides them
+func statFields(path string, info os.FileInfo) map[string]interface{} {
+	fields := map[string]interface{}{
+		"path":          path,
+		"resolved_path": pathutil.Resolve(path),
+		"name":          info.Name(),
+		"size":          info.Size(),
+		"mode":          info.Mode().String(),
+		"perm":          fmt.Sprintf("%04o", info.Mode().Perm()),
+		"mod_time":      info.ModTime().Format(time.RFC3339),
+		"is_dir":        info.IsDir(),
+	}
+
+	if sys, ok := info.Sys().(*syscall.Stat_t); ok {
+		fields["uid"] = sys.Uid
+		fields["gid"] = sys.Gid
+		fields["inode"] = sys.Ino
+		fields["nlink"] = uint64(sys.Nlink)
+	}
+
+	return fields
+}
+
+func formatStat(path string, info os.FileInfo) string {
+	output := fmt.Sprintf("File stats for %s:\n", path)
+	output += fmt.Sprintf("  Size: %d bytes\n", info.Size())
+	output += fmt.Sprintf("  Mode: %s\n", info.Mode())
+	output += fmt.Sprintf("  ModTime: %s\n", info.ModTime())
+	output += fmt.Sprintf("  IsDir: %t\n", info.IsDir())
+	return output
+}
+
 func (a *StatAgent) HealthCheck() error {
 	return nil
 }
@@ -66,5 +132,10 @@ func (a *StatAgent) Shutdown() error {
 	return nil
 }
 
+// Classification declares that the agent only reads file metadata
+func (a *StatAgent) Classification() interfaces.Classification {
+	return interfaces.Classification{ReadOnly: true, Idempotent: true}
+}
+
 // Export the agent for plugin loading
 var Agent interfaces.Agent = NewStatAgent()
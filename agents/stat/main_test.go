@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+func TestStatAgent_MultiplePaths(t *testing.T) {
+	agent := NewStatAgent()
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file.txt")
+	if err := os.WriteFile(file, []byte("hello"), 0640); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	output, err := agent.Process(t.Context(), interfaces.AgentInput{
+		Type: "stat",
+		Payload: map[string]interface{}{
+			"paths": []interface{}{file, dir, filepath.Join(dir, "missing")},
+		},
+	})
+	if err != nil || !output.Success {
+		t.Fatalf("Stat failed: err=%v, output=%s", err, output.Error)
+	}
+
+	files := output.Data["files"].([]map[string]interface{})
+	if len(files) != 3 || output.Data["errors"] != 1 {
+		t.Fatalf("Expected 3 entries with 1 error, got %v", output.Data)
+	}
+
+	if files[0]["size"] != int64(5) || files[0]["is_dir"] != false || files[0]["perm"] != "0640" {
+		t.Errorf("Unexpected file entry: %v", files[0])
+	}
+	if _, ok := files[0]["inode"]; !ok {
+		t.Error("Expected inode on Unix")
+	}
+	if resolved := files[0]["resolved_path"].(string); !filepath.IsAbs(resolved) || filepath.Clean(resolved) != resolved {
+		t.Errorf("Expected an absolute, cleaned resolved_path, got %q", resolved)
+	}
+	if files[1]["is_dir"] != true {
+		t.Errorf("Expected directory entry, got %v", files[1])
+	}
+	if _, ok := files[2]["error"]; !ok {
+		t.Errorf("Expected error for missing path, got %v", files[2])
+	}
+	if output.Data["formatted"] == "" {
+		t.Error("Expected formatted summary")
+	}
+}
+
+func TestStatAgent_RequiresPath(t *testing.T) {
+	output, _ := NewStatAgent().Process(t.Context(), interfaces.AgentInput{Type: "stat", Payload: map[string]interface{}{}})
+	if output.Success {
+		t.Error("Expected failure without a path")
+	}
+}
@@ -0,0 +1,603 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+// waitDelay bounds how long a cancelled task waits for output from
+// processes that escaped its process group
+const waitDelay = 2 * time.Second
+
+// Task states
+const (
+	TaskStatusRunning   = "running"
+	TaskStatusCompleted = "completed"
+	TaskStatusFailed    = "failed"
+	TaskStatusCancelled = "cancelled"
+)
+
+type TaskAgent struct {
+	name            string
+	defaultTimeout  time.Duration
+	allowedCommands []string
+	blockedCommands []string
+	// maxTasks limits how many tasks may run at once; 0 means no limit
+	maxTasks int
+	// retention is how long finished tasks are kept for status calls; 0
+	// keeps them until purged
+	retention time.Duration
+
+	// mu guards activeTasks, nextID, publish, stopPruning, and the mutable
+	// fields of every task, which executeTask updates while status and list
+	// calls read them
+	mu          sync.RWMutex
+	activeTasks map[string]*taskInfo
+	nextID      int
+	// publish receives a task_complete event when each task finishes
+	publish interfaces.EventFunc
+	// stopPruning stops the goroutine that removes expired tasks
+	stopPruning chan struct{}
+}
+
+// taskInfo tracks one command started by the agent. id, command, args,
+// startedAt, and cancel never change once the task is registered; the other
+// fields are guarded by TaskAgent.mu.
+type taskInfo struct {
+	id        string
+	command   string
+	args      []string
+	status    string
+	startedAt time.Time
+	endedAt   time.Time
+	cancel    context.CancelFunc
+
+	// Set when the task finishes
+	stdout   string
+	stderr   string
+	exitCode int
+}
+
+func NewTaskAgent() *TaskAgent {
+	return &TaskAgent{
+		name:           "task-agent",
+		defaultTimeout: 60 * time.Second,
+		activeTasks:    make(map[string]*taskInfo),
+	}
+}
+
+func (a *TaskAgent) Name() string {
+	return a.name
+}
+
+func (a *TaskAgent) Initialize(config map[string]interface{}) error {
+	if timeout, ok := getInt(config, "timeout"); ok && timeout > 0 {
+		a.defaultTimeout = time.Duration(timeout) * time.Second
+	}
+
+	a.allowedCommands = getStrings(config, "allowed_commands")
+	a.blockedCommands = getStrings(config, "blocked_commands")
+
+	if maxTasks, ok := getInt(config, "max_concurrent_tasks"); ok && maxTasks >= 0 {
+		a.maxTasks = maxTasks
+	}
+
+	retention, err := getDuration(config, "task_retention")
+	if err != nil {
+		return fmt.Errorf("invalid task_retention: %w", err)
+	}
+	a.retention = retention
+	a.startPruning()
+
+	log.Printf("Initializing %s agent: timeout=%v, max_concurrent_tasks=%d, task_retention=%v, allowed_commands=%d, blocked_commands=%d",
+		a.name, a.defaultTimeout, a.maxTasks, a.retention, len(a.allowedCommands), len(a.blockedCommands))
+	return nil
+}
+
+func (a *TaskAgent) Process(ctx context.Context, input interfaces.AgentInput) (interfaces.AgentOutput, error) {
+	switch input.Type {
+	case "execute", "":
+		return a.executeTask(ctx, input)
+	case "status":
+		return a.taskStatus(input)
+	case "list":
+		return a.listTasks()
+	case "cancel":
+		return a.cancelTask(input)
+	case "purge":
+		return a.purgeTasks()
+	default:
+		return interfaces.AgentOutput{
+			Success: false,
+			Error:   fmt.Sprintf("unknown operation: %s", input.Type),
+		}, nil
+	}
+}
+
+// executeTask runs a command from the payload and waits for it to finish
+func (a *TaskAgent) executeTask(ctx context.Context, input interfaces.AgentInput) (interfaces.AgentOutput, error) {
+	command, args, err := commandArgv(input.Payload)
+	if err != nil {
+		return interfaces.AgentOutput{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	if err := a.checkCommand(command); err != nil {
+		return interfaces.AgentOutput{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	timeout := a.defaultTimeout
+	if seconds, ok := getInt(input.Payload, "timeout"); ok && seconds > 0 {
+		timeout = time.Duration(seconds) * time.Second
+	}
+
+	taskCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	task, err := a.startTask(command, args, cancel)
+	if err != nil {
+		return interfaces.AgentOutput{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	cmd := exec.CommandContext(taskCtx, command, args...)
+
+	// Run the command in its own process group and kill the whole group on
+	// cancel or timeout, so children of a shell do not keep running
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = waitDelay
+
+	if workingDir, ok := input.Payload["working_dir"].(string); ok && workingDir != "" {
+		cmd.Dir = workingDir
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err = cmd.Run()
+
+	status := TaskStatusCompleted
+	switch {
+	case taskCtx.Err() == context.Canceled:
+		status = TaskStatusCancelled
+	case err != nil:
+		status = TaskStatusFailed
+	}
+	code := exitCode(err)
+	duration := a.finishTask(task, status, stdout.String(), stderr.String(), code)
+	a.publishCompletion(task, status, code, duration)
+
+	data := map[string]interface{}{
+		"task_id":   task.id,
+		"command":   command,
+		"args":      args,
+		"status":    status,
+		"stdout":    stdout.String(),
+		"stderr":    stderr.String(),
+		"exit_code": code,
+		"duration":  duration.String(),
+	}
+
+	if err != nil {
+		if taskCtx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("timed out after %v", timeout)
+		}
+		return interfaces.AgentOutput{
+			Success: false,
+			Data:    data,
+			Error:   fmt.Sprintf("task %s %s: %v", task.id, status, err),
+		}, nil
+	}
+
+	return interfaces.AgentOutput{
+		Success: true,
+		Data:    data,
+	}, nil
+}
+
+// commandArgv returns the program and arguments to run. A command with an
+// args list runs as given. Otherwise the command is a command line split into
+// words without a shell, unless the payload opts in with "shell": true, in
+// which case it runs with sh -c and the policy applies to sh.
+func commandArgv(payload map[string]interface{}) (string, []string, error) {
+	command, _ := payload["command"].(string)
+	if strings.TrimSpace(command) == "" {
+		return "", nil, fmt.Errorf("command not specified in payload")
+	}
+
+	if shell, _ := payload["shell"].(bool); shell {
+		return "sh", []string{"-c", command}, nil
+	}
+
+	if _, ok := payload["args"]; ok {
+		return command, getStrings(payload, "args"), nil
+	}
+
+	words, err := splitCommandLine(command)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid command line: %w", err)
+	}
+	if len(words) == 0 {
+		return "", nil, fmt.Errorf("command not specified in payload")
+	}
+	return words[0], words[1:], nil
+}
+
+// checkCommand applies the command policy. With allowed_commands set only
+// those commands may run, matched exactly so allowing "ls" does not allow
+// "/tmp/ls". Otherwise anything not in blocked_commands may run; blocked
+// entries also match the base name, so blocking "rm" blocks "/bin/rm".
+func (a *TaskAgent) checkCommand(command string) error {
+	if len(a.allowedCommands) > 0 {
+		if !commandListed(command, a.allowedCommands, false) {
+			return fmt.Errorf("command not permitted: %s", command)
+		}
+		return nil
+	}
+
+	if commandListed(command, a.blockedCommands, true) {
+		return fmt.Errorf("command not permitted: %s", command)
+	}
+	return nil
+}
+
+// commandListed reports whether command is in list, optionally also
+// matching its base name
+func commandListed(command string, list []string, matchBase bool) bool {
+	base := filepath.Base(command)
+	for _, entry := range list {
+		if entry == command || (matchBase && entry == base) {
+			return true
+		}
+	}
+	return false
+}
+
+// startTask registers a running task and assigns its ID, unless the limit
+// on running tasks has been reached. Finished tasks do not count.
+func (a *TaskAgent) startTask(command string, args []string, cancel context.CancelFunc) (*taskInfo, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.maxTasks > 0 {
+		running := 0
+		for _, task := range a.activeTasks {
+			if task.status == TaskStatusRunning {
+				running++
+			}
+		}
+		if running >= a.maxTasks {
+			return nil, fmt.Errorf("too many running tasks: limit is %d", a.maxTasks)
+		}
+	}
+
+	a.nextID++
+	task := &taskInfo{
+		id:        fmt.Sprintf("task-%d", a.nextID),
+		command:   command,
+		args:      args,
+		status:    TaskStatusRunning,
+		startedAt: time.Now(),
+		cancel:    cancel,
+	}
+	a.activeTasks[task.id] = task
+	return task, nil
+}
+
+// finishTask records the final status and output of a task and returns how
+// long it ran
+func (a *TaskAgent) finishTask(task *taskInfo, status, stdout, stderr string, exitCode int) time.Duration {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	task.status = status
+	task.stdout = stdout
+	task.stderr = stderr
+	task.exitCode = exitCode
+	task.endedAt = time.Now()
+	return task.endedAt.Sub(task.startedAt)
+}
+
+// SetEventFunc sets where task_complete events are published
+func (a *TaskAgent) SetEventFunc(publish interfaces.EventFunc) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.publish = publish
+}
+
+// publishCompletion announces that a task finished. The event function must
+// not block; the engine's queues the event for WebSocket clients.
+func (a *TaskAgent) publishCompletion(task *taskInfo, status string, exitCode int, duration time.Duration) {
+	a.mu.RLock()
+	publish := a.publish
+	a.mu.RUnlock()
+
+	if publish == nil {
+		return
+	}
+	publish(map[string]interface{}{
+		"type":      "task_complete",
+		"task_id":   task.id,
+		"command":   task.command,
+		"status":    status,
+		"exit_code": exitCode,
+		"duration":  duration.String(),
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// exitCode returns the exit status of a finished command: 0 on success, the
+// process's code when it exited non-zero, and -1 when it could not be started
+// or was killed by a signal
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+func (a *TaskAgent) taskStatus(input interfaces.AgentInput) (interfaces.AgentOutput, error) {
+	taskID, _ := input.Payload["task_id"].(string)
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	task, exists := a.activeTasks[taskID]
+	if !exists {
+		return interfaces.AgentOutput{
+			Success: false,
+			Error:   fmt.Sprintf("task not found: %s", taskID),
+		}, nil
+	}
+
+	return interfaces.AgentOutput{
+		Success: true,
+		Data:    task.record(),
+	}, nil
+}
+
+func (a *TaskAgent) listTasks() (interfaces.AgentOutput, error) {
+	a.mu.RLock()
+	tasks := make([]*taskInfo, 0, len(a.activeTasks))
+	for _, task := range a.activeTasks {
+		tasks = append(tasks, task)
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].startedAt.Before(tasks[j].startedAt)
+	})
+
+	records := make([]map[string]interface{}, 0, len(tasks))
+	for _, task := range tasks {
+		records = append(records, task.record())
+	}
+	a.mu.RUnlock()
+
+	return interfaces.AgentOutput{
+		Success: true,
+		Data: map[string]interface{}{
+			"tasks": records,
+			"count": len(records),
+		},
+	}, nil
+}
+
+func (a *TaskAgent) cancelTask(input interfaces.AgentInput) (interfaces.AgentOutput, error) {
+	taskID, _ := input.Payload["task_id"].(string)
+
+	a.mu.RLock()
+	task, exists := a.activeTasks[taskID]
+	running := exists && task.status == TaskStatusRunning
+	a.mu.RUnlock()
+
+	if !exists {
+		return interfaces.AgentOutput{
+			Success: false,
+			Error:   fmt.Sprintf("task not found: %s", taskID),
+		}, nil
+	}
+	if !running {
+		return interfaces.AgentOutput{
+			Success: false,
+			Error:   fmt.Sprintf("task %s is not running", taskID),
+		}, nil
+	}
+
+	task.cancel()
+
+	return interfaces.AgentOutput{
+		Success: true,
+		Data: map[string]interface{}{
+			"task_id":   taskID,
+			"cancelled": true,
+		},
+	}, nil
+}
+
+func (a *TaskAgent) purgeTasks() (interfaces.AgentOutput, error) {
+	purged := a.removeFinished(time.Time{})
+
+	return interfaces.AgentOutput{
+		Success: true,
+		Data: map[string]interface{}{
+			"purged": purged,
+		},
+	}, nil
+}
+
+// removeFinished drops tasks that are no longer running and, unless cutoff is
+// zero, ended before cutoff. It returns how many were removed.
+func (a *TaskAgent) removeFinished(cutoff time.Time) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	removed := 0
+	for id, task := range a.activeTasks {
+		if task.status == TaskStatusRunning {
+			continue
+		}
+		if !cutoff.IsZero() && !task.endedAt.Before(cutoff) {
+			continue
+		}
+		delete(a.activeTasks, id)
+		removed++
+	}
+	return removed
+}
+
+// startPruning starts removing tasks that finished more than the retention
+// period ago, replacing any pruning goroutine already running
+func (a *TaskAgent) startPruning() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.stopPruning != nil {
+		close(a.stopPruning)
+		a.stopPruning = nil
+	}
+	if a.retention <= 0 {
+		return
+	}
+
+	// Check often enough that tasks outlive the retention period by at most
+	// half of it
+	interval := a.retention / 2
+	if interval > time.Minute {
+		interval = time.Minute
+	}
+
+	stop := make(chan struct{})
+	a.stopPruning = stop
+	go func(retention time.Duration) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				a.removeFinished(time.Now().Add(-retention))
+			case <-stop:
+				return
+			}
+		}
+	}(a.retention)
+}
+
+// record describes a task for status and list output; the caller holds a.mu
+// for reading
+func (t *taskInfo) record() map[string]interface{} {
+	record := map[string]interface{}{
+		"task_id":    t.id,
+		"command":    t.command,
+		"args":       t.args,
+		"status":     t.status,
+		"started_at": t.startedAt.Format(time.RFC3339),
+	}
+	if !t.endedAt.IsZero() {
+		record["ended_at"] = t.endedAt.Format(time.RFC3339)
+		record["duration"] = t.endedAt.Sub(t.startedAt).String()
+		record["stdout"] = t.stdout
+		record["stderr"] = t.stderr
+		record["exit_code"] = t.exitCode
+	}
+	return record
+}
+
+// getInt reads a number that may arrive as an int or as a JSON float64
+func getInt(values map[string]interface{}, key string) (int, bool) {
+	switch v := values[key].(type) {
+	case int:
+		return v, true
+	case int64:
+		return int(v), true
+	case float64:
+		return int(v), true
+	}
+	return 0, false
+}
+
+// getDuration reads a duration given as a string such as "30m" or as a
+// number of seconds
+func getDuration(values map[string]interface{}, key string) (time.Duration, error) {
+	if s, ok := values[key].(string); ok {
+		return time.ParseDuration(s)
+	}
+	if seconds, ok := getInt(values, key); ok {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	return 0, nil
+}
+
+// getStrings reads a list of strings, skipping entries of other types
+func getStrings(values map[string]interface{}, key string) []string {
+	var result []string
+	switch v := values[key].(type) {
+	case []string:
+		result = append(result, v...)
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+	}
+	return result
+}
+
+func (a *TaskAgent) HealthCheck() error {
+	return nil
+}
+
+func (a *TaskAgent) Shutdown() error {
+	log.Printf("Shutting down %s agent", a.name)
+
+	// Stop pruning and anything still running
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.stopPruning != nil {
+		close(a.stopPruning)
+		a.stopPruning = nil
+	}
+	for _, task := range a.activeTasks {
+		if task.status == TaskStatusRunning {
+			task.cancel()
+		}
+	}
+	return nil
+}
+
+// Classification declares that the agent runs arbitrary commands
+func (a *TaskAgent) Classification() interfaces.Classification {
+	return interfaces.Classification{Destructive: true}
+}
+
+// Export the agent for plugin loading
+var Agent interfaces.Agent = NewTaskAgent()
+
+// TaskAgent publishes task_complete events
+var _ interfaces.EventAware = (*TaskAgent)(nil)
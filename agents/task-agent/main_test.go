@@ -0,0 +1,435 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+func newTestAgent(t *testing.T, config map[string]interface{}) *TaskAgent {
+	t.Helper()
+	agent := NewTaskAgent()
+	if err := agent.Initialize(config); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	return agent
+}
+
+func execute(t *testing.T, agent *TaskAgent, command string, args ...interface{}) interfaces.AgentOutput {
+	t.Helper()
+	output, err := agent.Process(context.Background(), interfaces.AgentInput{
+		Type:    "execute",
+		Payload: map[string]interface{}{"command": command, "args": args},
+	})
+	if err != nil {
+		t.Fatalf("Process returned error: %v", err)
+	}
+	return output
+}
+
+func TestTaskAgent_EmptyPolicyIsPermissive(t *testing.T) {
+	agent := newTestAgent(t, map[string]interface{}{})
+
+	output := execute(t, agent, "echo", "hello")
+	if !output.Success {
+		t.Fatalf("Expected command to run, got: %s", output.Error)
+	}
+	if strings.TrimSpace(output.Data["stdout"].(string)) != "hello" {
+		t.Errorf("Unexpected output: %q", output.Data["stdout"])
+	}
+}
+
+func TestTaskAgent_AllowedCommands(t *testing.T) {
+	agent := newTestAgent(t, map[string]interface{}{
+		"allowed_commands": []interface{}{"echo"},
+		// The allowlist takes precedence over the blocklist
+		"blocked_commands": []interface{}{"echo"},
+	})
+
+	if output := execute(t, agent, "echo", "ok"); !output.Success {
+		t.Errorf("Expected allowed command to run, got: %s", output.Error)
+	}
+
+	for _, command := range []string{"true", "/bin/echo", "./echo", "sh"} {
+		output := execute(t, agent, command)
+		if output.Success || !strings.Contains(output.Error, "command not permitted") {
+			t.Errorf("Expected %s to be rejected, got success=%v error=%q", command, output.Success, output.Error)
+		}
+	}
+}
+
+func TestTaskAgent_BlockedCommands(t *testing.T) {
+	agent := newTestAgent(t, map[string]interface{}{
+		"blocked_commands": []interface{}{"rm", "sh"},
+	})
+
+	if output := execute(t, agent, "echo", "ok"); !output.Success {
+		t.Errorf("Expected unblocked command to run, got: %s", output.Error)
+	}
+
+	for _, command := range []string{"rm", "/bin/rm", "sh"} {
+		output := execute(t, agent, command, "-c", "true")
+		if output.Success || !strings.Contains(output.Error, "command not permitted") {
+			t.Errorf("Expected %s to be rejected, got success=%v error=%q", command, output.Success, output.Error)
+		}
+	}
+}
+
+func TestTaskAgent_CommandLine(t *testing.T) {
+	agent := newTestAgent(t, map[string]interface{}{
+		"allowed_commands": []interface{}{"echo"},
+	})
+
+	run := func(payload map[string]interface{}) interfaces.AgentOutput {
+		t.Helper()
+		output, err := agent.Process(context.Background(), interfaces.AgentInput{Type: "execute", Payload: payload})
+		if err != nil {
+			t.Fatalf("Process returned error: %v", err)
+		}
+		return output
+	}
+
+	// A whole command line is split into argv without a shell
+	output := run(map[string]interface{}{"command": `echo "hello   world" 'a|b'`})
+	if !output.Success || output.Data["stdout"] != "hello   world a|b\n" {
+		t.Errorf("Expected quoted args to be preserved, got success=%v data=%v error=%q", output.Success, output.Data, output.Error)
+	}
+	if output.Data["command"] != "echo" {
+		t.Errorf("Expected argv[0] to be echo, got %v", output.Data["command"])
+	}
+
+	// The allowlist checks argv[0]
+	output = run(map[string]interface{}{"command": "cat /etc/passwd"})
+	if output.Success || !strings.Contains(output.Error, "command not permitted: cat") {
+		t.Errorf("Expected cat to be rejected, got success=%v error=%q", output.Success, output.Error)
+	}
+
+	output = run(map[string]interface{}{"command": "echo hi; cat /etc/passwd"})
+	if output.Success || !strings.Contains(output.Error, "metacharacter") {
+		t.Errorf("Expected metacharacters to be rejected, got success=%v error=%q", output.Success, output.Error)
+	}
+
+	// Shell mode runs through sh, which the policy must allow
+	output = run(map[string]interface{}{"command": "echo hi | tr a-z A-Z", "shell": true})
+	if output.Success || !strings.Contains(output.Error, "command not permitted: sh") {
+		t.Errorf("Expected shell mode to need sh allowed, got success=%v error=%q", output.Success, output.Error)
+	}
+
+	agent = newTestAgent(t, map[string]interface{}{"allowed_commands": []interface{}{"sh"}})
+	output = run(map[string]interface{}{"command": "echo hi | tr a-z A-Z", "shell": true})
+	if !output.Success || output.Data["stdout"] != "HI\n" {
+		t.Errorf("Expected shell pipeline to run, got success=%v data=%v error=%q", output.Success, output.Data, output.Error)
+	}
+}
+
+func TestTaskAgent_CompletionEvents(t *testing.T) {
+	agent := newTestAgent(t, map[string]interface{}{})
+
+	// Without an event function tasks still run
+	if output := execute(t, agent, "true"); !output.Success {
+		t.Fatalf("Expected command to run, got: %s", output.Error)
+	}
+
+	events := make(chan map[string]interface{}, 2)
+	agent.SetEventFunc(func(event map[string]interface{}) { events <- event })
+
+	ok := execute(t, agent, "true")
+	failed := execute(t, agent, "sh", "-c", "exit 2")
+
+	for _, expected := range []struct {
+		taskID   interface{}
+		status   string
+		exitCode int
+	}{
+		{ok.Data["task_id"], TaskStatusCompleted, 0},
+		{failed.Data["task_id"], TaskStatusFailed, 2},
+	} {
+		event := <-events
+		if event["type"] != "task_complete" || event["task_id"] != expected.taskID ||
+			event["status"] != expected.status || event["exit_code"] != expected.exitCode {
+			t.Errorf("Expected task_complete for %v with status %s, got %v", expected.taskID, expected.status, event)
+		}
+		if _, ok := event["duration"].(string); !ok {
+			t.Errorf("Expected a duration, got %v", event)
+		}
+	}
+}
+
+func TestTaskAgent_StatusAndList(t *testing.T) {
+	agent := newTestAgent(t, map[string]interface{}{})
+
+	output := execute(t, agent, "false")
+	if output.Success {
+		t.Fatal("Expected failing command to report failure")
+	}
+	taskID := output.Data["task_id"].(string)
+
+	status, _ := agent.Process(context.Background(), interfaces.AgentInput{
+		Type:    "status",
+		Payload: map[string]interface{}{"task_id": taskID},
+	})
+	if !status.Success || status.Data["status"] != TaskStatusFailed {
+		t.Errorf("Expected failed status, got %v", status.Data)
+	}
+
+	list, _ := agent.Process(context.Background(), interfaces.AgentInput{Type: "list"})
+	if list.Data["count"] != 1 {
+		t.Errorf("Expected one task, got %v", list.Data["count"])
+	}
+}
+
+func TestTaskAgent_SeparateStreamsAndExitCode(t *testing.T) {
+	agent := newTestAgent(t, map[string]interface{}{})
+
+	// Warnings on stderr do not make a command fail
+	output := execute(t, agent, "sh", "-c", "echo result; echo warning >&2")
+	if !output.Success {
+		t.Fatalf("Expected command to succeed, got: %s", output.Error)
+	}
+	if output.Data["stdout"] != "result\n" || output.Data["stderr"] != "warning\n" || output.Data["exit_code"] != 0 {
+		t.Errorf("Expected separate streams and exit code 0, got %v", output.Data)
+	}
+
+	output = execute(t, agent, "sh", "-c", "echo partial; echo broken >&2; exit 3")
+	if output.Success {
+		t.Fatal("Expected non-zero exit to report failure")
+	}
+	if output.Data["exit_code"] != 3 || output.Data["stderr"] != "broken\n" {
+		t.Errorf("Expected exit code 3 with stderr, got %v", output.Data)
+	}
+
+	status, _ := agent.Process(context.Background(), interfaces.AgentInput{
+		Type:    "status",
+		Payload: map[string]interface{}{"task_id": output.Data["task_id"]},
+	})
+	if status.Data["stdout"] != "partial\n" || status.Data["stderr"] != "broken\n" || status.Data["exit_code"] != 3 {
+		t.Errorf("Expected status to include streams and exit code, got %v", status.Data)
+	}
+
+	// A command that cannot be started has no exit status
+	output = execute(t, agent, "/nonexistent/command")
+	if output.Success || output.Data["exit_code"] != -1 {
+		t.Errorf("Expected exit code -1 for a missing command, got %v", output.Data)
+	}
+}
+
+// Run with -race: tasks finish while other goroutines read their status
+func TestTaskAgent_ConcurrentTasksAndStatusReads(t *testing.T) {
+	agent := newTestAgent(t, map[string]interface{}{})
+	const taskCount = 8
+
+	var wg sync.WaitGroup
+	errs := make(chan error, taskCount)
+	for i := 0; i < taskCount; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			output := execute(t, agent, "sh", "-c", fmt.Sprintf("sleep 0.05; echo %d", i))
+			if !output.Success || output.Data["stdout"] != fmt.Sprintf("%d\n", i) {
+				errs <- fmt.Errorf("task %d: success=%v data=%v", i, output.Success, output.Data)
+			}
+		}(i)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	// Poll list and status until every task has finished
+	for polling := true; polling; {
+		select {
+		case <-done:
+			polling = false
+		default:
+		}
+
+		list, _ := agent.Process(context.Background(), interfaces.AgentInput{Type: "list"})
+		for _, record := range list.Data["tasks"].([]map[string]interface{}) {
+			agent.Process(context.Background(), interfaces.AgentInput{
+				Type:    "status",
+				Payload: map[string]interface{}{"task_id": record["task_id"]},
+			})
+		}
+	}
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+
+	list, _ := agent.Process(context.Background(), interfaces.AgentInput{Type: "list"})
+	if list.Data["count"] != taskCount {
+		t.Fatalf("Expected %d tasks, got %v", taskCount, list.Data["count"])
+	}
+	seen := map[string]bool{}
+	for _, record := range list.Data["tasks"].([]map[string]interface{}) {
+		if record["status"] != TaskStatusCompleted {
+			t.Errorf("Expected %v to be completed, got %v", record["task_id"], record["status"])
+		}
+		seen[record["task_id"].(string)] = true
+	}
+	if len(seen) != taskCount {
+		t.Errorf("Expected unique task IDs, got %v", seen)
+	}
+}
+
+// startRunning executes a command in the background and returns its task ID
+// once the task is running, with a channel that receives the final output
+func startRunning(t *testing.T, agent *TaskAgent, command string, args ...interface{}) (string, <-chan interfaces.AgentOutput) {
+	t.Helper()
+	result := make(chan interfaces.AgentOutput, 1)
+	go func() {
+		output, _ := agent.Process(context.Background(), interfaces.AgentInput{
+			Type:    "execute",
+			Payload: map[string]interface{}{"command": command, "args": args},
+		})
+		result <- output
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		list, _ := agent.Process(context.Background(), interfaces.AgentInput{Type: "list"})
+		for _, record := range list.Data["tasks"].([]map[string]interface{}) {
+			if record["status"] == TaskStatusRunning && record["command"] == command {
+				// Give the process time to start before it is cancelled
+				time.Sleep(50 * time.Millisecond)
+				return record["task_id"].(string), result
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("Task %s did not start", command)
+	return "", nil
+}
+
+// waitCancelled asserts a task reports cancellation within a second
+func waitCancelled(t *testing.T, result <-chan interfaces.AgentOutput) {
+	t.Helper()
+	select {
+	case output := <-result:
+		if output.Success || output.Data["status"] != TaskStatusCancelled {
+			t.Errorf("Expected a cancelled task, got success=%v data=%v", output.Success, output.Data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Process was still running a second after cancel")
+	}
+}
+
+func TestTaskAgent_CancelKillsProcess(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		command string
+		args    []interface{}
+	}{
+		{"sleep", "sleep", []interface{}{"30"}},
+		// The shell's child must be killed too, or it holds the output open
+		{"shell child", "sh", []interface{}{"-c", "sleep 30; echo done"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			agent := newTestAgent(t, map[string]interface{}{})
+			taskID, result := startRunning(t, agent, test.command, test.args...)
+
+			output, _ := agent.Process(context.Background(), interfaces.AgentInput{
+				Type:    "cancel",
+				Payload: map[string]interface{}{"task_id": taskID},
+			})
+			if !output.Success {
+				t.Fatalf("Cancel failed: %s", output.Error)
+			}
+			waitCancelled(t, result)
+		})
+	}
+}
+
+func TestTaskAgent_ShutdownKillsRunningTasks(t *testing.T) {
+	agent := newTestAgent(t, map[string]interface{}{})
+	_, result := startRunning(t, agent, "sleep", "30")
+
+	if err := agent.Shutdown(); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	waitCancelled(t, result)
+}
+
+func listCount(t *testing.T, agent *TaskAgent) int {
+	t.Helper()
+	list, _ := agent.Process(context.Background(), interfaces.AgentInput{Type: "list"})
+	return list.Data["count"].(int)
+}
+
+func TestTaskAgent_LimitCountsOnlyRunningTasks(t *testing.T) {
+	agent := newTestAgent(t, map[string]interface{}{"max_concurrent_tasks": 1})
+	defer agent.Shutdown()
+
+	// Finished tasks stay listed but do not use up the limit
+	for i := 0; i < 3; i++ {
+		if output := execute(t, agent, "true"); !output.Success {
+			t.Fatalf("Expected task %d to run, got: %s", i, output.Error)
+		}
+	}
+
+	_, result := startRunning(t, agent, "sleep", "30")
+	output := execute(t, agent, "true")
+	if output.Success || !strings.Contains(output.Error, "too many running tasks") {
+		t.Errorf("Expected the limit to reject a second running task, got success=%v error=%q", output.Success, output.Error)
+	}
+
+	agent.Shutdown()
+	waitCancelled(t, result)
+	if output := execute(t, agent, "true"); !output.Success {
+		t.Errorf("Expected a task to run once the running one ended, got: %s", output.Error)
+	}
+}
+
+func TestTaskAgent_Purge(t *testing.T) {
+	agent := newTestAgent(t, map[string]interface{}{})
+	execute(t, agent, "true")
+	execute(t, agent, "false")
+	runningID, result := startRunning(t, agent, "sleep", "30")
+
+	output, _ := agent.Process(context.Background(), interfaces.AgentInput{Type: "purge"})
+	if !output.Success || output.Data["purged"] != 2 {
+		t.Fatalf("Expected 2 finished tasks purged, got success=%v data=%v", output.Success, output.Data)
+	}
+
+	// The running task is kept
+	status, _ := agent.Process(context.Background(), interfaces.AgentInput{
+		Type:    "status",
+		Payload: map[string]interface{}{"task_id": runningID},
+	})
+	if !status.Success || listCount(t, agent) != 1 {
+		t.Errorf("Expected only the running task to remain, got %d tasks", listCount(t, agent))
+	}
+
+	agent.Shutdown()
+	waitCancelled(t, result)
+}
+
+func TestTaskAgent_RetentionPrunesFinishedTasks(t *testing.T) {
+	agent := newTestAgent(t, map[string]interface{}{"task_retention": "100ms"})
+	defer agent.Shutdown()
+
+	execute(t, agent, "true")
+	if listCount(t, agent) != 1 {
+		t.Fatal("Expected the finished task to be listed before it expires")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for listCount(t, agent) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Finished task was not pruned after the retention period")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := NewTaskAgent().Initialize(map[string]interface{}{"task_retention": "soon"}); err == nil {
+		t.Error("Expected an invalid task_retention to be rejected")
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// shellMetacharacters chain, redirect, or substitute commands in a shell.
+// Outside single quotes they are rejected so a command line cannot do more
+// than run one program.
+const shellMetacharacters = "|&;<>()$`\n"
+
+// splitCommandLine splits a command line into words the way a POSIX shell
+// would, without running one. Single quotes keep everything literally,
+// double quotes allow \" \\ \$ and \` escapes, and a backslash outside quotes
+// escapes the next character. Unquoted shell metacharacters, and $ or ` in
+// double quotes, are an error.
+func splitCommandLine(line string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		switch {
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+
+		case r == '\'':
+			end := indexRune(runes, i+1, '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated single quote at position %d", i)
+			}
+			word.WriteString(string(runes[i+1 : end]))
+			inWord = true
+			i = end
+
+		case r == '"':
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				c := runes[i]
+				if c == '\\' && i+1 < len(runes) && strings.ContainsRune("\"\\$`", runes[i+1]) {
+					i++
+					c = runes[i]
+				} else if c == '$' || c == '`' {
+					// Substitutions still happen inside double quotes
+					return nil, metacharacterError(c, i)
+				}
+				word.WriteRune(c)
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated double quote")
+			}
+			inWord = true
+
+		case r == '\\':
+			if i+1 >= len(runes) {
+				return nil, fmt.Errorf("trailing backslash")
+			}
+			i++
+			word.WriteRune(runes[i])
+			inWord = true
+
+		case strings.ContainsRune(shellMetacharacters, r):
+			return nil, metacharacterError(r, i)
+
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+func metacharacterError(r rune, position int) error {
+	return fmt.Errorf("shell metacharacter %q at position %d is not allowed; set \"shell\": true to run the command through a shell", r, position)
+}
+
+// indexRune returns the index of the first r in runes at or after start, or
+// -1 if there is none
+func indexRune(runes []rune, start int, r rune) int {
+	for i := start; i < len(runes); i++ {
+		if runes[i] == r {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitCommandLine(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected []string
+	}{
+		{"ls -la /tmp", []string{"ls", "-la", "/tmp"}},
+		{"  echo   spaced\targs  ", []string{"echo", "spaced", "args"}},
+		{`grep "hello world" notes.txt`, []string{"grep", "hello world", "notes.txt"}},
+		{`echo 'single $HOME; | quoted'`, []string{"echo", "single $HOME; | quoted"}},
+		{`echo "say \"hi\" \$5 C:\path"`, []string{"echo", `say "hi" $5 C:\path`}},
+		{`echo "a | b; c > d"`, []string{"echo", "a | b; c > d"}},
+		{`touch my\ file`, []string{"touch", "my file"}},
+		{`cat file\;name`, []string{"cat", "file;name"}},
+		{`echo pre"mid"'end'`, []string{"echo", "premidend"}},
+		{`printf '' x`, []string{"printf", "", "x"}},
+		{"", nil},
+	}
+
+	for _, test := range tests {
+		words, err := splitCommandLine(test.line)
+		if err != nil {
+			t.Errorf("splitCommandLine(%q) failed: %v", test.line, err)
+			continue
+		}
+		if !reflect.DeepEqual(words, test.expected) {
+			t.Errorf("splitCommandLine(%q) = %q, want %q", test.line, words, test.expected)
+		}
+	}
+}
+
+func TestSplitCommandLine_RejectsMetacharacters(t *testing.T) {
+	for _, line := range []string{
+		"ls | grep x",
+		"ls; rm -rf /",
+		"ls && rm x",
+		"cat < /etc/passwd",
+		"echo hi > out",
+		"echo $(whoami)",
+		"echo `whoami`",
+		"echo $HOME",
+		`echo "$HOME"`,
+		"sleep 5 &",
+		"(ls)",
+		"ls\nrm x",
+	} {
+		if words, err := splitCommandLine(line); err == nil || !strings.Contains(err.Error(), "metacharacter") {
+			t.Errorf("splitCommandLine(%q): expected a metacharacter error, got %q, %v", line, words, err)
+		}
+	}
+
+	for _, line := range []string{`echo "unterminated`, "echo 'unterminated", `echo trailing\`} {
+		if _, err := splitCommandLine(line); err == nil {
+			t.Errorf("splitCommandLine(%q): expected an error", line)
+		}
+	}
+}
@@ -67,5 +67,10 @@ func (a *TodoAgent) Shutdown() error {
 	return nil
 }
 
+// Classification declares that the agent only structures the steps it is given
+func (a *TodoAgent) Classification() interfaces.Classification {
+	return interfaces.Classification{ReadOnly: true, Idempotent: true}
+}
+
 // Export the agent for plugin loading
 var Agent interfaces.Agent = NewTodoAgent()
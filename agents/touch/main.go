@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/pathutil"
 )
 
 type TouchAgent struct {
@@ -37,12 +38,58 @@ func (a *TouchAgent) Process(ctx context.Context, input interfaces.AgentInput) (
 		}, nil
 	}
 
-	// Create empty file or update timestamp
-	err := os.WriteFile(file, []byte{}, 0644)
-	if err != nil {
+	// Use the requested timestamp if provided, otherwise the current time
+	timestamp := time.Now()
+	if timeStr, ok := input.Payload["time"].(string); ok && timeStr != "" {
+		parsed, err := time.Parse(time.RFC3339, timeStr)
+		if err != nil {
+			return interfaces.AgentOutput{
+				Success: false,
+				Error:   fmt.Sprintf("Error: invalid time %q, expected RFC3339: %v", timeStr, err),
+			}, nil
+		}
+		timestamp = parsed
+	}
+
+	noCreate, _ := input.Payload["no_create"].(bool)
+
+	created := false
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		if noCreate {
+			return interfaces.AgentOutput{
+				Success: true,
+				Data: map[string]interface{}{
+					"file":          file,
+					"path":          file,
+					"resolved_path": pathutil.Resolve(file),
+					"created":       false,
+					"skipped":       true,
+				},
+			}, nil
+		}
+
+		// Create the file without truncating anything that appears concurrently
+		f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return interfaces.AgentOutput{
+				Success: false,
+				Error:   fmt.Sprintf("Error creating file %s: %v", file, err),
+			}, nil
+		}
+		f.Close()
+		created = true
+	} else if err != nil {
 		return interfaces.AgentOutput{
 			Success: false,
-			Error:   fmt.Sprintf("Error creating file %s: %v", file, err),
+			Error:   fmt.Sprintf("Error getting file info for %s: %v", file, err),
+		}, nil
+	}
+
+	// Update access and modification times
+	if err := os.Chtimes(file, timestamp, timestamp); err != nil {
+		return interfaces.AgentOutput{
+			Success: false,
+			Error:   fmt.Sprintf("Error updating timestamps for %s: %v", file, err),
 		}, nil
 	}
 
@@ -58,11 +105,13 @@ func (a *TouchAgent) Process(ctx context.Context, input interfaces.AgentInput) (
 	return interfaces.AgentOutput{
 		Success: true,
 		Data: map[string]interface{}{
-			"file":     file,
-			"size":     fileInfo.Size(),
-			"modified": fileInfo.ModTime().Format(time.RFC3339),
-			"mode":     fileInfo.Mode(),
-			"created":  true,
+			"file":          file,
+			"path":          file,
+			"resolved_path": pathutil.Resolve(file),
+			"size":          fileInfo.Size(),
+			"modified":      fileInfo.ModTime().Format(time.RFC3339),
+			"mode":          fileInfo.Mode(),
+			"created":       created,
 		},
 	}, nil
 }
@@ -76,5 +125,10 @@ func (a *TouchAgent) Shutdown() error {
 	return nil
 }
 
+// Classification declares that the agent updates timestamps, to the current time unless given one
+func (a *TouchAgent) Classification() interfaces.Classification {
+	return interfaces.Classification{}
+}
+
 // Export the agent for plugin loading
 var Agent interfaces.Agent = NewTouchAgent()
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+func TestTouchAgent_PreservesExistingContent(t *testing.T) {
+	agent := NewTouchAgent()
+	file := filepath.Join(t.TempDir(), "existing.txt")
+
+	if err := os.WriteFile(file, []byte("keep me"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	old := time.Now().Add(-24 * time.Hour)
+	if err := os.Chtimes(file, old, old); err != nil {
+		t.Fatalf("Failed to set times: %v", err)
+	}
+
+	output, err := agent.Process(t.Context(), interfaces.AgentInput{
+		Type:    "touch",
+		Payload: map[string]interface{}{"file": file},
+	})
+	if err != nil || !output.Success {
+		t.Fatalf("Touch failed: err=%v, output=%s", err, output.Error)
+	}
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if string(content) != "keep me" {
+		t.Errorf("Expected content to survive touch, got %q", string(content))
+	}
+
+	info, _ := os.Stat(file)
+	if !info.ModTime().After(old) {
+		t.Errorf("Expected modification time to be updated, still %v", info.ModTime())
+	}
+	if output.Data["created"] != false {
+		t.Errorf("Expected created=false for existing file, got %v", output.Data["created"])
+	}
+}
+
+func TestTouchAgent_CreatesMissingFile(t *testing.T) {
+	agent := NewTouchAgent()
+	file := filepath.Join(t.TempDir(), "new.txt")
+
+	output, _ := agent.Process(t.Context(), interfaces.AgentInput{
+		Type:    "touch",
+		Payload: map[string]interface{}{"file": file},
+	})
+	if !output.Success || output.Data["created"] != true {
+		t.Fatalf("Expected file to be created, got %+v", output)
+	}
+	if _, err := os.Stat(file); err != nil {
+		t.Errorf("Expected file to exist: %v", err)
+	}
+}
+
+func TestTouchAgent_NoCreate(t *testing.T) {
+	agent := NewTouchAgent()
+	file := filepath.Join(t.TempDir(), "missing.txt")
+
+	output, _ := agent.Process(t.Context(), interfaces.AgentInput{
+		Type:    "touch",
+		Payload: map[string]interface{}{"file": file, "no_create": true},
+	})
+	if !output.Success {
+		t.Fatalf("Expected success with no_create, got error: %s", output.Error)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Error("Expected file not to be created when no_create is set")
+	}
+}
+
+func TestTouchAgent_SpecificTime(t *testing.T) {
+	agent := NewTouchAgent()
+	file := filepath.Join(t.TempDir(), "dated.txt")
+
+	output, _ := agent.Process(t.Context(), interfaces.AgentInput{
+		Type:    "touch",
+		Payload: map[string]interface{}{"file": file, "time": "2020-01-02T03:04:05Z"},
+	})
+	if !output.Success {
+		t.Fatalf("Touch failed: %s", output.Error)
+	}
+
+	info, _ := os.Stat(file)
+	expected := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	if !info.ModTime().Equal(expected) {
+		t.Errorf("Expected modification time %v, got %v", expected, info.ModTime())
+	}
+
+	output, _ = agent.Process(t.Context(), interfaces.AgentInput{
+		Type:    "touch",
+		Payload: map[string]interface{}{"file": file, "time": "yesterday"},
+	})
+	if output.Success {
+		t.Error("Expected failure for invalid time")
+	}
+}
@@ -0,0 +1,7 @@
+module github.com/AgentForgeEngine/AgentForgeEngine/agents/tree-hash
+
+go 1.24.0
+
+replace github.com/AgentForgeEngine/AgentForgeEngine => ../..
+
+require github.com/AgentForgeEngine/AgentForgeEngine v0.0.0-00010101000000-000000000000
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/gitignore"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+type TreeHashAgent struct {
+	name string
+	// exclude holds gitignore-style patterns skipped on every walk
+	exclude          []string
+	respectGitignore bool
+}
+
+// FileEntry is one regular file of a manifest
+type FileEntry struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+	Mode   string `json:"mode"`
+}
+
+// Manifest lists the files below a directory, sorted by path, with a root
+// hash that changes when any path, content, or mode does
+type Manifest struct {
+	Root     string      `json:"root,omitempty"`
+	Files    []FileEntry `json:"files"`
+	RootHash string      `json:"root_hash"`
+}
+
+// FileChange is a file present in both manifests whose content or mode differs
+type FileChange struct {
+	Path      string `json:"path"`
+	OldSHA256 string `json:"old_sha256"`
+	NewSHA256 string `json:"new_sha256"`
+	OldSize   int64  `json:"old_size"`
+	NewSize   int64  `json:"new_size"`
+	OldMode   string `json:"old_mode"`
+	NewMode   string `json:"new_mode"`
+}
+
+func NewTreeHashAgent() *TreeHashAgent {
+	return &TreeHashAgent{
+		name:             "tree-hash",
+		exclude:          []string{".git/"},
+		respectGitignore: true,
+	}
+}
+
+func (a *TreeHashAgent) Name() string {
+	return a.name
+}
+
+func (a *TreeHashAgent) Initialize(config map[string]interface{}) error {
+	if patterns, ok := getStrings(config, "exclude"); ok {
+		a.exclude = append([]string{".git/"}, patterns...)
+	}
+	if respect, ok := config["respect_gitignore"].(bool); ok {
+		a.respectGitignore = respect
+	}
+
+	log.Printf("Initializing %s agent: exclude=%v, respect_gitignore=%v", a.name, a.exclude, a.respectGitignore)
+	return nil
+}
+
+func (a *TreeHashAgent) Process(ctx context.Context, input interfaces.AgentInput) (interfaces.AgentOutput, error) {
+	switch input.Type {
+	case "generate", "execute", "":
+		return a.generate(ctx, input)
+	case "compare":
+		return a.compare(ctx, input)
+	default:
+		return interfaces.AgentOutput{
+			Success: false,
+			Error:   fmt.Sprintf("unknown operation: %s", input.Type),
+		}, nil
+	}
+}
+
+// generate builds the manifest of the directory in the payload's path
+func (a *TreeHashAgent) generate(ctx context.Context, input interfaces.AgentInput) (interfaces.AgentOutput, error) {
+	root, _ := input.Payload["path"].(string)
+	if root == "" {
+		root = "."
+	}
+
+	manifest, skipped, err := a.buildManifest(ctx, root, input.Payload)
+	if err != nil {
+		return interfaces.AgentOutput{
+			Success: false,
+			Error:   fmt.Sprintf("Error generating manifest: %v", err),
+		}, nil
+	}
+
+	var totalBytes int64
+	for _, file := range manifest.Files {
+		totalBytes += file.Size
+	}
+
+	return interfaces.AgentOutput{
+		Success: true,
+		Data: map[string]interface{}{
+			"root":        manifest.Root,
+			"files":       manifest.Files,
+			"root_hash":   manifest.RootHash,
+			"count":       len(manifest.Files),
+			"total_bytes": totalBytes,
+			"skipped":     skipped,
+		},
+	}, nil
+}
+
+// compare diffs two manifests, base and target. Either may be a manifest, as
+// returned by generate, or the path of a directory to generate one from.
+func (a *TreeHashAgent) compare(ctx context.Context, input interfaces.AgentInput) (interfaces.AgentOutput, error) {
+	base, err := a.resolveManifest(ctx, input.Payload, "base")
+	if err != nil {
+		return interfaces.AgentOutput{Success: false, Error: err.Error()}, nil
+	}
+	target, err := a.resolveManifest(ctx, input.Payload, "target")
+	if err != nil {
+		return interfaces.AgentOutput{Success: false, Error: err.Error()}, nil
+	}
+
+	added, removed, changed, unchanged := diffManifests(base, target)
+
+	return interfaces.AgentOutput{
+		Success: true,
+		Data: map[string]interface{}{
+			"identical":        base.RootHash == target.RootHash,
+			"base_root_hash":   base.RootHash,
+			"target_root_hash": target.RootHash,
+			"added":            added,
+			"removed":          removed,
+			"changed":          changed,
+			"unchanged":        unchanged,
+		},
+	}, nil
+}
+
+// resolveManifest reads the manifest under key, generating it when the
+// value is a directory path
+func (a *TreeHashAgent) resolveManifest(ctx context.Context, payload map[string]interface{}, key string) (*Manifest, error) {
+	switch value := payload[key].(type) {
+	case nil:
+		return nil, fmt.Errorf("%s is required: a manifest or a directory path", key)
+	case string:
+		manifest, _, err := a.buildManifest(ctx, value, payload)
+		if err != nil {
+			return nil, fmt.Errorf("error generating %s manifest: %v", key, err)
+		}
+		return manifest, nil
+	default:
+		// Manifests arrive as decoded JSON or as generate's own output
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s manifest: %v", key, err)
+		}
+		var manifest Manifest
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			return nil, fmt.Errorf("invalid %s manifest: %v", key, err)
+		}
+		if manifest.RootHash == "" {
+			manifest.RootHash = rootHash(manifest.Files)
+		}
+		return &manifest, nil
+	}
+}
+
+// buildManifest hashes every regular file below root that is not excluded
+// by the agent's or payload's patterns or, unless turned off, by .gitignore
+// files. Symlinks and other special files are listed in skipped.
+func (a *TreeHashAgent) buildManifest(ctx context.Context, root string, payload map[string]interface{}) (*Manifest, []string, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !info.IsDir() {
+		return nil, nil, fmt.Errorf("%s is not a directory", root)
+	}
+
+	respectGitignore := a.respectGitignore
+	if respect, ok := payload["respect_gitignore"].(bool); ok {
+		respectGitignore = respect
+	}
+
+	exclude := gitignore.New()
+	exclude.AddPatterns("", a.exclude)
+	if patterns, ok := getStrings(payload, "exclude"); ok {
+		exclude.AddPatterns("", patterns)
+	}
+	ignored := gitignore.New()
+
+	files := []FileEntry{}
+	skipped := []string{}
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		if rel != "." && (exclude.Ignored(rel, d.IsDir()) || ignored.Ignored(rel, d.IsDir())) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if d.IsDir() {
+			if respectGitignore {
+				return ignored.LoadDir(root, rel)
+			}
+			return nil
+		}
+
+		rel = filepath.ToSlash(rel)
+		if !d.Type().IsRegular() {
+			skipped = append(skipped, rel)
+			return nil
+		}
+
+		entry, err := hashFile(path, rel)
+		if err != nil {
+			return err
+		}
+		files = append(files, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+
+	return &Manifest{
+		Root:     root,
+		Files:    files,
+		RootHash: rootHash(files),
+	}, skipped, nil
+}
+
+// hashFile returns the manifest entry of one regular file
+func hashFile(path, rel string) (FileEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return FileEntry{}, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return FileEntry{}, err
+	}
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return FileEntry{}, fmt.Errorf("failed to read %s: %w", rel, err)
+	}
+
+	return FileEntry{
+		Path:   rel,
+		Size:   size,
+		SHA256: hex.EncodeToString(hash.Sum(nil)),
+		Mode:   fmt.Sprintf("%04o", info.Mode().Perm()),
+	}, nil
+}
+
+// rootHash hashes one "sha256 mode path" line per file in path order, so it
+// does not depend on the order files were listed in
+func rootHash(files []FileEntry) string {
+	sorted := append([]FileEntry(nil), files...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Path < sorted[j].Path })
+
+	hash := sha256.New()
+	for _, file := range sorted {
+		fmt.Fprintf(hash, "%s %s %s\n", file.SHA256, file.Mode, file.Path)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// diffManifests reports the paths only in target, the paths only in base,
+// the files whose content or mode changed, and how many are the same
+func diffManifests(base, target *Manifest) (added, removed []string, changed []FileChange, unchanged int) {
+	added, removed, changed = []string{}, []string{}, []FileChange{}
+
+	baseFiles := make(map[string]FileEntry, len(base.Files))
+	for _, file := range base.Files {
+		baseFiles[file.Path] = file
+	}
+
+	seen := make(map[string]bool, len(target.Files))
+	for _, file := range target.Files {
+		seen[file.Path] = true
+		old, ok := baseFiles[file.Path]
+		switch {
+		case !ok:
+			added = append(added, file.Path)
+		case old.SHA256 != file.SHA256 || old.Mode != file.Mode:
+			changed = append(changed, FileChange{
+				Path:      file.Path,
+				OldSHA256: old.SHA256,
+				NewSHA256: file.SHA256,
+				OldSize:   old.Size,
+				NewSize:   file.Size,
+				OldMode:   old.Mode,
+				NewMode:   file.Mode,
+			})
+		default:
+			unchanged++
+		}
+	}
+	for _, file := range base.Files {
+		if !seen[file.Path] {
+			removed = append(removed, file.Path)
+		}
+	}
+
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Slice(changed, func(i, j int) bool { return changed[i].Path < changed[j].Path })
+	return added, removed, changed, unchanged
+}
+
+// getStrings reads a list of strings decoded from YAML or JSON
+func getStrings(values map[string]interface{}, key string) ([]string, bool) {
+	switch v := values[key].(type) {
+	case []string:
+		return v, true
+	case []interface{}:
+		strs := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				strs = append(strs, s)
+			}
+		}
+		return strs, true
+	}
+	return nil, false
+}
+
+func (a *TreeHashAgent) HealthCheck() error {
+	return nil
+}
+
+func (a *TreeHashAgent) Shutdown() error {
+	log.Printf("Shutting down %s agent", a.name)
+	return nil
+}
+
+// Classification declares that the agent only reads the file tree
+func (a *TreeHashAgent) Classification() interfaces.Classification {
+	return interfaces.Classification{ReadOnly: true, Idempotent: true}
+}
+
+// Export the agent for plugin loading
+var Agent interfaces.Agent = NewTreeHashAgent()
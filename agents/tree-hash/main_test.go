@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+// writeFiles creates files with the given contents below root
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+}
+
+func run(t *testing.T, opType string, payload map[string]interface{}) map[string]interface{} {
+	t.Helper()
+	output, err := NewTreeHashAgent().Process(context.Background(), interfaces.AgentInput{Type: opType, Payload: payload})
+	if err != nil || !output.Success {
+		t.Fatalf("Expected success, got err=%v error=%s", err, output.Error)
+	}
+	return output.Data
+}
+
+func paths(files []FileEntry) []string {
+	names := make([]string, len(files))
+	for i, file := range files {
+		names[i] = file.Path
+	}
+	return names
+}
+
+func TestGenerate_Manifest(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"main.go":          "package main\n",
+		"docs/readme.md":   "# Readme\n",
+		"build/out.bin":    "binary",
+		"app.log":          "log line",
+		"vendor/dep.go":    "package dep\n",
+		".gitignore":       "build/\n*.log\n",
+		".git/HEAD":        "ref: refs/heads/main\n",
+		"docs/.gitignore":  "draft.md\n",
+		"docs/draft.md":    "draft",
+		"notes/todo.txt":   "todo",
+		"notes/secret.key": "key",
+	})
+	os.Chmod(filepath.Join(root, "main.go"), 0755)
+
+	data := run(t, "generate", map[string]interface{}{"path": root, "exclude": []interface{}{"vendor", "*.key"}})
+	files := data["files"].([]FileEntry)
+
+	expected := []string{".gitignore", "docs/.gitignore", "docs/readme.md", "main.go", "notes/todo.txt"}
+	if got := paths(files); len(got) != len(expected) {
+		t.Fatalf("Expected files %v, got %v", expected, got)
+	} else {
+		for i := range expected {
+			if got[i] != expected[i] {
+				t.Errorf("File %d: expected %s, got %s", i, expected[i], got[i])
+			}
+		}
+	}
+
+	sum := sha256.Sum256([]byte("package main\n"))
+	main := files[3]
+	if main.SHA256 != hex.EncodeToString(sum[:]) || main.Size != 13 || main.Mode != "0755" {
+		t.Errorf("Unexpected entry for main.go: %+v", main)
+	}
+	if data["root_hash"] != rootHash(files) || len(data["root_hash"].(string)) != 64 {
+		t.Errorf("Expected the root hash of the listed files, got %v", data["root_hash"])
+	}
+
+	// Without .gitignore the ignored files are listed too
+	data = run(t, "generate", map[string]interface{}{"path": root, "respect_gitignore": false})
+	if count := data["count"].(int); count != 10 {
+		t.Errorf("Expected 10 files when ignoring .gitignore, got %d: %v", count, paths(data["files"].([]FileEntry)))
+	}
+}
+
+func TestCompare_DetectsChanges(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"config.yaml": "replicas: 2\n",
+		"app.go":      "package app\n",
+		"old.txt":     "obsolete",
+	})
+
+	before := run(t, "generate", map[string]interface{}{"path": root})
+
+	// Deploy a new version
+	writeFiles(t, root, map[string]string{"config.yaml": "replicas: 3\n", "new.txt": "added"})
+	os.Remove(filepath.Join(root, "old.txt"))
+
+	// The old manifest arrives as JSON, as from an API client
+	encoded, _ := json.Marshal(before)
+	var baseManifest map[string]interface{}
+	json.Unmarshal(encoded, &baseManifest)
+
+	data := run(t, "compare", map[string]interface{}{"base": baseManifest, "target": root})
+
+	if data["identical"] != false {
+		t.Error("Expected the trees to differ")
+	}
+	if added := data["added"].([]string); len(added) != 1 || added[0] != "new.txt" {
+		t.Errorf("Expected new.txt added, got %v", added)
+	}
+	if removed := data["removed"].([]string); len(removed) != 1 || removed[0] != "old.txt" {
+		t.Errorf("Expected old.txt removed, got %v", removed)
+	}
+	changed := data["changed"].([]FileChange)
+	if len(changed) != 1 || changed[0].Path != "config.yaml" || changed[0].OldSHA256 == changed[0].NewSHA256 {
+		t.Errorf("Expected config.yaml changed, got %+v", changed)
+	}
+	if data["unchanged"] != 1 {
+		t.Errorf("Expected app.go unchanged, got %v", data["unchanged"])
+	}
+
+	// A tree compared with itself is identical
+	data = run(t, "compare", map[string]interface{}{"base": root, "target": root})
+	if data["identical"] != true || len(data["changed"].([]FileChange)) != 0 {
+		t.Errorf("Expected identical trees, got %v", data)
+	}
+}
+
+func TestCompare_ModeChange(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"run.sh": "echo hi\n"})
+	before := run(t, "generate", map[string]interface{}{"path": root})
+
+	os.Chmod(filepath.Join(root, "run.sh"), 0755)
+	data := run(t, "compare", map[string]interface{}{"base": before, "target": root})
+
+	changed := data["changed"].([]FileChange)
+	if len(changed) != 1 || changed[0].OldMode != "0644" || changed[0].NewMode != "0755" {
+		t.Errorf("Expected a mode change, got %+v", changed)
+	}
+}
+
+func TestCompare_MissingManifest(t *testing.T) {
+	output, _ := NewTreeHashAgent().Process(context.Background(), interfaces.AgentInput{
+		Type:    "compare",
+		Payload: map[string]interface{}{"base": t.TempDir()},
+	})
+	if output.Success {
+		t.Error("Expected compare without a target to fail")
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"container/list"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache states reported in fetch output
+const (
+	cacheHit         = "hit"
+	cacheRevalidated = "revalidated"
+	cacheMiss        = "miss"
+)
+
+// cachedPage is a fetched body together with the validators needed to
+// revalidate it. Extraction runs again on every hit so the request's
+// max_tokens still applies.
+type cachedPage struct {
+	key          string
+	content      string
+	contentType  string
+	finalURL     string
+	redirects    []string
+	etag         string
+	lastModified string
+	// revalidate marks a page sent with Cache-Control: no-cache, which is
+	// never served without checking with the server first
+	revalidate bool
+	storedAt   time.Time
+}
+
+// size is the number of bytes the entry counts against the cache limit
+func (p *cachedPage) size() int64 {
+	size := len(p.key) + len(p.content) + len(p.finalURL)
+	for _, redirect := range p.redirects {
+		size += len(redirect)
+	}
+	return int64(size)
+}
+
+// hasValidators reports whether a stale entry can be revalidated
+func (p *cachedPage) hasValidators() bool {
+	return p.etag != "" || p.lastModified != ""
+}
+
+// pageCache is an LRU cache of fetched pages bounded by total size in bytes
+// and by number of entries. Entries older than ttl are stale: they are kept
+// for revalidation but not served without a round trip.
+type pageCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxBytes   int64
+	maxEntries int
+	size       int64
+	order      *list.List
+	entries    map[string]*list.Element
+	now        func() time.Time
+}
+
+func newPageCache(ttl time.Duration, maxBytes int64, maxEntries int) *pageCache {
+	return &pageCache{
+		ttl:        ttl,
+		maxBytes:   maxBytes,
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+		now:        time.Now,
+	}
+}
+
+// get returns the entry for key and whether it is still within its TTL.
+// Pages that must be revalidated are never fresh.
+func (c *pageCache) get(key string) (*cachedPage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	c.order.MoveToFront(elem)
+	page := elem.Value.(*cachedPage)
+	return page, !page.revalidate && c.now().Sub(page.storedAt) < c.ttl
+}
+
+// put stores page, evicting least recently used entries until the cache
+// fits in both size and entry count. Pages larger than the whole cache are
+// not stored.
+func (c *pageCache) put(page *cachedPage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.removeLocked(page.key)
+	if page.size() > c.maxBytes {
+		return
+	}
+
+	page.storedAt = c.now()
+	c.entries[page.key] = c.order.PushFront(page)
+	c.size += page.size()
+
+	for c.size > c.maxBytes || len(c.entries) > c.maxEntries {
+		oldest := c.order.Back()
+		c.removeLocked(oldest.Value.(*cachedPage).key)
+	}
+}
+
+// refresh restarts the TTL of an entry the server confirmed is unchanged
+func (c *pageCache) refresh(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		elem.Value.(*cachedPage).storedAt = c.now()
+		c.order.MoveToFront(elem)
+	}
+}
+
+// remove drops key from the cache
+func (c *pageCache) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.removeLocked(key)
+}
+
+func (c *pageCache) removeLocked(key string) {
+	elem, ok := c.entries[key]
+	if !ok {
+		return
+	}
+	c.order.Remove(elem)
+	delete(c.entries, key)
+	c.size -= elem.Value.(*cachedPage).size()
+}
+
+// len returns the number of cached pages
+func (c *pageCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.entries)
+}
+
+// normalizeCacheKey maps equivalent URLs to one key: scheme and host are
+// lowercased, default ports and fragments dropped, an empty path becomes "/",
+// and query parameters are sorted
+func normalizeCacheKey(u *url.URL) string {
+	normalized := *u
+	normalized.Scheme = strings.ToLower(u.Scheme)
+	normalized.Fragment = ""
+	normalized.RawFragment = ""
+
+	host := strings.ToLower(u.Hostname())
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	port := u.Port()
+	if (normalized.Scheme == "http" && port == "80") || (normalized.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	if port != "" {
+		host = host + ":" + port
+	}
+	normalized.Host = host
+
+	if normalized.Path == "" {
+		normalized.Path = "/"
+	}
+	normalized.RawQuery = normalized.Query().Encode()
+
+	return normalized.String()
+}
+
+// cacheable reports whether the response headers permit storing the body
+func cacheable(cacheControl string) bool {
+	return !hasDirective(cacheControl, "no-store")
+}
+
+// mustRevalidate reports whether a stored body must be checked with the
+// server before every use
+func mustRevalidate(cacheControl string) bool {
+	return hasDirective(cacheControl, "no-cache")
+}
+
+// hasDirective reports whether a Cache-Control header contains name, with or
+// without a value
+func hasDirective(cacheControl, name string) bool {
+	for _, directive := range strings.Split(strings.ToLower(cacheControl), ",") {
+		directive, _, _ = strings.Cut(strings.TrimSpace(directive), "=")
+		if directive == name {
+			return true
+		}
+	}
+	return false
+}
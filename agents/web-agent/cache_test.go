@@ -0,0 +1,323 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+// fakeClock is a manually advanced time source for the cache
+type fakeClock struct {
+	current time.Time
+}
+
+func (fc *fakeClock) now() time.Time {
+	return fc.current
+}
+
+func (fc *fakeClock) advance(d time.Duration) {
+	fc.current = fc.current.Add(d)
+}
+
+func newTestCache(ttl time.Duration, maxBytes int64) (*pageCache, *fakeClock) {
+	clock := &fakeClock{current: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	cache := newPageCache(ttl, maxBytes, defaultCacheSize)
+	cache.now = clock.now
+	return cache, clock
+}
+
+func testPageEntry(key string, size int) *cachedPage {
+	return &cachedPage{key: key, content: strings.Repeat("x", size-len(key))}
+}
+
+func TestPageCache_EvictsLeastRecentlyUsedBySize(t *testing.T) {
+	cache, _ := newTestCache(time.Minute, 300)
+
+	cache.put(testPageEntry("a", 100))
+	cache.put(testPageEntry("b", 100))
+	cache.put(testPageEntry("c", 100))
+
+	// Touch a so b becomes the least recently used entry
+	if page, _ := cache.get("a"); page == nil {
+		t.Fatal("Expected a to be cached")
+	}
+
+	cache.put(testPageEntry("d", 100))
+
+	if page, _ := cache.get("b"); page != nil {
+		t.Error("Expected b to be evicted")
+	}
+	for _, key := range []string{"a", "c", "d"} {
+		if page, _ := cache.get(key); page == nil {
+			t.Errorf("Expected %s to remain cached", key)
+		}
+	}
+	if cache.size != 300 {
+		t.Errorf("Expected cache size 300, got %d", cache.size)
+	}
+
+	// An entry larger than the whole cache is not stored and evicts nothing
+	cache.put(testPageEntry("huge", 301))
+	if page, _ := cache.get("huge"); page != nil {
+		t.Error("Expected oversized entry to be skipped")
+	}
+	if cache.len() != 3 {
+		t.Errorf("Expected 3 entries, got %d", cache.len())
+	}
+}
+
+func TestPageCache_EvictsLeastRecentlyUsedByCount(t *testing.T) {
+	cache, _ := newTestCache(time.Minute, 1024)
+	cache.maxEntries = 2
+
+	cache.put(testPageEntry("a", 10))
+	cache.put(testPageEntry("b", 10))
+	cache.get("a")
+	cache.put(testPageEntry("c", 10))
+
+	if page, _ := cache.get("b"); page != nil {
+		t.Error("Expected b to be evicted")
+	}
+	if cache.len() != 2 {
+		t.Errorf("Expected 2 entries, got %d", cache.len())
+	}
+}
+
+func TestPageCache_TTLExpiry(t *testing.T) {
+	cache, clock := newTestCache(time.Minute, 1024)
+
+	cache.put(testPageEntry("a", 10))
+
+	clock.advance(59 * time.Second)
+	if page, fresh := cache.get("a"); page == nil || !fresh {
+		t.Fatal("Expected entry to be fresh before the TTL")
+	}
+
+	clock.advance(time.Second)
+	page, fresh := cache.get("a")
+	if page == nil || fresh {
+		t.Fatalf("Expected a stale entry at the TTL, got page=%v fresh=%v", page != nil, fresh)
+	}
+
+	cache.refresh("a")
+	if _, fresh := cache.get("a"); !fresh {
+		t.Error("Expected refresh to restart the TTL")
+	}
+}
+
+func TestNormalizeCacheKey(t *testing.T) {
+	testCases := []struct {
+		a, b string
+	}{
+		{"HTTP://Example.COM", "http://example.com/"},
+		{"https://example.com:443/docs#intro", "https://example.com/docs"},
+		{"http://example.com/search?b=2&a=1", "http://example.com/search?a=1&b=2"},
+	}
+
+	for _, tc := range testCases {
+		a, _ := url.Parse(tc.a)
+		b, _ := url.Parse(tc.b)
+		if normalizeCacheKey(a) != normalizeCacheKey(b) {
+			t.Errorf("Expected %q and %q to share a key, got %q and %q", tc.a, tc.b, normalizeCacheKey(a), normalizeCacheKey(b))
+		}
+	}
+}
+
+func TestFetch_CacheHitRevalidateAndBypass(t *testing.T) {
+	var requests, notModified int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		atomic.AddInt32(&requests, 1)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("ETag", `"v1"`)
+		fmt.Fprint(w, testPage)
+	}))
+	defer server.Close()
+
+	wa := newTestAgent()
+	wa.blockedDomains = nil
+	cache, clock := newTestCache(time.Minute, 1024*1024)
+	wa.cache = cache
+
+	fetch := func(payload map[string]interface{}) map[string]interface{} {
+		t.Helper()
+		output := process(t, wa, "fetch", payload)
+		if !output.Success {
+			t.Fatalf("Expected fetch to succeed, got: %s", output.Error)
+		}
+		return output.Data
+	}
+
+	pageURL := server.URL + "/docs"
+	if data := fetch(map[string]interface{}{"url": pageURL}); data["cache"] != cacheMiss || data["cached"] != false {
+		t.Errorf("Expected first fetch to miss, got cache=%v cached=%v", data["cache"], data["cached"])
+	}
+
+	data := fetch(map[string]interface{}{"url": pageURL + "#section"})
+	if data["cache"] != cacheHit || data["cached"] != true || data["title"] != "Test Page" {
+		t.Errorf("Expected cached extraction, got cache=%v cached=%v title=%v", data["cache"], data["cached"], data["title"])
+	}
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Errorf("Expected a hit to skip the network, got %d requests", got)
+	}
+
+	clock.advance(2 * time.Minute)
+	data = fetch(map[string]interface{}{"url": pageURL})
+	if data["cache"] != cacheRevalidated || data["title"] != "Test Page" {
+		t.Errorf("Expected revalidated extraction, got cache=%v title=%v", data["cache"], data["title"])
+	}
+	if got := atomic.LoadInt32(&notModified); got != 1 {
+		t.Errorf("Expected one conditional request, got %d", got)
+	}
+
+	if data := fetch(map[string]interface{}{"url": pageURL}); data["cache"] != cacheHit {
+		t.Errorf("Expected revalidation to restart the TTL, got %v", data["cache"])
+	}
+
+	if data := fetch(map[string]interface{}{"url": pageURL, "no_cache": true}); data["cache"] != cacheMiss {
+		t.Errorf("Expected no_cache to bypass the cache, got %v", data["cache"])
+	}
+	if got := atomic.LoadInt32(&requests); got != 3 {
+		t.Errorf("Expected 3 requests, got %d", got)
+	}
+}
+
+func TestFetch_CredentialsBypassCache(t *testing.T) {
+	var requests int32
+	var authHeaders []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		atomic.AddInt32(&requests, 1)
+		authHeaders = append(authHeaders, r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, testPage)
+	}))
+	defer server.Close()
+
+	wa := newTestAgent()
+	wa.blockedDomains = nil
+	cache, _ := newTestCache(time.Minute, 1024*1024)
+	wa.cache = cache
+
+	pageURL := server.URL + "/private"
+	output, err := wa.Process(t.Context(), interfaces.AgentInput{
+		Type:    "fetch",
+		Payload: map[string]interface{}{"url": pageURL},
+		Secrets: interfaces.Secrets{"bearer_token": "s3cr3t"},
+	})
+	if err != nil || !output.Success {
+		t.Fatalf("Expected fetch to succeed, got %v, %s", err, output.Error)
+	}
+	if output.Data["cache"] != cacheMiss {
+		t.Errorf("Expected an authenticated fetch to skip the cache, got %v", output.Data["cache"])
+	}
+	if len(cache.entries) != 0 {
+		t.Errorf("Expected an authenticated page not to be stored, got %d entries", len(cache.entries))
+	}
+
+	// Without credentials the page is fetched again, not served from the
+	// private copy
+	if data := process(t, wa, "fetch", map[string]interface{}{"url": pageURL}).Data; data["cache"] != cacheMiss {
+		t.Errorf("Expected an anonymous fetch to miss, got %v", data["cache"])
+	}
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Errorf("Expected 2 requests, got %d", got)
+	}
+	if len(authHeaders) != 2 || authHeaders[0] != "Bearer s3cr3t" || authHeaders[1] != "" {
+		t.Errorf("Expected the token to be sent only with the authenticated fetch, got %q", authHeaders)
+	}
+	for key := range cache.entries {
+		if strings.Contains(key, "s3cr3t") {
+			t.Errorf("Cache key %q holds the secret", key)
+		}
+	}
+}
+
+func TestFetch_NoCacheResponses(t *testing.T) {
+	var requests, notModified int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Cache-Control", "no-cache")
+		if r.URL.Path == "/validated" {
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				atomic.AddInt32(&notModified, 1)
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", `"v1"`)
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, testPage)
+	}))
+	defer server.Close()
+
+	wa := newTestAgent()
+	wa.blockedDomains = nil
+	wa.cache, _ = newTestCache(time.Minute, 1024*1024)
+
+	// Without validators a no-cache page is fetched every time
+	for i := 0; i < 2; i++ {
+		output := process(t, wa, "fetch", map[string]interface{}{"url": server.URL + "/plain"})
+		if !output.Success || output.Data["cache"] != cacheMiss {
+			t.Fatalf("Expected fetch %d to miss, got %v (%s)", i, output.Data["cache"], output.Error)
+		}
+	}
+	if wa.cache.len() != 0 {
+		t.Errorf("Expected the page not to be stored, got %d entries", wa.cache.len())
+	}
+
+	// With validators it is stored but checked with the server within the TTL
+	process(t, wa, "fetch", map[string]interface{}{"url": server.URL + "/validated"})
+	output := process(t, wa, "fetch", map[string]interface{}{"url": server.URL + "/validated"})
+	if !output.Success || output.Data["cache"] != cacheRevalidated || output.Data["cached"] != true {
+		t.Errorf("Expected the page to be revalidated, got %v", output.Data["cache"])
+	}
+	if got := atomic.LoadInt32(&requests); got != 4 || atomic.LoadInt32(&notModified) != 1 {
+		t.Errorf("Expected 4 requests with one conditional, got %d", got)
+	}
+}
+
+func TestInitialize_CacheOptions(t *testing.T) {
+	wa := NewWebAgent()
+	config := map[string]interface{}{"health_check": "none", "cache_ttl": "10m", "cache_size": 5}
+	if err := wa.Initialize(config); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	if wa.cache.ttl != 10*time.Minute || wa.cache.maxEntries != 5 {
+		t.Errorf("Expected a 10m TTL and 5 entries, got %v and %d", wa.cache.ttl, wa.cache.maxEntries)
+	}
+
+	wa = NewWebAgent()
+	if err := wa.Initialize(map[string]interface{}{"health_check": "none", "cache_size": 0}); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	if wa.cache != nil {
+		t.Error("Expected cache_size 0 to disable the cache")
+	}
+
+	if err := NewWebAgent().Initialize(map[string]interface{}{"health_check": "none", "cache_ttl": "soon"}); err == nil {
+		t.Error("Expected an invalid cache_ttl to be rejected")
+	}
+}
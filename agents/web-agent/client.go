@@ -2,67 +2,175 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"mime"
+	"net"
 	"net/http"
 	"net/url"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
 )
 
+// defaultMaxRedirects is the number of redirects followed by default
+const defaultMaxRedirects = 10
+
+// domainPolicyError reports a URL, or a redirect target, whose domain is not allowed
+type domainPolicyError struct {
+	host string
+}
+
+func (e *domainPolicyError) Error() string {
+	return fmt.Sprintf("domain not allowed: %s", e.host)
+}
+
+// privateAddressError reports a host that resolved to an address inside the
+// engine's own network
+type privateAddressError struct {
+	address string
+}
+
+func (e *privateAddressError) Error() string {
+	return fmt.Sprintf("address not allowed: %s is private, loopback, or link-local", e.address)
+}
+
+// bodyTooLargeError reports a response body larger than maxBodySize
+type bodyTooLargeError struct {
+	limit int64
+}
+
+func (e *bodyTooLargeError) Error() string {
+	return fmt.Sprintf("response body exceeds %d bytes", e.limit)
+}
+
 func (wa *WebAgent) fetchURL(ctx context.Context, input interfaces.AgentInput) (interfaces.AgentOutput, error) {
-	// Extract URL from payload
-	urlStr, ok := input.Payload["url"].(string)
-	if !ok {
+	// Extract and validate URL from payload
+	parsedURL, err := wa.parseRequestURL(input.Payload)
+	if err != nil {
 		return interfaces.AgentOutput{
 			Success: false,
-			Error:   "url not specified in payload",
+			Error:   err.Error(),
 		}, nil
 	}
 
-	// Parse and validate URL
-	parsedURL, err := url.Parse(urlStr)
+	// Get max tokens and output format for this request
+	maxTokens := wa.getMaxTokens(input.Payload)
+	format, err := getFormat(input.Payload)
 	if err != nil {
 		return interfaces.AgentOutput{
 			Success: false,
-			Error:   fmt.Sprintf("invalid URL: %w", err),
+			Error:   err.Error(),
 		}, nil
 	}
 
-	// Check domain restrictions
-	if !wa.isAllowedDomain(parsedURL.Hostname()) {
-		return interfaces.AgentOutput{
-			Success: false,
-			Error:   fmt.Sprintf("domain not allowed: %s", parsedURL.Hostname()),
-		}, nil
+	// raw returns the body as downloaded instead of extracting it
+	raw, _ := input.Payload["raw"].(bool)
+
+	// Serve fresh cache entries directly; stale ones with validators are
+	// revalidated with a conditional request. Pages fetched with credentials
+	// are the caller's alone, so they are neither served from the cache nor
+	// stored in it.
+	noCache, _ := input.Payload["no_cache"].(bool)
+	auth := authorization(input.Secrets)
+	useCache := wa.cache != nil && !noCache && auth == ""
+	cacheKey := normalizeCacheKey(parsedURL)
+
+	var stale *cachedPage
+	if useCache {
+		page, fresh := wa.cache.get(cacheKey)
+		if page != nil && fresh {
+			return interfaces.AgentOutput{
+				Success: true,
+				Data:    wa.pageResult(page, parsedURL, maxTokens, format, raw, cacheHit),
+			}, nil
+		}
+		if page != nil && page.hasValidators() {
+			stale = page
+		}
 	}
 
-	// Get max tokens for this request
-	maxTokens := wa.getMaxTokens(input.Payload)
+	// Honor the host's robots.txt before going to the network
+	if err := wa.checkRobots(ctx, parsedURL); err != nil {
+		output := interfaces.AgentOutput{
+			Success: false,
+			Error:   err.Error(),
+		}
+		var robotsErr *robotsDisallowedError
+		if errors.As(err, &robotsErr) {
+			output.Data = map[string]interface{}{"error_type": "robots_disallowed"}
+		}
+		return output, nil
+	}
 
 	// Create HTTP request
-	req, err := http.NewRequestWithContext(ctx, "GET", urlStr, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", parsedURL.String(), nil)
 	if err != nil {
 		return interfaces.AgentOutput{
 			Success: false,
-			Error:   fmt.Sprintf("request creation failed: %w", err),
+			Error:   fmt.Sprintf("request creation failed: %v", err),
 		}, nil
 	}
 
 	req.Header.Set("User-Agent", wa.userAgent)
 	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	if stale != nil {
+		if stale.etag != "" {
+			req.Header.Set("If-None-Match", stale.etag)
+		}
+		if stale.lastModified != "" {
+			req.Header.Set("If-Modified-Since", stale.lastModified)
+		}
+	}
 
-	// Make request
-	resp, err := wa.httpClient.Do(req)
+	// Make request; redirects are checked against the domain policy as they happen
+	resp, err := wa.do(req)
 	if err != nil {
 		return interfaces.AgentOutput{
 			Success: false,
-			Error:   fmt.Sprintf("request failed: %w", err),
+			Error:   requestError(err),
 		}, nil
 	}
 	defer resp.Body.Close()
 
+	// Surface throttling with the requested delay so callers can back off
+	if limited := checkRateLimited(resp, time.Now()); limited != nil {
+		return interfaces.AgentOutput{
+			Success: false,
+			Data:    limited.data(),
+			Error:   limited.Error(),
+		}, nil
+	}
+
+	if stale != nil && resp.StatusCode == http.StatusNotModified {
+		wa.cache.refresh(cacheKey)
+		return interfaces.AgentOutput{
+			Success: true,
+			Data:    wa.pageResult(stale, parsedURL, maxTokens, format, raw, cacheRevalidated),
+		}, nil
+	}
+
+	finalURL := resp.Request.URL.String()
+	redirects := redirectChain(resp)
+
+	// With redirects disabled the redirect itself is the result
+	if !wa.followRedirects && isRedirect(resp.StatusCode) {
+		return interfaces.AgentOutput{
+			Success: true,
+			Data: map[string]interface{}{
+				"url":         parsedURL.String(),
+				"status_code": resp.StatusCode,
+				"location":    resp.Header.Get("Location"),
+			},
+		}, nil
+	}
+
 	// Check response
 	if resp.StatusCode != http.StatusOK {
 		return interfaces.AgentOutput{
@@ -71,36 +179,100 @@ func (wa *WebAgent) fetchURL(ctx context.Context, input interfaces.AgentInput) (
 		}, nil
 	}
 
-	// Check content type
+	// Check content type before reading, so a binary download never reaches
+	// the extractor
 	contentType := resp.Header.Get("Content-Type")
 	if !wa.isAllowedContentType(contentType) {
 		return interfaces.AgentOutput{
 			Success: false,
-			Error:   fmt.Sprintf("content type not allowed: %s", contentType),
+			Data: map[string]interface{}{
+				"url":                   parsedURL.String(),
+				"content_type":          contentType,
+				"rejected_content_type": baseContentType(contentType),
+				"allowed_content_types": wa.allowedContentTypes,
+			},
+			Error: fmt.Sprintf("content type not allowed: %s", contentType),
 		}, nil
 	}
 
 	// Read content with size limit
-	content, err := wa.readContent(resp.Body, 10*1024*1024) // 10MB max
+	if resp.ContentLength > wa.maxBodySize {
+		return interfaces.AgentOutput{
+			Success: false,
+			Error:   (&bodyTooLargeError{limit: wa.maxBodySize}).Error(),
+		}, nil
+	}
+
+	content, err := wa.readContent(resp.Body, wa.maxBodySize)
 	if err != nil {
 		return interfaces.AgentOutput{
 			Success: false,
-			Error:   fmt.Sprintf("content reading failed: %w", err),
+			Error:   fmt.Sprintf("content reading failed: %v", err),
 		}, nil
 	}
 
-	// Extract and process content
-	result := wa.extractAndOptimizeContent(content, urlStr, maxTokens)
+	page := &cachedPage{
+		key:          cacheKey,
+		content:      content,
+		contentType:  contentType,
+		finalURL:     finalURL,
+		redirects:    redirects,
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+	}
+
+	// A no-cache page is only worth storing when it can be revalidated
+	if useCache {
+		cacheControl := resp.Header.Get("Cache-Control")
+		page.revalidate = mustRevalidate(cacheControl)
+		if cacheable(cacheControl) && (!page.revalidate || page.hasValidators()) {
+			wa.cache.put(page)
+		} else {
+			wa.cache.remove(cacheKey)
+		}
+	}
 
 	return interfaces.AgentOutput{
 		Success: true,
-		Data:    result,
+		Data:    wa.pageResult(page, parsedURL, maxTokens, format, raw, cacheMiss),
 	}, nil
 }
 
+// pageResult extracts a fetched or cached page, or returns its body as is when
+// raw is set, and annotates it with the response details and cache state
+func (wa *WebAgent) pageResult(page *cachedPage, requestURL *url.URL, maxTokens int, format string, raw bool, cacheState string) map[string]interface{} {
+	// HTML goes through extraction; other allowed types are returned as text
+	var result map[string]interface{}
+	switch {
+	case raw:
+		result = map[string]interface{}{
+			"url":  requestURL.String(),
+			"body": page.content,
+			"size": len(page.content),
+		}
+	case isHTML(page.contentType):
+		result = wa.extractAndOptimizeContent(page.content, page.finalURL, maxTokens, format)
+	default:
+		result = wa.plainContent(page.content, page.finalURL, maxTokens)
+	}
+
+	result["content_type"] = page.contentType
+	result["status_code"] = http.StatusOK
+	result["cache"] = cacheState
+	result["cached"] = cacheState != cacheMiss
+	if page.finalURL != requestURL.String() {
+		result["final_url"] = page.finalURL
+	}
+	if len(page.redirects) > 0 {
+		result["redirect_chain"] = page.redirects
+	}
+
+	return result
+}
+
 func (wa *WebAgent) validateURL(ctx context.Context, input interfaces.AgentInput) (interfaces.AgentOutput, error) {
 	urlStr, ok := input.Payload["url"].(string)
-	if !ok {
+	if !ok || urlStr == "" {
 		return interfaces.AgentOutput{
 			Success: false,
 			Error:   "url not specified in payload",
@@ -109,45 +281,43 @@ func (wa *WebAgent) validateURL(ctx context.Context, input interfaces.AgentInput
 
 	// Parse and validate URL
 	parsedURL, err := url.Parse(urlStr)
-	if err != nil {
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") {
+		if err == nil {
+			err = fmt.Errorf("unsupported scheme %q", parsedURL.Scheme)
+		}
 		return interfaces.AgentOutput{
 			Success: true,
 			Data: map[string]interface{}{
 				"url":   urlStr,
 				"valid": false,
-				"error": fmt.Sprintf("invalid URL: %w", err),
+				"error": fmt.Sprintf("invalid URL: %v", err),
 			},
 		}, nil
 	}
 
-	// Check domain restrictions
-	domainAllowed := wa.isAllowedDomain(parsedURL.Hostname())
-
-	// Do a HEAD request to check accessibility
-	req, err := http.NewRequestWithContext(ctx, "HEAD", urlStr, nil)
-	if err != nil {
+	// Check domain restrictions before touching the network
+	if !wa.isAllowedDomain(parsedURL.Hostname()) {
 		return interfaces.AgentOutput{
 			Success: true,
 			Data: map[string]interface{}{
 				"url":            urlStr,
 				"valid":          false,
-				"domain_allowed": domainAllowed,
-				"error":          fmt.Sprintf("request creation failed: %w", err),
+				"domain_allowed": false,
+				"error":          (&domainPolicyError{host: parsedURL.Hostname()}).Error(),
 			},
 		}, nil
 	}
 
-	req.Header.Set("User-Agent", wa.userAgent)
-
-	resp, err := wa.httpClient.Do(req)
+	resp, err := wa.probe(ctx, urlStr, authorization(input.Secrets))
 	if err != nil {
+		var policyErr *domainPolicyError
 		return interfaces.AgentOutput{
 			Success: true,
 			Data: map[string]interface{}{
 				"url":            urlStr,
 				"valid":          false,
-				"domain_allowed": domainAllowed,
-				"error":          fmt.Sprintf("request failed: %w", err),
+				"domain_allowed": !errors.As(err, &policyErr),
+				"error":          requestError(err),
 			},
 		}, nil
 	}
@@ -156,33 +326,291 @@ func (wa *WebAgent) validateURL(ctx context.Context, input interfaces.AgentInput
 	contentType := resp.Header.Get("Content-Type")
 	contentTypeAllowed := wa.isAllowedContentType(contentType)
 
+	data := map[string]interface{}{
+		"url":            urlStr,
+		"final_url":      resp.Request.URL.String(),
+		"redirect_chain": redirectChain(resp),
+		"valid":          resp.StatusCode < 400 && contentTypeAllowed,
+		"status_code":    resp.StatusCode,
+		"domain_allowed": true,
+		"content_type":   contentType,
+		"type_allowed":   contentTypeAllowed,
+		"content_length": resp.ContentLength,
+	}
+
+	if limited := checkRateLimited(resp, time.Now()); limited != nil {
+		for key, value := range limited.data() {
+			data[key] = value
+		}
+		data["error"] = limited.Error()
+	}
+
+	// Report whether a fetch would be refused, without failing validation
+	if !wa.ignoreRobots {
+		var robotsErr *robotsDisallowedError
+		data["robots_allowed"] = !errors.As(wa.checkRobots(ctx, parsedURL), &robotsErr)
+	}
+
 	return interfaces.AgentOutput{
 		Success: true,
-		Data: map[string]interface{}{
-			"url":            urlStr,
-			"valid":          resp.StatusCode < 400,
-			"status_code":    resp.StatusCode,
-			"domain_allowed": domainAllowed,
-			"content_type":   contentType,
-			"type_allowed":   contentTypeAllowed,
-		},
+		Data:    data,
 	}, nil
 }
 
+// probe sends a HEAD request, falling back to a GET whose body is never read
+// for servers that do not implement HEAD. auth, when set, is sent as the
+// Authorization header.
+func (wa *WebAgent) probe(ctx context.Context, urlStr, auth string) (*http.Response, error) {
+	for _, method := range []string{"HEAD", "GET"} {
+		req, err := http.NewRequestWithContext(ctx, method, urlStr, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("User-Agent", wa.userAgent)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+
+		resp, err := wa.do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		if method == "HEAD" && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) {
+			resp.Body.Close()
+			continue
+		}
+		return resp, nil
+	}
+
+	return nil, fmt.Errorf("server rejected HEAD and GET requests")
+}
+
+// extractContent runs extraction over HTML supplied in the payload, for callers
+// that already have the page. Without html it fetches url instead.
 func (wa *WebAgent) extractContent(ctx context.Context, input interfaces.AgentInput) (interfaces.AgentOutput, error) {
-	// For now, this is the same as fetchURL - can be enhanced later
-	return wa.fetchURL(ctx, input)
+	htmlContent, _ := input.Payload["html"].(string)
+	if htmlContent == "" {
+		if _, hasURL := input.Payload["url"].(string); hasURL {
+			return wa.fetchURL(ctx, input)
+		}
+		return interfaces.AgentOutput{
+			Success: false,
+			Error:   "html or url not specified in payload",
+		}, nil
+	}
+
+	if int64(len(htmlContent)) > wa.maxBodySize {
+		return interfaces.AgentOutput{
+			Success: false,
+			Error:   (&bodyTooLargeError{limit: wa.maxBodySize}).Error(),
+		}, nil
+	}
+
+	format, err := getFormat(input.Payload)
+	if err != nil {
+		return interfaces.AgentOutput{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	// url is optional here and only used to classify and resolve links
+	urlStr, _ := input.Payload["url"].(string)
+
+	return interfaces.AgentOutput{
+		Success: true,
+		Data:    wa.extractAndOptimizeContent(htmlContent, urlStr, wa.getMaxTokens(input.Payload), format),
+	}, nil
+}
+
+// authorization returns the Authorization header the call's secrets ask
+// for: the authorization secret as is, or the bearer_token secret as a
+// bearer token. The HTTP client drops it on redirects to other hosts.
+func authorization(secrets interfaces.Secrets) string {
+	if value := secrets.Get("authorization"); value != "" {
+		return value
+	}
+	if token := secrets.Get("bearer_token"); token != "" {
+		return "Bearer " + token
+	}
+	return ""
+}
+
+// parseRequestURL reads the payload url and checks its scheme and domain
+func (wa *WebAgent) parseRequestURL(payload map[string]interface{}) (*url.URL, error) {
+	urlStr, ok := payload["url"].(string)
+	if !ok || urlStr == "" {
+		return nil, fmt.Errorf("url not specified in payload")
+	}
+
+	parsedURL, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %v", err)
+	}
+	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
+		return nil, fmt.Errorf("invalid URL: unsupported scheme %q", parsedURL.Scheme)
+	}
+
+	if !wa.isAllowedDomain(parsedURL.Hostname()) {
+		return nil, &domainPolicyError{host: parsedURL.Hostname()}
+	}
+
+	return parsedURL, nil
 }
 
+// do sends req once the per-host rate limit allows it
+func (wa *WebAgent) do(req *http.Request) (*http.Response, error) {
+	if wa.limiter != nil {
+		if err := wa.limiter.wait(req.Context(), req.URL.Hostname()); err != nil {
+			return nil, err
+		}
+	}
+	return wa.httpClient.Do(req)
+}
+
+// checkRedirect applies the domain policy to every redirect hop so a permitted
+// URL cannot bounce the request to a blocked domain. It stops at a URL already
+// visited and after maxRedirects hops, and returns the redirect response
+// itself when redirects are not followed.
+func (wa *WebAgent) checkRedirect(req *http.Request, via []*http.Request) error {
+	if !wa.followRedirects {
+		return http.ErrUseLastResponse
+	}
+	// via holds the original request too, so it is one longer than the
+	// number of redirects already followed
+	if len(via) > wa.maxRedirects {
+		return fmt.Errorf("too many redirects: limit is %d", wa.maxRedirects)
+	}
+	for _, previous := range via {
+		if previous.URL.String() == req.URL.String() {
+			return fmt.Errorf("redirect loop at %s", req.URL)
+		}
+	}
+	if !wa.isAllowedDomain(req.URL.Hostname()) {
+		return &domainPolicyError{host: req.URL.Hostname()}
+	}
+	return nil
+}
+
+// newTransport returns the agent's HTTP transport. Every connection is
+// checked after DNS resolution, so neither an allowed domain that resolves
+// to an internal address nor a redirect can reach one. No proxy is used,
+// since a proxy would resolve the host past the check.
+func (wa *WebAgent) newTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   wa.checkDialAddress,
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}
+
+// checkDialAddress refuses connections to loopback, private, link-local
+// (including cloud metadata services), and unspecified addresses unless
+// allow_private_networks is set
+func (wa *WebAgent) checkDialAddress(network, address string, _ syscall.RawConn) error {
+	if wa.allowPrivateNetworks {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || isInternalIP(ip) {
+		return &privateAddressError{address: host}
+	}
+	return nil
+}
+
+// isInternalIP reports whether ip belongs to this host or its private
+// network rather than the public internet
+func isInternalIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsUnspecified() || sharedAddressSpace.Contains(ip)
+}
+
+// sharedAddressSpace is the carrier-grade NAT range of RFC 6598, which is
+// not routed on the internet either
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// redirectChain lists the URLs that redirected on the way to resp, in the
+// order they were requested
+func redirectChain(resp *http.Response) []string {
+	chain := []string{}
+	for req := resp.Request; req.Response != nil; req = req.Response.Request {
+		chain = append([]string{req.Response.Request.URL.String()}, chain...)
+	}
+	return chain
+}
+
+// isRedirect reports whether status asks the client to go to the Location
+func isRedirect(status int) bool {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// requestError flattens client errors so policy violations read the same
+// whether they came from the original URL or a redirect
+func requestError(err error) string {
+	var policyErr *domainPolicyError
+	if errors.As(err, &policyErr) {
+		return fmt.Sprintf("redirect blocked: %v", policyErr)
+	}
+	var addressErr *privateAddressError
+	if errors.As(err, &addressErr) {
+		return fmt.Sprintf("request blocked: %v", addressErr)
+	}
+	return fmt.Sprintf("request failed: %v", err)
+}
+
+// readContent reads at most maxSize bytes, failing rather than truncating
+// when the body is larger
 func (wa *WebAgent) readContent(body io.ReadCloser, maxSize int64) (string, error) {
-	limiter := io.LimitReader(body, maxSize)
+	limiter := io.LimitReader(body, maxSize+1)
 	content, err := io.ReadAll(limiter)
 	if err != nil {
 		return "", err
 	}
+	if int64(len(content)) > maxSize {
+		return "", &bodyTooLargeError{limit: maxSize}
+	}
 	return string(content), nil
 }
 
+// plainContent wraps a non-HTML body in the same shape as extracted HTML
+func (wa *WebAgent) plainContent(content, urlStr string, maxTokens int) map[string]interface{} {
+	tokens := wa.estimateTokens(content)
+	truncated := false
+	if tokens > maxTokens {
+		content = wa.tokenizer.Truncate(content, maxTokens)
+		tokens = wa.estimateTokens(content)
+		truncated = true
+	}
+
+	return map[string]interface{}{
+		"url":          urlStr,
+		"main_content": content,
+		"token_count":  tokens,
+		"truncated":    truncated,
+		"word_count":   wa.countWords(content),
+	}
+}
+
+func isHTML(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	return strings.Contains(contentType, "text/html") || strings.Contains(contentType, "application/xhtml")
+}
+
 func (wa *WebAgent) isAllowedDomain(hostname string) bool {
 	if hostname == "" {
 		return false
@@ -192,7 +620,7 @@ func (wa *WebAgent) isAllowedDomain(hostname string) bool {
 
 	// Check blocked domains first
 	for _, blocked := range wa.blockedDomains {
-		if strings.HasSuffix(hostname, strings.TrimPrefix(blocked, ".")) {
+		if domainMatches(hostname, blocked) {
 			return false
 		}
 	}
@@ -204,7 +632,7 @@ func (wa *WebAgent) isAllowedDomain(hostname string) bool {
 
 	// Check allowed domains
 	for _, allowed := range wa.allowedDomains {
-		if allowed == "*" || strings.HasSuffix(hostname, strings.TrimPrefix(allowed, ".")) {
+		if domainMatches(hostname, allowed) {
 			return true
 		}
 	}
@@ -212,11 +640,38 @@ func (wa *WebAgent) isAllowedDomain(hostname string) bool {
 	return false
 }
 
+// domainMatches reports whether hostname matches pattern. A plain domain
+// matches itself and its subdomains, "*.example.com" or ".example.com" only
+// subdomains, "ads.*" any domain starting with that label, and "*" everything.
+func domainMatches(hostname, pattern string) bool {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+
+	switch {
+	case pattern == "*":
+		return true
+	case strings.HasPrefix(pattern, "*."):
+		return strings.HasSuffix(hostname, pattern[1:])
+	case strings.HasPrefix(pattern, "."):
+		return strings.HasSuffix(hostname, pattern)
+	case strings.HasSuffix(pattern, ".*"):
+		return strings.HasPrefix(hostname, pattern[:len(pattern)-1])
+	default:
+		return hostname == pattern || strings.HasSuffix(hostname, "."+pattern)
+	}
+}
+
+// isAllowedContentType reports whether the base type of a Content-Type header,
+// without its parameters, is in the allowlist. Entries may be exact types,
+// "text/*" for every subtype, or "*/*" for any type. A response without a
+// Content-Type is not allowed.
 func (wa *WebAgent) isAllowedContentType(contentType string) bool {
-	contentType = strings.ToLower(contentType)
+	base := baseContentType(contentType)
+	if base == "" {
+		return false
+	}
 
 	for _, allowed := range wa.allowedContentTypes {
-		if strings.Contains(contentType, allowed) {
+		if contentTypeMatches(base, allowed) {
 			return true
 		}
 	}
@@ -224,16 +679,63 @@ func (wa *WebAgent) isAllowedContentType(contentType string) bool {
 	return false
 }
 
+// baseContentType returns the lowercase media type of a Content-Type header,
+// such as "text/html" for "text/html; charset=utf-8"
+func baseContentType(contentType string) string {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		return mediaType
+	}
+	// Keep what precedes the parameters of a header mime cannot parse
+	base, _, _ := strings.Cut(contentType, ";")
+	return strings.ToLower(strings.TrimSpace(base))
+}
+
+// contentTypeMatches reports whether a base content type matches an
+// allowlist entry
+func contentTypeMatches(base, pattern string) bool {
+	pattern = baseContentType(pattern)
+	switch {
+	case pattern == "*/*" || pattern == "*":
+		return true
+	case strings.HasSuffix(pattern, "/*"):
+		return strings.HasPrefix(base, pattern[:len(pattern)-1])
+	default:
+		return base == pattern
+	}
+}
+
+// getMaxTokens reads the per-request token budget, which may arrive as an int
+// or as a JSON number, and clamps it to the configured bounds
 func (wa *WebAgent) getMaxTokens(payload map[string]interface{}) int {
-	if maxTokens, ok := payload["max_tokens"].(int); ok {
-		// Enforce bounds
-		if maxTokens < wa.minAllowedTokens {
-			return wa.minAllowedTokens
-		}
-		if maxTokens > wa.maxAllowedTokens {
-			return wa.maxAllowedTokens
-		}
-		return maxTokens
+	var maxTokens int
+	switch v := payload["max_tokens"].(type) {
+	case int:
+		maxTokens = v
+	case float64:
+		maxTokens = int(v)
+	default:
+		return wa.defaultMaxTokens
+	}
+
+	// Enforce bounds
+	if maxTokens < wa.minAllowedTokens {
+		return wa.minAllowedTokens
+	}
+	if maxTokens > wa.maxAllowedTokens {
+		return wa.maxAllowedTokens
+	}
+	return maxTokens
+}
+
+// getFormat reads the per-request main_content format, text unless markdown
+// is asked for
+func getFormat(payload map[string]interface{}) (string, error) {
+	format, _ := payload["format"].(string)
+	switch format {
+	case "", formatText:
+		return formatText, nil
+	case formatMarkdown:
+		return formatMarkdown, nil
 	}
-	return wa.defaultMaxTokens
+	return "", fmt.Errorf("invalid format %q (expected text or markdown)", format)
 }
@@ -0,0 +1,412 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+const testPage = `<html><head><title>Test Page</title></head>
+<body><main><h1>Welcome</h1><p>Some useful article text for the reader.</p></main></body></html>`
+
+// newTestServer serves an HTML page, a large body, a JSON document,
+// redirects to the same server and to the same port on localhost, a chain of
+// redirects, and a redirect loop
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	port := server.URL[strings.LastIndex(server.URL, ":")+1:]
+
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, testPage)
+	})
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		// Stream without a Content-Length so the reader has to enforce the limit
+		for i := 0; i < 64; i++ {
+			fmt.Fprint(w, strings.Repeat("x", 1024))
+			w.(http.Flusher).Flush()
+		}
+	})
+	mux.HandleFunc("/binary", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write([]byte{0, 1, 2})
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/page", http.StatusFound)
+	})
+	mux.HandleFunc("/redirect-blocked", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://localhost:"+port+"/page", http.StatusFound)
+	})
+	// /hop/N redirects N more times before reaching /page
+	mux.HandleFunc("/hop/", func(w http.ResponseWriter, r *http.Request) {
+		var n int
+		fmt.Sscanf(strings.TrimPrefix(r.URL.Path, "/hop/"), "%d", &n)
+		if n == 0 {
+			http.Redirect(w, r, "/page", http.StatusFound)
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/hop/%d", n-1), http.StatusFound)
+	})
+	mux.HandleFunc("/loop-a", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop-b", http.StatusFound)
+	})
+	mux.HandleFunc("/loop-b", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop-a", http.StatusFound)
+	})
+
+	return server
+}
+
+func newTestAgent() *WebAgent {
+	wa := NewWebAgent()
+	wa.blockedDomains = []string{"localhost"}
+	wa.maxBodySize = 16 * 1024
+	wa.limiter = nil
+	// httptest servers listen on loopback
+	wa.allowPrivateNetworks = true
+	return wa
+}
+
+func process(t *testing.T, wa *WebAgent, opType string, payload map[string]interface{}) interfaces.AgentOutput {
+	t.Helper()
+
+	output, err := wa.Process(t.Context(), interfaces.AgentInput{Type: opType, Payload: payload})
+	if err != nil {
+		t.Fatalf("Process returned error: %v", err)
+	}
+	return output
+}
+
+func TestFetch_FollowsAllowedRedirect(t *testing.T) {
+	server := newTestServer(t)
+	wa := newTestAgent()
+
+	output := process(t, wa, "fetch", map[string]interface{}{"url": server.URL + "/redirect"})
+	if !output.Success {
+		t.Fatalf("Expected fetch to succeed, got: %s", output.Error)
+	}
+
+	if output.Data["title"] != "Test Page" {
+		t.Errorf("Expected extracted title, got %v", output.Data["title"])
+	}
+	if output.Data["final_url"] != server.URL+"/page" {
+		t.Errorf("Expected final URL after redirect, got %v", output.Data["final_url"])
+	}
+}
+
+func TestFetch_RedirectChain(t *testing.T) {
+	server := newTestServer(t)
+	wa := newTestAgent()
+
+	output := process(t, wa, "fetch", map[string]interface{}{"url": server.URL + "/hop/1"})
+	if !output.Success {
+		t.Fatalf("Expected fetch to succeed, got: %s", output.Error)
+	}
+	expected := []string{server.URL + "/hop/1", server.URL + "/hop/0"}
+	if chain, _ := output.Data["redirect_chain"].([]string); !reflect.DeepEqual(chain, expected) {
+		t.Errorf("Expected redirect chain %v, got %v", expected, output.Data["redirect_chain"])
+	}
+	if output.Data["final_url"] != server.URL+"/page" {
+		t.Errorf("Expected final URL after redirects, got %v", output.Data["final_url"])
+	}
+
+	output = process(t, wa, "fetch", map[string]interface{}{"url": server.URL + "/page"})
+	if _, ok := output.Data["redirect_chain"]; ok {
+		t.Errorf("Expected no redirect chain without redirects, got %v", output.Data["redirect_chain"])
+	}
+}
+
+func TestFetch_RedirectLimits(t *testing.T) {
+	server := newTestServer(t)
+	wa := newTestAgent()
+	wa.maxRedirects = 2
+
+	output := process(t, wa, "fetch", map[string]interface{}{"url": server.URL + "/hop/1"})
+	if !output.Success {
+		t.Errorf("Expected two redirects to be followed, got: %s", output.Error)
+	}
+
+	output = process(t, wa, "fetch", map[string]interface{}{"url": server.URL + "/hop/2"})
+	if output.Success || !strings.Contains(output.Error, "too many redirects: limit is 2") {
+		t.Errorf("Expected the redirect limit to stop the fetch, got success=%v error=%q", output.Success, output.Error)
+	}
+
+	wa.maxRedirects = defaultMaxRedirects
+	output = process(t, wa, "fetch", map[string]interface{}{"url": server.URL + "/loop-a"})
+	if output.Success || !strings.Contains(output.Error, "redirect loop") {
+		t.Errorf("Expected a redirect loop to be detected, got success=%v error=%q", output.Success, output.Error)
+	}
+}
+
+func TestFetch_NoFollowRedirects(t *testing.T) {
+	server := newTestServer(t)
+	wa := newTestAgent()
+	wa.followRedirects = false
+
+	output := process(t, wa, "fetch", map[string]interface{}{"url": server.URL + "/redirect"})
+	if !output.Success {
+		t.Fatalf("Expected the redirect to be returned, got: %s", output.Error)
+	}
+	if output.Data["status_code"] != http.StatusFound || output.Data["location"] != "/page" {
+		t.Errorf("Expected the 302 and its Location, got %v", output.Data)
+	}
+
+	output = process(t, wa, "validate", map[string]interface{}{"url": server.URL + "/redirect"})
+	if output.Data["status_code"] != http.StatusFound || output.Data["final_url"] != server.URL+"/redirect" {
+		t.Errorf("Expected validate not to follow the redirect, got %v", output.Data)
+	}
+}
+
+func TestFetch_Raw(t *testing.T) {
+	server := newTestServer(t)
+	wa := newTestAgent()
+
+	output := process(t, wa, "fetch", map[string]interface{}{"url": server.URL + "/page", "raw": true})
+	if !output.Success {
+		t.Fatalf("Expected fetch to succeed, got: %s", output.Error)
+	}
+	if output.Data["body"] != testPage || output.Data["size"] != len(testPage) {
+		t.Errorf("Expected the unmodified body, got %v", output.Data["body"])
+	}
+	if output.Data["status_code"] != http.StatusOK || output.Data["content_type"] != "text/html; charset=utf-8" {
+		t.Errorf("Expected status and content type, got %v", output.Data)
+	}
+	if _, ok := output.Data["main_content"]; ok {
+		t.Error("Expected no extraction for a raw fetch")
+	}
+
+	// The content type policy still applies
+	output = process(t, wa, "fetch", map[string]interface{}{"url": server.URL + "/binary", "raw": true})
+	if output.Success || !strings.Contains(output.Error, "content type not allowed") {
+		t.Errorf("Expected a disallowed type to be rejected, got success=%v error=%q", output.Success, output.Error)
+	}
+}
+
+func TestFetch_BlockedDomains(t *testing.T) {
+	server := newTestServer(t)
+	wa := newTestAgent()
+
+	output := process(t, wa, "fetch", map[string]interface{}{"url": server.URL + "/redirect-blocked"})
+	if output.Success || !strings.Contains(output.Error, "domain not allowed: localhost") {
+		t.Errorf("Expected redirect to a blocked domain to abort, got success=%v error=%q", output.Success, output.Error)
+	}
+
+	localURL, _ := url.Parse(server.URL)
+	localURL.Host = "localhost:" + localURL.Port()
+	output = process(t, wa, "fetch", map[string]interface{}{"url": localURL.String() + "/page"})
+	if output.Success || !strings.Contains(output.Error, "domain not allowed") {
+		t.Errorf("Expected blocked domain to be rejected, got success=%v error=%q", output.Success, output.Error)
+	}
+
+	wa.blockedDomains = nil
+	wa.allowedDomains = []string{"example.com"}
+	output = process(t, wa, "fetch", map[string]interface{}{"url": server.URL + "/page"})
+	if output.Success {
+		t.Error("Expected domain outside the allowlist to be rejected")
+	}
+}
+
+func TestFetch_RefusesPrivateAddresses(t *testing.T) {
+	server := newTestServer(t)
+	wa := newTestAgent()
+	wa.allowPrivateNetworks = false
+
+	// The host passes the domain policy but resolves to loopback
+	output := process(t, wa, "fetch", map[string]interface{}{"url": server.URL + "/page"})
+	if output.Success || !strings.Contains(output.Error, "address not allowed: 127.0.0.1") {
+		t.Errorf("Expected loopback target to be refused, got success=%v error=%q", output.Success, output.Error)
+	}
+}
+
+func TestFetch_RejectsOversizedAndDisallowedContent(t *testing.T) {
+	server := newTestServer(t)
+	wa := newTestAgent()
+
+	output := process(t, wa, "fetch", map[string]interface{}{"url": server.URL + "/large"})
+	if output.Success || !strings.Contains(output.Error, "exceeds") {
+		t.Errorf("Expected oversized body to be rejected, got success=%v error=%q", output.Success, output.Error)
+	}
+
+	output = process(t, wa, "fetch", map[string]interface{}{"url": server.URL + "/binary"})
+	if output.Success || !strings.Contains(output.Error, "content type not allowed") {
+		t.Errorf("Expected binary content to be rejected, got success=%v error=%q", output.Success, output.Error)
+	}
+}
+
+func TestFetch_ContentTypeAllowlist(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", r.URL.Query().Get("type"))
+		fmt.Fprint(w, "body")
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		allowed     []string
+		contentType string
+		rejected    string
+	}{
+		{"exact match ignores charset", []string{"text/plain"}, "text/plain; charset=utf-8", ""},
+		{"match ignores case", []string{"application/json"}, "Application/JSON", ""},
+		{"wildcard subtype", []string{"text/*"}, "text/csv", ""},
+		{"wildcard covers only its type", []string{"text/*"}, "application/pdf", "application/pdf"},
+		{"binary download", []string{"text/html"}, "application/octet-stream", "application/octet-stream"},
+		{"no substring matches", []string{"text/html"}, "text/htmlx", "text/htmlx"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wa := newTestAgent()
+			wa.allowedContentTypes = tt.allowed
+
+			output := process(t, wa, "fetch", map[string]interface{}{
+				"url": server.URL + "/?type=" + url.QueryEscape(tt.contentType),
+				"raw": true,
+			})
+			if tt.rejected == "" {
+				if !output.Success {
+					t.Fatalf("Expected %q to be allowed, got %q", tt.contentType, output.Error)
+				}
+				return
+			}
+			if output.Success || output.Data["rejected_content_type"] != tt.rejected {
+				t.Errorf("Expected %q to be rejected, got success=%v data=%v", tt.rejected, output.Success, output.Data)
+			}
+			if _, ok := output.Data["body"]; ok {
+				t.Error("Expected a rejected body not to be read")
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	server := newTestServer(t)
+	wa := newTestAgent()
+
+	output := process(t, wa, "validate", map[string]interface{}{"url": server.URL + "/redirect"})
+	if !output.Success {
+		t.Fatalf("Expected validate to succeed, got: %s", output.Error)
+	}
+	if output.Data["valid"] != true || output.Data["status_code"] != http.StatusOK {
+		t.Errorf("Expected a valid 200 response, got %v", output.Data)
+	}
+	if output.Data["final_url"] != server.URL+"/page" {
+		t.Errorf("Expected final URL after redirect, got %v", output.Data["final_url"])
+	}
+	if !strings.HasPrefix(output.Data["content_type"].(string), "text/html") {
+		t.Errorf("Expected HTML content type, got %v", output.Data["content_type"])
+	}
+
+	output = process(t, wa, "validate", map[string]interface{}{"url": server.URL + "/redirect-blocked"})
+	if output.Data["valid"] != false || output.Data["domain_allowed"] != false {
+		t.Errorf("Expected redirect to a blocked domain to be invalid, got %v", output.Data)
+	}
+}
+
+func TestExtract_RawHTML(t *testing.T) {
+	wa := newTestAgent()
+
+	output := process(t, wa, "extract", map[string]interface{}{
+		"html":       testPage,
+		"max_tokens": float64(1000),
+	})
+	if !output.Success {
+		t.Fatalf("Expected extract to succeed, got: %s", output.Error)
+	}
+	if output.Data["title"] != "Test Page" {
+		t.Errorf("Expected extracted title, got %v", output.Data["title"])
+	}
+	if !strings.Contains(output.Data["main_content"].(string), "useful article text") {
+		t.Errorf("Expected main content, got %v", output.Data["main_content"])
+	}
+
+	output = process(t, wa, "extract", map[string]interface{}{})
+	if output.Success {
+		t.Error("Expected extract without html or url to fail")
+	}
+}
+
+func TestGetMaxTokens_Clamped(t *testing.T) {
+	wa := newTestAgent()
+
+	testCases := []struct {
+		value    interface{}
+		expected int
+	}{
+		{nil, wa.defaultMaxTokens},
+		{float64(10), wa.minAllowedTokens},
+		{1000000, wa.maxAllowedTokens},
+		{float64(2000), 2000},
+	}
+
+	for _, tc := range testCases {
+		payload := map[string]interface{}{}
+		if tc.value != nil {
+			payload["max_tokens"] = tc.value
+		}
+		if got := wa.getMaxTokens(payload); got != tc.expected {
+			t.Errorf("getMaxTokens(%v) = %d, expected %d", tc.value, got, tc.expected)
+		}
+	}
+}
+
+func TestDomainMatches(t *testing.T) {
+	testCases := []struct {
+		host, pattern string
+		expected      bool
+	}{
+		{"example.com", "example.com", true},
+		{"docs.example.com", "example.com", true},
+		{"badexample.com", "example.com", false},
+		{"docs.example.com", "*.example.com", true},
+		{"example.com", "*.example.com", false},
+		{"ads.tracker.net", "ads.*", true},
+		{"myads.tracker.net", "ads.*", false},
+		{"anything.org", "*", true},
+	}
+
+	for _, tc := range testCases {
+		if got := domainMatches(tc.host, tc.pattern); got != tc.expected {
+			t.Errorf("domainMatches(%q, %q) = %v, expected %v", tc.host, tc.pattern, got, tc.expected)
+		}
+	}
+}
+
+func TestIsInternalIP(t *testing.T) {
+	testCases := []struct {
+		ip       string
+		expected bool
+	}{
+		{"127.0.0.1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"100.64.0.1", true},
+		{"0.0.0.0", true},
+		{"::1", true},
+		{"fe80::1", true},
+		{"fd00::1", true},
+		{"93.184.216.34", false},
+		{"2606:2800:220:1::1", false},
+	}
+
+	for _, tc := range testCases {
+		if got := isInternalIP(net.ParseIP(tc.ip)); got != tc.expected {
+			t.Errorf("isInternalIP(%s) = %v, expected %v", tc.ip, got, tc.expected)
+		}
+	}
+}
@@ -1,10 +1,21 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
+	"net/url"
 	"regexp"
 	"strings"
-	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Output formats for main_content
+const (
+	formatText     = "text"
+	formatMarkdown = "markdown"
 )
 
 type ExtractedContent struct {
@@ -18,6 +29,9 @@ type ExtractedContent struct {
 	TokenCount  int               `json:"token_count"`
 	Truncated   bool              `json:"truncated"`
 	WordCount   int               `json:"word_count"`
+
+	StructuredData []map[string]interface{} `json:"structured_data,omitempty"`
+	OpenGraph      map[string]string        `json:"open_graph,omitempty"`
 }
 
 type Heading struct {
@@ -31,7 +45,35 @@ type Link struct {
 	Type string `json:"type"` // "internal", "external", "special"
 }
 
-func (wa *WebAgent) extractAndOptimizeContent(htmlContent, urlStr string, maxTokens int) map[string]interface{} {
+// droppedTags are removed with their whole subtree before extraction
+var droppedTags = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Template: true,
+	atom.Svg:      true,
+	atom.Iframe:   true,
+	atom.Nav:      true,
+	atom.Header:   true,
+	atom.Footer:   true,
+	atom.Aside:    true,
+	atom.Form:     true,
+	atom.Button:   true,
+	atom.Select:   true,
+	atom.Textarea: true,
+}
+
+var (
+	// unlikelyCandidate matches class and id values of page furniture that is
+	// dropped unless it also looks like content
+	unlikelyCandidate = regexp.MustCompile(`(?i)banner|breadcrumb|comment|cookie|disqus|footer|gdpr|masthead|menu|modal|pagination|popup|related|share|sidebar|social|sponsor|subscribe|widget`)
+	maybeCandidate    = regexp.MustCompile(`(?i)and|article|body|column|content|main|post`)
+
+	positiveWeight = regexp.MustCompile(`(?i)article|body|content|entry|hentry|main|page|post|text|blog|story`)
+	negativeWeight = regexp.MustCompile(`(?i)hidden|banner|combx|comment|contact|foot|footnote|masthead|media|meta|promo|related|scroll|share|shoutbox|sidebar|sponsor|tags|tool|widget`)
+)
+
+func (wa *WebAgent) extractAndOptimizeContent(htmlContent, urlStr string, maxTokens int, format string) map[string]interface{} {
 	content := &ExtractedContent{
 		URL:   urlStr,
 		Links: []Link{},
@@ -41,27 +83,42 @@ func (wa *WebAgent) extractAndOptimizeContent(htmlContent, urlStr string, maxTok
 		},
 	}
 
-	// Extract title
-	content.Title = wa.extractTitle(htmlContent)
+	// html.Parse recovers from malformed markup the way browsers do and only
+	// fails if reading the input fails, which cannot happen for a string
+	doc, err := html.Parse(strings.NewReader(htmlContent))
+	if err != nil {
+		doc = &html.Node{Type: html.DocumentNode}
+	}
+	base, _ := url.Parse(urlStr)
 
-	// Extract meta description
-	content.Description = wa.extractMetaDescription(htmlContent)
+	// Title and description live in <head>, which pruning leaves alone
+	content.Title = wa.extractTitle(doc)
+	content.Description = wa.extractMetaDescription(doc)
 
-	// Extract headings
-	content.Headings = wa.extractHeadings(htmlContent)
+	// JSON-LD sits in script tags, so it is read before pruning drops them
+	content.StructuredData = extractStructuredData(doc)
+	content.OpenGraph = extractOpenGraph(doc)
 
-	// Extract main content (simplified approach)
-	content.MainContent = wa.extractMainContent(htmlContent)
+	pruneNode(doc)
+
+	if content.Title == "" {
+		if h1 := findFirst(doc, atom.H1); h1 != nil {
+			content.Title = wa.cleanText(textContent(h1))
+		}
+	}
+
+	content.Headings = wa.extractHeadings(doc)
+	content.MainContent = wa.extractMainContent(doc, base, format)
 
 	// Extract links if enabled
 	if wa.includeLinks {
-		content.Links = wa.extractLinks(htmlContent, urlStr)
+		content.Links = wa.extractLinks(doc, base)
 	}
 
 	// Add metadata if enabled
 	if wa.includeMetadata {
 		content.Metadata["content_length"] = fmt.Sprintf("%d", len(htmlContent))
-		content.Metadata["content_density"] = wa.calculateContentDensity(htmlContent)
+		content.Metadata["content_density"] = wa.calculateContentDensity(doc, len(htmlContent))
 	}
 
 	// Count words and estimate tokens
@@ -95,136 +152,170 @@ func (wa *WebAgent) extractAndOptimizeContent(htmlContent, urlStr string, maxTok
 		result["metadata"] = content.Metadata
 	}
 
+	if len(content.StructuredData) > 0 {
+		result["structured_data"] = content.StructuredData
+	}
+	if len(content.OpenGraph) > 0 {
+		result["open_graph"] = content.OpenGraph
+	}
+
 	return result
 }
 
-func (wa *WebAgent) extractTitle(html string) string {
-	// Simple regex to extract title
-	titleRegex := regexp.MustCompile(`(?i)<title[^>]*>([^<]+)</title>`)
-	matches := titleRegex.FindStringSubmatch(html)
-	if len(matches) > 1 {
-		return wa.cleanText(matches[1])
+func (wa *WebAgent) extractTitle(doc *html.Node) string {
+	if title := findFirst(doc, atom.Title); title != nil {
+		return wa.cleanText(textContent(title))
 	}
 	return ""
 }
 
-func (wa *WebAgent) extractMetaDescription(html string) string {
-	// Extract meta description
-	descRegex := regexp.MustCompile(`(?i)<meta[^>]+name=["\']description["\'][^>]+content=["\']([^"\']+)["\']`)
-	matches := descRegex.FindStringSubmatch(html)
-	if len(matches) > 1 {
-		return wa.cleanText(matches[1])
+// extractMetaDescription reads the description meta tag, falling back to the
+// Open Graph description
+func (wa *WebAgent) extractMetaDescription(doc *html.Node) string {
+	var description, ogDescription string
+	walk(doc, func(n *html.Node) bool {
+		if n.Type != html.ElementNode || n.DataAtom != atom.Meta {
+			return true
+		}
+		switch {
+		case strings.EqualFold(attr(n, "name"), "description") && description == "":
+			description = attr(n, "content")
+		case strings.EqualFold(attr(n, "property"), "og:description") && ogDescription == "":
+			ogDescription = attr(n, "content")
+		}
+		return true
+	})
+
+	if description == "" {
+		description = ogDescription
 	}
-	return ""
+	return wa.cleanText(description)
 }
 
-func (wa *WebAgent) extractHeadings(html string) []Heading {
-	var headings []Heading
+// extractStructuredData decodes the JSON-LD script blocks of the page. A
+// block holding an array or an @graph contributes each of its objects.
+// Blocks that are not valid JSON are skipped.
+func extractStructuredData(doc *html.Node) []map[string]interface{} {
+	var items []map[string]interface{}
+	walk(doc, func(n *html.Node) bool {
+		if n.Type != html.ElementNode || n.DataAtom != atom.Script {
+			return true
+		}
+		scriptType := strings.TrimSpace(strings.SplitN(attr(n, "type"), ";", 2)[0])
+		if !strings.EqualFold(scriptType, "application/ld+json") {
+			return false
+		}
 
-	// Match h1-h6 tags
-	headingRegex := regexp.MustCompile(`(?i)<h([1-6])[^>]*>([^<]+)</h[1-6]>`)
-	matches := headingRegex.FindAllStringSubmatch(html, -1)
-
-	for _, match := range matches {
-		if len(match) > 2 {
-			level := 0
-			fmt.Sscanf(match[1], "%d", &level)
-			text := wa.cleanText(match[2])
-			if text != "" && level > 0 {
-				headings = append(headings, Heading{
-					Level: level,
-					Text:  text,
-				})
-			}
+		var value interface{}
+		if err := json.Unmarshal([]byte(textContent(n)), &value); err != nil {
+			return false
 		}
+		items = appendJSONLD(items, value)
+		return false
+	})
+	return items
+}
+
+// appendJSONLD appends the objects of a decoded JSON-LD value to items,
+// flattening arrays and top-level @graph lists
+func appendJSONLD(items []map[string]interface{}, value interface{}) []map[string]interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			items = appendJSONLD(items, item)
+		}
+	case map[string]interface{}:
+		if graph, ok := v["@graph"].([]interface{}); ok {
+			return appendJSONLD(items, graph)
+		}
+		items = append(items, v)
 	}
+	return items
+}
 
-	return headings
+// extractOpenGraph collects the og: and twitter: meta tags, keyed by their
+// property or name. The first tag for a key wins.
+func extractOpenGraph(doc *html.Node) map[string]string {
+	tags := map[string]string{}
+	walk(doc, func(n *html.Node) bool {
+		if n.Type != html.ElementNode || n.DataAtom != atom.Meta {
+			return true
+		}
+		key := attr(n, "property")
+		if key == "" {
+			key = attr(n, "name")
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		if !strings.HasPrefix(key, "og:") && !strings.HasPrefix(key, "twitter:") {
+			return true
+		}
+		if _, seen := tags[key]; !seen {
+			tags[key] = strings.TrimSpace(attr(n, "content"))
+		}
+		return true
+	})
+	return tags
 }
 
-func (wa *WebAgent) extractMainContent(html string) string {
-	// Remove script, style, nav, header, footer elements
-	replacements := []struct {
-		pattern *regexp.Regexp
-	}{
-		{regexp.MustCompile(`(?i)<script[^>]*>.*?</script>`)},
-		{regexp.MustCompile(`(?i)<style[^>]*>.*?</style>`)},
-		{regexp.MustCompile(`(?i)<nav[^>]*>.*?</nav>`)},
-		{regexp.MustCompile(`(?i)<header[^>]*>.*?</header>`)},
-		{regexp.MustCompile(`(?i)<footer[^>]*>.*?</footer>`)},
-		{regexp.MustCompile(`(?i)<aside[^>]*>.*?</aside>`)},
-		{regexp.MustCompile(`(?i)<form[^>]*>.*?</form>`)},
-	}
-
-	cleaned := html
-	for _, repl := range replacements {
-		cleaned = repl.pattern.ReplaceAllString(cleaned, "")
-	}
-
-	// Look for common content containers
-	contentSelectors := []string{
-		`(?i)<main[^>]*>(.*?)</main>`,
-		`(?i)<article[^>]*>(.*?)</article>`,
-		`(?i)<div[^>]+class="[^"]*content[^"]*"[^>]*>(.*?)</div>`,
-		`(?i)<div[^>]+id="[^"]*content[^"]*"[^>]*>(.*?)</div>`,
-		`(?i)<div[^>]+class="[^"]*post[^"]*"[^>]*>(.*?)</div>`,
-		`(?i)<div[^>]+class="[^"]*entry[^"]*"[^>]*>(.*?)</div>`,
-	}
-
-	for _, pattern := range contentSelectors {
-		re := regexp.MustCompile(pattern)
-		matches := re.FindStringSubmatch(cleaned)
-		if len(matches) > 1 {
-			cleaned = matches[1]
-			break
+func (wa *WebAgent) extractHeadings(doc *html.Node) []Heading {
+	var headings []Heading
+
+	walk(doc, func(n *html.Node) bool {
+		level := headingLevel(n)
+		if level == 0 {
+			return true
 		}
-	}
+		if text := wa.cleanText(textContent(n)); text != "" {
+			headings = append(headings, Heading{
+				Level: level,
+				Text:  text,
+			})
+		}
+		return false
+	})
 
-	// Remove all HTML tags
-	htmlTagRegex := regexp.MustCompile(`<[^>]+>`)
-	text := htmlTagRegex.ReplaceAllString(cleaned, " ")
+	return headings
+}
 
-	// Clean and normalize text
-	return wa.cleanText(text)
+// extractMainContent picks the main content node of a pruned document and
+// renders it, along with any sibling content, as text or markdown
+func (wa *WebAgent) extractMainContent(doc *html.Node, base *url.URL, format string) string {
+	r := &renderer{markdown: format == formatMarkdown, base: base}
+	for _, node := range findMainContent(doc) {
+		r.block(node)
+	}
+	return r.String()
 }
 
-func (wa *WebAgent) extractLinks(html string, baseUrl string) []Link {
+func (wa *WebAgent) extractLinks(doc *html.Node, base *url.URL) []Link {
 	var links []Link
 
-	// Extract links
-	linkRegex := regexp.MustCompile(`(?i)<a[^>]+href=["\']([^"\']+)["\'][^>]*>([^<]*)</a>`)
-	matches := linkRegex.FindAllStringSubmatch(html, -1)
-
-	for _, match := range matches {
-		if len(match) > 2 {
-			url := match[1]
-			text := wa.cleanText(match[2])
+	walk(doc, func(n *html.Node) bool {
+		if n.Type != html.ElementNode || n.DataAtom != atom.A {
+			return true
+		}
 
-			// Skip empty or navigation links
-			if text == "" || len(text) < 3 {
-				continue
-			}
+		href := strings.TrimSpace(attr(n, "href"))
+		text := wa.cleanText(textContent(n))
 
-			// Determine link type
-			linkType := "external"
-			if strings.HasPrefix(url, "#") || strings.HasPrefix(url, "/") {
-				linkType = "internal"
-			} else if strings.HasPrefix(url, "mailto:") || strings.HasPrefix(url, "tel:") {
-				linkType = "special"
-			}
+		// Skip empty or navigation links
+		if href == "" || text == "" || len(text) < 3 || strings.HasPrefix(href, "javascript:") {
+			return false
+		}
 
-			// Skip common navigation links
-			if wa.isNavigationLink(text) {
-				continue
-			}
+		// Determine link type
+		linkType := linkTypeOf(href, base)
 
+		// Skip common navigation links
+		if !wa.isNavigationLink(text) {
 			links = append(links, Link{
 				Text: text,
-				URL:  url,
+				URL:  href,
 				Type: linkType,
 			})
 		}
-	}
+		return false
+	})
 
 	// Limit to top links to save tokens
 	if len(links) > 10 {
@@ -234,19 +325,31 @@ func (wa *WebAgent) extractLinks(html string, baseUrl string) []Link {
 	return links
 }
 
-func (wa *WebAgent) cleanText(text string) string {
-	// Remove extra whitespace
-	text = regexp.MustCompile(`\s+`).ReplaceAllString(text, " ")
-	text = strings.TrimSpace(text)
+// linkTypeOf classifies href as internal when it is relative or points at
+// the page's own host, special for mailto and tel links, and external
+// otherwise
+func linkTypeOf(href string, base *url.URL) string {
+	if strings.HasPrefix(href, "mailto:") || strings.HasPrefix(href, "tel:") {
+		return "special"
+	}
 
-	// Remove common HTML entities
-	text = strings.ReplaceAll(text, "&nbsp;", " ")
-	text = strings.ReplaceAll(text, "&amp;", "&")
-	text = strings.ReplaceAll(text, "&lt;", "<")
-	text = strings.ReplaceAll(text, "&gt;", ">")
-	text = strings.ReplaceAll(text, "&quot;", "\"")
+	ref, err := url.Parse(href)
+	if err != nil {
+		return "external"
+	}
+	if !ref.IsAbs() && ref.Host == "" {
+		return "internal"
+	}
+	if base != nil && base.Host != "" && strings.EqualFold(ref.Host, base.Host) {
+		return "internal"
+	}
+	return "external"
+}
 
-	return text
+// cleanText collapses runs of whitespace. Entities are already decoded by the
+// parser.
+func (wa *WebAgent) cleanText(text string) string {
+	return collapseSpace(text)
 }
 
 func (wa *WebAgent) countWords(text string) int {
@@ -255,37 +358,49 @@ func (wa *WebAgent) countWords(text string) int {
 }
 
 func (wa *WebAgent) estimateTokens(text string) int {
-	// Rough estimation: 1 token ≈ 4 characters for English text
-	// This is a simplification but works well for most content
-	charCount := utf8.RuneCountInString(text)
-	return charCount / 4
+	return wa.tokenizer.CountTokens(text)
 }
 
+// smartTruncate cuts text down to maxTokens keeping whole blocks where it can,
+// then whole sentences of the first block that does not fit
 func (wa *WebAgent) smartTruncate(text string, maxTokens int) string {
-	// Estimate target character count
-	targetChars := maxTokens * 4
-
-	if len(text) <= targetChars {
+	if wa.tokenizer.CountTokens(text) <= maxTokens {
 		return text
 	}
 
-	// Try to truncate at sentence boundaries
-	sentences := regexp.MustCompile(`[.!?]+\s+`).Split(text, -1)
-	var result string
+	var kept []string
+	used := 0
+	separatorTokens := wa.tokenizer.CountTokens("\n\n")
 
-	for _, sentence := range sentences {
-		if len(result)+len(sentence) > targetChars {
-			break
+	for _, block := range strings.Split(text, "\n\n") {
+		blockTokens := wa.tokenizer.CountTokens(block)
+		if len(kept) > 0 {
+			blockTokens += separatorTokens
 		}
-		if result != "" {
-			result += ". "
+		if used+blockTokens <= maxTokens {
+			kept = append(kept, block)
+			used += blockTokens
+			continue
 		}
-		result += strings.TrimSpace(sentence)
+
+		// Splitting a code block would leave an unterminated fence
+		if !strings.HasPrefix(block, "```") {
+			remaining := maxTokens - used
+			if len(kept) > 0 {
+				remaining -= separatorTokens
+			}
+			if partial := wa.truncateSentences(block, remaining); partial != "" {
+				kept = append(kept, partial)
+			}
+		}
+		break
 	}
 
-	// If we couldn't get a good sentence break, fall back to character truncation
+	result := strings.Join(kept, "\n\n")
+
+	// If we couldn't get a good sentence break, fall back to token truncation
 	if len(result) == 0 {
-		result = text[:targetChars]
+		result = wa.tokenizer.Truncate(text, maxTokens)
 		lastSpace := strings.LastIndex(result, " ")
 		if lastSpace > 0 {
 			result = result[:lastSpace]
@@ -295,6 +410,33 @@ func (wa *WebAgent) smartTruncate(text string, maxTokens int) string {
 	return result + " [...]"
 }
 
+// truncateSentences returns the leading sentences of text that fit in
+// maxTokens
+func (wa *WebAgent) truncateSentences(text string, maxTokens int) string {
+	sentences := regexp.MustCompile(`[.!?]+\s+`).Split(text, -1)
+	var result string
+	resultTokens := 0
+	separatorTokens := wa.tokenizer.CountTokens(". ")
+
+	for _, sentence := range sentences {
+		sentence = strings.TrimSpace(sentence)
+		sentenceTokens := wa.tokenizer.CountTokens(sentence)
+		if result != "" {
+			sentenceTokens += separatorTokens
+		}
+		if resultTokens+sentenceTokens > maxTokens {
+			break
+		}
+		if result != "" {
+			result += ". "
+		}
+		result += sentence
+		resultTokens += sentenceTokens
+	}
+
+	return result
+}
+
 func (wa *WebAgent) isNavigationLink(text string) bool {
 	lowerText := strings.ToLower(text)
 	navKeywords := []string{
@@ -312,16 +454,18 @@ func (wa *WebAgent) isNavigationLink(text string) bool {
 	return false
 }
 
-func (wa *WebAgent) calculateContentDensity(html string) string {
-	// Simple content density: text length vs HTML length
-	textRegex := regexp.MustCompile(`>[^<]+<`)
-	textMatches := textRegex.FindAllString(html, -1)
-	textLength := 0
-	for _, match := range textMatches {
-		textLength += len(strings.TrimSpace(match))
+// calculateContentDensity compares the visible text left after pruning with
+// the size of the raw HTML
+func (wa *WebAgent) calculateContentDensity(doc *html.Node, htmlLength int) string {
+	if htmlLength == 0 {
+		return "low"
 	}
 
-	density := float64(textLength) / float64(len(html)) * 100
+	body := findFirst(doc, atom.Body)
+	if body == nil {
+		return "low"
+	}
+	density := float64(len(collapseSpace(textContent(body)))) / float64(htmlLength) * 100
 
 	if density > 50 {
 		return "high"
@@ -331,3 +475,269 @@ func (wa *WebAgent) calculateContentDensity(html string) string {
 		return "low"
 	}
 }
+
+// pruneNode removes dropped tags, hidden elements, and unlikely candidates
+// from the tree rooted at n
+func pruneNode(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		switch {
+		case c.Type == html.CommentNode:
+			n.RemoveChild(c)
+		case c.Type == html.ElementNode && shouldPrune(c):
+			n.RemoveChild(c)
+		default:
+			pruneNode(c)
+		}
+		c = next
+	}
+}
+
+func shouldPrune(n *html.Node) bool {
+	if droppedTags[n.DataAtom] {
+		return true
+	}
+	if hasAttr(n, "hidden") || attr(n, "aria-hidden") == "true" {
+		return true
+	}
+
+	switch n.DataAtom {
+	case atom.Html, atom.Body, atom.Main, atom.Article, atom.A:
+		return false
+	}
+	matchString := attr(n, "class") + " " + attr(n, "id")
+	return unlikelyCandidate.MatchString(matchString) && !maybeCandidate.MatchString(matchString)
+}
+
+// findMainContent scores the containers of paragraph-like elements by their
+// text and link density, readability style, and returns the best one along
+// with any siblings that also look like content. Without a candidate it
+// falls back to <main>, <article>, or <body>.
+func findMainContent(doc *html.Node) []*html.Node {
+	scores := map[*html.Node]float64{}
+	var order []*html.Node
+
+	addScore := func(n *html.Node, score float64) {
+		if n == nil || n.Type != html.ElementNode {
+			return
+		}
+		if _, ok := scores[n]; !ok {
+			scores[n] = initialScore(n)
+			order = append(order, n)
+		}
+		scores[n] += score
+	}
+
+	walk(doc, func(n *html.Node) bool {
+		if !isScorable(n) {
+			return true
+		}
+		text := collapseSpace(textContent(n))
+		if len(text) < 25 {
+			return false
+		}
+
+		// One point for the paragraph, one per comma, and up to three for length
+		score := 1 + float64(strings.Count(text, ",")) + math.Min(float64(len(text)/100), 3)
+		addScore(n.Parent, score)
+		if n.Parent != nil {
+			addScore(n.Parent.Parent, score/2)
+		}
+		return false
+	})
+
+	var top *html.Node
+	topScore := 0.0
+	for _, n := range order {
+		scores[n] *= 1 - linkDensity(n)
+		if top == nil || scores[n] > topScore {
+			top, topScore = n, scores[n]
+		}
+	}
+
+	if top == nil {
+		for _, tag := range []atom.Atom{atom.Main, atom.Article, atom.Body} {
+			if n := findFirst(doc, tag); n != nil {
+				return []*html.Node{n}
+			}
+		}
+		return []*html.Node{doc}
+	}
+	if top.Parent == nil || top.DataAtom == atom.Body {
+		return []*html.Node{top}
+	}
+
+	// Content is often split across sibling containers, for example an intro
+	// div followed by the article body
+	threshold := math.Max(10, topScore*0.2)
+	var nodes []*html.Node
+	for sibling := top.Parent.FirstChild; sibling != nil; sibling = sibling.NextSibling {
+		if sibling.Type != html.ElementNode {
+			continue
+		}
+		if sibling == top {
+			nodes = append(nodes, sibling)
+			continue
+		}
+		if score, ok := scores[sibling]; ok && score >= threshold {
+			nodes = append(nodes, sibling)
+			continue
+		}
+		if sibling.DataAtom == atom.P {
+			text := collapseSpace(textContent(sibling))
+			if len(text) >= 80 && linkDensity(sibling) < 0.25 {
+				nodes = append(nodes, sibling)
+			}
+		}
+	}
+	return nodes
+}
+
+// isScorable reports whether n holds a paragraph of content: a p, pre, or
+// blockquote, or a div, section, or table cell used as one
+func isScorable(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+	switch n.DataAtom {
+	case atom.P, atom.Pre, atom.Blockquote:
+		return true
+	case atom.Div, atom.Section, atom.Td:
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && blockTags[c.DataAtom] {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// initialScore biases a candidate by its tag and its class and id names
+func initialScore(n *html.Node) float64 {
+	score := 0.0
+	switch n.DataAtom {
+	case atom.Article, atom.Main:
+		score += 10
+	case atom.Div, atom.Section:
+		score += 5
+	case atom.Pre, atom.Td, atom.Blockquote:
+		score += 3
+	case atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li, atom.Address:
+		score -= 3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		score -= 5
+	}
+
+	for _, value := range []string{attr(n, "class"), attr(n, "id")} {
+		if value == "" {
+			continue
+		}
+		if negativeWeight.MatchString(value) {
+			score -= 25
+		}
+		if positiveWeight.MatchString(value) {
+			score += 25
+		}
+	}
+	return score
+}
+
+// linkDensity is the share of n's text that sits inside links
+func linkDensity(n *html.Node) float64 {
+	textLength := len(collapseSpace(textContent(n)))
+	if textLength == 0 {
+		return 0
+	}
+
+	linkLength := 0
+	walk(n, func(c *html.Node) bool {
+		if c.Type == html.ElementNode && c.DataAtom == atom.A {
+			linkLength += len(collapseSpace(textContent(c)))
+			return false
+		}
+		return true
+	})
+	return float64(linkLength) / float64(textLength)
+}
+
+// walk calls visit for n and its descendants in document order, skipping
+// the children of nodes for which visit returns false
+func walk(n *html.Node, visit func(*html.Node) bool) {
+	if !visit(n) {
+		return
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walk(c, visit)
+	}
+}
+
+func findFirst(n *html.Node, tag atom.Atom) *html.Node {
+	var found *html.Node
+	walk(n, func(c *html.Node) bool {
+		if found != nil {
+			return false
+		}
+		if c.Type == html.ElementNode && c.DataAtom == tag {
+			found = c
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+// textContent concatenates the text nodes under n
+func textContent(n *html.Node) string {
+	var sb strings.Builder
+	walk(n, func(c *html.Node) bool {
+		if c.Type == html.TextNode {
+			sb.WriteString(c.Data)
+		}
+		return true
+	})
+	return sb.String()
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Namespace == "" && a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func hasAttr(n *html.Node, key string) bool {
+	for _, a := range n.Attr {
+		if a.Namespace == "" && a.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func headingLevel(n *html.Node) int {
+	if n.Type != html.ElementNode {
+		return 0
+	}
+	switch n.DataAtom {
+	case atom.H1:
+		return 1
+	case atom.H2:
+		return 2
+	case atom.H3:
+		return 3
+	case atom.H4:
+		return 4
+	case atom.H5:
+		return 5
+	case atom.H6:
+		return 6
+	}
+	return 0
+}
+
+func collapseSpace(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
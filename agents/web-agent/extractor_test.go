@@ -0,0 +1,291 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// extractionFixtures are saved pages with the word count of their article
+// text, counted independently of the extractor
+var extractionFixtures = []struct {
+	file     string
+	url      string
+	title    string
+	words    int
+	contains []string
+	excludes []string
+}{
+	{
+		file:     "blog_post.html",
+		url:      "https://blog.example.com/2024/03/build-cache/",
+		title:    "Why We Moved Our Build Cache to Object Storage – The Tinkering Engineer",
+		words:    368,
+		contains: []string{"For about three years", "The obvious fix, a shared cache", "Entries larger than two hundred megabytes", "Storage costs came to less"},
+		excludes: []string{"Subscribe to our newsletter", "Recent Posts", "Great write-up", "Share on Twitter", "We use cookies", "Proudly powered"},
+	},
+	{
+		file:     "docs_page.html",
+		url:      "https://relay.example.com/docs/retries",
+		title:    "Configuring retries | Relay Client Docs",
+		words:    206,
+		contains: []string{"transient error", "max_attempts | int | 3", "Raise max_attempts to five", "MaxAttempts: 5,", "circuit breaker"},
+		excludes: []string{"Installation", "Was this page helpful", "Next: Timeouts", "Apache 2.0", "searchIndex"},
+	},
+	{
+		file:     "news_article.html",
+		url:      "https://news.example.com/local/2024/bike-lanes",
+		title:    "City council approves expanded bike lane network - Riverside Daily",
+		words:    208,
+		contains: []string{"voted seven to two", "Councillor Rosa Ferreira", "Harbour Road"},
+		excludes: []string{"Hometown Motors", "hidden and should never appear", "unlimited access", "Related stories", "All rights reserved"},
+	},
+	{
+		file:     "legacy_table.html",
+		url:      "http://radios.example.net/shortwave.html",
+		title:    "Restoring a 1962 Shortwave Receiver",
+		words:    208,
+		contains: []string{"swap meet for twenty dollars", "realigned the IF stages", "dial calibration"},
+		excludes: []string{"Guestbook", "visitor number"},
+	},
+}
+
+func loadFixture(t *testing.T, name string) string {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	return string(content)
+}
+
+func TestExtract_Fixtures(t *testing.T) {
+	wa := newTestAgent()
+
+	for _, fixture := range extractionFixtures {
+		t.Run(fixture.file, func(t *testing.T) {
+			result := wa.extractAndOptimizeContent(loadFixture(t, fixture.file), fixture.url, 100000, formatText)
+			content := result["main_content"].(string)
+
+			if result["title"] != fixture.title {
+				t.Errorf("Expected title %q, got %q", fixture.title, result["title"])
+			}
+
+			// Table separators and headings outside the article may add a few words
+			words := result["word_count"].(int)
+			if words < fixture.words*95/100 || words > fixture.words*110/100 {
+				t.Errorf("Expected about %d words, got %d:\n%s", fixture.words, words, content)
+			}
+
+			for _, phrase := range fixture.contains {
+				if !strings.Contains(content, phrase) {
+					t.Errorf("Expected main content to contain %q", phrase)
+				}
+			}
+			for _, phrase := range fixture.excludes {
+				if strings.Contains(content, phrase) {
+					t.Errorf("Expected main content not to contain %q", phrase)
+				}
+			}
+		})
+	}
+}
+
+func TestExtract_HeadingsAndLinksFromDOM(t *testing.T) {
+	wa := newTestAgent()
+	result := wa.extractAndOptimizeContent(loadFixture(t, "docs_page.html"), "https://relay.example.com/docs/retries", 100000, formatText)
+
+	headings := result["headings"].([]Heading)
+	expected := []Heading{{1, "Configuring retries"}, {2, "Defaults"}, {2, "Tuning retries"}, {3, "Example"}}
+	if len(headings) != len(expected) {
+		t.Fatalf("Expected headings %v, got %v", expected, headings)
+	}
+	for i := range expected {
+		if headings[i] != expected[i] {
+			t.Errorf("Heading %d: expected %v, got %v", i, expected[i], headings[i])
+		}
+	}
+
+	// Sidebar links are pruned with the nav; the inline link has nested text
+	links := result["links"].([]Link)
+	if len(links) != 1 || links[0].Text != "timeouts guide" || links[0].Type != "internal" {
+		t.Errorf("Expected only the timeouts guide link, got %v", links)
+	}
+}
+
+func TestExtract_NestedContainersAndScripts(t *testing.T) {
+	page := `<html><head><title>Nested</title>
+<script>var tpl = '<div class="content">Injected by script</div>';</script></head>
+<body><div class="content"><div class="inner"><p>First paragraph of the article, long enough to count as content.</p></div>
+<div class="inner"><p>Second paragraph inside a sibling div, which a non-greedy regex would lose.</p></div>
+<table><tr><td>Cell text in a table</td></tr></table></div></body></html>`
+
+	wa := newTestAgent()
+	content := wa.extractAndOptimizeContent(page, "", 100000, formatText)["main_content"].(string)
+
+	for _, phrase := range []string{"First paragraph", "Second paragraph", "Cell text in a table"} {
+		if !strings.Contains(content, phrase) {
+			t.Errorf("Expected main content to contain %q, got:\n%s", phrase, content)
+		}
+	}
+	if strings.Contains(content, "Injected") {
+		t.Errorf("Expected script text to be dropped, got:\n%s", content)
+	}
+}
+
+func TestExtract_PathologicalMarkup(t *testing.T) {
+	// Angle brackets inside attributes, unclosed paragraphs and list items,
+	// a heading split over lines, and a stray closing tag
+	page := `<html><head><title>Odd > markup</title></head>
+<body><article class="content">
+<h2
+  class="title" data-note="a > b">Split
+heading</h2>
+<p title="1 < 2 > 0">First paragraph with a tricky attribute, long enough to count.
+<p>Second paragraph that is never closed, with <a href="/next?a=1&b=2" data-x="<b>">a link</a>
+<ul><li>first item<li>second item</ul>
+</span>
+<p>Third paragraph after a stray closing tag.
+</article></body></html>`
+
+	wa := newTestAgent()
+	result := wa.extractAndOptimizeContent(page, "https://relay.example.com/docs/", 100000, formatText)
+	content := result["main_content"].(string)
+
+	for _, phrase := range []string{"First paragraph", "Second paragraph", "first item", "second item", "Third paragraph"} {
+		if !strings.Contains(content, phrase) {
+			t.Errorf("Expected main content to contain %q, got:\n%s", phrase, content)
+		}
+	}
+	for _, garbage := range []string{"a > b", "1 < 2", "data-x", "<b>", "</span>"} {
+		if strings.Contains(content, garbage) {
+			t.Errorf("Expected attribute and tag text %q to stay out of the content, got:\n%s", garbage, content)
+		}
+	}
+
+	if headings := result["headings"].([]Heading); len(headings) != 1 || headings[0] != (Heading{2, "Split heading"}) {
+		t.Errorf("Expected the multiline heading, got %v", headings)
+	}
+	if title := result["title"].(string); title != "Odd > markup" {
+		t.Errorf("Expected the decoded title, got %q", title)
+	}
+
+	links := result["links"].([]Link)
+	if len(links) != 1 || links[0].Text != "a link" || links[0].URL != "/next?a=1&b=2" {
+		t.Errorf("Expected the link with its full href, got %v", links)
+	}
+}
+
+func TestExtract_Markdown(t *testing.T) {
+	wa := newTestAgent()
+	content := wa.extractAndOptimizeContent(loadFixture(t, "docs_page.html"), "https://relay.example.com/docs/retries", 100000, formatMarkdown)["main_content"].(string)
+
+	for _, expected := range []string{
+		"# Configuring retries\n",
+		"\n## Tuning retries\n",
+		"\n### Example\n",
+		"a **transient** error",
+		"| Option | Type | Default | Description |\n| --- | --- | --- | --- |\n| `max_attempts` | int | 3 |",
+		"1. Your requests are idempotent and the upstream is known to be flaky.\n   - Raise `max_attempts` to five.\n",
+		"2. Your requests are expensive",
+		"[timeouts guide](https://relay.example.com/docs/timeouts)",
+		"```go\nclient := relay.NewClient(relay.Options{\n    MaxAttempts: 5,\n",
+		"})\n```",
+		"> Retries multiply load",
+	} {
+		if !strings.Contains(content, expected) {
+			t.Errorf("Expected markdown to contain %q, got:\n%s", expected, content)
+		}
+	}
+}
+
+func TestExtract_InvalidFormat(t *testing.T) {
+	wa := newTestAgent()
+
+	output := process(t, wa, "extract", map[string]interface{}{"html": testPage, "format": "pdf"})
+	if output.Success || !strings.Contains(output.Error, "invalid format") {
+		t.Errorf("Expected an invalid format error, got success=%v error=%q", output.Success, output.Error)
+	}
+
+	output = process(t, wa, "extract", map[string]interface{}{"html": testPage, "format": "markdown"})
+	if !output.Success || !strings.Contains(output.Data["main_content"].(string), "# Welcome") {
+		t.Errorf("Expected markdown extraction, got %v", output.Data["main_content"])
+	}
+}
+
+func TestSmartTruncate_KeepsWholeBlocks(t *testing.T) {
+	wa := newTestAgent()
+	text := "# Title\n\n" + strings.Repeat("word ", 40) + "\n\n```go\n" + strings.Repeat("code\n", 200) + "```"
+
+	truncated := wa.smartTruncate(text, 100)
+	if !strings.HasPrefix(truncated, "# Title\n\n") || !strings.HasSuffix(truncated, " [...]") {
+		t.Errorf("Expected the heading to survive truncation, got %q", truncated)
+	}
+	if strings.Contains(truncated, "```") {
+		t.Errorf("Expected the code block to be dropped rather than split, got %q", truncated)
+	}
+}
+
+func TestExtract_StructuredData(t *testing.T) {
+	page := `<html><head><title>Structured</title>
+<script type="application/ld+json">{"@context":"https://schema.org","@type":"Article","headline":"Valid block"}</script>
+<script type="application/ld+json">{"@type": "Broken", </script>
+<script type="application/ld+json">[{"@type":"Person","name":"Ada"},{"@type":"Organization","name":"Relay"}]</script>
+<script type="application/ld+json">{"@context":"https://schema.org","@graph":[{"@type":"WebSite","name":"Relay docs"}]}</script>
+<script>{"@type":"NotJSONLD"}</script>
+</head><body><p>Body text of the page.</p></body></html>`
+
+	wa := newTestAgent()
+	result := wa.extractAndOptimizeContent(page, "", 100000, formatText)
+
+	items, ok := result["structured_data"].([]map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected structured_data, got %v", result["structured_data"])
+	}
+	var types []string
+	for _, item := range items {
+		types = append(types, item["@type"].(string))
+	}
+	expected := []string{"Article", "Person", "Organization", "WebSite"}
+	if strings.Join(types, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected types %v, got %v", expected, types)
+	}
+	if items[0]["headline"] != "Valid block" {
+		t.Errorf("Expected the article headline, got %v", items[0])
+	}
+}
+
+func TestExtract_OpenGraph(t *testing.T) {
+	page := `<html><head><title>Social</title>
+<meta property="og:title" content="Open Graph title">
+<meta property="og:title" content="Duplicate title">
+<meta property="og:image" content="https://relay.example.com/cover.png">
+<meta name="twitter:card" content="summary_large_image">
+<meta name="description" content="Plain description">
+</head><body><p>Body text of the page.</p></body></html>`
+
+	wa := newTestAgent()
+	result := wa.extractAndOptimizeContent(page, "", 100000, formatText)
+
+	tags, ok := result["open_graph"].(map[string]string)
+	if !ok {
+		t.Fatalf("Expected open_graph, got %v", result["open_graph"])
+	}
+	expected := map[string]string{
+		"og:title":     "Open Graph title",
+		"og:image":     "https://relay.example.com/cover.png",
+		"twitter:card": "summary_large_image",
+	}
+	if len(tags) != len(expected) {
+		t.Errorf("Expected %v, got %v", expected, tags)
+	}
+	for key, value := range expected {
+		if tags[key] != value {
+			t.Errorf("Expected %s=%q, got %q", key, value, tags[key])
+		}
+	}
+	if _, ok := result["structured_data"]; ok {
+		t.Errorf("Expected no structured_data for a page without JSON-LD")
+	}
+}
@@ -1,7 +1,9 @@
 module web-agent
 
-go 1.21
+go 1.24.0
 
 replace github.com/AgentForgeEngine/AgentForgeEngine => ../../
 
 require github.com/AgentForgeEngine/AgentForgeEngine v0.0.0-00010101000000-000000000000
+
+require golang.org/x/net v0.48.0
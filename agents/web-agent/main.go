@@ -5,10 +5,27 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/tokenizer"
+)
+
+// defaultHealthCheckURL is probed when neither health_check_url nor an
+// allowed_domains list is configured
+const defaultHealthCheckURL = "https://httpbin.org/get"
+
+// healthCheckNone disables the connectivity probe
+const healthCheckNone = "none"
+
+// Page cache defaults
+const (
+	defaultCacheTTL      = 5 * time.Minute
+	defaultCacheMaxBytes = 50 * 1024 * 1024 // 50MB
+	defaultCacheSize     = 1000
 )
 
 type WebAgent struct {
@@ -22,12 +39,25 @@ type WebAgent struct {
 	allowedDomains      []string
 	blockedDomains      []string
 	allowedContentTypes []string
+	maxBodySize         int64
+	maxRedirects        int
+	followRedirects     bool
+	healthCheckURL      string
+	cache               *pageCache
+	limiter             *hostLimiter
+	ignoreRobots        bool
+	robotsMu            sync.Mutex
+	robots              map[string]*robotsEntry
 	includeLinks        bool
 	includeMetadata     bool
+	tokenizer           tokenizer.Tokenizer
+	// allowPrivateNetworks lets requests reach loopback, private, and
+	// link-local addresses
+	allowPrivateNetworks bool
 }
 
 func NewWebAgent() *WebAgent {
-	return &WebAgent{
+	wa := &WebAgent{
 		name:             "web-agent",
 		defaultMaxTokens: 8000,
 		maxAllowedTokens: 15000,
@@ -41,12 +71,24 @@ func NewWebAgent() *WebAgent {
 			"application/xml",
 			"text/xml",
 		},
+		maxBodySize:     10 * 1024 * 1024, // 10MB
+		maxRedirects:    defaultMaxRedirects,
+		followRedirects: true,
+		healthCheckURL:  defaultHealthCheckURL,
+		cache:           newPageCache(defaultCacheTTL, defaultCacheMaxBytes, defaultCacheSize),
+		limiter:         newHostLimiter(defaultRateLimit, defaultRateBurst),
+		robots:          make(map[string]*robotsEntry),
 		includeLinks:    true,
 		includeMetadata: true,
-		httpClient: &http.Client{
-			Timeout: 15 * time.Second,
-		},
+		tokenizer:       tokenizer.NewHeuristic(),
+	}
+
+	wa.httpClient = &http.Client{
+		Timeout:       15 * time.Second,
+		CheckRedirect: wa.checkRedirect,
+		Transport:     wa.newTransport(),
 	}
+	return wa
 }
 
 func (wa *WebAgent) Name() string {
@@ -99,6 +141,10 @@ func (wa *WebAgent) Initialize(config map[string]interface{}) error {
 		wa.blockedDomains = domains
 	}
 
+	if allowPrivate, ok := config["allow_private_networks"].(bool); ok {
+		wa.allowPrivateNetworks = allowPrivate
+	}
+
 	// Set content types
 	if contentTypes, ok := config["content_types"].([]interface{}); ok {
 		var types []string
@@ -110,6 +156,74 @@ func (wa *WebAgent) Initialize(config map[string]interface{}) error {
 		wa.allowedContentTypes = types
 	}
 
+	// Set maximum response body size
+	if maxBodySize, ok := config["max_body_size"].(int); ok && maxBodySize > 0 {
+		wa.maxBodySize = int64(maxBodySize)
+	}
+
+	// Set redirect handling
+	if maxRedirects, ok := config["max_redirects"].(int); ok {
+		if maxRedirects < 0 {
+			return fmt.Errorf("web-agent initialization failed: max_redirects must not be negative")
+		}
+		wa.maxRedirects = maxRedirects
+	}
+	if followRedirects, ok := config["follow_redirects"].(bool); ok {
+		wa.followRedirects = followRedirects
+	}
+
+	// Set page cache limits; a zero TTL or size disables caching. The TTL is
+	// seconds or a duration string such as "10m".
+	cacheTTL := defaultCacheTTL
+	switch ttl := config["cache_ttl"].(type) {
+	case int:
+		cacheTTL = time.Duration(ttl) * time.Second
+	case string:
+		parsed, err := time.ParseDuration(ttl)
+		if err != nil {
+			return fmt.Errorf("invalid cache_ttl %q: %w", ttl, err)
+		}
+		cacheTTL = parsed
+	}
+	cacheMaxBytes := int64(defaultCacheMaxBytes)
+	if maxBytes, ok := config["cache_max_bytes"].(int); ok {
+		cacheMaxBytes = int64(maxBytes)
+	}
+	cacheSize := defaultCacheSize
+	if size, ok := config["cache_size"].(int); ok {
+		cacheSize = size
+	}
+	if cacheTTL > 0 && cacheMaxBytes > 0 && cacheSize > 0 {
+		wa.cache = newPageCache(cacheTTL, cacheMaxBytes, cacheSize)
+	} else {
+		wa.cache = nil
+	}
+
+	// Set per-host rate limit; a zero rate disables limiting
+	rateLimit := defaultRateLimit
+	switch v := config["rate_limit"].(type) {
+	case int:
+		rateLimit = float64(v)
+	case float64:
+		rateLimit = v
+	}
+	rateBurst := defaultRateBurst
+	if burst, ok := config["rate_burst"].(int); ok {
+		rateBurst = burst
+	}
+	if rateLimit > 0 {
+		wa.limiter = newHostLimiter(rateLimit, rateBurst)
+	} else {
+		wa.limiter = nil
+	}
+
+	if ignoreRobots, ok := config["ignore_robots"].(bool); ok {
+		wa.ignoreRobots = ignoreRobots
+	}
+	if respectRobots, ok := config["respect_robots"].(bool); ok {
+		wa.ignoreRobots = !respectRobots
+	}
+
 	// Set feature flags
 	if includeLinks, ok := config["include_links"].(bool); ok {
 		wa.includeLinks = includeLinks
@@ -119,12 +233,27 @@ func (wa *WebAgent) Initialize(config map[string]interface{}) error {
 		wa.includeMetadata = includeMetadata
 	}
 
-	// Test with a simple request to verify connectivity
-	if err := wa.HealthCheck(); err != nil {
+	// Set tokenizer used for token budgets
+	tok, err := tokenizer.FromConfig(config)
+	if err != nil {
+		return fmt.Errorf("web-agent tokenizer initialization failed: %w", err)
+	}
+	wa.tokenizer = tok
+
+	// Pick the connectivity probe target
+	healthCheckURL, err := wa.resolveHealthCheckURL(config)
+	if err != nil {
 		return fmt.Errorf("web-agent initialization failed: %w", err)
 	}
+	wa.healthCheckURL = healthCheckURL
 
-	log.Printf("WebAgent initialized: max_tokens=%d, timeout=%v", wa.defaultMaxTokens, wa.timeout)
+	// An unreachable probe target is not a configuration error: stay
+	// initialized and let HealthCheck keep reporting the problem
+	if err := wa.HealthCheck(); err != nil {
+		log.Printf("Warning: web-agent health probe to %s failed: %v", wa.healthCheckURL, err)
+	}
+
+	log.Printf("WebAgent initialized: max_tokens=%d, timeout=%v, tokenizer=%s", wa.defaultMaxTokens, wa.timeout, wa.tokenizer.Name())
 	return nil
 }
 
@@ -144,12 +273,48 @@ func (wa *WebAgent) Process(ctx context.Context, input interfaces.AgentInput) (i
 	}
 }
 
+// resolveHealthCheckURL chooses the probe target: an explicit health_check_url,
+// nothing when health_check is "none", otherwise the first allowed domain,
+// falling back to a public test endpoint when no domains are configured
+func (wa *WebAgent) resolveHealthCheckURL(config map[string]interface{}) (string, error) {
+	if mode, ok := config["health_check"].(string); ok {
+		switch mode {
+		case healthCheckNone:
+			return "", nil
+		case "", "probe":
+		default:
+			return "", fmt.Errorf("invalid health_check %q (expected \"probe\" or \"none\")", mode)
+		}
+	}
+
+	if probeURL, ok := config["health_check_url"].(string); ok && probeURL != "" {
+		parsed, err := url.Parse(probeURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return "", fmt.Errorf("invalid health_check_url %q", probeURL)
+		}
+		return probeURL, nil
+	}
+
+	for _, domain := range wa.allowedDomains {
+		host := strings.TrimPrefix(strings.TrimPrefix(domain, "*"), ".")
+		if host == "" || strings.Contains(host, "*") {
+			continue
+		}
+		return "https://" + host + "/", nil
+	}
+
+	return defaultHealthCheckURL, nil
+}
+
 func (wa *WebAgent) HealthCheck() error {
+	if wa.healthCheckURL == "" {
+		return nil
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	// Create a simple test request to httpbin.org (reliable testing endpoint)
-	req, err := http.NewRequestWithContext(ctx, "GET", "https://httpbin.org/get", nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", wa.healthCheckURL, nil)
 	if err != nil {
 		return fmt.Errorf("health check request creation failed: %w", err)
 	}
@@ -174,5 +339,11 @@ func (wa *WebAgent) Shutdown() error {
 	return nil
 }
 
+// Classification declares that the agent only fetches pages, which may change
+// between calls, from other hosts
+func (wa *WebAgent) Classification() interfaces.Classification {
+	return interfaces.Classification{ReadOnly: true, Network: true}
+}
+
 // Export the agent for plugin loading
 var Agent interfaces.Agent = NewWebAgent()
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestInitialize_HealthProbe(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	wa := NewWebAgent()
+	if err := wa.Initialize(map[string]interface{}{"health_check_url": server.URL, "allow_private_networks": true}); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	if err := wa.HealthCheck(); err != nil {
+		t.Errorf("Expected healthy probe, got: %v", err)
+	}
+
+	// A failing probe leaves the agent initialized but unhealthy
+	status = http.StatusServiceUnavailable
+	wa = NewWebAgent()
+	if err := wa.Initialize(map[string]interface{}{"health_check_url": server.URL, "allow_private_networks": true}); err != nil {
+		t.Fatalf("Expected probe failure not to fail Initialize, got: %v", err)
+	}
+	if err := wa.HealthCheck(); err == nil {
+		t.Error("Expected HealthCheck to report the failing probe")
+	}
+}
+
+func TestInitialize_HealthCheckNone(t *testing.T) {
+	wa := NewWebAgent()
+	if err := wa.Initialize(map[string]interface{}{"health_check": "none"}); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	if err := wa.HealthCheck(); err != nil {
+		t.Errorf("Expected no probe with health_check none, got: %v", err)
+	}
+}
+
+func TestInitialize_InvalidHealthConfig(t *testing.T) {
+	for _, config := range []map[string]interface{}{
+		{"health_check": "sometimes"},
+		{"health_check_url": "ftp://example.com"},
+	} {
+		if err := NewWebAgent().Initialize(config); err == nil {
+			t.Errorf("Expected invalid config %v to fail", config)
+		}
+	}
+}
+
+func TestResolveHealthCheckURL_AllowedDomain(t *testing.T) {
+	wa := NewWebAgent()
+	wa.allowedDomains = []string{"*.intranet.example", "docs.example.com"}
+
+	got, err := wa.resolveHealthCheckURL(map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("resolveHealthCheckURL failed: %v", err)
+	}
+	if got != "https://intranet.example/" {
+		t.Errorf("Expected probe of the first allowed domain, got %s", got)
+	}
+}
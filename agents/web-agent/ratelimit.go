@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rate limit defaults: one request per second per host with no burst
+const (
+	defaultRateLimit = 1.0
+	defaultRateBurst = 1
+)
+
+// hostLimiter is a token bucket per host shared by every operation that
+// touches the network. Callers that find the bucket empty reserve a future
+// token and sleep until it is due.
+type hostLimiter struct {
+	mu      sync.Mutex
+	rate    float64 // tokens per second
+	burst   float64
+	buckets map[string]*tokenBucket
+	now     func() time.Time
+	sleep   func(ctx context.Context, d time.Duration) error
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newHostLimiter(rate float64, burst int) *hostLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &hostLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+		sleep:   sleepContext,
+	}
+}
+
+// wait blocks until host may be sent another request or ctx is done
+func (l *hostLimiter) wait(ctx context.Context, host string) error {
+	delay := l.reserve(host)
+	if delay <= 0 {
+		return nil
+	}
+
+	if err := l.sleep(ctx, delay); err != nil {
+		// Hand the reserved token back so the next caller is not delayed
+		l.mu.Lock()
+		l.buckets[host].tokens++
+		l.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// reserve takes a token for host, letting the balance go negative, and
+// returns how long the caller must wait for it
+func (l *hostLimiter) reserve(host string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	bucket, ok := l.buckets[host]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[host] = bucket
+	}
+
+	bucket.tokens += now.Sub(bucket.last).Seconds() * l.rate
+	if bucket.tokens > l.burst {
+		bucket.tokens = l.burst
+	}
+	bucket.last = now
+
+	bucket.tokens--
+	if bucket.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-bucket.tokens / l.rate * float64(time.Second))
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// rateLimitedError reports a 429, or a 503 with Retry-After, from the remote
+// host along with how long it asked callers to wait
+type rateLimitedError struct {
+	host       string
+	statusCode int
+	retryAfter time.Duration
+}
+
+func (e *rateLimitedError) Error() string {
+	if e.retryAfter > 0 {
+		return fmt.Sprintf("rate limited by %s (HTTP %d): retry after %v", e.host, e.statusCode, e.retryAfter)
+	}
+	return fmt.Sprintf("rate limited by %s (HTTP %d)", e.host, e.statusCode)
+}
+
+// data describes the error in a form the orchestrator can use to back off
+func (e *rateLimitedError) data() map[string]interface{} {
+	return map[string]interface{}{
+		"error_type":          "rate_limited",
+		"retryable":           true,
+		"status_code":         e.statusCode,
+		"retry_after_seconds": int(e.retryAfter.Round(time.Second) / time.Second),
+	}
+}
+
+// checkRateLimited returns a rateLimitedError when resp asks the client to
+// slow down
+func checkRateLimited(resp *http.Response, now time.Time) *rateLimitedError {
+	retryAfter := resp.Header.Get("Retry-After")
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+	case resp.StatusCode == http.StatusServiceUnavailable && retryAfter != "":
+	default:
+		return nil
+	}
+
+	return &rateLimitedError{
+		host:       resp.Request.URL.Hostname(),
+		statusCode: resp.StatusCode,
+		retryAfter: parseRetryAfter(retryAfter, now),
+	}
+}
+
+// parseRetryAfter reads a Retry-After value given either in seconds or as
+// an HTTP date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	if when, err := http.ParseTime(value); err == nil && when.After(now) {
+		return when.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestLimiter returns a limiter whose sleeps advance a fake clock and are
+// recorded instead of blocking
+func newTestLimiter(rate float64, burst int) (*hostLimiter, *fakeClock, *[]time.Duration) {
+	clock := &fakeClock{current: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	sleeps := &[]time.Duration{}
+
+	limiter := newHostLimiter(rate, burst)
+	limiter.now = clock.now
+	limiter.sleep = func(ctx context.Context, d time.Duration) error {
+		*sleeps = append(*sleeps, d)
+		clock.advance(d)
+		return nil
+	}
+	return limiter, clock, sleeps
+}
+
+func TestHostLimiter_BurstThenRate(t *testing.T) {
+	limiter, clock, sleeps := newTestLimiter(2, 2)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		limiter.wait(ctx, "example.com")
+	}
+	if len(*sleeps) != 1 || (*sleeps)[0] != 500*time.Millisecond {
+		t.Fatalf("Expected the third request to wait 500ms, got %v", *sleeps)
+	}
+
+	// Other hosts have their own bucket
+	limiter.wait(ctx, "other.org")
+	if len(*sleeps) != 1 {
+		t.Errorf("Expected a new host not to wait, got %v", *sleeps)
+	}
+
+	// Idle time refills the bucket up to the burst size
+	clock.advance(10 * time.Second)
+	limiter.wait(ctx, "example.com")
+	limiter.wait(ctx, "example.com")
+	if len(*sleeps) != 1 {
+		t.Errorf("Expected refilled burst not to wait, got %v", *sleeps)
+	}
+}
+
+func TestHostLimiter_CancelledWaitReturnsToken(t *testing.T) {
+	limiter, _, _ := newTestLimiter(1, 1)
+	limiter.sleep = func(ctx context.Context, d time.Duration) error {
+		return context.Canceled
+	}
+
+	limiter.wait(context.Background(), "example.com")
+	if err := limiter.wait(context.Background(), "example.com"); err != context.Canceled {
+		t.Fatalf("Expected cancellation, got %v", err)
+	}
+	if tokens := limiter.buckets["example.com"].tokens; tokens != 0 {
+		t.Errorf("Expected the reserved token to be returned, bucket has %v", tokens)
+	}
+}
+
+func TestFetchAndValidate_ShareHostLimit(t *testing.T) {
+	server := newTestServer(t)
+	wa := newTestAgent()
+	wa.ignoreRobots = true
+	limiter, _, sleeps := newTestLimiter(1, 1)
+	wa.limiter = limiter
+
+	process(t, wa, "fetch", map[string]interface{}{"url": server.URL + "/page"})
+	process(t, wa, "validate", map[string]interface{}{"url": server.URL + "/page"})
+	process(t, wa, "fetch", map[string]interface{}{"url": server.URL + "/page", "no_cache": true})
+
+	if len(*sleeps) != 2 {
+		t.Errorf("Expected validate and the second fetch to wait, got %v", *sleeps)
+	}
+}
+
+func TestFetch_RateLimitedResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	wa := newTestAgent()
+	wa.blockedDomains = nil
+	wa.ignoreRobots = true
+
+	output := process(t, wa, "fetch", map[string]interface{}{"url": server.URL + "/page"})
+	if output.Success || !strings.Contains(output.Error, "retry after 30s") {
+		t.Fatalf("Expected a rate limit error, got success=%v error=%q", output.Success, output.Error)
+	}
+	if output.Data["error_type"] != "rate_limited" || output.Data["retry_after_seconds"] != 30 || output.Data["retryable"] != true {
+		t.Errorf("Expected structured rate limit data, got %v", output.Data)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{"120", 2 * time.Minute},
+		{now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second},
+		{"", 0},
+		{"soon", 0},
+	}
+
+	for _, tc := range testCases {
+		if got := parseRetryAfter(tc.value, now); got != tc.expected {
+			t.Errorf("parseRetryAfter(%q) = %v, expected %v", tc.value, got, tc.expected)
+		}
+	}
+}
+
+func TestRobots_DisallowedPathsAreRefused(t *testing.T) {
+	var robotsRequests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			atomic.AddInt32(&robotsRequests, 1)
+			fmt.Fprint(w, "User-agent: *\nDisallow: /private\nAllow: /private/docs\n")
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, testPage)
+		}
+	}))
+	defer server.Close()
+
+	wa := newTestAgent()
+	wa.blockedDomains = nil
+
+	output := process(t, wa, "fetch", map[string]interface{}{"url": server.URL + "/private/secret"})
+	if output.Success || !strings.Contains(output.Error, "disallowed by robots.txt") {
+		t.Errorf("Expected robots.txt to refuse the fetch, got success=%v error=%q", output.Success, output.Error)
+	}
+
+	for _, path := range []string{"/public", "/private/docs/intro"} {
+		if output := process(t, wa, "fetch", map[string]interface{}{"url": server.URL + path}); !output.Success {
+			t.Errorf("Expected %s to be allowed, got: %s", path, output.Error)
+		}
+	}
+
+	if got := atomic.LoadInt32(&robotsRequests); got != 1 {
+		t.Errorf("Expected robots.txt to be fetched once, got %d", got)
+	}
+
+	wa.ignoreRobots = true
+	if output := process(t, wa, "fetch", map[string]interface{}{"url": server.URL + "/private/secret"}); !output.Success {
+		t.Errorf("Expected ignore_robots to allow the fetch, got: %s", output.Error)
+	}
+}
+
+func TestInitialize_RespectRobots(t *testing.T) {
+	testCases := []struct {
+		config   map[string]interface{}
+		expected bool
+	}{
+		{map[string]interface{}{}, false},
+		{map[string]interface{}{"respect_robots": false}, true},
+		{map[string]interface{}{"ignore_robots": true}, true},
+		{map[string]interface{}{"ignore_robots": true, "respect_robots": true}, false},
+	}
+
+	for _, tc := range testCases {
+		tc.config["health_check"] = "none"
+		wa := NewWebAgent()
+		if err := wa.Initialize(tc.config); err != nil {
+			t.Fatalf("Initialize(%v) failed: %v", tc.config, err)
+		}
+		if wa.ignoreRobots != tc.expected {
+			t.Errorf("Initialize(%v): ignoreRobots = %v, expected %v", tc.config, wa.ignoreRobots, tc.expected)
+		}
+	}
+}
+
+func TestParseRobots_AgentSpecificGroup(t *testing.T) {
+	content := `# example
+User-agent: *
+Disallow: /
+
+User-agent: OtherBot
+User-agent: AgentForgeEngine-WebAgent
+Disallow: /admin
+Disallow: /*.pdf$
+`
+	rules := parseRobots(content, "AgentForgeEngine-WebAgent/1.0")
+
+	testCases := map[string]bool{
+		"/":                          true,
+		"/admin/users":               false,
+		"/files/spec.pdf":            false,
+		"/files/spec.pdf?download=1": true,
+	}
+	for path, expected := range testCases {
+		if got := rules.allowed(path); got != expected {
+			t.Errorf("allowed(%q) = %v, expected %v", path, got, expected)
+		}
+	}
+
+	if parseRobots(content, "SomeCrawler/2.0").allowed("/anything") {
+		t.Error("Expected the wildcard group to apply to other agents")
+	}
+}
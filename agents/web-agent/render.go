@@ -0,0 +1,382 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// blockTags start a new block when rendered; everything else is inline
+var blockTags = map[atom.Atom]bool{
+	atom.Address:    true,
+	atom.Article:    true,
+	atom.Aside:      true,
+	atom.Blockquote: true,
+	atom.Dd:         true,
+	atom.Details:    true,
+	atom.Div:        true,
+	atom.Dl:         true,
+	atom.Dt:         true,
+	atom.Fieldset:   true,
+	atom.Figcaption: true,
+	atom.Figure:     true,
+	atom.Footer:     true,
+	atom.H1:         true,
+	atom.H2:         true,
+	atom.H3:         true,
+	atom.H4:         true,
+	atom.H5:         true,
+	atom.H6:         true,
+	atom.Header:     true,
+	atom.Hr:         true,
+	atom.Li:         true,
+	atom.Main:       true,
+	atom.Nav:        true,
+	atom.Ol:         true,
+	atom.P:          true,
+	atom.Pre:        true,
+	atom.Section:    true,
+	atom.Summary:    true,
+	atom.Table:      true,
+	atom.Td:         true,
+	atom.Th:         true,
+	atom.Tr:         true,
+	atom.Ul:         true,
+}
+
+// renderer turns a DOM subtree into blocks separated by blank lines. In
+// markdown mode headings keep their level, lists and tables keep their
+// structure, code blocks are fenced, and inline links and emphasis are
+// preserved; in text mode only the block structure is kept.
+type renderer struct {
+	markdown bool
+	base     *url.URL
+	blocks   []string
+	pending  strings.Builder
+}
+
+func (r *renderer) String() string {
+	r.flush()
+	return strings.Join(r.blocks, "\n\n")
+}
+
+// flush ends the inline run being collected as a block of its own
+func (r *renderer) flush() {
+	if text := collapseSpace(r.pending.String()); text != "" {
+		r.blocks = append(r.blocks, text)
+	}
+	r.pending.Reset()
+}
+
+func (r *renderer) add(block string) {
+	r.flush()
+	if block != "" {
+		r.blocks = append(r.blocks, block)
+	}
+}
+
+// sub returns an empty renderer with the same settings for rendering nested
+// content that is prefixed or indented as a whole
+func (r *renderer) sub() *renderer {
+	return &renderer{markdown: r.markdown, base: r.base}
+}
+
+func (r *renderer) children(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		switch {
+		case c.Type == html.TextNode:
+			r.pending.WriteString(c.Data)
+		case c.Type == html.ElementNode && blockTags[c.DataAtom]:
+			r.flush()
+			r.block(c)
+		default:
+			r.pending.WriteString(r.inline(c))
+		}
+	}
+}
+
+func (r *renderer) block(n *html.Node) {
+	if n.Type != html.ElementNode {
+		r.children(n)
+		r.flush()
+		return
+	}
+
+	if level := headingLevel(n); level > 0 {
+		text := collapseSpace(r.inlineChildren(n))
+		if r.markdown && text != "" {
+			text = strings.Repeat("#", level) + " " + text
+		}
+		r.add(text)
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.Pre:
+		r.add(r.pre(n))
+	case atom.Ul, atom.Ol:
+		r.add(strings.Join(r.list(n), "\n"))
+	case atom.Table:
+		r.table(n)
+	case atom.Blockquote:
+		sub := r.sub()
+		sub.children(n)
+		quote := sub.String()
+		if r.markdown && quote != "" {
+			lines := strings.Split(quote, "\n")
+			for i, line := range lines {
+				lines[i] = strings.TrimRight("> "+line, " ")
+			}
+			quote = strings.Join(lines, "\n")
+		}
+		r.add(quote)
+	case atom.Hr:
+		if r.markdown {
+			r.add("---")
+		}
+	default:
+		r.children(n)
+		r.flush()
+	}
+}
+
+func (r *renderer) inlineChildren(n *html.Node) string {
+	var sb strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		sb.WriteString(r.inline(c))
+	}
+	return sb.String()
+}
+
+func (r *renderer) inline(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		return n.Data
+	case html.ElementNode:
+	default:
+		return ""
+	}
+
+	switch n.DataAtom {
+	case atom.Br:
+		return " "
+	case atom.Img:
+		return ""
+	case atom.A:
+		text := r.inlineChildren(n)
+		if !r.markdown || collapseSpace(text) == "" {
+			return text
+		}
+		if href := r.resolve(attr(n, "href")); href != "" {
+			return wrapInline(text, "[", "]("+href+")")
+		}
+		return text
+	case atom.Strong, atom.B:
+		if r.markdown {
+			return wrapInline(r.inlineChildren(n), "**", "**")
+		}
+	case atom.Em, atom.I:
+		if r.markdown {
+			return wrapInline(r.inlineChildren(n), "*", "*")
+		}
+	case atom.Code, atom.Kbd, atom.Samp:
+		if r.markdown {
+			return wrapInline(collapseSpace(textContent(n)), "`", "`")
+		}
+	}
+
+	if blockTags[n.DataAtom] {
+		// A block inside inline content, such as a list item's paragraph
+		return " " + r.inlineChildren(n) + " "
+	}
+	return r.inlineChildren(n)
+}
+
+// wrapInline surrounds the text of inner with open and close, keeping any
+// whitespace at either end outside the markers
+func wrapInline(inner, open, close string) string {
+	text := strings.TrimSpace(inner)
+	if text == "" {
+		return inner
+	}
+
+	var leading, trailing string
+	if text[0] != inner[0] {
+		leading = " "
+	}
+	if text[len(text)-1] != inner[len(inner)-1] {
+		trailing = " "
+	}
+	return leading + open + text + close + trailing
+}
+
+// resolve makes href absolute against the page URL, dropping script and
+// fragment-only links
+func (r *renderer) resolve(href string) string {
+	href = strings.TrimSpace(href)
+	if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+		return ""
+	}
+
+	ref, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	if r.base != nil && r.base.IsAbs() {
+		ref = r.base.ResolveReference(ref)
+	}
+	return ref.String()
+}
+
+// pre keeps the whitespace of a preformatted block, fencing it in markdown
+// with the language named by a language-* or lang-* class
+func (r *renderer) pre(n *html.Node) string {
+	code := strings.TrimRight(strings.Trim(textContent(n), "\n"), " \t\n")
+	if code == "" || !r.markdown {
+		return code
+	}
+
+	language := codeLanguage(n)
+	if code := findFirst(n, atom.Code); language == "" && code != nil {
+		language = codeLanguage(code)
+	}
+	return "```" + language + "\n" + code + "\n```"
+}
+
+func codeLanguage(n *html.Node) string {
+	for _, class := range strings.Fields(attr(n, "class")) {
+		for _, prefix := range []string{"language-", "lang-"} {
+			if strings.HasPrefix(class, prefix) {
+				return strings.TrimPrefix(class, prefix)
+			}
+		}
+	}
+	return ""
+}
+
+// list renders the items of a ul or ol one per line, with nested lists
+// indented under their item. Text mode drops the markers.
+func (r *renderer) list(n *html.Node) []string {
+	ordered := n.DataAtom == atom.Ol
+	index := 1
+	if start, err := strconv.Atoi(attr(n, "start")); err == nil && ordered {
+		index = start
+	}
+
+	var lines []string
+	for li := n.FirstChild; li != nil; li = li.NextSibling {
+		if li.Type != html.ElementNode || li.DataAtom != atom.Li {
+			continue
+		}
+
+		var text strings.Builder
+		var nested []string
+		for c := li.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && (c.DataAtom == atom.Ul || c.DataAtom == atom.Ol) {
+				nested = append(nested, r.list(c)...)
+				continue
+			}
+			text.WriteString(r.inline(c))
+		}
+
+		marker := ""
+		if r.markdown {
+			marker = "- "
+			if ordered {
+				marker = fmt.Sprintf("%d. ", index)
+			}
+		}
+		index++
+
+		item := collapseSpace(text.String())
+		if item != "" {
+			lines = append(lines, marker+item)
+		}
+
+		indent := strings.Repeat(" ", len(marker))
+		if indent == "" {
+			indent = "  "
+		}
+		for _, line := range nested {
+			lines = append(lines, indent+line)
+		}
+	}
+	return lines
+}
+
+// table renders a data table one row per line, as a pipe table in markdown.
+// Tables whose cells hold blocks are page layout and are rendered cell by
+// cell instead.
+func (r *renderer) table(n *html.Node) {
+	var rows []*html.Node
+	layout := false
+	walk(n, func(c *html.Node) bool {
+		if c.Type != html.ElementNode || c == n {
+			return true
+		}
+		switch c.DataAtom {
+		case atom.Table:
+			layout = true
+			return false
+		case atom.Tr:
+			rows = append(rows, c)
+		case atom.Caption:
+			return false
+		case atom.P, atom.Div, atom.Ul, atom.Ol, atom.Pre, atom.Blockquote,
+			atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+			layout = true
+			return false
+		}
+		return true
+	})
+
+	if layout {
+		for _, row := range rows {
+			for cell := row.FirstChild; cell != nil; cell = cell.NextSibling {
+				if cell.Type == html.ElementNode {
+					r.block(cell)
+				}
+			}
+		}
+		return
+	}
+
+	if caption := findFirst(n, atom.Caption); caption != nil {
+		r.add(collapseSpace(r.inlineChildren(caption)))
+	}
+
+	var lines []string
+	columns := 0
+	for _, row := range rows {
+		var cells []string
+		for cell := row.FirstChild; cell != nil; cell = cell.NextSibling {
+			if cell.Type != html.ElementNode || (cell.DataAtom != atom.Td && cell.DataAtom != atom.Th) {
+				continue
+			}
+			text := collapseSpace(r.inlineChildren(cell))
+			if r.markdown {
+				text = strings.ReplaceAll(text, "|", `\|`)
+			}
+			cells = append(cells, text)
+		}
+		if len(cells) == 0 {
+			continue
+		}
+
+		if !r.markdown {
+			lines = append(lines, strings.Join(cells, " | "))
+			continue
+		}
+
+		lines = append(lines, "| "+strings.Join(cells, " | ")+" |")
+		if columns == 0 {
+			columns = len(cells)
+			lines = append(lines, "|"+strings.Repeat(" --- |", columns))
+		}
+	}
+	r.add(strings.Join(lines, "\n"))
+}
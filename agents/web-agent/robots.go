@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// maxRobotsSize bounds how much of a robots.txt file is read
+const maxRobotsSize = 512 * 1024
+
+// robotsDisallowedError reports a URL excluded by the host's robots.txt
+type robotsDisallowedError struct {
+	path string
+}
+
+func (e *robotsDisallowedError) Error() string {
+	return fmt.Sprintf("disallowed by robots.txt: %s", e.path)
+}
+
+// robotsRules are the Allow and Disallow rules that apply to this agent
+type robotsRules struct {
+	rules []robotsRule
+}
+
+type robotsRule struct {
+	pattern string
+	allow   bool
+	re      *regexp.Regexp
+}
+
+// robotsEntry is a per-host cache slot; ready is closed once rules is set so
+// concurrent requests to a new host share a single robots.txt fetch
+type robotsEntry struct {
+	ready chan struct{}
+	rules *robotsRules
+}
+
+// allowed reports whether path may be fetched. The longest matching rule
+// wins and Allow wins ties; with no matching rule everything is allowed.
+func (r *robotsRules) allowed(path string) bool {
+	if r == nil {
+		return true
+	}
+
+	best := -1
+	allow := true
+	for _, rule := range r.rules {
+		if !rule.re.MatchString(path) {
+			continue
+		}
+		if len(rule.pattern) > best || (len(rule.pattern) == best && rule.allow) {
+			best = len(rule.pattern)
+			allow = rule.allow
+		}
+	}
+	return allow
+}
+
+// parseRobots collects the rules from the groups naming userAgent's product
+// token, falling back to the "*" group when none do
+func parseRobots(content, userAgent string) *robotsRules {
+	token := strings.ToLower(userAgent)
+	if slash := strings.Index(token, "/"); slash >= 0 {
+		token = token[:slash]
+	}
+
+	var specific, wildcard []robotsRule
+	var groupAgents []string
+	inRules := false
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if hash := strings.Index(line, "#"); hash >= 0 {
+			line = line[:hash]
+		}
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// A user-agent line after rules starts a new group
+			if inRules {
+				groupAgents = nil
+				inRules = false
+			}
+			groupAgents = append(groupAgents, strings.ToLower(value))
+		case "allow", "disallow":
+			inRules = true
+			if value == "" {
+				// An empty Disallow allows everything and adds no rule
+				continue
+			}
+			rule := robotsRule{pattern: value, allow: key == "allow", re: robotsPattern(value)}
+			for _, agent := range groupAgents {
+				switch {
+				case agent == "*":
+					wildcard = append(wildcard, rule)
+				case strings.Contains(token, agent):
+					specific = append(specific, rule)
+				}
+			}
+		}
+	}
+
+	if len(specific) > 0 {
+		return &robotsRules{rules: specific}
+	}
+	return &robotsRules{rules: wildcard}
+}
+
+// robotsPattern compiles a robots.txt path pattern, where "*" matches any
+// run of characters and a trailing "$" anchors the end
+func robotsPattern(pattern string) *regexp.Regexp {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	expr := "^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*")
+	if anchored {
+		expr += "$"
+	}
+	return regexp.MustCompile(expr)
+}
+
+// checkRobots fails when target is disallowed by its host's robots.txt
+func (wa *WebAgent) checkRobots(ctx context.Context, target *url.URL) error {
+	if wa.ignoreRobots {
+		return nil
+	}
+
+	rules, err := wa.robotsFor(ctx, target)
+	if err != nil {
+		return err
+	}
+
+	path := target.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if target.RawQuery != "" {
+		path += "?" + target.RawQuery
+	}
+
+	if !rules.allowed(path) {
+		return &robotsDisallowedError{path: path}
+	}
+	return nil
+}
+
+// robotsFor returns the cached rules for target's host, fetching robots.txt
+// the first time the host is seen
+func (wa *WebAgent) robotsFor(ctx context.Context, target *url.URL) (*robotsRules, error) {
+	key := strings.ToLower(target.Scheme + "://" + target.Host)
+
+	wa.robotsMu.Lock()
+	entry, ok := wa.robots[key]
+	if ok {
+		wa.robotsMu.Unlock()
+		select {
+		case <-entry.ready:
+			return entry.rules, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	entry = &robotsEntry{ready: make(chan struct{})}
+	wa.robots[key] = entry
+	wa.robotsMu.Unlock()
+
+	rules, cache := wa.fetchRobots(ctx, key+"/robots.txt")
+	entry.rules = rules
+	if !cache {
+		// Let a later request try again after a transient failure
+		wa.robotsMu.Lock()
+		delete(wa.robots, key)
+		wa.robotsMu.Unlock()
+	}
+	close(entry.ready)
+
+	return rules, nil
+}
+
+// fetchRobots downloads and parses a robots.txt file. A missing file or an
+// error response allows everything; network failures also allow the request
+// but are not cached.
+func (wa *WebAgent) fetchRobots(ctx context.Context, robotsURL string) (*robotsRules, bool) {
+	req, err := http.NewRequestWithContext(ctx, "GET", robotsURL, nil)
+	if err != nil {
+		return nil, true
+	}
+	req.Header.Set("User-Agent", wa.userAgent)
+
+	resp, err := wa.do(req)
+	if err != nil {
+		log.Printf("Warning: web-agent could not fetch %s: %v", robotsURL, err)
+		return nil, false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, true
+	}
+
+	content, err := wa.readContent(resp.Body, maxRobotsSize)
+	if err != nil {
+		log.Printf("Warning: web-agent could not read %s: %v", robotsURL, err)
+		return nil, true
+	}
+
+	return parseRobots(content, wa.userAgent), true
+}
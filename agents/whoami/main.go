@@ -4,7 +4,8 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"os/exec
+	"os/exec"
+	"strings"
 
 	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
 )
@@ -32,14 +33,16 @@ func (a *WhoamiAgent) Process(ctx context.Context, input interfaces.AgentInput)
 	output, err := cmd.Output()
 	if err != nil {
 		return interfaces.AgentOutput{
-			Text:     fmt.Sprintf("Error executing whoami: %v", err),
-			Finished: true,
-		}, err
+			Success: false,
+			Error:   fmt.Sprintf("Error executing whoami: %v", err),
+		}, nil
 	}
 
 	return interfaces.AgentOutput{
-		Text:     string(output),
-		Finished: true,
+		Success: true,
+		Data: map[string]interface{}{
+			"output": strings.TrimSpace(string(output)),
+		},
 	}, nil
 }
 
@@ -52,5 +55,10 @@ func (a *WhoamiAgent) Shutdown() error {
 	return nil
 }
 
+// Classification declares that the agent only reports the user
+func (a *WhoamiAgent) Classification() interfaces.Classification {
+	return interfaces.Classification{ReadOnly: true, Idempotent: true}
+}
+
 // Export the agent for plugin loading
 var Agent interfaces.Agent = NewWhoamiAgent()
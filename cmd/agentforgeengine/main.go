@@ -1,13 +1,28 @@
 package main
 
 import (
+	"context"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/AgentForgeEngine/AgentForgeEngine/internal/cmd"
 )
 
 func main() {
-	if err := cmd.Execute(); err != nil {
+	// Cancel the root context on SIGINT/SIGTERM so long-running commands
+	// can shut down gracefully
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		// Restore default handling after the first signal so a second one
+		// terminates a shutdown that hangs
+		<-ctx.Done()
+		stop()
+	}()
+
+	err := cmd.ExecuteContext(ctx)
+	stop()
+	if err != nil {
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// helperEnv makes the test binary run main with the arguments in helperArgsEnv
+// instead of the tests, so it can be driven as a real afe subprocess
+const (
+	helperEnv     = "AFE_TEST_RUN_MAIN"
+	helperArgsEnv = "AFE_TEST_ARGS"
+)
+
+func TestMain(m *testing.M) {
+	if os.Getenv(helperEnv) == "1" {
+		os.Args = append([]string{"afe"}, strings.Fields(os.Getenv(helperArgsEnv))...)
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestStart_ShutsDownCleanlyOnSIGTERM(t *testing.T) {
+	home := t.TempDir()
+	afeDir := filepath.Join(home, ".afe")
+	if err := os.MkdirAll(afeDir, 0755); err != nil {
+		t.Fatalf("Failed to create afe dir: %v", err)
+	}
+
+	configPath := filepath.Join(home, "afe.yaml")
+	config := "server:\n  host: 127.0.0.1\n  port: 0\n"
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	var output bytes.Buffer
+	cmd := exec.Command(os.Args[0])
+	cmd.Env = append(os.Environ(),
+		helperEnv+"=1",
+		helperArgsEnv+"=start --config "+configPath,
+		"HOME="+home,
+	)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start afe: %v", err)
+	}
+
+	// The PID file is written after the signal handler is installed
+	pidFile := filepath.Join(afeDir, "afe.pid")
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		if _, err := os.Stat(pidFile); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			cmd.Process.Kill()
+			cmd.Wait()
+			t.Fatalf("afe did not start in time, output:\n%s", output.String())
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	if err := cmd.Process.Signal(syscall.SIGTERM); err != nil {
+		t.Fatalf("Failed to send SIGTERM: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Expected clean exit, got %v, output:\n%s", err, output.String())
+		}
+	case <-time.After(15 * time.Second):
+		cmd.Process.Kill()
+		t.Fatalf("afe did not shut down after SIGTERM, output:\n%s", output.String())
+	}
+
+	if !strings.Contains(output.String(), "AgentForgeEngine stopped") {
+		t.Errorf("Expected graceful shutdown message, got:\n%s", output.String())
+	}
+	if _, err := os.Stat(pidFile); !os.IsNotExist(err) {
+		t.Errorf("Expected PID file to be removed on shutdown, stat error: %v", err)
+	}
+}
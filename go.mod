@@ -11,6 +11,8 @@ require (
 	github.com/spf13/viper v1.18.2
 	github.com/syndtr/goleveldb v1.0.0
 	golang.org/x/crypto v0.47.0
+	golang.org/x/term v0.39.0
+	golang.org/x/time v0.14.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
@@ -33,7 +35,6 @@ require (
 	golang.org/x/exp v0.0.0-20230905200255-921286631fa9 // indirect
 	golang.org/x/net v0.48.0 // indirect
 	golang.org/x/sys v0.40.0 // indirect
-	golang.org/x/term v0.39.0 // indirect
 	golang.org/x/text v0.33.0 // indirect
 	gopkg.in/ini.v1 v1.67.0 // indirect
 )
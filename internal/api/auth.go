@@ -0,0 +1,218 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/internal/policy"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/auth"
+	"github.com/gorilla/websocket"
+)
+
+// Authenticator checks the credentials of API requests; *auth.UserManager
+// implements it
+type Authenticator interface {
+	ValidateAPIKey(apiKey string) (*auth.User, *auth.APIKey, error)
+	AuthenticateUser(email, password string) (*auth.User, error)
+	GetUserByUID(uid string) (*auth.User, error)
+}
+
+// Principal is the caller a request was authenticated as
+type Principal struct {
+	User *auth.User
+	// APIKey is the key the request presented, or nil when it presented a
+	// session token, which has the scopes of the user's roles
+	APIKey *auth.APIKey
+}
+
+// HasScope reports whether the principal may use routes that need scope
+func (p *Principal) HasScope(scope string) bool {
+	if p.APIKey == nil {
+		return p.User.HasScope(scope)
+	}
+	return p.APIKey.HasScope(scope)
+}
+
+// Identity names the principal by its API key, or by its user when it
+// presented a session token
+func (p *Principal) Identity() string {
+	if p.APIKey != nil {
+		return "key:" + p.APIKey.KeyID
+	}
+	return "user:" + p.User.UID
+}
+
+type contextKey int
+
+const (
+	principalKey contextKey = iota
+	chatStreamKey
+)
+
+// PrincipalFromContext returns the caller attached to a request's context by
+// the auth middleware; there is none when auth is disabled
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey).(*Principal)
+	return principal, ok
+}
+
+// policyCaller describes who made an agent call through source, such as
+// "chat" or "api", to the policy. A caller that presented an API key limited
+// to some agents may call only those.
+func policyCaller(ctx context.Context, source string) policy.Caller {
+	caller := policy.Caller{Source: source}
+	if principal, ok := PrincipalFromContext(ctx); ok {
+		caller.Identity = principal.Identity()
+		if principal.APIKey != nil {
+			caller.AllowedAgents = principal.APIKey.AllowedAgents
+		}
+	}
+	return caller
+}
+
+// SetAuth enables authentication: every route but health and login then
+// needs an API key or a session token issued by tokens. A nil authenticator
+// disables it. An authenticator that is also a UserAdmin serves the admin
+// user routes.
+func (s *Server) SetAuth(authenticator Authenticator, tokens *auth.TokenSigner) {
+	s.authenticator = authenticator
+	s.tokens = tokens
+	s.users, _ = authenticator.(UserAdmin)
+}
+
+// requireAuth wraps a handler so that, when auth is enabled, it runs only for
+// requests whose caller has scope, with the caller in the request's context.
+// Missing or invalid credentials are a 401 and a missing scope a 403.
+func (s *Server) requireAuth(scope string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.authenticator == nil {
+			handler(w, r)
+			return
+		}
+
+		principal, err := s.authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="afe"`)
+			s.sendError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		if !principal.HasScope(scope) {
+			credential := "API key"
+			if principal.APIKey == nil {
+				credential = "user"
+			}
+			s.sendError(w, http.StatusForbidden, fmt.Sprintf("%s lacks the %s scope", credential, scope))
+			return
+		}
+
+		handler(w, r.WithContext(context.WithValue(r.Context(), principalKey, principal)))
+	}
+}
+
+// authenticate resolves the credentials of a request: an X-API-Key header,
+// or an Authorization bearer holding a session token or an API key. Browsers
+// cannot set headers on a WebSocket upgrade, so it may pass api_key or token
+// as query parameters instead.
+func (s *Server) authenticate(r *http.Request) (*Principal, error) {
+	apiKey := r.Header.Get("X-API-Key")
+	token := ""
+	if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		// Session tokens have a signature after a dot; API keys have none
+		if strings.Contains(bearer, ".") {
+			token = bearer
+		} else if apiKey == "" {
+			apiKey = bearer
+		}
+	}
+	if apiKey == "" && token == "" && websocket.IsWebSocketUpgrade(r) {
+		apiKey = r.URL.Query().Get("api_key")
+		token = r.URL.Query().Get("token")
+	}
+
+	switch {
+	case apiKey != "":
+		user, key, err := s.authenticator.ValidateAPIKey(apiKey)
+		if err != nil {
+			if errors.Is(err, auth.ErrAPIKeyExpired) {
+				return nil, err
+			}
+			return nil, auth.ErrInvalidAPIKey
+		}
+		return &Principal{User: user, APIKey: key}, nil
+
+	case token != "" && s.tokens != nil:
+		uid, err := s.tokens.Verify(token)
+		if err != nil {
+			return nil, err
+		}
+		user, err := s.authenticator.GetUserByUID(uid)
+		if err != nil || !user.IsActive {
+			return nil, auth.ErrInvalidToken
+		}
+		return &Principal{User: user}, nil
+	}
+
+	return nil, errors.New("authentication required")
+}
+
+// LoginRequest holds the credentials exchanged for a session token
+type LoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// LoginResponse is a session token and the user it authenticates
+type LoginResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	User      struct {
+		UID   string `json:"uid"`
+		Name  string `json:"name"`
+		Email string `json:"email"`
+	} `json:"user"`
+}
+
+// handleLogin exchanges an email and password for a session token, sent
+// back as an Authorization bearer
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		s.sendError(w, http.StatusMethodNotAllowed, "Only POST method allowed")
+		return
+	}
+	if s.authenticator == nil || s.tokens == nil {
+		s.sendError(w, http.StatusNotFound, "Authentication is not enabled")
+		return
+	}
+
+	var req LoginRequest
+	if !s.decodeJSON(w, r, &req) {
+		return
+	}
+	if req.Email == "" || req.Password == "" {
+		s.sendError(w, http.StatusBadRequest, "Email and password are required")
+		return
+	}
+
+	// The reason a login failed is not told to the caller
+	user, err := s.authenticator.AuthenticateUser(req.Email, req.Password)
+	if err != nil {
+		s.sendError(w, http.StatusUnauthorized, "Invalid email or password")
+		return
+	}
+
+	token, expiresAt, err := s.tokens.Issue(user.UID)
+	if err != nil {
+		s.sendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to issue token: %v", err))
+		return
+	}
+
+	response := LoginResponse{Token: token, ExpiresAt: expiresAt.UTC()}
+	response.User.UID = user.UID
+	response.User.Name = user.Name
+	response.User.Email = user.Email
+	s.sendSuccess(w, response)
+}
@@ -0,0 +1,309 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/internal/policy"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/auth"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+	"github.com/gorilla/websocket"
+)
+
+// authFixture is a server with auth enabled against a temporary account
+// store holding one user and three of their keys
+type authFixture struct {
+	server  *Server
+	handler http.Handler
+	users   *auth.UserManager
+	// readKey has the chat and agents:read scopes, expiredKey the same scopes
+	// but expired an hour ago, and execKey only agents:execute
+	readKey, expiredKey, execKey string
+}
+
+func newAuthFixture(t *testing.T) *authFixture {
+	t.Helper()
+
+	users, err := auth.NewUserManager(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewUserManager failed: %v", err)
+	}
+	t.Cleanup(func() { users.Close() })
+
+	user, err := users.CreateUser("Ada", "ada@example.com", "correct horse", nil)
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+
+	fixture := &authFixture{users: users}
+	expired := time.Now().Add(-time.Hour)
+	for _, key := range []struct {
+		dest    *string
+		expires *time.Time
+		scopes  []string
+	}{
+		{&fixture.readKey, nil, []string{auth.ScopeChat, auth.ScopeAgentsRead}},
+		{&fixture.expiredKey, &expired, []string{auth.ScopeChat, auth.ScopeAgentsRead}},
+		{&fixture.execKey, nil, []string{auth.ScopeAgentsExecute}},
+	} {
+		if _, *key.dest, err = users.CreateAPIKey(user.UID, "test", key.expires, key.scopes); err != nil {
+			t.Fatalf("CreateAPIKey failed: %v", err)
+		}
+	}
+
+	tokens, err := auth.NewTokenSigner([]byte("test secret"), time.Hour)
+	if err != nil {
+		t.Fatalf("NewTokenSigner failed: %v", err)
+	}
+
+	fixture.server = NewServer("localhost", 0)
+	fixture.server.pluginManager = fakeRegistry{"ls": &fakeAgent{name: "ls", output: interfaces.AgentOutput{Success: true}}}
+	fixture.server.SetAuth(users, tokens)
+	fixture.handler = fixture.server.wrapHandlers()
+	return fixture
+}
+
+// do sends a request through the server's routes with the given headers
+func (f *authFixture) do(method, path, body string, headers map[string]string) (int, APIResponse) {
+	request := httptest.NewRequest(method, path, strings.NewReader(body))
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+	recorder := httptest.NewRecorder()
+	f.handler.ServeHTTP(recorder, request)
+
+	var response APIResponse
+	json.Unmarshal(recorder.Body.Bytes(), &response)
+	return recorder.Code, response
+}
+
+// tamper changes the last character of a key
+func tamper(key string) string {
+	if strings.HasSuffix(key, "0") {
+		return key[:len(key)-1] + "1"
+	}
+	return key[:len(key)-1] + "0"
+}
+
+func TestRequireAuth_APIKeys(t *testing.T) {
+	f := newAuthFixture(t)
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		headers map[string]string
+		status  int
+		err     string
+	}{
+		{"health is open", http.MethodGet, "/api/v1/health", nil, http.StatusOK, ""},
+		{"no credentials", http.MethodGet, "/api/v1/agents", nil, http.StatusUnauthorized, "authentication required"},
+		{"valid key", http.MethodGet, "/api/v1/agents", map[string]string{"X-API-Key": f.readKey}, http.StatusOK, ""},
+		{"valid key as bearer", http.MethodGet, "/api/v1/agents", map[string]string{"Authorization": "Bearer " + f.readKey}, http.StatusOK, ""},
+		{"expired key", http.MethodGet, "/api/v1/agents", map[string]string{"X-API-Key": f.expiredKey}, http.StatusUnauthorized, "API key expired"},
+		{"tampered key", http.MethodGet, "/api/v1/agents", map[string]string{"X-API-Key": tamper(f.readKey)}, http.StatusUnauthorized, "invalid API key"},
+		{"insufficient scope", http.MethodPost, "/api/v1/agents/ls", map[string]string{"X-API-Key": f.readKey}, http.StatusForbidden, "API key lacks the agents:execute scope"},
+		{"scope granted", http.MethodPost, "/api/v1/agents/ls", map[string]string{"X-API-Key": f.execKey}, http.StatusOK, ""},
+		{"admin routes need admin", http.MethodGet, "/api/v1/logs", map[string]string{"X-API-Key": f.execKey}, http.StatusForbidden, "API key lacks the admin scope"},
+	}
+
+	allowAgents(f.server, "ls")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, response := f.do(tt.method, tt.path, `{"type": "execute"}`, tt.headers)
+			if status != tt.status {
+				t.Fatalf("Expected %d, got %d: %s", tt.status, status, response.Error)
+			}
+			if response.Error != tt.err {
+				t.Errorf("Expected error %q, got %q", tt.err, response.Error)
+			}
+		})
+	}
+}
+
+func TestRequireAuth_AttachesPrincipal(t *testing.T) {
+	f := newAuthFixture(t)
+
+	var principal *Principal
+	handler := f.server.requireAuth(auth.ScopeChat, func(w http.ResponseWriter, r *http.Request) {
+		principal, _ = PrincipalFromContext(r.Context())
+	})
+
+	request := httptest.NewRequest(http.MethodGet, "/api/v1/chat", nil)
+	request.Header.Set("X-API-Key", f.readKey)
+	handler(httptest.NewRecorder(), request)
+
+	if principal == nil || principal.User.Email != "ada@example.com" || principal.APIKey == nil {
+		t.Fatalf("Expected the key's user in the context, got %+v", principal)
+	}
+	if !principal.HasScope(auth.ScopeChat) || principal.HasScope(auth.ScopeAdmin) {
+		t.Errorf("Expected the key's scopes, got %v", principal.APIKey.Scopes)
+	}
+}
+
+func TestAPIKey_AllowedAgents(t *testing.T) {
+	f := newAuthFixture(t)
+	cat := &fakeAgent{name: "cat", output: interfaces.AgentOutput{Success: true}}
+	f.server.pluginManager = fakeRegistry{"ls": f.server.pluginManager.(fakeRegistry)["ls"], "cat": cat}
+	allowAgents(f.server, "ls", "cat")
+
+	user, key, err := f.users.ValidateAPIKey(f.execKey)
+	if err != nil {
+		t.Fatalf("ValidateAPIKey failed: %v", err)
+	}
+	if err := f.users.SetAPIKeyAgents(user.UID, key.KeyID, []string{"cat"}); err != nil {
+		t.Fatalf("SetAPIKeyAgents failed: %v", err)
+	}
+
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+
+	headers := map[string]string{"X-API-Key": f.execKey}
+	if status, response := f.do(http.MethodPost, "/api/v1/agents/cat", `{"type": "execute"}`, headers); status != http.StatusOK {
+		t.Errorf("Expected the key's agent to run, got %d: %s", status, response.Error)
+	}
+	status, response := f.do(http.MethodPost, "/api/v1/agents/ls", `{"type": "execute"}`, headers)
+	if status != http.StatusForbidden || response.Code != policy.CodeAgentDenied {
+		t.Fatalf("Expected another agent to be denied, got %d: %+v", status, response)
+	}
+	if !strings.Contains(logged.String(), "policy: denied api call by key:"+key.KeyID+" to ls") {
+		t.Errorf("Expected the denial to be logged with the key, got %q", logged.String())
+	}
+}
+
+func TestHandleLogin(t *testing.T) {
+	f := newAuthFixture(t)
+
+	status, _ := f.do(http.MethodPost, "/api/v1/auth/login", `{"email": "ada@example.com", "password": "wrong"}`, nil)
+	if status != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a wrong password, got %d", status)
+	}
+
+	status, response := f.do(http.MethodPost, "/api/v1/auth/login", `{"email": "ada@example.com", "password": "correct horse"}`, nil)
+	if status != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", status, response.Error)
+	}
+	token, _ := response.Data.(map[string]interface{})["token"].(string)
+	if token == "" {
+		t.Fatalf("Expected a token, got %v", response.Data)
+	}
+
+	// A session token has the scopes of the user's roles
+	allowAgents(f.server, "ls")
+	if status, response := f.do(http.MethodPost, "/api/v1/agents/ls", `{"type": "execute"}`, map[string]string{"Authorization": "Bearer " + token}); status != http.StatusOK {
+		t.Errorf("Expected the token to be accepted, got %d: %s", status, response.Error)
+	}
+	if status, response := f.do(http.MethodGet, "/api/v1/logs", "", map[string]string{"Authorization": "Bearer " + token}); status != http.StatusForbidden || response.Error != "user lacks the admin scope" {
+		t.Errorf("Expected 403 for a user without the admin role, got %d: %s", status, response.Error)
+	}
+
+	other, _ := auth.NewTokenSigner([]byte("other secret"), time.Hour)
+	forged, _, _ := other.Issue(response.Data.(map[string]interface{})["user"].(map[string]interface{})["uid"].(string))
+	if status, _ := f.do(http.MethodGet, "/api/v1/agents", "", map[string]string{"Authorization": "Bearer " + forged}); status != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a token signed with another secret, got %d", status)
+	}
+}
+
+func TestRequireAuth_WebSocket(t *testing.T) {
+	f := newAuthFixture(t)
+	httpServer := httptest.NewServer(f.handler)
+	defer httpServer.Close()
+	url := "ws" + strings.TrimPrefix(httpServer.URL, "http") + "/api/v1/events"
+
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	if err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Expected the upgrade to be refused with 401, got %v", err)
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(url+"?api_key="+f.readKey, nil)
+	if err != nil {
+		t.Fatalf("Expected the key in the query to be accepted: %v", err)
+	}
+	conn.Close()
+}
+
+func TestUserAdminRoutes(t *testing.T) {
+	f := newAuthFixture(t)
+	ada, _ := f.users.GetUserByEmail("ada@example.com")
+	adminUser, _ := f.users.CreateUser("Root", "root@example.com", "secret", nil)
+	_, adminKey, _ := f.users.CreateAPIKey(adminUser.UID, "admin", nil, []string{auth.ScopeAdmin})
+	admin := map[string]string{"X-API-Key": adminKey}
+
+	if status, _ := f.do(http.MethodGet, "/api/v1/users", "", map[string]string{"X-API-Key": f.readKey}); status != http.StatusForbidden {
+		t.Errorf("Expected 403 without the admin scope, got %d", status)
+	}
+
+	status, response := f.do(http.MethodGet, "/api/v1/users?limit=1", "", admin)
+	if status != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", status, response.Error)
+	}
+	page := response.Data.(map[string]interface{})
+	if users := page["users"].([]interface{}); len(users) != 1 || page["next"] == "" {
+		t.Errorf("Expected one user and a cursor, got %v", page)
+	}
+	if _, ok := page["users"].([]interface{})[0].(map[string]interface{})["password_hash"]; ok {
+		t.Error("Expected the password hash to be left out")
+	}
+
+	if status, _ := f.do(http.MethodPost, "/api/v1/users/"+ada.UID+"/password", `{"old_password": "wrong", "new_password": "battery staple"}`, admin); status != http.StatusForbidden {
+		t.Errorf("Expected 403 for a wrong current password, got %d", status)
+	}
+	if status, response := f.do(http.MethodPost, "/api/v1/users/"+ada.UID+"/password", `{"old_password": "correct horse", "new_password": "battery staple"}`, admin); status != http.StatusOK {
+		t.Errorf("Expected the password change to succeed, got %d: %s", status, response.Error)
+	}
+
+	if status, response := f.do(http.MethodPost, "/api/v1/users/"+ada.UID+"/deactivate", "", admin); status != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", status, response.Error)
+	}
+	if status, _ := f.do(http.MethodGet, "/api/v1/agents", "", map[string]string{"X-API-Key": f.readKey}); status != http.StatusUnauthorized {
+		t.Errorf("Expected a deactivated user's key to be refused, got %d", status)
+	}
+
+	status, response = f.do(http.MethodGet, "/api/v1/users?active=false", "", admin)
+	if users := response.Data.(map[string]interface{})["users"].([]interface{}); status != http.StatusOK || len(users) != 1 {
+		t.Errorf("Expected only the deactivated user, got %d: %v", status, response.Data)
+	}
+
+	if status, _ := f.do(http.MethodPost, "/api/v1/users/missing/reactivate", "", admin); status != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown user, got %d", status)
+	}
+}
+
+func TestAdminRoutes_NeedAdminRole(t *testing.T) {
+	f := newAuthFixture(t)
+	login := func(email, password string) map[string]string {
+		t.Helper()
+		status, response := f.do(http.MethodPost, "/api/v1/auth/login", `{"email": "`+email+`", "password": "`+password+`"}`, nil)
+		if status != http.StatusOK {
+			t.Fatalf("Login failed: %d %s", status, response.Error)
+		}
+		return map[string]string{"Authorization": "Bearer " + response.Data.(map[string]interface{})["token"].(string)}
+	}
+
+	// A plain user may log in but not use the admin routes
+	user := login("ada@example.com", "correct horse")
+	for _, route := range []struct{ method, path string }{
+		{http.MethodGet, "/api/v1/users"},
+		{http.MethodPost, "/api/v1/stop"},
+	} {
+		if status, response := f.do(route.method, route.path, "", user); status != http.StatusForbidden {
+			t.Errorf("Expected 403 for %s %s with a user token, got %d: %s", route.method, route.path, status, response.Error)
+		}
+	}
+
+	root, _ := f.users.CreateUser("Root", "root@example.com", "secret", nil)
+	if _, err := f.users.UpdateUser(root.UID, map[string]interface{}{"roles": []string{auth.RoleAdmin}}); err != nil {
+		t.Fatalf("UpdateUser failed: %v", err)
+	}
+	if status, response := f.do(http.MethodGet, "/api/v1/users", "", login("root@example.com", "secret")); status != http.StatusOK {
+		t.Errorf("Expected an admin's token to list users, got %d: %s", status, response.Error)
+	}
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+// coerceInput converts the payload of input to the types the agent declares,
+// if it declares any. The payload is copied, so the caller's map, which may
+// be reported back as the call's arguments, keeps the values as sent.
+func coerceInput(agent interfaces.Agent, input interfaces.AgentInput) (interfaces.AgentInput, error) {
+	provider, ok := agent.(interfaces.SchemaProvider)
+	if !ok || len(input.Payload) == 0 {
+		return input, nil
+	}
+
+	payload, err := coercePayload(provider.InputSchema(), input.Payload)
+	if err != nil {
+		return input, err
+	}
+	input.Payload = payload
+	return input, nil
+}
+
+// coercePayload returns a copy of payload with each value whose key the
+// schema lists converted to the declared type. Null values and keys the
+// schema does not list are copied unchanged.
+func coercePayload(schema interfaces.InputSchema, payload map[string]interface{}) (map[string]interface{}, error) {
+	coerced := make(map[string]interface{}, len(payload))
+	for key, value := range payload {
+		paramType, ok := schema[key]
+		if !ok || value == nil {
+			coerced[key] = value
+			continue
+		}
+
+		converted, err := coerceValue(paramType, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", key, err)
+		}
+		coerced[key] = converted
+	}
+	return coerced, nil
+}
+
+// coerceValue converts one value to paramType. Numbers become ints only
+// when they have no fraction, and strings are parsed, so a model that writes
+// "5" or 5.0 for a count gets the same result as one that writes 5.
+func coerceValue(paramType interfaces.ParamType, value interface{}) (interface{}, error) {
+	switch paramType {
+	case interfaces.ParamInt:
+		switch v := value.(type) {
+		case int:
+			return v, nil
+		case int64:
+			return int(v), nil
+		case float64:
+			if v != math.Trunc(v) || math.IsInf(v, 0) {
+				return nil, fmt.Errorf("%v is not an integer", v)
+			}
+			return int(v), nil
+		case string:
+			n, err := strconv.Atoi(strings.TrimSpace(v))
+			if err != nil {
+				return nil, fmt.Errorf("%q is not an integer", v)
+			}
+			return n, nil
+		}
+	case interfaces.ParamFloat:
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case int:
+			return float64(v), nil
+		case int64:
+			return float64(v), nil
+		case string:
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return nil, fmt.Errorf("%q is not a number", v)
+			}
+			return f, nil
+		}
+	case interfaces.ParamBool:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			b, err := strconv.ParseBool(strings.TrimSpace(v))
+			if err != nil {
+				return nil, fmt.Errorf("%q is not a boolean", v)
+			}
+			return b, nil
+		}
+	case interfaces.ParamString:
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		case int:
+			return strconv.Itoa(v), nil
+		case bool:
+			return strconv.FormatBool(v), nil
+		}
+	case interfaces.ParamStrings:
+		switch v := value.(type) {
+		case []string:
+			return v, nil
+		case string:
+			// A single value where a list is expected
+			return []string{v}, nil
+		case []interface{}:
+			strs := make([]string, len(v))
+			for i, item := range v {
+				s, err := coerceValue(interfaces.ParamString, item)
+				if err != nil {
+					return nil, fmt.Errorf("item %d: %v", i, err)
+				}
+				strs[i] = s.(string)
+			}
+			return strs, nil
+		}
+	default:
+		// Types this engine does not know are left to the agent
+		return value, nil
+	}
+	return nil, fmt.Errorf("expected %s, got %T", paramType, value)
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+// schemaAgent is a fakeAgent that declares its parameter types
+type schemaAgent struct {
+	*fakeAgent
+	schema interfaces.InputSchema
+}
+
+func (a schemaAgent) InputSchema() interfaces.InputSchema { return a.schema }
+
+var testSchema = interfaces.InputSchema{
+	"count":   interfaces.ParamInt,
+	"ratio":   interfaces.ParamFloat,
+	"recurse": interfaces.ParamBool,
+	"name":    interfaces.ParamString,
+	"include": interfaces.ParamStrings,
+}
+
+func TestCoercePayload(t *testing.T) {
+	tests := []struct {
+		name     string
+		payload  map[string]interface{}
+		expected map[string]interface{}
+		wantErr  bool
+	}{
+		{
+			name:     "float to int",
+			payload:  map[string]interface{}{"count": float64(5)},
+			expected: map[string]interface{}{"count": 5},
+		},
+		{
+			name:     "strings parsed",
+			payload:  map[string]interface{}{"count": "12", "ratio": "0.5", "recurse": "true"},
+			expected: map[string]interface{}{"count": 12, "ratio": 0.5, "recurse": true},
+		},
+		{
+			name:     "numbers to strings",
+			payload:  map[string]interface{}{"name": float64(42), "include": []interface{}{"*.go", float64(7)}},
+			expected: map[string]interface{}{"name": "42", "include": []string{"*.go", "7"}},
+		},
+		{
+			name:     "single string to list",
+			payload:  map[string]interface{}{"include": "*.md"},
+			expected: map[string]interface{}{"include": []string{"*.md"}},
+		},
+		{
+			name:     "undeclared keys and nulls unchanged",
+			payload:  map[string]interface{}{"path": float64(1), "count": nil},
+			expected: map[string]interface{}{"path": float64(1), "count": nil},
+		},
+		{
+			name:    "fraction is not an int",
+			payload: map[string]interface{}{"count": 2.5},
+			wantErr: true,
+		},
+		{
+			name:    "unparseable bool",
+			payload: map[string]interface{}{"recurse": "maybe"},
+			wantErr: true,
+		},
+		{
+			name:    "object for int",
+			payload: map[string]interface{}{"count": map[string]interface{}{}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			coerced, err := coercePayload(testSchema, tt.payload)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected an error, got %v", coerced)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(coerced, tt.expected) {
+				t.Errorf("Expected %#v, got %#v", tt.expected, coerced)
+			}
+		})
+	}
+}
+
+func TestHandleCallAgent_CoercesPayload(t *testing.T) {
+	agent := schemaAgent{&fakeAgent{name: "grep", output: interfaces.AgentOutput{Success: true}}, testSchema}
+	server := NewServer("localhost", 0)
+	server.pluginManager = fakeRegistry{"grep": agent}
+	allowAgents(server, "grep")
+
+	status, _ := callAgent(t, server, "/api/v1/agents/grep", `{"type": "execute", "payload": {"count": 3, "pattern": "TODO"}}`)
+	if status != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", status)
+	}
+	if count, ok := agent.input.Payload["count"].(int); !ok || count != 3 {
+		t.Errorf("Expected count to arrive as int 3, got %#v", agent.input.Payload["count"])
+	}
+	if agent.input.Payload["pattern"] != "TODO" {
+		t.Errorf("Expected undeclared keys to pass through, got %v", agent.input.Payload)
+	}
+
+	status, response := callAgent(t, server, "/api/v1/agents/grep", `{"type": "execute", "payload": {"count": 1.5}}`)
+	if status != http.StatusBadRequest || agent.calls != 1 {
+		t.Errorf("Expected 400 without calling the agent, got %d: %s", status, response.Error)
+	}
+}
+
+func TestExecuteFunctionCall_CoercesArguments(t *testing.T) {
+	agent := schemaAgent{&fakeAgent{name: "ls", output: interfaces.AgentOutput{Success: true}}, testSchema}
+	server := NewServer("localhost", 0)
+	server.pluginManager = fakeRegistry{"ls": agent}
+	allowAgents(server, "ls")
+
+	call := FunctionCall{Name: "ls", Arguments: map[string]interface{}{"count": float64(2)}}
+	server.executeFunctionCall(context.Background(), &call)
+
+	if !call.Response.Success {
+		t.Fatalf("Expected the call to succeed, got %s", call.Response.Error)
+	}
+	if count, ok := agent.input.Payload["count"].(int); !ok || count != 2 {
+		t.Errorf("Expected count to arrive as int 2, got %#v", agent.input.Payload["count"])
+	}
+	// The trace keeps the arguments as the model wrote them
+	if _, ok := call.Arguments["count"].(float64); !ok {
+		t.Errorf("Expected the call's arguments unchanged, got %#v", call.Arguments["count"])
+	}
+}
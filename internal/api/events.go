@@ -0,0 +1,374 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/internal/logging"
+	"github.com/AgentForgeEngine/AgentForgeEngine/internal/orchestrator"
+	"github.com/gorilla/websocket"
+)
+
+// Topics /api/v1/events clients subscribe to. A client that has not
+// subscribed receives every topic but logs, which only clients of
+// /api/v1/logs?follow=true receive.
+const (
+	TopicChat      = "chat"
+	TopicAgents    = "agents"
+	TopicBuild     = "build"
+	TopicWorkflows = orchestrator.EventTopic
+	TopicLogs      = "logs"
+)
+
+var eventTopics = map[string]bool{TopicChat: true, TopicAgents: true, TopicBuild: true, TopicWorkflows: true}
+
+// Types of the events sent to /api/v1/events clients
+const (
+	EventWelcome      = "welcome"
+	EventSubscribed   = "subscribed"
+	EventError        = "error"
+	EventChatStart    = "chat_start"
+	EventChatDelta    = "chat_delta"
+	EventChatToolCall = "chat_tool_call"
+	EventChatComplete = "chat_complete"
+	EventAgentCall    = "agent_call"
+	EventLog          = "log"
+)
+
+const (
+	// clientSendBuffer is how many events may wait for one client's writer;
+	// a client that falls further behind is disconnected
+	clientSendBuffer = 256
+	// wsWriteTimeout bounds a write to one WebSocket client
+	wsWriteTimeout = 5 * time.Second
+	// wsPongWait is how long a client may stay silent, answering no ping,
+	// before it is disconnected; pings are sent every wsPingPeriod
+	wsPongWait   = 60 * time.Second
+	wsPingPeriod = wsPongWait * 9 / 10
+	// wsMaxMessageSize bounds the messages a client sends, which are only
+	// subscription changes
+	wsMaxMessageSize = 4096
+)
+
+// eventClient is one connection to /api/v1/events. Only its writer goroutine
+// writes to conn.
+type eventClient struct {
+	conn *websocket.Conn
+	send chan []byte
+	// topics the client subscribed to, or nil for every topic; guarded by
+	// the hub's lock
+	topics map[string]bool
+	// filter, when set, drops the events it returns false for
+	filter    func(event map[string]interface{}) bool
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func (c *eventClient) close() {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		c.conn.Close()
+	})
+}
+
+func (c *eventClient) subscribed(topic string, event map[string]interface{}) bool {
+	if c.topics == nil && !eventTopics[topic] || c.topics != nil && !c.topics[topic] {
+		return false
+	}
+	return c.filter == nil || c.filter(event)
+}
+
+// topicList returns the topics the client receives, sorted
+func (c *eventClient) topicList() []string {
+	topics := make([]string, 0, len(eventTopics))
+	for topic := range eventTopics {
+		if c.topics == nil || c.topics[topic] {
+			topics = append(topics, topic)
+		}
+	}
+	if c.topics[TopicLogs] {
+		topics = append(topics, TopicLogs)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// eventHub numbers published events and queues them for each subscribed
+// client without blocking the publisher. A client whose queue is full is
+// disconnected; it can reconnect and use the sequence numbers to see what it
+// missed.
+type eventHub struct {
+	mu         sync.Mutex
+	seq        uint64
+	clients    map[*eventClient]bool
+	bufferSize int
+	logger     *logging.Logger
+}
+
+func newEventHub(bufferSize int) *eventHub {
+	return &eventHub{clients: make(map[*eventClient]bool), bufferSize: bufferSize, logger: logging.New("api")}
+}
+
+// publish sends an event to the clients subscribed to topic, adding the
+// topic and the next sequence number to it
+func (h *eventHub) publish(topic string, event map[string]interface{}) {
+	h.mu.Lock()
+
+	numbered := make(map[string]interface{}, len(event)+2)
+	for key, value := range event {
+		numbered[key] = value
+	}
+	numbered["topic"] = topic
+	numbered["seq"] = h.seq + 1
+
+	data, err := json.Marshal(numbered)
+	if err != nil {
+		h.mu.Unlock()
+		h.logger.Errorf("Failed to marshal %v event: %v", event["type"], err)
+		return
+	}
+	h.seq++
+
+	var dropped []*eventClient
+	for client := range h.clients {
+		if !client.subscribed(topic, event) {
+			continue
+		}
+		select {
+		case client.send <- data:
+		default:
+			delete(h.clients, client)
+			client.close()
+			dropped = append(dropped, client)
+		}
+	}
+	h.mu.Unlock()
+
+	// Logging publishes log records, so it must wait until the lock is
+	// released
+	for _, client := range dropped {
+		h.logger.Warnf("WebSocket client %s is too slow, disconnecting", client.conn.RemoteAddr())
+	}
+}
+
+// register adds a client after queueing its welcome, which carries the
+// sequence number of the last event so the client knows where it starts
+func (h *eventHub) register(client *eventClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.sendLocked(client, map[string]interface{}{
+		"type":      EventWelcome,
+		"message":   "Connected to AgentForgeEngine API",
+		"seq":       h.seq,
+		"topics":    client.topicList(),
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+	})
+	h.clients[client] = true
+}
+
+func (h *eventHub) unregister(client *eventClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.clients, client)
+}
+
+// subscribe changes the topics of a client and answers with the topics it
+// now receives, or with an error naming an unknown topic
+func (h *eventHub) subscribe(client *eventClient, add, remove []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, topic := range append(append([]string(nil), add...), remove...) {
+		if !eventTopics[topic] {
+			h.sendLocked(client, map[string]interface{}{"type": EventError, "error": fmt.Sprintf("unknown topic %q", topic)})
+			return
+		}
+	}
+
+	// The first subscription narrows a client from every topic to those
+	// it names
+	if client.topics == nil {
+		client.topics = make(map[string]bool)
+		if len(add) == 0 {
+			for topic := range eventTopics {
+				client.topics[topic] = true
+			}
+		}
+	}
+	for _, topic := range add {
+		client.topics[topic] = true
+	}
+	for _, topic := range remove {
+		delete(client.topics, topic)
+	}
+
+	h.sendLocked(client, map[string]interface{}{"type": EventSubscribed, "topics": client.topicList()})
+}
+
+// send queues a reply for one client
+func (h *eventHub) send(client *eventClient, message map[string]interface{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sendLocked(client, message)
+}
+
+// sendLocked queues a reply for one client; replies have no sequence number.
+// The caller holds the lock.
+func (h *eventHub) sendLocked(client *eventClient, message map[string]interface{}) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return
+	}
+	select {
+	case client.send <- data:
+	default:
+	}
+}
+
+// closeAll disconnects every client, as the server stops
+func (h *eventHub) closeAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for client := range h.clients {
+		client.close()
+		delete(h.clients, client)
+	}
+}
+
+// flush waits until every client has been handed its queued events, or
+// until timeout
+func (h *eventHub) flush(timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for h.queued() > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// queued counts the events waiting in client queues
+func (h *eventHub) queued() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	queued := 0
+	for client := range h.clients {
+		queued += len(client.send)
+	}
+	return queued
+}
+
+func (h *eventHub) len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.clients)
+}
+
+// PublishEvent sends an event to the WebSocket clients subscribed to its
+// "topic" field, or to the agents topic when it names none, without waiting
+// for it to be sent
+func (s *Server) PublishEvent(event map[string]interface{}) {
+	topic, _ := event["topic"].(string)
+	if !eventTopics[topic] {
+		topic = TopicAgents
+	}
+	s.publish(topic, event)
+}
+
+// publish sends an event to the WebSocket clients subscribed to topic
+func (s *Server) publish(topic string, event map[string]interface{}) {
+	s.events.publish(topic, event)
+}
+
+// subscriptionRequest is what clients send to change their topics
+type subscriptionRequest struct {
+	Subscribe   []string `json:"subscribe"`
+	Unsubscribe []string `json:"unsubscribe"`
+}
+
+// handleWebSocket serves /api/v1/events. Clients receive every topic until
+// they send {"subscribe": [...]}; {"unsubscribe": [...]} drops topics again.
+// Clients that answer no ping within wsPongWait are disconnected.
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	s.serveEvents(w, r, nil, nil)
+}
+
+// serveEvents upgrades a request to a WebSocket receiving the given topics,
+// or the default ones when topics is nil, with the events filter accepts
+func (s *Server) serveEvents(w http.ResponseWriter, r *http.Request, topics []string, filter func(event map[string]interface{}) bool) {
+	conn, err := s.wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		s.logger.Warnf("WebSocket upgrade error: %v", err)
+		return
+	}
+
+	s.logger.Infof("WebSocket client connected: %s", conn.RemoteAddr())
+
+	client := &eventClient{
+		conn:   conn,
+		send:   make(chan []byte, s.events.bufferSize),
+		filter: filter,
+		closed: make(chan struct{}),
+	}
+	if topics != nil {
+		client.topics = make(map[string]bool, len(topics))
+		for _, topic := range topics {
+			client.topics[topic] = true
+		}
+	}
+	s.events.register(client)
+	defer func() {
+		s.events.unregister(client)
+		client.close()
+	}()
+	go s.writeEvents(client)
+
+	conn.SetReadLimit(wsMaxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			s.logger.Infof("WebSocket client disconnected: %v", err)
+			return
+		}
+		conn.SetReadDeadline(time.Now().Add(wsPongWait))
+
+		var req subscriptionRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			s.events.send(client, map[string]interface{}{"type": EventError, "error": "invalid message"})
+			continue
+		}
+		s.events.subscribe(client, req.Subscribe, req.Unsubscribe)
+	}
+}
+
+// writeEvents writes a client's queued events and pings it until it is
+// closed or a write fails
+func (s *Server) writeEvents(client *eventClient) {
+	ticker := time.NewTicker(wsPingPeriod)
+	defer ticker.Stop()
+	defer client.close()
+
+	for {
+		select {
+		case data := <-client.send:
+			client.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := client.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				s.logger.Warnf("WebSocket write error: %v", err)
+				return
+			}
+		case <-ticker.C:
+			if err := client.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)); err != nil {
+				return
+			}
+		case <-client.closed:
+			return
+		}
+	}
+}
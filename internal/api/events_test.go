@@ -0,0 +1,150 @@
+package api
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// dialEvents connects to /api/v1/events and reads the welcome
+func dialEvents(t *testing.T, url string) *websocket.Conn {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(url, "http")+"/api/v1/events", nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	if welcome := readEvent(t, conn); welcome["type"] != EventWelcome {
+		t.Fatalf("Expected a welcome message, got %v", welcome)
+	}
+	return conn
+}
+
+func readEvent(t *testing.T, conn *websocket.Conn) map[string]interface{} {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var event map[string]interface{}
+	if err := conn.ReadJSON(&event); err != nil {
+		t.Fatalf("Failed to read event: %v", err)
+	}
+	return event
+}
+
+// waitForClients waits until the hub has n clients
+func waitForClients(t *testing.T, server *Server, n int) {
+	t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); server.events.len() != n; time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d clients, have %d", n, server.events.len())
+		}
+	}
+}
+
+func TestServer_PublishEventReachesWebSocketClients(t *testing.T) {
+	server := NewServer("localhost", 0)
+	httpServer := httptest.NewServer(server.wrapHandlers())
+	defer httpServer.Close()
+
+	conn := dialEvents(t, httpServer.URL)
+	waitForClients(t, server, 1)
+
+	server.PublishEvent(map[string]interface{}{"type": "task_complete", "task_id": "task-1", "status": "completed"})
+
+	event := readEvent(t, conn)
+	if event["type"] != "task_complete" || event["task_id"] != "task-1" || event["status"] != "completed" {
+		t.Errorf("Unexpected event: %v", event)
+	}
+	if event["topic"] != TopicAgents || event["seq"] != float64(1) {
+		t.Errorf("Expected the first event of the agents topic, got %v", event)
+	}
+}
+
+func TestEvents_SubscriptionFiltering(t *testing.T) {
+	server := NewServer("localhost", 0)
+	httpServer := httptest.NewServer(server.wrapHandlers())
+	defer httpServer.Close()
+
+	chatOnly := dialEvents(t, httpServer.URL)
+	everything := dialEvents(t, httpServer.URL)
+	waitForClients(t, server, 2)
+
+	chatOnly.WriteJSON(map[string]interface{}{"subscribe": []string{"nonsense"}})
+	if reply := readEvent(t, chatOnly); reply["type"] != EventError {
+		t.Errorf("Expected an unknown topic to be refused, got %v", reply)
+	}
+	chatOnly.WriteJSON(map[string]interface{}{"subscribe": []string{TopicChat}})
+	if reply := readEvent(t, chatOnly); reply["type"] != EventSubscribed || len(reply["topics"].([]interface{})) != 1 {
+		t.Fatalf("Expected the subscription to be confirmed, got %v", reply)
+	}
+
+	server.publish(TopicAgents, map[string]interface{}{"type": EventAgentCall, "agent": "ls"})
+	server.publish(TopicBuild, map[string]interface{}{"type": "plugin_reloaded", "name": "ls"})
+	server.publish(TopicChat, map[string]interface{}{"type": EventChatStart, "chat_id": "chat_1"})
+
+	// The chat subscriber sees only the chat event, and the gap in seq
+	if event := readEvent(t, chatOnly); event["type"] != EventChatStart || event["seq"] != float64(3) {
+		t.Errorf("Expected only the chat event, got %v", event)
+	}
+	for i, expected := range []string{EventAgentCall, "plugin_reloaded", EventChatStart} {
+		if event := readEvent(t, everything); event["type"] != expected || event["seq"] != float64(i+1) {
+			t.Errorf("Expected %s as event %d, got %v", expected, i+1, event)
+		}
+	}
+
+	// Unsubscribing from the last topic silences the client
+	chatOnly.WriteJSON(map[string]interface{}{"unsubscribe": []string{TopicChat}})
+	if reply := readEvent(t, chatOnly); reply["type"] != EventSubscribed || len(reply["topics"].([]interface{})) != 0 {
+		t.Fatalf("Expected no topics left, got %v", reply)
+	}
+	server.publish(TopicChat, map[string]interface{}{"type": EventChatStart, "chat_id": "chat_2"})
+	readEvent(t, everything)
+	chatOnly.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	var event map[string]interface{}
+	if err := chatOnly.ReadJSON(&event); err == nil {
+		t.Errorf("Expected no event after unsubscribing, got %v", event)
+	}
+}
+
+func TestEvents_SlowClientIsDisconnected(t *testing.T) {
+	server := NewServer("localhost", 0)
+	server.events = newEventHub(4)
+	httpServer := httptest.NewServer(server.wrapHandlers())
+	defer httpServer.Close()
+
+	// The slow client never reads; the other only follows chat
+	slow := dialEvents(t, httpServer.URL)
+	fast := dialEvents(t, httpServer.URL)
+	fast.WriteJSON(map[string]interface{}{"subscribe": []string{TopicChat}})
+	readEvent(t, fast)
+	waitForClients(t, server, 2)
+
+	// Large events fill the socket buffers, then the slow client's queue
+	payload := strings.Repeat("x", 64*1024)
+	start := time.Now()
+	for i := 0; i < 2000 && server.events.len() == 2; i++ {
+		server.publish(TopicAgents, map[string]interface{}{"type": "task_complete", "payload": payload})
+	}
+	if server.events.len() != 1 {
+		t.Fatal("Expected the slow client to be disconnected")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected publishing not to wait for the slow client, took %v", elapsed)
+	}
+
+	server.publish(TopicChat, map[string]interface{}{"type": EventChatStart})
+	if event := readEvent(t, fast); event["type"] != EventChatStart {
+		t.Errorf("Expected the other client to keep receiving, got %v", event)
+	}
+
+	// The slow client's connection is closed once it catches up
+	slow.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		if _, _, err := slow.ReadMessage(); err != nil {
+			break
+		}
+	}
+}
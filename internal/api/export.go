@@ -0,0 +1,197 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+// sessionExportVersion is the format of session exports; imports of a newer
+// format are refused
+const sessionExportVersion = 1
+
+// redactedText replaces each secret found in an exported session
+const redactedText = "[REDACTED]"
+
+// defaultExportRedactions match secrets that commonly end up in a chat: AFE
+// API keys, bearer tokens, and the values of key, token, secret, and password
+// fields. Where a pattern has a "secret" group only that group is replaced,
+// so the field name stays readable.
+var defaultExportRedactions = []*regexp.Regexp{
+	regexp.MustCompile(`afe_[0-9a-f]+_[0-9a-f]+`),
+	regexp.MustCompile(`(?i)\bbearer\s+(?P<secret>[A-Za-z0-9._~+/=-]+)`),
+	regexp.MustCompile(`(?i)\b(?:api[_-]?key|access[_-]?token|token|secret|password|passwd)["']?\s*[:=]\s*["']?(?P<secret>[^\s"',}]+)`),
+}
+
+// SessionExport is a chat session as exported and imported. Messages holds
+// the whole conversation: user messages, assistant replies including their
+// function calls, and the "tool" messages holding the calls' results.
+type SessionExport struct {
+	Version    int                      `json:"version"`
+	SessionID  string                   `json:"session_id"`
+	ExportedAt time.Time                `json:"exported_at"`
+	Messages   []interfaces.ChatMessage `json:"messages"`
+	// Redacted counts the secrets replaced on export
+	Redacted int `json:"redacted,omitempty"`
+}
+
+// SetExportRedaction sets whether exported sessions have secrets replaced by
+// [REDACTED]: the default patterns and patterns, regular expressions that may
+// name the part to replace with a "secret" group. Redaction is on by default.
+func (s *Server) SetExportRedaction(enabled bool, patterns []string) error {
+	if !enabled {
+		s.exportRedactions = nil
+		return nil
+	}
+
+	redactions := append([]*regexp.Regexp(nil), defaultExportRedactions...)
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid export redaction pattern %q: %w", pattern, err)
+		}
+		redactions = append(redactions, re)
+	}
+	s.exportRedactions = redactions
+	return nil
+}
+
+// redact replaces the secrets matched by redactions in text and reports how
+// many it replaced
+func redact(text string, redactions []*regexp.Regexp) (string, int) {
+	count := 0
+	for _, re := range redactions {
+		matches := re.FindAllStringSubmatchIndex(text, -1)
+		if len(matches) == 0 {
+			continue
+		}
+
+		group := re.SubexpIndex("secret")
+		var b strings.Builder
+		last := 0
+		for _, match := range matches {
+			start, end := match[0], match[1]
+			if group > 0 && match[2*group] >= 0 {
+				start, end = match[2*group], match[2*group+1]
+			}
+			b.WriteString(text[last:start])
+			b.WriteString(redactedText)
+			last = end
+		}
+		b.WriteString(text[last:])
+		text = b.String()
+		count += len(matches)
+	}
+	return text, count
+}
+
+// handleExportSession returns a session's conversation as a SessionExport,
+// with secrets redacted unless that is disabled. A session of another
+// principal is not found.
+func (s *Server) handleExportSession(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodGet {
+		s.sendError(w, http.StatusMethodNotAllowed, "Only GET method allowed")
+		return
+	}
+
+	if owned, err := s.findSession(r.Context(), id); err != nil {
+		s.sendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to read session: %v", err))
+		return
+	} else if !owned {
+		s.sendError(w, http.StatusNotFound, fmt.Sprintf("Session %s not found", id))
+		return
+	}
+
+	messages, ok, err := s.sessions.History(id)
+	if err != nil {
+		s.sendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to read session: %v", err))
+		return
+	}
+	if !ok {
+		s.sendError(w, http.StatusNotFound, fmt.Sprintf("Session %s not found", id))
+		return
+	}
+
+	export := SessionExport{
+		Version:    sessionExportVersion,
+		SessionID:  id,
+		ExportedAt: time.Now().UTC(),
+		Messages:   messages,
+	}
+	for i := range export.Messages {
+		content, count := redact(export.Messages[i].Content, s.exportRedactions)
+		export.Messages[i].Content = content
+		export.Redacted += count
+	}
+	s.sendSuccess(w, export)
+}
+
+// handleImportSession recreates a session from a SessionExport so it can be
+// continued by the principal that imported it. The session keeps the
+// export's ID, or a new one when it has none; an ID already in use is a
+// conflict.
+func (s *Server) handleImportSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.sendError(w, http.StatusMethodNotAllowed, "Only POST method allowed")
+		return
+	}
+
+	var export SessionExport
+	if !s.decodeJSON(w, r, &export) {
+		return
+	}
+	if export.Version > sessionExportVersion {
+		s.sendError(w, http.StatusBadRequest, fmt.Sprintf("Unsupported export version %d", export.Version))
+		return
+	}
+	if len(export.Messages) == 0 {
+		s.sendError(w, http.StatusBadRequest, "An export must hold at least one message")
+		return
+	}
+	for i, msg := range export.Messages {
+		switch msg.Role {
+		case "system", "user", "assistant", "tool":
+		default:
+			s.sendError(w, http.StatusBadRequest, fmt.Sprintf("Message %d has unknown role %q", i, msg.Role))
+			return
+		}
+	}
+
+	id := export.SessionID
+	if id == "" {
+		id = newSessionID()
+	}
+	if id == "import" || strings.Contains(id, "/") {
+		s.sendError(w, http.StatusBadRequest, fmt.Sprintf("Invalid session ID %q", id))
+		return
+	}
+
+	if !s.lockSession(id) {
+		s.sendError(w, http.StatusConflict, fmt.Sprintf("Session %s has a chat in progress", id))
+		return
+	}
+	defer s.unlockSession(id)
+
+	if _, exists, err := s.sessions.History(id); err != nil {
+		s.sendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to read session: %v", err))
+		return
+	} else if exists {
+		s.sendError(w, http.StatusConflict, fmt.Sprintf("Session %s already exists", id))
+		return
+	}
+
+	if err := s.sessions.Save(id, sessionOwner(r.Context()), export.Messages); err != nil {
+		s.sendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to save session: %v", err))
+		return
+	}
+
+	tokens := 0
+	for _, msg := range export.Messages {
+		tokens += s.tokenizer.CountTokens(msg.Content)
+	}
+	s.sendSuccess(w, SessionResponse{SessionID: id, Messages: export.Messages, Tokens: tokens})
+}
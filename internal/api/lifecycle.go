@@ -0,0 +1,368 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/internal/logging"
+)
+
+// EngineState is whether the engine's components are running
+type EngineState string
+
+const (
+	EngineStopped EngineState = "stopped"
+	EngineRunning EngineState = "running"
+)
+
+// States of a single component
+const (
+	ComponentStopped = "stopped"
+	ComponentRunning = "running"
+	ComponentFailed  = "failed"
+)
+
+const (
+	// defaultShutdownTimeout bounds each component's Shutdown when no
+	// timeout is set
+	defaultShutdownTimeout = 10 * time.Second
+	// eventFlushTimeout bounds how long a stop waits for WebSocket clients
+	// to be handed their queued events
+	eventFlushTimeout = 2 * time.Second
+)
+
+// ErrEngineStopped is returned when reloading an engine that is not running
+var ErrEngineStopped = errors.New("engine is stopped")
+
+// Component is a part of the engine the lifecycle controller starts and
+// stops, such as the plugin manager. ctx only bounds the start; components
+// must not keep it.
+type Component interface {
+	Name() string
+	Start(ctx context.Context) error
+	Shutdown() error
+}
+
+// Reloader is implemented by components that can apply a new configuration
+// while they run
+type Reloader interface {
+	Reload(ctx context.Context) error
+}
+
+// ComponentFuncs adapts functions to Component and Reloader. A nil ReloadFunc
+// leaves the component as it is on reload.
+type ComponentFuncs struct {
+	ComponentName string
+	StartFunc     func(ctx context.Context) error
+	ShutdownFunc  func() error
+	ReloadFunc    func(ctx context.Context) error
+}
+
+func (c ComponentFuncs) Name() string { return c.ComponentName }
+
+func (c ComponentFuncs) Start(ctx context.Context) error {
+	if c.StartFunc == nil {
+		return nil
+	}
+	return c.StartFunc(ctx)
+}
+
+func (c ComponentFuncs) Shutdown() error {
+	if c.ShutdownFunc == nil {
+		return nil
+	}
+	return c.ShutdownFunc()
+}
+
+func (c ComponentFuncs) Reload(ctx context.Context) error {
+	if c.ReloadFunc == nil {
+		return nil
+	}
+	return c.ReloadFunc(ctx)
+}
+
+// ComponentStatus is the state of one component after the last transition,
+// with the error it reported
+type ComponentStatus struct {
+	Name  string `json:"name"`
+	State string `json:"state"`
+	Error string `json:"error,omitempty"`
+}
+
+// EngineStatus is what the start, stop, and reload endpoints return
+type EngineStatus struct {
+	State      EngineState       `json:"state"`
+	Components []ComponentStatus `json:"components"`
+}
+
+// Lifecycle starts, stops, and reloads the engine's components. Components
+// start in the order given and stop in reverse. Transitions run one at a
+// time, so concurrent calls cannot interleave, and each returns the status it
+// left the engine in.
+type Lifecycle struct {
+	// transitionMu is held for a whole transition; mu guards the state
+	transitionMu    sync.Mutex
+	mu              sync.Mutex
+	components      []Component
+	statuses        []ComponentStatus
+	state           EngineState
+	shutdownTimeout time.Duration
+	logger          *logging.Logger
+}
+
+// NewLifecycle returns a controller for components, all of them stopped
+func NewLifecycle(components ...Component) *Lifecycle {
+	statuses := make([]ComponentStatus, len(components))
+	for i, component := range components {
+		statuses[i] = ComponentStatus{Name: component.Name(), State: ComponentStopped}
+	}
+	return &Lifecycle{
+		components:      components,
+		statuses:        statuses,
+		state:           EngineStopped,
+		shutdownTimeout: defaultShutdownTimeout,
+		logger:          logging.New("engine"),
+	}
+}
+
+// SetShutdownTimeout sets how long each component may take to shut down
+// before the stop moves on and reports it as failed. Values of zero or less
+// restore the default.
+func (l *Lifecycle) SetShutdownTimeout(timeout time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
+	}
+	l.shutdownTimeout = timeout
+}
+
+// Status returns the engine's state without waiting for a transition in
+// progress
+func (l *Lifecycle) Status() EngineStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return EngineStatus{State: l.state, Components: append([]ComponentStatus(nil), l.statuses...)}
+}
+
+// Running reports whether the engine is running
+func (l *Lifecycle) Running() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.state == EngineRunning
+}
+
+// Start starts every component unless the engine is already running. When a
+// component fails to start, those started before it are shut down again and
+// the engine stays stopped.
+func (l *Lifecycle) Start(ctx context.Context) (EngineStatus, error) {
+	l.transitionMu.Lock()
+	defer l.transitionMu.Unlock()
+
+	if l.Running() {
+		return l.Status(), nil
+	}
+
+	for i, component := range l.components {
+		if err := component.Start(ctx); err != nil {
+			l.setStatus(i, ComponentFailed, err)
+			for j := i - 1; j >= 0; j-- {
+				l.shutdown(j)
+			}
+			return l.Status(), fmt.Errorf("failed to start %s: %w", component.Name(), err)
+		}
+		l.setStatus(i, ComponentRunning, nil)
+	}
+
+	l.setState(EngineRunning)
+	l.logger.Infof("Engine started")
+	return l.Status(), nil
+}
+
+// Stop shuts every component down, in reverse order, unless the engine is
+// already stopped. A component that fails or takes longer than the shutdown
+// timeout does not stop the others; the engine is stopped either way.
+func (l *Lifecycle) Stop() (EngineStatus, error) {
+	l.transitionMu.Lock()
+	defer l.transitionMu.Unlock()
+
+	if !l.Running() {
+		return l.Status(), nil
+	}
+
+	var failures []string
+	for i := len(l.components) - 1; i >= 0; i-- {
+		if err := l.shutdown(i); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", l.components[i].Name(), err))
+		}
+	}
+
+	l.setState(EngineStopped)
+	l.logger.Infof("Engine stopped")
+	if len(failures) > 0 {
+		return l.Status(), fmt.Errorf("%d components failed to stop: %s", len(failures), strings.Join(failures, "; "))
+	}
+	return l.Status(), nil
+}
+
+// Reload has every running component that implements Reloader apply the
+// current configuration. A component that fails to reload keeps running with
+// the configuration it had.
+func (l *Lifecycle) Reload(ctx context.Context) (EngineStatus, error) {
+	l.transitionMu.Lock()
+	defer l.transitionMu.Unlock()
+
+	if !l.Running() {
+		return l.Status(), ErrEngineStopped
+	}
+
+	var failures []string
+	for i, component := range l.components {
+		reloader, ok := component.(Reloader)
+		if !ok {
+			continue
+		}
+		if err := reloader.Reload(ctx); err != nil {
+			l.setStatus(i, ComponentRunning, fmt.Errorf("reload failed: %w", err))
+			failures = append(failures, fmt.Sprintf("%s: %v", component.Name(), err))
+			continue
+		}
+		l.setStatus(i, ComponentRunning, nil)
+	}
+
+	if len(failures) > 0 {
+		return l.Status(), fmt.Errorf("%d components failed to reload: %s", len(failures), strings.Join(failures, "; "))
+	}
+	l.logger.Infof("Engine configuration reloaded")
+	return l.Status(), nil
+}
+
+// shutdown shuts one component down within the shutdown timeout, turning a
+// panic into an error
+func (l *Lifecycle) shutdown(i int) error {
+	l.mu.Lock()
+	timeout := l.shutdownTimeout
+	l.mu.Unlock()
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				done <- fmt.Errorf("panic during shutdown: %v", recovered)
+			}
+		}()
+		done <- l.components[i].Shutdown()
+	}()
+
+	var err error
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err = <-done:
+	case <-timer.C:
+		err = fmt.Errorf("shutdown timed out after %s", timeout)
+	}
+
+	if err != nil {
+		l.logger.Errorf("Shutdown of %s failed: %v", l.components[i].Name(), err)
+		l.setStatus(i, ComponentFailed, err)
+		return err
+	}
+	l.setStatus(i, ComponentStopped, nil)
+	return nil
+}
+
+func (l *Lifecycle) setStatus(i int, state string, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.statuses[i].State = state
+	l.statuses[i].Error = ""
+	if err != nil {
+		l.statuses[i].Error = err.Error()
+	}
+}
+
+func (l *Lifecycle) setState(state EngineState) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.state = state
+}
+
+// SetLifecycle sets the controller behind /api/v1/start, /api/v1/stop, and
+// /api/v1/reload. Without one they answer 501.
+func (s *Server) SetLifecycle(lifecycle *Lifecycle) {
+	s.lifecycle = lifecycle
+}
+
+// handleStart starts the engine's components, or reports them when the
+// engine is already running
+func (s *Server) handleStart(w http.ResponseWriter, r *http.Request) {
+	if !s.lifecycleRequest(w, r) {
+		return
+	}
+	status, err := s.lifecycle.Start(r.Context())
+	s.sendEngineStatus(w, status, err)
+}
+
+// handleStop shuts the engine's components down and waits briefly for
+// WebSocket clients to receive what was published before. The HTTP server
+// keeps running so the engine can be started again.
+func (s *Server) handleStop(w http.ResponseWriter, r *http.Request) {
+	if !s.lifecycleRequest(w, r) {
+		return
+	}
+	status, err := s.lifecycle.Stop()
+	s.events.flush(eventFlushTimeout)
+	s.sendEngineStatus(w, status, err)
+}
+
+// handleReload re-reads the configuration and applies it to the running
+// components
+func (s *Server) handleReload(w http.ResponseWriter, r *http.Request) {
+	if !s.lifecycleRequest(w, r) {
+		return
+	}
+	status, err := s.lifecycle.Reload(r.Context())
+	if errors.Is(err, ErrEngineStopped) {
+		s.sendJSON(w, http.StatusConflict, APIResponse{Success: false, Data: status, Error: "Engine is stopped; start it before reloading"})
+		return
+	}
+	s.sendEngineStatus(w, status, err)
+}
+
+// lifecycleRequest checks the method of a lifecycle request and that the
+// server has a controller, answering the request when it cannot go ahead
+func (s *Server) lifecycleRequest(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		s.sendError(w, http.StatusMethodNotAllowed, "Only POST method allowed")
+		return false
+	}
+	if s.lifecycle == nil {
+		s.sendError(w, http.StatusNotImplemented, "Engine lifecycle control is not configured")
+		return false
+	}
+	return true
+}
+
+func (s *Server) sendEngineStatus(w http.ResponseWriter, status EngineStatus, err error) {
+	if err != nil {
+		s.sendJSON(w, http.StatusInternalServerError, APIResponse{Success: false, Data: status, Error: err.Error()})
+		return
+	}
+	s.sendSuccess(w, status)
+}
+
+// sendUnavailable answers a request that needs a component the server does
+// not have: 503 while the engine is stopped, 500 otherwise
+func (s *Server) sendUnavailable(w http.ResponseWriter, message string) {
+	if s.lifecycle != nil && !s.lifecycle.Running() {
+		s.sendError(w, http.StatusServiceUnavailable, "Engine is stopped")
+		return
+	}
+	s.sendError(w, http.StatusInternalServerError, message)
+}
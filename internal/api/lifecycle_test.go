@@ -0,0 +1,259 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeComponent counts its starts, shutdowns, and reloads
+type fakeComponent struct {
+	name      string
+	startErr  error
+	reloadErr error
+	block     chan struct{}
+	starts    atomic.Int32
+	shutdowns atomic.Int32
+	reloads   atomic.Int32
+	order     *[]string
+	mu        *sync.Mutex
+}
+
+func (c *fakeComponent) Name() string { return c.name }
+
+func (c *fakeComponent) Start(ctx context.Context) error {
+	c.starts.Add(1)
+	c.record("start " + c.name)
+	return c.startErr
+}
+
+func (c *fakeComponent) Shutdown() error {
+	c.shutdowns.Add(1)
+	c.record("stop " + c.name)
+	if c.block != nil {
+		<-c.block
+	}
+	return nil
+}
+
+func (c *fakeComponent) Reload(ctx context.Context) error {
+	c.reloads.Add(1)
+	return c.reloadErr
+}
+
+func (c *fakeComponent) record(step string) {
+	if c.order == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	*c.order = append(*c.order, step)
+}
+
+func newFakeComponents(names ...string) ([]*fakeComponent, []Component, *[]string) {
+	order := &[]string{}
+	mu := &sync.Mutex{}
+	fakes := make([]*fakeComponent, len(names))
+	components := make([]Component, len(names))
+	for i, name := range names {
+		fakes[i] = &fakeComponent{name: name, order: order, mu: mu}
+		components[i] = fakes[i]
+	}
+	return fakes, components, order
+}
+
+func TestLifecycle_StartStop(t *testing.T) {
+	fakes, components, order := newFakeComponents("plugins", "models", "requests")
+	lifecycle := NewLifecycle(components...)
+
+	status, err := lifecycle.Start(context.Background())
+	if err != nil || status.State != EngineRunning {
+		t.Fatalf("Expected a running engine, got %+v, %v", status, err)
+	}
+	// Starting a running engine only reports it
+	if status, err := lifecycle.Start(context.Background()); err != nil || status.State != EngineRunning {
+		t.Errorf("Expected the engine to keep running, got %+v, %v", status, err)
+	}
+
+	status, err = lifecycle.Stop()
+	if err != nil || status.State != EngineStopped {
+		t.Fatalf("Expected a stopped engine, got %+v, %v", status, err)
+	}
+	for _, component := range status.Components {
+		if component.State != ComponentStopped || component.Error != "" {
+			t.Errorf("Unexpected component status: %+v", component)
+		}
+	}
+	if _, err := lifecycle.Stop(); err != nil {
+		t.Errorf("Expected stopping a stopped engine to succeed, got %v", err)
+	}
+
+	for _, fake := range fakes {
+		if fake.starts.Load() != 1 || fake.shutdowns.Load() != 1 {
+			t.Errorf("%s: expected one start and one shutdown, got %d and %d", fake.name, fake.starts.Load(), fake.shutdowns.Load())
+		}
+	}
+	want := "start plugins|start models|start requests|stop requests|stop models|stop plugins"
+	if got := strings.Join(*order, "|"); got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
+
+func TestLifecycle_ConcurrentStopsShutDownOnce(t *testing.T) {
+	fakes, components, _ := newFakeComponents("plugins", "models")
+	lifecycle := NewLifecycle(components...)
+	lifecycle.Start(context.Background())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if status, _ := lifecycle.Stop(); status.State != EngineStopped {
+				t.Errorf("Expected a stopped engine, got %+v", status)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, fake := range fakes {
+		if got := fake.shutdowns.Load(); got != 1 {
+			t.Errorf("%s: expected one shutdown, got %d", fake.name, got)
+		}
+	}
+}
+
+func TestLifecycle_FailedStartRollsBack(t *testing.T) {
+	fakes, components, _ := newFakeComponents("plugins", "models", "requests")
+	fakes[1].startErr = errors.New("no providers")
+	lifecycle := NewLifecycle(components...)
+
+	status, err := lifecycle.Start(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "failed to start models: no providers") {
+		t.Fatalf("Expected the start to fail, got %v", err)
+	}
+	if status.State != EngineStopped || status.Components[1].State != ComponentFailed || status.Components[1].Error != "no providers" {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+	if fakes[0].shutdowns.Load() != 1 || fakes[1].shutdowns.Load() != 0 || fakes[2].starts.Load() != 0 {
+		t.Errorf("Expected only the started component to be shut down")
+	}
+}
+
+func TestLifecycle_ShutdownTimeout(t *testing.T) {
+	fakes, components, _ := newFakeComponents("plugins", "models")
+	fakes[1].block = make(chan struct{})
+	defer close(fakes[1].block)
+	lifecycle := NewLifecycle(components...)
+	lifecycle.SetShutdownTimeout(20 * time.Millisecond)
+	lifecycle.Start(context.Background())
+
+	status, err := lifecycle.Stop()
+	if err == nil || !strings.Contains(err.Error(), "models: shutdown timed out") {
+		t.Errorf("Expected the slow component to be reported, got %v", err)
+	}
+	if status.State != EngineStopped || status.Components[1].State != ComponentFailed || status.Components[0].State != ComponentStopped {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+	if fakes[0].shutdowns.Load() != 1 {
+		t.Error("Expected the other component to be shut down after the timeout")
+	}
+}
+
+func TestLifecycle_Reload(t *testing.T) {
+	fakes, components, _ := newFakeComponents("plugins", "models")
+	lifecycle := NewLifecycle(components...)
+
+	if _, err := lifecycle.Reload(context.Background()); !errors.Is(err, ErrEngineStopped) {
+		t.Errorf("Expected ErrEngineStopped, got %v", err)
+	}
+
+	lifecycle.Start(context.Background())
+	fakes[1].reloadErr = errors.New("bad config")
+	status, err := lifecycle.Reload(context.Background())
+	if err == nil || status.State != EngineRunning {
+		t.Fatalf("Expected a failed reload of a running engine, got %+v, %v", status, err)
+	}
+	if component := status.Components[1]; component.State != ComponentRunning || component.Error != "reload failed: bad config" {
+		t.Errorf("Unexpected component status: %+v", component)
+	}
+	if fakes[0].reloads.Load() != 1 || fakes[0].shutdowns.Load() != 0 {
+		t.Error("Expected a reload without a restart")
+	}
+}
+
+func TestHandleLifecycle(t *testing.T) {
+	server := NewServer("localhost", 0)
+	post := func(handler http.HandlerFunc, method string) (int, APIResponse, EngineStatus) {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		handler(recorder, httptest.NewRequest(method, "/api/v1/engine", nil))
+		var response struct {
+			APIResponse
+			Data EngineStatus `json:"data"`
+		}
+		json.Unmarshal(recorder.Body.Bytes(), &response)
+		return recorder.Code, response.APIResponse, response.Data
+	}
+
+	if code, _, _ := post(server.handleStart, http.MethodPost); code != http.StatusNotImplemented {
+		t.Errorf("Expected 501 without a lifecycle controller, got %d", code)
+	}
+
+	fakes, components, _ := newFakeComponents("plugins", "requests")
+	components[1] = ComponentFuncs{
+		ComponentName: "requests",
+		StartFunc: func(ctx context.Context) error {
+			fakes[1].starts.Add(1)
+			server.SetComponents(nil, nil, nil)
+			server.pluginManager = fakeRegistry{}
+			return nil
+		},
+		ShutdownFunc: func() error {
+			fakes[1].shutdowns.Add(1)
+			server.SetComponents(nil, nil, nil)
+			return nil
+		},
+	}
+	server.SetLifecycle(NewLifecycle(components...))
+
+	if code, _, _ := post(server.handleStart, http.MethodGet); code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", code)
+	}
+	if code, response, _ := post(server.handleReload, http.MethodPost); code != http.StatusConflict || response.Success {
+		t.Errorf("Expected reloading a stopped engine to conflict, got %d", code)
+	}
+
+	code, _, status := post(server.handleStart, http.MethodPost)
+	if code != http.StatusOK || status.State != EngineRunning || len(status.Components) != 2 || status.Components[1].State != ComponentRunning {
+		t.Errorf("Expected a running engine, got %d %+v", code, status)
+	}
+	recorder := httptest.NewRecorder()
+	server.handleListAgents(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/agents", nil))
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected agents to be listed while running, got %d", recorder.Code)
+	}
+
+	code, _, status = post(server.handleStop, http.MethodPost)
+	if code != http.StatusOK || status.State != EngineStopped {
+		t.Errorf("Expected a stopped engine, got %d %+v", code, status)
+	}
+	post(server.handleStop, http.MethodPost)
+	if fakes[0].shutdowns.Load() != 1 || fakes[1].shutdowns.Load() != 1 {
+		t.Errorf("Expected one shutdown per component, got %d and %d", fakes[0].shutdowns.Load(), fakes[1].shutdowns.Load())
+	}
+
+	// Requests needing the stopped components are refused until a restart
+	recorder = httptest.NewRecorder()
+	server.handleListAgents(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/agents", nil))
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 while stopped, got %d", recorder.Code)
+	}
+}
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultReadTimeout bounds reading a request, body included
+	defaultReadTimeout = 30 * time.Second
+	// defaultIdleTimeout bounds how long a keep-alive connection waits for
+	// the next request
+	defaultIdleTimeout = 120 * time.Second
+	// defaultMaxRequestTimeout bounds a chat, and the timeout a request may
+	// ask for
+	defaultMaxRequestTimeout = 5 * time.Minute
+	// writeTimeoutMargin is the time left to send a response after the
+	// longest request ran out of time, so the client gets the 504
+	writeTimeoutMargin = 30 * time.Second
+	// defaultMaxBodyBytes bounds request bodies
+	defaultMaxBodyBytes = 10 << 20
+)
+
+// SetHTTPTimeouts sets how long the HTTP server waits to read a request,
+// to write its response, and for the next request on an idle connection.
+// Values of zero or less restore the defaults; the write timeout defaults
+// to the max request timeout plus a margin, and is raised to that if set
+// lower, since a shorter one would drop chats before they time out.
+func (s *Server) SetHTTPTimeouts(read, write, idle time.Duration) {
+	if read <= 0 {
+		read = defaultReadTimeout
+	}
+	if idle <= 0 {
+		idle = defaultIdleTimeout
+	}
+	s.readTimeout, s.writeTimeout, s.idleTimeout = read, write, idle
+}
+
+// SetMaxRequestTimeout sets how long a chat may run, which is also the
+// longest timeout a chat or agent call may ask for. Values of zero or less
+// restore the default.
+func (s *Server) SetMaxRequestTimeout(timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = defaultMaxRequestTimeout
+	}
+	s.maxRequestTimeout = timeout
+}
+
+// SetMaxBodyBytes sets the largest request body the server reads. Values of
+// zero or less restore the default.
+func (s *Server) SetMaxBodyBytes(limit int64) {
+	if limit <= 0 {
+		limit = defaultMaxBodyBytes
+	}
+	s.maxBodyBytes = limit
+}
+
+// effectiveWriteTimeout is the write timeout the HTTP server uses, never
+// shorter than the longest request plus writeTimeoutMargin
+func (s *Server) effectiveWriteTimeout() time.Duration {
+	minimum := s.maxRequestTimeout + writeTimeoutMargin
+	if s.writeTimeout <= 0 {
+		return minimum
+	}
+	if s.writeTimeout < minimum {
+		s.logger.Warnf("server.write_timeout %v is shorter than server.max_request_timeout %v; using %v", s.writeTimeout, s.maxRequestTimeout, minimum)
+		return minimum
+	}
+	return s.writeTimeout
+}
+
+// limitRequest refuses bodies that are not JSON with 415 and bodies larger
+// than the server's limit with 413, and caps the body of the rest so reading
+// past the limit fails. It reports whether the request may go ahead.
+func (s *Server) limitRequest(w http.ResponseWriter, r *http.Request) bool {
+	switch r.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+	default:
+		return true
+	}
+
+	if contentType := r.Header.Get("Content-Type"); contentType != "" && !isJSON(contentType) {
+		s.sendError(w, http.StatusUnsupportedMediaType, fmt.Sprintf("Content-Type %s is not supported; send application/json", contentType))
+		return false
+	}
+	if r.ContentLength > s.maxBodyBytes {
+		s.sendBodyTooLarge(w)
+		return false
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, s.maxBodyBytes)
+	return true
+}
+
+// isJSON reports whether a Content-Type names JSON, such as
+// application/json or application/vnd.api+json
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// decodeJSON reads the request body into v, answering 413 when it is over
+// the limit and 400 when it is not valid JSON. It reports whether v was
+// read.
+func (s *Server) decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err == nil {
+		return true
+	}
+
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		s.sendBodyTooLarge(w)
+	} else {
+		s.sendError(w, http.StatusBadRequest, "Invalid JSON request body")
+	}
+	return false
+}
+
+func (s *Server) sendBodyTooLarge(w http.ResponseWriter) {
+	s.sendError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Request body exceeds %d bytes", s.maxBodyBytes))
+}
+
+// requestTimeout returns how long a request asking for seconds may run: the
+// server's max when it asks for none or more
+func (s *Server) requestTimeout(seconds float64) time.Duration {
+	timeout := time.Duration(seconds * float64(time.Second))
+	if timeout <= 0 || timeout > s.maxRequestTimeout {
+		return s.maxRequestTimeout
+	}
+	return timeout
+}
+
+// timedOut reports whether a request failed because its deadline passed,
+// whether or not err says so
+func timedOut(ctx context.Context, err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded)
+}
@@ -0,0 +1,163 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/internal/models"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+// hungProvider never answers, returning only once the request is cancelled
+type hungProvider struct{ scriptedProvider }
+
+func (p *hungProvider) Generate(ctx context.Context, req interfaces.GenerationRequest) (*interfaces.GenerationResponse, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// serve sends a request through the server's middleware and returns the
+// status and the decoded envelope
+func serve(t *testing.T, server *Server, request *http.Request) (int, APIResponse) {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	server.wrapHandlers().ServeHTTP(recorder, request)
+
+	var response APIResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Invalid response body %q: %v", recorder.Body.String(), err)
+	}
+	return recorder.Code, response
+}
+
+func TestWrapHandler_OversizedBody(t *testing.T) {
+	server := newToolLoopServer(&scriptedProvider{replies: []string{"hi"}}, fakeRegistry{})
+	server.SetMaxBodyBytes(64)
+	body := `{"message": "` + strings.Repeat("a", 100) + `"}`
+
+	tests := []struct {
+		name    string
+		request *http.Request
+	}{
+		{"declared length", httptest.NewRequest(http.MethodPost, "/api/v1/chat", strings.NewReader(body))},
+		// A body of unknown length, as when chunked, is cut off while it is
+		// read
+		{"chunked", httptest.NewRequest(http.MethodPost, "/api/v1/chat", struct{ *strings.Reader }{strings.NewReader(body)})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.request.Header.Set("Content-Type", "application/json")
+
+			status, response := serve(t, server, tt.request)
+			if status != http.StatusRequestEntityTooLarge {
+				t.Errorf("Expected 413, got %d", status)
+			}
+			if response.Success || !strings.Contains(response.Error, "exceeds 64 bytes") {
+				t.Errorf("Expected the standard error shape, got %+v", response)
+			}
+		})
+	}
+
+	// Bodies within the limit go through
+	request := httptest.NewRequest(http.MethodPost, "/api/v1/chat", strings.NewReader(`{"message": "hi"}`))
+	if status, response := serve(t, server, request); status != http.StatusOK {
+		t.Errorf("Expected a small body to be served, got %d: %+v", status, response)
+	}
+}
+
+func TestWrapHandler_ContentType(t *testing.T) {
+	server := newToolLoopServer(&scriptedProvider{replies: []string{"hi"}}, fakeRegistry{})
+
+	tests := []struct {
+		contentType string
+		expected    int
+	}{
+		{"application/json", http.StatusOK},
+		{"application/json; charset=utf-8", http.StatusOK},
+		{"application/vnd.afe+json", http.StatusOK},
+		{"", http.StatusOK},
+		{"text/plain", http.StatusUnsupportedMediaType},
+		{"application/x-www-form-urlencoded", http.StatusUnsupportedMediaType},
+		{"not a media type", http.StatusUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		request := httptest.NewRequest(http.MethodPost, "/api/v1/chat", strings.NewReader(`{"message": "hi"}`))
+		if tt.contentType != "" {
+			request.Header.Set("Content-Type", tt.contentType)
+		}
+		status, response := serve(t, server, request)
+		if status != tt.expected {
+			t.Errorf("Content-Type %q: expected %d, got %d", tt.contentType, tt.expected, status)
+		}
+		if status == http.StatusUnsupportedMediaType && (response.Success || response.Error == "") {
+			t.Errorf("Content-Type %q: expected the standard error shape, got %+v", tt.contentType, response)
+		}
+	}
+
+	// Requests without a body are not checked
+	request := httptest.NewRequest(http.MethodGet, "/api/v1/health", nil)
+	request.Header.Set("Content-Type", "text/plain")
+	if status, _ := serve(t, server, request); status != http.StatusOK {
+		t.Errorf("Expected a GET to be served whatever its Content-Type, got %d", status)
+	}
+}
+
+func TestHandleChat_Timeout(t *testing.T) {
+	manager := models.NewManager()
+	manager.RegisterProvider("hung", &hungProvider{})
+	manager.SetDefaultModel("hung")
+	server := NewServer("localhost", 0)
+	server.modelManager = manager
+	server.SetMaxRequestTimeout(50 * time.Millisecond)
+
+	// The chat's own timeout cannot exceed the server's max
+	for _, body := range []string{`{"message": "hi"}`, `{"message": "hi", "timeout": 600}`} {
+		start := time.Now()
+		status, response := serve(t, server, httptest.NewRequest(http.MethodPost, "/api/v1/chat", strings.NewReader(body)))
+		if status != http.StatusGatewayTimeout {
+			t.Errorf("%s: expected 504, got %d: %+v", body, status, response)
+		}
+		if response.Success || !strings.Contains(response.Error, "did not complete within 50ms") {
+			t.Errorf("%s: expected the standard error shape, got %+v", body, response)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("%s: expected the chat to stop at its deadline, took %v", body, elapsed)
+		}
+	}
+
+	status, _ := serve(t, server, httptest.NewRequest(http.MethodPost, "/api/v1/chat", strings.NewReader(`{"message": "hi", "timeout": -1}`)))
+	if status != http.StatusBadRequest {
+		t.Errorf("Expected a negative timeout to be refused, got %d", status)
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	server := NewServer("localhost", 0)
+	server.SetMaxRequestTimeout(time.Minute)
+
+	tests := []struct {
+		seconds  float64
+		expected time.Duration
+	}{
+		{0, time.Minute},
+		{1.5, 1500 * time.Millisecond},
+		{60, time.Minute},
+		{3600, time.Minute},
+	}
+	for _, tt := range tests {
+		if got := server.requestTimeout(tt.seconds); got != tt.expected {
+			t.Errorf("requestTimeout(%v) = %v, expected %v", tt.seconds, got, tt.expected)
+		}
+	}
+
+	// The write timeout leaves time to send a timed out request's 504
+	server.SetHTTPTimeouts(0, time.Second, 0)
+	if got := server.effectiveWriteTimeout(); got != time.Minute+writeTimeoutMargin {
+		t.Errorf("Expected a short write timeout to be raised, got %v", got)
+	}
+}
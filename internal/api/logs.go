@@ -0,0 +1,135 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/internal/logging"
+	"github.com/gorilla/websocket"
+)
+
+// defaultLogLimit is how many records GET /api/v1/logs returns when the
+// request sets no limit
+const defaultLogLimit = 100
+
+// LogsResponse holds log records, newest first
+type LogsResponse struct {
+	Logs  []logging.Record `json:"logs"`
+	Count int              `json:"count"`
+}
+
+// SetLogBuffer sets the buffer /api/v1/logs reads and the server's own
+// records are written to
+func (s *Server) SetLogBuffer(buffer *logging.Buffer) {
+	s.logs = buffer
+	s.logger = logging.NewWithBuffer(buffer, "api")
+	s.events.logger = s.logger
+}
+
+// handleGetLogs returns the recent records matching the level, component,
+// since, and limit query parameters, newest first. With follow=true the
+// request must be a WebSocket upgrade, and new matching records are sent on
+// the logs topic as they are logged.
+func (s *Server) handleGetLogs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.sendError(w, http.StatusMethodNotAllowed, "Only GET method allowed")
+		return
+	}
+
+	query := r.URL.Query()
+	filter := logging.Filter{Component: query.Get("component"), Limit: defaultLogLimit}
+	if level := query.Get("level"); level != "" {
+		min, err := logging.ParseLevel(level)
+		if err != nil {
+			s.sendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		filter.MinLevel = min
+	}
+	if since := query.Get("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			s.sendError(w, http.StatusBadRequest, "since must be an RFC3339 timestamp")
+			return
+		}
+		filter.Since = t
+	}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			s.sendError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		filter.Limit = n
+	}
+
+	if follow := query.Get("follow"); follow != "" {
+		value, err := strconv.ParseBool(follow)
+		if err != nil {
+			s.sendError(w, http.StatusBadRequest, fmt.Sprintf("Invalid follow value %q", follow))
+			return
+		}
+		if value {
+			if !websocket.IsWebSocketUpgrade(r) {
+				s.sendError(w, http.StatusBadRequest, "follow=true needs a WebSocket upgrade")
+				return
+			}
+			s.followLogs()
+			s.serveEvents(w, r, []string{TopicLogs}, func(event map[string]interface{}) bool {
+				return filter.Match(logRecordOf(event))
+			})
+			return
+		}
+	}
+
+	records := s.logs.Query(filter)
+	s.sendSuccess(w, LogsResponse{Logs: records, Count: len(records)})
+}
+
+// followLogs starts publishing new records on the logs topic, if it has not
+// already
+func (s *Server) followLogs() {
+	s.logsMu.Lock()
+	defer s.logsMu.Unlock()
+	if s.stopLogFollow == nil {
+		s.stopLogFollow = s.logs.Subscribe(s.publishLog)
+	}
+}
+
+// stopFollowingLogs stops publishing records on the logs topic
+func (s *Server) stopFollowingLogs() {
+	s.logsMu.Lock()
+	defer s.logsMu.Unlock()
+	if s.stopLogFollow != nil {
+		s.stopLogFollow()
+		s.stopLogFollow = nil
+	}
+}
+
+// publishLog sends a record to the clients following the logs
+func (s *Server) publishLog(record logging.Record) {
+	event := map[string]interface{}{
+		"type":      EventLog,
+		"timestamp": record.Time,
+		"level":     record.Level,
+		"component": record.Component,
+		"message":   record.Message,
+	}
+	if record.RequestID != "" {
+		event["request_id"] = record.RequestID
+	}
+	s.publish(TopicLogs, event)
+}
+
+// logRecordOf returns the record a log event was published for, as far as
+// filters look at it
+func logRecordOf(event map[string]interface{}) logging.Record {
+	record := logging.Record{}
+	record.Time, _ = event["timestamp"].(time.Time)
+	record.Level, _ = event["level"].(logging.Level)
+	record.Component, _ = event["component"].(string)
+	record.Message, _ = event["message"].(string)
+	return record
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/internal/logging"
+	"github.com/gorilla/websocket"
+)
+
+func TestHandleGetLogs(t *testing.T) {
+	buffer := logging.NewBuffer(10)
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	buffer.Add(logging.Record{Time: start, Level: logging.LevelInfo, Component: "loader", Message: "loaded ls"})
+	buffer.Add(logging.Record{Time: start.Add(time.Minute), Level: logging.LevelWarn, Component: "models", Message: "model down"})
+	buffer.Add(logging.Record{Time: start.Add(2 * time.Minute), Level: logging.LevelError, Component: "api", Message: "encode failed", RequestID: "req-1"})
+
+	server := NewServer("localhost", 0)
+	server.SetLogBuffer(buffer)
+
+	get := func(query string) (int, LogsResponse) {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		server.handleGetLogs(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/logs"+query, nil))
+		var response struct {
+			Data LogsResponse `json:"data"`
+		}
+		json.Unmarshal(recorder.Body.Bytes(), &response)
+		return recorder.Code, response.Data
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"encode failed", "model down", "loaded ls"}},
+		{"?level=warn", []string{"encode failed", "model down"}},
+		{"?component=models", []string{"model down"}},
+		{"?since=2024-06-01T12:01:00Z", []string{"encode failed", "model down"}},
+		{"?limit=1", []string{"encode failed"}},
+	}
+	for _, tt := range tests {
+		status, response := get(tt.query)
+		var got []string
+		for _, record := range response.Logs {
+			got = append(got, record.Message)
+		}
+		if status != http.StatusOK || strings.Join(got, "|") != strings.Join(tt.want, "|") || response.Count != len(tt.want) {
+			t.Errorf("%q: expected %v, got %d %v", tt.query, tt.want, status, got)
+		}
+	}
+
+	if _, response := get("?component=api"); response.Logs[0].RequestID != "req-1" {
+		t.Errorf("Expected the record's request ID, got %+v", response.Logs[0])
+	}
+
+	for _, query := range []string{"?level=loud", "?since=yesterday", "?limit=0", "?follow=true"} {
+		if status, _ := get(query); status != http.StatusBadRequest {
+			t.Errorf("%q: expected 400, got %d", query, status)
+		}
+	}
+}
+
+func TestHandleGetLogs_Follow(t *testing.T) {
+	server := NewServer("localhost", 0)
+	server.SetLogBuffer(logging.NewBuffer(100))
+	httpServer := httptest.NewServer(server.wrapHandlers())
+	defer httpServer.Close()
+
+	url := "ws" + strings.TrimPrefix(httpServer.URL, "http") + "/api/v1/logs?follow=true&level=warn&component=api"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	welcome := readEvent(t, conn)
+	if topics, _ := welcome["topics"].([]interface{}); len(topics) != 1 || topics[0] != TopicLogs {
+		t.Fatalf("Expected a welcome for the logs topic, got %v", welcome)
+	}
+	waitForClients(t, server, 1)
+
+	// Only records matching the follow filters are sent
+	server.logger.Infof("too quiet")
+	logging.NewWithBuffer(server.logs, "models").Errorf("other component")
+	server.logger.Warnf("slow client")
+
+	event := readEvent(t, conn)
+	if event["type"] != EventLog || event["topic"] != TopicLogs || event["message"] != "slow client" || event["level"] != "warn" || event["component"] != "api" {
+		t.Errorf("Unexpected log event: %v", event)
+	}
+
+	// Events clients do not receive logs
+	events := dialEvents(t, httpServer.URL)
+	waitForClients(t, server, 2)
+	server.logger.Warnf("second")
+	server.PublishEvent(map[string]interface{}{"type": "task_complete"})
+	if event := readEvent(t, events); event["type"] != "task_complete" {
+		t.Errorf("Expected the events client to skip log records, got %v", event)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	server := NewServer("localhost", 0)
+	buffer := logging.NewBuffer(10)
+	server.SetLogBuffer(buffer)
+	handler := server.wrapHandlers()
+
+	request := httptest.NewRequest(http.MethodGet, "/api/v1/health", nil)
+	request.Header.Set("X-Request-ID", "req-42")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if got := recorder.Header().Get("X-Request-ID"); got != "req-42" {
+		t.Errorf("Expected the request ID to be echoed, got %q", got)
+	}
+	if records := buffer.Query(logging.Filter{}); len(records) == 0 || records[0].RequestID != "req-42" {
+		t.Errorf("Expected the request to be logged with its ID, got %+v", records)
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/health", nil))
+	if recorder.Header().Get("X-Request-ID") == "" {
+		t.Error("Expected a generated request ID")
+	}
+}
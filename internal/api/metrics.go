@@ -0,0 +1,180 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/internal/metrics"
+	"github.com/AgentForgeEngine/AgentForgeEngine/internal/models"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/cache"
+)
+
+// serverMetrics are what /metrics reports about the requests the server
+// served and the models and agents it called. Its methods do nothing on a
+// nil receiver, as while metrics are disabled.
+type serverMetrics struct {
+	registry *metrics.Registry
+
+	httpRequests *metrics.Counter
+	httpDuration *metrics.Histogram
+	chats        *metrics.Counter
+
+	providerTokens   *metrics.Counter
+	providerDuration *metrics.Histogram
+	providerFailures *metrics.Counter
+
+	agentCalls    *metrics.Counter
+	agentDuration *metrics.Histogram
+	agentFailures *metrics.Counter
+}
+
+// Outcomes of a chat, as reported by afe_chat_requests_total
+const (
+	chatSucceeded = "success"
+	chatFailed    = "error"
+	chatTimedOut  = "timeout"
+)
+
+// EnableMetrics serves Prometheus metrics at /metrics. buildCache, when not
+// nil, reads the plugin build cache whose hit rate is reported; a scrape
+// leaves the rate out when it fails. Call it before the server starts.
+func (s *Server) EnableMetrics(buildCache func() (*cache.CacheStatus, error)) {
+	registry := metrics.NewRegistry()
+	s.metrics = &serverMetrics{
+		registry: registry,
+		httpRequests: registry.NewCounter("afe_http_requests_total",
+			"HTTP requests served, by route and status.", "route", "status"),
+		httpDuration: registry.NewHistogram("afe_http_request_duration_seconds",
+			"Time to serve HTTP requests, by route.", metrics.DurationBuckets, "route"),
+		chats: registry.NewCounter("afe_chat_requests_total",
+			"Chat requests, by the provider that served them and outcome.", "provider", "outcome"),
+		providerTokens: registry.NewCounter("afe_provider_tokens_total",
+			"Tokens generated, by provider.", "provider"),
+		providerDuration: registry.NewHistogram("afe_provider_request_duration_seconds",
+			"Latency of model calls, by provider.", metrics.DurationBuckets, "provider"),
+		providerFailures: registry.NewCounter("afe_provider_failures_total",
+			"Model calls that failed, by provider.", "provider"),
+		agentCalls: registry.NewCounter("afe_agent_calls_total",
+			"Agent calls from chats and the API, by agent.", "agent"),
+		agentDuration: registry.NewHistogram("afe_agent_call_duration_seconds",
+			"Time agent calls took, by agent.", metrics.DurationBuckets, "agent"),
+		agentFailures: registry.NewCounter("afe_agent_call_failures_total",
+			"Agent calls that failed or reported failure, by agent.", "agent"),
+	}
+
+	registry.NewGaugeFunc("afe_websocket_clients", "Connected WebSocket clients.", func() (float64, bool) {
+		return float64(s.events.len()), true
+	})
+	if buildCache != nil {
+		registry.NewGaugeFunc("afe_build_cache_hit_ratio", "Share of plugin builds served from the build cache.", func() (float64, bool) {
+			status, err := buildCache()
+			if err != nil {
+				return 0, false
+			}
+			return status.CacheHitRate / 100, true
+		})
+	}
+}
+
+// ObserveModel records a model call in the metrics; set it as the model
+// manager's observer
+func (s *Server) ObserveModel(observation models.Observation) {
+	s.metrics.observeModel(observation)
+}
+
+// handleMetrics serves the metrics in the Prometheus text format
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		s.sendError(w, http.StatusMethodNotAllowed, "Only GET method allowed")
+		return
+	}
+	s.metrics.registry.Handler().ServeHTTP(w, r)
+}
+
+func (m *serverMetrics) observeRequest(route string, status int, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	// Requests no route matched are counted together
+	if route == "" {
+		route = "unmatched"
+	}
+	m.httpRequests.Inc(route, strconv.Itoa(status))
+	m.httpDuration.Observe(duration.Seconds(), route)
+}
+
+func (m *serverMetrics) observeChat(provider, outcome string) {
+	if m == nil {
+		return
+	}
+	m.chats.Inc(provider, outcome)
+}
+
+// observeModel records a model call. Calls cancelled by the client are not
+// the provider's failure.
+func (m *serverMetrics) observeModel(observation models.Observation) {
+	if m == nil {
+		return
+	}
+	m.providerDuration.Observe(observation.Duration.Seconds(), observation.Model)
+	if observation.Tokens > 0 {
+		m.providerTokens.Add(float64(observation.Tokens), observation.Model)
+	}
+	if observation.Err != nil && !errors.Is(observation.Err, context.Canceled) {
+		m.providerFailures.Inc(observation.Model)
+	}
+}
+
+func (m *serverMetrics) observeAgentCall(name string, duration time.Duration, succeeded bool) {
+	if m == nil {
+		return
+	}
+	m.agentCalls.Inc(name)
+	m.agentDuration.Observe(duration.Seconds(), name)
+	if !succeeded {
+		m.agentFailures.Inc(name)
+	}
+}
+
+// statusRecorder remembers the status a handler responded with. It passes
+// hijacking and flushing through, so WebSocket upgrades keep working.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *statusRecorder) Flush() {
+	http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(r.ResponseWriter).Hijack()
+	if err == nil && !r.wroteHeader {
+		r.status = http.StatusSwitchingProtocols
+		r.wroteHeader = true
+	}
+	return conn, rw, err
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/internal/metrics"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/cache"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+func TestMetrics_Scrape(t *testing.T) {
+	provider := &scriptedProvider{replies: []string{
+		`Listing first. <function_call name="ls">{"path": "."}</function_call>`,
+		"Done.",
+	}}
+	agents := fakeRegistry{
+		"ls":     &fakeAgent{name: "ls", output: interfaces.AgentOutput{Success: true}},
+		"broken": &fakeAgent{name: "broken", output: interfaces.AgentOutput{Success: false, Error: "no"}},
+	}
+	server := newToolLoopServer(provider, agents)
+	server.EnableMetrics(func() (*cache.CacheStatus, error) {
+		return &cache.CacheStatus{CacheHits: 3, CacheMisses: 1, CacheHitRate: 75}, nil
+	})
+	server.modelManager.SetObserver(server.ObserveModel)
+
+	requests := []struct {
+		method, path, body string
+		expected           int
+	}{
+		{http.MethodPost, "/api/v1/chat", `{"message": "list the files"}`, http.StatusOK},
+		{http.MethodPost, "/api/v1/agents/ls", `{"type": "execute"}`, http.StatusOK},
+		{http.MethodPost, "/api/v1/agents/broken", `{"type": "execute"}`, http.StatusUnprocessableEntity},
+		{http.MethodGet, "/api/v1/health", "", http.StatusOK},
+	}
+	for _, r := range requests {
+		if status, response := serve(t, server, httptest.NewRequest(r.method, r.path, strings.NewReader(r.body))); status != r.expected {
+			t.Fatalf("%s %s: expected %d, got %d: %+v", r.method, r.path, r.expected, status, response)
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	server.wrapHandlers().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if recorder.Code != http.StatusOK || recorder.Header().Get("Content-Type") != metrics.ContentType {
+		t.Fatalf("Expected the metrics, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	scrape := recorder.Body.String()
+	for _, line := range []string{
+		`afe_http_requests_total{route="/api/v1/chat",status="200"} 1`,
+		`afe_http_requests_total{route="/api/v1/agents/",status="200"} 1`,
+		`afe_http_requests_total{route="/api/v1/agents/",status="422"} 1`,
+		`afe_http_requests_total{route="/api/v1/health",status="200"} 1`,
+		`afe_http_request_duration_seconds_count{route="/api/v1/agents/"} 2`,
+		`afe_chat_requests_total{provider="scripted",outcome="success"} 1`,
+		// The chat called the model once for the tool and once for the answer
+		`afe_provider_request_duration_seconds_count{provider="scripted"} 2`,
+		// The chat's tool call and the API call both ran ls
+		`afe_agent_calls_total{agent="ls"} 2`,
+		`afe_agent_calls_total{agent="broken"} 1`,
+		`afe_agent_call_failures_total{agent="broken"} 1`,
+		`afe_agent_call_duration_seconds_count{agent="ls"} 2`,
+		`afe_websocket_clients 0`,
+		`afe_build_cache_hit_ratio 0.75`,
+	} {
+		if !strings.Contains(scrape, line+"\n") {
+			t.Errorf("Expected %q in the scrape:\n%s", line, scrape)
+		}
+	}
+	if strings.Contains(scrape, `afe_agent_call_failures_total{agent="ls"}`) {
+		t.Error("Expected ls not to be counted as failing")
+	}
+}
+
+func TestMetrics_DisabledByDefault(t *testing.T) {
+	server := NewServer("localhost", 0)
+	recorder := httptest.NewRecorder()
+	server.wrapHandlers().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected no /metrics route, got %d", recorder.Code)
+	}
+}
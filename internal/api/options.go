@@ -0,0 +1,162 @@
+package api
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+const (
+	// defaultChatMaxTokens and defaultChatTemperature apply when a chat
+	// request does not set max_tokens or temperature
+	defaultChatMaxTokens   = 8000
+	defaultChatTemperature = 0.7
+)
+
+// floatRange bounds a float option; either bound may be infinite
+type floatRange struct {
+	min, max float64
+}
+
+// floatOptions are the float sampling options a chat request may set and
+// their allowed ranges
+var floatOptions = map[string]floatRange{
+	"temperature":       {0, 2},
+	"top_p":             {0, 1},
+	"repeat_penalty":    {0, math.Inf(1)},
+	"presence_penalty":  {-2, 2},
+	"frequency_penalty": {-2, 2},
+}
+
+// chatRoles are the roles a message in a chat request may have
+var chatRoles = map[string]bool{
+	"system":    true,
+	"user":      true,
+	"assistant": true,
+	"tool":      true,
+}
+
+// generationRequest builds the model request for a chat, applying the
+// sampling options from req.Options. Options other than the known sampling
+// parameters are ignored; known ones with the wrong type or out of range
+// return an error, as do messages with an unknown role.
+func generationRequest(req ChatRequest) (interfaces.GenerationRequest, error) {
+	genReq := interfaces.GenerationRequest{
+		Prompt:      req.Message,
+		MaxTokens:   defaultChatMaxTokens,
+		Temperature: defaultChatTemperature,
+		Stream:      req.Stream,
+	}
+
+	if len(req.Messages) > 0 {
+		for i, msg := range req.Messages {
+			if !chatRoles[msg.Role] {
+				return genReq, fmt.Errorf("invalid message %d: unknown role %q", i, msg.Role)
+			}
+		}
+		genReq.Messages = append([]interfaces.ChatMessage(nil), req.Messages...)
+		if req.Message != "" {
+			genReq.Messages = append(genReq.Messages, interfaces.ChatMessage{Role: "user", Content: req.Message})
+		}
+		// Models that only take a prompt see the latest message
+		genReq.Prompt = genReq.Messages[len(genReq.Messages)-1].Content
+	}
+
+	floats := make(map[string]float64)
+	for name, bounds := range floatOptions {
+		raw, ok := req.Options[name]
+		if !ok {
+			continue
+		}
+		value, ok := raw.(float64)
+		if !ok {
+			return genReq, fmt.Errorf("invalid option %s: must be a number", name)
+		}
+		if value < bounds.min || value > bounds.max {
+			return genReq, fmt.Errorf("invalid option %s: %v is out of range %s", name, value, bounds)
+		}
+		floats[name] = value
+	}
+	if value, ok := floats["temperature"]; ok {
+		genReq.Temperature = value
+	}
+	genReq.TopP = floats["top_p"]
+	genReq.RepeatPenalty = floats["repeat_penalty"]
+	genReq.PresencePenalty = floats["presence_penalty"]
+	genReq.FrequencyPenalty = floats["frequency_penalty"]
+
+	if value, ok, err := intOption(req.Options, "max_tokens"); err != nil {
+		return genReq, err
+	} else if ok {
+		if value < 1 {
+			return genReq, fmt.Errorf("invalid option max_tokens: must be at least 1")
+		}
+		genReq.MaxTokens = value
+	}
+
+	if value, ok, err := intOption(req.Options, "top_k"); err != nil {
+		return genReq, err
+	} else if ok {
+		if value < 0 {
+			return genReq, fmt.Errorf("invalid option top_k: must not be negative")
+		}
+		genReq.TopK = value
+	}
+
+	if value, ok, err := intOption(req.Options, "seed"); err != nil {
+		return genReq, err
+	} else if ok {
+		genReq.Seed = &value
+	}
+
+	stop, err := stopOption(req.Options)
+	if err != nil {
+		return genReq, err
+	}
+	genReq.StopTokens = stop
+
+	return genReq, nil
+}
+
+func (r floatRange) String() string {
+	if math.IsInf(r.max, 1) {
+		return fmt.Sprintf("[%v, ∞)", r.min)
+	}
+	return fmt.Sprintf("[%v, %v]", r.min, r.max)
+}
+
+// intOption reads a whole number option, which arrives from JSON as a float64
+func intOption(options map[string]interface{}, name string) (int, bool, error) {
+	raw, ok := options[name]
+	if !ok {
+		return 0, false, nil
+	}
+	value, ok := raw.(float64)
+	if !ok || value != math.Trunc(value) {
+		return 0, false, fmt.Errorf("invalid option %s: must be a whole number", name)
+	}
+	return int(value), true, nil
+}
+
+// stopOption reads the stop option, which may be one string or a list
+func stopOption(options map[string]interface{}) ([]string, error) {
+	switch stop := options["stop"].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{stop}, nil
+	case []interface{}:
+		tokens := make([]string, 0, len(stop))
+		for _, token := range stop {
+			s, ok := token.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid option stop: must be a string or a list of strings")
+			}
+			tokens = append(tokens, s)
+		}
+		return tokens, nil
+	default:
+		return nil, fmt.Errorf("invalid option stop: must be a string or a list of strings")
+	}
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+func TestGenerationRequest_Defaults(t *testing.T) {
+	genReq, err := generationRequest(ChatRequest{Message: "hi", Stream: true})
+	if err != nil {
+		t.Fatalf("generationRequest failed: %v", err)
+	}
+	if genReq.Prompt != "hi" || genReq.MaxTokens != defaultChatMaxTokens || genReq.Temperature != defaultChatTemperature || !genReq.Stream {
+		t.Errorf("Unexpected defaults: %+v", genReq)
+	}
+	if genReq.TopP != 0 || genReq.TopK != 0 || genReq.Seed != nil || genReq.StopTokens != nil {
+		t.Errorf("Expected sampling parameters to be unset, got %+v", genReq)
+	}
+}
+
+func TestGenerationRequest_Options(t *testing.T) {
+	genReq, err := generationRequest(ChatRequest{
+		Message: "hi",
+		Options: map[string]interface{}{
+			"temperature":       float64(0),
+			"max_tokens":        float64(256),
+			"top_p":             0.95,
+			"top_k":             float64(40),
+			"repeat_penalty":    1.1,
+			"presence_penalty":  -0.5,
+			"frequency_penalty": 0.5,
+			"seed":              float64(42),
+			"stop":              []interface{}{"</answer>", "\n\n"},
+			"verbose":           true,
+		},
+	})
+	if err != nil {
+		t.Fatalf("generationRequest failed: %v", err)
+	}
+
+	if genReq.Temperature != 0 || genReq.MaxTokens != 256 || genReq.TopP != 0.95 || genReq.TopK != 40 ||
+		genReq.RepeatPenalty != 1.1 || genReq.PresencePenalty != -0.5 || genReq.FrequencyPenalty != 0.5 {
+		t.Errorf("Unexpected sampling parameters: %+v", genReq)
+	}
+	if genReq.Seed == nil || *genReq.Seed != 42 {
+		t.Errorf("Expected seed 42, got %v", genReq.Seed)
+	}
+	if !reflect.DeepEqual(genReq.StopTokens, []string{"</answer>", "\n\n"}) {
+		t.Errorf("Unexpected stop tokens: %v", genReq.StopTokens)
+	}
+
+	genReq, _ = generationRequest(ChatRequest{Message: "hi", Options: map[string]interface{}{"stop": "END"}})
+	if !reflect.DeepEqual(genReq.StopTokens, []string{"END"}) {
+		t.Errorf("Expected a single stop string to be accepted, got %v", genReq.StopTokens)
+	}
+}
+
+func TestGenerationRequest_Messages(t *testing.T) {
+	history := []interfaces.ChatMessage{
+		{Role: "system", Content: "Be brief."},
+		{Role: "user", Content: "What is 2+2?"},
+		{Role: "assistant", Content: "4"},
+	}
+
+	genReq, err := generationRequest(ChatRequest{Messages: history, Message: "And 3+3?"})
+	if err != nil {
+		t.Fatalf("generationRequest failed: %v", err)
+	}
+	expected := append(append([]interfaces.ChatMessage(nil), history...), interfaces.ChatMessage{Role: "user", Content: "And 3+3?"})
+	if !reflect.DeepEqual(genReq.Messages, expected) {
+		t.Errorf("Expected the message appended to the history, got %+v", genReq.Messages)
+	}
+	if genReq.Prompt != "And 3+3?" {
+		t.Errorf("Expected the latest message as the prompt, got %q", genReq.Prompt)
+	}
+
+	genReq, err = generationRequest(ChatRequest{Messages: history[:2]})
+	if err != nil || len(genReq.Messages) != 2 || genReq.Prompt != "What is 2+2?" {
+		t.Errorf("Expected the history alone to be used, got %+v, %v", genReq, err)
+	}
+
+	genReq, _ = generationRequest(ChatRequest{Message: "hi"})
+	if genReq.Messages != nil {
+		t.Errorf("Expected no messages for a plain message, got %+v", genReq.Messages)
+	}
+
+	_, err = generationRequest(ChatRequest{Messages: []interfaces.ChatMessage{{Role: "wizard", Content: "hi"}}})
+	if err == nil || !strings.Contains(err.Error(), "unknown role") {
+		t.Errorf("Expected an unknown role to be rejected, got %v", err)
+	}
+}
+
+func TestGenerationRequest_InvalidOptions(t *testing.T) {
+	tests := []struct {
+		option string
+		value  interface{}
+	}{
+		{"temperature", -0.1},
+		{"temperature", "hot"},
+		{"top_p", 1.5},
+		{"top_k", float64(-1)},
+		{"top_k", 2.5},
+		{"max_tokens", float64(0)},
+		{"repeat_penalty", -1.0},
+		{"presence_penalty", 3.0},
+		{"frequency_penalty", -2.5},
+		{"seed", "abc"},
+		{"stop", []interface{}{"ok", 1.0}},
+		{"stop", 7.0},
+	}
+
+	for _, test := range tests {
+		_, err := generationRequest(ChatRequest{Message: "hi", Options: map[string]interface{}{test.option: test.value}})
+		if err == nil || !strings.Contains(err.Error(), "invalid option "+test.option) {
+			t.Errorf("Expected %s=%v to be rejected, got %v", test.option, test.value, err)
+		}
+	}
+}
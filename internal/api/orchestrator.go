@@ -0,0 +1,141 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/internal/orchestrator"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+// orchestratorPrefix marks the function calls the orchestrator serves, such
+// as orch.manager, rather than an agent
+const orchestratorPrefix = "orch."
+
+// SetOrchestrator sets the orchestrator that serves the model's orch.
+// function calls; nil makes them fail, as while the engine is stopped. Its
+// workflow tasks run like the model's own calls, checked against the policy,
+// and its workflow events are sent to /api/v1/events clients.
+func (s *Server) SetOrchestrator(manager *orchestrator.Manager) {
+	if manager != nil {
+		manager.SetAgentCaller(chatCaller{s})
+		manager.SetEventFunc(s.PublishEvent)
+	}
+
+	s.componentsMu.Lock()
+	defer s.componentsMu.Unlock()
+	s.orchestrator = manager
+}
+
+// SetWorkflowStore sets the store /api/v1/workflows lists the orchestrator's
+// workflows from. Call it before the server starts.
+func (s *Server) SetWorkflowStore(store orchestrator.WorkflowStore) {
+	s.workflows = store
+}
+
+// WorkflowListResponse lists stored workflows, oldest first
+type WorkflowListResponse struct {
+	Workflows []orchestrator.Workflow `json:"workflows"`
+}
+
+// handleListWorkflows lists the stored workflows, filtered by the status
+// query parameter. They are read from the store, so the workflows of earlier
+// runs are listed too, and still are while the engine is stopped.
+func (s *Server) handleListWorkflows(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.sendError(w, http.StatusMethodNotAllowed, "Only GET method allowed")
+		return
+	}
+	if s.workflows == nil {
+		s.sendError(w, http.StatusNotFound, "Workflow persistence is not enabled")
+		return
+	}
+
+	workflows, err := s.workflows.List()
+	if err != nil {
+		s.sendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to list workflows: %v", err))
+		return
+	}
+
+	response := WorkflowListResponse{Workflows: workflows}
+	if status := r.URL.Query().Get("status"); status != "" {
+		response.Workflows = []orchestrator.Workflow{}
+		for _, workflow := range workflows {
+			if string(workflow.Status) == status {
+				response.Workflows = append(response.Workflows, workflow)
+			}
+		}
+	}
+	s.sendSuccess(w, response)
+}
+
+// orchestratorManager returns the current orchestrator, or nil
+func (s *Server) orchestratorManager() *orchestrator.Manager {
+	s.componentsMu.RLock()
+	defer s.componentsMu.RUnlock()
+	return s.orchestrator
+}
+
+// executeOrchestratorCall runs an orch. function call, setting its response
+// and duration. The function itself is not checked against the policy; the
+// agents of its tasks are.
+func (s *Server) executeOrchestratorCall(ctx context.Context, call *FunctionCall) {
+	start := time.Now()
+	manager := s.orchestratorManager()
+	if manager == nil {
+		call.Response = &FunctionResponse{Name: call.Name, Success: false, Error: "orchestrator is not enabled"}
+		return
+	}
+	if _, ok := manager.GetAvailableOrchestrators()[call.Name]; !ok {
+		call.Response = &FunctionResponse{
+			Name:    call.Name,
+			Success: false,
+			Error:   fmt.Sprintf("Orchestrator function %s not found", call.Name),
+		}
+		return
+	}
+
+	output, err := manager.Process(ctx, interfaces.AgentInput{
+		Type:    strings.TrimPrefix(call.Name, orchestratorPrefix),
+		Payload: call.Arguments,
+	})
+	call.Duration = time.Since(start).String()
+	if err != nil {
+		call.Response = &FunctionResponse{Name: call.Name, Success: false, Error: err.Error()}
+		return
+	}
+	call.Response = &FunctionResponse{
+		Name:    call.Name,
+		Success: output.Success,
+		Data:    output.Data,
+		Error:   output.Error,
+	}
+}
+
+// chatCaller runs the orchestrator's workflow tasks the way a chat runs the
+// model's function calls
+type chatCaller struct {
+	server *Server
+}
+
+func (c chatCaller) CallAgent(ctx context.Context, name string, input interfaces.AgentInput) (interfaces.AgentOutput, error) {
+	// A workflow cannot start another
+	if strings.HasPrefix(name, orchestratorPrefix) {
+		return interfaces.AgentOutput{Success: false, Error: fmt.Sprintf("Agent %s not found", name)}, nil
+	}
+
+	call := FunctionCall{Name: name, Arguments: input.Payload}
+	c.server.executeFunctionCall(ctx, &call)
+	response := call.Response
+	return interfaces.AgentOutput{
+		Success:  response.Success,
+		Data:     response.Data,
+		Error:    response.Error,
+		Code:     response.Code,
+		Warnings: response.Warnings,
+		Meta:     response.Meta,
+	}, nil
+}
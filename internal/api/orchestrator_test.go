@@ -0,0 +1,166 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/internal/orchestrator"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+// orderedAgent records the order agents ran in
+type orderedAgent struct {
+	*fakeAgent
+	mu  *sync.Mutex
+	ran *[]string
+}
+
+func (a orderedAgent) Process(ctx context.Context, input interfaces.AgentInput) (interfaces.AgentOutput, error) {
+	a.mu.Lock()
+	*a.ran = append(*a.ran, a.name)
+	a.mu.Unlock()
+	return a.fakeAgent.Process(ctx, input)
+}
+
+func TestChat_OrchestratorRunsTodoList(t *testing.T) {
+	var mu sync.Mutex
+	var ran []string
+	ls := &fakeAgent{name: "ls", output: interfaces.AgentOutput{Success: true}}
+	touch := &fakeAgent{name: "touch", output: interfaces.AgentOutput{Success: true}}
+	agents := fakeRegistry{
+		"ls":    orderedAgent{ls, &mu, &ran},
+		"touch": orderedAgent{touch, &mu, &ran},
+	}
+
+	provider := &scriptedProvider{replies: []string{
+		`<function_call name="orch.manager">{"name": "setup", "todos": ["list the project directory", "create notes.txt"]}</function_call>`,
+		"Both steps are done.",
+	}}
+	server := newToolLoopServer(provider, agents)
+	server.SetOrchestrator(orchestrator.NewManager(agents, nil))
+
+	httpServer := httptest.NewServer(server.wrapHandlers())
+	defer httpServer.Close()
+	events := dialEvents(t, httpServer.URL)
+	waitForClients(t, server, 1)
+	events.WriteJSON(map[string]interface{}{"subscribe": []string{TopicWorkflows}})
+	readEvent(t, events)
+
+	response := chat(t, server, `{"message": "set up the project"}`)
+	if response.Message != "Both steps are done." || len(response.FunctionCalls) != 1 {
+		t.Fatalf("Expected the model to answer after the workflow, got %+v", response)
+	}
+	if call := response.FunctionCalls[0]; call.Response == nil || !call.Response.Success {
+		t.Fatalf("Expected the orchestrator call to succeed, got %+v", call.Response)
+	}
+
+	if strings.Join(ran, ",") != "ls,touch" {
+		t.Errorf("Expected ls then touch to run, got %v", ran)
+	}
+	if ls.input.Payload["path"] != "." || touch.input.Payload["file"] != "notes.txt" {
+		t.Errorf("Expected the todos' arguments, got %v and %v", ls.input.Payload, touch.input.Payload)
+	}
+
+	var types []string
+	for range 6 {
+		event := readEvent(t, events)
+		types = append(types, event["type"].(string))
+	}
+	expected := "workflow_start workflow_task workflow_task workflow_task workflow_task workflow_complete"
+	if strings.Join(types, " ") != expected {
+		t.Errorf("Expected the workflow's events, got %v", types)
+	}
+}
+
+func TestChat_OrchestratorTasksFollowPolicy(t *testing.T) {
+	touch := &fakeAgent{name: "touch", output: interfaces.AgentOutput{Success: true}}
+	agents := fakeRegistry{"touch": touch}
+	replies := []string{`<function_call name="orch.manager">{"todos": "create notes.txt"}</function_call>`, "Done."}
+	server := newToolLoopServer(&scriptedProvider{replies: replies}, agents)
+	server.SetOrchestrator(orchestrator.NewManager(agents, nil))
+	// The policy allows no agent; the orchestrator itself is not checked
+	allowAgents(server)
+
+	response := chat(t, server, `{"message": "take notes"}`)
+	if touch.calls != 0 {
+		t.Error("Expected the denied agent not to run")
+	}
+	if call := response.FunctionCalls[0]; call.Response == nil || !strings.Contains(call.Response.RawResponse, "not allowed") {
+		t.Errorf("Expected the workflow to report the denied task, got %+v", call.Response)
+	}
+
+	// Without an orchestrator the call fails
+	server.SetOrchestrator(nil)
+	server.modelManager.RegisterProvider("scripted", &scriptedProvider{replies: replies})
+	response = chat(t, server, `{"message": "take notes"}`)
+	if call := response.FunctionCalls[0]; call.Response.Success || call.Response.Error != "orchestrator is not enabled" {
+		t.Errorf("Expected the call to fail, got %+v", call.Response)
+	}
+}
+
+func TestChat_OrchestratorDryRun(t *testing.T) {
+	touch := &fakeAgent{name: "touch", output: interfaces.AgentOutput{Success: true}}
+	agents := fakeRegistry{"touch": touch}
+	replies := []string{`<function_call name="orch.manager">{"todos": ["create notes.txt", "water the plants"], "dry_run": true}</function_call>`, "Here is the plan."}
+	server := newToolLoopServer(&scriptedProvider{replies: replies}, agents)
+	server.SetOrchestrator(orchestrator.NewManager(agents, nil))
+
+	response := chat(t, server, `{"message": "plan the notes"}`)
+	call := response.FunctionCalls[0]
+	if call.Response == nil || !call.Response.Success {
+		t.Fatalf("Expected the plan, got %+v", call.Response)
+	}
+	if touch.calls != 0 {
+		t.Error("Expected a dry run to run no agent")
+	}
+	for _, expected := range []string{"notes.txt", "unmapped", "rephrase"} {
+		if !strings.Contains(call.Response.RawResponse, expected) {
+			t.Errorf("Expected the plan to contain %q, got %s", expected, call.Response.RawResponse)
+		}
+	}
+}
+
+func TestListWorkflows(t *testing.T) {
+	touch := &fakeAgent{name: "touch", output: interfaces.AgentOutput{Success: true}}
+	agents := fakeRegistry{"touch": touch}
+	replies := []string{`<function_call name="orch.manager">{"name": "notes", "todos": "create notes.txt"}</function_call>`, "Done."}
+	server := newToolLoopServer(&scriptedProvider{replies: replies}, agents)
+
+	request := httptest.NewRequest(http.MethodGet, "/api/v1/workflows", nil)
+	if status, _ := serve(t, server, request); status != http.StatusNotFound {
+		t.Fatalf("Expected 404 without a workflow store, got %d", status)
+	}
+
+	store, err := orchestrator.NewLevelDBStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLevelDBStore failed: %v", err)
+	}
+	defer store.Close()
+	manager := orchestrator.NewManager(agents, nil)
+	if err := manager.SetStore(store); err != nil {
+		t.Fatalf("SetStore failed: %v", err)
+	}
+	server.SetOrchestrator(manager)
+	server.SetWorkflowStore(store)
+	chat(t, server, `{"message": "take notes"}`)
+
+	// The workflow is listed from the store even once the engine stops
+	server.SetOrchestrator(nil)
+	for query, expected := range map[string]int{"": 1, "?status=completed": 1, "?status=failed": 0} {
+		status, response := serve(t, server, httptest.NewRequest(http.MethodGet, "/api/v1/workflows"+query, nil))
+		if status != http.StatusOK {
+			t.Fatalf("%q: expected 200, got %d: %+v", query, status, response)
+		}
+		workflows := response.Data.(map[string]interface{})["workflows"].([]interface{})
+		if len(workflows) != expected {
+			t.Fatalf("%q: expected %d workflows, got %v", query, expected, workflows)
+		}
+		if expected == 1 && workflows[0].(map[string]interface{})["name"] != "notes" {
+			t.Errorf("%q: expected the notes workflow, got %v", query, workflows[0])
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"container/list"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// maxRateLimitClients bounds the clients whose buckets are kept; the one
+// idle longest is dropped for a new one, and starts full if it comes back
+const maxRateLimitClients = 10000
+
+// rateLimiter keeps a token bucket per client
+type rateLimiter struct {
+	limit rate.Limit
+	burst int
+	max   int
+
+	mu      sync.Mutex
+	buckets map[string]*list.Element
+	// idle orders the buckets from most to least recently used
+	idle *list.List
+}
+
+type clientBucket struct {
+	key     string
+	limiter *rate.Limiter
+}
+
+func newRateLimiter(requestsPerMinute, burst, max int) *rateLimiter {
+	return &rateLimiter{
+		limit:   rate.Limit(float64(requestsPerMinute) / 60),
+		burst:   burst,
+		max:     max,
+		buckets: make(map[string]*list.Element),
+		idle:    list.New(),
+	}
+}
+
+// reserve takes a token from the client's bucket. When the bucket is empty
+// it takes none and returns how long until one is available.
+func (l *rateLimiter) reserve(key string, now time.Time) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	element, ok := l.buckets[key]
+	if ok {
+		l.idle.MoveToFront(element)
+	} else {
+		element = l.idle.PushFront(&clientBucket{key: key, limiter: rate.NewLimiter(l.limit, l.burst)})
+		l.buckets[key] = element
+		if l.idle.Len() > l.max {
+			oldest := l.idle.Back()
+			l.idle.Remove(oldest)
+			delete(l.buckets, oldest.Value.(*clientBucket).key)
+		}
+	}
+
+	reservation := element.Value.(*clientBucket).limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return delay, false
+	}
+	return 0, true
+}
+
+// SetRateLimit limits how often each client may call the API: up to burst
+// requests at once, refilled at requestsPerMinute. Clients are told apart by
+// their API key or user when authenticated, and by remote IP otherwise.
+// requestsPerMinute of zero or less disables the limit; a burst of zero or
+// less allows one request at a time. Call it before the server starts.
+func (s *Server) SetRateLimit(requestsPerMinute, burst int) {
+	if requestsPerMinute <= 0 {
+		s.rateLimiter = nil
+		return
+	}
+	if burst <= 0 {
+		burst = 1
+	}
+	s.rateLimiter = newRateLimiter(requestsPerMinute, burst, maxRateLimitClients)
+}
+
+// rateLimit wraps a handler so that clients over the rate limit are refused
+// with 429 and a Retry-After header. Wrap it within requireAuth, so that
+// authenticated clients are limited by their credentials.
+func (s *Server) rateLimit(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.rateLimiter == nil {
+			handler(w, r)
+			return
+		}
+
+		delay, ok := s.rateLimiter.reserve(rateLimitKey(r), time.Now())
+		if !ok {
+			seconds := int(math.Ceil(delay.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(seconds))
+			s.sendError(w, http.StatusTooManyRequests, fmt.Sprintf("Rate limit exceeded; retry in %d seconds", seconds))
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// rateLimitKey names the client of a request: its API key, the user of its
+// session token, or its remote IP
+func rateLimitKey(r *http.Request) string {
+	if principal, ok := PrincipalFromContext(r.Context()); ok {
+		return principal.Identity()
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimit_ByRemoteIP(t *testing.T) {
+	server := newToolLoopServer(&scriptedProvider{replies: []string{"hi"}}, fakeRegistry{})
+	server.SetRateLimit(60, 2)
+	handler := server.wrapHandlers()
+
+	get := func(path, remoteAddr string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, path, nil)
+		request.RemoteAddr = remoteAddr
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	for i := 0; i < 2; i++ {
+		if recorder := get("/api/v1/agents", "10.0.0.1:1234"); recorder.Code != http.StatusOK {
+			t.Fatalf("Request %d: expected 200 within the burst, got %d", i, recorder.Code)
+		}
+	}
+	// The port does not make another client
+	recorder := get("/api/v1/agents", "10.0.0.1:5678")
+	if recorder.Code != http.StatusTooManyRequests || recorder.Header().Get("Retry-After") != "1" {
+		t.Errorf("Expected 429 with Retry-After 1, got %d and %q", recorder.Code, recorder.Header().Get("Retry-After"))
+	}
+
+	if recorder := get("/api/v1/agents", "10.0.0.2:1234"); recorder.Code != http.StatusOK {
+		t.Errorf("Expected another client to be served, got %d", recorder.Code)
+	}
+	if recorder := get("/api/v1/health", "10.0.0.1:1234"); recorder.Code != http.StatusOK {
+		t.Errorf("Expected health not to be limited, got %d", recorder.Code)
+	}
+}
+
+func TestRateLimit_ByAPIKey(t *testing.T) {
+	f := newAuthFixture(t)
+	f.server.SetRateLimit(60, 1)
+
+	// Both requests come from the same address
+	if status, _ := f.do(http.MethodGet, "/api/v1/agents", "", map[string]string{"X-API-Key": f.readKey}); status != http.StatusOK {
+		t.Fatalf("Expected the first request to be served, got %d", status)
+	}
+	if status, response := f.do(http.MethodGet, "/api/v1/agents", "", map[string]string{"X-API-Key": f.readKey}); status != http.StatusTooManyRequests {
+		t.Errorf("Expected the key's second request to be limited, got %d: %+v", status, response)
+	}
+
+	// A session token is limited as its user
+	status, response := f.do(http.MethodPost, "/api/v1/auth/login", `{"email": "ada@example.com", "password": "correct horse"}`, nil)
+	if status != http.StatusOK {
+		t.Fatalf("Expected login to succeed, got %d: %+v", status, response)
+	}
+	token := response.Data.(map[string]interface{})["token"].(string)
+	if status, _ := f.do(http.MethodGet, "/api/v1/agents", "", map[string]string{"Authorization": "Bearer " + token}); status != http.StatusOK {
+		t.Errorf("Expected the session to be served, got %d", status)
+	}
+}
+
+func TestRateLimiter_EvictsIdleClients(t *testing.T) {
+	limiter := newRateLimiter(1, 1, 2)
+	now := time.Now()
+
+	if _, ok := limiter.reserve("a", now); !ok {
+		t.Fatal("Expected a's first request to be allowed")
+	}
+	if delay, ok := limiter.reserve("a", now); ok || delay != time.Minute {
+		t.Fatalf("Expected a to wait a minute, got %v, %v", delay, ok)
+	}
+
+	// b and c push out a, the client idle longest, whose bucket starts over
+	limiter.reserve("b", now)
+	limiter.reserve("c", now)
+	if len(limiter.buckets) != 2 {
+		t.Errorf("Expected two buckets to be kept, got %d", len(limiter.buckets))
+	}
+	if _, ok := limiter.reserve("a", now); !ok {
+		t.Error("Expected a's evicted bucket to start full")
+	}
+	if _, ok := limiter.reserve("c", now); ok {
+		t.Error("Expected c's bucket to be kept")
+	}
+}
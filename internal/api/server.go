@@ -2,22 +2,33 @@ package api
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"net"
 	"net/http"
+	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/AgentForgeEngine/AgentForgeEngine/internal/loader"
+	"github.com/AgentForgeEngine/AgentForgeEngine/internal/logging"
 	"github.com/AgentForgeEngine/AgentForgeEngine/internal/models"
+	"github.com/AgentForgeEngine/AgentForgeEngine/internal/orchestrator"
+	"github.com/AgentForgeEngine/AgentForgeEngine/internal/policy"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/auth"
 	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/retry"
 
 	"github.com/AgentForgeEngine/AgentForgeEngine/internal/response"
 
 	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/status"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/tokenizer"
 	"github.com/gorilla/websocket"
 )
 
@@ -27,48 +38,168 @@ type Server struct {
 	host       string
 	router     *http.ServeMux
 	wsUpgrader websocket.Upgrader
-	wsClients  map[*websocket.Conn]bool
-	wsMutex    sync.RWMutex
+	// events sends published events to /api/v1/events clients
+	events     *eventHub
+	httpServer *http.Server
+	serverMu   sync.Mutex
 
-	// AFE components
+	// AFE components, replaced as the engine starts and stops
+	componentsMu  sync.RWMutex
 	statusManager *status.Manager
-	pluginManager *loader.Manager
+	pluginManager agentRegistry
 	modelManager  *models.Manager
-	// orchestratorManager *orchestrator.Manager // Disabled for now
+	// agentTimeout bounds agent calls that do not set timeout_seconds
+	agentTimeout time.Duration
+	// readTimeout, writeTimeout, and idleTimeout bound the HTTP server's
+	// connections; maxRequestTimeout bounds chats and the timeouts requests
+	// ask for; maxBodyBytes bounds request bodies
+	readTimeout       time.Duration
+	writeTimeout      time.Duration
+	idleTimeout       time.Duration
+	maxRequestTimeout time.Duration
+	maxBodyBytes      int64
+	// maxToolIterations bounds the model calls of one chat
+	maxToolIterations int
+	// retryBudget bounds the retries of one chat's agent and model calls;
+	// agentRetry retries agent calls that fail with a transient error
+	retryBudget int
+	agentRetry  retry.Policy
+	// sessions keeps the history of chat sessions; busySessions holds those
+	// with a chat in flight
+	sessions         ConversationStore
+	sessionMaxTokens int
+	busySessions     map[string]bool
+	sessionMu        sync.Mutex
+	tokenizer        tokenizer.Tokenizer
+	// exportRedactions match the secrets replaced in exported sessions
+	exportRedactions []*regexp.Regexp
+	// policy decides which agent calls run
+	policy *policy.Engine
+	// secretStore resolves the secrets agent calls refer to
+	secretStore SecretStore
+	// allowedOrigins are the browser origins that may call the API across
+	// origins; none may unless configured
+	allowedOrigins map[string]bool
+	// rateLimiter, when set, limits how often each client calls the API
+	rateLimiter *rateLimiter
+	// metrics, when enabled, are served at /metrics
+	metrics *serverMetrics
+	// authenticator, when set, checks the credentials of every request but
+	// health and login; tokens signs the session tokens login issues
+	authenticator Authenticator
+	tokens        *auth.TokenSigner
+	// users serves the admin user routes when the authenticator can manage
+	// accounts
+	users UserAdmin
+	// lifecycle starts, stops, and reloads the engine's components
+	lifecycle *Lifecycle
+	// orchestrator serves the model's orch. function calls
+	orchestrator *orchestrator.Manager
+	// workflows, when set, holds the orchestrator's workflows, which
+	// /api/v1/workflows lists
+	workflows orchestrator.WorkflowStore
 	formatter *response.XMLFormatter
+	// logger writes the server's records to logs, which /api/v1/logs
+	// serves; stopLogFollow ends the forwarding of new records to the logs
+	// topic, once a client follows them
+	logger        *logging.Logger
+	logs          *logging.Buffer
+	logsMu        sync.Mutex
+	stopLogFollow func()
 }
 
+// agentRegistry is the part of the plugin manager the API uses to find and
+// run agents
+type agentRegistry interface {
+	GetAgent(name string) (interfaces.Agent, bool)
+	ListAgents() []string
+	CallAgent(ctx context.Context, name string, input interfaces.AgentInput) (interfaces.AgentOutput, error)
+}
+
+const (
+	// defaultAgentTimeout bounds agent calls when no timeout is configured
+	defaultAgentTimeout = 60 * time.Second
+)
+
 // NewServer creates a new API server instance
 func NewServer(host string, port int) *Server {
-	return &Server{
-		host:   host,
-		port:   port,
-		router: http.NewServeMux(),
-		wsUpgrader: websocket.Upgrader{
-			CheckOrigin: func(r *http.Request) bool {
-				return true // Allow same origin for now
-			},
-		},
-		wsClients: make(map[*websocket.Conn]bool),
-		formatter: response.NewXMLFormatter(),
+	s := &Server{
+		host:              host,
+		port:              port,
+		router:            http.NewServeMux(),
+		events:            newEventHub(clientSendBuffer),
+		agentTimeout:      defaultAgentTimeout,
+		readTimeout:       defaultReadTimeout,
+		idleTimeout:       defaultIdleTimeout,
+		maxRequestTimeout: defaultMaxRequestTimeout,
+		maxBodyBytes:      defaultMaxBodyBytes,
+		maxToolIterations: defaultMaxToolIterations,
+		retryBudget:       defaultRetryBudget,
+		agentRetry:        retry.DefaultPolicy(),
+		sessions:          NewMemoryStore(),
+		sessionMaxTokens:  defaultSessionMaxTokens,
+		busySessions:      make(map[string]bool),
+		exportRedactions:  defaultExportRedactions,
+		tokenizer:         tokenizer.NewHeuristic(),
+		policy:            policy.New(policy.DefaultConfig()),
+		secretStore:       EnvSecretStore{},
+		formatter:         response.NewXMLFormatter(),
+		logger:            logging.New("api"),
+		logs:              logging.Default(),
 	}
+	s.wsUpgrader.CheckOrigin = s.checkWebSocketOrigin
+	return s
 }
 
-// SetComponents sets the AFE components for the server
+// SetComponents sets the AFE components for the server. Nil managers make
+// the handlers that need them unavailable, as while the engine is stopped;
+// requests already running keep the managers they started with.
 func (s *Server) SetComponents(statusMgr *status.Manager, pluginMgr *loader.Manager, modelMgr *models.Manager) {
+	s.componentsMu.Lock()
+	defer s.componentsMu.Unlock()
 	s.statusManager = statusMgr
-	s.pluginManager = pluginMgr
+	// A nil manager must leave the interface nil so handlers can detect it
+	s.pluginManager = nil
+	if pluginMgr != nil {
+		s.pluginManager = pluginMgr
+	}
 	s.modelManager = modelMgr
 }
 
+// plugins returns the current plugin manager, or nil
+func (s *Server) plugins() agentRegistry {
+	s.componentsMu.RLock()
+	defer s.componentsMu.RUnlock()
+	return s.pluginManager
+}
+
+// modelRouter returns the current model manager, or nil
+func (s *Server) modelRouter() *models.Manager {
+	s.componentsMu.RLock()
+	defer s.componentsMu.RUnlock()
+	return s.modelManager
+}
+
+// SetAgentTimeout sets how long an agent call through the API may run when
+// the request does not set timeout_seconds. Values below one second restore
+// the default.
+func (s *Server) SetAgentTimeout(timeout time.Duration) {
+	if timeout < time.Second {
+		timeout = defaultAgentTimeout
+	}
+	s.agentTimeout = timeout
+}
+
 // setupRoutes configures all API routes
 func (s *Server) setupRoutes() {
 	// Status endpoints
 	s.router.HandleFunc("/api/v1/status", s.handleStatus)
 	s.router.HandleFunc("/api/v1/health", s.handleHealth)
+	s.router.HandleFunc("/api/v1/auth/login", s.handleLogin)
 
 	// Chat endpoints
 	s.router.HandleFunc("/api/v1/chat", s.handleChat)
+	s.router.HandleFunc("/api/v1/sessions/", s.handleSession)
 
 	// Agent endpoints
 	s.router.HandleFunc("/api/v1/agents", s.handleListAgents)
@@ -77,80 +208,147 @@ func (s *Server) setupRoutes() {
 	// Log endpoints
 	s.router.HandleFunc("/api/v1/logs", s.handleGetLogs)
 
+	// Workflow endpoints
+	s.router.HandleFunc("/api/v1/workflows", s.handleListWorkflows)
+
+	// User admin endpoints
+	s.router.HandleFunc("/api/v1/users", s.handleListUsers)
+	s.router.HandleFunc("/api/v1/users/", s.handleUser)
+
 	// System control endpoints
 	s.router.HandleFunc("/api/v1/start", s.handleStart)
 	s.router.HandleFunc("/api/v1/stop", s.handleStop)
+	s.router.HandleFunc("/api/v1/reload", s.handleReload)
 
 	// WebSocket endpoint for real-time events
 	s.router.HandleFunc("/api/v1/events", s.handleWebSocket)
 }
 
-// corsMiddleware adds CORS headers
-func (s *Server) corsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key")
-
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-
-		next.ServeHTTP(w, r)
-	})
-}
-
 // loggingMiddleware logs all requests
 func (s *Server) loggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = withRequestID(w, r)
+		logger := s.logger.WithContext(r.Context())
 		start := time.Now()
-		log.Printf("API Request: %s %s", r.Method, r.URL.Path)
+		logger.Infof("API Request: %s %s", r.Method, r.URL.Path)
 
 		next.ServeHTTP(w, r)
 
-		log.Printf("API Response: %s %s - %v", r.Method, r.URL.Path, time.Since(start))
+		logger.Infof("API Response: %s %s - %v", r.Method, r.URL.Path, time.Since(start))
 	})
 }
 
-// wrapHandler adds CORS and logging to handlers
+// maxRequestIDLength bounds the X-Request-ID a client may choose
+const maxRequestIDLength = 128
+
+// withRequestID tags a request with the client's X-Request-ID, or a new one,
+// so the records logged for it can be found; the ID is echoed in the response
+func withRequestID(w http.ResponseWriter, r *http.Request) *http.Request {
+	id := r.Header.Get("X-Request-ID")
+	if id == "" || len(id) > maxRequestIDLength {
+		buf := make([]byte, 8)
+		rand.Read(buf)
+		id = hex.EncodeToString(buf)
+	}
+	w.Header().Set("X-Request-ID", id)
+	return r.WithContext(logging.ContextWithRequestID(r.Context(), id))
+}
+
+// wrapHandler adds CORS, logging, metrics, and the request body limits to
+// handlers
 func (s *Server) wrapHandler(handler http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// CORS headers
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key")
+		if s.metrics != nil {
+			recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			start := time.Now()
+			defer func() { s.metrics.observeRequest(r.Pattern, recorder.status, time.Since(start)) }()
+			w = recorder
+		}
 
+		s.setCORSHeaders(w, r)
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
 			return
 		}
 
 		// Log request
+		r = withRequestID(w, r)
+		logger := s.logger.WithContext(r.Context())
 		start := time.Now()
-		log.Printf("API Request: %s %s", r.Method, r.URL.Path)
+		logger.Infof("API Request: %s %s", r.Method, r.URL.Path)
 
 		// Call handler
-		handler(w, r)
+		if s.limitRequest(w, r) {
+			handler(w, r)
+		}
+
+		logger.Infof("API Response: %s %s - %v", r.Method, r.URL.Path, time.Since(start))
+	}
+}
 
-		log.Printf("API Response: %s %s - %v", r.Method, r.URL.Path, time.Since(start))
+// SetAllowedOrigins sets the browser origins, such as
+// "https://app.example.com", that may call the API across origins. "*"
+// allows any origin. With none, only same-origin pages may.
+func (s *Server) SetAllowedOrigins(origins []string) {
+	s.allowedOrigins = make(map[string]bool, len(origins))
+	for _, origin := range origins {
+		s.allowedOrigins[strings.TrimSuffix(origin, "/")] = true
 	}
 }
 
+// originAllowed reports whether origin was configured as allowed
+func (s *Server) originAllowed(origin string) bool {
+	return s.allowedOrigins["*"] || s.allowedOrigins[origin]
+}
+
+// setCORSHeaders lets the request's origin read the response when it is
+// allowed
+func (s *Server) setCORSHeaders(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Vary", "Origin")
+	origin := r.Header.Get("Origin")
+	if origin == "" || !s.originAllowed(origin) {
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key, X-Request-ID")
+}
+
+// checkWebSocketOrigin accepts WebSocket upgrades from clients that send no
+// origin, from pages served by this host, and from allowed origins
+func (s *Server) checkWebSocketOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || s.originAllowed(origin) {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
 // wrapHandlers creates a new router with all handlers wrapped
 func (s *Server) wrapHandlers() http.Handler {
 	wrappedRouter := http.NewServeMux()
 
-	// Wrap all handlers
-	wrappedRouter.HandleFunc("/api/v1/status", s.wrapHandler(s.handleStatus))
+	// Wrap all handlers; health and login stay open when auth is enabled, and
+	// health is never rate limited
+	wrappedRouter.HandleFunc("/api/v1/status", s.wrapHandler(s.requireAuth(auth.ScopeAgentsRead, s.rateLimit(s.handleStatus))))
 	wrappedRouter.HandleFunc("/api/v1/health", s.wrapHandler(s.handleHealth))
-	wrappedRouter.HandleFunc("/api/v1/chat", s.wrapHandler(s.handleChat))
-	wrappedRouter.HandleFunc("/api/v1/agents", s.wrapHandler(s.handleListAgents))
-	wrappedRouter.HandleFunc("/api/v1/agents/", s.wrapHandler(s.handleCallAgent))
-	wrappedRouter.HandleFunc("/api/v1/logs", s.wrapHandler(s.handleGetLogs))
-	wrappedRouter.HandleFunc("/api/v1/start", s.wrapHandler(s.handleStart))
-	wrappedRouter.HandleFunc("/api/v1/stop", s.wrapHandler(s.handleStop))
-	wrappedRouter.HandleFunc("/api/v1/events", s.handleWebSocket)
+	wrappedRouter.HandleFunc("/api/v1/auth/login", s.wrapHandler(s.rateLimit(s.handleLogin)))
+	wrappedRouter.HandleFunc("/api/v1/chat", s.wrapHandler(s.requireAuth(auth.ScopeChat, s.rateLimit(s.handleChat))))
+	wrappedRouter.HandleFunc("/api/v1/sessions/", s.wrapHandler(s.requireAuth(auth.ScopeChat, s.rateLimit(s.handleSession))))
+	wrappedRouter.HandleFunc("/api/v1/agents", s.wrapHandler(s.requireAuth(auth.ScopeAgentsRead, s.rateLimit(s.handleListAgents))))
+	wrappedRouter.HandleFunc("/api/v1/agents/", s.wrapHandler(s.requireAuth(auth.ScopeAgentsExecute, s.rateLimit(s.handleCallAgent))))
+	wrappedRouter.HandleFunc("/api/v1/logs", s.wrapHandler(s.requireAuth(auth.ScopeAdmin, s.rateLimit(s.handleGetLogs))))
+	wrappedRouter.HandleFunc("/api/v1/workflows", s.wrapHandler(s.requireAuth(auth.ScopeAgentsRead, s.rateLimit(s.handleListWorkflows))))
+	wrappedRouter.HandleFunc("/api/v1/users", s.wrapHandler(s.requireAuth(auth.ScopeAdmin, s.rateLimit(s.handleListUsers))))
+	wrappedRouter.HandleFunc("/api/v1/users/", s.wrapHandler(s.requireAuth(auth.ScopeAdmin, s.rateLimit(s.handleUser))))
+	wrappedRouter.HandleFunc("/api/v1/start", s.wrapHandler(s.requireAuth(auth.ScopeAdmin, s.rateLimit(s.handleStart))))
+	wrappedRouter.HandleFunc("/api/v1/stop", s.wrapHandler(s.requireAuth(auth.ScopeAdmin, s.rateLimit(s.handleStop))))
+	wrappedRouter.HandleFunc("/api/v1/reload", s.wrapHandler(s.requireAuth(auth.ScopeAdmin, s.rateLimit(s.handleReload))))
+	wrappedRouter.HandleFunc("/api/v1/events", s.requireAuth(auth.ScopeAgentsRead, s.rateLimit(s.handleWebSocket)))
+	if s.metrics != nil {
+		wrappedRouter.HandleFunc("/metrics", s.wrapHandler(s.requireAuth(auth.ScopeAgentsRead, s.rateLimit(s.handleMetrics))))
+	}
 
 	return wrappedRouter
 }
@@ -164,96 +362,54 @@ func (s *Server) Start(ctx context.Context) error {
 
 	addr := fmt.Sprintf("%s:%d", s.host, s.port)
 	server := &http.Server{
-		Addr:    addr,
-		Handler: wrappedRouter,
+		Addr:         addr,
+		Handler:      wrappedRouter,
+		ReadTimeout:  s.readTimeout,
+		WriteTimeout: s.effectiveWriteTimeout(),
+		IdleTimeout:  s.idleTimeout,
+		// Derive request contexts from ctx so agents still running when the
+		// server stops are cancelled and kill any processes they spawned
+		BaseContext: func(net.Listener) context.Context { return ctx },
 	}
+	s.serverMu.Lock()
+	s.httpServer = server
+	s.serverMu.Unlock()
 
-	log.Printf("API Server starting on %s", addr)
+	s.logger.Infof("API Server starting on %s", addr)
 
 	// Start server in goroutine
 	go func() {
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Printf("API Server error: %v", err)
+			s.logger.Errorf("API Server error: %v", err)
 		}
 	}()
 
 	// Handle shutdown
 	<-ctx.Done()
-	log.Println("Shutting down API Server...")
-
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	return server.Shutdown(shutdownCtx)
+	return s.Shutdown()
 }
 
-// BroadcastWebSocket sends a message to all connected WebSocket clients
-func (s *Server) BroadcastWebSocket(message interface{}) {
-	s.wsMutex.RLock()
-	defer s.wsMutex.RUnlock()
-
-	data, err := json.Marshal(message)
-	if err != nil {
-		log.Printf("Failed to marshal WebSocket message: %v", err)
-		return
-	}
-
-	for client := range s.wsClients {
-		if err := client.WriteMessage(websocket.TextMessage, data); err != nil {
-			log.Printf("WebSocket write error: %v", err)
-			client.Close()
-			delete(s.wsClients, client)
-		}
-	}
-}
+// Shutdown stops the server from accepting new requests and waits up to five
+// seconds for in-flight requests to finish
+func (s *Server) Shutdown() error {
+	s.serverMu.Lock()
+	server := s.httpServer
+	s.serverMu.Unlock()
 
-// handleWebSocket handles WebSocket connections
-func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
-	conn, err := s.wsUpgrader.Upgrade(w, r, nil)
-	if err != nil {
-		log.Printf("WebSocket upgrade error: %v", err)
-		return
+	if server == nil {
+		return nil
 	}
-	defer conn.Close()
 
-	s.wsMutex.Lock()
-	s.wsClients[conn] = true
-	s.wsMutex.Unlock()
+	s.logger.Infof("Shutting down API Server...")
 
-	log.Printf("WebSocket client connected: %s", conn.RemoteAddr())
+	// Hijacked WebSocket connections are not closed by server.Shutdown
+	s.stopFollowingLogs()
+	s.events.closeAll()
 
-	// Send welcome message
-	s.sendToClient(conn, map[string]interface{}{
-		"type":      "welcome",
-		"message":   "Connected to AgentForgeEngine API",
-		"timestamp": time.Now().UTC().Format(time.RFC3339),
-	})
-
-	// Keep connection alive
-	for {
-		_, _, err := conn.ReadMessage()
-		if err != nil {
-			log.Printf("WebSocket client disconnected: %v", err)
-			break
-		}
-	}
-
-	s.wsMutex.Lock()
-	delete(s.wsClients, conn)
-	s.wsMutex.Unlock()
-}
-
-// sendToClient sends a message to a specific WebSocket client
-func (s *Server) sendToClient(conn *websocket.Conn, message interface{}) {
-	data, err := json.Marshal(message)
-	if err != nil {
-		log.Printf("Failed to marshal WebSocket message: %v", err)
-		return
-	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
 
-	if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
-		log.Printf("WebSocket write error: %v", err)
-	}
+	return server.Shutdown(shutdownCtx)
 }
 
 // API Response helpers
@@ -261,6 +417,8 @@ type APIResponse struct {
 	Success bool        `json:"success"`
 	Data    interface{} `json:"data,omitempty"`
 	Error   string      `json:"error,omitempty"`
+	// Code identifies the error for clients, such as a policy denial
+	Code string `json:"code,omitempty"`
 }
 
 func (s *Server) sendJSON(w http.ResponseWriter, status int, response APIResponse) {
@@ -268,7 +426,7 @@ func (s *Server) sendJSON(w http.ResponseWriter, status int, response APIRespons
 	w.WriteHeader(status)
 
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Printf("Failed to encode JSON response: %v", err)
+		s.logger.Errorf("Failed to encode JSON response: %v", err)
 	}
 }
 
@@ -282,54 +440,108 @@ func (s *Server) sendError(w http.ResponseWriter, status int, message string) {
 
 // Chat request/response structures
 type ChatRequest struct {
-	Message   string                 `json:"message"`
-	Model     string                 `json:"model,omitempty"`
-	Options   map[string]interface{} `json:"options,omitempty"`
-	Verbosity int                    `json:"verbosity,omitempty"`
-	Timeout   int                    `json:"timeout,omitempty"`
+	Message string `json:"message"`
+	// SessionID continues a conversation kept by the server; the history is
+	// sent before Messages and Message
+	SessionID string `json:"session_id,omitempty"`
+	// Messages is the conversation so far; Message, when set, is appended
+	// to it as the next user turn
+	Messages  []interfaces.ChatMessage `json:"messages,omitempty"`
+	Model     string                   `json:"model,omitempty"`
+	Options   map[string]interface{}   `json:"options,omitempty"`
+	Verbosity int                      `json:"verbosity,omitempty"`
+	// Timeout bounds the chat in seconds; zero or more than the server's
+	// max request timeout uses that max
+	Timeout int `json:"timeout,omitempty"`
+	// Stream sends the reply to /api/v1/events clients as chat_delta events
+	// while it is generated; requests accepting text/event-stream always
+	// stream
+	Stream bool `json:"stream,omitempty"`
+	// MaxIterations overrides how many times the model may be called while
+	// it keeps asking for tools
+	MaxIterations int `json:"max_iterations,omitempty"`
 }
 
 type ChatResponse struct {
-	Message       string         `json:"message"`
+	ChatID string `json:"chat_id"`
+	// SessionID names the conversation to continue in the next request
+	SessionID string `json:"session_id"`
+	Message   string `json:"message"`
+	// Provider is the model that served the chat, after any fallback
+	Provider string `json:"provider,omitempty"`
+	// Stats reports the time to first token and token rate of streamed chats
+	Stats *interfaces.GenerationStats `json:"stats,omitempty"`
+	// FunctionCalls traces the tool calls of every iteration
 	FunctionCalls []FunctionCall `json:"function_calls,omitempty"`
-	Completed     bool           `json:"completed"`
-	Timestamp     time.Time      `json:"timestamp"`
-	Duration      string         `json:"duration"`
+	// Iterations counts the model calls; StopReason says why they ended
+	Iterations int    `json:"iterations"`
+	StopReason string `json:"stop_reason"`
+	// RetryBudget reports the retries the chat's calls made and had left
+	RetryBudget retry.BudgetReport `json:"retry_budget"`
+	Completed   bool               `json:"completed"`
+	Timestamp   time.Time          `json:"timestamp"`
+	Duration    string             `json:"duration"`
 }
 
 type FunctionCall struct {
 	Name      string                 `json:"name"`
 	Arguments map[string]interface{} `json:"arguments"`
 	Response  *FunctionResponse      `json:"response,omitempty"`
-	Timestamp time.Time              `json:"timestamp"`
-	Duration  string                 `json:"duration"`
+	// Iteration is the model call, counting from one, that made the call
+	Iteration int `json:"iteration,omitempty"`
+	// Cached is set when the response was reused from an earlier identical
+	// call to a read-only, idempotent agent
+	Cached    bool      `json:"cached,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	Duration  string    `json:"duration"`
 }
 
 type FunctionResponse struct {
-	Name        string                 `json:"name"`
-	Data        map[string]interface{} `json:"data"`
-	Success     bool                   `json:"success"`
-	Error       string                 `json:"error,omitempty"`
-	RawResponse string                 `json:"raw_response,omitempty"`
+	Name    string                 `json:"name"`
+	Data    map[string]interface{} `json:"data"`
+	Success bool                   `json:"success"`
+	Error   string                 `json:"error,omitempty"`
+	// Code is the policy's denial code when the call was not allowed, or the
+	// agent output's code
+	Code        string `json:"code,omitempty"`
+	RawResponse string `json:"raw_response,omitempty"`
+	// Warnings and Meta are the agent output's
+	Warnings []string             `json:"warnings,omitempty"`
+	Meta     *interfaces.CallMeta `json:"meta,omitempty"`
 }
 
 // API Handler Methods
 
 // handleStatus returns the current engine status
 func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
-	if s.statusManager == nil {
+	s.componentsMu.RLock()
+	statusManager, modelManager := s.statusManager, s.modelManager
+	s.componentsMu.RUnlock()
+	if statusManager == nil {
 		s.sendError(w, http.StatusInternalServerError, "Status manager not initialized")
 		return
 	}
 
 	// Try to get detailed status via socket
-	statusInfo, err := s.statusManager.GetStatusViaSocket()
+	statusInfo, err := statusManager.GetStatusViaSocket()
 	if err != nil {
 		// Fallback to basic status
-		statusInfo = s.statusManager.GetBasicStatus()
+		statusInfo = statusManager.GetBasicStatus()
 	}
 
-	s.sendSuccess(w, statusInfo)
+	response := statusResponse{StatusInfo: statusInfo}
+	if modelManager != nil {
+		routing := modelManager.RoutingStatus()
+		response.Models = &routing
+	}
+	s.sendSuccess(w, response)
+}
+
+// statusResponse is the engine status with the model routes and the health
+// of each model as seen by recent requests
+type statusResponse struct {
+	*status.StatusInfo
+	Models *models.RoutingStatus `json:"models,omitempty"`
 }
 
 // handleHealth performs a health check
@@ -350,86 +562,224 @@ func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
 
 	// Parse request body
 	var req ChatRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		s.sendError(w, http.StatusBadRequest, "Invalid JSON request body")
+	if !s.decodeJSON(w, r, &req) {
 		return
 	}
 
 	// Validate request
-	if req.Message == "" {
-		s.sendError(w, http.StatusBadRequest, "Message field is required")
+	if req.Message == "" && len(req.Messages) == 0 {
+		s.sendError(w, http.StatusBadRequest, "Message or messages field is required")
+		return
+	}
+	if req.Timeout < 0 {
+		s.sendError(w, http.StatusBadRequest, "timeout must not be negative")
+		return
+	}
+
+	// Sampling options are validated before anything is sent to the model
+	genReq, err := generationRequest(req)
+	if err != nil {
+		s.sendError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	// Use model manager for real model integration
 	startTime := time.Now()
+	chatID := fmt.Sprintf("chat_%d", startTime.UnixNano())
 
 	// Broadcast chat start event
-	s.BroadcastWebSocket(map[string]interface{}{
-		"type":      "chat_start",
+	s.publish(TopicChat, map[string]interface{}{
+		"type":      EventChatStart,
+		"chat_id":   chatID,
 		"message":   req.Message,
 		"model":     req.Model,
 		"timestamp": startTime,
 	})
 
 	// Check if model manager is available
-	if s.modelManager == nil {
-		s.sendError(w, http.StatusInternalServerError, "Model manager not initialized")
+	modelManager := s.modelRouter()
+	if modelManager == nil {
+		s.sendUnavailable(w, "Model manager not initialized")
 		return
 	}
 
-	// Default to llamacpp model, or use request model
-	modelName := req.Model
-	if modelName == "" {
-		modelName = "llamacpp"
+	// Resolve the requested model (or the configured default) before dispatch
+	route, err := modelManager.ResolveRoute(req.Model)
+	if err != nil {
+		s.sendError(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	// Create generation request
-	genReq := interfaces.GenerationRequest{
-		Prompt:      req.Message,
-		MaxTokens:   8000,
-		Temperature: 0.7,
-		Stream:      false,
+	// The session's history goes ahead of the new turn; a second chat on the
+	// same session would save over this one's history, so it is rejected
+	if req.SessionID == "" {
+		req.SessionID = newSessionID()
+	}
+	if !s.lockSession(req.SessionID) {
+		s.sendError(w, http.StatusConflict, fmt.Sprintf("Session %s has a chat in progress", req.SessionID))
+		return
 	}
+	defer s.unlockSession(req.SessionID)
 
-	// Call the model
-	modelResponse, err := s.modelManager.Generate(r.Context(), modelName, genReq)
+	// A session of another principal is not continued, nor saved over
+	owner := sessionOwner(r.Context())
+	if existing, exists, err := s.sessions.Owner(req.SessionID); err != nil {
+		s.sendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to read session: %v", err))
+		return
+	} else if exists && existing != owner {
+		s.sendError(w, http.StatusNotFound, fmt.Sprintf("Session %s not found", req.SessionID))
+		return
+	}
+
+	genReq, err = s.withHistory(req.SessionID, genReq)
 	if err != nil {
-		s.sendError(w, http.StatusInternalServerError, fmt.Sprintf("Model generation failed: %v", err))
+		s.sendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to read session: %v", err))
 		return
 	}
 
-	// Parse function calls from response
-	var functionCalls []FunctionCall
-	if modelResponse.Text != "" && strings.Contains(modelResponse.Text, "<function_call") {
-		calls, err := s.parseFunctionCalls(modelResponse.Text)
-		if err == nil {
-			// Execute function calls with safety check
-			s.executeFunctionCalls(calls)
-			functionCalls = calls
+	// Call the model, running the tools it asks for and feeding their
+	// results back until it answers; the manager falls back along the
+	// alias's route. Every retry on the way shares the chat's budget, and
+	// the whole chat its deadline.
+	timeout := s.requestTimeout(float64(req.Timeout))
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	// A client that accepts server-sent events gets the reply as it is
+	// generated; from here on it hears of errors as events, not statuses
+	var stream *chatStream
+	sendError := func(status int, message string) {
+		if stream != nil {
+			stream.sendError(status, message)
+			return
+		}
+		s.sendError(w, status, message)
+	}
+	if wantsEventStream(r) {
+		req.Stream = true
+		genReq.Stream = true
+		stream = newChatStream(w)
+		ctx = withChatStream(ctx, stream)
+	}
+
+	budget := retry.NewBudget(s.retryBudget)
+	result, err := s.runToolLoop(retry.WithBudget(ctx, budget), chatID, req, genReq)
+	if err != nil {
+		// A failed chat is counted against the first model of its route
+		if timedOut(ctx, err) {
+			s.metrics.observeChat(route[0], chatTimedOut)
+			sendError(http.StatusGatewayTimeout, fmt.Sprintf("Chat did not complete within %v", timeout))
+			return
 		}
+		s.metrics.observeChat(route[0], chatFailed)
+		sendError(http.StatusInternalServerError, fmt.Sprintf("Model generation failed: %v", err))
+		return
+	}
+	modelResponse := result.response
+	s.metrics.observeChat(modelResponse.Provider, chatSucceeded)
+
+	if err := s.sessions.Save(req.SessionID, owner, result.messages); err != nil {
+		sendError(http.StatusInternalServerError, fmt.Sprintf("Failed to save session: %v", err))
+		return
 	}
 
 	// Create response
 	response := ChatResponse{
+		ChatID:        chatID,
+		SessionID:     req.SessionID,
 		Message:       modelResponse.Text,
-		FunctionCalls: functionCalls,
+		Provider:      modelResponse.Provider,
+		Stats:         modelResponse.Stats,
+		FunctionCalls: result.calls,
+		Iterations:    result.iterations,
+		StopReason:    result.stopReason,
+		RetryBudget:   budget.Report(),
 		Completed:     modelResponse.Finished,
 		Timestamp:     time.Now(),
 		Duration:      time.Since(startTime).String(),
 	}
 
 	// Broadcast completion event
-	s.BroadcastWebSocket(map[string]interface{}{
-		"type":      "chat_complete",
+	completeEvent := map[string]interface{}{
+		"type":      EventChatComplete,
+		"chat_id":   chatID,
 		"message":   response.Message,
 		"completed": response.Completed,
 		"timestamp": response.Timestamp,
-	})
+	}
+	if response.Stats != nil {
+		completeEvent["stats"] = response.Stats
+	}
+	s.publish(TopicChat, completeEvent)
 
+	if stream != nil {
+		stream.send(sseEventDone, APIResponse{Success: true, Data: response})
+		return
+	}
 	s.sendSuccess(w, response)
 }
 
+// streamChat generates a reply chunk by chunk, broadcasting each chunk as a
+// chat_delta event as it arrives, and returns the joined response. The last
+// event has done set and carries the token count, finish reason, and stats.
+// When the chat's client asked for server-sent events, each delta is also
+// written to it.
+func (s *Server) streamChat(ctx context.Context, chatID, modelName string, genReq interfaces.GenerationRequest) (*interfaces.GenerationResponse, error) {
+	modelManager := s.modelRouter()
+	if modelManager == nil {
+		return nil, ErrEngineStopped
+	}
+	chunks, err := modelManager.GenerateStream(ctx, modelName, genReq)
+	if err != nil {
+		return nil, err
+	}
+
+	var text strings.Builder
+	response := &interfaces.GenerationResponse{Model: modelName}
+
+	for chunk := range chunks {
+		if chunk.Error != "" {
+			return nil, fmt.Errorf("%s", chunk.Error)
+		}
+		text.WriteString(chunk.Delta)
+
+		event := map[string]interface{}{
+			"type":      EventChatDelta,
+			"chat_id":   chatID,
+			"delta":     chunk.Delta,
+			"done":      chunk.Done,
+			"timestamp": time.Now(),
+		}
+		if chunk.Done {
+			event["tokens"] = chunk.Tokens
+			event["prompt_tokens"] = chunk.PromptTokens
+			event["finish_reason"] = chunk.FinishReason
+			event["provider"] = chunk.Provider
+			event["stats"] = chunk.Stats
+			response.Tokens = chunk.Tokens
+			response.PromptTokens = chunk.PromptTokens
+			response.FinishReason = chunk.FinishReason
+			response.Provider = chunk.Provider
+			response.Stats = chunk.Stats
+			response.Finished = true
+		}
+		s.publish(TopicChat, event)
+		if stream, ok := chatStreamFromContext(ctx); ok && chunk.Delta != "" {
+			stream.send("", map[string]interface{}{"chat_id": chatID, "delta": chunk.Delta})
+		}
+	}
+
+	// The stream closes without a final chunk when the request is cancelled
+	if !response.Finished {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	response.Text = text.String()
+	return response, nil
+}
+
 // parseFunctionCalls parses function calls from model response text
 func (s *Server) parseFunctionCalls(text string) ([]FunctionCall, error) {
 	var calls []FunctionCall
@@ -451,112 +801,380 @@ func (s *Server) parseFunctionCalls(text string) ([]FunctionCall, error) {
 	return calls, nil
 }
 
-// executeFunctionCalls executes parsed function calls via agents
-func (s *Server) executeFunctionCalls(functionCalls []FunctionCall) {
-	if s.pluginManager == nil {
+// executeFunctionCalls executes parsed function calls via agents, recording
+// each agent's output formatted as a function_response for the model.
+// Consecutive calls to read-only agents run in parallel; any other call runs
+// alone, after the calls before it. Successful results of read-only,
+// idempotent calls are stored in cache, when it is not nil, and answer the
+// same calls later until a call that may change state clears it.
+func (s *Server) executeFunctionCalls(ctx context.Context, functionCalls []FunctionCall, cache callCache) {
+	if s.plugins() == nil {
 		return
 	}
 
-	for i := range functionCalls {
-		call := &functionCalls[i]
+	for start := 0; start < len(functionCalls); {
+		readOnly := s.classify(functionCalls[start].Name).ReadOnly
+		end := start + 1
+		for readOnly && end < len(functionCalls) && s.classify(functionCalls[end].Name).ReadOnly {
+			end++
+		}
 
-		// Safety check - only allow safe commands
-		if !s.isSafeCommand(call.Name, call.Arguments) {
-			call.Response = &FunctionResponse{
-				Name:    call.Name,
-				Success: false,
-				Error:   "Command not allowed for safety reasons",
+		var wg sync.WaitGroup
+		for i := start; i < end; i++ {
+			call := &functionCalls[i]
+			if cached, ok := cache[callSignature(*call)]; ok {
+				response := *cached
+				call.Response = &response
+				call.Cached = true
+				call.Duration = time.Duration(0).String()
+				continue
 			}
-			continue
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				s.executeFunctionCall(ctx, call)
+			}()
 		}
+		wg.Wait()
 
-		start := time.Now()
-		agent, exists := s.pluginManager.GetAgent(call.Name)
-		if !exists {
-			call.Response = &FunctionResponse{
-				Name:    call.Name,
-				Success: false,
-				Error:   fmt.Sprintf("Agent %s not found", call.Name),
+		if !readOnly {
+			clear(cache)
+		}
+		for i := start; i < end; i++ {
+			call := &functionCalls[i]
+			if cache != nil && !call.Cached && call.Response.Success && s.classify(call.Name).Cacheable() {
+				cache[callSignature(*call)] = call.Response
 			}
-			call.Duration = time.Since(start).String()
-			continue
 		}
+		start = end
+	}
+
+	for i := range functionCalls {
+		call := &functionCalls[i]
+		call.Response.RawResponse = s.formatFunctionResponse(call.Response)
+	}
+}
+
+// classify returns the classification the named agent declares. Agents that
+// are not loaded are treated as destructive.
+func (s *Server) classify(name string) interfaces.Classification {
+	if plugins := s.plugins(); plugins != nil {
+		if agent, exists := plugins.GetAgent(name); exists {
+			return interfaces.ClassificationOf(agent)
+		}
+	}
+	return interfaces.ClassificationOf(nil)
+}
+
+// executeFunctionCall runs one call, setting its response and duration.
+// Calls to orch. functions go to the orchestrator.
+func (s *Server) executeFunctionCall(ctx context.Context, call *FunctionCall) {
+	if strings.HasPrefix(call.Name, orchestratorPrefix) {
+		s.executeOrchestratorCall(ctx, call)
+		return
+	}
+
+	// The model only gets to run what the policy allows
+	if decision := s.policy.Authorize(policyCaller(ctx, "chat"), call.Name, s.classify(call.Name), call.Arguments); !decision.Allowed {
+		call.Response = &FunctionResponse{
+			Name:    call.Name,
+			Success: false,
+			Code:    decision.Code,
+			Error:   decision.Error(),
+		}
+		return
+	}
 
-		// Execute agent
-		agentInput := interfaces.AgentInput{
-			Type:    "execute",
-			Payload: call.Arguments,
+	start := time.Now()
+	plugins := s.plugins()
+	if plugins == nil {
+		call.Response = &FunctionResponse{Name: call.Name, Success: false, Error: ErrEngineStopped.Error()}
+		return
+	}
+	agent, exists := plugins.GetAgent(call.Name)
+	if !exists {
+		call.Response = &FunctionResponse{
+			Name:    call.Name,
+			Success: false,
+			Error:   fmt.Sprintf("Agent %s not found", call.Name),
 		}
+		call.Duration = time.Since(start).String()
+		return
+	}
 
-		output, err := agent.Process(context.Background(), agentInput)
+	// Execute agent, with the arguments converted to the types it declares
+	agentInput, err := coerceInput(agent, interfaces.AgentInput{
+		Type:    "execute",
+		Payload: call.Arguments,
+	})
+	if err != nil {
+		call.Response = &FunctionResponse{
+			Name:    call.Name,
+			Success: false,
+			Error:   err.Error(),
+		}
 		call.Duration = time.Since(start).String()
+		return
+	}
 
-		if err != nil {
-			call.Response = &FunctionResponse{
-				Name:    call.Name,
-				Success: false,
-				Error:   err.Error(),
-			}
-		} else {
-			call.Response = &FunctionResponse{
-				Name:    call.Name,
-				Success: output.Success,
-				Data:    output.Data,
-				Error:   output.Error,
-			}
+	// Calls made by the agent count towards the recursion limit. Transient
+	// failures, such as a remote agent timing out, are retried within the
+	// chat's retry budget.
+	var output interfaces.AgentOutput
+	err = s.agentRetry.Do(ctx, nil, func(ctx context.Context) error {
+		var callErr error
+		output, callErr = plugins.CallAgent(ctx, call.Name, agentInput)
+		return callErr
+	})
+	call.Duration = time.Since(start).String()
+	s.metrics.observeAgentCall(call.Name, time.Since(start), err == nil && output.Success)
+
+	if err != nil {
+		call.Response = &FunctionResponse{
+			Name:    call.Name,
+			Success: false,
+			Error:   err.Error(),
+		}
+	} else {
+		call.Response = &FunctionResponse{
+			Name:     call.Name,
+			Success:  output.Success,
+			Data:     output.Data,
+			Error:    output.Error,
+			Code:     output.Code,
+			Warnings: output.Warnings,
+			Meta:     output.Meta,
 		}
 	}
 }
 
-// isSafeCommand checks if a command is safe to execute
-func (s *Server) isSafeCommand(agentName string, args map[string]interface{}) bool {
-	// Whitelist of safe commands as requested
-	safeCommands := map[string]bool{
-		"ls":     true,
-		"cat":    true,
-		"pwd":    true,
-		"whoami": true,
-		"df":     true,
-		"uname":  true,
+// formatFunctionResponse renders a call's response the way the model
+// expects to read it back
+func (s *Server) formatFunctionResponse(response *FunctionResponse) string {
+	output := interfaces.AgentOutput{
+		Success: response.Success,
+		Data:    response.Data,
+		Error:   response.Error,
 	}
+	formatted, err := s.formatter.FormatAgentOutput(response.Name, output)
+	if err != nil {
+		return fmt.Sprintf(`<function_response name="%s"><error>%v</error></function_response>`, response.Name, err)
+	}
+	return formatted
+}
 
-	return safeCommands[agentName]
+// SetPolicy sets the policy agent calls from chats and API clients are
+// checked against
+func (s *Server) SetPolicy(engine *policy.Engine) {
+	if engine != nil {
+		s.policy = engine
+	}
 }
 
 // handleListAgents lists available agents
 func (s *Server) handleListAgents(w http.ResponseWriter, r *http.Request) {
-	if s.pluginManager == nil {
-		s.sendError(w, http.StatusInternalServerError, "Plugin manager not initialized")
+	plugins := s.plugins()
+	if plugins == nil {
+		s.sendUnavailable(w, "Plugin manager not initialized")
 		return
 	}
 
-	agents := s.pluginManager.ListAgents()
+	agents := plugins.ListAgents()
 	s.sendSuccess(w, map[string]interface{}{
 		"agents": agents,
 		"count":  len(agents),
 	})
 }
 
-// handleCallAgent calls a specific agent (placeholder for now)
+// AgentCallRequest is the body of POST /api/v1/agents/{name}
+type AgentCallRequest struct {
+	Type     string                 `json:"type"`
+	Payload  map[string]interface{} `json:"payload"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	// TimeoutSeconds overrides the configured agent call timeout, up to the
+	// server's max request timeout
+	TimeoutSeconds float64 `json:"timeout_seconds,omitempty"`
+	// Secrets are credentials passed to the agent as AgentInput.Secrets;
+	// SecretRefs name secrets to read from the secret store, keyed by the
+	// name the agent reads them as
+	Secrets    map[string]string `json:"secrets,omitempty"`
+	SecretRefs map[string]string `json:"secret_refs,omitempty"`
+}
+
+// handleCallAgent runs the agent named in the path with the input in the
+// body and returns its output. A malformed body is a 400, an unknown agent a
+// 404, a call the policy denies or that refers to a secret policy.secrets
+// does not grant to the agent a 403, an agent that reports failure a 422 with
+// its output, an agent that panicked a 500 and a quarantined one a 503, and a
+// call that runs out of time a 504. With dry_run=true the input is checked by agents that
+// implement interfaces.InputValidator and nothing is run.
 func (s *Server) handleCallAgent(w http.ResponseWriter, r *http.Request) {
-	// Placeholder - we'll implement this later
-	s.sendError(w, http.StatusNotImplemented, "Agent call endpoint not yet implemented")
+	if r.Method != "POST" {
+		s.sendError(w, http.StatusMethodNotAllowed, "Only POST method allowed")
+		return
+	}
+
+	plugins := s.plugins()
+	if plugins == nil {
+		s.sendUnavailable(w, "Plugin manager not initialized")
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, "/api/v1/agents/")
+	if name == "" || strings.Contains(name, "/") {
+		s.sendError(w, http.StatusBadRequest, "Path must be /api/v1/agents/{name}")
+		return
+	}
+
+	dryRun := false
+	if value := r.URL.Query().Get("dry_run"); value != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			s.sendError(w, http.StatusBadRequest, fmt.Sprintf("Invalid dry_run value %q", value))
+			return
+		}
+	}
+
+	var req AgentCallRequest
+	if !s.decodeJSON(w, r, &req) {
+		return
+	}
+	if req.Type == "" {
+		s.sendError(w, http.StatusBadRequest, "Type field is required")
+		return
+	}
+	if req.TimeoutSeconds < 0 {
+		s.sendError(w, http.StatusBadRequest, "timeout_seconds must not be negative")
+		return
+	}
+
+	agent, exists := plugins.GetAgent(name)
+	if !exists {
+		s.sendError(w, http.StatusNotFound, fmt.Sprintf("Agent %s not found", name))
+		return
+	}
+
+	input, err := coerceInput(agent, interfaces.AgentInput{
+		Type:     req.Type,
+		Payload:  req.Payload,
+		Metadata: req.Metadata,
+	})
+	if err != nil {
+		s.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	caller := policyCaller(r.Context(), "api")
+	if decision := s.policy.Authorize(caller, name, interfaces.ClassificationOf(agent), input.Payload); !decision.Allowed {
+		s.sendJSON(w, http.StatusForbidden, APIResponse{Success: false, Error: decision.Error(), Code: decision.Code})
+		return
+	}
+
+	// The policy checks and audits the payload; secrets never reach it
+	input.Secrets, err = s.resolveSecrets(caller, name, input.Payload, req.Secrets, req.SecretRefs)
+	var denied *secretDeniedError
+	switch {
+	case errors.As(err, &denied):
+		s.sendJSON(w, http.StatusForbidden, APIResponse{Success: false, Error: denied.Error(), Code: denied.decision.Code})
+		return
+	case err != nil:
+		s.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if dryRun {
+		s.validateAgentInput(w, name, agent, input)
+		return
+	}
+
+	timeout := s.agentTimeout
+	if req.TimeoutSeconds > 0 {
+		timeout = s.requestTimeout(req.TimeoutSeconds)
+	}
+
+	startTime := time.Now()
+	output, err := s.callAgentWithTimeout(r.Context(), plugins, name, input, timeout)
+	s.metrics.observeAgentCall(name, time.Since(startTime), err == nil && output.Success)
+	s.publishAgentCall(name, startTime, output, err)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		s.sendError(w, http.StatusGatewayTimeout, fmt.Sprintf("Agent %s did not respond within %v", name, timeout))
+	case err != nil:
+		s.sendError(w, http.StatusInternalServerError, fmt.Sprintf("Agent %s failed: %v", name, err))
+	case output.Code == loader.CodeInternal:
+		s.sendJSON(w, http.StatusInternalServerError, APIResponse{Success: false, Data: output, Error: output.Error, Code: output.Code})
+	case output.Code == loader.CodeQuarantined:
+		s.sendJSON(w, http.StatusServiceUnavailable, APIResponse{Success: false, Data: output, Error: output.Error, Code: output.Code})
+	case !output.Success:
+		s.sendJSON(w, http.StatusUnprocessableEntity, APIResponse{Success: false, Data: output, Error: output.Error, Code: output.Code})
+	default:
+		s.sendSuccess(w, output)
+	}
 }
 
-// handleGetLogs retrieves system logs (placeholder for now)
-func (s *Server) handleGetLogs(w http.ResponseWriter, r *http.Request) {
-	// Placeholder - we'll implement this later
-	s.sendError(w, http.StatusNotImplemented, "Logs endpoint not yet implemented")
+// publishAgentCall tells clients of the agents topic that an agent call
+// through the API finished
+func (s *Server) publishAgentCall(name string, startTime time.Time, output interfaces.AgentOutput, err error) {
+	event := map[string]interface{}{
+		"type":        EventAgentCall,
+		"agent":       name,
+		"success":     err == nil && output.Success,
+		"duration_ms": float64(time.Since(startTime).Microseconds()) / 1000,
+		"timestamp":   time.Now(),
+	}
+	if err != nil {
+		event["error"] = err.Error()
+	} else if output.Error != "" {
+		event["error"] = output.Error
+	}
+	s.publish(TopicAgents, event)
 }
 
-// handleStart starts the engine (placeholder for now)
-func (s *Server) handleStart(w http.ResponseWriter, r *http.Request) {
-	// Placeholder - we'll implement this later
-	s.sendError(w, http.StatusNotImplemented, "Start endpoint not yet implemented")
+// callAgentWithTimeout runs the agent with a deadline and stops waiting for
+// it when the deadline passes, even if the agent ignores its context
+func (s *Server) callAgentWithTimeout(ctx context.Context, plugins agentRegistry, name string, input interfaces.AgentInput, timeout time.Duration) (interfaces.AgentOutput, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type result struct {
+		output interfaces.AgentOutput
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		output, err := plugins.CallAgent(ctx, name, input)
+		done <- result{output, err}
+	}()
+
+	select {
+	case res := <-done:
+		if res.err != nil && ctx.Err() != nil {
+			return res.output, ctx.Err()
+		}
+		return res.output, res.err
+	case <-ctx.Done():
+		return interfaces.AgentOutput{}, ctx.Err()
+	}
 }
 
-// handleStop stops the engine (placeholder for now)
-func (s *Server) handleStop(w http.ResponseWriter, r *http.Request) {
-	// Placeholder - we'll implement this later
-	s.sendError(w, http.StatusNotImplemented, "Stop endpoint not yet implemented")
+// validateAgentInput answers a dry run. Agents that cannot check their input
+// accept any well-formed request, which the response reports.
+func (s *Server) validateAgentInput(w http.ResponseWriter, name string, agent interfaces.Agent, input interfaces.AgentInput) {
+	result := map[string]interface{}{
+		"agent":   name,
+		"dry_run": true,
+		"valid":   true,
+	}
+
+	validator, ok := agent.(interfaces.InputValidator)
+	result["validated_by_agent"] = ok
+	if ok {
+		if err := validator.ValidateInput(input); err != nil {
+			result["valid"] = false
+			s.sendJSON(w, http.StatusUnprocessableEntity, APIResponse{Success: false, Data: result, Error: err.Error()})
+			return
+		}
+	}
+
+	s.sendSuccess(w, result)
 }
@@ -0,0 +1,451 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/internal/loader"
+	"github.com/AgentForgeEngine/AgentForgeEngine/internal/policy"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+// fakeAgent answers every call with output after waiting for delay, or
+// until its context is done
+type fakeAgent struct {
+	name   string
+	output interfaces.AgentOutput
+	delay  time.Duration
+	// validate, when set, makes the agent an interfaces.InputValidator
+	validate func(input interfaces.AgentInput) error
+	calls    int
+	input    interfaces.AgentInput
+	// err, when set, is returned with output
+	err error
+}
+
+func (a *fakeAgent) Name() string                                   { return a.name }
+func (a *fakeAgent) Initialize(config map[string]interface{}) error { return nil }
+func (a *fakeAgent) HealthCheck() error                             { return nil }
+func (a *fakeAgent) Shutdown() error                                { return nil }
+
+func (a *fakeAgent) Process(ctx context.Context, input interfaces.AgentInput) (interfaces.AgentOutput, error) {
+	a.calls++
+	a.input = input
+	select {
+	case <-time.After(a.delay):
+		return a.output, a.err
+	case <-ctx.Done():
+		return interfaces.AgentOutput{}, ctx.Err()
+	}
+}
+
+type validatingAgent struct{ *fakeAgent }
+
+func (a validatingAgent) ValidateInput(input interfaces.AgentInput) error {
+	return a.validate(input)
+}
+
+// classifiedAgent declares a classification for the agent it wraps
+type classifiedAgent struct {
+	*fakeAgent
+	class interfaces.Classification
+}
+
+func (a classifiedAgent) Classification() interfaces.Classification { return a.class }
+
+var (
+	readOnlyClass    = interfaces.Classification{ReadOnly: true, Idempotent: true}
+	writesClass      = interfaces.Classification{Idempotent: true}
+	destructiveClass = interfaces.Classification{Destructive: true}
+)
+
+// allowAgents replaces the server's policy with one allowing only the named
+// agents
+func allowAgents(server *Server, names ...string) {
+	server.SetPolicy(policy.New(policy.Config{Default: policy.ModeDeny, AllowedAgents: names}))
+}
+
+// fakeRegistry is an agentRegistry holding fixed agents
+type fakeRegistry map[string]interfaces.Agent
+
+func (r fakeRegistry) GetAgent(name string) (interfaces.Agent, bool) {
+	agent, ok := r[name]
+	return agent, ok
+}
+
+func (r fakeRegistry) ListAgents() []string {
+	var names []string
+	for name := range r {
+		names = append(names, name)
+	}
+	return names
+}
+
+func (r fakeRegistry) CallAgent(ctx context.Context, name string, input interfaces.AgentInput) (interfaces.AgentOutput, error) {
+	return r[name].Process(ctx, input)
+}
+
+func callAgent(t *testing.T, server *Server, path, body string) (int, APIResponse) {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	server.handleCallAgent(recorder, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+
+	var response APIResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Invalid response body %q: %v", recorder.Body.String(), err)
+	}
+	return recorder.Code, response
+}
+
+func TestHandleCallAgent(t *testing.T) {
+	ok := &fakeAgent{name: "ls", output: interfaces.AgentOutput{Success: true, Data: map[string]interface{}{"files": "a b"}}}
+	failing := &fakeAgent{name: "cat", output: interfaces.AgentOutput{Success: false, Error: "file not found"}}
+	slow := &fakeAgent{name: "slow", delay: time.Minute}
+	denied := &fakeAgent{name: "rm"}
+
+	server := NewServer("localhost", 0)
+	server.pluginManager = fakeRegistry{"ls": ok, "cat": failing, "slow": slow, "rm": denied}
+	allowAgents(server, "ls", "cat", "slow")
+
+	tests := []struct {
+		name, path, body string
+		status           int
+		error            string
+	}{
+		{"success", "/api/v1/agents/ls", `{"type": "execute", "payload": {"path": "/tmp"}}`, http.StatusOK, ""},
+		{"agent failure", "/api/v1/agents/cat", `{"type": "execute"}`, http.StatusUnprocessableEntity, "file not found"},
+		{"unknown agent", "/api/v1/agents/missing", `{"type": "execute"}`, http.StatusNotFound, "Agent missing not found"},
+		{"timeout", "/api/v1/agents/slow", `{"type": "execute", "timeout_seconds": 0.05}`, http.StatusGatewayTimeout, "did not respond"},
+		{"denied by policy", "/api/v1/agents/rm", `{"type": "execute"}`, http.StatusForbidden, "denied by policy (agent_denied)"},
+		{"missing name", "/api/v1/agents/", `{"type": "execute"}`, http.StatusBadRequest, "Path must be"},
+		{"nested path", "/api/v1/agents/ls/extra", `{"type": "execute"}`, http.StatusBadRequest, "Path must be"},
+		{"invalid JSON", "/api/v1/agents/ls", `{"type":`, http.StatusBadRequest, "Invalid JSON"},
+		{"missing type", "/api/v1/agents/ls", `{"payload": {}}`, http.StatusBadRequest, "Type field is required"},
+		{"negative timeout", "/api/v1/agents/ls", `{"type": "execute", "timeout_seconds": -1}`, http.StatusBadRequest, "timeout_seconds"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, response := callAgent(t, server, tt.path, tt.body)
+			if status != tt.status {
+				t.Errorf("Expected status %d, got %d (%+v)", tt.status, status, response)
+			}
+			if response.Success != (tt.status == http.StatusOK) {
+				t.Errorf("Expected success %v, got %+v", tt.status == http.StatusOK, response)
+			}
+			if !strings.Contains(response.Error, tt.error) {
+				t.Errorf("Expected error containing %q, got %q", tt.error, response.Error)
+			}
+		})
+	}
+
+	if ok.input.Type != "execute" || ok.input.Payload["path"] != "/tmp" {
+		t.Errorf("Expected the body to be passed as the agent input, got %+v", ok.input)
+	}
+	// The agent's output comes back in the envelope, failed or not
+	_, response := callAgent(t, server, "/api/v1/agents/cat", `{"type": "execute"}`)
+	if data, _ := response.Data.(map[string]interface{}); data["error"] != "file not found" {
+		t.Errorf("Expected the agent output as data, got %+v", response.Data)
+	}
+}
+
+func TestHandleCallAgent_DefaultTimeout(t *testing.T) {
+	slow := &fakeAgent{name: "slow", delay: time.Minute}
+	server := NewServer("localhost", 0)
+	server.pluginManager = fakeRegistry{"slow": slow}
+	allowAgents(server, "slow")
+	server.agentTimeout = 50 * time.Millisecond
+
+	if status, _ := callAgent(t, server, "/api/v1/agents/slow", `{"type": "execute"}`); status != http.StatusGatewayTimeout {
+		t.Errorf("Expected the configured timeout to apply, got status %d", status)
+	}
+}
+
+func TestHandleCallAgent_Envelope(t *testing.T) {
+	manager := loader.NewManager(t.TempDir(), t.TempDir())
+	manager.AddAgentToRegistry("ls", &fakeAgent{
+		name:   "ls",
+		delay:  time.Millisecond,
+		output: interfaces.AgentOutput{Success: true, Warnings: []string{"output truncated"}},
+	})
+	server := NewServer("localhost", 0)
+	server.SetComponents(nil, manager, nil)
+	allowAgents(server, "ls")
+
+	status, response := callAgent(t, server, "/api/v1/agents/ls", `{"type": "execute"}`)
+	if status != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", status, response.Error)
+	}
+
+	data := response.Data.(map[string]interface{})
+	meta, ok := data["meta"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected the output to carry meta, got %v", data)
+	}
+	if meta["agent"] != "ls" || meta["started_at"] == "" {
+		t.Errorf("Expected the agent and start time, got %v", meta)
+	}
+	if duration, _ := meta["duration_ms"].(float64); duration < 1 {
+		t.Errorf("Expected a duration of at least 1ms, got %v", meta["duration_ms"])
+	}
+	if warnings, _ := data["warnings"].([]interface{}); len(warnings) != 1 || warnings[0] != "output truncated" {
+		t.Errorf("Expected the agent's warning, got %v", data["warnings"])
+	}
+}
+
+// panicAgent panics on every call
+type panicAgent struct{ fakeAgent }
+
+func (a *panicAgent) Process(ctx context.Context, input interfaces.AgentInput) (interfaces.AgentOutput, error) {
+	panic("nil map")
+}
+
+func TestHandleCallAgent_Panic(t *testing.T) {
+	manager := loader.NewManager(t.TempDir(), t.TempDir())
+	manager.SetMaxAgentPanics(1)
+	manager.AddAgentToRegistry("broken", &panicAgent{fakeAgent{name: "broken"}})
+	manager.AddAgentToRegistry("ls", &fakeAgent{name: "ls", output: interfaces.AgentOutput{Success: true}})
+	server := NewServer("localhost", 0)
+	server.SetComponents(nil, manager, nil)
+	allowAgents(server, "broken", "ls")
+
+	status, response := callAgent(t, server, "/api/v1/agents/broken", `{"type": "execute"}`)
+	if status != http.StatusInternalServerError || response.Code != loader.CodeInternal || response.Error == "" {
+		t.Fatalf("Expected a 500 with %s, got %d %+v", loader.CodeInternal, status, response)
+	}
+
+	status, response = callAgent(t, server, "/api/v1/agents/broken", `{"type": "execute"}`)
+	if status != http.StatusServiceUnavailable || response.Code != loader.CodeQuarantined {
+		t.Errorf("Expected the agent to be quarantined, got %d %+v", status, response)
+	}
+
+	// The server keeps serving other agents
+	if status, _ := callAgent(t, server, "/api/v1/agents/ls", `{"type": "execute"}`); status != http.StatusOK {
+		t.Errorf("Expected other agents to keep working, got %d", status)
+	}
+}
+
+func TestHandleCallAgent_DryRun(t *testing.T) {
+	plain := &fakeAgent{name: "ls"}
+	checked := validatingAgent{&fakeAgent{name: "cat", validate: func(input interfaces.AgentInput) error {
+		if _, ok := input.Payload["path"]; !ok {
+			return fmt.Errorf("path is required")
+		}
+		return nil
+	}}}
+
+	server := NewServer("localhost", 0)
+	server.pluginManager = fakeRegistry{"ls": plain, "cat": checked}
+	allowAgents(server, "ls", "cat")
+
+	status, response := callAgent(t, server, "/api/v1/agents/cat?dry_run=true", `{"type": "read", "payload": {}}`)
+	if status != http.StatusUnprocessableEntity || response.Error != "path is required" {
+		t.Errorf("Expected the agent to reject the input, got %d %+v", status, response)
+	}
+
+	status, response = callAgent(t, server, "/api/v1/agents/cat?dry_run=true", `{"type": "read", "payload": {"path": "a.txt"}}`)
+	data, _ := response.Data.(map[string]interface{})
+	if status != http.StatusOK || data["valid"] != true || data["validated_by_agent"] != true {
+		t.Errorf("Expected the agent to accept the input, got %d %+v", status, response)
+	}
+
+	status, response = callAgent(t, server, "/api/v1/agents/ls?dry_run=1", `{"type": "execute"}`)
+	data, _ = response.Data.(map[string]interface{})
+	if status != http.StatusOK || data["validated_by_agent"] != false {
+		t.Errorf("Expected an unchecked dry run to pass, got %d %+v", status, response)
+	}
+
+	if plain.calls != 0 || checked.calls != 0 {
+		t.Error("Expected dry runs not to run the agents")
+	}
+
+	if status, _ := callAgent(t, server, "/api/v1/agents/ls?dry_run=maybe", `{"type": "execute"}`); status != http.StatusBadRequest {
+		t.Errorf("Expected an invalid dry_run value to be rejected, got %d", status)
+	}
+}
+
+func TestExecuteFunctionCall_Policy(t *testing.T) {
+	rm := &fakeAgent{name: "rm", output: interfaces.AgentOutput{Success: true}}
+	cat := &fakeAgent{name: "cat", output: interfaces.AgentOutput{Success: true}}
+
+	server := NewServer("localhost", 0)
+	server.pluginManager = fakeRegistry{"rm": rm, "cat": cat}
+	server.SetPolicy(policy.New(policy.Config{
+		Default: policy.ModeDeny,
+		Agents:  map[string]policy.AgentRule{"cat": {PathPrefixes: []string{"/srv/data"}}},
+	}))
+
+	tests := []struct {
+		name string
+		call FunctionCall
+		code string
+	}{
+		{"unlisted agent", FunctionCall{Name: "rm", Arguments: map[string]interface{}{"path": "/srv/data/a"}}, policy.CodeAgentDenied},
+		{"path outside prefix", FunctionCall{Name: "cat", Arguments: map[string]interface{}{"path": "/etc/passwd"}}, policy.CodePathDenied},
+		{"path inside prefix", FunctionCall{Name: "cat", Arguments: map[string]interface{}{"path": "/srv/data/a"}}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server.executeFunctionCall(context.Background(), &tt.call)
+			if tt.call.Response.Code != tt.code || tt.call.Response.Success != (tt.code == "") {
+				t.Errorf("Expected code %q, got %+v", tt.code, tt.call.Response)
+			}
+			if tt.code != "" && !strings.Contains(tt.call.Response.Error, tt.code) {
+				t.Errorf("Expected the error to name %s for the model, got %q", tt.code, tt.call.Response.Error)
+			}
+		})
+	}
+
+	if rm.calls != 0 || cat.calls != 1 {
+		t.Errorf("Expected only the allowed call to run, got rm=%d cat=%d", rm.calls, cat.calls)
+	}
+}
+
+func TestHandleCallAgent_Classification(t *testing.T) {
+	ls := classifiedAgent{&fakeAgent{name: "ls", output: interfaces.AgentOutput{Success: true}}, readOnlyClass}
+	touch := classifiedAgent{&fakeAgent{name: "touch", output: interfaces.AgentOutput{Success: true}}, writesClass}
+	rm := classifiedAgent{&fakeAgent{name: "rm", output: interfaces.AgentOutput{Success: true}}, destructiveClass}
+	undeclared := &fakeAgent{name: "legacy", output: interfaces.AgentOutput{Success: true}}
+
+	server := NewServer("localhost", 0)
+	server.pluginManager = fakeRegistry{"ls": ls, "touch": touch, "rm": rm, "legacy": undeclared}
+
+	// The default policy allows what declares itself read-only
+	for name, want := range map[string]int{"ls": http.StatusOK, "touch": http.StatusForbidden, "rm": http.StatusForbidden, "legacy": http.StatusForbidden} {
+		if status, response := callAgent(t, server, "/api/v1/agents/"+name, `{"type": "execute"}`); status != want {
+			t.Errorf("%s: expected %d by default, got %d: %s", name, want, status, response.Error)
+		}
+	}
+
+	// Allowing everything still needs destructive agents, and those that do
+	// not say, to be allowed by name
+	server.SetPolicy(policy.New(policy.Config{Default: policy.ModeAllow}))
+	tests := []struct {
+		agent  string
+		status int
+		code   string
+	}{
+		{"touch", http.StatusOK, ""},
+		{"rm", http.StatusForbidden, policy.CodeApprovalRequired},
+		{"legacy", http.StatusForbidden, policy.CodeApprovalRequired},
+	}
+	for _, tt := range tests {
+		status, response := callAgent(t, server, "/api/v1/agents/"+tt.agent, `{"type": "execute"}`)
+		if status != tt.status || response.Code != tt.code {
+			t.Errorf("%s: expected %d %q, got %d %+v", tt.agent, tt.status, tt.code, status, response)
+		}
+	}
+
+	if ls.calls != 1 || touch.calls != 1 || rm.calls != 0 || undeclared.calls != 0 {
+		t.Errorf("Expected only the allowed calls to run, got ls=%d touch=%d rm=%d legacy=%d", ls.calls, touch.calls, rm.calls, undeclared.calls)
+	}
+}
+
+// overlapAgent records how many calls to the agents sharing its counters
+// run at once
+type overlapAgent struct {
+	name          string
+	class         interfaces.Classification
+	running, peak *atomic.Int32
+}
+
+func (a overlapAgent) Name() string                                   { return a.name }
+func (a overlapAgent) Initialize(config map[string]interface{}) error { return nil }
+func (a overlapAgent) HealthCheck() error                             { return nil }
+func (a overlapAgent) Shutdown() error                                { return nil }
+func (a overlapAgent) Classification() interfaces.Classification      { return a.class }
+
+func (a overlapAgent) Process(ctx context.Context, input interfaces.AgentInput) (interfaces.AgentOutput, error) {
+	running := a.running.Add(1)
+	defer a.running.Add(-1)
+	for peak := a.peak.Load(); running > peak && !a.peak.CompareAndSwap(peak, running); peak = a.peak.Load() {
+	}
+	time.Sleep(20 * time.Millisecond)
+	return interfaces.AgentOutput{Success: true}, nil
+}
+
+func TestExecuteFunctionCalls_ParallelReadOnly(t *testing.T) {
+	tests := []struct {
+		name  string
+		calls []string
+		peak  int32
+	}{
+		{"read-only calls overlap", []string{"ls", "cat", "ls"}, 3},
+		{"state changes run alone", []string{"ls", "rm", "cat"}, 1},
+		{"read-only calls overlap between state changes", []string{"touch", "ls", "cat", "touch"}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var running, peak atomic.Int32
+			agent := func(name string, class interfaces.Classification) overlapAgent {
+				return overlapAgent{name: name, class: class, running: &running, peak: &peak}
+			}
+			server := NewServer("localhost", 0)
+			server.pluginManager = fakeRegistry{
+				"ls":    agent("ls", readOnlyClass),
+				"cat":   agent("cat", interfaces.Classification{ReadOnly: true}),
+				"touch": agent("touch", writesClass),
+				"rm":    agent("rm", destructiveClass),
+			}
+			allowAgents(server, "ls", "cat", "touch", "rm")
+
+			var calls []FunctionCall
+			for i, name := range tt.calls {
+				calls = append(calls, FunctionCall{Name: name, Arguments: map[string]interface{}{"n": i}})
+			}
+			server.executeFunctionCalls(context.Background(), calls, nil)
+
+			for _, call := range calls {
+				if call.Response == nil || !call.Response.Success || call.Response.RawResponse == "" {
+					t.Errorf("Expected %s to run, got %+v", call.Name, call.Response)
+				}
+			}
+			if got := peak.Load(); got != tt.peak {
+				t.Errorf("Expected at most %d calls at once, got %d", tt.peak, got)
+			}
+		})
+	}
+}
+
+func TestWrapHandler_CORS(t *testing.T) {
+	server := NewServer("localhost", 0)
+	handler := server.wrapHandler(func(w http.ResponseWriter, r *http.Request) {})
+
+	request := func(origin string) http.Header {
+		req := httptest.NewRequest(http.MethodOptions, "/api/v1/status", nil)
+		req.Header.Set("Origin", origin)
+		recorder := httptest.NewRecorder()
+		handler(recorder, req)
+		return recorder.Header()
+	}
+
+	// No origin is allowed by default
+	if got := request("https://evil.example").Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Expected no CORS grant by default, got %q", got)
+	}
+
+	server.SetAllowedOrigins([]string{"https://app.example.com/"})
+	if got := request("https://app.example.com").Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("Expected the allowed origin to be echoed, got %q", got)
+	}
+	if got := request("https://evil.example").Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Expected another origin to get no grant, got %q", got)
+	}
+
+	ws := httptest.NewRequest(http.MethodGet, "http://afe.local/api/v1/events", nil)
+	for origin, want := range map[string]bool{"": true, "http://afe.local": true, "https://app.example.com": true, "https://evil.example": false} {
+		ws.Header.Set("Origin", origin)
+		if got := server.checkWebSocketOrigin(ws); got != want {
+			t.Errorf("WebSocket origin %q: expected %v, got %v", origin, want, got)
+		}
+	}
+}
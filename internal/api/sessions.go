@@ -0,0 +1,322 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/tokenizer"
+)
+
+const (
+	// defaultSessionMaxTokens bounds the history sent with a session's chat
+	// when no limit is configured
+	defaultSessionMaxTokens = 6000
+	// maxMemorySessions bounds the sessions an in-memory store keeps; the
+	// least recently saved is dropped first
+	maxMemorySessions = 1000
+)
+
+// ConversationStore keeps the message history of chat sessions. The API
+// reads a session's history before a chat and saves it, with the new turn,
+// afterwards; it never has two chats of one session in flight.
+type ConversationStore interface {
+	// History returns the messages of a session, oldest first, and whether
+	// the session exists
+	History(id string) ([]interfaces.ChatMessage, bool, error)
+	// Owner returns the identity of the principal a session belongs to,
+	// empty for sessions created without auth, and whether it exists
+	Owner(id string) (string, bool, error)
+	// Save replaces the messages and owner of a session, creating it if
+	// needed
+	Save(id, owner string, messages []interfaces.ChatMessage) error
+	// Delete removes a session and reports whether it existed
+	Delete(id string) (bool, error)
+}
+
+// memoryStore is a ConversationStore that lives as long as the server and
+// keeps at most maxSessions sessions
+type memoryStore struct {
+	mu          sync.RWMutex
+	sessions    map[string]memorySession
+	order       []string // session IDs, least recently saved first
+	maxSessions int
+}
+
+// memorySession is a session kept by memoryStore
+type memorySession struct {
+	owner    string
+	messages []interfaces.ChatMessage
+}
+
+// NewMemoryStore returns an empty in-memory ConversationStore
+func NewMemoryStore() ConversationStore {
+	return &memoryStore{
+		sessions:    make(map[string]memorySession),
+		maxSessions: maxMemorySessions,
+	}
+}
+
+func (m *memoryStore) History(id string) ([]interfaces.ChatMessage, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	session, ok := m.sessions[id]
+	return append([]interfaces.ChatMessage(nil), session.messages...), ok, nil
+}
+
+func (m *memoryStore) Owner(id string) (string, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	session, ok := m.sessions[id]
+	return session.owner, ok, nil
+}
+
+func (m *memoryStore) Save(id, owner string, messages []interfaces.ChatMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.forget(id)
+	m.sessions[id] = memorySession{owner: owner, messages: append([]interfaces.ChatMessage(nil), messages...)}
+	m.order = append(m.order, id)
+
+	for len(m.order) > m.maxSessions {
+		delete(m.sessions, m.order[0])
+		m.order = m.order[1:]
+	}
+	return nil
+}
+
+func (m *memoryStore) Delete(id string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.sessions[id]
+	m.forget(id)
+	return ok, nil
+}
+
+// forget removes a session; the caller holds the lock
+func (m *memoryStore) forget(id string) {
+	delete(m.sessions, id)
+	for i, sessionID := range m.order {
+		if sessionID == id {
+			m.order = append(m.order[:i], m.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// SessionResponse is the history of a chat session
+type SessionResponse struct {
+	SessionID string                   `json:"session_id"`
+	Messages  []interfaces.ChatMessage `json:"messages"`
+	Tokens    int                      `json:"tokens"`
+}
+
+// SetConversationStore replaces the store chat sessions are kept in
+func (s *Server) SetConversationStore(store ConversationStore) {
+	if store != nil {
+		s.sessions = store
+	}
+}
+
+// SetSessionMaxTokens sets how many tokens of a session's history are sent
+// with a chat; older turns are dropped. Values below one restore the default.
+func (s *Server) SetSessionMaxTokens(tokens int) {
+	if tokens < 1 {
+		tokens = defaultSessionMaxTokens
+	}
+	s.sessionMaxTokens = tokens
+}
+
+// newSessionID returns an identifier for a session the client did not name,
+// random so that one client cannot guess another's sessions
+func newSessionID() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return "session_" + hex.EncodeToString(buf)
+}
+
+// sessionOwner returns the identity of the principal that made a request,
+// which owns the sessions it creates; empty when auth is disabled
+func sessionOwner(ctx context.Context) string {
+	if principal, ok := PrincipalFromContext(ctx); ok {
+		return principal.Identity()
+	}
+	return ""
+}
+
+// findSession reports whether a session exists and belongs to the principal
+// that made a request. Other principals' sessions are reported missing, so
+// their IDs cannot be probed.
+func (s *Server) findSession(ctx context.Context, id string) (bool, error) {
+	owner, ok, err := s.sessions.Owner(id)
+	if err != nil || !ok {
+		return false, err
+	}
+	return owner == sessionOwner(ctx), nil
+}
+
+// lockSession marks a session as having a chat in flight. It returns false
+// when one already is, so the caller can reject the request.
+func (s *Server) lockSession(id string) bool {
+	s.sessionMu.Lock()
+	defer s.sessionMu.Unlock()
+	if s.busySessions[id] {
+		return false
+	}
+	s.busySessions[id] = true
+	return true
+}
+
+func (s *Server) unlockSession(id string) {
+	s.sessionMu.Lock()
+	defer s.sessionMu.Unlock()
+	delete(s.busySessions, id)
+}
+
+// withHistory prefixes the conversation of genReq with the session's history
+// and trims the oldest turns to fit the token budget
+func (s *Server) withHistory(sessionID string, genReq interfaces.GenerationRequest) (interfaces.GenerationRequest, error) {
+	history, _, err := s.sessions.History(sessionID)
+	if err != nil {
+		return genReq, err
+	}
+
+	turn := genReq.Messages
+	if len(turn) == 0 {
+		turn = []interfaces.ChatMessage{{Role: "user", Content: genReq.Prompt}}
+	}
+
+	genReq.Messages = trimHistory(append(history, turn...), s.sessionMaxTokens, s.tokenizer)
+	genReq.Prompt = genReq.Messages[len(genReq.Messages)-1].Content
+	return genReq, nil
+}
+
+// trimHistory drops the oldest turns, each a user message and the replies
+// and tool results that follow it, until the messages fit in maxTokens.
+// System messages are kept, as is the latest turn even when it alone is over
+// the budget.
+func trimHistory(messages []interfaces.ChatMessage, maxTokens int, tok tokenizer.Tokenizer) []interfaces.ChatMessage {
+	total := 0
+	for _, msg := range messages {
+		total += tok.CountTokens(msg.Content)
+	}
+
+	for total > maxTokens {
+		// The oldest turn starts at the first user message after the system
+		// messages and ends before the next user message
+		start := -1
+		end := -1
+		for i, msg := range messages {
+			if msg.Role != "user" {
+				continue
+			}
+			if start < 0 {
+				start = i
+			} else {
+				end = i
+				break
+			}
+		}
+		if start < 0 || end < 0 {
+			break
+		}
+
+		kept := make([]interfaces.ChatMessage, 0, len(messages)-(end-start))
+		for i, msg := range messages {
+			if i >= end || msg.Role == "system" || i < start {
+				kept = append(kept, msg)
+				continue
+			}
+			total -= tok.CountTokens(msg.Content)
+		}
+		messages = kept
+	}
+	return messages
+}
+
+// handleSession returns (GET) or clears (DELETE) the history of the session
+// named in the path, /api/v1/sessions/{id}. It also serves the export of a
+// session, /api/v1/sessions/{id}/export, and imports, /api/v1/sessions/import.
+// A session of another principal is not found.
+func (s *Server) handleSession(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/sessions/")
+	if path == "import" {
+		s.handleImportSession(w, r)
+		return
+	}
+
+	id, action, _ := strings.Cut(path, "/")
+	if id == "" {
+		s.sendError(w, http.StatusBadRequest, "Session ID is required")
+		return
+	}
+	switch action {
+	case "":
+	case "export":
+		s.handleExportSession(w, r, id)
+		return
+	default:
+		s.sendError(w, http.StatusNotFound, fmt.Sprintf("Unknown session action %q", action))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if owned, err := s.findSession(r.Context(), id); err != nil {
+			s.sendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to read session: %v", err))
+			return
+		} else if !owned {
+			s.sendError(w, http.StatusNotFound, fmt.Sprintf("Session %s not found", id))
+			return
+		}
+
+		messages, ok, err := s.sessions.History(id)
+		if err != nil {
+			s.sendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to read session: %v", err))
+			return
+		}
+		if !ok {
+			s.sendError(w, http.StatusNotFound, fmt.Sprintf("Session %s not found", id))
+			return
+		}
+
+		tokens := 0
+		for _, msg := range messages {
+			tokens += s.tokenizer.CountTokens(msg.Content)
+		}
+		s.sendSuccess(w, SessionResponse{SessionID: id, Messages: messages, Tokens: tokens})
+	case http.MethodDelete:
+		// Clearing a session mid-chat would be undone when the chat saves
+		if !s.lockSession(id) {
+			s.sendError(w, http.StatusConflict, fmt.Sprintf("Session %s has a chat in progress", id))
+			return
+		}
+		defer s.unlockSession(id)
+
+		if owned, err := s.findSession(r.Context(), id); err != nil {
+			s.sendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to read session: %v", err))
+			return
+		} else if !owned {
+			s.sendError(w, http.StatusNotFound, fmt.Sprintf("Session %s not found", id))
+			return
+		}
+
+		ok, err := s.sessions.Delete(id)
+		if err != nil {
+			s.sendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to delete session: %v", err))
+			return
+		}
+		if !ok {
+			s.sendError(w, http.StatusNotFound, fmt.Sprintf("Session %s not found", id))
+			return
+		}
+		s.sendSuccess(w, map[string]interface{}{"session_id": id, "deleted": true})
+	default:
+		s.sendError(w, http.StatusMethodNotAllowed, "Only GET and DELETE methods allowed")
+	}
+}
@@ -0,0 +1,272 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/auth"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/tokenizer"
+)
+
+func sessionRequest(server *Server, method, id string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	server.handleSession(recorder, httptest.NewRequest(method, "/api/v1/sessions/"+id, nil))
+	return recorder
+}
+
+func TestChat_SessionKeepsHistory(t *testing.T) {
+	provider := &scriptedProvider{replies: []string{"Hello Ada.", "Your name is Ada."}}
+	server := newToolLoopServer(provider, fakeRegistry{})
+
+	first := chat(t, server, `{"message": "My name is Ada"}`)
+	if first.SessionID == "" {
+		t.Fatal("Expected the server to start a session")
+	}
+
+	second := chat(t, server, `{"message": "What is my name?", "session_id": "`+first.SessionID+`"}`)
+	if second.SessionID != first.SessionID {
+		t.Errorf("Expected session %s to continue, got %s", first.SessionID, second.SessionID)
+	}
+
+	sent := provider.requests[1].Messages
+	expected := []interfaces.ChatMessage{
+		{Role: "user", Content: "My name is Ada"},
+		{Role: "assistant", Content: "Hello Ada."},
+		{Role: "user", Content: "What is my name?"},
+	}
+	if len(sent) != len(expected) {
+		t.Fatalf("Expected the history to be sent, got %+v", sent)
+	}
+	for i := range expected {
+		if sent[i] != expected[i] {
+			t.Errorf("Message %d: expected %+v, got %+v", i, expected[i], sent[i])
+		}
+	}
+
+	recorder := sessionRequest(server, http.MethodGet, first.SessionID)
+	var envelope struct {
+		Data SessionResponse `json:"data"`
+	}
+	json.Unmarshal(recorder.Body.Bytes(), &envelope)
+	if recorder.Code != http.StatusOK || len(envelope.Data.Messages) != 4 || envelope.Data.Tokens == 0 {
+		t.Errorf("Expected 4 messages in the session, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	if recorder := sessionRequest(server, http.MethodDelete, first.SessionID); recorder.Code != http.StatusOK {
+		t.Errorf("Expected the session to be deleted, got %d", recorder.Code)
+	}
+	if recorder := sessionRequest(server, http.MethodGet, first.SessionID); recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected a deleted session to be gone, got %d", recorder.Code)
+	}
+}
+
+func TestChat_SessionBusy(t *testing.T) {
+	provider := &scriptedProvider{replies: []string{"Hi."}}
+	server := newToolLoopServer(provider, fakeRegistry{})
+
+	// A chat is in flight on the session
+	server.lockSession("session_1")
+
+	recorder := httptest.NewRecorder()
+	body := strings.NewReader(`{"message": "Hello", "session_id": "session_1"}`)
+	server.handleChat(recorder, httptest.NewRequest(http.MethodPost, "/api/v1/chat", body))
+	if recorder.Code != http.StatusConflict || len(provider.requests) != 0 {
+		t.Errorf("Expected 409 without calling the model, got %d", recorder.Code)
+	}
+	if recorder := sessionRequest(server, http.MethodDelete, "session_1"); recorder.Code != http.StatusConflict {
+		t.Errorf("Expected 409 deleting a busy session, got %d", recorder.Code)
+	}
+
+	server.unlockSession("session_1")
+	if response := chat(t, server, `{"message": "Hello", "session_id": "session_1"}`); response.Message != "Hi." {
+		t.Errorf("Expected the session to be usable again, got %q", response.Message)
+	}
+}
+
+func TestTrimHistory(t *testing.T) {
+	// The heuristic tokenizer counts four characters as a token
+	tok := tokenizer.NewHeuristic()
+	messages := []interfaces.ChatMessage{
+		{Role: "system", Content: "Be brief."},
+		{Role: "user", Content: strings.Repeat("a", 400)},
+		{Role: "assistant", Content: strings.Repeat("b", 400)},
+		{Role: "user", Content: strings.Repeat("c", 40)},
+		{Role: "assistant", Content: strings.Repeat("d", 40)},
+		{Role: "user", Content: "latest"},
+	}
+
+	trimmed := trimHistory(messages, 100, tok)
+	if len(trimmed) != 4 || trimmed[0].Role != "system" || trimmed[1].Content[0] != 'c' {
+		t.Errorf("Expected the oldest turn dropped, got %+v", trimmed)
+	}
+
+	// The latest turn is kept even when it alone is over the budget
+	trimmed = trimHistory(messages, 1, tok)
+	if len(trimmed) != 2 || trimmed[1].Content != "latest" {
+		t.Errorf("Expected only the system message and latest turn, got %+v", trimmed)
+	}
+
+	if trimmed := trimHistory(messages, 10000, tok); len(trimmed) != len(messages) {
+		t.Errorf("Expected nothing dropped under the budget, got %d messages", len(trimmed))
+	}
+}
+
+func TestMemoryStore_DropsLeastRecentlySaved(t *testing.T) {
+	store := NewMemoryStore().(*memoryStore)
+	store.maxSessions = 2
+
+	store.Save("a", "", nil)
+	store.Save("b", "", nil)
+	store.Save("a", "", []interfaces.ChatMessage{{Role: "user", Content: "again"}})
+	store.Save("c", "", nil)
+
+	if _, ok, _ := store.History("b"); ok {
+		t.Error("Expected b, the least recently saved, to be dropped")
+	}
+	if messages, ok, _ := store.History("a"); !ok || len(messages) != 1 {
+		t.Errorf("Expected a to be kept, got %v %v", messages, ok)
+	}
+}
+
+func TestNewSessionID(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		id := newSessionID()
+		if !strings.HasPrefix(id, "session_") || len(id) != len("session_")+32 {
+			t.Fatalf("Expected session_ and 32 hex digits, got %q", id)
+		}
+		if seen[id] {
+			t.Fatalf("Expected unique IDs, got %q twice", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestSession_ExportImportRoundTrip(t *testing.T) {
+	provider := &scriptedProvider{replies: []string{
+		`Listing. <function_call name="ls">{"path": "."}</function_call>`,
+		"There is a README.",
+		"Stored.",
+		"You have a README.",
+	}}
+	ls := &fakeAgent{name: "ls", output: interfaces.AgentOutput{Success: true, Data: map[string]interface{}{"files": []string{"README.md"}}}}
+	server := newToolLoopServer(provider, fakeRegistry{"ls": ls})
+	allowAgents(server, "ls")
+
+	first := chat(t, server, `{"message": "What is here?"}`)
+	chat(t, server, `{"message": "Remember api_key=s3cr3t-value", "session_id": "`+first.SessionID+`"}`)
+	original, _, _ := server.sessions.History(first.SessionID)
+
+	recorder := sessionRequest(server, http.MethodGet, first.SessionID+"/export")
+	var exported struct {
+		Data SessionExport `json:"data"`
+	}
+	json.Unmarshal(recorder.Body.Bytes(), &exported)
+	export := exported.Data
+	if recorder.Code != http.StatusOK || export.Version != sessionExportVersion || len(export.Messages) != len(original) {
+		t.Fatalf("Expected the %d messages of the session, got %d: %s", len(original), recorder.Code, recorder.Body.String())
+	}
+	if !strings.Contains(export.Messages[2].Content, `<function_response name="ls">`) || export.Messages[2].Role != "tool" {
+		t.Errorf("Expected the function result in the export, got %+v", export.Messages[2])
+	}
+	if strings.Contains(recorder.Body.String(), "s3cr3t-value") || export.Redacted != 1 ||
+		!strings.Contains(export.Messages[4].Content, "api_key=[REDACTED]") {
+		t.Errorf("Expected the key to be redacted, got %+v", export.Messages[4])
+	}
+
+	// Import it under a new ID and continue it
+	export.SessionID = "imported"
+	body, _ := json.Marshal(export)
+	recorder = httptest.NewRecorder()
+	server.handleSession(recorder, httptest.NewRequest(http.MethodPost, "/api/v1/sessions/import", strings.NewReader(string(body))))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected the import to succeed, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	chat(t, server, `{"message": "What did you find?", "session_id": "imported"}`)
+	sent := provider.requests[len(provider.requests)-1].Messages
+	if len(sent) != len(original)+1 {
+		t.Fatalf("Expected the imported history to be continued, got %+v", sent)
+	}
+	for i, msg := range export.Messages {
+		if sent[i] != msg {
+			t.Errorf("Message %d: expected %+v, got %+v", i, msg, sent[i])
+		}
+	}
+
+	// Importing over an existing session is refused
+	recorder = httptest.NewRecorder()
+	server.handleSession(recorder, httptest.NewRequest(http.MethodPost, "/api/v1/sessions/import", strings.NewReader(string(body))))
+	if recorder.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for an existing session, got %d", recorder.Code)
+	}
+}
+
+func TestSession_OtherPrincipalsNotFound(t *testing.T) {
+	f := newAuthFixture(t)
+	grace, _ := f.users.CreateUser("Grace", "grace@example.com", "secret", nil)
+	_, graceKey, err := f.users.CreateAPIKey(grace.UID, "test", nil, []string{auth.ScopeChat})
+	if err != nil {
+		t.Fatalf("CreateAPIKey failed: %v", err)
+	}
+	ada := map[string]string{"X-API-Key": f.readKey}
+	other := map[string]string{"X-API-Key": graceKey}
+
+	body := `{"version": 1, "session_id": "ada-notes", "messages": [{"role": "user", "content": "My name is Ada"}]}`
+	if status, response := f.do(http.MethodPost, "/api/v1/sessions/import", body, ada); status != http.StatusOK {
+		t.Fatalf("Expected the import to succeed, got %d: %s", status, response.Error)
+	}
+	if owner, _, _ := f.server.sessions.Owner("ada-notes"); !strings.HasPrefix(owner, "key:") {
+		t.Errorf("Expected the session to belong to Ada's key, got %q", owner)
+	}
+
+	for _, route := range []struct{ method, path string }{
+		{http.MethodGet, "/api/v1/sessions/ada-notes"},
+		{http.MethodGet, "/api/v1/sessions/ada-notes/export"},
+		{http.MethodDelete, "/api/v1/sessions/ada-notes"},
+	} {
+		if status, response := f.do(route.method, route.path, "", other); status != http.StatusNotFound {
+			t.Errorf("Expected 404 for another user's %s %s, got %d: %s", route.method, route.path, status, response.Error)
+		}
+	}
+
+	if status, response := f.do(http.MethodGet, "/api/v1/sessions/ada-notes", "", ada); status != http.StatusOK {
+		t.Errorf("Expected the owner to read the session, got %d: %s", status, response.Error)
+	}
+	if status, response := f.do(http.MethodGet, "/api/v1/sessions/ada-notes/export", "", ada); status != http.StatusOK {
+		t.Errorf("Expected the owner to export the session, got %d: %s", status, response.Error)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+		count    int
+	}{
+		{"use afe_0a1b_2c3d please", "use [REDACTED] please", 1},
+		{"Authorization: Bearer abc.def-123", "Authorization: Bearer [REDACTED]", 1},
+		{`{"password": "hunter2", "token":"xyz"}`, `{"password": "[REDACTED]", "token":"[REDACTED]"}`, 2},
+		{"nothing secret here", "nothing secret here", 0},
+	}
+
+	for _, tt := range tests {
+		got, count := redact(tt.text, defaultExportRedactions)
+		if got != tt.expected || count != tt.count {
+			t.Errorf("redact(%q) = %q, %d; expected %q, %d", tt.text, got, count, tt.expected, tt.count)
+		}
+	}
+
+	server := NewServer("localhost", 0)
+	if err := server.SetExportRedaction(true, []string{"("}); err == nil {
+		t.Error("Expected an invalid pattern to be refused")
+	}
+	server.SetExportRedaction(false, nil)
+	if got, _ := redact("afe_0a1b_2c3d", server.exportRedactions); got != "afe_0a1b_2c3d" {
+		t.Errorf("Expected redaction to be disabled, got %q", got)
+	}
+}
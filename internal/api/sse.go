@@ -0,0 +1,106 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// eventStreamType is the media type of server-sent events
+const eventStreamType = "text/event-stream"
+
+// Events of a streamed chat after its deltas, which are sent unnamed
+const (
+	sseEventDone  = "done"
+	sseEventError = "error"
+)
+
+// wantsEventStream reports whether a request's Accept header asks for
+// server-sent events
+func wantsEventStream(r *http.Request) bool {
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		if mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accepted)); err == nil && mediaType == eventStreamType {
+			return true
+		}
+	}
+	return false
+}
+
+// chatStream writes a chat to its client as server-sent events. Once a write
+// fails, as when the client went away, the rest are dropped.
+type chatStream struct {
+	w          http.ResponseWriter
+	controller *http.ResponseController
+
+	mu     sync.Mutex
+	failed bool
+}
+
+// newChatStream starts a 200 event stream response
+func newChatStream(w http.ResponseWriter) *chatStream {
+	w.Header().Set("Content-Type", eventStreamType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// Proxies such as nginx would otherwise hold the events back
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	stream := &chatStream{w: w, controller: http.NewResponseController(w)}
+	stream.controller.Flush()
+	return stream
+}
+
+// send writes one event as JSON data and flushes it; an empty event name
+// sends a default message event
+func (c *chatStream) send(event string, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		payload, _ = json.Marshal(APIResponse{Success: false, Error: fmt.Sprintf("failed to encode event: %v", err)})
+		event = sseEventError
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.failed {
+		return
+	}
+	var frame strings.Builder
+	if event != "" {
+		fmt.Fprintf(&frame, "event: %s\n", event)
+	}
+	fmt.Fprintf(&frame, "data: %s\n\n", payload)
+	if _, err := c.w.Write([]byte(frame.String())); err != nil {
+		c.failed = true
+		return
+	}
+	if err := c.controller.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		c.failed = true
+	}
+}
+
+// sendError ends the stream with an error event; the status is the one a
+// buffered response would have had
+func (c *chatStream) sendError(status int, message string) {
+	c.send(sseEventError, map[string]interface{}{
+		"success": false,
+		"error":   message,
+		"status":  status,
+	})
+}
+
+// withChatStream attaches the stream a chat's deltas are written to
+func withChatStream(ctx context.Context, stream *chatStream) context.Context {
+	return context.WithValue(ctx, chatStreamKey, stream)
+}
+
+// chatStreamFromContext returns the stream a chat's deltas are written to,
+// if its client asked for one
+func chatStreamFromContext(ctx context.Context) (*chatStream, bool) {
+	stream, ok := ctx.Value(chatStreamKey).(*chatStream)
+	return stream, ok
+}
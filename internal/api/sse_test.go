@@ -0,0 +1,155 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+// streamingProvider streams its scripted replies a word at a time. With a
+// gate it waits after the first word of each reply until the gate opens.
+type streamingProvider struct {
+	*scriptedProvider
+	gate chan struct{}
+	err  error
+}
+
+func (p *streamingProvider) GenerateStream(ctx context.Context, req interfaces.GenerationRequest) (<-chan interfaces.GenerationChunk, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	response, _ := p.Generate(ctx, req)
+
+	chunks := make(chan interfaces.GenerationChunk)
+	go func() {
+		defer close(chunks)
+		for i, word := range strings.SplitAfter(response.Text, " ") {
+			chunks <- interfaces.GenerationChunk{Delta: word}
+			if i == 0 && p.gate != nil {
+				<-p.gate
+			}
+		}
+		chunks <- interfaces.GenerationChunk{Done: true, FinishReason: "stop"}
+	}()
+	return chunks, nil
+}
+
+// sseEvent is one server-sent event
+type sseEvent struct {
+	name string
+	data map[string]interface{}
+}
+
+// readSSEEvent reads the next event of a stream
+func readSSEEvent(t *testing.T, reader *bufio.Reader) sseEvent {
+	t.Helper()
+	var event sseEvent
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read the event stream: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			return event
+		case strings.HasPrefix(line, "event: "):
+			event.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event.data); err != nil {
+				t.Fatalf("Invalid event data %q: %v", line, err)
+			}
+		}
+	}
+}
+
+func TestChat_StreamsServerSentEvents(t *testing.T) {
+	provider := &streamingProvider{
+		scriptedProvider: &scriptedProvider{replies: []string{`<function_call name="ls">{"path": "."}</function_call>`, "The directory is empty."}},
+		gate:             make(chan struct{}),
+	}
+	ls := &fakeAgent{name: "ls", output: interfaces.AgentOutput{Success: true}}
+	server := newToolLoopServer(provider.scriptedProvider, fakeRegistry{"ls": ls})
+	server.modelManager.RegisterProvider("scripted", provider)
+
+	httpServer := httptest.NewServer(server.wrapHandlers())
+	defer httpServer.Close()
+	request, _ := http.NewRequest(http.MethodPost, httpServer.URL+"/api/v1/chat", strings.NewReader(`{"message": "what is here?"}`))
+	request.Header.Set("Accept", "text/event-stream")
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK || response.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %d %q", response.StatusCode, response.Header.Get("Content-Type"))
+	}
+	reader := bufio.NewReader(response.Body)
+
+	// The first word arrives while the model is still generating
+	event := readSSEEvent(t, reader)
+	if event.name != "" || event.data["delta"] != `<function_call ` {
+		t.Fatalf("Expected the first delta, got %+v", event)
+	}
+	close(provider.gate)
+
+	var text strings.Builder
+	text.WriteString(event.data["delta"].(string))
+	for {
+		event = readSSEEvent(t, reader)
+		if event.name != "" {
+			break
+		}
+		text.WriteString(event.data["delta"].(string))
+	}
+	if text.String() != `<function_call name="ls">{"path": "."}</function_call>The directory is empty.` {
+		t.Errorf("Expected the deltas of both model calls, got %q", text.String())
+	}
+
+	if event.name != "done" || event.data["success"] != true {
+		t.Fatalf("Expected the done event, got %+v", event)
+	}
+	data := event.data["data"].(map[string]interface{})
+	calls, _ := data["function_calls"].([]interface{})
+	if data["message"] != "The directory is empty." || data["completed"] != true || data["stop_reason"] != stopComplete || len(calls) != 1 {
+		t.Errorf("Expected the chat's response with its function call, got %+v", data)
+	}
+	if ls.calls != 1 {
+		t.Errorf("Expected ls to run once, got %d", ls.calls)
+	}
+}
+
+func TestChat_StreamErrors(t *testing.T) {
+	provider := &streamingProvider{scriptedProvider: &scriptedProvider{replies: []string{"hi"}}, err: errors.New("model is down")}
+	server := newToolLoopServer(provider.scriptedProvider, fakeRegistry{})
+	server.modelManager.RegisterProvider("scripted", provider)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, "/api/v1/chat", strings.NewReader(body))
+		request.Header.Set("Accept", "application/json, text/event-stream")
+		recorder := httptest.NewRecorder()
+		server.handleChat(recorder, request)
+		return recorder
+	}
+
+	// A request refused before the model is called keeps its status
+	if recorder := post(`{"message": "hi", "model": "missing"}`); recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown model, got %d", recorder.Code)
+	}
+
+	recorder := post(`{"message": "hi", "timeout": 5}`)
+	event := readSSEEvent(t, bufio.NewReader(recorder.Body))
+	if recorder.Code != http.StatusOK || event.name != "error" || event.data["status"] != float64(http.StatusInternalServerError) {
+		t.Fatalf("Expected an error event, got %d and %+v", recorder.Code, event)
+	}
+	if message, _ := event.data["error"].(string); !strings.Contains(message, "model is down") {
+		t.Errorf("Expected the model's error, got %q", message)
+	}
+}
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+// defaultMaxToolIterations bounds the model calls of one chat when no limit
+// is configured
+const defaultMaxToolIterations = 8
+
+// defaultRetryBudget bounds the retries of one chat when no budget is
+// configured
+const defaultRetryBudget = 10
+
+// Reasons the tool-call loop of a chat ends
+const (
+	// stopComplete means the model answered without calling a tool
+	stopComplete = "complete"
+	// stopMaxIterations means the model still called tools at the limit
+	stopMaxIterations = "max_iterations"
+	// stopRepeatedCall means the model repeated a call it had already made
+	stopRepeatedCall = "repeated_call"
+)
+
+// callCache maps the signatures of a chat's read-only, idempotent calls to
+// their responses
+type callCache map[string]*FunctionResponse
+
+// functionCallTag matches a whole function call in model output
+var functionCallTag = regexp.MustCompile(`(?s)<function_call[^>]*>.*?</function_call>`)
+
+// chatResult is the outcome of a chat's tool-call loop
+type chatResult struct {
+	// response is the model's last response
+	response   *interfaces.GenerationResponse
+	calls      []FunctionCall
+	iterations int
+	stopReason string
+	// messages is the conversation ending with the model's reply, with the
+	// calls and function responses of every iteration
+	messages []interfaces.ChatMessage
+}
+
+// SetMaxToolIterations sets how many times a chat may call the model while
+// the model keeps asking for tools. Values below one restore the default.
+func (s *Server) SetMaxToolIterations(iterations int) {
+	if iterations < 1 {
+		iterations = defaultMaxToolIterations
+	}
+	s.maxToolIterations = iterations
+}
+
+// SetRetryBudget sets how many retries the agent and model calls of one chat
+// may make in total, so a chat whose calls all fail gives up instead of
+// retrying each call in turn. Zero disables retries; negative values restore
+// the default.
+func (s *Server) SetRetryBudget(retries int) {
+	if retries < 0 {
+		retries = defaultRetryBudget
+	}
+	s.retryBudget = retries
+}
+
+// runToolLoop calls the model, runs the function calls in its response, and
+// calls it again with the conversation extended by its response and the
+// function responses, until it answers without calling a tool. The loop also
+// ends when the model is still calling tools after the maximum number of
+// iterations, or repeats a call it made before with the same arguments; the
+// unexecuted calls are then removed from the reply. Repeated calls to
+// read-only, idempotent agents do not end the loop: they are answered from
+// the earlier result, or run again once a call that may change state has
+// run. Each executed call is broadcast as a chat_tool_call event.
+func (s *Server) runToolLoop(ctx context.Context, chatID string, req ChatRequest, genReq interfaces.GenerationRequest) (*chatResult, error) {
+	maxIterations := s.maxToolIterations
+	if req.MaxIterations > 0 {
+		maxIterations = req.MaxIterations
+	}
+
+	// The loop needs a conversation to extend with the tool results
+	if len(genReq.Messages) == 0 {
+		genReq.Messages = []interfaces.ChatMessage{{Role: "user", Content: genReq.Prompt}}
+	}
+
+	result := &chatResult{}
+	executed := make(map[string]bool)
+	cache := make(callCache)
+	for {
+		result.iterations++
+		response, err := s.generateReply(ctx, chatID, req, genReq)
+		if err != nil {
+			return nil, err
+		}
+		result.response = response
+
+		calls, _ := s.parseFunctionCalls(response.Text)
+		if len(calls) == 0 {
+			result.stopReason = stopComplete
+			result.messages = append(genReq.Messages, interfaces.ChatMessage{Role: "assistant", Content: response.Text})
+			return result, nil
+		}
+		if result.iterations >= maxIterations {
+			result.stopReason = stopMaxIterations
+			break
+		}
+
+		// Running the same call again would give the model the same answer
+		repeated := false
+		for i := range calls {
+			calls[i].Iteration = result.iterations
+			if s.classify(calls[i].Name).Cacheable() {
+				continue
+			}
+			signature := callSignature(calls[i])
+			if executed[signature] {
+				repeated = true
+			}
+			executed[signature] = true
+		}
+		if repeated {
+			result.stopReason = stopRepeatedCall
+			break
+		}
+
+		s.executeFunctionCalls(ctx, calls, cache)
+		genReq.Messages = append(genReq.Messages, interfaces.ChatMessage{Role: "assistant", Content: response.Text})
+		for _, call := range calls {
+			s.publishToolCall(chatID, call)
+			if call.Response != nil {
+				genReq.Messages = append(genReq.Messages, interfaces.ChatMessage{Role: "tool", Content: call.Response.RawResponse})
+			}
+		}
+		result.calls = append(result.calls, calls...)
+
+		// Models that only take a prompt see the latest message
+		genReq.Prompt = genReq.Messages[len(genReq.Messages)-1].Content
+	}
+
+	result.response.Text = strings.TrimSpace(functionCallTag.ReplaceAllString(result.response.Text, ""))
+	result.messages = append(genReq.Messages, interfaces.ChatMessage{Role: "assistant", Content: result.response.Text})
+	return result, nil
+}
+
+// generateReply makes one model call of a chat, streaming it when asked
+func (s *Server) generateReply(ctx context.Context, chatID string, req ChatRequest, genReq interfaces.GenerationRequest) (*interfaces.GenerationResponse, error) {
+	if req.Stream {
+		return s.streamChat(ctx, chatID, req.Model, genReq)
+	}
+	modelManager := s.modelRouter()
+	if modelManager == nil {
+		return nil, ErrEngineStopped
+	}
+	return modelManager.Generate(ctx, req.Model, genReq)
+}
+
+// publishToolCall broadcasts an executed call so clients can show progress
+func (s *Server) publishToolCall(chatID string, call FunctionCall) {
+	event := map[string]interface{}{
+		"type":      EventChatToolCall,
+		"chat_id":   chatID,
+		"iteration": call.Iteration,
+		"name":      call.Name,
+		"duration":  call.Duration,
+		"timestamp": time.Now(),
+	}
+	if call.Cached {
+		event["cached"] = true
+	}
+	if call.Response != nil {
+		event["success"] = call.Response.Success
+		if call.Response.Error != "" {
+			event["error"] = call.Response.Error
+		}
+	}
+	s.publish(TopicChat, event)
+}
+
+// callSignature identifies a call by its name and arguments. Maps marshal
+// with sorted keys, so equal arguments give equal signatures.
+func callSignature(call FunctionCall) string {
+	args, _ := json.Marshal(call.Arguments)
+	return call.Name + ":" + string(args)
+}
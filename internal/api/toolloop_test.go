@@ -0,0 +1,217 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/internal/models"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/retry"
+)
+
+// scriptedProvider answers each request with the next reply of its script,
+// repeating the last one once the script runs out
+type scriptedProvider struct {
+	mu       sync.Mutex
+	replies  []string
+	requests []interfaces.GenerationRequest
+}
+
+func (p *scriptedProvider) Name() string                                   { return "scripted" }
+func (p *scriptedProvider) Initialize(config map[string]interface{}) error { return nil }
+func (p *scriptedProvider) HealthCheck() error                             { return nil }
+func (p *scriptedProvider) Shutdown() error                                { return nil }
+
+func (p *scriptedProvider) Generate(ctx context.Context, req interfaces.GenerationRequest) (*interfaces.GenerationResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	reply := p.replies[len(p.replies)-1]
+	if len(p.requests) < len(p.replies) {
+		reply = p.replies[len(p.requests)]
+	}
+	p.requests = append(p.requests, req)
+	return &interfaces.GenerationResponse{Text: reply, Finished: true, Model: "scripted"}, nil
+}
+
+// newToolLoopServer serves the scripted model and allows every agent given
+func newToolLoopServer(provider *scriptedProvider, agents fakeRegistry) *Server {
+	manager := models.NewManager()
+	manager.RegisterProvider("scripted", provider)
+	manager.SetDefaultModel("scripted")
+
+	server := NewServer("localhost", 0)
+	server.modelManager = manager
+	server.pluginManager = agents
+	allowAgents(server, agents.ListAgents()...)
+	return server
+}
+
+func chat(t *testing.T, server *Server, body string) ChatResponse {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	server.handleChat(recorder, httptest.NewRequest(http.MethodPost, "/api/v1/chat", strings.NewReader(body)))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	var envelope struct {
+		Data ChatResponse `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("Invalid response: %v", err)
+	}
+	return envelope.Data
+}
+
+func TestChat_ToolLoopFeedsResultsBack(t *testing.T) {
+	provider := &scriptedProvider{replies: []string{
+		`Listing first. <function_call name="ls">{"path": "."}</function_call>`,
+		`<function_call name="cat">{"path": "README.md"}</function_call>`,
+		`The README describes the engine.`,
+	}}
+	ls := &fakeAgent{name: "ls", output: interfaces.AgentOutput{Success: true, Data: map[string]interface{}{"files": []string{"README.md"}}}}
+	cat := &fakeAgent{name: "cat", output: interfaces.AgentOutput{Success: true, Data: map[string]interface{}{"content": "# AgentForgeEngine"}}}
+	server := newToolLoopServer(provider, fakeRegistry{"ls": ls, "cat": cat})
+
+	response := chat(t, server, `{"message": "List the directory then read the README"}`)
+
+	if response.Message != "The README describes the engine." || response.StopReason != stopComplete || response.Iterations != 3 {
+		t.Errorf("Expected the final answer after 3 iterations, got %q (%s, %d)", response.Message, response.StopReason, response.Iterations)
+	}
+	if len(response.FunctionCalls) != 2 {
+		t.Fatalf("Expected a trace of 2 calls, got %+v", response.FunctionCalls)
+	}
+	for i, name := range []string{"ls", "cat"} {
+		call := response.FunctionCalls[i]
+		if call.Name != name || call.Iteration != i+1 || call.Response == nil || !call.Response.Success {
+			t.Errorf("Call %d: expected a successful %s in iteration %d, got %+v", i, name, i+1, call)
+		}
+	}
+
+	// The second model call sees the first reply and the ls result
+	second := provider.requests[1].Messages
+	if len(second) != 3 || second[1].Role != "assistant" || second[2].Role != "tool" {
+		t.Fatalf("Expected user, assistant, and tool messages, got %+v", second)
+	}
+	if !strings.Contains(second[2].Content, `<function_response name="ls">`) || !strings.Contains(second[2].Content, "README.md") {
+		t.Errorf("Expected the ls function_response, got %q", second[2].Content)
+	}
+	if third := provider.requests[2].Messages; len(third) != 5 {
+		t.Errorf("Expected the conversation to keep growing, got %d messages", len(third))
+	}
+}
+
+func TestChat_ToolLoopStopsOnRepeatedCall(t *testing.T) {
+	provider := &scriptedProvider{replies: []string{
+		`Checking. <function_call name="ls">{"path": "."}</function_call>`,
+	}}
+	ls := &fakeAgent{name: "ls", output: interfaces.AgentOutput{Success: true}}
+	server := newToolLoopServer(provider, fakeRegistry{"ls": ls})
+
+	response := chat(t, server, `{"message": "What is here?"}`)
+
+	if response.StopReason != stopRepeatedCall || response.Iterations != 2 {
+		t.Errorf("Expected the repeat to stop the loop at iteration 2, got %s after %d", response.StopReason, response.Iterations)
+	}
+	if ls.calls != 1 || len(response.FunctionCalls) != 1 {
+		t.Errorf("Expected ls to run once, ran %d times with trace %+v", ls.calls, response.FunctionCalls)
+	}
+	if response.Message != "Checking." {
+		t.Errorf("Expected the unexecuted call to be removed from the reply, got %q", response.Message)
+	}
+}
+
+func TestChat_ToolLoopReusesReadOnlyResults(t *testing.T) {
+	provider := &scriptedProvider{replies: []string{
+		`<function_call name="ls">{"path": "."}</function_call>`,
+		`<function_call name="ls">{"path": "."}</function_call>`,
+		`<function_call name="touch">{"path": "new.txt"}</function_call>`,
+		`<function_call name="ls">{"path": "."}</function_call>`,
+		`<function_call name="touch">{"path": "new.txt"}</function_call>`,
+		`Done.`,
+	}}
+	ls := classifiedAgent{&fakeAgent{name: "ls", output: interfaces.AgentOutput{Success: true}}, readOnlyClass}
+	touch := classifiedAgent{&fakeAgent{name: "touch", output: interfaces.AgentOutput{Success: true}}, writesClass}
+	server := newToolLoopServer(provider, fakeRegistry{"ls": ls, "touch": touch})
+
+	response := chat(t, server, `{"message": "Create new.txt"}`)
+
+	// The second ls is answered from the first; touch may have changed what
+	// ls sees, so the third runs again
+	if ls.calls != 2 || len(response.FunctionCalls) != 4 {
+		t.Fatalf("Expected ls to run twice in 4 calls, ran %d times with trace %+v", ls.calls, response.FunctionCalls)
+	}
+	cached := []bool{false, true, false, false}
+	for i, call := range response.FunctionCalls {
+		if call.Cached != cached[i] || !call.Response.Success {
+			t.Errorf("Call %d (%s): expected cached=%v, got %+v", i, call.Name, cached[i], call)
+		}
+	}
+
+	// Repeating a call that changes state still ends the loop
+	if response.StopReason != stopRepeatedCall || touch.calls != 1 {
+		t.Errorf("Expected the repeated touch to stop the loop after one run, got %s with %d runs", response.StopReason, touch.calls)
+	}
+}
+
+func TestChat_ToolLoopMaxIterations(t *testing.T) {
+	var replies []string
+	for i := 0; i < 10; i++ {
+		replies = append(replies, fmt.Sprintf(`<function_call name="ls">{"path": "dir%d"}</function_call>`, i))
+	}
+	provider := &scriptedProvider{replies: replies}
+	ls := &fakeAgent{name: "ls", output: interfaces.AgentOutput{Success: true}}
+	server := newToolLoopServer(provider, fakeRegistry{"ls": ls})
+
+	response := chat(t, server, `{"message": "Walk everything", "max_iterations": 3}`)
+	if response.StopReason != stopMaxIterations || response.Iterations != 3 || len(provider.requests) != 3 {
+		t.Errorf("Expected 3 model calls, got %d (%s)", len(provider.requests), response.StopReason)
+	}
+	if ls.calls != 2 || response.Message != "" {
+		t.Errorf("Expected 2 executed calls and no raw calls in the reply, got %d and %q", ls.calls, response.Message)
+	}
+
+	// The configured limit applies when the request sets none
+	provider.requests = nil
+	server.SetMaxToolIterations(2)
+	if response := chat(t, server, `{"message": "Walk everything"}`); response.Iterations != 2 {
+		t.Errorf("Expected the configured limit of 2, got %d", response.Iterations)
+	}
+}
+
+func TestChat_CallsShareRetryBudget(t *testing.T) {
+	provider := &scriptedProvider{replies: []string{
+		`<function_call name="ls">{"path": "a"}</function_call>
+<function_call name="ls">{"path": "b"}</function_call>
+<function_call name="ls">{"path": "c"}</function_call>`,
+		`The remote directories are unavailable.`,
+	}}
+	ls := &fakeAgent{name: "ls", err: fmt.Errorf("remote agent: %w", &retry.StatusError{StatusCode: 503})}
+	server := newToolLoopServer(provider, fakeRegistry{"ls": ls})
+	server.agentRetry = retry.Policy{MaxAttempts: 3}
+	server.SetRetryBudget(3)
+
+	response := chat(t, server, `{"message": "List a, b, and c"}`)
+
+	// The first call retries twice, the second once before the budget runs
+	// out, and the third not at all
+	if ls.calls != 3+2+1 {
+		t.Errorf("Expected 6 agent calls, got %d", ls.calls)
+	}
+	if response.RetryBudget != (retry.BudgetReport{Limit: 3, Used: 3, Remaining: 0}) {
+		t.Errorf("Unexpected retry budget %+v", response.RetryBudget)
+	}
+	for i, call := range response.FunctionCalls {
+		exhausted := strings.Contains(call.Response.Error, retry.ErrBudgetExhausted.Error())
+		if call.Response.Success || exhausted != (i > 0) {
+			t.Errorf("call %d: unexpected response %+v", i, call.Response)
+		}
+	}
+}
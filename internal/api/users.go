@@ -0,0 +1,157 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/auth"
+)
+
+// UserAdmin manages the accounts behind the admin user routes;
+// *auth.UserManager implements it
+type UserAdmin interface {
+	ListUsers(opts auth.ListUsersOptions) ([]*auth.User, string, error)
+	ChangePassword(uid, oldPassword, newPassword string) error
+	DeactivateUser(uid string) (*auth.User, error)
+	ReactivateUser(uid string) (*auth.User, error)
+}
+
+// UserInfo is a user as the API reports it, without the password hash
+type UserInfo struct {
+	UID       string     `json:"uid"`
+	Name      string     `json:"name"`
+	Email     string     `json:"email"`
+	Roles     []string   `json:"roles,omitempty"`
+	IsActive  bool       `json:"is_active"`
+	CreatedAt time.Time  `json:"created_at"`
+	LastLogin *time.Time `json:"last_login,omitempty"`
+}
+
+// UserListResponse is a page of users; Next is the after cursor for the next
+// page, empty on the last
+type UserListResponse struct {
+	Users []UserInfo `json:"users"`
+	Next  string     `json:"next,omitempty"`
+}
+
+// ChangePasswordRequest holds a user's current and new passwords
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
+}
+
+func newUserInfo(user *auth.User) UserInfo {
+	return UserInfo{
+		UID:       user.UID,
+		Name:      user.Name,
+		Email:     user.Email,
+		Roles:     user.Roles,
+		IsActive:  user.IsActive,
+		CreatedAt: user.CreatedAt,
+		LastLogin: user.LastLogin,
+	}
+}
+
+// handleListUsers lists users a page at a time, filtered by the limit, after,
+// and active query parameters
+func (s *Server) handleListUsers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.sendError(w, http.StatusMethodNotAllowed, "Only GET method allowed")
+		return
+	}
+	if s.users == nil {
+		s.sendError(w, http.StatusNotFound, "Authentication is not enabled")
+		return
+	}
+
+	query := r.URL.Query()
+	opts := auth.ListUsersOptions{After: query.Get("after")}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			s.sendError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		opts.Limit = n
+	}
+	if active := query.Get("active"); active != "" {
+		value, err := strconv.ParseBool(active)
+		if err != nil {
+			s.sendError(w, http.StatusBadRequest, "active must be true or false")
+			return
+		}
+		opts.Active = &value
+	}
+
+	users, next, err := s.users.ListUsers(opts)
+	if err != nil {
+		s.sendError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to list users: %v", err))
+		return
+	}
+
+	response := UserListResponse{Users: make([]UserInfo, 0, len(users)), Next: next}
+	for _, user := range users {
+		response.Users = append(response.Users, newUserInfo(user))
+	}
+	s.sendSuccess(w, response)
+}
+
+// handleUser serves the actions on one user: POST
+// /api/v1/users/{uid}/password, /deactivate, and /reactivate
+func (s *Server) handleUser(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.sendError(w, http.StatusMethodNotAllowed, "Only POST method allowed")
+		return
+	}
+	if s.users == nil {
+		s.sendError(w, http.StatusNotFound, "Authentication is not enabled")
+		return
+	}
+
+	uid, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/v1/users/"), "/")
+	if uid == "" {
+		s.sendError(w, http.StatusBadRequest, "User ID is required")
+		return
+	}
+
+	var (
+		user *auth.User
+		err  error
+	)
+	switch action {
+	case "password":
+		var req ChangePasswordRequest
+		if !s.decodeJSON(w, r, &req) {
+			return
+		}
+		if req.OldPassword == "" || req.NewPassword == "" {
+			s.sendError(w, http.StatusBadRequest, "old_password and new_password are required")
+			return
+		}
+		err = s.users.ChangePassword(uid, req.OldPassword, req.NewPassword)
+	case "deactivate":
+		user, err = s.users.DeactivateUser(uid)
+	case "reactivate":
+		user, err = s.users.ReactivateUser(uid)
+	default:
+		s.sendError(w, http.StatusNotFound, fmt.Sprintf("Unknown user action %q", action))
+		return
+	}
+
+	switch {
+	case errors.Is(err, auth.ErrUserNotFound):
+		s.sendError(w, http.StatusNotFound, fmt.Sprintf("User %s not found", uid))
+	case errors.Is(err, auth.ErrWrongPassword):
+		s.sendError(w, http.StatusForbidden, "Current password is incorrect")
+	case err != nil:
+		s.sendError(w, http.StatusInternalServerError, err.Error())
+	case user != nil:
+		s.sendSuccess(w, newUserInfo(user))
+	default:
+		s.sendSuccess(w, map[string]string{"uid": uid, "status": "password changed"})
+	}
+}
@@ -0,0 +1,260 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+// Func is a single benchmarked operation. A returned error is counted as a
+// failed iteration but does not stop the run.
+type Func func(ctx context.Context) error
+
+// Options controls how a benchmark is run
+type Options struct {
+	Iterations  int // total measured iterations
+	Concurrency int // number of goroutines invoking Func
+	Warmup      int // unmeasured iterations run before the benchmark
+}
+
+// Result summarizes a benchmark run
+type Result struct {
+	Iterations  int           `json:"iterations"`
+	Errors      int           `json:"errors"`
+	Concurrency int           `json:"concurrency"`
+	Elapsed     time.Duration `json:"elapsed"`
+	Throughput  float64       `json:"throughput"` // operations per second
+	Min         time.Duration `json:"min"`
+	Mean        time.Duration `json:"mean"`
+	P50         time.Duration `json:"p50"`
+	P90         time.Duration `json:"p90"`
+	P99         time.Duration `json:"p99"`
+	Max         time.Duration `json:"max"`
+	AllocsPerOp uint64        `json:"allocs_per_op"`
+	BytesPerOp  uint64        `json:"bytes_per_op"`
+	LastError   string        `json:"last_error,omitempty"`
+}
+
+// Run invokes fn opts.Iterations times across opts.Concurrency goroutines and
+// reports throughput, latency percentiles, and allocations. Allocations are
+// process-wide, so they include any background work running at the same time.
+func Run(ctx context.Context, opts Options, fn Func) (*Result, error) {
+	if opts.Iterations <= 0 {
+		return nil, fmt.Errorf("iterations must be positive, got %d", opts.Iterations)
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+	if opts.Concurrency > opts.Iterations {
+		opts.Concurrency = opts.Iterations
+	}
+
+	for i := 0; i < opts.Warmup; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		fn(ctx)
+	}
+
+	latencies := make([]time.Duration, opts.Iterations)
+	var next int64 = -1
+	var errorCount int64
+	var lastError atomic.Value
+	var wg sync.WaitGroup
+
+	runtime.GC()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+
+	for w := 0; w < opts.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := atomic.AddInt64(&next, 1)
+				if i >= int64(opts.Iterations) || ctx.Err() != nil {
+					return
+				}
+
+				opStart := time.Now()
+				err := fn(ctx)
+				latencies[i] = time.Since(opStart)
+
+				if err != nil {
+					atomic.AddInt64(&errorCount, 1)
+					lastError.Store(err.Error())
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		Iterations:  opts.Iterations,
+		Errors:      int(errorCount),
+		Concurrency: opts.Concurrency,
+		Elapsed:     elapsed,
+		AllocsPerOp: (after.Mallocs - before.Mallocs) / uint64(opts.Iterations),
+		BytesPerOp:  (after.TotalAlloc - before.TotalAlloc) / uint64(opts.Iterations),
+	}
+	if elapsed > 0 {
+		result.Throughput = float64(opts.Iterations) / elapsed.Seconds()
+	}
+	if msg, ok := lastError.Load().(string); ok {
+		result.LastError = msg
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	var total time.Duration
+	for _, latency := range latencies {
+		total += latency
+	}
+	result.Min = latencies[0]
+	result.Max = latencies[len(latencies)-1]
+	result.Mean = total / time.Duration(len(latencies))
+	result.P50 = percentile(latencies, 50)
+	result.P90 = percentile(latencies, 90)
+	result.P99 = percentile(latencies, 99)
+
+	return result, nil
+}
+
+// percentile returns the nearest-rank percentile of sorted latencies
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// Print writes a human-readable summary of the result
+func (r *Result) Print(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Iterations:\t%d (%d errors)\n", r.Iterations, r.Errors)
+	fmt.Fprintf(tw, "Concurrency:\t%d\n", r.Concurrency)
+	fmt.Fprintf(tw, "Elapsed:\t%s\n", r.Elapsed.Round(time.Microsecond))
+	fmt.Fprintf(tw, "Throughput:\t%.2f ops/s\n", r.Throughput)
+	fmt.Fprintf(tw, "Latency:\tmin %s, mean %s, max %s\n", r.Min, r.Mean, r.Max)
+	fmt.Fprintf(tw, "Percentiles:\tp50 %s, p90 %s, p99 %s\n", r.P50, r.P90, r.P99)
+	fmt.Fprintf(tw, "Allocations:\t%d allocs/op, %d B/op\n", r.AllocsPerOp, r.BytesPerOp)
+	if r.LastError != "" {
+		fmt.Fprintf(tw, "Last error:\t%s\n", r.LastError)
+	}
+	tw.Flush()
+}
+
+// StartCPUProfile begins writing a pprof CPU profile to path. The returned
+// function stops profiling and closes the file.
+func StartCPUProfile(path string) (func() error, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CPU profile: %w", err)
+	}
+
+	if err := pprof.StartCPUProfile(file); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to start CPU profile: %w", err)
+	}
+
+	return func() error {
+		pprof.StopCPUProfile()
+		return file.Close()
+	}, nil
+}
+
+// WriteHeapProfile writes a pprof heap profile to path
+func WriteHeapProfile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create memory profile: %w", err)
+	}
+	defer file.Close()
+
+	runtime.GC()
+	if err := pprof.WriteHeapProfile(file); err != nil {
+		return fmt.Errorf("failed to write memory profile: %w", err)
+	}
+	return nil
+}
+
+// AgentFunc benchmarks a single agent invocation. An agent reporting failure in
+// its output counts as an error just like a returned error.
+func AgentFunc(agent interfaces.Agent, input interfaces.AgentInput) Func {
+	return func(ctx context.Context) error {
+		output, err := agent.Process(ctx, input)
+		if err != nil {
+			return err
+		}
+		if !output.Success {
+			return fmt.Errorf("agent %s failed: %s", agent.Name(), output.Error)
+		}
+		return nil
+	}
+}
+
+// ProviderFunc benchmarks a single generation request against a provider
+func ProviderFunc(provider interfaces.Provider, req interfaces.GenerationRequest) Func {
+	return func(ctx context.Context) error {
+		resp, err := provider.Generate(ctx, req)
+		if err != nil {
+			return err
+		}
+		if resp != nil && resp.Error != "" {
+			return fmt.Errorf("provider %s failed: %s", provider.Name(), resp.Error)
+		}
+		return nil
+	}
+}
+
+// Loader builds and loads plugins from source. It is satisfied by
+// *loader.Manager.
+type Loader interface {
+	LoadLocalAgent(path, name string) error
+	GetAgent(name string) (interfaces.Agent, bool)
+	LoadLocalProvider(path, name string) error
+	GetProvider(name string) (interfaces.Provider, bool)
+}
+
+// LoadAgent loads the agent plugin at path and returns the agent it exports
+func LoadAgent(l Loader, path, name string) (interfaces.Agent, error) {
+	if err := l.LoadLocalAgent(path, name); err != nil {
+		return nil, fmt.Errorf("failed to load agent %s: %w", name, err)
+	}
+	agent, ok := l.GetAgent(name)
+	if !ok {
+		return nil, fmt.Errorf("plugin %s does not export an Agent", name)
+	}
+	return agent, nil
+}
+
+// LoadProvider loads the provider plugin at path and returns the provider it exports
+func LoadProvider(l Loader, path, name string) (interfaces.Provider, error) {
+	if err := l.LoadLocalProvider(path, name); err != nil {
+		return nil, fmt.Errorf("failed to load provider %s: %w", name, err)
+	}
+	provider, ok := l.GetProvider(name)
+	if !ok {
+		return nil, fmt.Errorf("plugin %s does not export a Provider", name)
+	}
+	return provider, nil
+}
@@ -0,0 +1,165 @@
+package bench
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+func TestRun(t *testing.T) {
+	var calls int64
+	result, err := Run(context.Background(), Options{Iterations: 50, Concurrency: 4, Warmup: 5}, func(ctx context.Context) error {
+		n := atomic.AddInt64(&calls, 1)
+		time.Sleep(time.Millisecond)
+		if n%10 == 0 {
+			return fmt.Errorf("failure %d", n)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if calls != 55 {
+		t.Errorf("Expected 55 calls including warmup, got %d", calls)
+	}
+	if result.Iterations != 50 || result.Concurrency != 4 {
+		t.Errorf("Unexpected result shape: %+v", result)
+	}
+	if result.Errors == 0 || result.LastError == "" {
+		t.Errorf("Expected errors to be counted, got %d (%q)", result.Errors, result.LastError)
+	}
+
+	if result.Throughput <= 0 {
+		t.Errorf("Expected positive throughput, got %f", result.Throughput)
+	}
+	if result.Min < time.Millisecond || result.Min > result.P50 || result.P50 > result.P90 ||
+		result.P90 > result.P99 || result.P99 > result.Max {
+		t.Errorf("Expected ordered latencies of at least 1ms, got %+v", result)
+	}
+
+	var out bytes.Buffer
+	result.Print(&out)
+	if !strings.Contains(out.String(), "ops/s") {
+		t.Errorf("Expected throughput in summary, got %q", out.String())
+	}
+}
+
+func TestRun_InvalidIterations(t *testing.T) {
+	if _, err := Run(context.Background(), Options{}, func(ctx context.Context) error { return nil }); err == nil {
+		t.Error("Expected error for zero iterations")
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	dir := t.TempDir()
+	cpuPath := filepath.Join(dir, "cpu.pprof")
+	memPath := filepath.Join(dir, "mem.pprof")
+
+	stop, err := StartCPUProfile(cpuPath)
+	if err != nil {
+		t.Fatalf("Failed to start CPU profile: %v", err)
+	}
+	Run(context.Background(), Options{Iterations: 10}, func(ctx context.Context) error { return nil })
+	if err := stop(); err != nil {
+		t.Fatalf("Failed to stop CPU profile: %v", err)
+	}
+
+	if err := WriteHeapProfile(memPath); err != nil {
+		t.Fatalf("Failed to write heap profile: %v", err)
+	}
+
+	for _, path := range []string{cpuPath, memPath} {
+		if info, err := os.Stat(path); err != nil || info.Size() == 0 {
+			t.Errorf("Expected non-empty profile at %s", path)
+		}
+	}
+}
+
+type stubAgent struct{ fail bool }
+
+func (a *stubAgent) Name() string                                   { return "stub" }
+func (a *stubAgent) Initialize(config map[string]interface{}) error { return nil }
+func (a *stubAgent) Process(ctx context.Context, input interfaces.AgentInput) (interfaces.AgentOutput, error) {
+	if a.fail {
+		return interfaces.AgentOutput{Success: false, Error: "boom"}, nil
+	}
+	return interfaces.AgentOutput{Success: true, Data: map[string]interface{}{"echo": input.Payload["message"]}}, nil
+}
+func (a *stubAgent) HealthCheck() error { return nil }
+func (a *stubAgent) Shutdown() error    { return nil }
+
+func TestAgentFunc(t *testing.T) {
+	input := interfaces.AgentInput{Type: "echo", Payload: map[string]interface{}{"message": "hi"}}
+
+	result, err := Run(context.Background(), Options{Iterations: 100, Concurrency: 2}, AgentFunc(&stubAgent{}, input))
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if result.Errors != 0 || result.Throughput <= 0 || result.AllocsPerOp == 0 {
+		t.Errorf("Expected error-free run with throughput and allocations, got %+v", result)
+	}
+
+	result, _ = Run(context.Background(), Options{Iterations: 10}, AgentFunc(&stubAgent{fail: true}, input))
+	if result.Errors != 10 || !strings.Contains(result.LastError, "boom") {
+		t.Errorf("Expected agent failures to be counted, got %+v", result)
+	}
+}
+
+type stubLoader struct {
+	agents    map[string]interfaces.Agent
+	providers map[string]interfaces.Provider
+	loadErr   error
+}
+
+func (l *stubLoader) LoadLocalAgent(path, name string) error    { return l.loadErr }
+func (l *stubLoader) LoadLocalProvider(path, name string) error { return l.loadErr }
+
+func (l *stubLoader) GetAgent(name string) (interfaces.Agent, bool) {
+	agent, ok := l.agents[name]
+	return agent, ok
+}
+
+func (l *stubLoader) GetProvider(name string) (interfaces.Provider, bool) {
+	provider, ok := l.providers[name]
+	return provider, ok
+}
+
+func TestLoadAgent(t *testing.T) {
+	l := &stubLoader{agents: map[string]interfaces.Agent{"stub": &stubAgent{}}}
+	if agent, err := LoadAgent(l, "./agents/stub", "stub"); err != nil || agent == nil {
+		t.Fatalf("Expected stub agent, got %v (%v)", agent, err)
+	}
+
+	// A plugin that loads but exports no Agent must be an error, not a nil agent
+	_, err := LoadAgent(l, "./agents/missing", "missing")
+	if err == nil || !strings.Contains(err.Error(), "does not export an Agent") {
+		t.Errorf("Expected missing export error, got %v", err)
+	}
+
+	l.loadErr = fmt.Errorf("build failed")
+	if _, err := LoadAgent(l, "./agents/stub", "stub"); err == nil || !strings.Contains(err.Error(), "build failed") {
+		t.Errorf("Expected load error, got %v", err)
+	}
+}
+
+func TestLoadProvider(t *testing.T) {
+	l := &stubLoader{}
+	_, err := LoadProvider(l, "./providers/missing", "missing")
+	if err == nil || !strings.Contains(err.Error(), "does not export a Provider") {
+		t.Errorf("Expected missing export error, got %v", err)
+	}
+
+	l.loadErr = fmt.Errorf("build failed")
+	if _, err := LoadProvider(l, "./providers/missing", "missing"); err == nil || !strings.Contains(err.Error(), "build failed") {
+		t.Errorf("Expected load error, got %v", err)
+	}
+}
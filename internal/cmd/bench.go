@@ -0,0 +1,216 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/internal/bench"
+	"github.com/AgentForgeEngine/AgentForgeEngine/internal/config"
+	"github.com/AgentForgeEngine/AgentForgeEngine/internal/loader"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/userdirs"
+	"github.com/spf13/cobra"
+)
+
+// benchCmd represents the bench command group
+var benchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Benchmark and profile agents and providers",
+	Long: `Repeatedly invoke an agent or provider and report throughput,
+latency percentiles, and allocations, optionally writing pprof CPU and
+memory profiles.`,
+}
+
+// benchAgentCmd represents the 'afe bench agent' command
+var benchAgentCmd = &cobra.Command{
+	Use:   "agent <name>",
+	Short: "Benchmark an agent",
+	Long: `Build and load an agent, then invoke it repeatedly with the given payload.
+The agent is initialized with its config from afe.yaml when it is listed there.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runBenchAgent,
+}
+
+// benchProviderCmd represents the 'afe bench provider' command
+var benchProviderCmd = &cobra.Command{
+	Use:   "provider <name>",
+	Short: "Benchmark a provider",
+	Long:  `Build and load a provider, then send it repeated generation requests.`,
+	Args:  cobra.ExactArgs(1),
+	RunE:  runBenchProvider,
+}
+
+var (
+	benchIterations     int
+	benchConcurrency    int
+	benchWarmup         int
+	benchCPUProfile     string
+	benchMemProfile     string
+	benchPath           string
+	benchInputType      string
+	benchPayload        string
+	benchPrompt         string
+	benchMaxTokens      int
+	benchProviderConfig string
+	benchJSON           bool
+)
+
+func init() {
+	rootCmd.AddCommand(benchCmd)
+	benchCmd.AddCommand(benchAgentCmd)
+	benchCmd.AddCommand(benchProviderCmd)
+
+	benchCmd.PersistentFlags().IntVarP(&benchIterations, "iterations", "n", 100, "Number of measured invocations")
+	benchCmd.PersistentFlags().IntVarP(&benchConcurrency, "concurrency", "c", 1, "Number of concurrent invocations")
+	benchCmd.PersistentFlags().IntVar(&benchWarmup, "warmup", 5, "Unmeasured invocations before the benchmark")
+	benchCmd.PersistentFlags().StringVar(&benchCPUProfile, "cpuprofile", "", "Write a pprof CPU profile to this file")
+	benchCmd.PersistentFlags().StringVar(&benchMemProfile, "memprofile", "", "Write a pprof heap profile to this file")
+	benchCmd.PersistentFlags().StringVar(&benchPath, "path", "", "Plugin source directory (default ./agents/<name> or ./providers/<name>)")
+	benchCmd.PersistentFlags().BoolVar(&benchJSON, "json", false, "Print the result as JSON")
+
+	benchAgentCmd.Flags().StringVar(&benchInputType, "type", "", "Agent input type")
+	benchAgentCmd.Flags().StringVar(&benchPayload, "payload", "{}", "Agent payload as a JSON object")
+
+	benchProviderCmd.Flags().StringVar(&benchPrompt, "prompt", "Hello", "Prompt sent with each generation request")
+	benchProviderCmd.Flags().IntVar(&benchMaxTokens, "max-tokens", 64, "Maximum tokens per generation")
+	benchProviderCmd.Flags().StringVar(&benchProviderConfig, "provider-config", "{}", "Provider config as a JSON object")
+}
+
+// runBenchAgent handles the 'afe bench agent' command
+func runBenchAgent(cmd *cobra.Command, args []string) error {
+	name := args[0]
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal([]byte(benchPayload), &payload); err != nil {
+		return fmt.Errorf("invalid payload: %w", err)
+	}
+
+	path, agentConfig := resolveBenchAgent(name)
+	manager, err := newBenchLoader()
+	if err != nil {
+		return err
+	}
+
+	agent, err := bench.LoadAgent(manager, path, name)
+	if err != nil {
+		return err
+	}
+	defer agent.Shutdown()
+
+	if err := agent.Initialize(agentConfig); err != nil {
+		return fmt.Errorf("failed to initialize agent %s: %w", name, err)
+	}
+
+	input := interfaces.AgentInput{Type: benchInputType, Payload: payload}
+	return runBenchmark(cmd.Context(), fmt.Sprintf("agent %s", name), bench.AgentFunc(agent, input))
+}
+
+// runBenchProvider handles the 'afe bench provider' command
+func runBenchProvider(cmd *cobra.Command, args []string) error {
+	name := args[0]
+
+	var providerConfig map[string]interface{}
+	if err := json.Unmarshal([]byte(benchProviderConfig), &providerConfig); err != nil {
+		return fmt.Errorf("invalid provider config: %w", err)
+	}
+
+	path := benchPath
+	if path == "" {
+		path = "./providers/" + name
+	}
+
+	manager, err := newBenchLoader()
+	if err != nil {
+		return err
+	}
+
+	provider, err := bench.LoadProvider(manager, path, name)
+	if err != nil {
+		return err
+	}
+	defer provider.Shutdown()
+
+	if err := provider.Initialize(providerConfig); err != nil {
+		return fmt.Errorf("failed to initialize provider %s: %w", name, err)
+	}
+
+	req := interfaces.GenerationRequest{Prompt: benchPrompt, MaxTokens: benchMaxTokens}
+	return runBenchmark(cmd.Context(), fmt.Sprintf("provider %s", name), bench.ProviderFunc(provider, req))
+}
+
+// runBenchmark runs fn with the command's flags, handling profiles and output
+func runBenchmark(ctx context.Context, label string, fn bench.Func) error {
+	if benchCPUProfile != "" {
+		stopProfile, err := bench.StartCPUProfile(benchCPUProfile)
+		if err != nil {
+			return err
+		}
+		defer stopProfile()
+	}
+
+	if !benchJSON {
+		fmt.Printf("Benchmarking %s: %d iterations, concurrency %d\n", label, benchIterations, benchConcurrency)
+	}
+
+	result, err := bench.Run(ctx, bench.Options{
+		Iterations:  benchIterations,
+		Concurrency: benchConcurrency,
+		Warmup:      benchWarmup,
+	}, fn)
+	if err != nil {
+		return fmt.Errorf("benchmark failed: %w", err)
+	}
+
+	if benchMemProfile != "" {
+		if err := bench.WriteHeapProfile(benchMemProfile); err != nil {
+			return err
+		}
+	}
+
+	if benchJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	}
+
+	result.Print(os.Stdout)
+	return nil
+}
+
+// resolveBenchAgent finds the agent's source directory and config, preferring
+// the --path flag, then afe.yaml, then ./agents/<name>
+func resolveBenchAgent(name string) (string, map[string]interface{}) {
+	path := "./agents/" + name
+	var agentConfig map[string]interface{}
+
+	configManager := config.NewManager()
+	if err := configManager.Load(getConfigPath()); err == nil {
+		for _, ac := range configManager.GetAgentConfigs() {
+			if ac.Name == name {
+				if ac.Path != "" {
+					path = ac.Path
+				}
+				agentConfig = ac.Config
+				break
+			}
+		}
+	}
+
+	if benchPath != "" {
+		path = benchPath
+	}
+	if agentConfig == nil {
+		agentConfig = map[string]interface{}{}
+	}
+	return path, agentConfig
+}
+
+func newBenchLoader() (*loader.Manager, error) {
+	userDirs, err := userdirs.NewUserDirectories()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user directories: %w", err)
+	}
+	return loader.NewManager(userDirs.AgentsDir, userDirs.CacheDir), nil
+}
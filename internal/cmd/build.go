@@ -1,7 +1,9 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/exec"
@@ -9,6 +11,8 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"syscall"
+	"text/tabwriter"
 	"time"
 
 	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/cache"
@@ -21,6 +25,7 @@ var (
 	forceBuild     bool
 	cleanBuild     bool
 	verboseBuild   bool
+	planBuild      bool
 	buildName      string
 )
 
@@ -42,10 +47,11 @@ func init() {
 	buildCmd.PersistentFlags().BoolVar(&forceBuild, "force", false, "Force rebuild of all plugins")
 	buildCmd.PersistentFlags().BoolVar(&cleanBuild, "clean", false, "Clean cache and rebuild all plugins")
 	buildCmd.PersistentFlags().BoolVarP(&verboseBuild, "verbose", "v", false, "Verbose build output")
+	buildCmd.PersistentFlags().BoolVar(&planBuild, "plan", false, "Show what would be rebuilt and why without building (dry run)")
 }
 
 // runBuildCommand handles building specific plugin types
-func runBuildCommand(pluginType string, args []string) error {
+func runBuildCommand(ctx context.Context, pluginType string, args []string) error {
 	if verboseBuild {
 		log.SetFlags(log.LstdFlags | log.Lshortfile)
 		if len(args) > 0 {
@@ -115,33 +121,10 @@ func runBuildCommand(pluginType string, args []string) error {
 	}
 
 	// Analyze plugins of the specified type
-	for _, pluginName := range pluginsToBuild {
-		pluginPath := filepath.Join(cwd, pluginType+"s", pluginName)
-		shouldRebuild, reason, err := cacheManager.ShouldRebuild(pluginType, pluginName, pluginPath)
-		if err != nil && verboseBuild {
-			fmt.Printf("⚠️  Error checking %s %s: %v\n", pluginType, pluginName, err)
-			shouldRebuild = true
-		}
-
-		if forceBuild || cleanBuild || shouldRebuild {
-			if pluginType == "provider" {
-				buildPlan.ProvidersToBuild = append(buildPlan.ProvidersToBuild, pluginName)
-			} else {
-				buildPlan.AgentsToBuild = append(buildPlan.AgentsToBuild, pluginName)
-			}
-			if verboseBuild {
-				fmt.Printf("🔨 %s %s: %s\n", strings.Title(pluginType), pluginName, reason)
-			}
-		} else {
-			if pluginType == "provider" {
-				buildPlan.ProvidersCached = append(buildPlan.ProvidersCached, pluginName)
-			} else {
-				buildPlan.AgentsCached = append(buildPlan.AgentsCached, pluginName)
-			}
-			if verboseBuild {
-				fmt.Printf("📦 %s %s: cached (unchanged)\n", strings.Title(pluginType), pluginName)
-			}
-		}
+	plans := planPlugins(cacheManager, pluginType, cwd, pluginsToBuild, buildPlan)
+	if planBuild {
+		printBuildPlan(os.Stdout, plans)
+		return nil
 	}
 
 	// Show build summary
@@ -157,7 +140,7 @@ func runBuildCommand(pluginType string, args []string) error {
 
 	// Execute build
 	startTime := time.Now()
-	buildResult, err := executeBuild(buildPlan, cwd, userDirs, cacheManager)
+	buildResult, err := executeBuild(ctx, buildPlan, cwd, userDirs, cacheManager)
 	if err != nil {
 		return fmt.Errorf("build execution failed: %w", err)
 	}
@@ -211,6 +194,84 @@ type BuildPlan struct {
 	AgentsCached     []string
 }
 
+// planPlugins decides which of the named plugins of one type to rebuild,
+// adding each to the build plan, and returns the decisions with their reasons
+func planPlugins(cacheManager *cache.Manager, pluginType, projectDir string, names []string, buildPlan *BuildPlan) []cache.PluginPlan {
+	plans := make([]cache.PluginPlan, 0, len(names))
+	for _, pluginName := range names {
+		plan := cacheManager.PlanPlugin(pluginType, pluginName, filepath.Join(projectDir, pluginType+"s", pluginName))
+		if !plan.Rebuild && (forceBuild || cleanBuild) {
+			plan.Rebuild = true
+			plan.Reason = "rebuild forced"
+		}
+
+		if plan.Rebuild {
+			if pluginType == "provider" {
+				buildPlan.ProvidersToBuild = append(buildPlan.ProvidersToBuild, pluginName)
+			} else {
+				buildPlan.AgentsToBuild = append(buildPlan.AgentsToBuild, pluginName)
+			}
+			if verboseBuild {
+				fmt.Printf("🔨 %s %s: %s\n", strings.Title(pluginType), pluginName, plan.Reason)
+			}
+		} else {
+			if pluginType == "provider" {
+				buildPlan.ProvidersCached = append(buildPlan.ProvidersCached, pluginName)
+			} else {
+				buildPlan.AgentsCached = append(buildPlan.AgentsCached, pluginName)
+			}
+			if verboseBuild {
+				fmt.Printf("📦 %s %s: cached (unchanged)\n", strings.Title(pluginType), pluginName)
+			}
+		}
+
+		plans = append(plans, plan)
+	}
+	return plans
+}
+
+// printBuildPlan writes a table of what a build would do with each plugin,
+// for --plan
+func printBuildPlan(w io.Writer, plans []cache.PluginPlan) {
+	fmt.Fprintln(w, "📋 Build plan (dry run, nothing is built)")
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TYPE\tPLUGIN\tACTION\tREASON\tESTIMATE")
+
+	rebuilds, estimatedMs := 0, 0
+	for _, plan := range plans {
+		action, estimate := "cached", "-"
+		if plan.Rebuild {
+			action = "rebuild"
+			rebuilds++
+			estimatedMs += plan.EstimatedMs
+			switch plan.EstimateFrom {
+			case cache.EstimatePlugin:
+				estimate = formatMs(plan.EstimatedMs)
+			case cache.EstimateAverage:
+				estimate = formatMs(plan.EstimatedMs) + " (all plugins)"
+			default:
+				estimate = "unknown"
+			}
+		}
+
+		reason := plan.Reason
+		if len(plan.ChangedFiles) > 0 {
+			reason += ": " + strings.Join(plan.ChangedFiles, ", ")
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", plan.Type, plan.Name, action, reason, estimate)
+	}
+	tw.Flush()
+
+	fmt.Fprintf(w, "📊 Build Plan: %d to rebuild, %d cached, about %s of build time\n",
+		rebuilds, len(plans)-rebuilds, formatMs(estimatedMs))
+}
+
+// formatMs formats a duration in milliseconds to a tenth of a second
+func formatMs(ms int) string {
+	return (time.Duration(ms) * time.Millisecond).Round(100 * time.Millisecond).String()
+}
+
 // BuildResult contains the results of a build operation
 type BuildResult struct {
 	Success       bool
@@ -255,8 +316,9 @@ func discoverPlugins(pluginType, pluginDir string) ([]string, error) {
 	return plugins, nil
 }
 
-// executeBuild executes the build plan
-func executeBuild(plan *BuildPlan, projectDir string, userDirs *userdirs.UserDirectories, cacheManager *cache.Manager) (*BuildResult, error) {
+// executeBuild executes the build plan. Cancelling ctx kills running builds
+// and skips the plugins that have not started yet.
+func executeBuild(ctx context.Context, plan *BuildPlan, projectDir string, userDirs *userdirs.UserDirectories, cacheManager *cache.Manager) (*BuildResult, error) {
 	result := &BuildResult{
 		BuiltPlugins:  []string{},
 		FailedPlugins: []string{},
@@ -288,7 +350,7 @@ func executeBuild(plan *BuildPlan, projectDir string, userDirs *userdirs.UserDir
 			semaphore <- struct{}{}
 			defer func() { <-semaphore }()
 
-			if err := buildPlugin("provider", pluginName, projectDir, userDirs, cacheManager); err != nil {
+			if err := buildPlugin(ctx, "provider", pluginName, projectDir, userDirs, cacheManager); err != nil {
 				mu.Lock()
 				result.FailedPlugins = append(result.FailedPlugins, pluginName)
 				result.Errors = append(result.Errors, err)
@@ -310,7 +372,7 @@ func executeBuild(plan *BuildPlan, projectDir string, userDirs *userdirs.UserDir
 			semaphore <- struct{}{}
 			defer func() { <-semaphore }()
 
-			if err := buildPlugin("agent", pluginName, projectDir, userDirs, cacheManager); err != nil {
+			if err := buildPlugin(ctx, "agent", pluginName, projectDir, userDirs, cacheManager); err != nil {
 				mu.Lock()
 				result.FailedPlugins = append(result.FailedPlugins, pluginName)
 				result.Errors = append(result.Errors, err)
@@ -333,7 +395,11 @@ func executeBuild(plan *BuildPlan, projectDir string, userDirs *userdirs.UserDir
 }
 
 // buildPlugin builds a single plugin
-func buildPlugin(pluginType, pluginName, projectDir string, userDirs *userdirs.UserDirectories, cacheManager *cache.Manager) error {
+func buildPlugin(ctx context.Context, pluginType, pluginName, projectDir string, userDirs *userdirs.UserDirectories, cacheManager *cache.Manager) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("build of %s %s cancelled: %w", pluginType, pluginName, err)
+	}
+
 	startTime := time.Now()
 
 	if verboseBuild {
@@ -355,7 +421,7 @@ func buildPlugin(pluginType, pluginName, projectDir string, userDirs *userdirs.U
 	}
 
 	// Build the plugin using go build directly
-	if err := buildGoPlugin(sourcePath, outputPath); err != nil {
+	if err := buildGoPlugin(ctx, sourcePath, outputPath); err != nil {
 		if verboseBuild {
 			fmt.Printf("❌ Build failed: %v\n", err)
 		}
@@ -382,11 +448,18 @@ func buildPlugin(pluginType, pluginName, projectDir string, userDirs *userdirs.U
 }
 
 // buildGoPlugin builds a Go plugin using the go build command
-func buildGoPlugin(source, output string) error {
+func buildGoPlugin(ctx context.Context, source, output string) error {
 	// Build the plugin - change directory to source and build .
-	cmd := exec.Command("go", "build", "-buildmode=plugin", "-o", output, ".")
+	cmd := exec.CommandContext(ctx, "go", "build", "-buildmode=plugin", "-o", output, ".")
 	cmd.Dir = source
 
+	// Run go build in its own process group and kill the whole group on
+	// cancellation, so compiler and linker children do not outlive it
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+
 	// Set environment variables for consistent build
 	cmd.Env = append(os.Environ(),
 		"CGO_ENABLED=0",
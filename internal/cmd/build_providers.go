@@ -44,12 +44,12 @@ unnecessary rebuilds and supporting parallel compilation for faster builds.`,
 
 // runBuildProviders handles the 'afe build providers' command
 func runBuildProviders(cmd *cobra.Command, args []string) error {
-	return runBuildCommand("provider", args)
+	return runBuildCommand(cmd.Context(), "provider", args)
 }
 
 // runBuildAgents handles the 'afe build agents' command
 func runBuildAgents(cmd *cobra.Command, args []string) error {
-	return runBuildCommand("agent", args)
+	return runBuildCommand(cmd.Context(), "agent", args)
 }
 
 // runBuildAll handles the 'afe build all' command
@@ -118,46 +118,12 @@ func runBuildAll(cmd *cobra.Command, args []string) error {
 		AgentsCached:     []string{},
 	}
 
-	// Analyze providers
-	for _, provider := range providers {
-		shouldRebuild, reason, err := cacheManager.ShouldRebuild("provider", provider, filepath.Join(cwd, "providers", provider))
-		if err != nil && verboseBuild {
-			fmt.Printf("⚠️  Error checking provider %s: %v\n", provider, err)
-			shouldRebuild = true
-		}
-
-		if forceBuild || cleanBuild || shouldRebuild {
-			buildPlan.ProvidersToBuild = append(buildPlan.ProvidersToBuild, provider)
-			if verboseBuild {
-				fmt.Printf("🔨 Provider %s: %s\n", provider, reason)
-			}
-		} else {
-			buildPlan.ProvidersCached = append(buildPlan.ProvidersCached, provider)
-			if verboseBuild {
-				fmt.Printf("📦 Provider %s: cached (unchanged)\n", provider)
-			}
-		}
-	}
-
-	// Analyze agents
-	for _, agent := range agents {
-		shouldRebuild, reason, err := cacheManager.ShouldRebuild("agent", agent, filepath.Join(cwd, "agents", agent))
-		if err != nil && verboseBuild {
-			fmt.Printf("⚠️  Error checking agent %s: %v\n", agent, err)
-			shouldRebuild = true
-		}
-
-		if forceBuild || cleanBuild || shouldRebuild {
-			buildPlan.AgentsToBuild = append(buildPlan.AgentsToBuild, agent)
-			if verboseBuild {
-				fmt.Printf("🔨 Agent %s: %s\n", agent, reason)
-			}
-		} else {
-			buildPlan.AgentsCached = append(buildPlan.AgentsCached, agent)
-			if verboseBuild {
-				fmt.Printf("📦 Agent %s: cached (unchanged)\n", agent)
-			}
-		}
+	// Analyze providers and agents
+	plans := planPlugins(cacheManager, "provider", cwd, providers, buildPlan)
+	plans = append(plans, planPlugins(cacheManager, "agent", cwd, agents, buildPlan)...)
+	if planBuild {
+		printBuildPlan(os.Stdout, plans)
+		return nil
 	}
 
 	// Show build summary
@@ -173,7 +139,7 @@ func runBuildAll(cmd *cobra.Command, args []string) error {
 
 	// Execute build
 	startTime := time.Now()
-	buildResult, err := executeBuild(buildPlan, cwd, userDirs, cacheManager)
+	buildResult, err := executeBuild(cmd.Context(), buildPlan, cwd, userDirs, cacheManager)
 	if err != nil {
 		return fmt.Errorf("build execution failed: %w", err)
 	}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -63,7 +64,7 @@ func runChat(cmd *cobra.Command, args []string) error {
 	}
 
 	// Call the chat API
-	response, err := callChatAPI(chatMessage, chatModel, chatVerbosity, chatTimeout)
+	response, err := callChatAPI(cmd.Context(), chatMessage, chatModel, chatVerbosity, chatTimeout)
 	if err != nil {
 		return fmt.Errorf("failed to call chat API: %w", err)
 	}
@@ -95,7 +96,7 @@ type ChatAPIResponse struct {
 }
 
 // callChatAPI sends a request to the chat API
-func callChatAPI(message, model string, verbosity, timeout int) (*ChatAPIResponse, error) {
+func callChatAPI(ctx context.Context, message, model string, verbosity, timeout int) (*ChatAPIResponse, error) {
 	// Get server configuration (hardcoded for now, will read from config later)
 	apiURL := "http://localhost:8082/api/v1/chat"
 
@@ -117,7 +118,7 @@ func callChatAPI(message, model string, verbosity, timeout int) (*ChatAPIRespons
 	}
 
 	// Create HTTP request
-	req, err := http.NewRequest("POST", apiURL, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/internal/api"
+	"github.com/AgentForgeEngine/AgentForgeEngine/internal/config"
+	"github.com/AgentForgeEngine/AgentForgeEngine/internal/loader"
+	"github.com/AgentForgeEngine/AgentForgeEngine/internal/models"
+	"github.com/AgentForgeEngine/AgentForgeEngine/internal/orchestrator"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/status"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/userdirs"
+)
+
+// engine holds the components the API's lifecycle controller starts, stops,
+// and reloads. Its methods only run within lifecycle transitions, which
+// never overlap.
+type engine struct {
+	configPath    string
+	configManager *config.Manager
+	userDirs      *userdirs.UserDirectories
+	statusInfo    *status.StatusInfo
+	apiServer     *api.Server
+	modelManager  *models.Manager
+	// orchestrator serves the chats' orch. calls when enabled
+	orchestrator *orchestrator.Manager
+	// workflowStore, when set, keeps the orchestrator's workflows across
+	// restarts
+	workflowStore orchestrator.WorkflowStore
+	// providerConfigs are the configs the loaded providers were last
+	// initialized with, by name
+	providerConfigs map[string]map[string]interface{}
+}
+
+// components returns the engine's components in start order: the API serves
+// requests with the plugin and model managers once both are up, and stops
+// before them
+func (e *engine) components() []api.Component {
+	return []api.Component{
+		api.ComponentFuncs{ComponentName: "plugins", StartFunc: e.startPlugins, ShutdownFunc: e.stopPlugins},
+		api.ComponentFuncs{ComponentName: "models", StartFunc: e.startModels, ShutdownFunc: e.stopModels, ReloadFunc: e.reloadModels},
+		api.ComponentFuncs{ComponentName: "status", StartFunc: e.startStatus, ShutdownFunc: e.stopStatus},
+		api.ComponentFuncs{ComponentName: "requests", StartFunc: e.serveRequests, ShutdownFunc: e.refuseRequests},
+	}
+}
+
+// startPlugins creates the plugin manager and loads the configured agents
+func (e *engine) startPlugins(ctx context.Context) error {
+	pluginManager = loader.NewManager(e.userDirs.AgentsDir, e.userDirs.CacheDir)
+	pluginManager.SetMaxCallDepth(e.configManager.GetMaxAgentCallDepth())
+	pluginManager.SetMaxAgentPanics(e.configManager.GetMaxAgentPanics())
+
+	if verbose {
+		fmt.Printf("Plugin manager initialized with plugins dir: %s\n", e.userDirs.AgentsDir)
+	}
+
+	// Load available agents
+	for _, agentConfig := range e.configManager.GetAgentConfigs() {
+		if agentConfig.Type == "local" {
+			err := pluginManager.LoadLocalAgent(agentConfig.Path, agentConfig.Name)
+			if err != nil && verbose {
+				log.Printf("Failed to load agent %s: %v", agentConfig.Name, err)
+			} else if verbose {
+				fmt.Printf("Loaded agent: %s\n", agentConfig.Name)
+			}
+		}
+	}
+
+	// Agents publish events, such as task completion, to WebSocket clients
+	pluginManager.SetEventFunc(e.apiServer.PublishEvent)
+	return nil
+}
+
+func (e *engine) stopPlugins() error {
+	return pluginManager.ShutdownAgents()
+}
+
+// startModels creates the model manager and serves the configured models and
+// the built provider plugins
+func (e *engine) startModels(ctx context.Context) error {
+	e.modelManager = models.NewManager()
+	e.modelManager.SetObserver(e.apiServer.ObserveModel)
+	e.modelManager.SetDefaultModel(e.configManager.GetDefaultModel())
+	e.modelManager.SetAliases(e.configManager.GetModelAliases())
+	modelConfigs := e.configManager.GetModelConfigs()
+	if err := e.modelManager.InitializeModels(modelConfigs); err != nil {
+		log.Printf("Failed to initialize models: %v", err)
+	} else if verbose {
+		fmt.Printf("Initialized %d models\n", len(modelConfigs))
+	}
+
+	// Load built provider plugins and serve them as models
+	providerConfigs := e.configManager.GetProviderConfigs()
+	providerFailures := pluginManager.DiscoverProviders(e.userDirs.ProvidersDir, providerConfigs, e.modelManager)
+	for name, err := range providerFailures {
+		log.Printf("Failed to load provider %s: %v", name, err)
+	}
+	if verbose {
+		fmt.Printf("Loaded providers: %v\n", pluginManager.ListProviders())
+	}
+
+	e.providerConfigs = make(map[string]map[string]interface{})
+	for _, providerConfig := range providerConfigs {
+		e.providerConfigs[providerConfig.Name] = providerOptions(providerConfig.Config)
+	}
+	return nil
+}
+
+// stopModels shuts the models down, providers included
+func (e *engine) stopModels() error {
+	return e.modelManager.Shutdown()
+}
+
+// reloadModels re-reads the configuration file and initializes each loaded
+// provider whose config changed again. Requests in flight on a provider
+// finish with its old config first.
+func (e *engine) reloadModels(ctx context.Context) error {
+	next := config.NewManager()
+	if err := next.Load(e.configPath); err != nil {
+		return fmt.Errorf("failed to reload config: %w", err)
+	}
+	e.configManager = next
+
+	var failures []string
+	for _, providerConfig := range next.GetProviderConfigs() {
+		options := providerOptions(providerConfig.Config)
+		previous, loaded := e.providerConfigs[providerConfig.Name]
+		if !loaded || reflect.DeepEqual(previous, options) {
+			continue
+		}
+		if err := e.modelManager.ReconfigureProvider(providerConfig.Name, options); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", providerConfig.Name, err))
+			continue
+		}
+		e.providerConfigs[providerConfig.Name] = options
+		log.Printf("Reconfigured provider %s", providerConfig.Name)
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("failed to reconfigure providers: %s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// providerOptions returns a provider's config, empty when it has none, as
+// DiscoverProviders passes it
+func providerOptions(options map[string]interface{}) map[string]interface{} {
+	if options == nil {
+		return map[string]interface{}{}
+	}
+	return options
+}
+
+// startStatus reports the engine as running with what it loaded to afe status
+func (e *engine) startStatus(ctx context.Context) error {
+	e.statusInfo.Status = "RUNNING"
+	e.statusInfo.AgentsCount = len(pluginManager.ListAgents())
+	e.statusInfo.ModelsCount = len(e.modelManager.ListModels())
+	return nil
+}
+
+func (e *engine) stopStatus() error {
+	e.statusInfo.Status = "STOPPED"
+	e.statusInfo.AgentsCount = 0
+	e.statusInfo.ModelsCount = 0
+	return nil
+}
+
+// serveRequests hands the managers to the API server, with an orchestrator
+// over the loaded agents when it is enabled
+func (e *engine) serveRequests(ctx context.Context) error {
+	e.apiServer.SetComponents(statusManager, pluginManager, e.modelManager)
+
+	orchestratorConfig := e.configManager.GetOrchestratorConfig()
+	if orchestratorConfig.Enabled {
+		e.orchestrator = orchestrator.NewManager(pluginManager, map[string]interface{}{
+			"max_todos":            orchestratorConfig.MaxTodos,
+			"max_concurrent_tasks": orchestratorConfig.MaxConcurrentTasks,
+		})
+		if e.workflowStore != nil {
+			// Workflows that were running when the engine stopped are
+			// restored as interrupted
+			if err := e.orchestrator.SetStore(e.workflowStore); err != nil {
+				log.Printf("Failed to restore workflows: %v", err)
+			}
+		}
+		e.apiServer.SetOrchestrator(e.orchestrator)
+	}
+	return nil
+}
+
+// refuseRequests takes the managers from the API server, which answers the
+// requests that need them with 503 until the engine starts again
+func (e *engine) refuseRequests() error {
+	e.apiServer.SetComponents(statusManager, nil, nil)
+	e.apiServer.SetOrchestrator(nil)
+	if e.orchestrator != nil {
+		e.orchestrator.Shutdown()
+		e.orchestrator = nil
+	}
+	return nil
+}
@@ -5,15 +5,18 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"os/signal"
-	"syscall"
+	"path/filepath"
 	"time"
 
 	"github.com/AgentForgeEngine/AgentForgeEngine/internal/api"
 	"github.com/AgentForgeEngine/AgentForgeEngine/internal/config"
 	"github.com/AgentForgeEngine/AgentForgeEngine/internal/loader"
-	"github.com/AgentForgeEngine/AgentForgeEngine/internal/models"
+	"github.com/AgentForgeEngine/AgentForgeEngine/internal/logging"
+	"github.com/AgentForgeEngine/AgentForgeEngine/internal/orchestrator"
+	"github.com/AgentForgeEngine/AgentForgeEngine/internal/policy"
 
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/auth"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/cache"
 	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/status"
 	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/userdirs"
 	"github.com/spf13/cobra"
@@ -30,10 +33,12 @@ var serverCtx context.Context
 var serverCancel context.CancelFunc
 var statusManager *status.Manager
 var pluginManager *loader.Manager
-
-// var orchestratorManager *orchestrator.Manager // Disabled for now
+var shutdownSequence = loader.NewShutdownSequence()
 
 func runStart(cmd *cobra.Command, args []string) error {
+	// Keep what the engine and its plugins log for /api/v1/logs
+	logging.CaptureStandardLog("engine")
+
 	// Initialize user directories and status manager
 	userDirs, err := userdirs.NewUserDirectories()
 	if err != nil {
@@ -63,12 +68,9 @@ func runStart(cmd *cobra.Command, args []string) error {
 		fmt.Printf("Socket file: %s\n", statusManager.GetSocketFile())
 	}
 
-	// Create context with cancellation
-	serverCtx, serverCancel = context.WithCancel(context.Background())
-
-	// Setup signal handling
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	// Create context with cancellation, derived from the command context so
+	// SIGINT/SIGTERM trigger the graceful shutdown below
+	serverCtx, serverCancel = context.WithCancel(cmd.Context())
 
 	// Create status info
 	statusInfo := &status.StatusInfo{
@@ -96,14 +98,12 @@ func runStart(cmd *cobra.Command, args []string) error {
 		}
 	}()
 
-	// Wait for signals
-	select {
-	case sig := <-sigChan:
-		if verbose {
-			fmt.Printf("\nReceived signal: %v\n", sig)
-		}
-	case <-serverCtx.Done():
-		if verbose {
+	// Wait for a signal or a fatal server error
+	<-serverCtx.Done()
+	if verbose {
+		if cmd.Context().Err() != nil {
+			fmt.Println("\nReceived shutdown signal")
+		} else {
 			fmt.Println("Server context cancelled")
 		}
 	}
@@ -113,8 +113,13 @@ func runStart(cmd *cobra.Command, args []string) error {
 		fmt.Println("Shutting down gracefully...")
 	}
 
+	// Stop accepting work, cancel running tasks, close provider connections,
+	// then flush caches, reporting every component that fails
+	if err := shutdownSequence.Shutdown(); err != nil {
+		log.Printf("Shutdown errors: %v", err)
+	}
+
 	serverCancel()
-	time.Sleep(2 * time.Second) // Give components time to cleanup
 
 	// Cleanup status files
 	if err := statusManager.Cleanup(); err != nil && verbose {
@@ -135,46 +140,82 @@ func startServerComponents(ctx context.Context, configManager *config.Manager, s
 	statusInfo.Host = serverConfig.Host
 	statusInfo.Port = serverConfig.Port
 
-	// Initialize plugin manager
 	userDirs, err := userdirs.NewUserDirectories()
 	if err != nil {
 		return fmt.Errorf("failed to create user directories: %w", err)
 	}
 
-	pluginManager = loader.NewManager(userDirs.AgentsDir, userDirs.CacheDir)
+	// Initialize HTTP API server
+	apiServer := api.NewServer(serverConfig.Host, serverConfig.Port)
+	apiServer.SetAgentTimeout(configManager.GetAgentCallTimeout())
+	apiServer.SetHTTPTimeouts(
+		time.Duration(serverConfig.ReadTimeout)*time.Second,
+		time.Duration(serverConfig.WriteTimeout)*time.Second,
+		time.Duration(serverConfig.IdleTimeout)*time.Second,
+	)
+	apiServer.SetMaxRequestTimeout(time.Duration(serverConfig.MaxRequestTimeout) * time.Second)
+	apiServer.SetMaxBodyBytes(serverConfig.MaxBodyBytes)
+	apiServer.SetRateLimit(serverConfig.RateLimit.RequestsPerMinute, serverConfig.RateLimit.Burst)
+	apiServer.SetAllowedOrigins(serverConfig.AllowedOrigins)
+	apiServer.SetMaxToolIterations(configManager.GetMaxToolIterations())
+	apiServer.SetSessionMaxTokens(configManager.GetSessionMaxTokens())
+	apiServer.SetRetryBudget(configManager.GetRetryBudget())
+	if err := apiServer.SetExportRedaction(configManager.GetExportRedaction()); err != nil {
+		return fmt.Errorf("invalid chat config: %w", err)
+	}
+	apiServer.SetPolicy(policy.New(configManager.GetPolicyConfig()))
+	if configManager.GetMetricsConfig().Enabled {
+		apiServer.EnableMetrics(buildCacheStatus)
+	}
 
-	if verbose {
-		fmt.Printf("Plugin manager initialized with plugins dir: %s\n", userDirs.AgentsDir)
-	}
-
-	// Load available agents
-	agentConfigs := configManager.GetAgentConfigs()
-	for _, agentConfig := range agentConfigs {
-		if agentConfig.Type == "local" {
-			err := pluginManager.LoadLocalAgent(agentConfig.Path, agentConfig.Name)
-			if err != nil && verbose {
-				log.Printf("Failed to load agent %s: %v", agentConfig.Name, err)
-			} else if verbose {
-				fmt.Printf("Loaded agent: %s\n", agentConfig.Name)
-			}
+	authConfig := configManager.GetAuthConfig()
+	if authConfig.Enabled {
+		userManager, err := openAuth(authConfig, userDirs, apiServer)
+		if err != nil {
+			return err
 		}
+		shutdownSequence.Register(loader.PhaseFlushCaches, "accounts", loader.ShutdownFunc(userManager.Close))
+		if verbose {
+			fmt.Println("API authentication enabled")
+		}
+	}
 
-		// This would be called by model via function_call
-
+	// Keep the orchestrator's workflows across restarts
+	var workflowStore orchestrator.WorkflowStore
+	if orchestratorConfig := configManager.GetOrchestratorConfig(); orchestratorConfig.Enabled {
+		store, err := orchestrator.NewLevelDBStore(workflowsDir(orchestratorConfig, userDirs))
+		if err != nil {
+			return fmt.Errorf("failed to open workflows: %w", err)
+		}
+		workflowStore = store
+		apiServer.SetWorkflowStore(store)
+		shutdownSequence.Register(loader.PhaseFlushCaches, "workflows", loader.ShutdownFunc(store.Close))
 	}
 
-	// Initialize model manager
-	modelManager := models.NewManager()
-	modelConfigs := configManager.GetModelConfigs()
-	if err := modelManager.InitializeModels(modelConfigs); err != nil {
-		log.Printf("Failed to initialize models: %v", err)
-	} else if verbose {
-		fmt.Printf("Initialized %d models\n", len(modelConfigs))
+	// Load the agents, models, and providers through the lifecycle
+	// controller, which /api/v1/start, /api/v1/stop, and /api/v1/reload
+	// drive from then on
+	lifecycle := api.NewLifecycle((&engine{
+		configPath:    getConfigPath(),
+		configManager: configManager,
+		userDirs:      userDirs,
+		statusInfo:    statusInfo,
+		apiServer:     apiServer,
+		workflowStore: workflowStore,
+	}).components()...)
+	apiServer.SetLifecycle(lifecycle)
+	if _, err := lifecycle.Start(ctx); err != nil {
+		return err
 	}
 
-	// Initialize HTTP API server
-	apiServer := api.NewServer(serverConfig.Host, serverConfig.Port)
-	apiServer.SetComponents(statusManager, pluginManager, modelManager)
+	// Register components for ordered shutdown: the server stops accepting
+	// requests, then agents, models, and providers stop unless the engine was
+	// already stopped through the API
+	shutdownSequence.Register(loader.PhaseStopAccepting, "api-server", apiServer)
+	shutdownSequence.Register(loader.PhaseCancelTasks, "engine", loader.ShutdownFunc(func() error {
+		_, err := lifecycle.Stop()
+		return err
+	}))
 
 	// Start API server in goroutine
 	go func() {
@@ -189,6 +230,40 @@ func startServerComponents(ctx context.Context, configManager *config.Manager, s
 	return nil
 }
 
+// openAuth opens the account stores and makes the API server require their
+// credentials
+func openAuth(authConfig config.AuthConfig, userDirs *userdirs.UserDirectories, apiServer *api.Server) (*auth.UserManager, error) {
+	accountsDir := authConfig.AccountsDir
+	if accountsDir == "" {
+		accountsDir = filepath.Join(userDirs.AFEDir, "accounts")
+	}
+
+	userManager, err := auth.NewUserManager(accountsDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open accounts: %w", err)
+	}
+
+	if authConfig.TokenSecret == "" {
+		log.Printf("auth.token_secret is not set; session tokens will not survive a restart")
+	}
+	tokens, err := auth.NewTokenSigner([]byte(authConfig.TokenSecret), time.Duration(authConfig.TokenTTL)*time.Second)
+	if err != nil {
+		userManager.Close()
+		return nil, err
+	}
+
+	apiServer.SetAuth(userManager, tokens)
+	return userManager, nil
+}
+
+// workflowsDir returns the directory of the workflow store
+func workflowsDir(orchestratorConfig config.OrchestratorConfig, userDirs *userdirs.UserDirectories) string {
+	if orchestratorConfig.WorkflowsDir != "" {
+		return orchestratorConfig.WorkflowsDir
+	}
+	return filepath.Join(userDirs.AFEDir, "workflows")
+}
+
 func getConfigPath() string {
 	if cfgFile != "" {
 		return cfgFile
@@ -214,3 +289,16 @@ func getConfigPath() string {
 func init() {
 	rootCmd.AddCommand(startCmd)
 }
+
+// buildCacheStatus reads the plugin build cache, which afe build updates
+// while the engine runs, for the metrics
+func buildCacheStatus() (*cache.CacheStatus, error) {
+	cacheManager, err := cache.NewManager()
+	if err != nil {
+		return nil, err
+	}
+	if err := cacheManager.LoadCache(); err != nil {
+		return nil, err
+	}
+	return cacheManager.GetCacheStatus()
+}
@@ -41,6 +41,51 @@ This command validates credentials and returns user information.`,
 	RunE: runUserLogin,
 }
 
+// userListCmd represents the 'afe user list' command
+var userListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List user accounts",
+	Long: `List user accounts a page at a time.
+Pass the cursor printed after a full page to --after to see the next one.`,
+	RunE: runUserList,
+}
+
+// userPasswdCmd represents the 'afe user passwd' command
+var userPasswdCmd = &cobra.Command{
+	Use:   "passwd",
+	Short: "Change a user's password",
+	Long: `Change a user's password.
+The current password is required, and the new one is asked for twice.`,
+	RunE: runUserPasswd,
+}
+
+// userDeactivateCmd represents the 'afe user deactivate' command
+var userDeactivateCmd = &cobra.Command{
+	Use:   "deactivate",
+	Short: "Deactivate a user account",
+	Long: `Deactivate a user account.
+A deactivated user cannot log in and their API keys are refused, but the
+account and its keys are kept so it can be reactivated.`,
+	RunE: runUserDeactivate,
+}
+
+// userReactivateCmd represents the 'afe user reactivate' command
+var userReactivateCmd = &cobra.Command{
+	Use:   "reactivate",
+	Short: "Reactivate a deactivated user account",
+	RunE:  runUserReactivate,
+}
+
+// userRolesCmd represents the 'afe user roles' command
+var userRolesCmd = &cobra.Command{
+	Use:   "roles",
+	Short: "Set a user's roles",
+	Long: `Set a user's roles, which decide the scopes of their session tokens.
+The user role may chat and call agents; the admin role may also manage users
+and control the engine.`,
+	RunE: runUserRoles,
+}
+
 // userApiKeyCmd represents the 'afe user api-key' command
 var userApiKeyCmd = &cobra.Command{
 	Use:   "api-key",
@@ -67,6 +112,25 @@ Shows key details including creation date and expiration.`,
 	RunE: runAPIKeyList,
 }
 
+// apiKeyRevokeCmd represents the 'afe user api-key revoke' command
+var apiKeyRevokeCmd = &cobra.Command{
+	Use:   "revoke",
+	Short: "Revoke an API key",
+	Long: `Revoke one of a user's API keys.
+The key is refused from then on; its record is kept and listed as revoked.`,
+	RunE: runAPIKeyRevoke,
+}
+
+// apiKeyAgentsCmd represents the 'afe user api-key agents' command
+var apiKeyAgentsCmd = &cobra.Command{
+	Use:   "agents",
+	Short: "Limit the agents an API key may call",
+	Long: `Limit one of a user's API keys to calling the given agents.
+The engine's policy still applies; without --agents the key may call any
+agent the policy allows.`,
+	RunE: runAPIKeyAgents,
+}
+
 var (
 	userName      string
 	userEmail     string
@@ -75,15 +139,30 @@ var (
 	apiKeyName    string
 	apiKeyExpires string
 	apiKeyScopes  []string
+	apiKeyAgents  []string
+	apiKeyID      string
+	userRoles     []string
+
+	listLimit    int
+	listAfter    string
+	listActive   bool
+	listInactive bool
 )
 
 func init() {
 	rootCmd.AddCommand(userCmd)
 	userCmd.AddCommand(userCreateCmd)
 	userCmd.AddCommand(userLoginCmd)
+	userCmd.AddCommand(userListCmd)
+	userCmd.AddCommand(userPasswdCmd)
+	userCmd.AddCommand(userDeactivateCmd)
+	userCmd.AddCommand(userReactivateCmd)
+	userCmd.AddCommand(userRolesCmd)
 	userCmd.AddCommand(userApiKeyCmd)
 	userApiKeyCmd.AddCommand(apiKeyCreateCmd)
 	userApiKeyCmd.AddCommand(apiKeyListCmd)
+	userApiKeyCmd.AddCommand(apiKeyRevokeCmd)
+	userApiKeyCmd.AddCommand(apiKeyAgentsCmd)
 
 	// User create flags
 	userCreateCmd.Flags().StringVar(&userName, "name", "", "User name (required)")
@@ -94,11 +173,35 @@ func init() {
 	userLoginCmd.Flags().StringVar(&userEmail, "email", "", "User email (required)")
 	userLoginCmd.Flags().StringVar(&userPassword, "password", "", "User password (required)")
 
+	// User list flags
+	userListCmd.Flags().IntVar(&listLimit, "limit", 50, "Maximum users to list")
+	userListCmd.Flags().StringVar(&listAfter, "after", "", "List users after this cursor")
+	userListCmd.Flags().BoolVar(&listActive, "active", false, "List only active users")
+	userListCmd.Flags().BoolVar(&listInactive, "inactive", false, "List only deactivated users")
+
+	// User passwd, deactivate, and reactivate flags
+	userPasswdCmd.Flags().StringVar(&userEmail, "email", "", "User email (required)")
+	userDeactivateCmd.Flags().StringVar(&userEmail, "email", "", "User email (required)")
+	userReactivateCmd.Flags().StringVar(&userEmail, "email", "", "User email (required)")
+
+	// User roles flags
+	userRolesCmd.Flags().StringVar(&userEmail, "email", "", "User email (required)")
+	userRolesCmd.Flags().StringSliceVar(&userRoles, "roles", []string{auth.RoleUser}, "User roles: user, admin")
+
 	// API key create flags
 	apiKeyCreateCmd.Flags().StringVar(&apiKeyName, "name", "", "API key name (required)")
 	apiKeyCreateCmd.Flags().StringVar(&apiKeyExpires, "expires", "", "Expiration date (optional, format: 2024-12-31)")
-	apiKeyCreateCmd.Flags().StringSliceVar(&apiKeyScopes, "scopes", []string{"read"}, "API key scopes")
+	apiKeyCreateCmd.Flags().StringSliceVar(&apiKeyScopes, "scopes", []string{auth.ScopeChat, auth.ScopeAgentsRead}, "API key scopes: chat, agents:read, agents:execute, admin")
+	apiKeyCreateCmd.Flags().StringSliceVar(&apiKeyAgents, "agents", nil, "Agents the key may call (optional; default any the policy allows)")
 	apiKeyCreateCmd.Flags().StringVar(&userEmail, "email", "", "User email (required)")
+
+	// API key list and revoke flags
+	apiKeyListCmd.Flags().StringVar(&userEmail, "email", "", "User email (required)")
+	apiKeyRevokeCmd.Flags().StringVar(&userEmail, "email", "", "User email (required)")
+	apiKeyRevokeCmd.Flags().StringVar(&apiKeyID, "key-id", "", "ID of the key to revoke (required)")
+	apiKeyAgentsCmd.Flags().StringVar(&userEmail, "email", "", "User email (required)")
+	apiKeyAgentsCmd.Flags().StringVar(&apiKeyID, "key-id", "", "ID of the key to limit (required)")
+	apiKeyAgentsCmd.Flags().StringSliceVar(&apiKeyAgents, "agents", nil, "Agents the key may call; none lifts the limit")
 }
 
 // readPassword reads password from terminal without echoing, or from stdin if piped
@@ -125,6 +228,25 @@ func readPassword(prompt string) (string, error) {
 	}
 }
 
+// openUserManager opens the account store under the AFE directory
+func openUserManager() (*auth.UserManager, error) {
+	userDirs, err := userdirs.NewUserDirectories()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user directories: %w", err)
+	}
+
+	accountsDir := filepath.Join(userDirs.AFEDir, "accounts")
+	if err := os.MkdirAll(accountsDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create accounts directory: %w", err)
+	}
+
+	userManager, err := auth.NewUserManager(accountsDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user manager: %w", err)
+	}
+	return userManager, nil
+}
+
 // runUserCreate creates a new user account
 func runUserCreate(cmd *cobra.Command, args []string) error {
 	if userName == "" || userEmail == "" {
@@ -284,6 +406,11 @@ func runAPIKeyCreate(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("failed to create API key: %w", err)
 	}
+	if len(apiKeyAgents) > 0 {
+		if err := userManager.SetAPIKeyAgents(user.UID, apiKeyRecord.KeyID, apiKeyAgents); err != nil {
+			return fmt.Errorf("failed to limit the API key's agents: %w", err)
+		}
+	}
 
 	fmt.Printf("✅ API key created successfully!\n")
 	fmt.Printf("🔑 Key: %s\n", apiKey)
@@ -294,6 +421,9 @@ func runAPIKeyCreate(cmd *cobra.Command, args []string) error {
 		fmt.Printf("⏰ Expires: %s\n", apiKeyRecord.ExpiresAt.Format("2006-01-02 15:04:05"))
 	}
 	fmt.Printf("🔒 Scopes: %v\n", apiKeyRecord.Scopes)
+	if len(apiKeyAgents) > 0 {
+		fmt.Printf("🤖 Agents: %s\n", strings.Join(apiKeyAgents, ", "))
+	}
 
 	fmt.Println("\n⚠️  Save this API key securely. It will not be shown again.")
 
@@ -306,37 +436,263 @@ func runAPIKeyList(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("user email is required")
 	}
 
-	// Initialize user directories
-	userDirs, err := userdirs.NewUserDirectories()
+	userManager, err := openUserManager()
 	if err != nil {
-		return fmt.Errorf("failed to create user directories: %w", err)
+		return err
 	}
+	defer userManager.Close()
 
-	// Create accounts directory if it doesn't exist
-	accountsDir := filepath.Join(userDirs.AFEDir, "accounts")
-	if err := os.MkdirAll(accountsDir, 0700); err != nil {
-		return fmt.Errorf("failed to create accounts directory: %w", err)
+	// Get user by email
+	user, err := userManager.GetUserByEmail(userEmail)
+	if err != nil {
+		return fmt.Errorf("user not found: %w", err)
 	}
 
-	// Create user manager
-	userManager, err := auth.NewUserManager(accountsDir)
+	keys, err := userManager.ListAPIKeys(user.UID)
 	if err != nil {
-		return fmt.Errorf("failed to create user manager: %w", err)
+		return fmt.Errorf("failed to list API keys: %w", err)
+	}
+
+	fmt.Printf("🔑 API Keys for %s (%s)\n", user.Name, user.Email)
+	fmt.Println(strings.Repeat("=", 50))
+
+	if len(keys) == 0 {
+		fmt.Println("📝 No API keys found")
+		return nil
+	}
+
+	fmt.Printf("%-20s %-34s %-19s %-19s %-19s %-8s %-32s %s\n", "NAME", "KEY ID", "CREATED", "EXPIRES", "LAST USED", "STATUS", "SCOPES", "AGENTS")
+	for _, key := range keys {
+		agents := "any"
+		if len(key.AllowedAgents) > 0 {
+			agents = strings.Join(key.AllowedAgents, ",")
+		}
+		fmt.Printf("%-20s %-34s %-19s %-19s %-19s %-8s %-32s %s\n",
+			key.Name,
+			key.KeyID,
+			key.CreatedAt.Format("2006-01-02 15:04:05"),
+			formatOptionalTime(key.ExpiresAt),
+			formatOptionalTime(key.LastUsed),
+			key.Status(),
+			strings.Join(key.Scopes, ","),
+			agents,
+		)
+	}
+
+	return nil
+}
+
+// runAPIKeyRevoke revokes one of a user's API keys
+func runAPIKeyRevoke(cmd *cobra.Command, args []string) error {
+	if userEmail == "" || apiKeyID == "" {
+		return fmt.Errorf("user email and key ID are required")
+	}
+
+	userManager, err := openUserManager()
+	if err != nil {
+		return err
 	}
 	defer userManager.Close()
 
-	// Get user by email
 	user, err := userManager.GetUserByEmail(userEmail)
 	if err != nil {
 		return fmt.Errorf("user not found: %w", err)
 	}
 
-	fmt.Printf("🔑 API Keys for %s (%s)\n", user.Name, user.Email)
-	fmt.Println(strings.Repeat("=", 50))
+	if err := userManager.RevokeAPIKey(user.UID, apiKeyID); err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
 
-	// TODO: Implement API key listing
-	// This would require adding a method to list API keys for a user
-	fmt.Println("📝 API key listing not yet implemented")
+	fmt.Printf("✅ API key %s revoked\n", apiKeyID)
+	return nil
+}
+
+// runAPIKeyAgents limits the agents one of a user's API keys may call
+func runAPIKeyAgents(cmd *cobra.Command, args []string) error {
+	if userEmail == "" || apiKeyID == "" {
+		return fmt.Errorf("user email and key ID are required")
+	}
+
+	userManager, err := openUserManager()
+	if err != nil {
+		return err
+	}
+	defer userManager.Close()
+
+	user, err := userManager.GetUserByEmail(userEmail)
+	if err != nil {
+		return fmt.Errorf("user not found: %w", err)
+	}
+
+	if err := userManager.SetAPIKeyAgents(user.UID, apiKeyID, apiKeyAgents); err != nil {
+		return fmt.Errorf("failed to limit the API key's agents: %w", err)
+	}
+
+	if len(apiKeyAgents) == 0 {
+		fmt.Printf("✅ API key %s may call any agent the policy allows\n", apiKeyID)
+	} else {
+		fmt.Printf("✅ API key %s may call %s\n", apiKeyID, strings.Join(apiKeyAgents, ", "))
+	}
+	return nil
+}
+
+// formatOptionalTime formats a time for the key table, or "-" when unset
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
 
+// runUserList lists user accounts a page at a time
+func runUserList(cmd *cobra.Command, args []string) error {
+	if listActive && listInactive {
+		return fmt.Errorf("--active and --inactive cannot be used together")
+	}
+
+	userManager, err := openUserManager()
+	if err != nil {
+		return err
+	}
+	defer userManager.Close()
+
+	opts := auth.ListUsersOptions{After: listAfter, Limit: listLimit}
+	if listActive || listInactive {
+		active := listActive
+		opts.Active = &active
+	}
+
+	users, next, err := userManager.ListUsers(opts)
+	if err != nil {
+		return fmt.Errorf("failed to list users: %w", err)
+	}
+
+	if len(users) == 0 {
+		fmt.Println("No users found")
+		return nil
+	}
+
+	fmt.Printf("%-34s %-30s %-24s %-8s %s\n", "UID", "EMAIL", "NAME", "ACTIVE", "CREATED")
+	for _, user := range users {
+		fmt.Printf("%-34s %-30s %-24s %-8t %s\n", user.UID, user.Email, user.Name, user.IsActive, user.CreatedAt.Format("2006-01-02 15:04:05"))
+	}
+	if next != "" {
+		fmt.Printf("\nMore users: afe user list --after %s\n", next)
+	}
+
+	return nil
+}
+
+// runUserPasswd changes a user's password
+func runUserPasswd(cmd *cobra.Command, args []string) error {
+	if userEmail == "" {
+		return fmt.Errorf("user email is required")
+	}
+
+	userManager, err := openUserManager()
+	if err != nil {
+		return err
+	}
+	defer userManager.Close()
+
+	user, err := userManager.GetUserByEmail(userEmail)
+	if err != nil {
+		return fmt.Errorf("user not found: %w", err)
+	}
+
+	oldPassword, err := readPassword("Current password: ")
+	if err != nil {
+		return err
+	}
+	newPassword, err := readPassword("New password: ")
+	if err != nil {
+		return err
+	}
+	if len(newPassword) == 0 {
+		return fmt.Errorf("password cannot be empty")
+	}
+	confirmPassword, err := readPassword("Confirm new password: ")
+	if err != nil {
+		return err
+	}
+	if newPassword != confirmPassword {
+		return fmt.Errorf("passwords do not match")
+	}
+
+	if err := userManager.ChangePassword(user.UID, oldPassword, newPassword); err != nil {
+		return fmt.Errorf("failed to change password: %w", err)
+	}
+
+	fmt.Printf("✅ Password changed for %s\n", user.Email)
+	return nil
+}
+
+// runUserDeactivate deactivates a user account
+func runUserDeactivate(cmd *cobra.Command, args []string) error {
+	return setUserActive(false)
+}
+
+// runUserReactivate reactivates a deactivated user account
+func runUserReactivate(cmd *cobra.Command, args []string) error {
+	return setUserActive(true)
+}
+
+// runUserRoles replaces the roles of the user given by --email
+func runUserRoles(cmd *cobra.Command, args []string) error {
+	if userEmail == "" {
+		return fmt.Errorf("user email is required")
+	}
+	for _, role := range userRoles {
+		if role != auth.RoleUser && role != auth.RoleAdmin {
+			return fmt.Errorf("unknown role %q: use %s or %s", role, auth.RoleUser, auth.RoleAdmin)
+		}
+	}
+
+	userManager, err := openUserManager()
+	if err != nil {
+		return err
+	}
+	defer userManager.Close()
+
+	user, err := userManager.GetUserByEmail(userEmail)
+	if err != nil {
+		return fmt.Errorf("user not found: %w", err)
+	}
+
+	if _, err := userManager.UpdateUser(user.UID, map[string]interface{}{"roles": userRoles}); err != nil {
+		return fmt.Errorf("failed to set roles: %w", err)
+	}
+	fmt.Printf("✅ User %s now has roles: %s\n", user.Email, strings.Join(userRoles, ", "))
+	return nil
+}
+
+// setUserActive activates or deactivates the user given by --email
+func setUserActive(active bool) error {
+	if userEmail == "" {
+		return fmt.Errorf("user email is required")
+	}
+
+	userManager, err := openUserManager()
+	if err != nil {
+		return err
+	}
+	defer userManager.Close()
+
+	user, err := userManager.GetUserByEmail(userEmail)
+	if err != nil {
+		return fmt.Errorf("user not found: %w", err)
+	}
+
+	if active {
+		if _, err := userManager.ReactivateUser(user.UID); err != nil {
+			return fmt.Errorf("failed to reactivate user: %w", err)
+		}
+		fmt.Printf("✅ User %s reactivated\n", user.Email)
+	} else {
+		if _, err := userManager.DeactivateUser(user.UID); err != nil {
+			return fmt.Errorf("failed to deactivate user: %w", err)
+		}
+		fmt.Printf("✅ User %s deactivated\n", user.Email)
+	}
 	return nil
 }
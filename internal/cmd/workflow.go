@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/internal/config"
+	"github.com/AgentForgeEngine/AgentForgeEngine/internal/orchestrator"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/userdirs"
+	"github.com/spf13/cobra"
+)
+
+// workflowCmd represents the workflow command group
+var workflowCmd = &cobra.Command{
+	Use:   "workflow",
+	Short: "Inspect orchestrator workflows",
+	Long: `Inspect the workflows the orchestrator ran.
+Workflows are kept in the workflow store, so those of earlier runs are
+listed too.`,
+}
+
+// workflowListCmd represents the 'afe workflow list' command
+var workflowListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List workflows",
+	Long: `List the stored workflows, oldest first.
+The store is locked while the engine runs; GET /api/v1/workflows lists
+them then.`,
+	RunE: runWorkflowList,
+}
+
+var workflowStatus string
+
+func init() {
+	rootCmd.AddCommand(workflowCmd)
+	workflowCmd.AddCommand(workflowListCmd)
+
+	workflowListCmd.Flags().StringVar(&workflowStatus, "status", "", "List only workflows with this status, such as interrupted")
+}
+
+// runWorkflowList lists the stored workflows
+func runWorkflowList(cmd *cobra.Command, args []string) error {
+	userDirs, err := userdirs.NewUserDirectories()
+	if err != nil {
+		return fmt.Errorf("failed to create user directories: %w", err)
+	}
+
+	// The store's directory is configured in afe.yaml; the default is used
+	// without one
+	configManager := config.NewManager()
+	var orchestratorConfig config.OrchestratorConfig
+	if err := configManager.Load(getConfigPath()); err == nil {
+		orchestratorConfig = configManager.GetOrchestratorConfig()
+	}
+
+	store, err := orchestrator.NewLevelDBStore(workflowsDir(orchestratorConfig, userDirs))
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	workflows, err := store.List()
+	if err != nil {
+		return err
+	}
+
+	listed := 0
+	for _, workflow := range workflows {
+		if workflowStatus != "" && string(workflow.Status) != workflowStatus {
+			continue
+		}
+		if listed == 0 {
+			fmt.Printf("%-32s %-24s %-12s %-7s %s\n", "ID", "NAME", "STATUS", "TASKS", "CREATED")
+		}
+		completed := 0
+		for _, task := range workflow.Tasks {
+			if task.Status == orchestrator.TaskStatusCompleted {
+				completed++
+			}
+		}
+		tasks := fmt.Sprintf("%d/%d", completed, len(workflow.Tasks))
+		fmt.Printf("%-32s %-24s %-12s %-7s %s\n", workflow.ID, workflow.Name, workflow.Status, tasks, workflow.CreatedAt.Format("2006-01-02 15:04:05"))
+		listed++
+	}
+	if listed == 0 {
+		fmt.Println("No workflows found")
+	}
+
+	return nil
+}
@@ -3,7 +3,9 @@ package config
 import (
 	"fmt"
 	"log"
+	"time"
 
+	"github.com/AgentForgeEngine/AgentForgeEngine/internal/policy"
 	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
 	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/viper"
@@ -15,24 +17,82 @@ type Manager struct {
 }
 
 type Config struct {
-	Server       interfaces.ServerConfig   `yaml:"server"`
-	Models       []interfaces.ModelConfig  `yaml:"models"`
-	Agents       AgentsConfig              `yaml:"agents"`
-	Recovery     interfaces.RecoveryConfig `yaml:"recovery"`
-	Orchestrator OrchestratorConfig        `yaml:"orchestrator"`
+	Server       interfaces.ServerConfig     `yaml:"server"`
+	Models       []interfaces.ModelConfig    `yaml:"models"`
+	Providers    []interfaces.ProviderConfig `yaml:"providers"`
+	Agents       AgentsConfig                `yaml:"agents"`
+	Recovery     interfaces.RecoveryConfig   `yaml:"recovery"`
+	Orchestrator OrchestratorConfig          `yaml:"orchestrator"`
+	Chat         ChatConfig                  `yaml:"chat"`
+	Policy       policy.Config               `yaml:"policy"`
+	Auth         AuthConfig                  `yaml:"auth"`
+	Metrics      MetricsConfig               `yaml:"metrics"`
+	DefaultModel string                      `yaml:"default_model" mapstructure:"default_model"`
+	// ModelAliases map an alias to a model, or to an ordered list of models
+	// tried in turn when the ones before them are down
+	ModelAliases map[string][]string `yaml:"model_aliases" mapstructure:"model_aliases"`
 }
 
 type OrchestratorConfig struct {
-	Enabled            bool   `yaml:"enabled"`
-	MaxConcurrentTasks int    `yaml:"max_concurrent_tasks"`
-	TaskTimeout        string `yaml:"task_timeout"`
-	RetryAttempts      int    `yaml:"retry_attempts"`
-	TaskQueueSize      int    `yaml:"task_queue_size"`
+	// Enabled serves the orch. functions chats may call
+	Enabled            bool   `yaml:"enabled" mapstructure:"enabled"`
+	MaxConcurrentTasks int    `yaml:"max_concurrent_tasks" mapstructure:"max_concurrent_tasks"`
+	TaskTimeout        string `yaml:"task_timeout" mapstructure:"task_timeout"`
+	RetryAttempts      int    `yaml:"retry_attempts" mapstructure:"retry_attempts"`
+	TaskQueueSize      int    `yaml:"task_queue_size" mapstructure:"task_queue_size"`
+	// MaxTodos bounds the todo list of a manager request; zero uses the
+	// orchestrator's default of 100
+	MaxTodos int `yaml:"max_todos" mapstructure:"max_todos"`
+	// WorkflowsDir holds the workflow store; ~/.afe/workflows when empty
+	WorkflowsDir string `yaml:"workflows_dir" mapstructure:"workflows_dir"`
+}
+
+// ChatConfig controls the chat API's sessions
+type ChatConfig struct {
+	// SessionMaxTokens bounds the history sent with a session's chat; the
+	// oldest turns are dropped to fit
+	SessionMaxTokens int `yaml:"session_max_tokens" mapstructure:"session_max_tokens"`
+	// RetryBudget bounds the retries of all agent and model calls of a chat
+	RetryBudget int `yaml:"retry_budget" mapstructure:"retry_budget"`
+	// RedactExports replaces secrets in exported sessions; RedactPatterns
+	// are regular expressions matched besides the built-in ones
+	RedactExports  bool     `yaml:"redact_exports" mapstructure:"redact_exports"`
+	RedactPatterns []string `yaml:"redact_patterns" mapstructure:"redact_patterns"`
+}
+
+// AuthConfig controls authentication of API requests
+type AuthConfig struct {
+	// Enabled requires an API key or session token on every route but health
+	// and login
+	Enabled bool `yaml:"enabled" mapstructure:"enabled"`
+	// AccountsDir holds the user and API key stores; ~/.afe/accounts when empty
+	AccountsDir string `yaml:"accounts_dir" mapstructure:"accounts_dir"`
+	// TokenSecret signs session tokens; a random one, lost on restart, is used
+	// when empty
+	TokenSecret string `yaml:"token_secret" mapstructure:"token_secret"`
+	// TokenTTL is how long, in seconds, a session token lasts
+	TokenTTL int `yaml:"token_ttl" mapstructure:"token_ttl"`
+}
+
+// MetricsConfig controls the Prometheus metrics the API serves
+type MetricsConfig struct {
+	// Enabled serves the metrics at /metrics
+	Enabled bool `yaml:"enabled" mapstructure:"enabled"`
 }
 
 type AgentsConfig struct {
 	Local  []interfaces.AgentConfig `yaml:"local"`
 	Remote []interfaces.AgentConfig `yaml:"remote"`
+	// MaxCallDepth bounds how deeply agents may call each other
+	MaxCallDepth int `yaml:"max_call_depth" mapstructure:"max_call_depth"`
+	// CallTimeout bounds, in seconds, an agent call made through the API
+	CallTimeout int `yaml:"call_timeout" mapstructure:"call_timeout"`
+	// MaxToolIterations bounds the model calls of a chat that keeps asking
+	// for agents to be run
+	MaxToolIterations int `yaml:"max_tool_iterations" mapstructure:"max_tool_iterations"`
+	// MaxPanics quarantines an agent that panicked this many times; zero
+	// never quarantines
+	MaxPanics int `yaml:"max_panics" mapstructure:"max_panics"`
 }
 
 func NewManager() *Manager {
@@ -72,6 +132,48 @@ func (m *Manager) setDefaults() {
 	// Server defaults
 	m.v.SetDefault("server.host", "localhost")
 	m.v.SetDefault("server.port", 8080)
+	m.v.SetDefault("server.read_timeout", 30)
+	m.v.SetDefault("server.write_timeout", 330)
+	m.v.SetDefault("server.idle_timeout", 120)
+	m.v.SetDefault("server.max_request_timeout", 300)
+	m.v.SetDefault("server.max_body_bytes", 10<<20)
+	m.v.SetDefault("server.rate_limit.requests_per_minute", 0)
+	m.v.SetDefault("server.rate_limit.burst", 10)
+
+	// Model defaults
+	m.v.SetDefault("default_model", "llamacpp")
+
+	// Agent defaults
+	m.v.SetDefault("agents.max_call_depth", 8)
+	m.v.SetDefault("agents.call_timeout", 60)
+	m.v.SetDefault("agents.max_tool_iterations", 8)
+	m.v.SetDefault("agents.max_panics", 0)
+
+	// Chat defaults
+	m.v.SetDefault("chat.session_max_tokens", 6000)
+	m.v.SetDefault("chat.retry_budget", 10)
+	m.v.SetDefault("chat.redact_exports", true)
+
+	// Policy defaults: only ls, cat, pwd, whoami, df, and uname run unless
+	// configured otherwise. AFE_POLICY_DEFAULT,
+	// AFE_POLICY_ALLOWED_AGENTS (comma-separated), and
+	// AFE_POLICY_ALLOW_READ_ONLY override the file.
+	m.v.SetDefault("policy.default", policy.ModeDeny)
+	m.v.SetDefault("policy.allowed_agents", policy.DefaultAllowedAgents)
+	m.v.SetDefault("policy.allow_read_only", false)
+	m.v.SetDefault("policy.audit", true)
+	m.v.BindEnv("policy.default", "AFE_POLICY_DEFAULT")
+	m.v.BindEnv("policy.allowed_agents", "AFE_POLICY_ALLOWED_AGENTS")
+	m.v.BindEnv("policy.allow_read_only", "AFE_POLICY_ALLOW_READ_ONLY")
+
+	// Auth defaults: the API is open unless enabled. AFE_AUTH_TOKEN_SECRET
+	// keeps the secret out of the file.
+	m.v.SetDefault("auth.enabled", false)
+	m.v.SetDefault("auth.token_ttl", 86400)
+	m.v.BindEnv("auth.token_secret", "AFE_AUTH_TOKEN_SECRET")
+
+	// Metrics defaults
+	m.v.SetDefault("metrics.enabled", false)
 
 	// Recovery defaults
 	m.v.SetDefault("recovery.hot_reload", true)
@@ -111,6 +213,117 @@ func (m *Manager) GetAgentConfigs() []interfaces.AgentConfig {
 	return allAgents
 }
 
+// GetProviderConfigs returns the configuration of provider plugins
+func (m *Manager) GetProviderConfigs() []interfaces.ProviderConfig {
+	if m.config == nil {
+		return []interfaces.ProviderConfig{}
+	}
+	return m.config.Providers
+}
+
+// GetMaxAgentCallDepth returns how deeply cross-agent calls may nest
+func (m *Manager) GetMaxAgentCallDepth() int {
+	if m.config == nil {
+		return 0
+	}
+	return m.config.Agents.MaxCallDepth
+}
+
+// GetAgentCallTimeout returns how long an agent call made through the API
+// may run, or zero when it is not configured
+func (m *Manager) GetAgentCallTimeout() time.Duration {
+	if m.config == nil {
+		return 0
+	}
+	return time.Duration(m.config.Agents.CallTimeout) * time.Second
+}
+
+// GetPolicyConfig returns the policy agent calls are checked against
+func (m *Manager) GetPolicyConfig() policy.Config {
+	if m.config == nil {
+		return policy.DefaultConfig()
+	}
+	return m.config.Policy
+}
+
+// GetAuthConfig returns how API requests are authenticated
+func (m *Manager) GetAuthConfig() AuthConfig {
+	if m.config == nil {
+		return AuthConfig{}
+	}
+	return m.config.Auth
+}
+
+// GetMetricsConfig returns whether the API serves metrics
+func (m *Manager) GetMetricsConfig() MetricsConfig {
+	if m.config == nil {
+		return MetricsConfig{}
+	}
+	return m.config.Metrics
+}
+
+// GetSessionMaxTokens returns how many tokens of a chat session's history
+// are sent to the model
+func (m *Manager) GetSessionMaxTokens() int {
+	if m.config == nil {
+		return 0
+	}
+	return m.config.Chat.SessionMaxTokens
+}
+
+// GetRetryBudget returns how many retries the agent and model calls of one
+// chat may make in total
+func (m *Manager) GetRetryBudget() int {
+	if m.config == nil {
+		return -1
+	}
+	return m.config.Chat.RetryBudget
+}
+
+// GetExportRedaction returns whether exported chat sessions have secrets
+// redacted, and the patterns matched besides the built-in ones
+func (m *Manager) GetExportRedaction() (bool, []string) {
+	if m.config == nil {
+		return true, nil
+	}
+	return m.config.Chat.RedactExports, m.config.Chat.RedactPatterns
+}
+
+// GetMaxToolIterations returns how many times a chat may call the model while
+// the model keeps calling agents
+func (m *Manager) GetMaxToolIterations() int {
+	if m.config == nil {
+		return 0
+	}
+	return m.config.Agents.MaxToolIterations
+}
+
+// GetMaxAgentPanics returns how many panics quarantine an agent, or zero when
+// agents are never quarantined
+func (m *Manager) GetMaxAgentPanics() int {
+	if m.config == nil {
+		return 0
+	}
+	return m.config.Agents.MaxPanics
+}
+
+// GetDefaultModel returns the model used when a request does not name one
+func (m *Manager) GetDefaultModel() string {
+	if m.config == nil {
+		return ""
+	}
+	return m.config.DefaultModel
+}
+
+// GetModelAliases returns the configured model name aliases and the models
+// each one routes to
+func (m *Manager) GetModelAliases() map[string][]string {
+	if m.config == nil {
+		return map[string][]string{}
+	}
+	return m.config.ModelAliases
+}
+
 func (m *Manager) GetServerConfig() interfaces.ServerConfig {
 	if m.config == nil {
 		return interfaces.ServerConfig{
@@ -126,6 +339,9 @@ func (m *Manager) GetRecoveryConfig() interfaces.RecoveryConfig {
 }
 
 func (m *Manager) GetOrchestratorConfig() OrchestratorConfig {
+	if m.config == nil {
+		return OrchestratorConfig{}
+	}
 	return m.config.Orchestrator
 }
 
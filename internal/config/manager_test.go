@@ -3,7 +3,10 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/internal/policy"
 )
 
 func TestManager_Load(t *testing.T) {
@@ -111,3 +114,108 @@ func TestManager_Defaults(t *testing.T) {
 		t.Errorf("Expected default hot_reload true, got false")
 	}
 }
+
+func TestManager_ModelAliases(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "test.yaml")
+	configContent := `
+default_model: "qwen3"
+model_aliases:
+  fast: "llamacpp"
+  default: ["qwen3", "openai-compat"]
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config: %v", err)
+	}
+
+	manager := NewManager()
+	if err := manager.Load(configPath); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	aliases := manager.GetModelAliases()
+	if fast := aliases["fast"]; len(fast) != 1 || fast[0] != "llamacpp" {
+		t.Errorf("Expected a single model alias to load as one target, got %v", fast)
+	}
+	if route := aliases["default"]; len(route) != 2 || route[0] != "qwen3" || route[1] != "openai-compat" {
+		t.Errorf("Expected the fallback list in order, got %v", route)
+	}
+}
+
+func TestManager_ServerLimits(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "test.yaml")
+	configContent := `
+server:
+  port: 9090
+  max_request_timeout: 60
+  rate_limit:
+    requests_per_minute: 30
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config: %v", err)
+	}
+
+	manager := NewManager()
+	if err := manager.Load(configPath); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	serverConfig := manager.GetServerConfig()
+	if serverConfig.MaxRequestTimeout != 60 {
+		t.Errorf("Expected max_request_timeout 60, got %d", serverConfig.MaxRequestTimeout)
+	}
+	if serverConfig.ReadTimeout != 30 || serverConfig.WriteTimeout != 330 || serverConfig.IdleTimeout != 120 {
+		t.Errorf("Expected the default HTTP timeouts, got %+v", serverConfig)
+	}
+	if serverConfig.MaxBodyBytes != 10<<20 {
+		t.Errorf("Expected default max_body_bytes %d, got %d", 10<<20, serverConfig.MaxBodyBytes)
+	}
+	if limit := serverConfig.RateLimit; limit.RequestsPerMinute != 30 || limit.Burst != 10 {
+		t.Errorf("Expected 30 requests per minute with the default burst, got %+v", limit)
+	}
+}
+
+func TestManager_Policy(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "test.yaml")
+	configContent := `
+policy:
+  default: deny
+  agents:
+    web-agent:
+      domains: ["example.com"]
+    cat:
+      path_prefixes: ["/srv/data"]
+    rm:
+      allow: false
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config: %v", err)
+	}
+
+	manager := NewManager()
+	if err := manager.Load(configPath); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	config := manager.GetPolicyConfig()
+	if !reflect.DeepEqual(config.AllowedAgents, policy.DefaultAllowedAgents) || config.AllowReadOnly || !config.Audit {
+		t.Errorf("Expected the default agents to be allowed and auditing, got %+v", config)
+	}
+	if rule := config.Agents["web-agent"]; len(rule.Domains) != 1 || rule.Domains[0] != "example.com" {
+		t.Errorf("Expected the web-agent domains, got %+v", rule)
+	}
+	if rule := config.Agents["rm"]; rule.Allow == nil || *rule.Allow {
+		t.Errorf("Expected rm to be denied, got %+v", rule)
+	}
+
+	// The environment overrides the file
+	t.Setenv("AFE_POLICY_DEFAULT", "allow")
+	t.Setenv("AFE_POLICY_ALLOWED_AGENTS", "ls,web-agent")
+	manager = NewManager()
+	if err := manager.Load(configPath); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	config = manager.GetPolicyConfig()
+	if config.Default != "allow" || len(config.AllowedAgents) != 2 || config.AllowedAgents[1] != "web-agent" {
+		t.Errorf("Expected the environment's policy, got %+v", config)
+	}
+}
@@ -0,0 +1,107 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+// DefaultMaxCallDepth bounds nested cross-agent calls when no limit is
+// configured
+const DefaultMaxCallDepth = 8
+
+// ErrMaxCallDepth is returned when a chain of cross-agent calls, such as two
+// agents calling each other, nests deeper than the configured maximum
+var ErrMaxCallDepth = errors.New("max agent recursion depth exceeded")
+
+type callDepthKey struct{}
+
+// CallDepth returns how many cross-agent calls ctx is nested in. Requests that
+// did not come from another agent are at depth zero.
+func CallDepth(ctx context.Context) int {
+	depth, _ := ctx.Value(callDepthKey{}).(int)
+	return depth
+}
+
+// SetMaxCallDepth sets how deeply cross-agent calls may nest. Values below one
+// restore DefaultMaxCallDepth.
+func (pm *Manager) SetMaxCallDepth(depth int) {
+	if depth < 1 {
+		depth = DefaultMaxCallDepth
+	}
+	pm.maxCallDepth = depth
+}
+
+// CallAgent runs the named agent one call deeper than ctx and records the
+// call's timing and the agent's version in the output's Meta. It fails with
+// ErrMaxCallDepth instead of running the agent when that would exceed the
+// maximum depth. An agent that panics returns a failed output with
+// CodeInternal.
+func (pm *Manager) CallAgent(ctx context.Context, name string, input interfaces.AgentInput) (interfaces.AgentOutput, error) {
+	depth := CallDepth(ctx) + 1
+	if depth > pm.maxCallDepth {
+		return interfaces.AgentOutput{}, fmt.Errorf("%w: calling %s at depth %d (max %d)", ErrMaxCallDepth, name, depth, pm.maxCallDepth)
+	}
+
+	agent, exists := pm.GetAgent(name)
+	if !exists {
+		return interfaces.AgentOutput{}, fmt.Errorf("agent %s not found", name)
+	}
+
+	meta := &interfaces.CallMeta{Agent: name, StartedAt: time.Now()}
+	if versioned, ok := agent.(interfaces.Versioned); ok {
+		meta.AgentVersion = versioned.Version()
+	}
+
+	output, err := pm.processAgent(context.WithValue(ctx, callDepthKey{}, depth), name, agent, input)
+	meta.DurationMs = float64(time.Since(meta.StartedAt).Microseconds()) / 1000
+	output.Meta = meta
+
+	// Input payloads may hold secrets, so only the outcome is logged
+	callLogger := logger.WithContext(ctx)
+	switch {
+	case err != nil:
+		callLogger.Warnf("Agent %s failed after %.1fms: %v", name, meta.DurationMs, err)
+	case !output.Success:
+		callLogger.Debugf("Agent %s reported failure after %.1fms: %s", name, meta.DurationMs, output.Error)
+	default:
+		callLogger.Debugf("Agent %s succeeded in %.1fms", name, meta.DurationMs)
+	}
+	return output, err
+}
+
+// SetEventFunc sets where agents publish events and hands it to every
+// registered agent that implements interfaces.EventAware, now and when
+// agents are registered later
+func (pm *Manager) SetEventFunc(publish interfaces.EventFunc) {
+	pm.eventFunc = publish
+	for _, agent := range pm.registry {
+		if aware, ok := agent.(interfaces.EventAware); ok {
+			aware.SetEventFunc(publish)
+		}
+	}
+}
+
+// PublishEvent publishes an event through the event function, if one is set
+func (pm *Manager) PublishEvent(event map[string]interface{}) {
+	if pm.eventFunc != nil {
+		pm.eventFunc(event)
+	}
+}
+
+// registerAgent adds an agent to the registry, giving it a caller when it
+// calls other agents and the event function when it publishes events. A
+// reloaded agent starts with no panics counted against it.
+func (pm *Manager) registerAgent(name string, agent interfaces.Agent) {
+	pm.resetPanics(name)
+	if aware, ok := agent.(interfaces.CallerAware); ok {
+		aware.SetAgentCaller(pm)
+	}
+	if aware, ok := agent.(interfaces.EventAware); ok && pm.eventFunc != nil {
+		aware.SetEventFunc(pm.eventFunc)
+	}
+	pm.registry[name] = agent
+}
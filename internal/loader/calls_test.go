@@ -0,0 +1,155 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+// relayAgent forwards each request to another agent, or reports its call
+// depth when it has nowhere to forward to
+type relayAgent struct {
+	name   string
+	next   string
+	caller interfaces.AgentCaller
+	calls  int
+}
+
+func (ra *relayAgent) Name() string                                   { return ra.name }
+func (ra *relayAgent) Initialize(config map[string]interface{}) error { return nil }
+func (ra *relayAgent) HealthCheck() error                             { return nil }
+func (ra *relayAgent) Shutdown() error                                { return nil }
+func (ra *relayAgent) SetAgentCaller(caller interfaces.AgentCaller)   { ra.caller = caller }
+
+func (ra *relayAgent) Process(ctx context.Context, input interfaces.AgentInput) (interfaces.AgentOutput, error) {
+	ra.calls++
+	if ra.next == "" {
+		return interfaces.AgentOutput{Success: true, Data: map[string]interface{}{"depth": CallDepth(ctx)}}, nil
+	}
+	return ra.caller.CallAgent(ctx, ra.next, input)
+}
+
+func TestManager_CallAgentRecursionLimit(t *testing.T) {
+	manager := NewManager(t.TempDir(), t.TempDir())
+	manager.SetMaxCallDepth(5)
+
+	ping := &relayAgent{name: "ping", next: "pong"}
+	pong := &relayAgent{name: "pong", next: "ping"}
+	manager.AddAgentToRegistry("ping", ping)
+	manager.AddAgentToRegistry("pong", pong)
+
+	if ping.caller == nil || pong.caller == nil {
+		t.Fatal("Expected agents to receive a caller when registered")
+	}
+
+	_, err := manager.CallAgent(context.Background(), "ping", interfaces.AgentInput{})
+	if !errors.Is(err, ErrMaxCallDepth) {
+		t.Fatalf("Expected ErrMaxCallDepth, got %v", err)
+	}
+	if ping.calls+pong.calls != 5 {
+		t.Errorf("Expected 5 calls before the guard fired, got %d", ping.calls+pong.calls)
+	}
+}
+
+func TestManager_CallAgentChain(t *testing.T) {
+	manager := NewManager(t.TempDir(), t.TempDir())
+	manager.SetMaxCallDepth(3)
+
+	manager.AddAgentToRegistry("first", &relayAgent{name: "first", next: "second"})
+	manager.AddAgentToRegistry("second", &relayAgent{name: "second", next: "last"})
+	manager.AddAgentToRegistry("last", &relayAgent{name: "last"})
+
+	// A chain exactly as deep as the limit still runs
+	output, err := manager.CallAgent(context.Background(), "first", interfaces.AgentInput{})
+	if err != nil || output.Data["depth"] != 3 {
+		t.Fatalf("Expected the last agent to run at depth 3, got %v, %v", output.Data, err)
+	}
+
+	if _, err := manager.CallAgent(context.Background(), "missing", interfaces.AgentInput{}); err == nil {
+		t.Error("Expected an error for an unknown agent")
+	}
+
+	// Non-positive limits fall back to the default
+	manager.SetMaxCallDepth(0)
+	if manager.maxCallDepth != DefaultMaxCallDepth {
+		t.Errorf("Expected default depth %d, got %d", DefaultMaxCallDepth, manager.maxCallDepth)
+	}
+}
+
+// eventAgent records the event function it is given
+type eventAgent struct {
+	relayAgent
+	publish interfaces.EventFunc
+}
+
+func (ea *eventAgent) SetEventFunc(publish interfaces.EventFunc) { ea.publish = publish }
+
+func TestManager_SetEventFunc(t *testing.T) {
+	manager := NewManager(t.TempDir(), t.TempDir())
+
+	var events []string
+	publish := func(event map[string]interface{}) { events = append(events, event["type"].(string)) }
+
+	// Agents registered before and after the event function is set get it
+	before := &eventAgent{relayAgent: relayAgent{name: "before"}}
+	manager.AddAgentToRegistry("before", before)
+	manager.SetEventFunc(publish)
+	after := &eventAgent{relayAgent: relayAgent{name: "after"}}
+	manager.AddAgentToRegistry("after", after)
+
+	if before.publish == nil || after.publish == nil {
+		t.Fatal("Expected both agents to receive the event function")
+	}
+	before.publish(map[string]interface{}{"type": "one"})
+	after.publish(map[string]interface{}{"type": "two"})
+	if len(events) != 2 || events[0] != "one" || events[1] != "two" {
+		t.Errorf("Expected events to reach the manager's function, got %v", events)
+	}
+}
+
+// versionedAgent is a relayAgent that reports a version and a warning
+type versionedAgent struct {
+	relayAgent
+}
+
+func (va *versionedAgent) Version() string { return "1.2.0" }
+
+func (va *versionedAgent) Process(ctx context.Context, input interfaces.AgentInput) (interfaces.AgentOutput, error) {
+	time.Sleep(2 * time.Millisecond)
+	return interfaces.AgentOutput{Success: true, Warnings: []string{"output truncated"}}, nil
+}
+
+func TestManager_CallAgentMeta(t *testing.T) {
+	manager := NewManager(t.TempDir(), t.TempDir())
+	manager.AddAgentToRegistry("echo", &versionedAgent{relayAgent{name: "echo"}})
+	manager.AddAgentToRegistry("plain", &relayAgent{name: "plain"})
+
+	before := time.Now()
+	output, err := manager.CallAgent(context.Background(), "echo", interfaces.AgentInput{})
+	if err != nil {
+		t.Fatalf("CallAgent failed: %v", err)
+	}
+
+	meta := output.Meta
+	if meta == nil {
+		t.Fatal("Expected the call's meta to be set")
+	}
+	if meta.Agent != "echo" || meta.AgentVersion != "1.2.0" {
+		t.Errorf("Expected echo 1.2.0, got %s %s", meta.Agent, meta.AgentVersion)
+	}
+	if meta.DurationMs < 2 || meta.StartedAt.Before(before) {
+		t.Errorf("Expected a duration of at least 2ms from the call's start, got %vms at %v", meta.DurationMs, meta.StartedAt)
+	}
+	if len(output.Warnings) != 1 || output.Warnings[0] != "output truncated" {
+		t.Errorf("Expected the agent's warning to be kept, got %v", output.Warnings)
+	}
+
+	// Agents without a version still get timing
+	output, _ = manager.CallAgent(context.Background(), "plain", interfaces.AgentInput{})
+	if output.Meta == nil || output.Meta.Agent != "plain" || output.Meta.AgentVersion != "" {
+		t.Errorf("Expected meta without a version, got %+v", output.Meta)
+	}
+}
@@ -8,17 +8,28 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
-	"plugin"
 	"runtime"
+	"sync"
 
+	"github.com/AgentForgeEngine/AgentForgeEngine/internal/logging"
 	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
 )
 
+// logger writes the loader's records to the engine's log buffer
+var logger = logging.New("loader")
+
 type Manager struct {
-	registry   map[string]interfaces.Agent
-	providers  map[string]interfaces.Provider
-	pluginsDir string
-	tempDir    string
+	registry     map[string]interfaces.Agent
+	providers    map[string]interfaces.Provider
+	pluginsDir   string
+	tempDir      string
+	maxCallDepth int
+	eventFunc    interfaces.EventFunc
+
+	// panicMu guards the panic counts of agents
+	panicMu   sync.Mutex
+	panics    map[string]int
+	maxPanics int
 }
 
 func NewManager(pluginsDir, tempDir string) *Manager {
@@ -30,10 +41,12 @@ func NewManager(pluginsDir, tempDir string) *Manager {
 	}
 
 	return &Manager{
-		registry:   make(map[string]interfaces.Agent),
-		providers:  make(map[string]interfaces.Provider),
-		pluginsDir: pluginsDir,
-		tempDir:    tempDir,
+		registry:     make(map[string]interfaces.Agent),
+		providers:    make(map[string]interfaces.Provider),
+		pluginsDir:   pluginsDir,
+		tempDir:      tempDir,
+		maxCallDepth: DefaultMaxCallDepth,
+		panics:       make(map[string]int),
 	}
 }
 
@@ -132,7 +145,7 @@ func (pm *Manager) UnloadProvider(name string) error {
 
 	provider := pm.providers[name]
 	if err := provider.Shutdown(); err != nil {
-		fmt.Printf("Error shutting down provider %s: %v", name, err)
+		logger.Warnf("Error shutting down provider %s: %v", name, err)
 	}
 
 	delete(pm.providers, name)
@@ -161,7 +174,7 @@ func (pm *Manager) AddProviderToRegistry(name string, provider interfaces.Provid
 
 // AddAgentToRegistry adds an agent to the registry (for hot reload)
 func (pm *Manager) AddAgentToRegistry(name string, agent interfaces.Agent) {
-	pm.registry[name] = agent
+	pm.registerAgent(name, agent)
 }
 
 func (pm *Manager) buildPlugin(source, output string) error {
@@ -189,7 +202,7 @@ func (pm *Manager) buildPlugin(source, output string) error {
 
 func (pm *Manager) loadPlugin(path, name string) error {
 	// Open the plugin
-	p, err := plugin.Open(path)
+	p, err := openPlugin(path)
 	if err != nil {
 		return fmt.Errorf("failed to open plugin: %w", err)
 	}
@@ -200,8 +213,8 @@ func (pm *Manager) loadPlugin(path, name string) error {
 		// Type assert to Agent interface
 		if agent, ok := symAgent.(interfaces.Agent); ok {
 			// Register the agent
-			pm.registry[name] = agent
-			fmt.Printf("Successfully loaded agent: %s", name)
+			pm.registerAgent(name, agent)
+			logger.Infof("Successfully loaded agent: %s", name)
 			return nil
 		}
 		return fmt.Errorf("invalid Agent type in plugin")
@@ -214,14 +227,14 @@ func (pm *Manager) loadPlugin(path, name string) error {
 	}
 
 	// Type assert to Provider interface
-	provider, ok := symProvider.(interfaces.Provider)
-	if !ok {
-		return fmt.Errorf("invalid Provider type in plugin")
+	provider, err := providerFromSymbol(symProvider)
+	if err != nil {
+		return err
 	}
 
 	// Register the provider
 	pm.providers[name] = provider
-	fmt.Printf("Successfully loaded provider: %s", name)
+	logger.Infof("Successfully loaded provider: %s", name)
 	return nil
 }
 
@@ -0,0 +1,123 @@
+package loader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"plugin"
+	"strings"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+// ProviderRegistry receives providers once they are loaded and initialized.
+// The model manager implements it to serve providers as models.
+type ProviderRegistry interface {
+	RegisterProvider(name string, provider interfaces.Provider)
+}
+
+// symbolLookup is the part of *plugin.Plugin the loader uses
+type symbolLookup interface {
+	Lookup(symName string) (plugin.Symbol, error)
+}
+
+// openPlugin opens a built plugin; tests replace it to load stub plugins
+var openPlugin = func(path string) (symbolLookup, error) {
+	return plugin.Open(path)
+}
+
+// DiscoverProviders loads every provider plugin (*.so) in dir, initializes
+// it from its entry in configs, adds it to the provider registry, and
+// registers it with registry when registry is not nil. A plugin is named by
+// its configured name, or by its file name without .so when it has no entry.
+//
+// A provider that fails to load or initialize does not stop the others; its
+// error is returned keyed by name. A missing dir means there is nothing to
+// discover.
+func (pm *Manager) DiscoverProviders(dir string, configs []interfaces.ProviderConfig, registry ProviderRegistry) map[string]error {
+	failures := make(map[string]error)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			failures[dir] = fmt.Errorf("failed to read providers directory: %w", err)
+		}
+		return failures
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".so" {
+			continue
+		}
+
+		stem := strings.TrimSuffix(entry.Name(), ".so")
+		name, config := stem, map[string]interface{}{}
+		if providerConfig, ok := findProviderConfig(configs, stem); ok {
+			name = providerConfig.Name
+			if providerConfig.Config != nil {
+				config = providerConfig.Config
+			}
+		}
+
+		provider, err := loadProvider(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			failures[name] = err
+			continue
+		}
+		if err := provider.Initialize(config); err != nil {
+			failures[name] = fmt.Errorf("failed to initialize provider: %w", err)
+			continue
+		}
+
+		pm.providers[name] = provider
+		if registry != nil {
+			registry.RegisterProvider(name, provider)
+		}
+	}
+
+	return failures
+}
+
+// findProviderConfig returns the entry for the plugin built as stem.so,
+// matching the base name of the entry's path first and its name second
+func findProviderConfig(configs []interfaces.ProviderConfig, stem string) (interfaces.ProviderConfig, bool) {
+	for _, config := range configs {
+		if config.Path != "" && filepath.Base(config.Path) == stem {
+			return config, true
+		}
+	}
+	for _, config := range configs {
+		if config.Name == stem {
+			return config, true
+		}
+	}
+	return interfaces.ProviderConfig{}, false
+}
+
+// loadProvider opens a plugin and returns its exported Provider
+func loadProvider(path string) (interfaces.Provider, error) {
+	p, err := openPlugin(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open plugin: %w", err)
+	}
+
+	symProvider, err := p.Lookup("Provider")
+	if err != nil {
+		return nil, fmt.Errorf("plugin missing Provider symbol: %w", err)
+	}
+	return providerFromSymbol(symProvider)
+}
+
+// providerFromSymbol asserts a plugin's Provider symbol, which is a pointer
+// to the exported variable, to interfaces.Provider
+func providerFromSymbol(sym plugin.Symbol) (interfaces.Provider, error) {
+	switch provider := sym.(type) {
+	case *interfaces.Provider:
+		if *provider != nil {
+			return *provider, nil
+		}
+	case interfaces.Provider:
+		return provider, nil
+	}
+	return nil, fmt.Errorf("invalid Provider type in plugin")
+}
@@ -0,0 +1,111 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"plugin"
+	"testing"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/internal/models"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+// stubPlugin stands in for an opened plugin, exporting symbols by name
+type stubPlugin map[string]plugin.Symbol
+
+func (p stubPlugin) Lookup(symName string) (plugin.Symbol, error) {
+	if sym, ok := p[symName]; ok {
+		return sym, nil
+	}
+	return nil, fmt.Errorf("symbol %s not found", symName)
+}
+
+// stubPlugins replaces openPlugin with one serving plugins by file name
+func stubPlugins(t *testing.T, plugins map[string]stubPlugin) {
+	t.Helper()
+	original := openPlugin
+	openPlugin = func(path string) (symbolLookup, error) {
+		if p, ok := plugins[filepath.Base(path)]; ok {
+			return p, nil
+		}
+		return nil, fmt.Errorf("not a plugin: %s", path)
+	}
+	t.Cleanup(func() { openPlugin = original })
+}
+
+type stubProvider struct {
+	config  map[string]interface{}
+	initErr error
+}
+
+func (sp *stubProvider) Name() string { return "stub" }
+func (sp *stubProvider) Initialize(config map[string]interface{}) error {
+	sp.config = config
+	return sp.initErr
+}
+func (sp *stubProvider) Generate(ctx context.Context, input interfaces.GenerationRequest) (*interfaces.GenerationResponse, error) {
+	return &interfaces.GenerationResponse{Text: fmt.Sprintf("%v: %s", sp.config["greeting"], input.Prompt), Finished: true}, nil
+}
+func (sp *stubProvider) HealthCheck() error { return nil }
+func (sp *stubProvider) Shutdown() error    { return nil }
+
+func TestManager_DiscoverProviders(t *testing.T) {
+	tmpDir := t.TempDir()
+	providersDir := filepath.Join(tmpDir, "providers")
+	if err := os.MkdirAll(providersDir, 0755); err != nil {
+		t.Fatalf("Failed to create providers directory: %v", err)
+	}
+	for _, file := range []string{"stub.so", "broken.so", "no-symbol.so", "failing.so", "README.md"} {
+		if err := os.WriteFile(filepath.Join(providersDir, file), nil, 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", file, err)
+		}
+	}
+
+	// Plugins export a pointer to their Provider variable
+	var stub interfaces.Provider = &stubProvider{}
+	var failing interfaces.Provider = &stubProvider{initErr: errors.New("no endpoint")}
+	stubPlugins(t, map[string]stubPlugin{
+		"stub.so":      {"Provider": &stub},
+		"no-symbol.so": {"Agent": &stub},
+		"failing.so":   {"Provider": &failing},
+	})
+
+	manager := NewManager(filepath.Join(tmpDir, "plugins"), filepath.Join(tmpDir, "temp"))
+	modelManager := models.NewManager()
+	failures := manager.DiscoverProviders(providersDir, []interfaces.ProviderConfig{
+		{Name: "stub-model", Path: "./providers/stub", Config: map[string]interface{}{"greeting": "hello"}},
+	}, modelManager)
+
+	// Each bad plugin is reported without stopping the others
+	for _, name := range []string{"broken", "no-symbol", "failing"} {
+		if failures[name] == nil {
+			t.Errorf("Expected a failure for %s, got %v", name, failures)
+		}
+	}
+	if len(failures) != 3 {
+		t.Errorf("Expected 3 failures, got %v", failures)
+	}
+
+	// The stub is registered under its configured name and initialized from
+	// its config
+	if _, ok := manager.GetProvider("stub-model"); !ok {
+		t.Fatalf("Expected stub-model to be registered, got %v", manager.ListProviders())
+	}
+	response, err := modelManager.Generate(context.Background(), "stub-model", interfaces.GenerationRequest{Prompt: "world"})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if response.Text != "hello: world" {
+		t.Errorf("Expected the configured provider to generate, got %q", response.Text)
+	}
+}
+
+func TestManager_DiscoverProvidersMissingDir(t *testing.T) {
+	manager := NewManager(t.TempDir(), t.TempDir())
+	if failures := manager.DiscoverProviders(filepath.Join(t.TempDir(), "missing"), nil, nil); len(failures) != 0 {
+		t.Errorf("Expected no failures for a missing directory, got %v", failures)
+	}
+}
@@ -0,0 +1,168 @@
+package loader
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ShutdownPhase determines when a component is shut down relative to others.
+// Phases run in ascending order so that work stops arriving before the
+// components doing that work, and connections close before caches are flushed.
+type ShutdownPhase int
+
+const (
+	// PhaseStopAccepting stops servers and listeners from accepting new work
+	PhaseStopAccepting ShutdownPhase = iota
+	// PhaseCancelTasks cancels or finishes work that is already running (agents)
+	PhaseCancelTasks
+	// PhaseCloseProviders closes provider and model connections
+	PhaseCloseProviders
+	// PhaseFlushCaches flushes caches and persists state
+	PhaseFlushCaches
+)
+
+func (p ShutdownPhase) String() string {
+	switch p {
+	case PhaseStopAccepting:
+		return "stop-accepting"
+	case PhaseCancelTasks:
+		return "cancel-tasks"
+	case PhaseCloseProviders:
+		return "close-providers"
+	case PhaseFlushCaches:
+		return "flush-caches"
+	default:
+		return fmt.Sprintf("phase-%d", int(p))
+	}
+}
+
+// Shutdowner is implemented by every component that takes part in shutdown
+type Shutdowner interface {
+	Shutdown() error
+}
+
+// ShutdownFunc adapts a plain function to the Shutdowner interface
+type ShutdownFunc func() error
+
+// Shutdown calls f
+func (f ShutdownFunc) Shutdown() error {
+	return f()
+}
+
+// ComponentError records a failed shutdown of a single component
+type ComponentError struct {
+	Phase     ShutdownPhase
+	Component string
+	Err       error
+}
+
+// ShutdownError collects every component that failed to shut down
+type ShutdownError struct {
+	Failures []ComponentError
+}
+
+func (e *ShutdownError) Error() string {
+	messages := make([]string, len(e.Failures))
+	for i, failure := range e.Failures {
+		messages[i] = fmt.Sprintf("%s (%s): %v", failure.Component, failure.Phase, failure.Err)
+	}
+	return fmt.Sprintf("%d components failed to shut down: %s", len(e.Failures), strings.Join(messages, "; "))
+}
+
+type shutdownEntry struct {
+	phase     ShutdownPhase
+	name      string
+	component Shutdowner
+	order     int
+}
+
+// ShutdownSequence shuts registered components down in phase order
+type ShutdownSequence struct {
+	entries []shutdownEntry
+	mu      sync.Mutex
+}
+
+// NewShutdownSequence creates an empty shutdown sequence
+func NewShutdownSequence() *ShutdownSequence {
+	return &ShutdownSequence{}
+}
+
+// Register adds a component to the given phase. Components in the same phase
+// are shut down in registration order.
+func (s *ShutdownSequence) Register(phase ShutdownPhase, name string, component Shutdowner) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries = append(s.entries, shutdownEntry{
+		phase:     phase,
+		name:      name,
+		component: component,
+		order:     len(s.entries),
+	})
+}
+
+// Shutdown shuts every registered component down, continuing past failures.
+// It returns a *ShutdownError listing each component that failed, or nil.
+func (s *ShutdownSequence) Shutdown() error {
+	s.mu.Lock()
+	entries := make([]shutdownEntry, len(s.entries))
+	copy(entries, s.entries)
+	s.mu.Unlock()
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].phase != entries[j].phase {
+			return entries[i].phase < entries[j].phase
+		}
+		return entries[i].order < entries[j].order
+	})
+
+	var failures []ComponentError
+	for _, entry := range entries {
+		if err := shutdownComponent(entry.component); err != nil {
+			logger.Errorf("Shutdown of %s (%s) failed: %v", entry.name, entry.phase, err)
+			failures = append(failures, ComponentError{
+				Phase:     entry.phase,
+				Component: entry.name,
+				Err:       err,
+			})
+		}
+	}
+
+	if len(failures) > 0 {
+		return &ShutdownError{Failures: failures}
+	}
+	return nil
+}
+
+// shutdownComponent calls Shutdown, converting a panic into an error so one
+// misbehaving plugin cannot stop the rest of the sequence
+func shutdownComponent(component Shutdowner) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic during shutdown: %v", r)
+		}
+	}()
+	return component.Shutdown()
+}
+
+// ShutdownAgents shuts every loaded agent down, continuing past failures,
+// and returns a *ShutdownError listing those that failed, or nil. Providers
+// are left to whoever serves them.
+func (pm *Manager) ShutdownAgents() error {
+	seq := NewShutdownSequence()
+	for _, name := range sortedKeys(pm.registry) {
+		seq.Register(PhaseCancelTasks, "agent:"+name, pm.registry[name])
+	}
+	return seq.Shutdown()
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,77 @@
+package loader
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// stubComponent records the order in which it was shut down
+type stubComponent struct {
+	name  string
+	calls *[]string
+	err   error
+}
+
+func (sc *stubComponent) Shutdown() error {
+	*sc.calls = append(*sc.calls, sc.name)
+	return sc.err
+}
+
+func TestShutdownSequence_Order(t *testing.T) {
+	var calls []string
+	seq := NewShutdownSequence()
+
+	// Register out of order to verify phases, not registration, decide the order
+	seq.Register(PhaseFlushCaches, "cache", &stubComponent{name: "cache", calls: &calls})
+	seq.Register(PhaseCloseProviders, "provider", &stubComponent{name: "provider", calls: &calls})
+	seq.Register(PhaseCancelTasks, "task-agent", &stubComponent{name: "task-agent", calls: &calls})
+	seq.Register(PhaseStopAccepting, "api", &stubComponent{name: "api", calls: &calls})
+	seq.Register(PhaseCancelTasks, "ls", &stubComponent{name: "ls", calls: &calls})
+
+	if err := seq.Shutdown(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	expected := "api,task-agent,ls,provider,cache"
+	if got := strings.Join(calls, ","); got != expected {
+		t.Errorf("Expected shutdown order %s, got %s", expected, got)
+	}
+}
+
+func TestShutdownSequence_ContinuesAfterErrors(t *testing.T) {
+	var calls []string
+	seq := NewShutdownSequence()
+
+	seq.Register(PhaseStopAccepting, "api", &stubComponent{name: "api", calls: &calls, err: fmt.Errorf("listener busy")})
+	seq.Register(PhaseCancelTasks, "agent", &stubComponent{name: "agent", calls: &calls})
+	seq.Register(PhaseCloseProviders, "provider", &stubComponent{name: "provider", calls: &calls, err: fmt.Errorf("connection reset")})
+	seq.Register(PhaseCloseProviders, "panicky", ShutdownFunc(func() error {
+		calls = append(calls, "panicky")
+		panic("boom")
+	}))
+	seq.Register(PhaseFlushCaches, "cache", &stubComponent{name: "cache", calls: &calls})
+
+	err := seq.Shutdown()
+	if len(calls) != 5 {
+		t.Fatalf("Expected all 5 components to be shut down, got %v", calls)
+	}
+
+	var shutdownErr *ShutdownError
+	if !errors.As(err, &shutdownErr) {
+		t.Fatalf("Expected *ShutdownError, got: %v", err)
+	}
+
+	if len(shutdownErr.Failures) != 3 {
+		t.Fatalf("Expected 3 failures, got %d: %v", len(shutdownErr.Failures), err)
+	}
+
+	failed := []string{}
+	for _, failure := range shutdownErr.Failures {
+		failed = append(failed, failure.Component)
+	}
+	if got := strings.Join(failed, ","); got != "api,provider,panicky" {
+		t.Errorf("Expected failures for api,provider,panicky, got %s", got)
+	}
+}
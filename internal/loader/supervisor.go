@@ -0,0 +1,81 @@
+package loader
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+// Codes of the failed outputs the manager returns in place of an agent's own
+const (
+	// CodeInternal marks the output of a call whose agent panicked
+	CodeInternal = "INTERNAL"
+	// CodeQuarantined marks a call refused because its agent was quarantined
+	// after panicking too often
+	CodeQuarantined = "QUARANTINED"
+)
+
+// SetMaxAgentPanics quarantines agents once they have panicked max times:
+// their calls then fail with CodeQuarantined without running them until the
+// agent is registered again, as on a hot reload. Zero never quarantines.
+func (pm *Manager) SetMaxAgentPanics(max int) {
+	pm.panicMu.Lock()
+	defer pm.panicMu.Unlock()
+	if max < 0 {
+		max = 0
+	}
+	pm.maxPanics = max
+}
+
+// AgentPanics returns how often the named agent panicked since it was
+// registered and whether it is quarantined
+func (pm *Manager) AgentPanics(name string) (count int, quarantined bool) {
+	pm.panicMu.Lock()
+	defer pm.panicMu.Unlock()
+	count = pm.panics[name]
+	return count, pm.maxPanics > 0 && count >= pm.maxPanics
+}
+
+// processAgent runs an agent's Process, turning a panic into a failed output
+// with CodeInternal so that one broken plugin cannot take the server down
+func (pm *Manager) processAgent(ctx context.Context, name string, agent interfaces.Agent, input interfaces.AgentInput) (output interfaces.AgentOutput, err error) {
+	if count, quarantined := pm.AgentPanics(name); quarantined {
+		return interfaces.AgentOutput{
+			Success: false,
+			Error:   fmt.Sprintf("agent %s is quarantined after %d panics", name, count),
+			Code:    CodeQuarantined,
+		}, nil
+	}
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			logger.WithContext(ctx).Errorf("Agent %s panicked: %v\n%s", name, recovered, debug.Stack())
+			pm.recordPanic(name)
+			output = interfaces.AgentOutput{
+				Success: false,
+				Error:   fmt.Sprintf("agent %s panicked: %v", name, recovered),
+				Code:    CodeInternal,
+			}
+			err = nil
+		}
+	}()
+	return agent.Process(ctx, input)
+}
+
+func (pm *Manager) recordPanic(name string) {
+	pm.panicMu.Lock()
+	defer pm.panicMu.Unlock()
+	pm.panics[name]++
+	if pm.maxPanics > 0 && pm.panics[name] == pm.maxPanics {
+		logger.Warnf("Agent %s quarantined after %d panics", name, pm.panics[name])
+	}
+}
+
+// resetPanics forgets the panics of an agent, releasing it from quarantine
+func (pm *Manager) resetPanics(name string) {
+	pm.panicMu.Lock()
+	defer pm.panicMu.Unlock()
+	delete(pm.panics, name)
+}
@@ -0,0 +1,63 @@
+package loader
+
+import (
+	"context"
+	"testing"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+// panicAgent panics on every call, as a plugin with a nil-map write would
+type panicAgent struct {
+	relayAgent
+}
+
+func (pa *panicAgent) Process(ctx context.Context, input interfaces.AgentInput) (interfaces.AgentOutput, error) {
+	pa.calls++
+	var counts map[string]int
+	counts["calls"]++
+	return interfaces.AgentOutput{Success: true}, nil
+}
+
+func TestManager_CallAgentRecoversPanics(t *testing.T) {
+	manager := NewManager(t.TempDir(), t.TempDir())
+	manager.SetMaxAgentPanics(2)
+	broken := &panicAgent{relayAgent{name: "broken"}}
+	manager.AddAgentToRegistry("broken", broken)
+
+	for i := 0; i < 2; i++ {
+		output, err := manager.CallAgent(context.Background(), "broken", interfaces.AgentInput{})
+		if err != nil || output.Success || output.Code != CodeInternal || output.Meta == nil {
+			t.Fatalf("call %d: expected a failed output with %s, got %+v, %v", i+1, CodeInternal, output, err)
+		}
+	}
+
+	// The second panic quarantines the agent, which is no longer run
+	output, err := manager.CallAgent(context.Background(), "broken", interfaces.AgentInput{})
+	if err != nil || output.Code != CodeQuarantined || broken.calls != 2 {
+		t.Fatalf("Expected the quarantined agent not to run, got %+v, %v after %d calls", output, err, broken.calls)
+	}
+	if count, quarantined := manager.AgentPanics("broken"); count != 2 || !quarantined {
+		t.Errorf("Expected 2 panics and quarantine, got %d, %v", count, quarantined)
+	}
+
+	// Reloading the agent releases it
+	manager.AddAgentToRegistry("broken", broken)
+	if output, _ := manager.CallAgent(context.Background(), "broken", interfaces.AgentInput{}); output.Code != CodeInternal {
+		t.Errorf("Expected the reloaded agent to run again, got %+v", output)
+	}
+}
+
+func TestManager_NoQuarantineByDefault(t *testing.T) {
+	manager := NewManager(t.TempDir(), t.TempDir())
+	manager.AddAgentToRegistry("broken", &panicAgent{relayAgent{name: "broken"}})
+
+	for i := 0; i < 5; i++ {
+		if output, _ := manager.CallAgent(context.Background(), "broken", interfaces.AgentInput{}); output.Code != CodeInternal {
+			t.Fatalf("call %d: expected %s, got %+v", i+1, CodeInternal, output)
+		}
+	}
+	if count, quarantined := manager.AgentPanics("broken"); count != 5 || quarantined {
+		t.Errorf("Expected 5 panics without quarantine, got %d, %v", count, quarantined)
+	}
+}
@@ -0,0 +1,297 @@
+// Package logging keeps the engine's recent log records in a bounded
+// in-memory buffer, with their level, component, and request ID, so the API
+// can show what the engine has been doing. Records are also written to the
+// standard logger's output as before.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level is the severity of a record
+type Level string
+
+const (
+	LevelDebug Level = "debug"
+	LevelInfo  Level = "info"
+	LevelWarn  Level = "warn"
+	LevelError Level = "error"
+)
+
+var levelSeverity = map[Level]int{LevelDebug: 0, LevelInfo: 1, LevelWarn: 2, LevelError: 3}
+
+// ParseLevel reads a level name, accepting "warning" for warn
+func ParseLevel(name string) (Level, error) {
+	level := Level(strings.ToLower(name))
+	if level == "warning" {
+		level = LevelWarn
+	}
+	if _, ok := levelSeverity[level]; !ok {
+		return "", fmt.Errorf("unknown log level %q: must be debug, info, warn, or error", name)
+	}
+	return level, nil
+}
+
+// AtLeast reports whether l is as severe as min
+func (l Level) AtLeast(min Level) bool {
+	return levelSeverity[l] >= levelSeverity[min]
+}
+
+// Record is one log line
+type Record struct {
+	Time      time.Time `json:"timestamp"`
+	Level     Level     `json:"level"`
+	Component string    `json:"component"`
+	Message   string    `json:"message"`
+	RequestID string    `json:"request_id,omitempty"`
+}
+
+// DefaultCapacity is how many records the default buffer keeps
+const DefaultCapacity = 1000
+
+// Buffer keeps the most recent records, overwriting the oldest once it is
+// full. It is safe for concurrent use.
+type Buffer struct {
+	mu      sync.Mutex
+	records []Record
+	// next is where the next record goes; the buffer is full once it wraps
+	next int
+	full bool
+
+	subscribers map[int]func(Record)
+	nextSub     int
+}
+
+// NewBuffer returns a buffer keeping capacity records; values below one keep
+// DefaultCapacity
+func NewBuffer(capacity int) *Buffer {
+	if capacity < 1 {
+		capacity = DefaultCapacity
+	}
+	return &Buffer{records: make([]Record, capacity), subscribers: make(map[int]func(Record))}
+}
+
+// Add stores a record and passes it to the subscribers
+func (b *Buffer) Add(record Record) {
+	b.mu.Lock()
+	b.records[b.next] = record
+	b.next = (b.next + 1) % len(b.records)
+	if b.next == 0 {
+		b.full = true
+	}
+	subscribers := make([]func(Record), 0, len(b.subscribers))
+	for _, fn := range b.subscribers {
+		subscribers = append(subscribers, fn)
+	}
+	b.mu.Unlock()
+
+	// Subscribers run outside the lock so they may log themselves
+	for _, fn := range subscribers {
+		fn(record)
+	}
+}
+
+// Subscribe calls fn with every record added from now on, until the
+// returned function is called. fn must not block.
+func (b *Buffer) Subscribe(fn func(Record)) (cancel func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := b.nextSub
+	b.nextSub++
+	b.subscribers[id] = fn
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subscribers, id)
+	}
+}
+
+// Filter selects records. Zero fields match everything.
+type Filter struct {
+	// MinLevel drops records less severe than it
+	MinLevel  Level
+	Component string
+	// Since drops records logged before it
+	Since time.Time
+	// Limit bounds how many records are returned
+	Limit int
+}
+
+// Match reports whether a record passes the filter, ignoring its limit
+func (f Filter) Match(record Record) bool {
+	if f.MinLevel != "" && !record.Level.AtLeast(f.MinLevel) {
+		return false
+	}
+	if f.Component != "" && record.Component != f.Component {
+		return false
+	}
+	return f.Since.IsZero() || !record.Time.Before(f.Since)
+}
+
+// Query returns the records matching filter, newest first
+func (b *Buffer) Query(filter Filter) []Record {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	count := b.next
+	if b.full {
+		count = len(b.records)
+	}
+
+	matched := []Record{}
+	for i := 1; i <= count; i++ {
+		record := b.records[(b.next-i+len(b.records))%len(b.records)]
+		if !filter.Match(record) {
+			continue
+		}
+		matched = append(matched, record)
+		if filter.Limit > 0 && len(matched) == filter.Limit {
+			break
+		}
+	}
+	return matched
+}
+
+// Capacity returns how many records the buffer keeps
+func (b *Buffer) Capacity() int {
+	return len(b.records)
+}
+
+var (
+	defaultBuffer = NewBuffer(DefaultCapacity)
+	// output is where records are printed: the standard logger's output
+	// before CaptureStandardLog teed it into the buffer
+	outputMu sync.Mutex
+	output   io.Writer
+)
+
+// Default returns the buffer the engine's loggers write to
+func Default() *Buffer {
+	return defaultBuffer
+}
+
+// Logger writes records for one component to a buffer and prints those of
+// info level and above with the standard logger's format
+type Logger struct {
+	buffer    *Buffer
+	component string
+	requestID string
+}
+
+// New returns a logger for component writing to the default buffer
+func New(component string) *Logger {
+	return &Logger{buffer: defaultBuffer, component: component}
+}
+
+// NewWithBuffer returns a logger for component writing to buffer
+func NewWithBuffer(buffer *Buffer, component string) *Logger {
+	return &Logger{buffer: buffer, component: component}
+}
+
+// WithContext returns a logger that tags its records with the request ID of
+// ctx, if it has one
+func (l *Logger) WithContext(ctx context.Context) *Logger {
+	id := RequestIDFromContext(ctx)
+	if id == "" {
+		return l
+	}
+	tagged := *l
+	tagged.requestID = id
+	return &tagged
+}
+
+func (l *Logger) Debugf(format string, args ...interface{}) { l.logf(LevelDebug, format, args...) }
+func (l *Logger) Infof(format string, args ...interface{})  { l.logf(LevelInfo, format, args...) }
+func (l *Logger) Warnf(format string, args ...interface{})  { l.logf(LevelWarn, format, args...) }
+func (l *Logger) Errorf(format string, args ...interface{}) { l.logf(LevelError, format, args...) }
+
+func (l *Logger) logf(level Level, format string, args ...interface{}) {
+	record := Record{
+		Time:      time.Now().UTC(),
+		Level:     level,
+		Component: l.component,
+		Message:   fmt.Sprintf(format, args...),
+		RequestID: l.requestID,
+	}
+	if level.AtLeast(LevelInfo) {
+		printRecord(record)
+	}
+	l.buffer.Add(record)
+}
+
+// printRecord writes a record the way the standard logger would, to its
+// output from before it was teed so the record is not buffered twice
+func printRecord(record Record) {
+	outputMu.Lock()
+	out := output
+	outputMu.Unlock()
+	if out == nil {
+		out = log.Writer()
+	}
+
+	prefix := ""
+	if record.Level.AtLeast(LevelWarn) {
+		prefix = strings.ToUpper(string(record.Level)) + " "
+	}
+	line := fmt.Sprintf("%s %s%s\n", record.Time.Local().Format("2006/01/02 15:04:05"), prefix, record.Message)
+	io.WriteString(out, line)
+}
+
+// CaptureStandardLog tees the standard logger into the default buffer, so
+// lines from code that still calls log.Printf, such as agent and provider
+// plugins, are kept as info records of component
+func CaptureStandardLog(component string) {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	if output != nil {
+		return
+	}
+	output = log.Writer()
+	log.SetOutput(&teeWriter{out: output, logger: New(component)})
+}
+
+// teeWriter passes standard logger output through and buffers each call,
+// which the standard logger makes once per line it logs
+type teeWriter struct {
+	out    io.Writer
+	logger *Logger
+}
+
+func (t *teeWriter) Write(p []byte) (int, error) {
+	n, err := t.out.Write(p)
+	if message := stripTimestamp(strings.TrimRight(string(p), "\n")); message != "" {
+		t.logger.buffer.Add(Record{Time: time.Now().UTC(), Level: LevelInfo, Component: t.logger.component, Message: message})
+	}
+	return n, err
+}
+
+// stripTimestamp removes the date and time the standard logger starts lines
+// with
+func stripTimestamp(line string) string {
+	const layout = "2006/01/02 15:04:05 "
+	if len(line) >= len(layout) {
+		if _, err := time.Parse(layout, line[:len(layout)]); err == nil {
+			return line[len(layout):]
+		}
+	}
+	return line
+}
+
+type requestIDKey struct{}
+
+// ContextWithRequestID returns a context whose records are tagged with id
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID of ctx, or "" when it has none
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
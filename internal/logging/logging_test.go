@@ -0,0 +1,103 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestBuffer_OverwritesOldest(t *testing.T) {
+	buffer := NewBuffer(3)
+	for i := 1; i <= 5; i++ {
+		buffer.Add(Record{Level: LevelInfo, Message: fmt.Sprintf("record %d", i)})
+	}
+
+	records := buffer.Query(Filter{})
+	if len(records) != 3 {
+		t.Fatalf("Expected the buffer to keep 3 records, got %d", len(records))
+	}
+	for i, want := range []string{"record 5", "record 4", "record 3"} {
+		if records[i].Message != want {
+			t.Errorf("Record %d: expected %q, got %q", i, want, records[i].Message)
+		}
+	}
+
+	if records := NewBuffer(3).Query(Filter{}); records == nil || len(records) != 0 {
+		t.Errorf("Expected an empty list from an empty buffer, got %v", records)
+	}
+}
+
+func TestBuffer_QueryFilters(t *testing.T) {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	buffer := NewBuffer(10)
+	buffer.Add(Record{Time: start, Level: LevelDebug, Component: "loader", Message: "agent ran"})
+	buffer.Add(Record{Time: start.Add(time.Minute), Level: LevelWarn, Component: "models", Message: "model down"})
+	buffer.Add(Record{Time: start.Add(2 * time.Minute), Level: LevelError, Component: "api", Message: "encode failed"})
+	buffer.Add(Record{Time: start.Add(3 * time.Minute), Level: LevelInfo, Component: "api", Message: "request"})
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"everything", Filter{}, []string{"request", "encode failed", "model down", "agent ran"}},
+		{"min level", Filter{MinLevel: LevelWarn}, []string{"encode failed", "model down"}},
+		{"component", Filter{Component: "api"}, []string{"request", "encode failed"}},
+		{"since", Filter{Since: start.Add(2 * time.Minute)}, []string{"request", "encode failed"}},
+		{"limit", Filter{Limit: 1}, []string{"request"}},
+		{"combined", Filter{MinLevel: LevelInfo, Component: "api", Limit: 1}, []string{"request"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records := buffer.Query(tt.filter)
+			var got []string
+			for _, record := range records {
+				got = append(got, record.Message)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for name, want := range map[string]Level{"debug": LevelDebug, "INFO": LevelInfo, "warning": LevelWarn, "error": LevelError} {
+		if level, err := ParseLevel(name); err != nil || level != want {
+			t.Errorf("ParseLevel(%q) = %q, %v; expected %q", name, level, err, want)
+		}
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Error("Expected an unknown level to be refused")
+	}
+}
+
+func TestLogger_RecordsAndSubscribers(t *testing.T) {
+	buffer := NewBuffer(10)
+	var seen []Record
+	cancel := buffer.Subscribe(func(record Record) { seen = append(seen, record) })
+
+	logger := NewWithBuffer(buffer, "api")
+	logger.WithContext(ContextWithRequestID(context.Background(), "req-1")).Debugf("call %d", 1)
+	cancel()
+	logger.Debugf("after cancel")
+
+	records := buffer.Query(Filter{})
+	if len(records) != 2 || records[1].Component != "api" || records[1].RequestID != "req-1" || records[1].Message != "call 1" {
+		t.Errorf("Unexpected records: %+v", records)
+	}
+	if len(seen) != 1 || seen[0].Message != "call 1" {
+		t.Errorf("Expected the subscriber to see only the first record, got %+v", seen)
+	}
+}
+
+func TestStripTimestamp(t *testing.T) {
+	if got := stripTimestamp("2024/06/01 12:00:00 Loaded agent ls"); got != "Loaded agent ls" {
+		t.Errorf("Expected the timestamp to be removed, got %q", got)
+	}
+	if got := stripTimestamp("no timestamp here"); got != "no timestamp here" {
+		t.Errorf("Expected the line unchanged, got %q", got)
+	}
+}
@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistry_WriteText(t *testing.T) {
+	registry := NewRegistry()
+	requests := registry.NewCounter("afe_test_requests_total", "Requests served.", "route", "status")
+	latency := registry.NewHistogram("afe_test_latency_seconds", "Request latency.", []float64{0.1, 1}, "route")
+	registry.NewGaugeFunc("afe_test_clients", "Connected clients.", func() (float64, bool) { return 3, true })
+	registry.NewGaugeFunc("afe_test_unavailable", "Not reported.", func() (float64, bool) { return 0, false })
+
+	requests.Inc("/b", "200")
+	requests.Add(2, "/a", "500")
+	requests.Inc("/a", "500")
+	requests.Inc(`say "hi"`+"\n", "200")
+	latency.Observe(0.05, "/a")
+	latency.Observe(0.5, "/a")
+	latency.Observe(5, "/a")
+
+	recorder := httptest.NewRecorder()
+	registry.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if got := recorder.Header().Get("Content-Type"); got != ContentType {
+		t.Errorf("Expected the exposition content type, got %q", got)
+	}
+
+	expected := `# HELP afe_test_requests_total Requests served.
+# TYPE afe_test_requests_total counter
+afe_test_requests_total{route="/a",status="500"} 3
+afe_test_requests_total{route="/b",status="200"} 1
+afe_test_requests_total{route="say \"hi\"\n",status="200"} 1
+# HELP afe_test_latency_seconds Request latency.
+# TYPE afe_test_latency_seconds histogram
+afe_test_latency_seconds_bucket{route="/a",le="0.1"} 1
+afe_test_latency_seconds_bucket{route="/a",le="1"} 2
+afe_test_latency_seconds_bucket{route="/a",le="+Inf"} 3
+afe_test_latency_seconds_sum{route="/a"} 5.55
+afe_test_latency_seconds_count{route="/a"} 3
+# HELP afe_test_clients Connected clients.
+# TYPE afe_test_clients gauge
+afe_test_clients 3
+`
+	if got := recorder.Body.String(); got != expected {
+		t.Errorf("Unexpected exposition:\n%s\nexpected:\n%s", got, expected)
+	}
+}
+
+func TestRegistry_RejectsMisuse(t *testing.T) {
+	expectPanic := func(name string, fn func()) {
+		t.Helper()
+		defer func() {
+			if recover() == nil {
+				t.Errorf("%s: expected a panic", name)
+			}
+		}()
+		fn()
+	}
+
+	registry := NewRegistry()
+	counter := registry.NewCounter("afe_test_total", "Test.", "label")
+	expectPanic("duplicate name", func() { registry.NewCounter("afe_test_total", "Again.") })
+	expectPanic("wrong label count", func() { counter.Inc() })
+	expectPanic("negative delta", func() { counter.Add(-1, "x") })
+
+	var out strings.Builder
+	if err := registry.WriteText(&out); err != nil || !strings.Contains(out.String(), "# TYPE afe_test_total counter") {
+		t.Errorf("Expected the counter to be written, got %q, %v", out.String(), err)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/retry"
 )
 
 type HTTPModel struct {
@@ -90,7 +91,7 @@ func (m *HTTPModel) Generate(ctx context.Context, req interfaces.GenerationReque
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
+		return nil, &retry.StatusError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	// Parse response based on model type
@@ -2,19 +2,146 @@ package models
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/AgentForgeEngine/AgentForgeEngine/internal/logging"
 	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/retry"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/streamstats"
 )
 
+// logger writes the model manager's records to the engine's log buffer
+var logger = logging.New("models")
+
+// ErrUnknownModel is returned when a requested model is not registered
+var ErrUnknownModel = errors.New("unknown model")
+
+// DefaultModelAlias always resolves to the configured default model
+const DefaultModelAlias = "default"
+
 type Manager struct {
-	models map[string]interfaces.Model
+	models       map[string]interfaces.Model
+	aliases      map[string][]string
+	defaultModel string
+
+	// health is what recent requests observed about each model
+	healthMu sync.Mutex
+	health   map[string]*modelHealth
+
+	// observe is told about every call to a model
+	observe func(Observation)
+}
+
+// Observation describes one call the manager made to a model: its latency,
+// the tokens it generated, and its error. Calls that fell back to another
+// model are observed too.
+type Observation struct {
+	Model    string
+	Duration time.Duration
+	Tokens   int
+	Err      error
 }
 
 func NewManager() *Manager {
 	return &Manager{
-		models: make(map[string]interfaces.Model),
+		models:  make(map[string]interfaces.Model),
+		aliases: make(map[string][]string),
+		health:  make(map[string]*modelHealth),
+	}
+}
+
+// SetAliases replaces the alias table used to resolve requested model names.
+// An alias maps to one model or to an ordered list of models that are tried
+// in turn when the ones before them fail.
+func (m *Manager) SetAliases(aliases map[string][]string) {
+	m.aliases = make(map[string][]string, len(aliases))
+	for alias, targets := range aliases {
+		m.aliases[alias] = append([]string(nil), targets...)
+	}
+}
+
+// SetObserver sets a function told about every call to a model, as for
+// metrics. Set it before the manager serves requests.
+func (m *Manager) SetObserver(observe func(Observation)) {
+	m.observe = observe
+}
+
+func (m *Manager) observed(observation Observation) {
+	if m.observe != nil {
+		m.observe(observation)
+	}
+}
+
+// SetDefaultModel sets the model used when a request does not name one
+func (m *Manager) SetDefaultModel(name string) {
+	m.defaultModel = name
+}
+
+// ResolveModel maps a requested model name or alias to a registered model name,
+// the first of the alias's route. An empty name resolves to the default model.
+// Unknown models return an error wrapping ErrUnknownModel that lists the
+// available models.
+func (m *Manager) ResolveModel(name string) (string, error) {
+	route, err := m.ResolveRoute(name)
+	if err != nil {
+		return "", err
+	}
+	return route[0], nil
+}
+
+// ResolveRoute maps a requested model name or alias to the registered models
+// that may serve it, in the order they are tried. Alias targets that are not
+// registered are left out.
+func (m *Manager) ResolveRoute(name string) ([]string, error) {
+	requested := name
+	if requested == "" {
+		requested = DefaultModelAlias
+	}
+
+	targets, ok := m.aliases[requested]
+	if !ok {
+		resolved := requested
+		if resolved == DefaultModelAlias {
+			resolved = m.defaultModel
+		}
+		if targets, ok = m.aliases[resolved]; !ok {
+			targets = []string{resolved}
+		}
+	}
+
+	var route []string
+	for _, target := range targets {
+		if target == DefaultModelAlias {
+			target = m.defaultModel
+		}
+		if _, exists := m.models[target]; exists {
+			route = append(route, target)
+		}
+	}
+
+	if len(route) == 0 {
+		if len(targets) == 1 && targets[0] == "" {
+			return nil, fmt.Errorf("%w: no model requested and no default model configured; available models: %s",
+				ErrUnknownModel, m.availableModels())
+		}
+		return nil, fmt.Errorf("%w %q; available models: %s", ErrUnknownModel, name, m.availableModels())
+	}
+
+	return route, nil
+}
+
+func (m *Manager) availableModels() string {
+	names := m.ListModels()
+	if len(names) == 0 {
+		return "none"
 	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
 }
 
 func (m *Manager) InitializeModels(configs []interfaces.ModelConfig) error {
@@ -46,6 +173,22 @@ func (m *Manager) InitializeModel(config interfaces.ModelConfig) error {
 	return nil
 }
 
+// RegisterProvider serves an already initialized provider plugin as a model
+// under name
+func (m *Manager) RegisterProvider(name string, provider interfaces.Provider) {
+	m.models[name] = NewProviderModel(provider)
+}
+
+// ReconfigureProvider initializes the provider served as name again with
+// config, after the requests it is serving have finished
+func (m *Manager) ReconfigureProvider(name string, config map[string]interface{}) error {
+	model, ok := m.models[name].(*ProviderModel)
+	if !ok {
+		return fmt.Errorf("%w: no provider named %s", ErrUnknownModel, name)
+	}
+	return model.Reconfigure(config)
+}
+
 func (m *Manager) GetModel(name string) (interfaces.Model, bool) {
 	model, exists := m.models[name]
 	return model, exists
@@ -59,13 +202,123 @@ func (m *Manager) ListModels() []string {
 	return names
 }
 
+// Generate sends the request to the first model of the requested route and
+// falls back to the next one when a model is down. Models that failed
+// recently are tried after the others, and each fallback is charged to the
+// retry budget of ctx. Request errors and cancellation are returned without
+// trying other models.
 func (m *Manager) Generate(ctx context.Context, modelName string, req interfaces.GenerationRequest) (*interfaces.GenerationResponse, error) {
-	model, exists := m.GetModel(modelName)
-	if !exists {
-		return nil, fmt.Errorf("model %s not found", modelName)
+	route, err := m.ResolveRoute(modelName)
+	if err != nil {
+		return nil, err
+	}
+
+	var failures []error
+	for i, name := range m.healthOrder(route) {
+		// Falling back to another model retries the request
+		if i > 0 && !retry.BudgetFromContext(ctx).Take() {
+			failures = append(failures, retry.ErrBudgetExhausted)
+			break
+		}
+		model, _ := m.GetModel(name)
+		start := time.Now()
+		response, err := model.Generate(ctx, req)
+		observation := Observation{Model: name, Duration: time.Since(start), Err: err}
+		if response != nil {
+			observation.Tokens = response.Tokens
+		}
+		m.observed(observation)
+		if err == nil {
+			m.recordSuccess(name)
+			response.Provider = name
+			return response, nil
+		}
+		if ctx.Err() != nil || !providerFailure(err) {
+			return nil, err
+		}
+		m.recordFailure(name, err)
+		failures = append(failures, fmt.Errorf("%s: %w", name, err))
+	}
+
+	return nil, routeError(failures)
+}
+
+// GenerateStream streams a generation from the named model, falling back
+// along the route like Generate when a model fails to start streaming. Once
+// a stream has started it is not retried elsewhere. Models that do not
+// implement interfaces.StreamingModel send their whole response as one final
+// chunk.
+func (m *Manager) GenerateStream(ctx context.Context, modelName string, req interfaces.GenerationRequest) (<-chan interfaces.GenerationChunk, error) {
+	route, err := m.ResolveRoute(modelName)
+	if err != nil {
+		return nil, err
+	}
+
+	var failures []error
+	for i, name := range m.healthOrder(route) {
+		// Falling back to another model retries the request
+		if i > 0 && !retry.BudgetFromContext(ctx).Take() {
+			failures = append(failures, retry.ErrBudgetExhausted)
+			break
+		}
+		model, _ := m.GetModel(name)
+		meter := streamstats.NewMeter()
+		start := time.Now()
+		var chunks <-chan interfaces.GenerationChunk
+		if streaming, ok := model.(interfaces.StreamingModel); ok {
+			req.Stream = true
+			chunks, err = streaming.GenerateStream(ctx, req)
+		} else {
+			chunks, err = generateAsStream(ctx, model.Generate, req)
+		}
+		if err == nil {
+			m.recordSuccess(name)
+			done := func(final interfaces.GenerationChunk) {
+				observation := Observation{Model: name, Duration: time.Since(start), Tokens: final.Tokens}
+				if final.Error != "" {
+					observation.Err = errors.New(final.Error)
+				}
+				m.observed(observation)
+			}
+			return withProvider(ctx, chunks, name, meter, done), nil
+		}
+		m.observed(Observation{Model: name, Duration: time.Since(start), Err: err})
+		if ctx.Err() != nil || !providerFailure(err) {
+			return nil, err
+		}
+		m.recordFailure(name, err)
+		failures = append(failures, fmt.Errorf("%s: %w", name, err))
+	}
+
+	return nil, routeError(failures)
+}
+
+// generateAsStream runs a non-streaming generation and returns its response
+// as a closed stream holding a single final chunk
+func generateAsStream(ctx context.Context, generate func(context.Context, interfaces.GenerationRequest) (*interfaces.GenerationResponse, error), req interfaces.GenerationRequest) (<-chan interfaces.GenerationChunk, error) {
+	req.Stream = false
+	response, err := generate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	final := interfaces.GenerationChunk{
+		Delta:        response.Text,
+		Done:         true,
+		Tokens:       response.Tokens,
+		PromptTokens: response.PromptTokens,
+		FinishReason: response.FinishReason,
+		Model:        response.Model,
+		Error:        response.Error,
+	}
+	if final.FinishReason == "" && response.Finished {
+		final.FinishReason = "stop"
 	}
 
-	return model.Generate(ctx, req)
+	chunks := make(chan interfaces.GenerationChunk, 1)
+	chunks <- final
+	close(chunks)
+	return chunks, nil
 }
 
 func (m *Manager) HealthCheckAll(ctx context.Context) map[string]error {
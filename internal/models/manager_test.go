@@ -2,7 +2,9 @@ package models
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -136,6 +138,82 @@ func TestManager_HealthCheckAll(t *testing.T) {
 	}
 }
 
+func TestManager_ResolveModel(t *testing.T) {
+	manager := NewManager()
+	manager.models["llamacpp"] = &mockModel{name: "llamacpp", healthy: true}
+	manager.models["qwen3"] = &mockModel{name: "qwen3", healthy: true}
+	manager.SetDefaultModel("llamacpp")
+	manager.SetAliases(map[string][]string{"fast": {"qwen3"}})
+
+	// Valid model
+	resolved, err := manager.ResolveModel("qwen3")
+	if err != nil || resolved != "qwen3" {
+		t.Errorf("Expected qwen3, got %q (err: %v)", resolved, err)
+	}
+
+	// Unknown model returns a clear error listing the available models
+	_, err = manager.ResolveModel("gpt-9")
+	if !errors.Is(err, ErrUnknownModel) {
+		t.Fatalf("Expected ErrUnknownModel, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "llamacpp, qwen3") {
+		t.Errorf("Expected error to list available models, got: %v", err)
+	}
+
+	// Alias resolution
+	resolved, err = manager.ResolveModel("fast")
+	if err != nil || resolved != "qwen3" {
+		t.Errorf("Expected alias fast to resolve to qwen3, got %q (err: %v)", resolved, err)
+	}
+
+	// Empty name and the default alias resolve to the configured default
+	for _, name := range []string{"", DefaultModelAlias} {
+		resolved, err = manager.ResolveModel(name)
+		if err != nil || resolved != "llamacpp" {
+			t.Errorf("Expected %q to resolve to llamacpp, got %q (err: %v)", name, resolved, err)
+		}
+	}
+
+	// Generate rejects unknown models before dispatch
+	if _, err := manager.Generate(context.Background(), "gpt-9", interfaces.GenerationRequest{}); !errors.Is(err, ErrUnknownModel) {
+		t.Errorf("Expected Generate to reject unknown model, got: %v", err)
+	}
+}
+
+func TestManager_GenerateStream(t *testing.T) {
+	manager := NewManager()
+	manager.models["plain"] = &mockModel{name: "plain"}
+	manager.RegisterProvider("streamer", &mockStreamingProvider{deltas: []string{"Hel", "lo"}})
+
+	collect := func(model string) []interfaces.GenerationChunk {
+		t.Helper()
+		chunks, err := manager.GenerateStream(context.Background(), model, interfaces.GenerationRequest{Prompt: "hi"})
+		if err != nil {
+			t.Fatalf("GenerateStream(%s) failed: %v", model, err)
+		}
+		var received []interfaces.GenerationChunk
+		for chunk := range chunks {
+			received = append(received, chunk)
+		}
+		return received
+	}
+
+	// Models that cannot stream send their whole response as the final chunk
+	chunks := collect("plain")
+	if len(chunks) != 1 || !chunks[0].Done || chunks[0].Delta != "test response" || chunks[0].FinishReason != "stop" {
+		t.Errorf("Expected a single final chunk, got %+v", chunks)
+	}
+
+	chunks = collect("streamer")
+	if len(chunks) != 3 || chunks[0].Delta != "Hel" || chunks[1].Delta != "lo" || !chunks[2].Done || chunks[2].Tokens != 2 {
+		t.Errorf("Expected provider deltas and a final chunk, got %+v", chunks)
+	}
+
+	if _, err := manager.GenerateStream(context.Background(), "missing", interfaces.GenerationRequest{}); !errors.Is(err, ErrUnknownModel) {
+		t.Errorf("Expected ErrUnknownModel, got %v", err)
+	}
+}
+
 func TestHTTPModel_CreatePayload(t *testing.T) {
 	model := HTTPModel{}
 
@@ -231,3 +309,85 @@ func (mm *mockModel) HealthCheck() error {
 	return fmt.Errorf("model is unhealthy")
 }
 func (mm *mockModel) Shutdown() error { return nil }
+
+// Mock provider that streams fixed deltas
+type mockStreamingProvider struct {
+	deltas []string
+}
+
+func (mp *mockStreamingProvider) Name() string                                   { return "streamer" }
+func (mp *mockStreamingProvider) Initialize(config map[string]interface{}) error { return nil }
+func (mp *mockStreamingProvider) Generate(ctx context.Context, req interfaces.GenerationRequest) (*interfaces.GenerationResponse, error) {
+	return &interfaces.GenerationResponse{Text: strings.Join(mp.deltas, ""), Finished: true, Model: "streamer"}, nil
+}
+func (mp *mockStreamingProvider) GenerateStream(ctx context.Context, req interfaces.GenerationRequest) (<-chan interfaces.GenerationChunk, error) {
+	chunks := make(chan interfaces.GenerationChunk, len(mp.deltas)+1)
+	for _, delta := range mp.deltas {
+		chunks <- interfaces.GenerationChunk{Delta: delta, Model: "streamer"}
+	}
+	chunks <- interfaces.GenerationChunk{Done: true, Tokens: len(mp.deltas), FinishReason: "stop", Model: "streamer"}
+	close(chunks)
+	return chunks, nil
+}
+func (mp *mockStreamingProvider) HealthCheck() error { return nil }
+func (mp *mockStreamingProvider) Shutdown() error    { return nil }
+
+// Mock provider whose stream stays open until release is closed
+type gatedProvider struct {
+	mockStreamingProvider
+	release chan struct{}
+	config  map[string]interface{}
+}
+
+func (gp *gatedProvider) Initialize(config map[string]interface{}) error {
+	gp.config = config
+	return nil
+}
+func (gp *gatedProvider) GenerateStream(ctx context.Context, req interfaces.GenerationRequest) (<-chan interfaces.GenerationChunk, error) {
+	chunks := make(chan interfaces.GenerationChunk)
+	go func() {
+		defer close(chunks)
+		<-gp.release
+		chunks <- interfaces.GenerationChunk{Done: true, FinishReason: "stop", Model: "streamer"}
+	}()
+	return chunks, nil
+}
+
+func TestManager_ReconfigureProviderWaitsForRequests(t *testing.T) {
+	provider := &gatedProvider{release: make(chan struct{})}
+	manager := NewManager()
+	manager.RegisterProvider("streamer", provider)
+
+	chunks, err := manager.GenerateStream(context.Background(), "streamer", interfaces.GenerationRequest{Prompt: "hi"})
+	if err != nil {
+		t.Fatalf("GenerateStream failed: %v", err)
+	}
+
+	reconfigured := make(chan error, 1)
+	go func() {
+		reconfigured <- manager.ReconfigureProvider("streamer", map[string]interface{}{"temperature": 0.2})
+	}()
+
+	select {
+	case <-reconfigured:
+		t.Fatal("Expected the provider to be reconfigured only after the stream in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(provider.release)
+	for chunk := range chunks {
+		if !chunk.Done || chunk.Error != "" {
+			t.Errorf("Expected the stream to finish normally, got %+v", chunk)
+		}
+	}
+	if err := <-reconfigured; err != nil {
+		t.Fatalf("ReconfigureProvider failed: %v", err)
+	}
+	if provider.config["temperature"] != 0.2 {
+		t.Errorf("Expected the new config, got %v", provider.config)
+	}
+
+	if err := manager.ReconfigureProvider("missing", nil); !errors.Is(err, ErrUnknownModel) {
+		t.Errorf("Expected ErrUnknownModel, got %v", err)
+	}
+}
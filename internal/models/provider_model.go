@@ -0,0 +1,100 @@
+package models
+
+import (
+	"context"
+	"sync"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+// ProviderModel adapts a provider plugin to the Model interface so it can be
+// served by the manager alongside HTTP and WebSocket models. Requests hold a
+// read lock until they finish, so Reconfigure waits for those in flight.
+type ProviderModel struct {
+	mu       sync.RWMutex
+	provider interfaces.Provider
+}
+
+func NewProviderModel(provider interfaces.Provider) *ProviderModel {
+	return &ProviderModel{provider: provider}
+}
+
+func (m *ProviderModel) Name() string {
+	return m.provider.Name()
+}
+
+func (m *ProviderModel) Type() interfaces.ModelType {
+	return interfaces.ModelTypeProvider
+}
+
+// Initialize passes the model options to the provider, with the configured
+// endpoint when one is set
+func (m *ProviderModel) Initialize(config interfaces.ModelConfig) error {
+	options := make(map[string]interface{}, len(config.Options)+1)
+	for key, value := range config.Options {
+		options[key] = value
+	}
+	if config.Endpoint != "" {
+		options["endpoint"] = config.Endpoint
+	}
+	return m.Reconfigure(options)
+}
+
+// Reconfigure initializes the provider again with config once the requests
+// in flight have finished. Requests made meanwhile wait for it.
+func (m *ProviderModel) Reconfigure(config map[string]interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.provider.Initialize(config)
+}
+
+func (m *ProviderModel) Generate(ctx context.Context, req interfaces.GenerationRequest) (*interfaces.GenerationResponse, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.provider.Generate(ctx, req)
+}
+
+// GenerateStream streams from providers that implement
+// interfaces.StreamingProvider and falls back to a single chunk otherwise
+func (m *ProviderModel) GenerateStream(ctx context.Context, req interfaces.GenerationRequest) (<-chan interfaces.GenerationChunk, error) {
+	m.mu.RLock()
+	var chunks <-chan interfaces.GenerationChunk
+	var err error
+	if streaming, ok := m.provider.(interfaces.StreamingProvider); ok {
+		chunks, err = streaming.GenerateStream(ctx, req)
+	} else {
+		chunks, err = generateAsStream(ctx, m.provider.Generate, req)
+	}
+	if err != nil {
+		m.mu.RUnlock()
+		return nil, err
+	}
+
+	// The stream is in flight until the provider closes it
+	out := make(chan interfaces.GenerationChunk)
+	go func() {
+		defer m.mu.RUnlock()
+		defer close(out)
+		for chunk := range chunks {
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				// Let the provider finish without a reader
+				for range chunks {
+				}
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (m *ProviderModel) HealthCheck() error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.provider.HealthCheck()
+}
+
+func (m *ProviderModel) Shutdown() error {
+	return m.provider.Shutdown()
+}
@@ -0,0 +1,175 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/retry"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/streamstats"
+)
+
+// failureCooldown is how long a model that failed is tried after the other
+// models of its route
+const failureCooldown = 30 * time.Second
+
+// modelHealth is what requests observed about a model
+type modelHealth struct {
+	lastSuccess time.Time
+	lastFailure time.Time
+	lastError   string
+}
+
+// failing reports whether the model failed more recently than it succeeded,
+// within the cooldown
+func (h *modelHealth) failing(now time.Time) bool {
+	return h != nil && h.lastFailure.After(h.lastSuccess) && now.Sub(h.lastFailure) < failureCooldown
+}
+
+// ModelHealth reports a model's state as observed by recent requests.
+// Status is "healthy", "failing", or "unknown" before the model was used.
+type ModelHealth struct {
+	Status      string     `json:"status"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastFailure *time.Time `json:"last_failure,omitempty"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+}
+
+// RoutingStatus lists where each alias routes and the health of every model
+type RoutingStatus struct {
+	Routes map[string][]string    `json:"routes"`
+	Health map[string]ModelHealth `json:"health"`
+}
+
+// RoutingStatus reports the route of every alias, including the default, and
+// the observed health of every registered model
+func (m *Manager) RoutingStatus() RoutingStatus {
+	status := RoutingStatus{
+		Routes: make(map[string][]string),
+		Health: make(map[string]ModelHealth),
+	}
+
+	aliases := []string{DefaultModelAlias}
+	for alias := range m.aliases {
+		aliases = append(aliases, alias)
+	}
+	for _, alias := range aliases {
+		if route, err := m.ResolveRoute(alias); err == nil {
+			status.Routes[alias] = route
+		}
+	}
+
+	now := time.Now()
+	m.healthMu.Lock()
+	defer m.healthMu.Unlock()
+	for name := range m.models {
+		h := m.health[name]
+		report := ModelHealth{Status: "unknown"}
+		if h != nil {
+			report.Status = "healthy"
+			if h.failing(now) {
+				report.Status = "failing"
+			}
+			report.LastError = h.lastError
+			if !h.lastFailure.IsZero() {
+				lastFailure := h.lastFailure
+				report.LastFailure = &lastFailure
+			}
+			if !h.lastSuccess.IsZero() {
+				lastSuccess := h.lastSuccess
+				report.LastSuccess = &lastSuccess
+			}
+		}
+		status.Health[name] = report
+	}
+	return status
+}
+
+// healthOrder returns route with models that failed recently moved to the
+// end, keeping the configured order otherwise
+func (m *Manager) healthOrder(route []string) []string {
+	now := time.Now()
+	m.healthMu.Lock()
+	defer m.healthMu.Unlock()
+
+	ordered := append([]string(nil), route...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return !m.health[ordered[i]].failing(now) && m.health[ordered[j]].failing(now)
+	})
+	return ordered
+}
+
+func (m *Manager) recordSuccess(name string) {
+	m.healthMu.Lock()
+	defer m.healthMu.Unlock()
+	m.healthFor(name).lastSuccess = time.Now()
+}
+
+func (m *Manager) recordFailure(name string, err error) {
+	logger.Warnf("Model %s failed: %v", name, err)
+
+	m.healthMu.Lock()
+	defer m.healthMu.Unlock()
+	h := m.healthFor(name)
+	h.lastFailure = time.Now()
+	h.lastError = err.Error()
+}
+
+// healthFor returns the health entry for name, creating it. The caller holds
+// healthMu.
+func (m *Manager) healthFor(name string) *modelHealth {
+	h, ok := m.health[name]
+	if !ok {
+		h = &modelHealth{}
+		m.health[name] = h
+	}
+	return h
+}
+
+// providerFailure reports whether err means the model is unavailable rather
+// than that the request was bad: connection failures, timeouts, server
+// errors, and open circuit breakers. Client errors such as a prompt that
+// exceeds the context window are the same on every model and do not fall back.
+func providerFailure(err error) bool {
+	return errors.Is(err, retry.ErrCircuitOpen) || retry.Retryable(err)
+}
+
+// routeError reports that every model of a route failed. A route of one model
+// returns that model's error unchanged.
+func routeError(failures []error) error {
+	if len(failures) == 1 {
+		return errors.Unwrap(failures[0])
+	}
+	return fmt.Errorf("all models failed: %w", errors.Join(failures...))
+}
+
+// withProvider forwards chunks, recording the model that served them on the
+// final chunk. Streams whose model does not report its speed get the timing
+// measured by meter. done is called with the final chunk.
+func withProvider(ctx context.Context, chunks <-chan interfaces.GenerationChunk, name string, meter *streamstats.Meter, done func(interfaces.GenerationChunk)) <-chan interfaces.GenerationChunk {
+	out := make(chan interfaces.GenerationChunk)
+	go func() {
+		defer close(out)
+		for chunk := range chunks {
+			if chunk.Delta != "" {
+				meter.Chunk()
+			}
+			if chunk.Done {
+				chunk.Provider = name
+				if chunk.Stats == nil && chunk.Error == "" {
+					chunk.Stats = meter.Stats(chunk.Tokens)
+				}
+				done(chunk)
+			}
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
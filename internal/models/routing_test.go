@@ -0,0 +1,188 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/retry"
+)
+
+// failingModel fails every request with err and counts the attempts
+type failingModel struct {
+	mockModel
+	err   error
+	calls int32
+}
+
+func (fm *failingModel) Generate(ctx context.Context, req interfaces.GenerationRequest) (*interfaces.GenerationResponse, error) {
+	atomic.AddInt32(&fm.calls, 1)
+	return nil, fm.err
+}
+
+// deadHTTPModel is an HTTP model whose server has gone away
+func deadHTTPModel(t *testing.T) *HTTPModel {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Close()
+	return NewHTTPModel(interfaces.ModelConfig{Name: "llamacpp", Type: interfaces.ModelTypeHTTP, Endpoint: server.URL})
+}
+
+func TestManager_FallsBackWhenProviderIsDown(t *testing.T) {
+	manager := NewManager()
+	manager.models["llamacpp"] = deadHTTPModel(t)
+	manager.models["backup"] = &mockModel{name: "backup"}
+	manager.SetDefaultModel("llamacpp")
+	manager.SetAliases(map[string][]string{DefaultModelAlias: {"llamacpp", "backup"}})
+
+	response, err := manager.Generate(context.Background(), "", interfaces.GenerationRequest{Prompt: "hi"})
+	if err != nil {
+		t.Fatalf("Expected transparent failover, got %v", err)
+	}
+	if response.Text != "test response" || response.Provider != "backup" {
+		t.Errorf("Expected the backup to serve the request, got %+v", response)
+	}
+
+	status := manager.RoutingStatus()
+	if route := status.Routes[DefaultModelAlias]; len(route) != 2 || route[0] != "llamacpp" {
+		t.Errorf("Expected the default route in order, got %v", route)
+	}
+	if health := status.Health["llamacpp"]; health.Status != "failing" || health.LastError == "" {
+		t.Errorf("Expected the dead model to be reported failing, got %+v", health)
+	}
+	if health := status.Health["backup"]; health.Status != "healthy" {
+		t.Errorf("Expected the backup to be reported healthy, got %+v", health)
+	}
+}
+
+func TestManager_FallbackChargesRetryBudget(t *testing.T) {
+	manager := NewManager()
+	down := &failingModel{err: &retry.StatusError{StatusCode: 503}}
+	backup := &failingModel{err: &retry.StatusError{StatusCode: 503}}
+	manager.models["primary"] = down
+	manager.models["backup"] = backup
+	manager.SetAliases(map[string][]string{"chat": {"primary", "backup"}})
+
+	budget := retry.NewBudget(0)
+	_, err := manager.Generate(retry.WithBudget(context.Background(), budget), "chat", interfaces.GenerationRequest{Prompt: "hi"})
+	if !errors.Is(err, retry.ErrBudgetExhausted) {
+		t.Fatalf("Expected the exhausted budget to stop the fallback, got %v", err)
+	}
+	if down.calls+backup.calls != 1 {
+		t.Errorf("Expected only the first model to be tried, got %d and %d calls", down.calls, backup.calls)
+	}
+}
+
+func TestManager_SkipsRecentlyFailedModels(t *testing.T) {
+	primary := &failingModel{mockModel: mockModel{name: "primary"}, err: &retry.StatusError{StatusCode: 503}}
+	manager := NewManager()
+	manager.models["primary"] = primary
+	manager.models["backup"] = &mockModel{name: "backup"}
+	manager.SetAliases(map[string][]string{"chat": {"primary", "backup"}})
+
+	for i := 0; i < 3; i++ {
+		response, err := manager.Generate(context.Background(), "chat", interfaces.GenerationRequest{})
+		if err != nil || response.Provider != "backup" {
+			t.Fatalf("Expected the backup to serve request %d, got %+v, %v", i, response, err)
+		}
+	}
+	if calls := atomic.LoadInt32(&primary.calls); calls != 1 {
+		t.Errorf("Expected a failing model to be tried last while cooling down, got %d calls", calls)
+	}
+
+	// A failing model is still tried when nothing else is left
+	manager.models["backup"] = &failingModel{mockModel: mockModel{name: "backup"}, err: retry.ErrCircuitOpen}
+	_, err := manager.Generate(context.Background(), "chat", interfaces.GenerationRequest{})
+	if err == nil || !strings.Contains(err.Error(), "all models failed") || !strings.Contains(err.Error(), "primary") {
+		t.Errorf("Expected every model's failure to be reported, got %v", err)
+	}
+	if calls := atomic.LoadInt32(&primary.calls); calls != 2 {
+		t.Errorf("Expected the failing model as a last resort, got %d calls", calls)
+	}
+}
+
+func TestManager_RequestErrorsDoNotFallBack(t *testing.T) {
+	tooLong := &retry.StatusError{StatusCode: 400, Body: "the request exceeds the available context size"}
+	primary := &failingModel{mockModel: mockModel{name: "primary"}, err: tooLong}
+	backup := &failingModel{mockModel: mockModel{name: "backup"}}
+	manager := NewManager()
+	manager.models["primary"] = primary
+	manager.models["backup"] = backup
+	manager.SetAliases(map[string][]string{"chat": {"primary", "backup"}})
+
+	_, err := manager.Generate(context.Background(), "chat", interfaces.GenerationRequest{})
+	if !errors.Is(err, tooLong) {
+		t.Errorf("Expected the request error unchanged, got %v", err)
+	}
+	if calls := atomic.LoadInt32(&backup.calls); calls != 0 {
+		t.Errorf("Expected no fallback for a request error, got %d calls", calls)
+	}
+	if health := manager.RoutingStatus().Health["primary"]; health.Status != "unknown" {
+		t.Errorf("Expected a request error not to mark the model failing, got %+v", health)
+	}
+}
+
+func TestManager_GenerateStreamFallsBack(t *testing.T) {
+	manager := NewManager()
+	manager.models["primary"] = &failingModel{mockModel: mockModel{name: "primary"}, err: &retry.StatusError{StatusCode: 502}}
+	manager.RegisterProvider("streamer", &mockStreamingProvider{deltas: []string{"Hel", "lo"}})
+	manager.SetAliases(map[string][]string{"chat": {"primary", "streamer"}})
+
+	chunks, err := manager.GenerateStream(context.Background(), "chat", interfaces.GenerationRequest{})
+	if err != nil {
+		t.Fatalf("GenerateStream failed: %v", err)
+	}
+	var text strings.Builder
+	var final interfaces.GenerationChunk
+	for chunk := range chunks {
+		text.WriteString(chunk.Delta)
+		final = chunk
+	}
+	if text.String() != "Hello" || !final.Done || final.Provider != "streamer" {
+		t.Errorf("Expected the stream from the fallback, got %q and %+v", text.String(), final)
+	}
+	// The mock does not time its stream, so the manager measures it
+	if final.Stats == nil || final.Stats.DurationMs < final.Stats.TimeToFirstTokenMs {
+		t.Errorf("Expected measured stream stats, got %+v", final.Stats)
+	}
+}
+
+func TestManager_ObservesEveryModelCall(t *testing.T) {
+	manager := NewManager()
+	manager.models["primary"] = &failingModel{mockModel: mockModel{name: "primary"}, err: &retry.StatusError{StatusCode: 502}}
+	manager.RegisterProvider("streamer", &mockStreamingProvider{deltas: []string{"Hel", "lo"}})
+	manager.SetAliases(map[string][]string{"chat": {"primary", "streamer"}})
+
+	var observed []Observation
+	manager.SetObserver(func(observation Observation) { observed = append(observed, observation) })
+
+	if _, err := manager.Generate(context.Background(), "chat", interfaces.GenerationRequest{}); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	// Failing models are tried last, so the stream goes straight to the fallback
+	chunks, err := manager.GenerateStream(context.Background(), "chat", interfaces.GenerationRequest{})
+	if err != nil {
+		t.Fatalf("GenerateStream failed: %v", err)
+	}
+	for range chunks {
+	}
+
+	if len(observed) != 3 {
+		t.Fatalf("Expected 3 observations, got %+v", observed)
+	}
+	if observed[0].Model != "primary" || observed[0].Err == nil {
+		t.Errorf("Expected the failed call to be observed, got %+v", observed[0])
+	}
+	if observed[1].Model != "streamer" || observed[1].Err != nil {
+		t.Errorf("Expected the fallback call to be observed, got %+v", observed[1])
+	}
+	// A stream is observed when it finishes, with the tokens it generated
+	if observed[2].Model != "streamer" || observed[2].Tokens != 2 || observed[2].Err != nil {
+		t.Errorf("Expected the finished stream to be observed, got %+v", observed[2])
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/retry"
 )
 
 type WebSocketModel struct {
@@ -73,6 +74,7 @@ func (m *WebSocketModel) generateOllama(ctx context.Context, req interfaces.Gene
 	// Fallback to HTTP-based generation for now
 	return m.generateGeneric(ctx, interfaces.GenerationRequest{
 		Prompt:      req.Prompt,
+		Messages:    req.Messages,
 		MaxTokens:   req.MaxTokens,
 		Temperature: req.Temperature,
 		StopTokens:  req.StopTokens,
@@ -148,7 +150,7 @@ func (m *WebSocketModel) makeAPIRequest(ctx context.Context, path string, payloa
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
+		return nil, &retry.StatusError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	return body, nil
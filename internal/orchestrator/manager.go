@@ -3,7 +3,6 @@ package orchestrator
 import (
 	"context"
 	"fmt"
-	"strings"
 	"sync"
 	"time"
 
@@ -17,13 +16,14 @@ type Manager struct {
 	workflowEngine WorkflowEngine
 	parser         TodoParser
 	router         TaskRouter
-	pluginMgr      interfaces.PluginManager
+	pluginMgr      AgentRegistry
 	formatter      response.Formatter
+	maxTodos       int
 	mu             sync.RWMutex
 }
 
 // NewManager creates a new orchestrator manager
-func NewManager(pluginMgr interfaces.PluginManager, config map[string]interface{}) *Manager {
+func NewManager(pluginMgr AgentRegistry, config map[string]interface{}) *Manager {
 	base := NewBaseOrchestrator("orchestrator", config)
 
 	parser := NewTodoParser()
@@ -31,6 +31,10 @@ func NewManager(pluginMgr interfaces.PluginManager, config map[string]interface{
 	workflowEngine := NewWorkflowEngine(pluginMgr, parser, router)
 	formatter := response.NewAutoFormatter()
 
+	// An unset or zero max_todos or max_concurrent_tasks keeps the default
+	maxTodos := positiveSetting(config, "max_todos", DefaultMaxTodos)
+	workflowEngine.SetMaxParallel(positiveSetting(config, "max_concurrent_tasks", DefaultMaxConcurrentTasks))
+
 	return &Manager{
 		BaseOrchestrator: base,
 		workflowEngine:   workflowEngine,
@@ -38,9 +42,63 @@ func NewManager(pluginMgr interfaces.PluginManager, config map[string]interface{
 		router:           router,
 		pluginMgr:        pluginMgr,
 		formatter:        formatter,
+		maxTodos:         maxTodos,
+	}
+}
+
+// DefaultMaxConcurrentTasks bounds the tasks of a workflow that run at once
+// when the config sets no max_concurrent_tasks
+const DefaultMaxConcurrentTasks = 10
+
+// positiveSetting reads a positive number from the config, or returns def
+func positiveSetting(config map[string]interface{}, key string, def int) int {
+	switch v := config[key].(type) {
+	case int:
+		if v > 0 {
+			return v
+		}
+	case float64:
+		if v > 0 {
+			return int(v)
+		}
+	}
+	return def
+}
+
+// SetAgentCaller sets what runs the agents of workflow tasks. The plugin
+// manager's agents are called directly until one is set.
+func (m *Manager) SetAgentCaller(caller interfaces.AgentCaller) {
+	if aware, ok := m.workflowEngine.(interfaces.CallerAware); ok {
+		aware.SetAgentCaller(caller)
+	}
+}
+
+// SetEventFunc sets where workflow events are published
+func (m *Manager) SetEventFunc(publish interfaces.EventFunc) {
+	if aware, ok := m.workflowEngine.(interfaces.EventAware); ok {
+		aware.SetEventFunc(publish)
 	}
 }
 
+// workflowPersistence is implemented by workflow engines that can keep their
+// workflows in a store
+type workflowPersistence interface {
+	SetStore(store WorkflowStore)
+	RestoreWorkflows() error
+}
+
+// SetStore sets where workflows are saved as they run, and restores the
+// workflows the store holds unfinished, marking those that were running as
+// interrupted so orch.error-recovery can resume them
+func (m *Manager) SetStore(store WorkflowStore) error {
+	persistence, ok := m.workflowEngine.(workflowPersistence)
+	if !ok {
+		return fmt.Errorf("the workflow engine cannot persist workflows")
+	}
+	persistence.SetStore(store)
+	return persistence.RestoreWorkflows()
+}
+
 // Process handles orchestrator requests
 func (m *Manager) Process(ctx context.Context, input interfaces.AgentInput) (interfaces.AgentOutput, error) {
 	switch input.Type {
@@ -69,17 +127,12 @@ func (m *Manager) processManagerRequest(ctx context.Context, input interfaces.Ag
 		}, nil
 	}
 
-	// Convert to string slice
-	todosRaw := fmt.Sprintf("%v", todosInterface)
-	todos := strings.Split(todosRaw, "\n")
-
-	// Clean up todos
-	var cleanTodos []string
-	for _, todo := range todos {
-		todo = strings.TrimSpace(todo)
-		if todo != "" {
-			cleanTodos = append(cleanTodos, todo)
-		}
+	cleanTodos, err := TodosFromPayload(todosInterface, m.maxTodos)
+	if err != nil {
+		return interfaces.AgentOutput{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
 	}
 
 	if len(cleanTodos) == 0 {
@@ -89,6 +142,11 @@ func (m *Manager) processManagerRequest(ctx context.Context, input interfaces.Ag
 		}, nil
 	}
 
+	// A dry run only reports how the todos would run
+	if dryRun, _ := input.Payload["dry_run"].(bool); dryRun {
+		return m.processDryRun(cleanTodos)
+	}
+
 	// Extract workflow name
 	nameInterface, _ := input.Payload["name"]
 	name := fmt.Sprintf("workflow-%d", time.Now().Unix())
@@ -97,13 +155,13 @@ func (m *Manager) processManagerRequest(ctx context.Context, input interfaces.Ag
 	}
 
 	// Extract context
-	workflowContext, _ := input.Payload["context"]
+	workflowContext, _ := input.Payload["context"].(map[string]interface{})
 	if workflowContext == nil {
 		workflowContext = make(map[string]interface{})
 	}
 
 	// Create and execute workflow
-	workflow, err := m.workflowEngine.CreateWorkflow(name, cleanTodos, workflowContext.(map[string]interface{}))
+	workflow, err := m.workflowEngine.CreateWorkflow(name, cleanTodos, workflowContext)
 	if err != nil {
 		return interfaces.AgentOutput{
 			Success: false,
@@ -129,9 +187,12 @@ func (m *Manager) processManagerRequest(ctx context.Context, input interfaces.Ag
 			"total_tasks":     result.TotalTasks,
 			"completed_tasks": result.CompletedTasks,
 			"failed_tasks":    result.FailedTasks,
+			"cancelled_tasks": result.CancelledTasks,
+			"skipped_tasks":   result.SkippedTasks,
 			"duration":        result.Duration.String(),
 			"summary":         result.Summary,
 			"tasks":           result.Tasks,
+			"plan":            workflow.Plan,
 			"context":         result.Context,
 		},
 	})
@@ -151,6 +212,48 @@ func (m *Manager) processManagerRequest(ctx context.Context, input interfaces.Ag
 	}, nil
 }
 
+// processDryRun reports the agent and arguments each todo would run with,
+// flagging those no agent serves, without running any
+func (m *Manager) processDryRun(todos []Todo) (interfaces.AgentOutput, error) {
+	plan, err := m.workflowEngine.PlanWorkflow(todos)
+	if err != nil {
+		return interfaces.AgentOutput{
+			Success: false,
+			Error:   fmt.Sprintf("failed to plan workflow: %v", err),
+		}, nil
+	}
+
+	unmapped := 0
+	for _, step := range plan {
+		if step.Unmapped {
+			unmapped++
+		}
+	}
+
+	formattedResponse, err := m.formatter.FormatAgentOutput("orchestrator", interfaces.AgentOutput{
+		Success: true,
+		Data: map[string]interface{}{
+			"dry_run":        true,
+			"total_tasks":    len(plan),
+			"unmapped_tasks": unmapped,
+			"plan":           plan,
+		},
+	})
+	if err != nil {
+		return interfaces.AgentOutput{
+			Success: false,
+			Error:   fmt.Sprintf("failed to format response: %v", err),
+		}, nil
+	}
+
+	return interfaces.AgentOutput{
+		Success: true,
+		Data: map[string]interface{}{
+			"function_response": formattedResponse,
+		},
+	}, nil
+}
+
 // processWorkflowProgress handles workflow progress requests
 func (m *Manager) processWorkflowProgress(ctx context.Context, input interfaces.AgentInput) (interfaces.AgentOutput, error) {
 	// Extract workflow ID
@@ -177,6 +280,7 @@ func (m *Manager) processWorkflowProgress(ctx context.Context, input interfaces.
 	totalTasks := len(workflow.Tasks)
 	completedTasks := 0
 	failedTasks := 0
+	skippedTasks := 0
 	var totalDuration time.Duration
 
 	for _, task := range workflow.Tasks {
@@ -185,13 +289,18 @@ func (m *Manager) processWorkflowProgress(ctx context.Context, input interfaces.
 			completedTasks++
 		case TaskStatusFailed:
 			failedTasks++
+		case TaskStatusSkipped:
+			skippedTasks++
 		}
 		totalDuration += task.Duration
 	}
 
 	// Generate progress summary
 	status := string(workflow.Status)
-	progress := float64(completedTasks) / float64(totalTasks) * 100
+	progress := 0.0
+	if totalTasks > 0 {
+		progress = float64(completedTasks) / float64(totalTasks) * 100
+	}
 
 	// Format the response for model
 	formattedResponse, err := m.formatter.FormatAgentOutput("orchestrator", interfaces.AgentOutput{
@@ -204,10 +313,11 @@ func (m *Manager) processWorkflowProgress(ctx context.Context, input interfaces.
 			"total_tasks":     totalTasks,
 			"completed_tasks": completedTasks,
 			"failed_tasks":    failedTasks,
+			"skipped_tasks":   skippedTasks,
 			"duration":        totalDuration.String(),
 			"created_at":      workflow.CreatedAt.Format(time.RFC3339),
-			"started_at":      workflow.StartedAt.Format(time.RFC3339),
-			"completed_at":    workflow.CompletedAt.Format(time.RFC3339),
+			"started_at":      formatTime(workflow.StartedAt),
+			"completed_at":    formatTime(workflow.CompletedAt),
 			"tasks":           workflow.Tasks,
 		},
 	})
@@ -249,67 +359,42 @@ func (m *Manager) processErrorRecovery(ctx context.Context, input interfaces.Age
 		}, nil
 	}
 
-	// Check if workflow is in failed state
-	if workflow.Status != WorkflowStatusFailed && workflow.Status != WorkflowStatusCancelled {
+	// Make the tasks that did not complete pending again, and resume the
+	// workflow with them
+	retried, err := m.workflowEngine.ResetFailedTasks(workflowID)
+	if err != nil {
 		return interfaces.AgentOutput{
 			Success: false,
-			Error:   fmt.Sprintf("workflow %s is not in a failed state (current: %s)", workflowID, workflow.Status),
+			Error:   err.Error(),
 		}, nil
 	}
 
-	// Find failed tasks
-	var failedTasks []Task
-	for _, task := range workflow.Tasks {
-		if task.Status == TaskStatusFailed {
-			// Reset task status for retry
-			task.Status = TaskStatusPending
-			task.Error = ""
-			failedTasks = append(failedTasks, task)
-		}
+	result, err := m.workflowEngine.ExecuteWorkflow(ctx, workflow)
+	if err != nil {
+		return interfaces.AgentOutput{
+			Success: false,
+			Error:   fmt.Sprintf("failed to execute retry workflow: %v", err),
+		}, nil
 	}
 
-	// Retry workflow with only failed tasks
-	if len(failedTasks) > 0 {
-		retryWorkflow := &Workflow{
-			ID:        workflow.ID + "-retry",
-			Name:      workflow.Name + " (retry)",
-			Status:    WorkflowStatusPending,
-			CreatedAt: time.Now(),
-			Context:   workflow.Context,
-		}
-
-		// Copy only failed tasks
-		for _, task := range failedTasks {
-			retryWorkflow.Tasks = append(retryWorkflow.Tasks, task)
-		}
-
-		// Execute retry workflow
-		result, err := m.workflowEngine.ExecuteWorkflow(ctx, retryWorkflow)
-		if err != nil {
-			return interfaces.AgentOutput{
-				Success: false,
-				Error:   fmt.Sprintf("failed to execute retry workflow: %v", err),
-			}, nil
-		}
+	// Format the response for model
+	formattedResponse, err := m.formatter.FormatAgentOutput("orchestrator", interfaces.AgentOutput{
+		Success: true,
+		Data: map[string]interface{}{
+			"recovery_successful": result.Status == WorkflowStatusCompleted,
+			"failed_tasks_count":  retried,
+			"workflow_id":         result.WorkflowID,
+			"retry_result":        result,
+			"message":             fmt.Sprintf("Attempted to recover %d failed tasks", retried),
+		},
+	})
 
-		// Format the response for model
-		formattedResponse, err := m.formatter.FormatAgentOutput("orchestrator", interfaces.AgentOutput{
-			Success: true,
-			Data: map[string]interface{}{
-				"recovery_successful": true,
-				"failed_tasks_count":  len(failedTasks),
-				"retry_workflow_id":   result.WorkflowID,
-				"retry_result":        result,
-				"message":             fmt.Sprintf("Attempted to recover %d failed tasks", len(failedTasks)),
-			},
-		})
-
-		if err != nil {
-			return interfaces.AgentOutput{
-				Success: false,
-				Error:   fmt.Sprintf("failed to format response: %v", err),
-			}, nil
-		}
+	if err != nil {
+		return interfaces.AgentOutput{
+			Success: false,
+			Error:   fmt.Sprintf("failed to format response: %v", err),
+		}, nil
+	}
 
 	return interfaces.AgentOutput{
 		Success: true,
@@ -319,17 +404,24 @@ func (m *Manager) processErrorRecovery(ctx context.Context, input interfaces.Age
 	}, nil
 }
 
-// GetAvailableOrchestrators returns list of available orchestrator functions
-
 // GetAvailableOrchestrators returns list of available orchestrator functions
 func (m *Manager) GetAvailableOrchestrators() map[string]string {
 	return map[string]string{
-		"orch.manager":           "Main orchestrator - processes todo lists and coordinates multiple agents",
+		"orch.manager":           "Main orchestrator - processes todo lists and coordinates multiple agents; dry_run previews the plan",
 		"orch.workflow-progress": "Check workflow status and progress",
 		"orch.error-recovery":    "Handle failed workflows and retry tasks",
 	}
 }
 
+// formatTime formats a time the workflow may not have reached yet as
+// RFC3339, or as an empty string
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
 // Name returns orchestrator name
 func (m *Manager) Name() string {
 	return "orchestrator"
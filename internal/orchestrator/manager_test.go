@@ -0,0 +1,158 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+func newTestManager(config map[string]interface{}) *Manager {
+	pm := &stubPluginManager{agents: map[string]interfaces.Agent{
+		"ls-agent":    &stubAgent{name: "ls-agent"},
+		"touch-agent": &stubAgent{name: "touch-agent"},
+	}}
+	return NewManager(pm, config)
+}
+
+func TestProcessManagerRequest_Todos(t *testing.T) {
+	tests := []struct {
+		name  string
+		todos interface{}
+	}{
+		{"newline string", "list the project directory\n\n  create notes.txt  \n"},
+		{"array of strings", []interface{}{"list the project directory", "", "create notes.txt"}},
+		{"array of objects", []interface{}{
+			map[string]interface{}{"task": "list the project directory"},
+			map[string]interface{}{"task": "create notes.txt"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestManager(nil)
+			output, err := m.Process(t.Context(), interfaces.AgentInput{
+				Type:    "manager",
+				Payload: map[string]interface{}{"todos": tt.todos, "name": "setup"},
+			})
+			if err != nil || !output.Success {
+				t.Fatalf("Expected the workflow to run, got %+v, %v", output, err)
+			}
+
+			workflows := m.workflowEngine.ListWorkflows()
+			if len(workflows) != 1 {
+				t.Fatalf("Expected one workflow, got %d", len(workflows))
+			}
+			todos := workflows[0].Todos
+			if strings.Join(todos, "|") != "list the project directory|create notes.txt" {
+				t.Errorf("Expected each todo as its own task, got %q", todos)
+			}
+			if tasks := workflows[0].Tasks; tasks[0].AgentName != "ls-agent" || tasks[1].AgentName != "touch-agent" {
+				t.Errorf("Unexpected tasks: %+v", tasks)
+			}
+		})
+	}
+}
+
+func TestProcessManagerRequest_TooManyTodos(t *testing.T) {
+	var todos []interface{}
+	for i := 0; i < 4; i++ {
+		todos = append(todos, fmt.Sprintf("create file-%d.txt", i))
+	}
+
+	m := newTestManager(map[string]interface{}{"max_todos": 3})
+	output, err := m.Process(t.Context(), interfaces.AgentInput{Type: "manager", Payload: map[string]interface{}{"todos": todos}})
+	if err != nil || output.Success || output.Error != "too many todos: the limit is 3" {
+		t.Errorf("Expected the list to be refused, got %+v, %v", output, err)
+	}
+	if workflows := m.workflowEngine.ListWorkflows(); len(workflows) != 0 {
+		t.Errorf("Expected no workflow to be created, got %d", len(workflows))
+	}
+
+	// The limit applies to todos, not to the blank lines between them
+	output, _ = m.Process(t.Context(), interfaces.AgentInput{
+		Type:    "manager",
+		Payload: map[string]interface{}{"todos": "create a.txt\n\n\n\ncreate b.txt\ncreate c.txt"},
+	})
+	if !output.Success {
+		t.Errorf("Expected three todos to be accepted, got %+v", output)
+	}
+}
+
+func TestTodosFromPayload_Invalid(t *testing.T) {
+	for _, value := range []interface{}{42, []interface{}{7}, []interface{}{map[string]interface{}{"name": "x"}}} {
+		if _, err := TodosFromPayload(value, DefaultMaxTodos); err == nil {
+			t.Errorf("Expected %v to be refused", value)
+		}
+	}
+}
+
+func TestTodosFromPayload_Dependencies(t *testing.T) {
+	var payload interface{}
+	json.Unmarshal([]byte(`[
+		"list the project directory",
+		{"task": "create a.txt", "id": "a", "depends_on": []},
+		{"task": "create b.txt", "depends_on": ["a", 0]}
+	]`), &payload)
+	todos, err := TodosFromPayload(payload, DefaultMaxTodos)
+	if err != nil {
+		t.Fatalf("TodosFromPayload failed: %v", err)
+	}
+	if !todos[0].Sequential || todos[1].Sequential || todos[2].Sequential {
+		t.Errorf("Expected only the string to be sequential, got %+v", todos)
+	}
+	if len(todos[2].DependsOn) != 2 || todos[2].DependsOn[0] != 1 || todos[2].DependsOn[1] != 0 {
+		t.Errorf("Expected b to depend on a and the first todo, got %v", todos[2].DependsOn)
+	}
+
+	for _, invalid := range []string{
+		`[{"task": "a", "depends_on": [3]}]`,
+		`[{"task": "a", "depends_on": ["missing"]}]`,
+		`[{"task": "a", "id": "a"}, {"task": "b", "id": "a"}]`,
+		`[{"task": "a", "depends_on": [1]}, {"task": "b", "depends_on": [0]}]`,
+		`[{"task": "a", "depends_on": [1]}, "b"]`,
+	} {
+		json.Unmarshal([]byte(invalid), &payload)
+		if _, err := TodosFromPayload(payload, DefaultMaxTodos); err == nil {
+			t.Errorf("Expected %s to be refused", invalid)
+		}
+	}
+}
+
+func TestProcessManagerRequest_DryRun(t *testing.T) {
+	ls := &flakyAgent{stubAgent: stubAgent{name: "ls-agent"}}
+	touch := &flakyAgent{stubAgent: stubAgent{name: "touch-agent"}}
+	m := NewManager(&stubPluginManager{agents: map[string]interfaces.Agent{"ls-agent": ls, "touch-agent": touch}}, nil)
+	todos := []interface{}{"list the project directory", "create notes.txt", "water the plants"}
+
+	output, err := m.Process(t.Context(), interfaces.AgentInput{
+		Type:    "manager",
+		Payload: map[string]interface{}{"todos": todos, "dry_run": true},
+	})
+	if err != nil || !output.Success {
+		t.Fatalf("Expected the plan, got %+v, %v", output, err)
+	}
+	response := output.Data["function_response"].(string)
+	for _, expected := range []string{`"unmapped_tasks":1`, `"agent_name":"touch-agent"`, `"file":"notes.txt"`, "water the plants"} {
+		if !strings.Contains(strings.ReplaceAll(response, " ", ""), strings.ReplaceAll(expected, " ", "")) {
+			t.Errorf("Expected the plan to contain %s, got %s", expected, response)
+		}
+	}
+	if ls.calls != 0 || touch.calls != 0 || len(m.workflowEngine.ListWorkflows()) != 0 {
+		t.Fatal("Expected a dry run to run no agent and create no workflow")
+	}
+
+	// A run keeps the same plan with its workflow
+	if output, _ := m.Process(t.Context(), interfaces.AgentInput{Type: "manager", Payload: map[string]interface{}{"todos": todos}}); !output.Success {
+		t.Fatalf("Expected the workflow to run, got %+v", output)
+	}
+	plan := m.workflowEngine.ListWorkflows()[0].Plan
+	if len(plan) != 3 || plan[0].Unmapped || plan[1].Unmapped || !plan[2].Unmapped {
+		t.Errorf("Expected the third todo to be unmapped, got %+v", plan)
+	}
+	if ls.calls != 1 || touch.calls != 1 {
+		t.Errorf("Expected each mapped agent to run once, got %d and %d", ls.calls, touch.calls)
+	}
+}
@@ -220,3 +220,161 @@ func getDefaultTodoPatterns() []TodoPattern {
 		},
 	}
 }
+
+// DefaultMaxTodos bounds the todo lists the manager accepts when its config
+// sets no max_todos
+const DefaultMaxTodos = 100
+
+// TodosFromPayload reads the todos of a manager request. They may be a
+// newline-delimited string or a JSON array whose entries are strings or
+// objects with a "task" field. Strings, and objects without "depends_on",
+// run after every todo before them has finished. An object's "depends_on"
+// lists the todos it needs, by their index in the list or their "id"
+// instead; it runs once they have completed, alongside any other todo whose
+// dependencies have, and is skipped if one of them did not complete. Blank
+// entries are skipped, and lists of more than max todos are refused; max of
+// zero or less means no limit.
+func TodosFromPayload(value interface{}, max int) ([]Todo, error) {
+	var entries []interface{}
+	switch v := value.(type) {
+	case string:
+		for _, line := range strings.Split(v, "\n") {
+			entries = append(entries, line)
+		}
+	case []string:
+		for _, todo := range v {
+			entries = append(entries, todo)
+		}
+	case []interface{}:
+		entries = v
+	default:
+		return nil, fmt.Errorf("todos must be a string or a list, got %T", value)
+	}
+
+	var todos []Todo
+	// dependencies holds each todo's depends_on as given, and positions
+	// maps the index of each kept entry to its todo
+	var dependencies [][]interface{}
+	positions := make(map[int]int)
+	ids := make(map[string]int)
+	for i, entry := range entries {
+		todo := Todo{Sequential: true}
+		var dependsOn []interface{}
+		switch e := entry.(type) {
+		case string:
+			todo.Task = e
+		case map[string]interface{}:
+			task, ok := e["task"].(string)
+			if !ok {
+				return nil, fmt.Errorf("todo %d has no task", i)
+			}
+			todo.Task = task
+			if id, ok := e["id"]; ok {
+				if todo.ID, ok = id.(string); !ok || todo.ID == "" {
+					return nil, fmt.Errorf("todo %d: id must be a non-empty string", i)
+				}
+			}
+			if value, ok := e["depends_on"]; ok {
+				if dependsOn, ok = value.([]interface{}); !ok && value != nil {
+					return nil, fmt.Errorf("todo %d: depends_on must be a list", i)
+				}
+				todo.Sequential = false
+			}
+		default:
+			return nil, fmt.Errorf("todo %d must be a string or an object, got %T", i, entry)
+		}
+
+		if todo.Task = strings.TrimSpace(todo.Task); todo.Task == "" {
+			continue
+		}
+		if todo.ID != "" {
+			if _, exists := ids[todo.ID]; exists {
+				return nil, fmt.Errorf("todo %d: id %q is used twice", i, todo.ID)
+			}
+			ids[todo.ID] = len(todos)
+		}
+		positions[i] = len(todos)
+		todos = append(todos, todo)
+		dependencies = append(dependencies, dependsOn)
+		if max > 0 && len(todos) > max {
+			return nil, fmt.Errorf("too many todos: the limit is %d", max)
+		}
+	}
+
+	// Resolve the dependencies to todos
+	for i, dependsOn := range dependencies {
+		for _, dependency := range dependsOn {
+			var index int
+			var ok bool
+			switch d := dependency.(type) {
+			case float64:
+				index, ok = positions[int(d)]
+				ok = ok && d == float64(int(d))
+			case int:
+				index, ok = positions[d]
+			case string:
+				index, ok = ids[d]
+			}
+			if !ok {
+				return nil, fmt.Errorf("todo %q depends on unknown todo %v", todos[i].Task, dependency)
+			}
+			todos[i].DependsOn = append(todos[i].DependsOn, index)
+		}
+	}
+	if err := checkTodoCycles(todos); err != nil {
+		return nil, err
+	}
+	return todos, nil
+}
+
+// SequentialTodos makes todos that run one after another
+func SequentialTodos(tasks []string) []Todo {
+	todos := make([]Todo, len(tasks))
+	for i, task := range tasks {
+		todos[i] = Todo{Task: task, Sequential: true}
+	}
+	return todos
+}
+
+// checkTodoCycles refuses todos that wait on each other, directly or through
+// others, and so could never run. A sequential todo waits on every todo
+// before it.
+func checkTodoCycles(todos []Todo) error {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make([]int, len(todos))
+
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case visiting:
+			return fmt.Errorf("todo %q depends on itself, directly or through other todos", todos[i].Task)
+		case done:
+			return nil
+		}
+		state[i] = visiting
+		waitsOn := append([]int(nil), todos[i].DependsOn...)
+		if todos[i].Sequential {
+			for j := 0; j < i; j++ {
+				waitsOn = append(waitsOn, j)
+			}
+		}
+		for _, j := range waitsOn {
+			if err := visit(j); err != nil {
+				return err
+			}
+		}
+		state[i] = done
+		return nil
+	}
+
+	for i := range todos {
+		if err := visit(i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
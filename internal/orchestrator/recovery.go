@@ -13,11 +13,11 @@ import (
 type RecoveryOrchestrator struct {
 	*BaseOrchestrator
 	workflowEngine WorkflowEngine
-	pluginMgr      interfaces.PluginManager
+	pluginMgr      AgentRegistry
 }
 
 // NewRecoveryOrchestrator creates a new recovery orchestrator
-func NewRecoveryOrchestrator(workflowEngine WorkflowEngine, pluginMgr interfaces.PluginManager, config map[string]interface{}) *RecoveryOrchestrator {
+func NewRecoveryOrchestrator(workflowEngine WorkflowEngine, pluginMgr AgentRegistry, config map[string]interface{}) *RecoveryOrchestrator {
 	base := NewBaseOrchestrator("error-recovery", config)
 
 	return &RecoveryOrchestrator{
@@ -89,7 +89,7 @@ func (ro *RecoveryOrchestrator) analyzeFailures(workflow *Workflow) map[string]i
 				errorPatterns["permission_denied"]++
 			} else if strings.Contains(task.Error, "timeout") {
 				errorPatterns["timeout"]++
-			} else if strings.Contains(task.Error, "network") {
+			} else if strings.Contains(task.Error, "network") || strings.Contains(task.Error, "rate limited") {
 				errorPatterns["network_error"]++
 			} else {
 				errorPatterns["unknown_error"]++
@@ -175,12 +175,13 @@ func (ro *RecoveryOrchestrator) executeRecoveryPlan(ctx context.Context, workflo
 func (ro *RecoveryOrchestrator) standardRetry(ctx context.Context, workflow *Workflow, recoveryPlan map[string]interface{}) (interfaces.AgentOutput, error) {
 	failedTasks, _ := recoveryPlan["failed_tasks"].([]Task)
 
-	// Reset failed tasks to pending
-	for i := range workflow.Tasks {
-		if workflow.Tasks[i].Status == TaskStatusFailed {
-			workflow.Tasks[i].Status = TaskStatusPending
-			workflow.Tasks[i].Error = ""
-		}
+	// Reset failed tasks to pending; workflow is a copy, so the engine's
+	// own workflow is reset
+	if _, err := ro.workflowEngine.ResetFailedTasks(workflow.ID); err != nil {
+		return interfaces.AgentOutput{
+			Success: false,
+			Error:   fmt.Sprintf("recovery failed: %v", err),
+		}, nil
 	}
 
 	// Execute workflow again
@@ -1,19 +1,15 @@
 package orchestrator
 
-import (
-	"fmt"
-
-	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
-)
+import "fmt"
 
 // TaskRouterImpl implements TaskRouter interface
 type TaskRouterImpl struct {
 	agentMappings map[string]string
-	pluginMgr     interfaces.PluginManager
+	pluginMgr     AgentRegistry
 }
 
 // NewTaskRouter creates a new task router
-func NewTaskRouter(pluginMgr interfaces.PluginManager) *TaskRouterImpl {
+func NewTaskRouter(pluginMgr AgentRegistry) *TaskRouterImpl {
 	agentMappings := map[string]string{
 		"ls-agent":    "ls",
 		"grep-agent":  "grep",
@@ -50,6 +46,37 @@ func (tr *TaskRouterImpl) RouteTask(todo *ParsedTodo) (string, map[string]interf
 	return agentName, todo.Arguments, nil
 }
 
+// MatchTask routes a parsed todo to a loaded agent, by its name or as
+// RouteTask maps it. The match carries the parser's confidence; a todo no
+// pattern matched, or whose agent is not loaded, is unmapped.
+func (tr *TaskRouterImpl) MatchTask(todo *ParsedTodo) RouteMatch {
+	if todo == nil {
+		return RouteMatch{Unmapped: true, Reason: "todo is nil"}
+	}
+
+	match := RouteMatch{AgentName: todo.AgentName, Arguments: todo.Arguments}
+	if tr.agentExists(todo.AgentName) {
+		match.Confidence = todo.Confidence
+		return match
+	}
+
+	agentName, args, err := tr.RouteTask(todo)
+	switch {
+	case err != nil:
+		match.Unmapped = true
+		match.Reason = fmt.Sprintf("no agent matches %q; rephrase it to start with what to do, such as list, create, or search", todo.Cleaned)
+	case !tr.agentExists(agentName):
+		match.AgentName = agentName
+		match.Unmapped = true
+		match.Reason = fmt.Sprintf("agent %s is not loaded", agentName)
+	default:
+		match.AgentName = agentName
+		match.Arguments = args
+		match.Confidence = todo.Confidence
+	}
+	return match
+}
+
 // ListAgents returns list of available agents
 func (tr *TaskRouterImpl) ListAgents() []string {
 	if tr.pluginMgr == nil {
@@ -97,6 +124,9 @@ func (tr *TaskRouterImpl) GetAgentCapabilities(agentName string) ([]string, erro
 
 // agentExists checks if an agent is available
 func (tr *TaskRouterImpl) agentExists(agentName string) bool {
+	if tr.pluginMgr == nil {
+		return false
+	}
 	_, exists := tr.pluginMgr.GetAgent(agentName)
 	return exists
 }
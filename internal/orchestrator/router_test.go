@@ -0,0 +1,36 @@
+package orchestrator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+func TestTaskRouter_MatchTask(t *testing.T) {
+	ls := &flakyAgent{stubAgent: stubAgent{name: "ls"}}
+	pm := &stubPluginManager{agents: map[string]interfaces.Agent{"ls": ls}}
+	parser := NewTodoParser()
+	router := NewTaskRouter(pm)
+
+	match := func(todo string) RouteMatch {
+		parsed, err := parser.ParseTodo(todo)
+		if err != nil {
+			t.Fatalf("ParseTodo failed: %v", err)
+		}
+		return router.MatchTask(parsed)
+	}
+
+	if m := match("list the project directory"); m.Unmapped || m.AgentName != "ls" || m.Arguments["path"] != "." || m.Confidence != 1 {
+		t.Errorf("Expected the todo to be routed to ls, got %+v", m)
+	}
+	if m := match("create notes.txt"); !m.Unmapped || m.AgentName != "touch" || m.Reason != "agent touch is not loaded" || m.Confidence != 0 {
+		t.Errorf("Expected touch to be flagged as not loaded, got %+v", m)
+	}
+	if m := match("water the plants"); !m.Unmapped || !strings.Contains(m.Reason, "rephrase") {
+		t.Errorf("Expected the todo to be flagged for rephrasing, got %+v", m)
+	}
+	if ls.calls != 0 {
+		t.Errorf("Expected no agent to run, got %d calls", ls.calls)
+	}
+}
@@ -0,0 +1,104 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// ErrWorkflowNotFound is returned when a store has no workflow with an ID
+var ErrWorkflowNotFound = errors.New("workflow not found")
+
+// WorkflowStore keeps workflows across engine restarts
+type WorkflowStore interface {
+	Save(workflow *Workflow) error
+	Load(workflowID string) (*Workflow, error)
+	// List returns the workflows in the order they were created
+	List() ([]Workflow, error)
+	Delete(workflowID string) error
+}
+
+// workflowPrefix prefixes the keys of stored workflows
+const workflowPrefix = "workflow:"
+
+// LevelDBStore is a WorkflowStore in a LevelDB database
+type LevelDBStore struct {
+	db *leveldb.DB
+}
+
+// NewLevelDBStore opens, or creates, the workflow store in dir
+func NewLevelDBStore(dir string) (*LevelDBStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create workflows directory: %w", err)
+	}
+
+	db, err := leveldb.OpenFile(dir, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open workflows database: %w", err)
+	}
+	return &LevelDBStore{db: db}, nil
+}
+
+// Save writes a workflow, replacing its earlier state
+func (s *LevelDBStore) Save(workflow *Workflow) error {
+	data, err := json.Marshal(workflow)
+	if err != nil {
+		return fmt.Errorf("failed to encode workflow %s: %w", workflow.ID, err)
+	}
+	return s.db.Put([]byte(workflowPrefix+workflow.ID), data, nil)
+}
+
+// Load reads a workflow, failing with ErrWorkflowNotFound when there is none
+func (s *LevelDBStore) Load(workflowID string) (*Workflow, error) {
+	data, err := s.db.Get([]byte(workflowPrefix+workflowID), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, ErrWorkflowNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	workflow := &Workflow{}
+	if err := json.Unmarshal(data, workflow); err != nil {
+		return nil, fmt.Errorf("failed to decode workflow %s: %w", workflowID, err)
+	}
+	return workflow, nil
+}
+
+// List returns every stored workflow, oldest first
+func (s *LevelDBStore) List() ([]Workflow, error) {
+	iter := s.db.NewIterator(util.BytesPrefix([]byte(workflowPrefix)), nil)
+	defer iter.Release()
+
+	workflows := []Workflow{}
+	for iter.Next() {
+		var workflow Workflow
+		if err := json.Unmarshal(iter.Value(), &workflow); err != nil {
+			return nil, fmt.Errorf("failed to decode workflow %s: %w", string(iter.Key()[len(workflowPrefix):]), err)
+		}
+		workflows = append(workflows, workflow)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, fmt.Errorf("failed to list workflows: %w", err)
+	}
+
+	sort.SliceStable(workflows, func(i, j int) bool {
+		return workflows[i].CreatedAt.Before(workflows[j].CreatedAt)
+	})
+	return workflows, nil
+}
+
+// Delete removes a workflow; removing one that is not stored is not an error
+func (s *LevelDBStore) Delete(workflowID string) error {
+	return s.db.Delete([]byte(workflowPrefix+workflowID), nil)
+}
+
+// Close closes the database
+func (s *LevelDBStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,136 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+// flakyAgent fails its first failures calls, then succeeds
+type flakyAgent struct {
+	stubAgent
+	failures int
+	calls    int
+}
+
+func (a *flakyAgent) Process(ctx context.Context, input interfaces.AgentInput) (interfaces.AgentOutput, error) {
+	a.calls++
+	if a.calls <= a.failures {
+		return interfaces.AgentOutput{Success: false, Error: "not yet"}, nil
+	}
+	return interfaces.AgentOutput{Success: true, Data: map[string]interface{}{"agent": a.name}}, nil
+}
+
+func TestLevelDBStore_SaveLoadListDelete(t *testing.T) {
+	store, err := NewLevelDBStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLevelDBStore failed: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Now()
+	for i, id := range []string{"b", "a"} {
+		workflow := &Workflow{ID: id, Name: id, Status: WorkflowStatusPending, CreatedAt: now.Add(time.Duration(i) * time.Second)}
+		if err := store.Save(workflow); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+	}
+
+	workflow, err := store.Load("a")
+	if err != nil || workflow.Name != "a" {
+		t.Fatalf("Expected workflow a, got %+v, %v", workflow, err)
+	}
+	if _, err := store.Load("missing"); !errors.Is(err, ErrWorkflowNotFound) {
+		t.Errorf("Expected ErrWorkflowNotFound, got %v", err)
+	}
+
+	workflows, err := store.List()
+	if err != nil || len(workflows) != 2 || workflows[0].ID != "b" || workflows[1].ID != "a" {
+		t.Fatalf("Expected b then a in creation order, got %+v, %v", workflows, err)
+	}
+
+	if err := store.Delete("b"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if workflows, _ := store.List(); len(workflows) != 1 {
+		t.Errorf("Expected one workflow left, got %d", len(workflows))
+	}
+}
+
+func TestManager_ResumesPersistedWorkflows(t *testing.T) {
+	dir := t.TempDir()
+	first := &flakyAgent{stubAgent: stubAgent{name: "first"}}
+	flaky := &flakyAgent{stubAgent: stubAgent{name: "flaky"}, failures: 1}
+	pm := &stubPluginManager{agents: map[string]interfaces.Agent{"first": first, "flaky": flaky}}
+
+	store, err := NewLevelDBStore(dir)
+	if err != nil {
+		t.Fatalf("NewLevelDBStore failed: %v", err)
+	}
+	engine := NewWorkflowEngine(pm, NewTodoParser(), nil)
+	engine.SetStore(store)
+
+	// One workflow fails a task
+	failed, err := engine.CreateWorkflow("failing", SequentialTodos([]string{"first", "flaky"}), nil)
+	if err != nil {
+		t.Fatalf("CreateWorkflow failed: %v", err)
+	}
+	failed.Tasks[0].AgentName, failed.Tasks[1].AgentName = "first", "flaky"
+	if result, err := engine.ExecuteWorkflow(t.Context(), failed); err != nil || result.Status != WorkflowStatusFailed {
+		t.Fatalf("Expected the workflow to fail, got %+v, %v", result, err)
+	}
+
+	// Another was running its second task when the engine stopped
+	running := &Workflow{ID: "running", Name: "running", Status: WorkflowStatusRunning, CreatedAt: time.Now(), Tasks: []Task{
+		{ID: "running-task-0", AgentName: "first", Status: TaskStatusCompleted},
+		{ID: "running-task-1", AgentName: "flaky", Status: TaskStatusRunning},
+	}, Results: []TaskResult{{TaskID: "running-task-0", AgentName: "first", Success: true}}}
+	if err := store.Save(running); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	store.Close()
+
+	store, err = NewLevelDBStore(dir)
+	if err != nil {
+		t.Fatalf("Reopening the store failed: %v", err)
+	}
+	defer store.Close()
+	manager := NewManager(pm, nil)
+	if err := manager.SetStore(store); err != nil {
+		t.Fatalf("SetStore failed: %v", err)
+	}
+
+	restored, ok := manager.workflowEngine.GetWorkflow("running")
+	if !ok || restored.Status != WorkflowStatusInterrupted || restored.Tasks[1].Status != TaskStatusInterrupted {
+		t.Fatalf("Expected the running workflow to be interrupted, got %+v", restored)
+	}
+	if restored, ok := manager.workflowEngine.GetWorkflow(failed.ID); !ok || restored.Status != WorkflowStatusFailed {
+		t.Fatalf("Expected the failed workflow to be restored, got %+v", restored)
+	}
+
+	for _, id := range []string{failed.ID, "running"} {
+		output, err := manager.Process(t.Context(), interfaces.AgentInput{
+			Type:    "error-recovery",
+			Payload: map[string]interface{}{"workflow_id": id},
+		})
+		if err != nil || !output.Success {
+			t.Fatalf("Expected %s to be recovered, got %+v, %v", id, output, err)
+		}
+
+		saved, err := store.Load(id)
+		if err != nil || saved.Status != WorkflowStatusCompleted {
+			t.Fatalf("Expected %s to be saved as completed, got %+v, %v", id, saved, err)
+		}
+		if len(saved.Results) != 2 {
+			t.Errorf("Expected both tasks' results for %s, got %+v", id, saved.Results)
+		}
+	}
+
+	// Only the tasks that had not completed ran again
+	if first.calls != 1 || flaky.calls != 3 {
+		t.Errorf("Expected first to run once and flaky three times, got %d and %d", first.calls, flaky.calls)
+	}
+}
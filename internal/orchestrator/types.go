@@ -65,6 +65,13 @@ func (bo *BaseOrchestrator) HealthCheck() error {
 	}
 }
 
+// AgentRegistry finds the agents workflow tasks run, such as the plugin
+// manager
+type AgentRegistry interface {
+	GetAgent(name string) (interfaces.Agent, bool)
+	ListAgents() []string
+}
+
 // Workflow represents a collection of tasks to be executed
 type Workflow struct {
 	ID          string                 `json:"id"`
@@ -77,6 +84,20 @@ type Workflow struct {
 	CompletedAt *time.Time             `json:"completed_at,omitempty"`
 	Context     map[string]interface{} `json:"context"`
 	Results     []TaskResult           `json:"results"`
+	// Plan is how the todos were routed when the workflow was created
+	Plan []PlanStep `json:"plan,omitempty"`
+}
+
+// Todo is a todo of a manager request and the todos it waits for
+type Todo struct {
+	Task string `json:"task"`
+	// ID, when set, names the todo for the depends_on of others
+	ID string `json:"id,omitempty"`
+	// DependsOn holds the indices of the todos that must complete first
+	DependsOn []int `json:"depends_on,omitempty"`
+	// Sequential todos run after every todo before them has finished,
+	// whatever its outcome
+	Sequential bool `json:"sequential,omitempty"`
 }
 
 // Task represents a single task to be executed by an agent
@@ -89,7 +110,14 @@ type Task struct {
 	Error       string                 `json:"error,omitempty"`
 	Duration    time.Duration          `json:"duration"`
 	Context     map[string]interface{} `json:"context"`
-	DependsOn   []string               `json:"depends_on,omitempty"`
+	// DependsOn holds the IDs of the tasks that must complete first; the
+	// task is skipped if one of them does not
+	DependsOn []string `json:"depends_on,omitempty"`
+	// Sequential tasks run after every task before them has finished,
+	// whatever its outcome
+	Sequential  bool       `json:"sequential,omitempty"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
 }
 
 // TaskResult represents the result of a task execution
@@ -100,6 +128,7 @@ type TaskResult struct {
 	Data      map[string]interface{} `json:"data,omitempty"`
 	Error     string                 `json:"error,omitempty"`
 	Duration  time.Duration          `json:"duration"`
+	StartedAt time.Time              `json:"started_at"`
 	Timestamp time.Time              `json:"timestamp"`
 }
 
@@ -113,6 +142,9 @@ const (
 	WorkflowStatusCompleted WorkflowStatus = "completed"
 	WorkflowStatusFailed    WorkflowStatus = "failed"
 	WorkflowStatusCancelled WorkflowStatus = "cancelled"
+	// WorkflowStatusInterrupted marks a workflow that was running when the
+	// engine stopped
+	WorkflowStatusInterrupted WorkflowStatus = "interrupted"
 )
 
 // TaskStatus represents the status of a task
@@ -124,6 +156,12 @@ const (
 	TaskStatusCompleted TaskStatus = "completed"
 	TaskStatusFailed    TaskStatus = "failed"
 	TaskStatusCancelled TaskStatus = "cancelled"
+	// TaskStatusInterrupted marks a task that was running when the engine
+	// stopped
+	TaskStatusInterrupted TaskStatus = "interrupted"
+	// TaskStatusSkipped marks a task one of whose dependencies did not
+	// complete
+	TaskStatusSkipped TaskStatus = "skipped"
 )
 
 // WorkflowResult represents the final result of a workflow execution
@@ -133,6 +171,8 @@ type WorkflowResult struct {
 	TotalTasks     int                    `json:"total_tasks"`
 	CompletedTasks int                    `json:"completed_tasks"`
 	FailedTasks    int                    `json:"failed_tasks"`
+	CancelledTasks int                    `json:"cancelled_tasks"`
+	SkippedTasks   int                    `json:"skipped_tasks"`
 	Duration       time.Duration          `json:"duration"`
 	Tasks          []TaskResult           `json:"tasks"`
 	Context        map[string]interface{} `json:"context"`
@@ -149,13 +189,41 @@ type ParsedTodo struct {
 	Confidence float64                `json:"confidence"`
 }
 
+// RouteMatch is the agent a router matched a parsed todo to
+type RouteMatch struct {
+	AgentName string                 `json:"agent_name"`
+	Arguments map[string]interface{} `json:"arguments"`
+	// Confidence scores the match from 0 to 1, and is 0 when unmapped
+	Confidence float64 `json:"confidence"`
+	// Unmapped todos match no loaded agent; Reason says why
+	Unmapped bool   `json:"unmapped,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// PlanStep is how a todo would run: the agent it is routed to and the
+// todos it waits for
+type PlanStep struct {
+	Index     int    `json:"index"`
+	Task      string `json:"task"`
+	ID        string `json:"id,omitempty"`
+	DependsOn []int  `json:"depends_on,omitempty"`
+	RouteMatch
+}
+
 // WorkflowEngine handles workflow execution and task management
 type WorkflowEngine interface {
-	CreateWorkflow(name string, todos []string, context map[string]interface{}) (*Workflow, error)
+	CreateWorkflow(name string, todos []Todo, context map[string]interface{}) (*Workflow, error)
+	// PlanWorkflow routes todos as CreateWorkflow would, without creating
+	// a workflow or running an agent
+	PlanWorkflow(todos []Todo) ([]PlanStep, error)
 	ExecuteWorkflow(ctx context.Context, workflow *Workflow) (*WorkflowResult, error)
 	GetWorkflow(workflowID string) (*Workflow, bool)
 	ListWorkflows() []Workflow
 	CancelWorkflow(workflowID string) error
+	// ResetFailedTasks makes a failed, cancelled, or interrupted workflow's
+	// unfinished tasks pending again, returning how many there are, so that
+	// ExecuteWorkflow runs them
+	ResetFailedTasks(workflowID string) (int, error)
 }
 
 // TodoParser handles parsing of todo lists into tasks
@@ -167,6 +235,9 @@ type TodoParser interface {
 // TaskRouter handles routing of tasks to appropriate agents
 type TaskRouter interface {
 	RouteTask(todo *ParsedTodo) (string, map[string]interface{}, error)
+	// MatchTask routes a todo as RouteTask does, scoring the match and
+	// flagging todos no loaded agent serves
+	MatchTask(todo *ParsedTodo) RouteMatch
 	ListAgents() []string
 	GetAgentCapabilities(agentName string) ([]string, error)
 }
@@ -0,0 +1,740 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+// Events the workflow engine publishes as workflows run
+const (
+	EventTopic            = "workflows"
+	EventWorkflowStart    = "workflow_start"
+	EventWorkflowTask     = "workflow_task"
+	EventWorkflowComplete = "workflow_complete"
+)
+
+// WorkflowEngineImpl implements WorkflowEngine interface
+type WorkflowEngineImpl struct {
+	workflows map[string]*Workflow
+	mu        sync.RWMutex
+	pluginMgr AgentRegistry
+	parser    TodoParser
+	router    TaskRouter
+	// caller, when set, runs tasks instead of calling agents directly;
+	// publish, when set, receives the workflow events
+	caller  interfaces.AgentCaller
+	publish interfaces.EventFunc
+	// store, when set, keeps each workflow's state as it changes
+	store WorkflowStore
+	// maxParallel bounds the tasks of a workflow that run at once
+	maxParallel int
+}
+
+// NewWorkflowEngine creates a new workflow engine
+func NewWorkflowEngine(pluginMgr AgentRegistry, parser TodoParser, router TaskRouter) *WorkflowEngineImpl {
+	return &WorkflowEngineImpl{
+		workflows:   make(map[string]*Workflow),
+		pluginMgr:   pluginMgr,
+		parser:      parser,
+		router:      router,
+		maxParallel: 1,
+	}
+}
+
+// SetMaxParallel sets how many of a workflow's tasks may run at once; the
+// engine runs one at a time until it is set. Values below one are ignored.
+func (we *WorkflowEngineImpl) SetMaxParallel(max int) {
+	if max > 0 {
+		we.maxParallel = max
+	}
+}
+
+// SetAgentCaller sets what runs the agent of each task, such as a caller
+// that checks the engine's policy first
+func (we *WorkflowEngineImpl) SetAgentCaller(caller interfaces.AgentCaller) {
+	we.caller = caller
+}
+
+// SetEventFunc sets where workflow events are published
+func (we *WorkflowEngineImpl) SetEventFunc(publish interfaces.EventFunc) {
+	we.publish = publish
+}
+
+// SetStore sets where workflows are saved as they change
+func (we *WorkflowEngineImpl) SetStore(store WorkflowStore) {
+	we.store = store
+}
+
+// RestoreWorkflows loads the workflows the store holds unfinished, as after
+// a restart. Those that were still running are marked interrupted, and their
+// running tasks with them, so error recovery can resume them.
+func (we *WorkflowEngineImpl) RestoreWorkflows() error {
+	if we.store == nil {
+		return nil
+	}
+	workflows, err := we.store.List()
+	if err != nil {
+		return err
+	}
+
+	we.mu.Lock()
+	defer we.mu.Unlock()
+	for i := range workflows {
+		workflow := &workflows[i]
+		switch workflow.Status {
+		case WorkflowStatusCompleted:
+			continue
+		case WorkflowStatusPending, WorkflowStatusRunning, WorkflowStatusPaused:
+			workflow.Status = WorkflowStatusInterrupted
+			for j := range workflow.Tasks {
+				if workflow.Tasks[j].Status == TaskStatusRunning {
+					workflow.Tasks[j].Status = TaskStatusInterrupted
+					workflow.Tasks[j].Error = "interrupted by an engine restart"
+				}
+			}
+			we.save(workflow)
+		}
+		we.workflows[workflow.ID] = workflow
+	}
+	return nil
+}
+
+// save writes a workflow to the store, if there is one. A failed write is
+// logged rather than failing the workflow.
+func (we *WorkflowEngineImpl) save(workflow *Workflow) {
+	if we.store == nil {
+		return
+	}
+	if err := we.store.Save(workflow); err != nil {
+		log.Printf("orchestrator: failed to save workflow %s: %v", workflow.ID, err)
+	}
+}
+
+// publishEvent publishes a workflow event on the workflows topic
+func (we *WorkflowEngineImpl) publishEvent(eventType string, workflow *Workflow, fields map[string]interface{}) {
+	if we.publish == nil {
+		return
+	}
+	event := map[string]interface{}{
+		"type":        eventType,
+		"topic":       EventTopic,
+		"workflow_id": workflow.ID,
+		"name":        workflow.Name,
+		"timestamp":   time.Now(),
+	}
+	for key, value := range fields {
+		event[key] = value
+	}
+	we.publish(event)
+}
+
+// saveTask saves a workflow with the results so far after one of its tasks
+// changed status, and publishes the task; the caller holds the lock
+func (we *WorkflowEngineImpl) saveTask(workflow *Workflow, task *Task, results []TaskResult) {
+	workflow.Results = results
+	we.save(workflow)
+	we.publishTask(workflow, task)
+}
+
+// publishTask publishes the status of a task
+func (we *WorkflowEngineImpl) publishTask(workflow *Workflow, task *Task) {
+	fields := map[string]interface{}{
+		"task_id":     task.ID,
+		"agent":       task.AgentName,
+		"description": task.Description,
+		"status":      task.Status,
+	}
+	if task.Status != TaskStatusRunning {
+		fields["duration_ms"] = float64(task.Duration.Microseconds()) / 1000
+	}
+	if task.Error != "" {
+		fields["error"] = task.Error
+	}
+	we.publishEvent(EventWorkflowTask, workflow, fields)
+}
+
+// CreateWorkflow creates a new workflow from todos
+func (we *WorkflowEngineImpl) CreateWorkflow(name string, todos []Todo, context map[string]interface{}) (*Workflow, error) {
+	we.mu.Lock()
+	defer we.mu.Unlock()
+
+	// Generate workflow ID
+	workflowID := fmt.Sprintf("workflow-%d-%d", time.Now().Unix(), len(we.workflows))
+
+	// Parse todos into tasks
+	descriptions, parsedTodos, err := we.parseTodos(todos)
+	if err != nil {
+		return nil, err
+	}
+
+	// Name the tasks first, so dependencies can refer to them
+	taskIDs := make([]string, len(todos))
+	seen := make(map[string]bool)
+	for i, todo := range todos {
+		taskIDs[i] = fmt.Sprintf("%s-task-%d", workflowID, i)
+		if todo.ID != "" {
+			taskIDs[i] = fmt.Sprintf("%s-%s", workflowID, todo.ID)
+		}
+		if seen[taskIDs[i]] {
+			return nil, fmt.Errorf("todo %d: task ID %s is used twice", i, taskIDs[i])
+		}
+		seen[taskIDs[i]] = true
+	}
+
+	// Create tasks from parsed todos
+	tasks := make([]Task, 0, len(parsedTodos))
+	for i, parsed := range parsedTodos {
+		task := Task{
+			ID:          taskIDs[i],
+			AgentName:   parsed.AgentName,
+			Description: parsed.Cleaned,
+			Arguments:   parsed.Arguments,
+			Status:      TaskStatusPending,
+			Context:     make(map[string]interface{}),
+			Sequential:  todos[i].Sequential,
+		}
+		for _, dependency := range todos[i].DependsOn {
+			if dependency < 0 || dependency >= len(todos) {
+				return nil, fmt.Errorf("todo %d depends on unknown todo %d", i, dependency)
+			}
+			task.DependsOn = append(task.DependsOn, taskIDs[dependency])
+		}
+		tasks = append(tasks, task)
+	}
+
+	// Create workflow
+	workflow := &Workflow{
+		ID:        workflowID,
+		Name:      name,
+		Todos:     descriptions,
+		Tasks:     tasks,
+		Status:    WorkflowStatusPending,
+		CreatedAt: time.Now(),
+		Context:   context,
+		Results:   make([]TaskResult, 0),
+		Plan:      we.planTodos(todos, parsedTodos),
+	}
+
+	we.workflows[workflowID] = workflow
+	we.save(workflow)
+	return workflow, nil
+}
+
+// PlanWorkflow parses and routes todos without creating a workflow, so
+// that no agent runs
+func (we *WorkflowEngineImpl) PlanWorkflow(todos []Todo) ([]PlanStep, error) {
+	_, parsedTodos, err := we.parseTodos(todos)
+	if err != nil {
+		return nil, err
+	}
+	return we.planTodos(todos, parsedTodos), nil
+}
+
+// parseTodos parses the tasks of todos
+func (we *WorkflowEngineImpl) parseTodos(todos []Todo) ([]string, []*ParsedTodo, error) {
+	descriptions := make([]string, len(todos))
+	for i, todo := range todos {
+		descriptions[i] = todo.Task
+	}
+	parsedTodos, err := we.parser.ParseMultiple(descriptions)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse todos: %w", err)
+	}
+	return descriptions, parsedTodos, nil
+}
+
+// planTodos routes parsed todos. Without a router only todos naming a
+// loaded agent are mapped.
+func (we *WorkflowEngineImpl) planTodos(todos []Todo, parsedTodos []*ParsedTodo) []PlanStep {
+	plan := make([]PlanStep, len(parsedTodos))
+	for i, parsed := range parsedTodos {
+		var match RouteMatch
+		if we.router != nil {
+			match = we.router.MatchTask(parsed)
+		} else if _, exists := we.pluginMgr.GetAgent(parsed.AgentName); exists {
+			match = RouteMatch{AgentName: parsed.AgentName, Arguments: parsed.Arguments, Confidence: parsed.Confidence}
+		} else {
+			match = RouteMatch{AgentName: parsed.AgentName, Arguments: parsed.Arguments, Unmapped: true, Reason: fmt.Sprintf("agent %s not found", parsed.AgentName)}
+		}
+		plan[i] = PlanStep{
+			Index:      i,
+			Task:       parsed.Cleaned,
+			ID:         todos[i].ID,
+			DependsOn:  todos[i].DependsOn,
+			RouteMatch: match,
+		}
+	}
+	return plan
+}
+
+// taskOutcome is what a task's agent returned
+type taskOutcome struct {
+	task       *Task
+	output     interfaces.AgentOutput
+	err        error
+	start, end time.Time
+}
+
+// ExecuteWorkflow executes a workflow's tasks that have not completed, such
+// as those ResetFailedTasks made pending again. A task starts once the tasks
+// it depends on have completed, and once every task before it has finished
+// if it is sequential; up to the engine's parallelism limit run at once, the
+// earlier ones first. The dependents of a task that does not complete are
+// skipped. If ctx is cancelled part way through, no further tasks are
+// dispatched, the running and pending tasks are marked cancelled, and the
+// result still carries every completed task's output. The engine's own copy
+// of the workflow is run when it has one, so a copy from GetWorkflow may be
+// passed.
+func (we *WorkflowEngineImpl) ExecuteWorkflow(ctx context.Context, workflow *Workflow) (*WorkflowResult, error) {
+	we.mu.Lock()
+	if stored, exists := we.workflows[workflow.ID]; exists {
+		workflow = stored
+	}
+	if workflow.Status != WorkflowStatusPending {
+		we.mu.Unlock()
+		return nil, fmt.Errorf("workflow %s is not pending", workflow.ID)
+	}
+
+	workflow.Status = WorkflowStatusRunning
+	if workflow.StartedAt == nil {
+		now := time.Now()
+		workflow.StartedAt = &now
+	}
+	we.save(workflow)
+	we.publishEvent(EventWorkflowStart, workflow, map[string]interface{}{"total_tasks": len(workflow.Tasks)})
+
+	// Keep the results of the tasks an earlier run completed. Tasks are only
+	// changed here, under the lock, which is released while waiting; the
+	// agents run in their own goroutines and send back what they returned.
+	start := time.Now()
+	results := completedResults(workflow)
+	we.mu.Unlock()
+	outcomes := make(chan taskOutcome)
+	running := 0
+
+	for {
+		// Settle the tasks that can no longer run and start those that can,
+		// until a pass changes nothing
+		we.mu.Lock()
+		changed := false
+		for i := range workflow.Tasks {
+			task := &workflow.Tasks[i]
+			if task.Status != TaskStatusPending {
+				continue
+			}
+
+			// Stop dispatching once the workflow has been cancelled
+			if ctx.Err() != nil {
+				task.Status = TaskStatusCancelled
+				task.Error = ctx.Err().Error()
+				we.saveTask(workflow, task, results)
+				changed = true
+				continue
+			}
+
+			ready, unmet := taskReady(workflow, i)
+			if unmet != "" {
+				task.Status = TaskStatusSkipped
+				task.Error = fmt.Sprintf("dependency %s did not complete", unmet)
+				we.saveTask(workflow, task, results)
+				changed = true
+				continue
+			}
+			if !ready || running >= we.maxParallel {
+				continue
+			}
+
+			changed = true
+			if result, ok := we.startTask(ctx, workflow, task, outcomes); !ok {
+				results = append(results, result)
+				we.saveTask(workflow, task, results)
+				continue
+			}
+			running++
+		}
+		we.mu.Unlock()
+
+		if running == 0 {
+			if changed {
+				continue
+			}
+			break
+		}
+		outcome := <-outcomes
+		running--
+		we.mu.Lock()
+		if result, ok := finishTask(ctx, outcome); ok {
+			results = append(results, result)
+		}
+		we.saveTask(workflow, outcome.task, results)
+		we.mu.Unlock()
+	}
+
+	we.mu.Lock()
+	defer we.mu.Unlock()
+
+	// Tasks still pending wait on tasks that can never finish, such as
+	// dependencies on unknown tasks
+	for i := range workflow.Tasks {
+		task := &workflow.Tasks[i]
+		if task.Status == TaskStatusPending {
+			task.Status = TaskStatusSkipped
+			task.Error = "dependencies cannot complete"
+			we.saveTask(workflow, task, results)
+		}
+	}
+
+	completedTasks, failedTasks, cancelledTasks, skippedTasks := 0, 0, 0, 0
+	for _, task := range workflow.Tasks {
+		switch task.Status {
+		case TaskStatusCompleted:
+			completedTasks++
+		case TaskStatusFailed:
+			failedTasks++
+		case TaskStatusCancelled:
+			cancelledTasks++
+		case TaskStatusSkipped:
+			skippedTasks++
+		}
+	}
+
+	// Update workflow status
+	status := WorkflowStatusCompleted
+	summary := fmt.Sprintf("Workflow '%s' completed: %d/%d tasks successful (%d failed)",
+		workflow.Name, completedTasks, len(workflow.Tasks), failedTasks)
+	errMessage := ""
+
+	if failedTasks > 0 || skippedTasks > 0 {
+		status = WorkflowStatusFailed
+		summary = fmt.Sprintf("Workflow '%s' failed: %d/%d tasks successful (%d failed, %d skipped)",
+			workflow.Name, completedTasks, len(workflow.Tasks), failedTasks, skippedTasks)
+	}
+	if err := ctx.Err(); err != nil {
+		status = WorkflowStatusCancelled
+		summary = fmt.Sprintf("Workflow '%s' cancelled: %d/%d tasks successful (%d failed, %d cancelled)",
+			workflow.Name, completedTasks, len(workflow.Tasks), failedTasks, cancelledTasks)
+		errMessage = err.Error()
+	}
+
+	workflow.Status = status
+	completedAt := time.Now()
+	workflow.CompletedAt = &completedAt
+	workflow.Results = results
+	we.save(workflow)
+
+	we.publishEvent(EventWorkflowComplete, workflow, map[string]interface{}{
+		"status":          status,
+		"total_tasks":     len(workflow.Tasks),
+		"completed_tasks": completedTasks,
+		"failed_tasks":    failedTasks,
+		"cancelled_tasks": cancelledTasks,
+		"skipped_tasks":   skippedTasks,
+		"summary":         summary,
+	})
+	return &WorkflowResult{
+		WorkflowID:     workflow.ID,
+		Status:         status,
+		TotalTasks:     len(workflow.Tasks),
+		CompletedTasks: completedTasks,
+		FailedTasks:    failedTasks,
+		CancelledTasks: cancelledTasks,
+		SkippedTasks:   skippedTasks,
+		Duration:       time.Since(start),
+		Tasks:          append([]TaskResult(nil), results...),
+		Context:        workflow.Context,
+		Error:          errMessage,
+		Summary:        summary,
+	}, nil
+}
+
+// taskReady reports whether the pending task at index may start, or names a
+// dependency that ended without completing, so the task never can
+func taskReady(workflow *Workflow, index int) (bool, string) {
+	task := &workflow.Tasks[index]
+	ready := true
+	for _, dependency := range task.DependsOn {
+		status := TaskStatus("")
+		for j := range workflow.Tasks {
+			if workflow.Tasks[j].ID == dependency {
+				status = workflow.Tasks[j].Status
+				break
+			}
+		}
+		switch status {
+		case TaskStatusCompleted:
+		case TaskStatusFailed, TaskStatusCancelled, TaskStatusSkipped, TaskStatusInterrupted:
+			return false, dependency
+		default:
+			ready = false
+		}
+	}
+
+	if task.Sequential {
+		for j := 0; j < index; j++ {
+			switch workflow.Tasks[j].Status {
+			case TaskStatusPending, TaskStatusRunning:
+				ready = false
+			}
+		}
+	}
+	return ready, ""
+}
+
+// startTask finds the agent of a task and runs it in a goroutine that sends
+// the outcome. A task whose agent cannot be found fails at once, with the
+// result returned. The caller holds the lock.
+func (we *WorkflowEngineImpl) startTask(ctx context.Context, workflow *Workflow, task *Task, outcomes chan<- taskOutcome) (TaskResult, bool) {
+	taskStart := time.Now()
+	task.Status = TaskStatusRunning
+	task.StartedAt = &taskStart
+	task.CompletedAt = nil
+	we.save(workflow)
+	we.publishTask(workflow, task)
+
+	// Get the agent for this task
+	agent, exists := we.pluginMgr.GetAgent(task.AgentName)
+	if !exists && we.router != nil {
+		// The parser names agents by role, such as ls-agent; the router
+		// maps them to the registered agents
+		agentName, args, err := we.router.RouteTask(&ParsedTodo{AgentName: task.AgentName, Arguments: task.Arguments})
+		if err == nil {
+			agent, exists = we.pluginMgr.GetAgent(agentName)
+			if exists {
+				task.AgentName = agentName
+				task.Arguments = args
+			}
+		}
+	}
+	if !exists {
+		taskEnd := time.Now()
+		task.Status = TaskStatusFailed
+		task.Error = fmt.Sprintf("agent %s not found", task.AgentName)
+		task.Duration = taskEnd.Sub(taskStart)
+		task.CompletedAt = &taskEnd
+		return TaskResult{
+			TaskID:    task.ID,
+			AgentName: task.AgentName,
+			Success:   false,
+			Error:     task.Error,
+			Duration:  task.Duration,
+			StartedAt: taskStart,
+			Timestamp: taskEnd,
+		}, false
+	}
+
+	// Execute the task
+	input := interfaces.AgentInput{
+		Type:    "execute",
+		Payload: task.Arguments,
+		Metadata: map[string]interface{}{
+			"workflow_id": workflow.ID,
+			"task_id":     task.ID,
+		},
+	}
+	name := task.AgentName
+	go func() {
+		var output interfaces.AgentOutput
+		var err error
+		if we.caller != nil {
+			output, err = we.caller.CallAgent(ctx, name, input)
+		} else {
+			output, err = agent.Process(ctx, input)
+		}
+		outcomes <- taskOutcome{task: task, output: output, err: err, start: taskStart, end: time.Now()}
+	}()
+	return TaskResult{}, true
+}
+
+// finishTask records the outcome of a task and returns its result, which a
+// task cancelled with the workflow does not have; the caller holds the lock
+func finishTask(ctx context.Context, outcome taskOutcome) (TaskResult, bool) {
+	task := outcome.task
+	output, err := outcome.output, outcome.err
+	task.Duration = outcome.end.Sub(outcome.start)
+	task.CompletedAt = &outcome.end
+
+	result := TaskResult{
+		TaskID:    task.ID,
+		AgentName: task.AgentName,
+		Success:   err == nil && output.Success,
+		Data:      output.Data,
+		Duration:  task.Duration,
+		StartedAt: outcome.start,
+		Timestamp: outcome.end,
+	}
+	switch {
+	case !result.Success && ctx.Err() != nil:
+		// The task was still running when the workflow was cancelled
+		task.Status = TaskStatusCancelled
+		task.Error = ctx.Err().Error()
+		return result, false
+	case !result.Success:
+		task.Status = TaskStatusFailed
+		if output.Error != "" {
+			task.Error = output.Error
+		} else {
+			task.Error = err.Error()
+		}
+		result.Error = task.Error
+	default:
+		task.Status = TaskStatusCompleted
+	}
+	return result, true
+}
+
+// GetWorkflow retrieves a copy of a workflow by ID, which a running
+// workflow does not change
+func (we *WorkflowEngineImpl) GetWorkflow(workflowID string) (*Workflow, bool) {
+	we.mu.RLock()
+	defer we.mu.RUnlock()
+
+	workflow, exists := we.workflows[workflowID]
+	if !exists {
+		return nil, false
+	}
+	return cloneWorkflow(workflow), true
+}
+
+// ListWorkflows returns copies of all workflows
+func (we *WorkflowEngineImpl) ListWorkflows() []Workflow {
+	we.mu.RLock()
+	defer we.mu.RUnlock()
+
+	workflows := make([]Workflow, 0, len(we.workflows))
+	for _, workflow := range we.workflows {
+		workflows = append(workflows, *cloneWorkflow(workflow))
+	}
+	return workflows
+}
+
+// cloneWorkflow copies a workflow with its tasks, results, and plan, and the
+// maps and times they hold; the caller holds the lock
+func cloneWorkflow(workflow *Workflow) *Workflow {
+	clone := *workflow
+	clone.Todos = append([]string(nil), workflow.Todos...)
+	clone.StartedAt = cloneTime(workflow.StartedAt)
+	clone.CompletedAt = cloneTime(workflow.CompletedAt)
+	clone.Context = cloneMap(workflow.Context)
+
+	clone.Tasks = make([]Task, len(workflow.Tasks))
+	for i, task := range workflow.Tasks {
+		task.Arguments = cloneMap(task.Arguments)
+		task.Context = cloneMap(task.Context)
+		task.DependsOn = append([]string(nil), task.DependsOn...)
+		task.StartedAt = cloneTime(task.StartedAt)
+		task.CompletedAt = cloneTime(task.CompletedAt)
+		clone.Tasks[i] = task
+	}
+
+	clone.Results = make([]TaskResult, len(workflow.Results))
+	for i, result := range workflow.Results {
+		result.Data = cloneMap(result.Data)
+		clone.Results[i] = result
+	}
+
+	if workflow.Plan != nil {
+		clone.Plan = make([]PlanStep, len(workflow.Plan))
+		for i, step := range workflow.Plan {
+			step.DependsOn = append([]int(nil), step.DependsOn...)
+			step.Arguments = cloneMap(step.Arguments)
+			clone.Plan[i] = step
+		}
+	}
+	return &clone
+}
+
+func cloneTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	copied := *t
+	return &copied
+}
+
+func cloneMap(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	copied := make(map[string]interface{}, len(m))
+	for key, value := range m {
+		copied[key] = value
+	}
+	return copied
+}
+
+// CancelWorkflow cancels a workflow
+func (we *WorkflowEngineImpl) CancelWorkflow(workflowID string) error {
+	we.mu.Lock()
+	defer we.mu.Unlock()
+
+	workflow, exists := we.workflows[workflowID]
+	if !exists {
+		return fmt.Errorf("workflow %s not found", workflowID)
+	}
+
+	if workflow.Status == WorkflowStatusCompleted || workflow.Status == WorkflowStatusCancelled {
+		return fmt.Errorf("workflow %s is already completed", workflowID)
+	}
+
+	workflow.Status = WorkflowStatusCancelled
+	we.save(workflow)
+	return nil
+}
+
+// ResetFailedTasks makes the tasks of a failed, cancelled, or interrupted
+// workflow that did not complete pending again, along with the workflow
+func (we *WorkflowEngineImpl) ResetFailedTasks(workflowID string) (int, error) {
+	we.mu.Lock()
+	defer we.mu.Unlock()
+
+	workflow, exists := we.workflows[workflowID]
+	if !exists {
+		return 0, fmt.Errorf("workflow %s not found", workflowID)
+	}
+	switch workflow.Status {
+	case WorkflowStatusFailed, WorkflowStatusCancelled, WorkflowStatusInterrupted:
+	default:
+		return 0, fmt.Errorf("workflow %s is not in a failed state (current: %s)", workflowID, workflow.Status)
+	}
+
+	reset := 0
+	for i := range workflow.Tasks {
+		task := &workflow.Tasks[i]
+		if task.Status != TaskStatusCompleted {
+			task.Status = TaskStatusPending
+			task.Error = ""
+			reset++
+		}
+	}
+	if reset == 0 {
+		return 0, fmt.Errorf("workflow %s has no failed tasks to retry", workflowID)
+	}
+
+	workflow.Status = WorkflowStatusPending
+	workflow.CompletedAt = nil
+	we.save(workflow)
+	return reset, nil
+}
+
+// completedResults returns the results of a workflow's completed tasks
+func completedResults(workflow *Workflow) []TaskResult {
+	completed := make(map[string]bool)
+	for _, task := range workflow.Tasks {
+		if task.Status == TaskStatusCompleted {
+			completed[task.ID] = true
+		}
+	}
+
+	var results []TaskResult
+	for _, result := range workflow.Results {
+		if completed[result.TaskID] {
+			results = append(results, result)
+		}
+	}
+	return results
+}
@@ -0,0 +1,331 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+// stubAgent completes immediately, or blocks until the context is cancelled
+type stubAgent struct {
+	name    string
+	block   bool
+	started chan struct{}
+}
+
+func (a *stubAgent) Name() string                                   { return a.name }
+func (a *stubAgent) Initialize(config map[string]interface{}) error { return nil }
+func (a *stubAgent) HealthCheck() error                             { return nil }
+func (a *stubAgent) Shutdown() error                                { return nil }
+func (a *stubAgent) Process(ctx context.Context, input interfaces.AgentInput) (interfaces.AgentOutput, error) {
+	if a.block {
+		close(a.started)
+		<-ctx.Done()
+		return interfaces.AgentOutput{}, ctx.Err()
+	}
+	return interfaces.AgentOutput{Success: true, Data: map[string]interface{}{"agent": a.name}}, nil
+}
+
+type stubPluginManager struct {
+	agents map[string]interfaces.Agent
+}
+
+func (pm *stubPluginManager) LoadLocalAgent(path, name string) error                 { return nil }
+func (pm *stubPluginManager) LoadRemoteAgent(repo, version, entrypoint string) error { return nil }
+func (pm *stubPluginManager) ListAgents() []string                                   { return nil }
+func (pm *stubPluginManager) UnloadAgent(name string) error                          { return nil }
+func (pm *stubPluginManager) ReloadAgent(name string) error                          { return nil }
+func (pm *stubPluginManager) GetAgent(name string) (interfaces.Agent, bool) {
+	agent, ok := pm.agents[name]
+	return agent, ok
+}
+
+func TestExecuteWorkflow_CancelledKeepsCompletedOutputs(t *testing.T) {
+	blocking := &stubAgent{name: "slow", block: true, started: make(chan struct{})}
+	pm := &stubPluginManager{agents: map[string]interfaces.Agent{
+		"first":  &stubAgent{name: "first"},
+		"second": &stubAgent{name: "second"},
+		"slow":   blocking,
+		"last":   &stubAgent{name: "last"},
+	}}
+	engine := NewWorkflowEngine(pm, nil, nil)
+
+	workflow := &Workflow{ID: "wf", Name: "partial", Status: WorkflowStatusPending}
+	for i, name := range []string{"first", "second", "slow", "last"} {
+		workflow.Tasks = append(workflow.Tasks, Task{
+			ID:        fmt.Sprintf("task-%d", i),
+			AgentName: name,
+			Status:    TaskStatusPending,
+		})
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	go func() {
+		<-blocking.started
+		cancel()
+	}()
+
+	result, err := engine.ExecuteWorkflow(ctx, workflow)
+	if err != nil {
+		t.Fatalf("Expected a partial result instead of an error, got: %v", err)
+	}
+
+	if result.Status != WorkflowStatusCancelled {
+		t.Errorf("Expected cancelled status, got %s", result.Status)
+	}
+	if result.CompletedTasks != 2 || result.CancelledTasks != 2 || result.FailedTasks != 0 {
+		t.Errorf("Expected 2 completed and 2 cancelled tasks, got %+v", result)
+	}
+
+	if len(result.Tasks) != 2 || result.Tasks[0].Data["agent"] != "first" || result.Tasks[1].Data["agent"] != "second" {
+		t.Errorf("Expected outputs of the completed tasks, got %+v", result.Tasks)
+	}
+
+	expected := []TaskStatus{TaskStatusCompleted, TaskStatusCompleted, TaskStatusCancelled, TaskStatusCancelled}
+	for i, task := range workflow.Tasks {
+		if task.Status != expected[i] {
+			t.Errorf("Task %d: expected status %s, got %s", i, expected[i], task.Status)
+		}
+	}
+}
+
+func TestExecuteWorkflow_RoutesTasksAndPublishesEvents(t *testing.T) {
+	pm := &stubPluginManager{agents: map[string]interfaces.Agent{
+		"ls": &stubAgent{name: "ls"},
+	}}
+	engine := NewWorkflowEngine(pm, NewTodoParser(), NewTaskRouter(pm))
+	var events []map[string]interface{}
+	engine.SetEventFunc(func(event map[string]interface{}) { events = append(events, event) })
+
+	workflow, err := engine.CreateWorkflow("setup", SequentialTodos([]string{"list the project directory", "ask about the weather"}), nil)
+	if err != nil {
+		t.Fatalf("CreateWorkflow failed: %v", err)
+	}
+	result, err := engine.ExecuteWorkflow(t.Context(), workflow)
+	if err != nil {
+		t.Fatalf("ExecuteWorkflow failed: %v", err)
+	}
+
+	// ls-agent is served by the registered ls; no agent chats
+	if workflow.Tasks[0].AgentName != "ls" || workflow.Tasks[0].Status != TaskStatusCompleted {
+		t.Errorf("Expected the role to be routed to ls, got %+v", workflow.Tasks[0])
+	}
+	if workflow.Tasks[1].Status != TaskStatusFailed || result.Status != WorkflowStatusFailed {
+		t.Errorf("Expected the unknown agent to fail the workflow, got %s and %+v", result.Status, workflow.Tasks[1])
+	}
+
+	var types []string
+	for _, event := range events {
+		if event["topic"] != EventTopic || event["workflow_id"] != workflow.ID {
+			t.Errorf("Expected a workflow event, got %v", event)
+		}
+		types = append(types, fmt.Sprintf("%v:%v", event["type"], event["status"]))
+	}
+	expected := "workflow_start:<nil> workflow_task:running workflow_task:completed " +
+		"workflow_task:running workflow_task:failed workflow_complete:failed"
+	if got := strings.Join(types, " "); got != expected {
+		t.Errorf("Expected events %s, got %s", expected, got)
+	}
+}
+
+// recordingAgent records that it ran. Agents sharing a barrier wait for each
+// other, so they only succeed when run at the same time.
+type recordingAgent struct {
+	stubAgent
+	fail    bool
+	barrier *sync.WaitGroup
+	mu      *sync.Mutex
+	ran     *[]string
+}
+
+func (a *recordingAgent) Process(ctx context.Context, input interfaces.AgentInput) (interfaces.AgentOutput, error) {
+	a.mu.Lock()
+	*a.ran = append(*a.ran, a.name)
+	a.mu.Unlock()
+
+	if a.barrier != nil {
+		a.barrier.Done()
+		met := make(chan struct{})
+		go func() {
+			a.barrier.Wait()
+			close(met)
+		}()
+		select {
+		case <-met:
+		case <-time.After(5 * time.Second):
+			return interfaces.AgentOutput{Success: false, Error: "ran alone"}, nil
+		}
+	}
+	if a.fail {
+		return interfaces.AgentOutput{Success: false, Error: "failed"}, nil
+	}
+	return interfaces.AgentOutput{Success: true}, nil
+}
+
+func TestExecuteWorkflow_DiamondDependencies(t *testing.T) {
+	// b and c depend on a, by ID and by index, and d on both
+	var payload interface{}
+	json.Unmarshal([]byte(`[
+		{"task": "a", "id": "a", "depends_on": []},
+		{"task": "b", "depends_on": ["a"]},
+		{"task": "c", "depends_on": [0]},
+		{"task": "d", "id": "d", "depends_on": [1, 2]}
+	]`), &payload)
+	todos, err := TodosFromPayload(payload, 0)
+	if err != nil {
+		t.Fatalf("TodosFromPayload failed: %v", err)
+	}
+
+	run := func(t *testing.T, failB bool) (*Workflow, *WorkflowResult, []string) {
+		var mu sync.Mutex
+		var ran []string
+		var barrier sync.WaitGroup
+		barrier.Add(2)
+		agents := map[string]interfaces.Agent{}
+		for _, name := range []string{"a", "b", "c", "d"} {
+			agent := &recordingAgent{stubAgent: stubAgent{name: name}, mu: &mu, ran: &ran}
+			if name == "b" || name == "c" {
+				agent.barrier = &barrier
+			}
+			agent.fail = failB && name == "b"
+			agents[name] = agent
+		}
+		engine := NewWorkflowEngine(&stubPluginManager{agents: agents}, NewTodoParser(), nil)
+		engine.SetMaxParallel(4)
+
+		workflow, err := engine.CreateWorkflow("diamond", todos, nil)
+		if err != nil {
+			t.Fatalf("CreateWorkflow failed: %v", err)
+		}
+		for i := range workflow.Tasks {
+			workflow.Tasks[i].AgentName = workflow.Todos[i]
+		}
+		result, err := engine.ExecuteWorkflow(t.Context(), workflow)
+		if err != nil {
+			t.Fatalf("ExecuteWorkflow failed: %v", err)
+		}
+		return workflow, result, ran
+	}
+
+	t.Run("all complete", func(t *testing.T) {
+		workflow, result, ran := run(t, false)
+		if result.Status != WorkflowStatusCompleted || result.CompletedTasks != 4 {
+			t.Fatalf("Expected every task to complete, got %+v", result)
+		}
+		// b and c met at the barrier, so they ran at the same time
+		if len(ran) != 4 || ran[0] != "a" || ran[3] != "d" {
+			t.Errorf("Expected a first and d last, got %v", ran)
+		}
+
+		a, b, c, d := workflow.Tasks[0], workflow.Tasks[1], workflow.Tasks[2], workflow.Tasks[3]
+		if b.StartedAt.Before(*a.CompletedAt) || c.StartedAt.Before(*a.CompletedAt) {
+			t.Error("Expected b and c to start after a completed")
+		}
+		if d.StartedAt.Before(*b.CompletedAt) || d.StartedAt.Before(*c.CompletedAt) {
+			t.Error("Expected d to start after b and c completed")
+		}
+		if d.ID != workflow.ID+"-d" || strings.Join(d.DependsOn, ",") != b.ID+","+c.ID {
+			t.Errorf("Expected d to depend on b and c, got %s depending on %v", d.ID, d.DependsOn)
+		}
+		for _, taskResult := range result.Tasks {
+			if taskResult.StartedAt.IsZero() || taskResult.Timestamp.Before(taskResult.StartedAt) {
+				t.Errorf("Expected the start and end of %s, got %+v", taskResult.TaskID, taskResult)
+			}
+		}
+	})
+
+	t.Run("failure skips dependents", func(t *testing.T) {
+		workflow, result, ran := run(t, true)
+		if result.Status != WorkflowStatusFailed || result.CompletedTasks != 2 || result.FailedTasks != 1 || result.SkippedTasks != 1 {
+			t.Fatalf("Expected a and c to complete, b to fail, and d to be skipped, got %+v", result)
+		}
+		for _, name := range ran {
+			if name == "d" {
+				t.Error("Expected d not to run")
+			}
+		}
+		d := workflow.Tasks[3]
+		if d.Status != TaskStatusSkipped || !strings.Contains(d.Error, workflow.Tasks[1].ID) {
+			t.Errorf("Expected d to be skipped for b, got %+v", d)
+		}
+	})
+}
+
+func TestExecuteWorkflow_ProgressReadableWhileRunning(t *testing.T) {
+	var mu sync.Mutex
+	var ran []string
+	var barrier sync.WaitGroup
+	barrier.Add(4)
+	agents := map[string]interfaces.Agent{}
+	var todos []Todo
+	for _, name := range []string{"a", "b", "c", "d"} {
+		agents[name] = &recordingAgent{stubAgent: stubAgent{name: name}, barrier: &barrier, mu: &mu, ran: &ran}
+		todos = append(todos, Todo{Task: name})
+	}
+	engine := NewWorkflowEngine(&stubPluginManager{agents: agents}, NewTodoParser(), nil)
+	engine.SetMaxParallel(4)
+
+	workflow, err := engine.CreateWorkflow("parallel", todos, nil)
+	if err != nil {
+		t.Fatalf("CreateWorkflow failed: %v", err)
+	}
+	for i := range workflow.Tasks {
+		workflow.Tasks[i].AgentName = workflow.Todos[i]
+	}
+
+	// Poll progress the way the manager's progress requests do while the
+	// tasks run; go test -race reports any unlocked access
+	done := make(chan struct{})
+	polled := make(chan int)
+	go func() {
+		polls := 0
+		for {
+			select {
+			case <-done:
+				polled <- polls
+				return
+			default:
+			}
+			if copied, ok := engine.GetWorkflow(workflow.ID); ok {
+				for i := range copied.Tasks {
+					_ = copied.Tasks[i].Status
+					copied.Tasks[i].Status = TaskStatusFailed
+				}
+				_ = len(copied.Results)
+			}
+			for _, listed := range engine.ListWorkflows() {
+				_ = listed.Status
+			}
+			polls++
+		}
+	}()
+
+	result, err := engine.ExecuteWorkflow(t.Context(), workflow)
+	close(done)
+	if polls := <-polled; polls == 0 {
+		t.Error("Expected progress to be polled during the run")
+	}
+	if err != nil {
+		t.Fatalf("ExecuteWorkflow failed: %v", err)
+	}
+	if result.Status != WorkflowStatusCompleted || result.CompletedTasks != 4 {
+		t.Fatalf("Expected every task to complete, got %+v", result)
+	}
+
+	// Changing a copy leaves the engine's workflow alone
+	final, _ := engine.GetWorkflow(workflow.ID)
+	for _, task := range final.Tasks {
+		if task.Status != TaskStatusCompleted {
+			t.Errorf("Expected task %s to be completed, got %s", task.ID, task.Status)
+		}
+	}
+	if len(final.Results) != 4 {
+		t.Errorf("Expected 4 results, got %d", len(final.Results))
+	}
+}
@@ -0,0 +1,338 @@
+// Package policy decides which agent calls the engine runs on behalf of a
+// model or an API client, and logs every decision for audit.
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+// Modes for agents the configuration does not list
+const (
+	ModeDeny  = "deny"
+	ModeAllow = "allow"
+)
+
+// Codes of denied decisions, reported to callers so a model can be told why
+// a call failed
+const (
+	CodeAgentDenied  = "agent_denied"
+	CodePathDenied   = "path_denied"
+	CodeDomainDenied = "domain_denied"
+	// CodeSecretDenied denies a call that refers to a stored secret the
+	// config does not grant to the agent or its hosts
+	CodeSecretDenied = "secret_denied"
+	// CodeApprovalRequired denies a destructive agent that the config
+	// allows only through the default mode
+	CodeApprovalRequired = "approval_required"
+)
+
+// DefaultPathKeys are the argument names checked against path_prefixes when
+// a rule names none
+var DefaultPathKeys = []string{"path", "paths", "file", "files", "source", "destination", "src", "dst", "dir", "directory", "target", "root"}
+
+// urlKeys are the argument names checked against domains
+var urlKeys = map[string]bool{"url": true, "urls": true, "link": true, "links": true, "href": true}
+
+// Config is the policy section of the engine config
+type Config struct {
+	// Default decides agents the config does not list: "deny" or "allow"
+	Default string `yaml:"default" mapstructure:"default"`
+	// AllowedAgents are allowed without constraints
+	AllowedAgents []string `yaml:"allowed_agents" mapstructure:"allowed_agents"`
+	// AllowReadOnly allows agents that declare themselves read-only, unless
+	// a rule denies them or they reach the network
+	AllowReadOnly bool `yaml:"allow_read_only" mapstructure:"allow_read_only"`
+	// Agents holds per-agent rules, which override AllowedAgents
+	Agents map[string]AgentRule `yaml:"agents" mapstructure:"agents"`
+	// Secrets grants stored secrets to agents, keyed by the secret's name in
+	// the store; a call may refer only to secrets granted to its agent
+	Secrets map[string]SecretGrant `yaml:"secrets" mapstructure:"secrets"`
+	// Audit logs every decision with the call's arguments
+	Audit bool `yaml:"audit" mapstructure:"audit"`
+}
+
+// SecretGrant names the agents a stored secret may be passed to and the
+// hosts it may be sent to
+type SecretGrant struct {
+	// Agents are the agents that may receive the secret
+	Agents []string `yaml:"agents" mapstructure:"agents"`
+	// Domains, when set, are the hosts, with their subdomains, the call's URL
+	// arguments must all name; a call that names no URL is denied
+	Domains []string `yaml:"domains" mapstructure:"domains"`
+}
+
+// AgentRule allows or denies one agent and constrains its arguments
+type AgentRule struct {
+	// Allow, when set, allows or denies the agent outright; a rule without it
+	// allows the agent within its constraints
+	Allow *bool `yaml:"allow" mapstructure:"allow"`
+	// PathPrefixes, when set, are the directories path arguments must be in
+	PathPrefixes []string `yaml:"path_prefixes" mapstructure:"path_prefixes"`
+	// PathKeys names the path arguments; DefaultPathKeys when empty
+	PathKeys []string `yaml:"path_keys" mapstructure:"path_keys"`
+	// Domains, when set, are the hosts URL arguments may name, with their
+	// subdomains
+	Domains []string `yaml:"domains" mapstructure:"domains"`
+}
+
+// DefaultAllowedAgents are the agents allowed when the config lists none
+var DefaultAllowedAgents = []string{"ls", "cat", "pwd", "whoami", "df", "uname"}
+
+// DefaultConfig denies every agent but DefaultAllowedAgents and audits every
+// call
+func DefaultConfig() Config {
+	return Config{
+		Default:       ModeDeny,
+		AllowedAgents: DefaultAllowedAgents,
+		Audit:         true,
+	}
+}
+
+// Decision is the outcome of checking one call
+type Decision struct {
+	Allowed bool
+	// Code is one of the Code constants when the call is denied
+	Code   string
+	Reason string
+}
+
+// Error describes a denial for the caller
+func (d Decision) Error() string {
+	return fmt.Sprintf("denied by policy (%s): %s", d.Code, d.Reason)
+}
+
+// Engine checks calls against a Config
+type Engine struct {
+	config  Config
+	allowed map[string]bool
+}
+
+// New returns an Engine for config. An unknown default mode denies.
+func New(config Config) *Engine {
+	allowed := make(map[string]bool, len(config.AllowedAgents))
+	for _, name := range config.AllowedAgents {
+		if name = strings.TrimSpace(name); name != "" {
+			allowed[name] = true
+		}
+	}
+	return &Engine{config: config, allowed: allowed}
+}
+
+// Check decides whether the agent, classified as class, may be called with
+// args. Destructive agents must be allowed by name or by a rule; the default
+// mode alone does not allow them.
+func (e *Engine) Check(agent string, class interfaces.Classification, args map[string]interface{}) Decision {
+	rule, hasRule := e.config.Agents[agent]
+	switch {
+	case hasRule && rule.Allow != nil && !*rule.Allow:
+		return deny(CodeAgentDenied, "agent %s is not allowed", agent)
+	case hasRule, e.allowed[agent]:
+	case e.config.AllowReadOnly && class.ReadOnly && !class.Network:
+	case e.config.Default == ModeAllow && class.Destructive:
+		return deny(CodeApprovalRequired, "agent %s is destructive and must be allowed by name", agent)
+	case e.config.Default == ModeAllow:
+	default:
+		return deny(CodeAgentDenied, "agent %s is not allowed", agent)
+	}
+
+	if len(rule.PathPrefixes) > 0 {
+		keys := rule.PathKeys
+		if len(keys) == 0 {
+			keys = DefaultPathKeys
+		}
+		names := make(map[string]bool, len(keys))
+		for _, key := range keys {
+			names[key] = true
+		}
+
+		for _, path := range collectStrings(args, names) {
+			if !withinPrefixes(path, rule.PathPrefixes) {
+				return deny(CodePathDenied, "path %s is outside %s", path, strings.Join(rule.PathPrefixes, ", "))
+			}
+		}
+	}
+
+	if len(rule.Domains) > 0 {
+		for _, raw := range collectStrings(args, urlKeys) {
+			if host := hostOf(raw); !withinDomains(host, rule.Domains) {
+				return deny(CodeDomainDenied, "host %q of %s is not in %s", host, raw, strings.Join(rule.Domains, ", "))
+			}
+		}
+	}
+
+	return Decision{Allowed: true}
+}
+
+// Caller is who asked for a call
+type Caller struct {
+	// Source is how the call was made, such as "chat" or "api"
+	Source string
+	// Identity names the caller in the log, such as key:<id> or user:<uid>;
+	// empty for anonymous callers
+	Identity string
+	// AllowedAgents, when set, are the only agents the caller may call,
+	// within what the config allows
+	AllowedAgents []string
+}
+
+// Authorize checks a call by caller. Denied calls are always logged with the
+// caller, and allowed ones too when auditing, with the call's arguments.
+func (e *Engine) Authorize(caller Caller, agent string, class interfaces.Classification, args map[string]interface{}) Decision {
+	var decision Decision
+	if len(caller.AllowedAgents) > 0 && !contains(caller.AllowedAgents, agent) {
+		decision = deny(CodeAgentDenied, "agent %s is not allowed for %s", agent, caller.identity())
+	} else {
+		decision = e.Check(agent, class, args)
+	}
+
+	if e.config.Audit || !decision.Allowed {
+		encoded, _ := json.Marshal(args)
+		if decision.Allowed {
+			log.Printf("policy: allowed %s call by %s to %s with %s", caller.Source, caller.identity(), agent, encoded)
+		} else {
+			log.Printf("policy: denied %s call by %s to %s with %s: %s", caller.Source, caller.identity(), agent, encoded, decision.Reason)
+		}
+	}
+	return decision
+}
+
+// CheckSecret decides whether a call to agent with args may receive the
+// stored secret ref. Secrets the config does not grant are denied.
+func (e *Engine) CheckSecret(ref, agent string, args map[string]interface{}) Decision {
+	grant, ok := e.config.Secrets[ref]
+	if !ok || !contains(grant.Agents, agent) {
+		return deny(CodeSecretDenied, "secret %s is not granted to agent %s", ref, agent)
+	}
+
+	if len(grant.Domains) > 0 {
+		urls := collectStrings(args, urlKeys)
+		if len(urls) == 0 {
+			return deny(CodeSecretDenied, "secret %s is only sent to %s and the call names no URL", ref, strings.Join(grant.Domains, ", "))
+		}
+		for _, raw := range urls {
+			if host := hostOf(raw); !withinDomains(host, grant.Domains) {
+				return deny(CodeSecretDenied, "secret %s is not granted to host %q of %s", ref, host, raw)
+			}
+		}
+	}
+
+	return Decision{Allowed: true}
+}
+
+// AuthorizeSecret checks a secret reference by caller, logging denials with
+// the caller like Authorize. The secret's value is never logged.
+func (e *Engine) AuthorizeSecret(caller Caller, ref, agent string, args map[string]interface{}) Decision {
+	decision := e.CheckSecret(ref, agent, args)
+	if !decision.Allowed {
+		log.Printf("policy: denied %s call by %s to %s: %s", caller.Source, caller.identity(), agent, decision.Reason)
+	}
+	return decision
+}
+
+// identity names the caller, or "anonymous"
+func (c Caller) identity() string {
+	if c.Identity == "" {
+		return "anonymous"
+	}
+	return c.Identity
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if strings.TrimSpace(n) == name {
+			return true
+		}
+	}
+	return false
+}
+
+func deny(code, format string, args ...interface{}) Decision {
+	return Decision{Code: code, Reason: fmt.Sprintf(format, args...)}
+}
+
+// collectStrings returns the strings, or lists of strings, stored under the
+// given keys anywhere in args, including in nested maps and lists, sorted so
+// decisions do not depend on map order
+func collectStrings(args map[string]interface{}, keys map[string]bool) []string {
+	var found []string
+	var walk func(value interface{}, match bool)
+	walk = func(value interface{}, match bool) {
+		switch v := value.(type) {
+		case string:
+			if match {
+				found = append(found, v)
+			}
+		case []string:
+			if match {
+				found = append(found, v...)
+			}
+		case []interface{}:
+			for _, item := range v {
+				walk(item, match)
+			}
+		case map[string]interface{}:
+			for key, item := range v {
+				walk(item, keys[key])
+			}
+		}
+	}
+	walk(args, false)
+
+	sort.Strings(found)
+	return found
+}
+
+// withinPrefixes reports whether path, made absolute, is one of the prefixes
+// or below one. Symlinks are not resolved.
+func withinPrefixes(path string, prefixes []string) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+
+	for _, prefix := range prefixes {
+		prefix, err := filepath.Abs(prefix)
+		if err != nil {
+			continue
+		}
+		if abs == prefix || strings.HasPrefix(abs, strings.TrimSuffix(prefix, string(filepath.Separator))+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// hostOf returns the lowercase host of a URL, which may omit its scheme
+func hostOf(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		u, err = url.Parse("http://" + raw)
+		if err != nil {
+			return ""
+		}
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// withinDomains reports whether host is one of the domains or a subdomain of
+// one; a leading "*." on a domain is ignored
+func withinDomains(host string, domains []string) bool {
+	if host == "" {
+		return false
+	}
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimPrefix(domain, "*."))
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,242 @@
+package policy
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+func boolPtr(b bool) *bool { return &b }
+
+var (
+	readOnly    = interfaces.Classification{ReadOnly: true, Idempotent: true}
+	writes      = interfaces.Classification{Idempotent: true}
+	destructive = interfaces.Classification{Destructive: true}
+)
+
+func TestCheck_Agents(t *testing.T) {
+	engine := New(Config{
+		Default:       ModeDeny,
+		AllowedAgents: []string{"ls", "cat"},
+		Agents: map[string]AgentRule{
+			"cat":       {Allow: boolPtr(false)},
+			"web-agent": {Domains: []string{"example.com"}},
+		},
+	})
+
+	tests := []struct {
+		agent   string
+		allowed bool
+	}{
+		{"ls", true},
+		{"cat", false},      // the rule overrides allowed_agents
+		{"web-agent", true}, // a rule without allow allows within constraints
+		{"rm", false},       // unlisted agents follow the default
+	}
+	for _, tt := range tests {
+		decision := engine.Check(tt.agent, destructive, nil)
+		if decision.Allowed != tt.allowed {
+			t.Errorf("%s: expected allowed=%v, got %+v", tt.agent, tt.allowed, decision)
+		}
+		if !decision.Allowed && decision.Code != CodeAgentDenied {
+			t.Errorf("%s: expected code %s, got %s", tt.agent, CodeAgentDenied, decision.Code)
+		}
+	}
+
+	allowAll := New(Config{Default: ModeAllow, Agents: map[string]AgentRule{"rm": {Allow: boolPtr(false)}}})
+	if !allowAll.Check("touch", writes, nil).Allowed || allowAll.Check("rm", readOnly, nil).Allowed {
+		t.Error("Expected default-allow to allow unlisted agents but not denied ones")
+	}
+}
+
+func TestCheck_PathPrefixes(t *testing.T) {
+	engine := New(Config{Agents: map[string]AgentRule{
+		"cat": {PathPrefixes: []string{"/srv/data", "/tmp/"}},
+		"cp":  {PathPrefixes: []string{"/srv/data"}, PathKeys: []string{"from", "to"}},
+	}})
+
+	tests := []struct {
+		name    string
+		agent   string
+		args    map[string]interface{}
+		allowed bool
+	}{
+		{"inside prefix", "cat", map[string]interface{}{"path": "/srv/data/report.txt"}, true},
+		{"prefix itself", "cat", map[string]interface{}{"path": "/srv/data"}, true},
+		{"trailing slash prefix", "cat", map[string]interface{}{"path": "/tmp/x"}, true},
+		{"sibling with shared prefix", "cat", map[string]interface{}{"path": "/srv/database/x"}, false},
+		{"escape with dot-dot", "cat", map[string]interface{}{"path": "/srv/data/../../etc/passwd"}, false},
+		{"outside", "cat", map[string]interface{}{"file": "/etc/passwd"}, false},
+		{"nested map", "cat", map[string]interface{}{"options": map[string]interface{}{"path": "/etc/shadow"}}, false},
+		{"list of paths", "cat", map[string]interface{}{"paths": []interface{}{"/srv/data/a", "/root/b"}}, false},
+		{"list in nested list", "cat", map[string]interface{}{"items": []interface{}{map[string]interface{}{"src": "/root/key"}}}, false},
+		{"other keys ignored", "cat", map[string]interface{}{"pattern": "/etc/passwd"}, true},
+		{"no path", "cat", map[string]interface{}{}, true},
+		{"custom keys", "cp", map[string]interface{}{"from": "/srv/data/a", "to": "/etc/cron.d/a"}, false},
+		{"custom keys replace defaults", "cp", map[string]interface{}{"path": "/etc/passwd"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := engine.Check(tt.agent, destructive, tt.args)
+			if decision.Allowed != tt.allowed {
+				t.Errorf("Expected allowed=%v, got %+v", tt.allowed, decision)
+			}
+			if !decision.Allowed && decision.Code != CodePathDenied {
+				t.Errorf("Expected code %s, got %s", CodePathDenied, decision.Code)
+			}
+		})
+	}
+}
+
+func TestCheck_Domains(t *testing.T) {
+	engine := New(Config{Agents: map[string]AgentRule{
+		"web-agent": {Domains: []string{"example.com", "*.golang.org"}},
+	}})
+
+	tests := []struct {
+		url     string
+		allowed bool
+	}{
+		{"https://example.com/page", true},
+		{"https://docs.example.com/page", true},
+		{"https://EXAMPLE.com:8443/", true},
+		{"pkg.golang.org/net/http", true},
+		{"https://example.com.evil.net/", false},
+		{"https://notexample.com/", false},
+		{"file:///etc/passwd", false},
+	}
+	for _, tt := range tests {
+		decision := engine.Check("web-agent", readOnly, map[string]interface{}{"url": tt.url})
+		if decision.Allowed != tt.allowed {
+			t.Errorf("%s: expected allowed=%v, got %+v", tt.url, tt.allowed, decision)
+		}
+		if !decision.Allowed && decision.Code != CodeDomainDenied {
+			t.Errorf("%s: expected code %s, got %s", tt.url, CodeDomainDenied, decision.Code)
+		}
+	}
+}
+
+func TestCheckSecret(t *testing.T) {
+	engine := New(Config{Secrets: map[string]SecretGrant{
+		"github-token": {Agents: []string{"web-agent"}, Domains: []string{"api.github.com"}},
+		"db-password":  {Agents: []string{"sql-agent"}},
+	}})
+
+	tests := []struct {
+		ref, agent string
+		args       map[string]interface{}
+		allowed    bool
+	}{
+		{"github-token", "web-agent", map[string]interface{}{"url": "https://api.github.com/user"}, true},
+		{"github-token", "web-agent", map[string]interface{}{"url": "https://evil.example/collect"}, false},
+		{"github-token", "web-agent", map[string]interface{}{"urls": []interface{}{"https://api.github.com/", "https://evil.example/"}}, false},
+		{"github-token", "web-agent", map[string]interface{}{"query": "no url"}, false},
+		{"github-token", "cat", map[string]interface{}{"url": "https://api.github.com/user"}, false},
+		{"db-password", "sql-agent", nil, true},
+		{"unlisted", "web-agent", map[string]interface{}{"url": "https://api.github.com/user"}, false},
+	}
+	for _, tt := range tests {
+		decision := engine.CheckSecret(tt.ref, tt.agent, tt.args)
+		if decision.Allowed != tt.allowed {
+			t.Errorf("%s for %s with %v: expected allowed=%v, got %+v", tt.ref, tt.agent, tt.args, tt.allowed, decision)
+		}
+		if !decision.Allowed && decision.Code != CodeSecretDenied {
+			t.Errorf("%s for %s: expected code %s, got %s", tt.ref, tt.agent, CodeSecretDenied, decision.Code)
+		}
+	}
+}
+
+func TestDefaultConfig(t *testing.T) {
+	engine := New(DefaultConfig())
+	for _, agent := range []string{"ls", "cat", "pwd", "whoami", "df", "uname"} {
+		if !engine.Check(agent, readOnly, nil).Allowed {
+			t.Errorf("Expected %s to be allowed by default", agent)
+		}
+	}
+	if decision := engine.Check("grep", readOnly, nil); decision.Allowed {
+		t.Errorf("Expected other read-only agents to be denied by default, got %+v", decision)
+	}
+	if decision := engine.Check("touch", writes, nil); decision.Allowed {
+		t.Errorf("Expected agents that change state to be denied by default, got %+v", decision)
+	}
+	if decision := engine.Check("task-agent", destructive, nil); decision.Allowed || decision.Error() != "denied by policy (agent_denied): agent task-agent is not allowed" {
+		t.Errorf("Expected task-agent to be denied by default, got %+v", decision)
+	}
+}
+
+func TestCheck_Classification(t *testing.T) {
+	engine := New(Config{
+		Default:       ModeAllow,
+		AllowReadOnly: true,
+		AllowedAgents: []string{"mv"},
+		Agents: map[string]AgentRule{
+			"cat": {Allow: boolPtr(false)},
+			"cp":  {PathPrefixes: []string{"/srv/data"}},
+		},
+	})
+
+	tests := []struct {
+		agent   string
+		class   interfaces.Classification
+		allowed bool
+		code    string
+	}{
+		{"ls", readOnly, true, ""},
+		{"cat", readOnly, false, CodeAgentDenied}, // rules override the classification
+		{"touch", writes, true, ""},
+		{"rm", destructive, false, CodeApprovalRequired}, // default-allow does not approve destructive agents
+		{"mv", destructive, true, ""},                    // listing an agent approves it
+		{"cp", destructive, true, ""},                    // so does a rule
+	}
+	for _, tt := range tests {
+		decision := engine.Check(tt.agent, tt.class, nil)
+		if decision.Allowed != tt.allowed || decision.Code != tt.code {
+			t.Errorf("%s: expected allowed=%v code=%q, got %+v", tt.agent, tt.allowed, tt.code, decision)
+		}
+	}
+}
+
+func TestCheck_NetworkAgentsNeedOptIn(t *testing.T) {
+	network := interfaces.Classification{ReadOnly: true, Network: true}
+
+	engine := New(Config{Default: ModeDeny, AllowReadOnly: true})
+	if decision := engine.Check("web-agent", network, nil); decision.Allowed || decision.Code != CodeAgentDenied {
+		t.Errorf("Expected a read-only network agent to need allowing by name, got %+v", decision)
+	}
+
+	engine = New(Config{Default: ModeDeny, AllowReadOnly: true, AllowedAgents: []string{"web-agent"}})
+	if decision := engine.Check("web-agent", network, nil); !decision.Allowed {
+		t.Errorf("Expected a listed network agent to be allowed, got %+v", decision)
+	}
+}
+
+func TestAuthorize_Caller(t *testing.T) {
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+
+	engine := New(Config{Default: ModeDeny, AllowedAgents: []string{"ls", "cat"}})
+	caller := Caller{Source: "api", Identity: "key:k1", AllowedAgents: []string{"cat"}}
+
+	if decision := engine.Authorize(caller, "cat", writes, nil); !decision.Allowed {
+		t.Errorf("Expected cat to be allowed, got %+v", decision)
+	}
+	if decision := engine.Authorize(caller, "ls", readOnly, nil); decision.Allowed || decision.Code != CodeAgentDenied {
+		t.Errorf("Expected ls to be denied for the caller, got %+v", decision)
+	}
+	// The caller's list does not widen the config
+	if decision := engine.Authorize(Caller{Source: "api", AllowedAgents: []string{"rm"}}, "rm", destructive, nil); decision.Allowed {
+		t.Error("Expected rm to stay denied")
+	}
+
+	// Without auditing only denials are logged
+	lines := strings.Split(strings.TrimSpace(logged.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "denied api call by key:k1 to ls") || !strings.Contains(lines[1], "by anonymous to rm") {
+		t.Errorf("Expected both denials to be logged with their callers, got %q", logged.String())
+	}
+}
@@ -3,23 +3,82 @@ package auth
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// Scopes an API key may grant; a key with ScopeAdmin has every scope
+const (
+	ScopeChat          = "chat"
+	ScopeAgentsRead    = "agents:read"
+	ScopeAgentsExecute = "agents:execute"
+	ScopeAdmin         = "admin"
+)
+
+// Roles a user may have. A session token has the scopes of its user's roles.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// roleScopes are the scopes each role grants
+var roleScopes = map[string][]string{
+	RoleUser:  {ScopeChat, ScopeAgentsRead, ScopeAgentsExecute},
+	RoleAdmin: {ScopeAdmin},
+}
+
+// APIKeyPrefix starts every API key. A key is "afe_<key id>_<secret>": the
+// key ID finds its record and only the secret is hashed.
+const APIKeyPrefix = "afe_"
+
+// Key prefixes of the API key store besides the "api_key:<uid>:<key id>"
+// records
+const (
+	// apiKeyIDPrefix indexes records by key ID
+	apiKeyIDPrefix = "api_key_id:"
+	// legacyKeyPrefix lists records made before keys carried their key ID,
+	// whose whole key is hashed and which can only be found by trying each
+	legacyKeyPrefix = "api_key_legacy:"
+	// keyIndexMarker records that existing keys have been indexed
+	keyIndexMarker = "meta:api_key_index"
+)
+
+var (
+	// ErrUserNotFound is returned for a UID or email no user has
+	ErrUserNotFound = errors.New("user not found")
+	// ErrEmailTaken is returned when an email is already another user's
+	ErrEmailTaken = errors.New("email already in use")
+	// ErrWrongPassword is returned when a password change gives the wrong
+	// current password
+	ErrWrongPassword = errors.New("current password is incorrect")
+	// ErrInvalidAPIKey is returned for a key that matches no active key
+	ErrInvalidAPIKey = errors.New("invalid API key")
+	// ErrAPIKeyExpired is returned for a key past its expiry
+	ErrAPIKeyExpired = errors.New("API key expired")
+	// ErrAPIKeyNotFound is returned for a key ID the user has no key with
+	ErrAPIKeyNotFound = errors.New("API key not found")
+)
+
 // UserManager handles secure user management with LevelDB
 type UserManager struct {
 	usersDB     *leveldb.DB
 	apiKeysDB   *leveldb.DB
 	accountsDir string
+	// keyMu orders the updates of stored keys, so recording a key's use
+	// cannot undo its revocation
+	keyMu sync.Mutex
 }
 
 // User represents a user account
@@ -36,6 +95,18 @@ type User struct {
 	Roles        []string   `json:"roles,omitempty"`
 }
 
+// HasScope reports whether the user's roles grant scope
+func (u *User) HasScope(scope string) bool {
+	for _, role := range u.Roles {
+		for _, granted := range roleScopes[role] {
+			if granted == scope || granted == ScopeAdmin {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // APIKey represents an API key
 type APIKey struct {
 	UID       string     `json:"uid"`
@@ -46,7 +117,39 @@ type APIKey struct {
 	ExpiresAt *time.Time `json:"expires_at,omitempty"`
 	LastUsed  *time.Time `json:"last_used,omitempty"`
 	IsActive  bool       `json:"is_active"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
 	Scopes    []string   `json:"scopes,omitempty"`
+	// AllowedAgents, when set, are the only agents the key may call, within
+	// what the engine's policy allows
+	AllowedAgents []string `json:"allowed_agents,omitempty"`
+}
+
+// Key statuses reported by APIKey.Status
+const (
+	KeyStatusActive  = "active"
+	KeyStatusExpired = "expired"
+	KeyStatusRevoked = "revoked"
+)
+
+// Status reports whether the key is active, expired, or revoked
+func (k *APIKey) Status() string {
+	switch {
+	case !k.IsActive:
+		return KeyStatusRevoked
+	case k.ExpiresAt != nil && time.Now().After(*k.ExpiresAt):
+		return KeyStatusExpired
+	}
+	return KeyStatusActive
+}
+
+// HasScope reports whether the key grants scope
+func (k *APIKey) HasScope(scope string) bool {
+	for _, granted := range k.Scopes {
+		if granted == scope || granted == ScopeAdmin {
+			return true
+		}
+	}
+	return false
 }
 
 // NewUserManager creates a new user manager
@@ -74,11 +177,46 @@ func NewUserManager(accountsDir string) (*UserManager, error) {
 		return nil, fmt.Errorf("failed to open API keys database: %w", err)
 	}
 
-	return &UserManager{
+	um := &UserManager{
 		usersDB:     usersDB,
 		apiKeysDB:   apiKeysDB,
 		accountsDir: accountsDir,
-	}, nil
+	}
+	if err := um.indexAPIKeys(); err != nil {
+		um.Close()
+		return nil, fmt.Errorf("failed to index API keys: %w", err)
+	}
+	return um, nil
+}
+
+// indexAPIKeys indexes the records of a store written before keys had an
+// index, once, marking them as legacy keys
+func (um *UserManager) indexAPIKeys() error {
+	if _, err := um.apiKeysDB.Get([]byte(keyIndexMarker), nil); err == nil {
+		return nil
+	} else if err != leveldb.ErrNotFound {
+		return err
+	}
+
+	batch := new(leveldb.Batch)
+	prefix := []byte("api_key:")
+	iter := um.apiKeysDB.NewIterator(nil, nil)
+	for iter.Seek(prefix); iter.Valid() && strings.HasPrefix(string(iter.Key()), string(prefix)); iter.Next() {
+		record := &APIKey{}
+		if _, err := um.deserializeAPIKey(iter.Value(), record); err != nil {
+			continue
+		}
+		recordKey := append([]byte(nil), iter.Key()...)
+		batch.Put([]byte(apiKeyIDPrefix+record.KeyID), recordKey)
+		batch.Put([]byte(legacyKeyPrefix+record.KeyID), recordKey)
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return err
+	}
+
+	batch.Put([]byte(keyIndexMarker), []byte("1"))
+	return um.apiKeysDB.Write(batch, nil)
 }
 
 // Close closes the database connections
@@ -132,7 +270,7 @@ func (um *UserManager) CreateUser(name, email, password string, phoneNumber *str
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
 		IsActive:     true,
-		Roles:        []string{"user"},
+		Roles:        []string{RoleUser},
 	}
 
 	if phoneNumber != nil {
@@ -185,7 +323,7 @@ func (um *UserManager) GetUserByEmail(email string) (*User, error) {
 	uidBytes, err := um.usersDB.Get(emailKey, nil)
 	if err != nil {
 		if err == leveldb.ErrNotFound {
-			return nil, fmt.Errorf("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, fmt.Errorf("failed to get user by email: %w", err)
 	}
@@ -201,32 +339,44 @@ func (um *UserManager) GetUserByUID(uid string) (*User, error) {
 	data, err := um.usersDB.Get(userKey, nil)
 	if err != nil {
 		if err == leveldb.ErrNotFound {
-			return nil, fmt.Errorf("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
-	// Deserialize user (simplified - in production, use proper JSON marshaling)
 	user := &User{}
-	if err := um.deserializeUser(data, user); err != nil {
+	legacy, err := um.deserializeUser(data, user)
+	if err != nil {
 		return nil, fmt.Errorf("failed to deserialize user: %w", err)
 	}
 
+	// Upgrade records in an older format in place
+	if legacy {
+		if err := um.storeUser(user); err != nil {
+			fmt.Printf("Warning: failed to migrate user record %s: %v\n", uid, err)
+		}
+	}
+
 	return user, nil
 }
 
-// UpdateUser updates an existing user
+// UpdateUser updates an existing user. Changing the email moves the user's
+// email index entry; an email another user has fails with ErrEmailTaken.
 func (um *UserManager) UpdateUser(uid string, updates map[string]interface{}) (*User, error) {
 	user, err := um.GetUserByUID(uid)
 	if err != nil {
 		return nil, fmt.Errorf("user not found: %w", err)
 	}
+	previousEmail := user.Email
 
 	// Apply updates
 	if name, ok := updates["name"].(string); ok {
 		user.Name = name
 	}
-	if email, ok := updates["email"].(string); ok {
+	if email, ok := updates["email"].(string); ok && email != user.Email {
+		if existing, err := um.GetUserByEmail(email); err == nil && existing.UID != uid {
+			return nil, fmt.Errorf("%w: %s", ErrEmailTaken, email)
+		}
 		user.Email = email
 	}
 	if phoneNumber, ok := updates["phone_number"].(string); ok {
@@ -242,13 +392,97 @@ func (um *UserManager) UpdateUser(uid string, updates map[string]interface{}) (*
 	user.UpdatedAt = time.Now()
 
 	// Store updated user
-	if err := um.storeUser(user); err != nil {
+	if err := um.replaceUser(user, previousEmail); err != nil {
 		return nil, fmt.Errorf("failed to update user: %w", err)
 	}
 
 	return user, nil
 }
 
+// ListUsersOptions select a page of users
+type ListUsersOptions struct {
+	// After is the UID of the last user of the previous page; empty starts
+	// at the first user
+	After string
+	// Limit bounds the users returned; zero or less returns every user
+	Limit int
+	// Active, when set, returns only users whose IsActive matches it
+	Active *bool
+}
+
+// ListUsers returns a page of users in UID order and the cursor of the next
+// page, which is empty on the last page
+func (um *UserManager) ListUsers(opts ListUsersOptions) ([]*User, string, error) {
+	prefix := []byte("user:")
+	iter := um.usersDB.NewIterator(util.BytesPrefix(prefix), nil)
+	defer iter.Release()
+
+	start := prefix
+	if opts.After != "" {
+		// The first key after the cursor's own
+		start = append([]byte("user:"+opts.After), 0)
+	}
+
+	users := []*User{}
+	for ok := iter.Seek(start); ok; ok = iter.Next() {
+		user := &User{}
+		if _, err := um.deserializeUser(iter.Value(), user); err != nil {
+			return nil, "", fmt.Errorf("failed to read user %s: %w", strings.TrimPrefix(string(iter.Key()), "user:"), err)
+		}
+		if opts.Active != nil && user.IsActive != *opts.Active {
+			continue
+		}
+		if opts.Limit > 0 && len(users) == opts.Limit {
+			return users, users[len(users)-1].UID, nil
+		}
+		users = append(users, user)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, "", fmt.Errorf("failed to list users: %w", err)
+	}
+
+	return users, "", nil
+}
+
+// ChangePassword replaces a user's password after checking the current one;
+// a wrong current password fails with ErrWrongPassword
+func (um *UserManager) ChangePassword(uid, oldPassword, newPassword string) error {
+	if newPassword == "" {
+		return fmt.Errorf("new password cannot be empty")
+	}
+
+	user, err := um.GetUserByUID(uid)
+	if err != nil {
+		return err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(oldPassword)); err != nil {
+		return ErrWrongPassword
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	user.PasswordHash = string(passwordHash)
+	user.UpdatedAt = time.Now()
+
+	if err := um.storeUser(user); err != nil {
+		return fmt.Errorf("failed to change password: %w", err)
+	}
+	return nil
+}
+
+// DeactivateUser stops a user from logging in or using their API keys, which
+// are kept for when the user is reactivated
+func (um *UserManager) DeactivateUser(uid string) (*User, error) {
+	return um.UpdateUser(uid, map[string]interface{}{"is_active": false})
+}
+
+// ReactivateUser undoes DeactivateUser
+func (um *UserManager) ReactivateUser(uid string) (*User, error) {
+	return um.UpdateUser(uid, map[string]interface{}{"is_active": true})
+}
+
 // DeleteUser deletes a user account
 func (um *UserManager) DeleteUser(uid string) error {
 	user, err := um.GetUserByUID(uid)
@@ -267,18 +501,26 @@ func (um *UserManager) DeleteUser(uid string) error {
 	emailKey := []byte(fmt.Sprintf("email:%s", user.Email))
 	batch.Delete(emailKey)
 
-	// Delete all API keys for this user
+	// Apply batch
+	if err := um.usersDB.Write(batch, nil); err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	// Delete all API keys for this user, with their index entries
+	keyBatch := new(leveldb.Batch)
 	apiKeyPrefix := []byte(fmt.Sprintf("api_key:%s:", uid))
 	iter := um.apiKeysDB.NewIterator(nil, nil)
 	defer iter.Release()
 
 	for iter.Seek(apiKeyPrefix); iter.Valid() && strings.HasPrefix(string(iter.Key()), string(apiKeyPrefix)); iter.Next() {
-		batch.Delete(iter.Key())
+		keyID := strings.TrimPrefix(string(iter.Key()), string(apiKeyPrefix))
+		keyBatch.Delete(append([]byte(nil), iter.Key()...))
+		keyBatch.Delete([]byte(apiKeyIDPrefix + keyID))
+		keyBatch.Delete([]byte(legacyKeyPrefix + keyID))
 	}
 
-	// Apply batch
-	if err := um.usersDB.Write(batch, nil); err != nil {
-		return fmt.Errorf("failed to delete user: %w", err)
+	if err := um.apiKeysDB.Write(keyBatch, nil); err != nil {
+		return fmt.Errorf("failed to delete API keys: %w", err)
 	}
 
 	return nil
@@ -291,8 +533,8 @@ func (um *UserManager) CreateAPIKey(uid, name string, expiresAt *time.Time, scop
 		return nil, "", fmt.Errorf("user not found: %w", err)
 	}
 
-	// Generate API key
-	apiKey, err := um.generateAPIKey()
+	// Generate the secret part of the key
+	secret, err := um.generateAPIKey()
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to generate API key: %w", err)
 	}
@@ -303,8 +545,8 @@ func (um *UserManager) CreateAPIKey(uid, name string, expiresAt *time.Time, scop
 		return nil, "", fmt.Errorf("failed to generate key ID: %w", err)
 	}
 
-	// Hash API key
-	keyHash, err := um.hashAPIKey(apiKey)
+	// Hash the secret; the key ID is what finds the record
+	keyHash, err := um.hashAPIKey(secret)
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to hash API key: %w", err)
 	}
@@ -326,44 +568,30 @@ func (um *UserManager) CreateAPIKey(uid, name string, expiresAt *time.Time, scop
 		return nil, "", fmt.Errorf("failed to store API key: %w", err)
 	}
 
-	return apiKeyRecord, apiKey, nil
+	return apiKeyRecord, APIKeyPrefix + keyID + "_" + secret, nil
 }
 
-// ValidateAPIKey validates an API key and returns the associated user
+// ValidateAPIKey validates an API key and returns the associated user. An
+// unknown or revoked key returns ErrInvalidAPIKey and an expired one
+// ErrAPIKeyExpired. The key's ID finds its record, so only one hash is
+// checked; keys made before keys carried an ID are tried against each legacy
+// record.
 func (um *UserManager) ValidateAPIKey(apiKey string) (*User, *APIKey, error) {
-	// Hash the provided API key
-	keyHash, err := um.hashAPIKey(apiKey)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to hash API key: %w", err)
-	}
-
-	// Search for API key by hash
-	iter := um.apiKeysDB.NewIterator(nil, nil)
-	defer iter.Release()
-
 	var foundAPIKey *APIKey
-	prefix := []byte("api_key:")
-
-	for iter.Seek(prefix); iter.Valid() && strings.HasPrefix(string(iter.Key()), string(prefix)); iter.Next() {
-		data := iter.Value()
-		keyRecord := &APIKey{}
-		if err := um.deserializeAPIKey(data, keyRecord); err != nil {
-			continue
-		}
-
-		if keyRecord.KeyHash == keyHash && keyRecord.IsActive {
-			// Check if key is expired
-			if keyRecord.ExpiresAt != nil && time.Now().After(*keyRecord.ExpiresAt) {
-				continue
-			}
-
-			foundAPIKey = keyRecord
-			break
+	if keyID, secret, ok := parseAPIKey(apiKey); ok {
+		record, err := um.getAPIKey(keyID)
+		if err == nil && bcrypt.CompareHashAndPassword([]byte(record.KeyHash), []byte(secret)) == nil {
+			foundAPIKey = record
 		}
+	} else {
+		foundAPIKey = um.findLegacyAPIKey(apiKey)
 	}
 
-	if foundAPIKey == nil {
-		return nil, nil, fmt.Errorf("invalid API key")
+	if foundAPIKey == nil || !foundAPIKey.IsActive {
+		return nil, nil, ErrInvalidAPIKey
+	}
+	if foundAPIKey.ExpiresAt != nil && time.Now().After(*foundAPIKey.ExpiresAt) {
+		return nil, nil, ErrAPIKeyExpired
 	}
 
 	// Get user
@@ -379,7 +607,7 @@ func (um *UserManager) ValidateAPIKey(apiKey string) (*User, *APIKey, error) {
 	// Update last used
 	now := time.Now()
 	foundAPIKey.LastUsed = &now
-	if err := um.storeAPIKey(foundAPIKey); err != nil {
+	if err := um.recordKeyUse(foundAPIKey.KeyID, now); err != nil {
 		// Don't fail validation if we can't update last used
 		fmt.Printf("Warning: failed to update last used for API key %s: %v\n", foundAPIKey.KeyID, err)
 	}
@@ -387,6 +615,148 @@ func (um *UserManager) ValidateAPIKey(apiKey string) (*User, *APIKey, error) {
 	return user, foundAPIKey, nil
 }
 
+// recordKeyUse stores when a key was last used, unless it has been revoked
+// since it was validated
+func (um *UserManager) recordKeyUse(keyID string, usedAt time.Time) error {
+	um.keyMu.Lock()
+	defer um.keyMu.Unlock()
+
+	record, err := um.getAPIKey(keyID)
+	if err != nil {
+		return err
+	}
+	if !record.IsActive {
+		return nil
+	}
+	record.LastUsed = &usedAt
+	return um.storeAPIKey(record)
+}
+
+// ListAPIKeys returns the keys of a user, revoked and expired ones included,
+// with their hashes removed
+func (um *UserManager) ListAPIKeys(uid string) ([]*APIKey, error) {
+	if _, err := um.GetUserByUID(uid); err != nil {
+		return nil, err
+	}
+
+	iter := um.apiKeysDB.NewIterator(util.BytesPrefix([]byte(fmt.Sprintf("api_key:%s:", uid))), nil)
+	defer iter.Release()
+
+	var keys []*APIKey
+	for iter.Next() {
+		record := &APIKey{}
+		if _, err := um.deserializeAPIKey(iter.Value(), record); err != nil {
+			return nil, fmt.Errorf("failed to deserialize API key: %w", err)
+		}
+		record.KeyHash = ""
+		keys = append(keys, record)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	return keys, nil
+}
+
+// SetAPIKeyAgents limits one of a user's keys to calling the named agents;
+// an empty list lifts the limit
+func (um *UserManager) SetAPIKeyAgents(uid, keyID string, agents []string) error {
+	um.keyMu.Lock()
+	defer um.keyMu.Unlock()
+
+	record, err := um.getAPIKey(keyID)
+	if err == leveldb.ErrNotFound || (err == nil && record.UID != uid) {
+		return ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get API key: %w", err)
+	}
+
+	record.AllowedAgents = nil
+	for _, agent := range agents {
+		if agent = strings.TrimSpace(agent); agent != "" {
+			record.AllowedAgents = append(record.AllowedAgents, agent)
+		}
+	}
+	return um.storeAPIKey(record)
+}
+
+// RevokeAPIKey deactivates one of a user's keys, which ValidateAPIKey then
+// refuses. The record is kept, with the time it was revoked.
+func (um *UserManager) RevokeAPIKey(uid, keyID string) error {
+	um.keyMu.Lock()
+	defer um.keyMu.Unlock()
+
+	record, err := um.getAPIKey(keyID)
+	if err == leveldb.ErrNotFound || (err == nil && record.UID != uid) {
+		return ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get API key: %w", err)
+	}
+	if !record.IsActive {
+		return nil
+	}
+
+	now := time.Now()
+	record.IsActive = false
+	record.RevokedAt = &now
+	return um.storeAPIKey(record)
+}
+
+// parseAPIKey splits a key into its key ID and secret
+func parseAPIKey(apiKey string) (keyID, secret string, ok bool) {
+	rest, ok := strings.CutPrefix(apiKey, APIKeyPrefix)
+	if !ok {
+		return "", "", false
+	}
+	keyID, secret, ok = strings.Cut(rest, "_")
+	return keyID, secret, ok && keyID != "" && secret != ""
+}
+
+// getAPIKey reads the record of a key ID through the index
+func (um *UserManager) getAPIKey(keyID string) (*APIKey, error) {
+	recordKey, err := um.apiKeysDB.Get([]byte(apiKeyIDPrefix+keyID), nil)
+	if err != nil {
+		return nil, err
+	}
+	data, err := um.apiKeysDB.Get(recordKey, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &APIKey{}
+	legacy, err := um.deserializeAPIKey(data, record)
+	if err != nil {
+		return nil, err
+	}
+
+	// Upgrade records in an older format in place
+	if legacy {
+		if err := um.storeAPIKey(record); err != nil {
+			fmt.Printf("Warning: failed to migrate API key record %s: %v\n", keyID, err)
+		}
+	}
+	return record, nil
+}
+
+// findLegacyAPIKey returns the legacy record whose hash matches the whole key
+func (um *UserManager) findLegacyAPIKey(apiKey string) *APIKey {
+	prefix := []byte(legacyKeyPrefix)
+	iter := um.apiKeysDB.NewIterator(nil, nil)
+	defer iter.Release()
+
+	for iter.Seek(prefix); iter.Valid() && strings.HasPrefix(string(iter.Key()), string(prefix)); iter.Next() {
+		record, err := um.getAPIKey(strings.TrimPrefix(string(iter.Key()), legacyKeyPrefix))
+		if err != nil {
+			continue
+		}
+		if bcrypt.CompareHashAndPassword([]byte(record.KeyHash), []byte(apiKey)) == nil {
+			return record
+		}
+	}
+	return nil
+}
+
 // Helper methods
 
 func (um *UserManager) generateUID() (string, error) {
@@ -414,108 +784,186 @@ func (um *UserManager) hashAPIKey(apiKey string) (string, error) {
 }
 
 func (um *UserManager) storeUser(user *User) error {
-	// Serialize user (simplified)
-	data := um.serializeUser(user)
+	return um.replaceUser(user, user.Email)
+}
 
-	// Store user record
-	userKey := []byte(fmt.Sprintf("user:%s", user.UID))
-	if err := um.usersDB.Put(userKey, data, nil); err != nil {
-		return fmt.Errorf("failed to store user: %w", err)
+// replaceUser stores a user whose email was previousEmail, writing the record
+// and moving the email index entry in one batch
+func (um *UserManager) replaceUser(user *User, previousEmail string) error {
+	data, err := um.serializeUser(user)
+	if err != nil {
+		return fmt.Errorf("failed to serialize user: %w", err)
 	}
 
-	// Store email index
-	emailKey := []byte(fmt.Sprintf("email:%s", user.Email))
-	if err := um.usersDB.Put(emailKey, []byte(user.UID), nil); err != nil {
-		return fmt.Errorf("failed to store email index: %w", err)
+	batch := new(leveldb.Batch)
+	batch.Put([]byte(fmt.Sprintf("user:%s", user.UID)), data)
+	if previousEmail != user.Email {
+		batch.Delete([]byte(fmt.Sprintf("email:%s", previousEmail)))
 	}
+	batch.Put([]byte(fmt.Sprintf("email:%s", user.Email)), []byte(user.UID))
 
+	if err := um.usersDB.Write(batch, nil); err != nil {
+		return fmt.Errorf("failed to store user: %w", err)
+	}
 	return nil
 }
 
 func (um *UserManager) storeAPIKey(apiKey *APIKey) error {
-	// Serialize API key (simplified)
-	data := um.serializeAPIKey(apiKey)
+	data, err := um.serializeAPIKey(apiKey)
+	if err != nil {
+		return fmt.Errorf("failed to serialize API key: %w", err)
+	}
 
-	// Store API key record
+	// Store API key record and its index entry
 	keyRecordKey := []byte(fmt.Sprintf("api_key:%s:%s", apiKey.UID, apiKey.KeyID))
-	if err := um.apiKeysDB.Put(keyRecordKey, data, nil); err != nil {
+	batch := new(leveldb.Batch)
+	batch.Put(keyRecordKey, data)
+	batch.Put([]byte(apiKeyIDPrefix+apiKey.KeyID), keyRecordKey)
+	if err := um.apiKeysDB.Write(batch, nil); err != nil {
 		return fmt.Errorf("failed to store API key: %w", err)
 	}
 
 	return nil
 }
 
-// Simplified serialization methods (in production, use proper JSON marshaling)
-func (um *UserManager) serializeUser(user *User) []byte {
-	return []byte(fmt.Sprintf(
-		"uid:%s|name:%s|email:%s|phone:%s|hash:%s|created:%d|updated:%d|active:%t",
-		user.UID, user.Name, user.Email, user.PhoneNumber, user.PasswordHash,
-		user.CreatedAt.Unix(), user.UpdatedAt.Unix(), user.IsActive,
-	))
+// recordVersion is the format of stored user and API key records. Version 1
+// records were "uid:...|name:..." text, which lost any value holding a "|"
+// and several fields; they are read and rewritten as JSON when next loaded.
+const recordVersion = 2
+
+// userRecord and apiKeyRecord are the stored forms of users and API keys
+type userRecord struct {
+	Version int `json:"version"`
+	User
 }
 
-func (um *UserManager) deserializeUser(data []byte, user *User) error {
-	// Simplified deserialization (in production, use proper JSON unmarshaling)
-	parts := strings.Split(string(data), "|")
-	if len(parts) < 8 {
-		return fmt.Errorf("invalid user data format")
-	}
+type apiKeyRecord struct {
+	Version int `json:"version"`
+	APIKey
+}
 
-	user.UID = parts[0]
-	user.Name = parts[1]
-	user.Email = parts[2]
-	user.PhoneNumber = parts[3]
-	user.PasswordHash = parts[4]
+// Field names of version 1 records, in the order they were written
+var (
+	legacyUserFields   = []string{"uid", "name", "email", "phone", "hash", "created", "updated", "active"}
+	legacyAPIKeyFields = []string{"uid", "key_id", "hash", "name", "created", "expires", "active", "scopes"}
+)
 
-	if created, err := strconv.ParseInt(parts[5], 10, 64); err == nil {
-		user.CreatedAt = time.Unix(created, 0)
-	}
-	if updated, err := strconv.ParseInt(parts[6], 10, 64); err == nil {
-		user.UpdatedAt = time.Unix(updated, 0)
+func (um *UserManager) serializeUser(user *User) ([]byte, error) {
+	return json.Marshal(userRecord{Version: recordVersion, User: *user})
+}
+
+// deserializeUser reads a stored user and reports whether the record is in
+// an older format that should be rewritten
+func (um *UserManager) deserializeUser(data []byte, user *User) (bool, error) {
+	if isJSONRecord(data) {
+		var record userRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			return false, fmt.Errorf("invalid user record: %w", err)
+		}
+		if record.Version > recordVersion {
+			return false, fmt.Errorf("unsupported user record version %d", record.Version)
+		}
+		*user = record.User
+		return record.Version < recordVersion, nil
 	}
-	if active, err := strconv.ParseBool(parts[7]); err == nil {
-		user.IsActive = active
+
+	fields, err := parseLegacyRecord(string(data), legacyUserFields, len(legacyUserFields))
+	if err != nil {
+		return false, fmt.Errorf("invalid user data format: %w", err)
 	}
 
-	return nil
+	user.UID = fields["uid"]
+	user.Name = fields["name"]
+	user.Email = fields["email"]
+	user.PhoneNumber = fields["phone"]
+	user.PasswordHash = fields["hash"]
+	user.CreatedAt = legacyTime(fields["created"])
+	user.UpdatedAt = legacyTime(fields["updated"])
+	user.IsActive, _ = strconv.ParseBool(fields["active"])
+	// Version 1 dropped roles; every user had the default one
+	user.Roles = []string{RoleUser}
+
+	return true, nil
 }
 
-func (um *UserManager) serializeAPIKey(apiKey *APIKey) []byte {
-	return []byte(fmt.Sprintf(
-		"uid:%s|key_id:%s|hash:%s|name:%s|created:%d|expires:%d|active:%t",
-		apiKey.UID, apiKey.KeyID, apiKey.KeyHash, apiKey.Name,
-		apiKey.CreatedAt.Unix(), um.timeToUnix(apiKey.ExpiresAt), apiKey.IsActive,
-	))
+func (um *UserManager) serializeAPIKey(apiKey *APIKey) ([]byte, error) {
+	return json.Marshal(apiKeyRecord{Version: recordVersion, APIKey: *apiKey})
 }
 
-func (um *UserManager) deserializeAPIKey(data []byte, apiKey *APIKey) error {
-	parts := strings.Split(string(data), "|")
-	if len(parts) < 7 {
-		return fmt.Errorf("invalid API key data format")
+// deserializeAPIKey reads a stored API key and reports whether the record is
+// in an older format that should be rewritten
+func (um *UserManager) deserializeAPIKey(data []byte, apiKey *APIKey) (bool, error) {
+	if isJSONRecord(data) {
+		var record apiKeyRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			return false, fmt.Errorf("invalid API key record: %w", err)
+		}
+		if record.Version > recordVersion {
+			return false, fmt.Errorf("unsupported API key record version %d", record.Version)
+		}
+		*apiKey = record.APIKey
+		return record.Version < recordVersion, nil
 	}
 
-	apiKey.UID = parts[0]
-	apiKey.KeyID = parts[1]
-	apiKey.KeyHash = parts[2]
-	apiKey.Name = parts[3]
-
-	if created, err := strconv.ParseInt(parts[4], 10, 64); err == nil {
-		apiKey.CreatedAt = time.Unix(created, 0)
+	// Keys written before scopes existed have no scopes field
+	fields, err := parseLegacyRecord(string(data), legacyAPIKeyFields, len(legacyAPIKeyFields)-1)
+	if err != nil {
+		return false, fmt.Errorf("invalid API key data format: %w", err)
 	}
-	if expires, err := strconv.ParseInt(parts[5], 10, 64); err == nil && expires > 0 {
-		t := time.Unix(expires, 0)
-		apiKey.ExpiresAt = &t
+
+	apiKey.UID = fields["uid"]
+	apiKey.KeyID = fields["key_id"]
+	apiKey.KeyHash = fields["hash"]
+	apiKey.Name = fields["name"]
+	apiKey.CreatedAt = legacyTime(fields["created"])
+	if expires := legacyTime(fields["expires"]); !expires.IsZero() {
+		apiKey.ExpiresAt = &expires
 	}
-	if active, err := strconv.ParseBool(parts[6]); err == nil {
-		apiKey.IsActive = active
+	apiKey.IsActive, _ = strconv.ParseBool(fields["active"])
+	if scopes := fields["scopes"]; scopes != "" {
+		apiKey.Scopes = strings.Split(scopes, ",")
 	}
 
-	return nil
+	return true, nil
+}
+
+// isJSONRecord tells JSON records from version 1 text, which starts with a
+// field name
+func isJSONRecord(data []byte) bool {
+	return len(data) > 0 && data[0] == '{'
+}
+
+// parseLegacyRecord splits a version 1 record into its fields. Each field ends
+// where "|" and the next field's name follow, so a value holding a "|" of its
+// own still reads back whole. Fields after the first required may be missing.
+func parseLegacyRecord(data string, names []string, required int) (map[string]string, error) {
+	fields := make(map[string]string, len(names))
+	rest := data
+	for i, name := range names {
+		value, ok := strings.CutPrefix(rest, name+":")
+		if !ok {
+			if i < required {
+				return nil, fmt.Errorf("missing %s field", name)
+			}
+			break
+		}
+
+		rest = ""
+		if i+1 < len(names) {
+			if end := strings.Index(value, "|"+names[i+1]+":"); end >= 0 {
+				value, rest = value[:end], value[end+1:]
+			}
+		}
+		fields[name] = value
+	}
+	return fields, nil
 }
 
-func (um *UserManager) timeToUnix(t *time.Time) int64 {
-	if t == nil {
-		return 0
+// legacyTime reads a version 1 Unix timestamp, where 0 meant unset
+func legacyTime(value string) time.Time {
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seconds == 0 {
+		return time.Time{}
 	}
-	return t.Unix()
+	return time.Unix(seconds, 0)
 }
@@ -0,0 +1,443 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newTestManager(t *testing.T, dir string) *UserManager {
+	t.Helper()
+	um, err := NewUserManager(dir)
+	if err != nil {
+		t.Fatalf("NewUserManager failed: %v", err)
+	}
+	return um
+}
+
+func TestValidateAPIKey(t *testing.T) {
+	um := newTestManager(t, t.TempDir())
+	defer um.Close()
+
+	user, err := um.CreateUser("Ada", "ada@example.com", "secret", nil)
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	record, key, err := um.CreateAPIKey(user.UID, "ci", nil, []string{ScopeChat})
+	if err != nil {
+		t.Fatalf("CreateAPIKey failed: %v", err)
+	}
+	if !strings.HasPrefix(key, APIKeyPrefix+record.KeyID+"_") {
+		t.Fatalf("Expected the key to carry its key ID, got %s", key)
+	}
+
+	gotUser, gotKey, err := um.ValidateAPIKey(key)
+	if err != nil {
+		t.Fatalf("Expected a fresh key to validate: %v", err)
+	}
+	if gotUser.UID != user.UID || gotKey.KeyID != record.KeyID || !gotKey.HasScope(ScopeChat) {
+		t.Errorf("Expected the key's user and scopes, got %+v, %+v", gotUser, gotKey)
+	}
+
+	last := key[len(key)-1:]
+	flipped := "0"
+	if last == "0" {
+		flipped = "1"
+	}
+	for name, tampered := range map[string]string{
+		"secret":    key[:len(key)-1] + flipped,
+		"key id":    APIKeyPrefix + strings.Repeat("0", 32) + key[len(APIKeyPrefix)+32:],
+		"no prefix": strings.TrimPrefix(key, APIKeyPrefix),
+		"empty":     "",
+	} {
+		if _, _, err := um.ValidateAPIKey(tampered); !errors.Is(err, ErrInvalidAPIKey) {
+			t.Errorf("%s: expected ErrInvalidAPIKey, got %v", name, err)
+		}
+	}
+
+	expired := time.Now().Add(-time.Minute)
+	_, expiredKey, _ := um.CreateAPIKey(user.UID, "old", &expired, nil)
+	if _, _, err := um.ValidateAPIKey(expiredKey); !errors.Is(err, ErrAPIKeyExpired) {
+		t.Errorf("Expected ErrAPIKeyExpired, got %v", err)
+	}
+}
+
+func TestNewUserManager_IndexesLegacyKeys(t *testing.T) {
+	dir := t.TempDir()
+	um := newTestManager(t, dir)
+	user, err := um.CreateUser("Ada", "ada@example.com", "secret", nil)
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+
+	// A key from before keys carried their ID: the whole key is hashed, and
+	// the store has no index or marker
+	legacyKey := strings.Repeat("ab", 32)
+	hash, _ := bcrypt.GenerateFromPassword([]byte(legacyKey), bcrypt.MinCost)
+	legacy := &APIKey{UID: user.UID, KeyID: "legacy1", KeyHash: string(hash), Name: "old", CreatedAt: time.Now(), IsActive: true}
+	recordKey := []byte(fmt.Sprintf("api_key:%s:%s", user.UID, legacy.KeyID))
+	data, _ := um.serializeAPIKey(legacy)
+	if err := um.apiKeysDB.Put(recordKey, data, nil); err != nil {
+		t.Fatalf("Failed to write legacy record: %v", err)
+	}
+	if err := um.apiKeysDB.Delete([]byte(keyIndexMarker), nil); err != nil {
+		t.Fatalf("Failed to remove the index marker: %v", err)
+	}
+	um.Close()
+
+	um = newTestManager(t, dir)
+	defer um.Close()
+
+	if _, err := um.apiKeysDB.Get([]byte(apiKeyIDPrefix+"legacy1"), nil); err != nil {
+		t.Errorf("Expected the legacy record to be indexed on open: %v", err)
+	}
+	if _, key, err := um.ValidateAPIKey(legacyKey); err != nil || key.KeyID != "legacy1" {
+		t.Errorf("Expected the legacy key to validate, got %v, %v", key, err)
+	}
+	if _, _, err := um.ValidateAPIKey(strings.Repeat("cd", 32)); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("Expected an unknown legacy-format key to be rejected, got %v", err)
+	}
+}
+
+func TestDeleteUser_RemovesAPIKeys(t *testing.T) {
+	um := newTestManager(t, t.TempDir())
+	defer um.Close()
+
+	user, _ := um.CreateUser("Ada", "ada@example.com", "secret", nil)
+	record, key, err := um.CreateAPIKey(user.UID, "ci", nil, nil)
+	if err != nil {
+		t.Fatalf("CreateAPIKey failed: %v", err)
+	}
+
+	if err := um.DeleteUser(user.UID); err != nil {
+		t.Fatalf("DeleteUser failed: %v", err)
+	}
+	if _, _, err := um.ValidateAPIKey(key); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("Expected the deleted user's key to be rejected, got %v", err)
+	}
+	if _, err := um.apiKeysDB.Get([]byte(apiKeyIDPrefix+record.KeyID), nil); err == nil {
+		t.Error("Expected the key's index entry to be deleted")
+	}
+}
+
+func TestUserRecords_RoundTrip(t *testing.T) {
+	um := newTestManager(t, t.TempDir())
+	defer um.Close()
+
+	phone := "+1 (555) 010|0"
+	user, err := um.CreateUser("Zoë | O'Brien: \"admin\"", "z|o:e+tag@例え.jp", "pa|ss:word", &phone)
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	if _, err := um.AuthenticateUser(user.Email, "pa|ss:word"); err != nil {
+		t.Fatalf("AuthenticateUser failed: %v", err)
+	}
+	if _, err := um.UpdateUser(user.UID, map[string]interface{}{"roles": []string{"user", "ops|team"}}); err != nil {
+		t.Fatalf("UpdateUser failed: %v", err)
+	}
+
+	got, err := um.GetUserByEmail(user.Email)
+	if err != nil {
+		t.Fatalf("GetUserByEmail failed: %v", err)
+	}
+	if got.Name != user.Name || got.Email != user.Email || got.PhoneNumber != phone {
+		t.Errorf("Expected the values unchanged, got %q %q %q", got.Name, got.Email, got.PhoneNumber)
+	}
+	if !reflect.DeepEqual(got.Roles, []string{"user", "ops|team"}) {
+		t.Errorf("Expected the roles to persist, got %v", got.Roles)
+	}
+	if got.LastLogin == nil {
+		t.Error("Expected the last login to persist")
+	}
+
+	expires := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+	record, key, err := um.CreateAPIKey(user.UID, "ci|deploy: ☃", &expires, []string{ScopeChat, ScopeAgentsRead})
+	if err != nil {
+		t.Fatalf("CreateAPIKey failed: %v", err)
+	}
+	if _, _, err := um.ValidateAPIKey(key); err != nil {
+		t.Fatalf("ValidateAPIKey failed: %v", err)
+	}
+
+	gotKey, err := um.getAPIKey(record.KeyID)
+	if err != nil {
+		t.Fatalf("getAPIKey failed: %v", err)
+	}
+	if gotKey.Name != record.Name || !gotKey.ExpiresAt.Equal(expires) || !reflect.DeepEqual(gotKey.Scopes, record.Scopes) {
+		t.Errorf("Expected the key unchanged, got %+v", gotKey)
+	}
+	if gotKey.LastUsed == nil {
+		t.Error("Expected the last use to persist")
+	}
+}
+
+func TestUserRecords_MigratesLegacyFormat(t *testing.T) {
+	um := newTestManager(t, t.TempDir())
+	defer um.Close()
+
+	// Version 1 records, as the pipe format wrote them
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	legacyUser := fmt.Sprintf("uid:u1|name:Ada|Lovelace|email:ada@example.com|phone:|hash:%s|created:1700000000|updated:1700000100|active:true", hash)
+	legacyKey := "uid:u1|key_id:k1|hash:x|name:ci|created:1700000000|expires:0|active:true|scopes:chat,agents:read"
+	um.usersDB.Put([]byte("user:u1"), []byte(legacyUser), nil)
+	um.usersDB.Put([]byte("email:ada@example.com"), []byte("u1"), nil)
+	um.apiKeysDB.Put([]byte("api_key:u1:k1"), []byte(legacyKey), nil)
+	um.apiKeysDB.Put([]byte(apiKeyIDPrefix+"k1"), []byte("api_key:u1:k1"), nil)
+
+	user, err := um.AuthenticateUser("ada@example.com", "secret")
+	if err != nil {
+		t.Fatalf("Expected the legacy user to authenticate: %v", err)
+	}
+	if user.UID != "u1" || user.Name != "Ada|Lovelace" || !user.CreatedAt.Equal(time.Unix(1700000000, 0)) || !user.IsActive {
+		t.Errorf("Unexpected legacy user %+v", user)
+	}
+
+	data, _ := um.usersDB.Get([]byte("user:u1"), nil)
+	var record userRecord
+	if err := json.Unmarshal(data, &record); err != nil || record.Version != recordVersion {
+		t.Fatalf("Expected the user to be rewritten as JSON, got %s", data)
+	}
+	if record.Name != "Ada|Lovelace" || record.LastLogin == nil {
+		t.Errorf("Expected the rewritten record to keep the name and the login, got %+v", record.User)
+	}
+
+	key, err := um.getAPIKey("k1")
+	if err != nil {
+		t.Fatalf("getAPIKey failed: %v", err)
+	}
+	if key.ExpiresAt != nil || !reflect.DeepEqual(key.Scopes, []string{ScopeChat, ScopeAgentsRead}) {
+		t.Errorf("Unexpected legacy key %+v", key)
+	}
+	data, _ = um.apiKeysDB.Get([]byte("api_key:u1:k1"), nil)
+	if !isJSONRecord(data) {
+		t.Errorf("Expected the key to be rewritten as JSON, got %s", data)
+	}
+
+	if _, err := um.deserializeUser([]byte(`{"version": 99}`), &User{}); err == nil {
+		t.Error("Expected a newer record version to be rejected")
+	}
+}
+
+func TestUpdateUser_ReindexesEmail(t *testing.T) {
+	um := newTestManager(t, t.TempDir())
+	defer um.Close()
+
+	user, _ := um.CreateUser("Ada", "ada@example.com", "secret", nil)
+	other, _ := um.CreateUser("Grace", "grace@example.com", "secret", nil)
+
+	if _, err := um.UpdateUser(user.UID, map[string]interface{}{"email": "ada@lovelace.dev"}); err != nil {
+		t.Fatalf("UpdateUser failed: %v", err)
+	}
+	if got, err := um.GetUserByEmail("ada@lovelace.dev"); err != nil || got.UID != user.UID {
+		t.Errorf("Expected the new email to find the user, got %v, %v", got, err)
+	}
+	if _, err := um.GetUserByEmail("ada@example.com"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Expected the old email to be unindexed, got %v", err)
+	}
+	if _, err := um.AuthenticateUser("ada@lovelace.dev", "secret"); err != nil {
+		t.Errorf("Expected login with the new email: %v", err)
+	}
+
+	// The old email is free again, and a taken one is refused
+	if _, err := um.CreateUser("Someone", "ada@example.com", "secret", nil); err != nil {
+		t.Errorf("Expected the old email to be reusable: %v", err)
+	}
+	if _, err := um.UpdateUser(user.UID, map[string]interface{}{"email": other.Email}); !errors.Is(err, ErrEmailTaken) {
+		t.Errorf("Expected ErrEmailTaken, got %v", err)
+	}
+	if got, _ := um.GetUserByEmail(other.Email); got == nil || got.UID != other.UID {
+		t.Errorf("Expected the other user's index entry untouched, got %v", got)
+	}
+}
+
+func TestListUsers(t *testing.T) {
+	um := newTestManager(t, t.TempDir())
+	defer um.Close()
+
+	var created []*User
+	for i := 0; i < 5; i++ {
+		user, err := um.CreateUser(fmt.Sprintf("User %d", i), fmt.Sprintf("user%d@example.com", i), "secret", nil)
+		if err != nil {
+			t.Fatalf("CreateUser failed: %v", err)
+		}
+		created = append(created, user)
+	}
+	if _, err := um.DeactivateUser(created[2].UID); err != nil {
+		t.Fatalf("DeactivateUser failed: %v", err)
+	}
+
+	// Pages of two cover every user once
+	seen := map[string]bool{}
+	cursor, pages := "", 0
+	for {
+		page, next, err := um.ListUsers(ListUsersOptions{After: cursor, Limit: 2})
+		if err != nil {
+			t.Fatalf("ListUsers failed: %v", err)
+		}
+		pages++
+		for _, user := range page {
+			if seen[user.UID] {
+				t.Errorf("User %s listed twice", user.UID)
+			}
+			seen[user.UID] = true
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	if len(seen) != 5 || pages != 3 {
+		t.Errorf("Expected 5 users in 3 pages, got %d in %d", len(seen), pages)
+	}
+
+	active := false
+	inactive, next, err := um.ListUsers(ListUsersOptions{Active: &active})
+	if err != nil || next != "" || len(inactive) != 1 || inactive[0].UID != created[2].UID {
+		t.Errorf("Expected only the deactivated user, got %v, %q, %v", inactive, next, err)
+	}
+}
+
+func TestChangePasswordAndDeactivate(t *testing.T) {
+	um := newTestManager(t, t.TempDir())
+	defer um.Close()
+
+	user, _ := um.CreateUser("Ada", "ada@example.com", "old secret", nil)
+	_, key, _ := um.CreateAPIKey(user.UID, "ci", nil, nil)
+
+	if err := um.ChangePassword(user.UID, "wrong", "new secret"); !errors.Is(err, ErrWrongPassword) {
+		t.Errorf("Expected ErrWrongPassword, got %v", err)
+	}
+	if err := um.ChangePassword(user.UID, "old secret", "new secret"); err != nil {
+		t.Fatalf("ChangePassword failed: %v", err)
+	}
+	if _, err := um.AuthenticateUser(user.Email, "old secret"); err == nil {
+		t.Error("Expected the old password to be rejected")
+	}
+	if _, err := um.AuthenticateUser(user.Email, "new secret"); err != nil {
+		t.Errorf("Expected the new password to work: %v", err)
+	}
+
+	if _, err := um.DeactivateUser(user.UID); err != nil {
+		t.Fatalf("DeactivateUser failed: %v", err)
+	}
+	if _, err := um.AuthenticateUser(user.Email, "new secret"); err == nil {
+		t.Error("Expected a deactivated user to be refused")
+	}
+	if _, _, err := um.ValidateAPIKey(key); err == nil {
+		t.Error("Expected a deactivated user's key to be refused")
+	}
+
+	if _, err := um.ReactivateUser(user.UID); err != nil {
+		t.Fatalf("ReactivateUser failed: %v", err)
+	}
+	if _, _, err := um.ValidateAPIKey(key); err != nil {
+		t.Errorf("Expected the key to work again: %v", err)
+	}
+}
+
+func TestListAndRevokeAPIKeys(t *testing.T) {
+	um := newTestManager(t, t.TempDir())
+	defer um.Close()
+
+	user, _ := um.CreateUser("Ada", "ada@example.com", "secret", nil)
+	other, _ := um.CreateUser("Grace", "grace@example.com", "secret", nil)
+	um.CreateAPIKey(other.UID, "not ada's", nil, nil)
+
+	expired := time.Now().Add(-time.Hour)
+	_, keepKey, _ := um.CreateAPIKey(user.UID, "keep", nil, []string{ScopeChat})
+	revoked, revokedKey, _ := um.CreateAPIKey(user.UID, "revoke", nil, []string{ScopeChat})
+	um.CreateAPIKey(user.UID, "old", &expired, []string{ScopeChat})
+
+	// Validating before revoking records a last use the revocation must win over
+	if _, _, err := um.ValidateAPIKey(revokedKey); err != nil {
+		t.Fatalf("ValidateAPIKey failed: %v", err)
+	}
+	if err := um.RevokeAPIKey(other.UID, revoked.KeyID); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("Expected another user's key to be out of reach, got %v", err)
+	}
+	if err := um.RevokeAPIKey(user.UID, revoked.KeyID); err != nil {
+		t.Fatalf("RevokeAPIKey failed: %v", err)
+	}
+
+	if _, _, err := um.ValidateAPIKey(revokedKey); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("Expected the revoked key to be refused, got %v", err)
+	}
+	if _, _, err := um.ValidateAPIKey(keepKey); err != nil {
+		t.Errorf("Expected the other keys to keep working: %v", err)
+	}
+
+	keys, err := um.ListAPIKeys(user.UID)
+	if err != nil {
+		t.Fatalf("ListAPIKeys failed: %v", err)
+	}
+	statuses := map[string]string{}
+	for _, key := range keys {
+		if key.KeyHash != "" {
+			t.Errorf("Expected key %s to be listed without its hash", key.Name)
+		}
+		statuses[key.Name] = key.Status()
+		if key.Name == "revoke" && (key.RevokedAt == nil || key.LastUsed == nil) {
+			t.Errorf("Expected the revocation time and last use, got %+v", key)
+		}
+	}
+	want := map[string]string{"keep": KeyStatusActive, "revoke": KeyStatusRevoked, "old": KeyStatusExpired}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Errorf("Expected %v, got %v", want, statuses)
+	}
+
+	if err := um.RevokeAPIKey(user.UID, "missing"); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("Expected ErrAPIKeyNotFound, got %v", err)
+	}
+}
+
+func TestSetAPIKeyAgents(t *testing.T) {
+	um := newTestManager(t, t.TempDir())
+	defer um.Close()
+
+	user, _ := um.CreateUser("Ada", "ada@example.com", "secret", nil)
+	other, _ := um.CreateUser("Grace", "grace@example.com", "secret", nil)
+	record, key, _ := um.CreateAPIKey(user.UID, "ci", nil, []string{ScopeChat})
+
+	if err := um.SetAPIKeyAgents(other.UID, record.KeyID, []string{"ls"}); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("Expected another user's key to be out of reach, got %v", err)
+	}
+	if err := um.SetAPIKeyAgents(user.UID, record.KeyID, []string{" ls", "", "file-agent"}); err != nil {
+		t.Fatalf("SetAPIKeyAgents failed: %v", err)
+	}
+	if _, validated, err := um.ValidateAPIKey(key); err != nil || !reflect.DeepEqual(validated.AllowedAgents, []string{"ls", "file-agent"}) {
+		t.Fatalf("Expected the key to carry its agents, got %+v, %v", validated, err)
+	}
+
+	if err := um.SetAPIKeyAgents(user.UID, record.KeyID, nil); err != nil {
+		t.Fatalf("SetAPIKeyAgents failed: %v", err)
+	}
+	if _, validated, _ := um.ValidateAPIKey(key); len(validated.AllowedAgents) != 0 {
+		t.Errorf("Expected the limit to be lifted, got %v", validated.AllowedAgents)
+	}
+}
+
+func TestUser_HasScope(t *testing.T) {
+	user := &User{Roles: []string{RoleUser}}
+	for _, scope := range []string{ScopeChat, ScopeAgentsRead, ScopeAgentsExecute} {
+		if !user.HasScope(scope) {
+			t.Errorf("Expected the user role to grant %s", scope)
+		}
+	}
+	if user.HasScope(ScopeAdmin) {
+		t.Error("Expected the user role not to grant admin")
+	}
+
+	admin := &User{Roles: []string{RoleAdmin}}
+	if !admin.HasScope(ScopeAdmin) || !admin.HasScope(ScopeChat) {
+		t.Error("Expected the admin role to grant every scope")
+	}
+	if (&User{Roles: []string{"ops"}}).HasScope(ScopeChat) {
+		t.Error("Expected an unknown role to grant nothing")
+	}
+}
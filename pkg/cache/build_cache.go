@@ -55,16 +55,19 @@ type PluginEntry struct {
 type PluginBuildInfo struct {
 	SourceHash      string    `yaml:"source_hash"`
 	GoModHash       string    `yaml:"go_mod_hash"`
+	GoSumHash       string    `yaml:"go_sum_hash,omitempty"`
 	BuildConfigHash string    `yaml:"build_config_hash"`
 	OutputPath      string    `yaml:"output_path"`
 	OutputHash      string    `yaml:"output_hash"`
 	BuildTime       time.Time `yaml:"build_time"`
 	BuildDurationMs int       `yaml:"build_duration_ms"`
-	PluginSizeBytes int64     `yaml:"plugin_size_bytes"`
-	NeedsRebuild    bool      `yaml:"needs_rebuild"`
-	LastUsed        time.Time `yaml:"last_used"`
-	BuildCount      int       `yaml:"build_count"`
-	CacheValid      bool      `yaml:"cache_valid"`
+	// AverageDurationMs is the mean duration of every build of the plugin
+	AverageDurationMs int       `yaml:"average_duration_ms,omitempty"`
+	PluginSizeBytes   int64     `yaml:"plugin_size_bytes"`
+	NeedsRebuild      bool      `yaml:"needs_rebuild"`
+	LastUsed          time.Time `yaml:"last_used"`
+	BuildCount        int       `yaml:"build_count"`
+	CacheValid        bool      `yaml:"cache_valid"`
 }
 
 // SourceFile represents a source file in a plugin
@@ -250,6 +253,19 @@ func (m *Manager) ShouldRebuild(pluginType, pluginName, pluginPath string) (bool
 		}
 	}
 
+	// Entries written before go.sum was tracked have no hash to compare
+	goSumPath := filepath.Join(pluginPath, "go.sum")
+	if pluginEntry.BuildInfo.GoSumHash != "" {
+		currentGoSumHash, err := m.calculateFileHash(goSumPath)
+		if err != nil {
+			return true, "go.sum removed", nil
+		}
+
+		if currentGoSumHash != pluginEntry.BuildInfo.GoSumHash {
+			return true, "go.sum modified", nil
+		}
+	}
+
 	// Check cache validity
 	if !pluginEntry.BuildInfo.CacheValid {
 		return true, "cache entry invalid", nil
@@ -279,6 +295,15 @@ func (m *Manager) UpdatePlugin(pluginType, pluginName, pluginPath string, buildD
 		}
 	}
 
+	goSumHash := ""
+	goSumPath := filepath.Join(pluginPath, "go.sum")
+	if _, err := os.Stat(goSumPath); err == nil {
+		goSumHash, err = m.calculateFileHash(goSumPath)
+		if err != nil {
+			return fmt.Errorf("failed to calculate go.sum hash: %w", err)
+		}
+	}
+
 	outputPath := m.userDirs.GetPluginOutputPath(pluginType, pluginName)
 	outputHash, err := m.calculateFileHash(outputPath)
 	if err != nil {
@@ -296,6 +321,7 @@ func (m *Manager) UpdatePlugin(pluginType, pluginName, pluginPath string, buildD
 		BuildInfo: PluginBuildInfo{
 			SourceHash:      sourceHash,
 			GoModHash:       goModHash,
+			GoSumHash:       goSumHash,
 			BuildConfigHash: "default", // TODO: Implement build config hashing
 			OutputPath:      outputPath,
 			OutputHash:      outputHash,
@@ -309,22 +335,24 @@ func (m *Manager) UpdatePlugin(pluginType, pluginName, pluginPath string, buildD
 		SourceFiles: sourceFiles,
 	}
 
-	// Update build count
+	// Update build count and average duration
+	plugins := m.cache.Plugins.Agents
 	if pluginType == "provider" {
-		if existing, exists := m.cache.Plugins.Providers[pluginName]; exists {
-			pluginEntry.BuildInfo.BuildCount = existing.BuildInfo.BuildCount + 1
-		} else {
-			pluginEntry.BuildInfo.BuildCount = 1
-		}
-		m.cache.Plugins.Providers[pluginName] = pluginEntry
-	} else {
-		if existing, exists := m.cache.Plugins.Agents[pluginName]; exists {
-			pluginEntry.BuildInfo.BuildCount = existing.BuildInfo.BuildCount + 1
-		} else {
-			pluginEntry.BuildInfo.BuildCount = 1
+		plugins = m.cache.Plugins.Providers
+	}
+	pluginEntry.BuildInfo.BuildCount = 1
+	pluginEntry.BuildInfo.AverageDurationMs = buildDurationMs
+	if existing, exists := plugins[pluginName]; exists {
+		previous := existing.BuildInfo
+		pluginEntry.BuildInfo.BuildCount = previous.BuildCount + 1
+		if previous.AverageDurationMs == 0 {
+			// Entries from before averages were kept have only the last build
+			previous.AverageDurationMs = previous.BuildDurationMs
 		}
-		m.cache.Plugins.Agents[pluginName] = pluginEntry
+		total := previous.AverageDurationMs*previous.BuildCount + buildDurationMs
+		pluginEntry.BuildInfo.AverageDurationMs = total / pluginEntry.BuildInfo.BuildCount
 	}
+	plugins[pluginName] = pluginEntry
 
 	// Update statistics
 	m.cache.Statistics.TotalBuilds++
@@ -0,0 +1,100 @@
+package cache
+
+import "sort"
+
+// Sources of a plugin's build time estimate
+const (
+	// EstimatePlugin means the estimate is the plugin's own average
+	EstimatePlugin = "plugin"
+	// EstimateAverage means the plugin has no builds and the estimate is the
+	// average over all builds
+	EstimateAverage = "average"
+)
+
+// PluginPlan explains what a build would do with one plugin
+type PluginPlan struct {
+	Type    string `yaml:"type" json:"type"`
+	Name    string `yaml:"name" json:"name"`
+	Rebuild bool   `yaml:"rebuild" json:"rebuild"`
+	Reason  string `yaml:"reason" json:"reason"`
+	// ChangedFiles lists the source files added, removed, or modified since
+	// the cached build when the sources changed
+	ChangedFiles []string `yaml:"changed_files,omitempty" json:"changed_files,omitempty"`
+	// EstimatedMs is the expected build time; zero when there is no history
+	EstimatedMs  int    `yaml:"estimated_ms" json:"estimated_ms"`
+	EstimateFrom string `yaml:"estimate_from,omitempty" json:"estimate_from,omitempty"`
+}
+
+// PlanPlugin reports whether the plugin would be rebuilt, why, and how long
+// the build is expected to take from the cache's history. It builds nothing.
+func (m *Manager) PlanPlugin(pluginType, pluginName, pluginPath string) PluginPlan {
+	plan := PluginPlan{Type: pluginType, Name: pluginName}
+
+	rebuild, reason, err := m.ShouldRebuild(pluginType, pluginName, pluginPath)
+	if err != nil {
+		rebuild, reason = true, err.Error()
+	}
+	plan.Rebuild = rebuild
+	plan.Reason = reason
+
+	entry, exists := m.pluginEntry(pluginType, pluginName)
+	if rebuild && exists {
+		plan.ChangedFiles = m.changedSourceFiles(entry, pluginPath)
+	}
+
+	switch {
+	case exists && entry.BuildInfo.AverageDurationMs > 0:
+		plan.EstimatedMs = entry.BuildInfo.AverageDurationMs
+		plan.EstimateFrom = EstimatePlugin
+	case exists && entry.BuildInfo.BuildDurationMs > 0:
+		plan.EstimatedMs = entry.BuildInfo.BuildDurationMs
+		plan.EstimateFrom = EstimatePlugin
+	case m.cache != nil && m.cache.Statistics.AverageBuildTimeMs > 0:
+		plan.EstimatedMs = m.cache.Statistics.AverageBuildTimeMs
+		plan.EstimateFrom = EstimateAverage
+	}
+
+	return plan
+}
+
+// pluginEntry returns the cache entry of a plugin
+func (m *Manager) pluginEntry(pluginType, pluginName string) (PluginEntry, bool) {
+	if m.cache == nil {
+		return PluginEntry{}, false
+	}
+	if pluginType == "provider" {
+		entry, ok := m.cache.Plugins.Providers[pluginName]
+		return entry, ok
+	}
+	entry, ok := m.cache.Plugins.Agents[pluginName]
+	return entry, ok
+}
+
+// changedSourceFiles compares the plugin's source files with those recorded
+// at its last build, in path order
+func (m *Manager) changedSourceFiles(entry PluginEntry, pluginPath string) []string {
+	current, err := m.getSourceFiles(pluginPath)
+	if err != nil {
+		return nil
+	}
+
+	recorded := make(map[string]string, len(entry.SourceFiles))
+	for _, file := range entry.SourceFiles {
+		recorded[file.Path] = file.Hash
+	}
+
+	var changed []string
+	for _, file := range current {
+		hash, ok := recorded[file.Path]
+		if !ok || hash != file.Hash {
+			changed = append(changed, file.Path)
+		}
+		delete(recorded, file.Path)
+	}
+	for path := range recorded {
+		changed = append(changed, path)
+	}
+
+	sort.Strings(changed)
+	return changed
+}
@@ -0,0 +1,110 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+// newBuiltPlugin returns a manager whose cache records one agent, echo, as
+// built from its current sources in 1200ms
+func newBuiltPlugin(t *testing.T) (*Manager, string) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+
+	manager, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	if err := manager.LoadCache(); err != nil {
+		t.Fatalf("LoadCache failed: %v", err)
+	}
+
+	pluginPath := filepath.Join(t.TempDir(), "agents", "echo")
+	writeFile(t, filepath.Join(pluginPath, "main.go"), "package main\n")
+	writeFile(t, filepath.Join(pluginPath, "util.go"), "package main\n\nfunc helper() {}\n")
+	writeFile(t, filepath.Join(pluginPath, "go.mod"), "module echo\n")
+	writeFile(t, filepath.Join(pluginPath, "go.sum"), "example.com/dep v1.0.0 h1:abc=\n")
+	writeFile(t, manager.userDirs.GetPluginOutputPath("agent", "echo"), "plugin")
+
+	if err := manager.UpdatePlugin("agent", "echo", pluginPath, 1200, 6); err != nil {
+		t.Fatalf("UpdatePlugin failed: %v", err)
+	}
+	return manager, pluginPath
+}
+
+func TestPlanPlugin_Cached(t *testing.T) {
+	manager, pluginPath := newBuiltPlugin(t)
+
+	plan := manager.PlanPlugin("agent", "echo", pluginPath)
+	if plan.Rebuild || plan.Reason != "plugin is up-to-date" {
+		t.Errorf("Expected an unchanged plugin to be cached, got %+v", plan)
+	}
+	if plan.EstimatedMs != 1200 || plan.EstimateFrom != EstimatePlugin {
+		t.Errorf("Expected the plugin's own 1200ms estimate, got %d from %q", plan.EstimatedMs, plan.EstimateFrom)
+	}
+}
+
+func TestPlanPlugin_ModifiedSource(t *testing.T) {
+	manager, pluginPath := newBuiltPlugin(t)
+
+	writeFile(t, filepath.Join(pluginPath, "main.go"), "package main\n\nfunc main() {}\n")
+	writeFile(t, filepath.Join(pluginPath, "extra.go"), "package main\n")
+	os.Remove(filepath.Join(pluginPath, "util.go"))
+
+	plan := manager.PlanPlugin("agent", "echo", pluginPath)
+	if !plan.Rebuild || plan.Reason != "source files modified" {
+		t.Fatalf("Expected a rebuild for modified sources, got %+v", plan)
+	}
+	expected := []string{"extra.go", "main.go", "util.go"}
+	if len(plan.ChangedFiles) != len(expected) {
+		t.Fatalf("Expected changed files %v, got %v", expected, plan.ChangedFiles)
+	}
+	for i := range expected {
+		if plan.ChangedFiles[i] != expected[i] {
+			t.Errorf("Changed file %d: expected %s, got %s", i, expected[i], plan.ChangedFiles[i])
+		}
+	}
+}
+
+func TestPlanPlugin_ModifiedGoSum(t *testing.T) {
+	manager, pluginPath := newBuiltPlugin(t)
+
+	writeFile(t, filepath.Join(pluginPath, "go.sum"), "example.com/dep v1.1.0 h1:def=\n")
+
+	plan := manager.PlanPlugin("agent", "echo", pluginPath)
+	if !plan.Rebuild || plan.Reason != "go.sum modified" || len(plan.ChangedFiles) != 0 {
+		t.Errorf("Expected a rebuild for go.sum alone, got %+v", plan)
+	}
+}
+
+func TestPlanPlugin_Estimates(t *testing.T) {
+	manager, pluginPath := newBuiltPlugin(t)
+
+	// A second, slower build moves the plugin's average
+	if err := manager.UpdatePlugin("agent", "echo", pluginPath, 1800, 6); err != nil {
+		t.Fatalf("UpdatePlugin failed: %v", err)
+	}
+	if plan := manager.PlanPlugin("agent", "echo", pluginPath); plan.EstimatedMs != 1500 {
+		t.Errorf("Expected the 1500ms average of both builds, got %d", plan.EstimatedMs)
+	}
+
+	// A plugin never built is estimated from every build
+	plan := manager.PlanPlugin("agent", "new", filepath.Join(filepath.Dir(pluginPath), "new"))
+	if !plan.Rebuild || plan.Reason != "plugin not found in cache" {
+		t.Errorf("Expected a rebuild for an unknown plugin, got %+v", plan)
+	}
+	if plan.EstimatedMs != 1500 || plan.EstimateFrom != EstimateAverage {
+		t.Errorf("Expected the 1500ms average over all builds, got %d from %q", plan.EstimatedMs, plan.EstimateFrom)
+	}
+}
@@ -0,0 +1,67 @@
+// Package execrun runs the commands behind exec-based agents, keeping their
+// exit code, stdout, and stderr apart so agents can tell "no results" from
+// "failed"
+package execrun
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Result is what a command wrote and how it exited
+type Result struct {
+	ExitCode int
+	Stdout   string
+	Stderr   string
+}
+
+// Run runs name with args. A command that exits non-zero is not an error; its
+// exit code is in the result. The error is set only when the command could
+// not be started or was stopped by ctx, and the exit code is then -1.
+func Run(ctx context.Context, name string, args ...string) (Result, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	result := Result{Stdout: stdout.String(), Stderr: stderr.String()}
+	if ctxErr := ctx.Err(); ctxErr != nil && err != nil {
+		result.ExitCode = -1
+		return result, ctxErr
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+	default:
+		result.ExitCode = -1
+		return result, err
+	}
+	return result, nil
+}
+
+// Error describes a non-zero exit, with stderr when the command wrote any
+func (r Result) Error() string {
+	message := fmt.Sprintf("exit status %d", r.ExitCode)
+	if stderr := strings.TrimSpace(r.Stderr); stderr != "" {
+		message += ": " + stderr
+	}
+	return message
+}
+
+// Fields returns the result under the exit_code, stdout, and stderr keys
+// agents add to their data
+func (r Result) Fields() map[string]interface{} {
+	return map[string]interface{}{
+		"exit_code": r.ExitCode,
+		"stdout":    r.Stdout,
+		"stderr":    r.Stderr,
+	}
+}
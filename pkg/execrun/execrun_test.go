@@ -0,0 +1,53 @@
+package execrun
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name     string
+		script   string
+		exitCode int
+		stdout   string
+		stderr   string
+	}{
+		{"success", "echo out", 0, "out\n", ""},
+		{"non-zero exit keeps both streams", "echo partial; echo broken >&2; exit 3", 3, "partial\n", "broken\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Run(context.Background(), "sh", "-c", tt.script)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result.ExitCode != tt.exitCode || result.Stdout != tt.stdout || result.Stderr != tt.stderr {
+				t.Errorf("Expected %d %q %q, got %+v", tt.exitCode, tt.stdout, tt.stderr, result)
+			}
+		})
+	}
+}
+
+func TestRun_Errors(t *testing.T) {
+	if result, err := Run(context.Background(), "afe-no-such-command"); err == nil || result.ExitCode != -1 {
+		t.Errorf("Expected an error for a missing command, got %+v", result)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if result, err := Run(ctx, "sleep", "5"); err != context.DeadlineExceeded || result.ExitCode != -1 {
+		t.Errorf("Expected the deadline error, got %+v, %v", result, err)
+	}
+}
+
+func TestResult_Error(t *testing.T) {
+	if got := (Result{ExitCode: 2, Stderr: "ls: cannot access 'x'\n"}).Error(); got != "exit status 2: ls: cannot access 'x'" {
+		t.Errorf("Unexpected message %q", got)
+	}
+	if got := (Result{ExitCode: 1}).Error(); got != "exit status 1" {
+		t.Errorf("Unexpected message %q", got)
+	}
+}
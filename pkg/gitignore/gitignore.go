@@ -0,0 +1,204 @@
+// Package gitignore matches paths against .gitignore rules so agents that
+// walk a repository can skip what git ignores.
+package gitignore
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// FileName is the name of the files rules are read from
+const FileName = ".gitignore"
+
+// rule is one pattern line, applied to paths below the directory of the file
+// it came from
+type rule struct {
+	base    string // slash-separated directory of the file, "" at the root
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// Matcher holds the rules of every .gitignore loaded below a root. Later
+// rules override earlier ones, so files must be loaded from the root down,
+// as a walk visits them.
+type Matcher struct {
+	rules []rule
+}
+
+// New returns a Matcher with no rules
+func New() *Matcher {
+	return &Matcher{}
+}
+
+// LoadDir reads the .gitignore in the directory dir, given relative to root,
+// if there is one
+func (m *Matcher) LoadDir(root, dir string) error {
+	file, err := os.Open(filepath.Join(root, dir, FileName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	m.AddPatterns(dir, lines)
+	return nil
+}
+
+// AddPatterns adds rules in .gitignore syntax that apply below dir, given
+// relative to the root. Blank lines and comments are skipped, as are
+// patterns that cannot be compiled.
+func (m *Matcher) AddPatterns(dir string, lines []string) {
+	base := filepath.ToSlash(filepath.Clean(dir))
+	if base == "." {
+		base = ""
+	}
+
+	for _, line := range lines {
+		if r, ok := parseRule(base, line); ok {
+			m.rules = append(m.rules, r)
+		}
+	}
+}
+
+// Ignored reports whether the path rel, relative to the root, is ignored.
+// A path inside an ignored directory is ignored whatever later rules say, as
+// in git.
+func (m *Matcher) Ignored(rel string, isDir bool) bool {
+	rel = strings.Trim(filepath.ToSlash(filepath.Clean(rel)), "/")
+	if rel == "." || rel == "" || len(m.rules) == 0 {
+		return false
+	}
+
+	parts := strings.Split(rel, "/")
+	for i := 1; i < len(parts); i++ {
+		if m.match(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+	return m.match(rel, isDir)
+}
+
+// match applies the rules to one path; the last matching rule decides
+func (m *Matcher) match(rel string, isDir bool) bool {
+	ignored := false
+	for _, r := range m.rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+
+		target := rel
+		if r.base != "" {
+			var ok bool
+			if target, ok = strings.CutPrefix(rel, r.base+"/"); !ok {
+				continue
+			}
+		}
+
+		if r.re.MatchString(target) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+// parseRule compiles one line of a .gitignore
+func parseRule(base, line string) (rule, bool) {
+	// Trailing spaces are dropped unless escaped
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule{}, false
+	}
+
+	r := rule{base: base}
+	if strings.HasPrefix(line, "!") {
+		r.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return rule{}, false
+	}
+
+	// A slash anywhere but the end anchors the pattern to the base directory;
+	// otherwise it matches a name at any depth
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	expr := globToRegexp(line)
+	if anchored {
+		expr = "^" + expr + "$"
+	} else {
+		expr = "^(?:.*/)?" + expr + "$"
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return rule{}, false
+	}
+	r.re = re
+	return r, true
+}
+
+// globToRegexp translates a gitignore glob: * and ? never match a slash, **
+// matches across directories, and [...] is a character class
+func globToRegexp(glob string) string {
+	var sb strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/") && (i == 0 || glob[i-1] == '/'):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case glob[i:] == "**" && (i == 0 || glob[i-1] == '/'):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			sb.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,82 @@
+package gitignore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMatcher_Patterns(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []string{
+		"# build output",
+		"*.log",
+		"/bin",
+		"build/",
+		"docs/**/*.tmp",
+		"!keep.log",
+		"[Tt]emp?",
+		`\#notes`,
+	})
+
+	testCases := []struct {
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{"app.log", false, true},
+		{"nested/deep/app.log", false, true},
+		{"keep.log", false, false},
+		{"bin", true, true},
+		{"bin/tool", false, true},
+		{"cmd/bin", true, false},
+		{"build", true, true},
+		{"build", false, false},
+		{"src/build/out.o", false, true},
+		{"docs/a/b/x.tmp", false, true},
+		{"docs/x.tmp", false, true},
+		{"src/x.tmp", false, false},
+		{"Temp1", false, true},
+		{"temps/file", false, true},
+		{"#notes", false, true},
+		{"main.go", false, false},
+	}
+
+	for _, tc := range testCases {
+		if got := m.Ignored(tc.path, tc.isDir); got != tc.ignored {
+			t.Errorf("Ignored(%q, %v) = %v, want %v", tc.path, tc.isDir, got, tc.ignored)
+		}
+	}
+}
+
+func TestMatcher_NestedFiles(t *testing.T) {
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "sub"), 0755)
+	os.WriteFile(filepath.Join(root, FileName), []byte("*.out\n"), 0644)
+	os.WriteFile(filepath.Join(root, "sub", FileName), []byte("!keep.out\n/local.txt\n"), 0644)
+
+	m := New()
+	for _, dir := range []string{".", "sub", "missing"} {
+		if err := m.LoadDir(root, dir); err != nil {
+			t.Fatalf("LoadDir(%q) failed: %v", dir, err)
+		}
+	}
+
+	testCases := []struct {
+		path    string
+		ignored bool
+	}{
+		{"a.out", true},
+		{"sub/a.out", true},
+		{"sub/keep.out", false},
+		{"keep.out", true},
+		{"sub/local.txt", true},
+		{"local.txt", false},
+		{"sub/deeper/local.txt", false},
+	}
+	for _, tc := range testCases {
+		if got := m.Ignored(tc.path, false); got != tc.ignored {
+			t.Errorf("Ignored(%q) = %v, want %v", tc.path, got, tc.ignored)
+		}
+	}
+}
@@ -140,6 +140,7 @@ func (hrm *Manager) reloadWorker(workerID int) {
 		result := hrm.processReload(request)
 		hrm.logReloadResult(result)
 		hrm.notifyCallbacks(request.PluginName, result.Error)
+		hrm.publishResult(result)
 	}
 
 	log.Printf("🔄 Hot reload worker %d stopped", workerID)
@@ -243,6 +244,24 @@ func (hrm *Manager) notifyCallbacks(pluginName string, err error) {
 	}
 }
 
+// publishResult publishes a reload to the build topic of the API's event
+// clients, when the plugin manager has an event function
+func (hrm *Manager) publishResult(result ReloadResult) {
+	event := map[string]interface{}{
+		"type":        "plugin_reloaded",
+		"topic":       "build",
+		"plugin_type": result.PluginType,
+		"name":        result.PluginName,
+		"success":     result.Success,
+		"duration_ms": float64(result.Duration.Microseconds()) / 1000,
+		"timestamp":   time.Now(),
+	}
+	if result.Error != nil {
+		event["error"] = result.Error.Error()
+	}
+	hrm.pluginManager.PublishEvent(event)
+}
+
 // RegisterCallback registers a callback for reload events
 func (hrm *Manager) RegisterCallback(id string, callback func(string, error)) {
 	hrm.mu.Lock()
@@ -1,6 +1,11 @@
 package interfaces
 
-import "context"
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
 
 // Agent represents an agent that can process requests
 type Agent interface {
@@ -16,6 +21,44 @@ type AgentInput struct {
 	Type     string                 `json:"type"`
 	Payload  map[string]interface{} `json:"payload"`
 	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	// Secrets hold the credentials of this call, such as an API token.
+	// Agents read credentials from here rather than the payload, which is
+	// logged, audited, and part of cache keys; secrets are none of those.
+	Secrets Secrets `json:"-"`
+}
+
+// Redacted replaces secret values wherever they are printed
+const Redacted = "[REDACTED]"
+
+// Secrets map credential names to their values. They print and marshal with
+// every value redacted, so an AgentInput can be logged or encoded without
+// leaking them; Get returns the value.
+type Secrets map[string]string
+
+// Get returns the named secret, or "" when it is not set
+func (s Secrets) Get(name string) string {
+	return s[name]
+}
+
+// redacted returns the secrets with each value replaced by Redacted
+func (s Secrets) redacted() map[string]string {
+	redacted := make(map[string]string, len(s))
+	for name := range s {
+		redacted[name] = Redacted
+	}
+	return redacted
+}
+
+func (s Secrets) String() string {
+	return fmt.Sprint(s.redacted())
+}
+
+func (s Secrets) GoString() string {
+	return fmt.Sprintf("interfaces.Secrets%#v", s.redacted())
+}
+
+func (s Secrets) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.redacted())
 }
 
 // AgentOutput represents output from an agent
@@ -23,6 +66,117 @@ type AgentOutput struct {
 	Success bool                   `json:"success"`
 	Data    map[string]interface{} `json:"data,omitempty"`
 	Error   string                 `json:"error,omitempty"`
+	// Code identifies the error for clients; the plugin manager sets it,
+	// such as for an agent that panicked
+	Code string `json:"code,omitempty"`
+	// Warnings report issues that did not fail the call, such as truncated
+	// output or a deprecated operation type
+	Warnings []string `json:"warnings,omitempty"`
+	// Meta describes the call; the plugin manager sets it, agents need not
+	Meta *CallMeta `json:"meta,omitempty"`
+}
+
+// CallMeta is the operational metadata of one agent call
+type CallMeta struct {
+	Agent string `json:"agent"`
+	// AgentVersion is set for agents that implement Versioned
+	AgentVersion string    `json:"agent_version,omitempty"`
+	StartedAt    time.Time `json:"started_at"`
+	DurationMs   float64   `json:"duration_ms"`
+}
+
+// Versioned is implemented by agents that report their version
+type Versioned interface {
+	Version() string
+}
+
+// AgentCaller dispatches a call from one agent to another loaded agent
+type AgentCaller interface {
+	CallAgent(ctx context.Context, name string, input AgentInput) (AgentOutput, error)
+}
+
+// CallerAware is implemented by agents that call other agents. The plugin
+// manager hands them an AgentCaller when they are registered; they must pass
+// on the context they were given so nested calls can be bounded.
+type CallerAware interface {
+	SetAgentCaller(caller AgentCaller)
+}
+
+// InputValidator is implemented by agents that can check an input without
+// running it, such as for a dry run of a call. It returns an error
+// describing what is wrong with the input.
+type InputValidator interface {
+	ValidateInput(input AgentInput) error
+}
+
+// Classification declares what calling an agent does to the system it runs
+// on. The engine allows read-only agents by default, runs their calls in
+// parallel, and reuses the results of those that are also idempotent.
+type Classification struct {
+	// ReadOnly agents change nothing, such as ls or cat
+	ReadOnly bool `json:"read_only"`
+	// Idempotent agents give the same result and leave the same state when
+	// called again with the same input, until something else changes it
+	Idempotent bool `json:"idempotent"`
+	// Destructive agents may delete or overwrite data, such as rm or mv
+	Destructive bool `json:"destructive"`
+	// Network agents reach other hosts, such as web-agent, so being read-only
+	// does not make them safe to allow
+	Network bool `json:"network"`
+}
+
+// Cacheable reports whether a call's result may be reused for the same call
+// until something changes state
+func (c Classification) Cacheable() bool {
+	return c.ReadOnly && c.Idempotent
+}
+
+// Classified is implemented by agents that declare their Classification
+type Classified interface {
+	Classification() Classification
+}
+
+// ClassificationOf returns the classification agent declares. Agents that
+// declare none are treated as destructive.
+func ClassificationOf(agent Agent) Classification {
+	if classified, ok := agent.(Classified); ok {
+		return classified.Classification()
+	}
+	return Classification{Destructive: true}
+}
+
+// ParamType is the declared type of a payload parameter
+type ParamType string
+
+const (
+	ParamString  ParamType = "string"
+	ParamInt     ParamType = "int"
+	ParamFloat   ParamType = "float"
+	ParamBool    ParamType = "bool"
+	ParamStrings ParamType = "[]string"
+)
+
+// InputSchema maps payload keys to the types an agent reads them as
+type InputSchema map[string]ParamType
+
+// SchemaProvider is implemented by agents that declare the types of their
+// payload parameters. Payloads decoded from JSON carry every number as a
+// float64, so callers such as the API convert the declared parameters before
+// calling Process; keys the schema does not list are passed through.
+type SchemaProvider interface {
+	InputSchema() InputSchema
+}
+
+// EventFunc publishes an event to clients of the engine, such as the API's
+// /api/v1/events WebSocket. Events carry a "type" field, and may name their
+// "topic"; the API sends them to the agents topic otherwise. They must be
+// JSON serializable; publishing never blocks.
+type EventFunc func(event map[string]interface{})
+
+// EventAware is implemented by agents that publish events. The plugin manager
+// hands them the engine's EventFunc once one is set.
+type EventAware interface {
+	SetEventFunc(publish EventFunc)
 }
 
 // Model represents a language model interface
@@ -35,12 +189,21 @@ type Model interface {
 	Shutdown() error
 }
 
+// StreamingModel is implemented by models that can emit text as it is
+// generated. The returned channel yields deltas and is closed after a final
+// chunk with Done set; cancelling ctx stops the stream early.
+type StreamingModel interface {
+	Model
+	GenerateStream(ctx context.Context, req GenerationRequest) (<-chan GenerationChunk, error)
+}
+
 // ModelType represents the type of model connection
 type ModelType string
 
 const (
 	ModelTypeHTTP      ModelType = "http"
 	ModelTypeWebSocket ModelType = "websocket"
+	ModelTypeProvider  ModelType = "provider"
 )
 
 // ModelConfig represents configuration for a model
@@ -51,23 +214,81 @@ type ModelConfig struct {
 	Options  map[string]interface{} `json:"options,omitempty"`
 }
 
-// GenerationRequest represents a request to generate text
+// ChatMessage is one turn of a conversation
+type ChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// GenerationRequest represents a request to generate text. Zero sampling
+// parameters are left unset so the backend's defaults apply.
 type GenerationRequest struct {
-	Prompt      string                 `json:"prompt"`
-	MaxTokens   int                    `json:"max_tokens,omitempty"`
-	Temperature float64                `json:"temperature,omitempty"`
-	StopTokens  []string               `json:"stop_tokens,omitempty"`
-	Stream      bool                   `json:"stream,omitempty"`
-	Options     map[string]interface{} `json:"options,omitempty"`
+	Prompt string `json:"prompt"`
+	// Messages, when set, is the conversation to continue and is used instead
+	// of Prompt by providers that take chat messages
+	Messages    []ChatMessage `json:"messages,omitempty"`
+	MaxTokens   int           `json:"max_tokens,omitempty"`
+	Temperature float64       `json:"temperature,omitempty"`
+	StopTokens  []string      `json:"stop_tokens,omitempty"`
+	Stream      bool          `json:"stream,omitempty"`
+
+	// Sampling parameters
+	TopP             float64 `json:"top_p,omitempty"`
+	TopK             int     `json:"top_k,omitempty"`
+	RepeatPenalty    float64 `json:"repeat_penalty,omitempty"`
+	PresencePenalty  float64 `json:"presence_penalty,omitempty"`
+	FrequencyPenalty float64 `json:"frequency_penalty,omitempty"`
+	// Seed makes sampling reproducible; nil picks a random seed
+	Seed *int `json:"seed,omitempty"`
+
+	Options map[string]interface{} `json:"options,omitempty"`
 }
 
 // GenerationResponse represents the response from text generation
 type GenerationResponse struct {
-	Text     string `json:"text"`
-	Tokens   int    `json:"tokens,omitempty"`
-	Finished bool   `json:"finished"`
-	Model    string `json:"model"`
-	Error    string `json:"error,omitempty"`
+	Text string `json:"text"`
+	// Tokens counts the completion; PromptTokens the prompt, when the
+	// provider reports it
+	Tokens       int  `json:"tokens,omitempty"`
+	PromptTokens int  `json:"prompt_tokens,omitempty"`
+	Finished     bool `json:"finished"`
+	// FinishReason is "stop" when generation ended naturally or on a stop
+	// sequence and "length" when it hit the token limit
+	FinishReason string `json:"finish_reason,omitempty"`
+	Model        string `json:"model"`
+	// Provider is the registered model that served the request, which may be
+	// a fallback for the one requested
+	Provider string           `json:"provider,omitempty"`
+	Stats    *GenerationStats `json:"stats,omitempty"`
+	Error    string           `json:"error,omitempty"`
+}
+
+// GenerationStats reports how fast a streamed generation was produced
+type GenerationStats struct {
+	// TimeToFirstTokenMs is the wait from sending the request to the first
+	// chunk of text
+	TimeToFirstTokenMs float64 `json:"time_to_first_token_ms"`
+	// DurationMs is the time from sending the request to the end of the stream
+	DurationMs float64 `json:"duration_ms"`
+	// TokensPerSecond is the generation rate after the first token
+	TokensPerSecond float64 `json:"tokens_per_second"`
+}
+
+// GenerationChunk is one piece of a streamed generation. Intermediate chunks
+// carry a text delta; the final chunk has Done set along with the token count
+// and the reason generation stopped, or Error if the stream failed.
+type GenerationChunk struct {
+	Delta        string `json:"delta,omitempty"`
+	Done         bool   `json:"done"`
+	Tokens       int    `json:"tokens,omitempty"`
+	PromptTokens int    `json:"prompt_tokens,omitempty"`
+	FinishReason string `json:"finish_reason,omitempty"`
+	Model        string `json:"model,omitempty"`
+	// Provider and Stats are set on the final chunk, as for
+	// GenerationResponse
+	Provider string           `json:"provider,omitempty"`
+	Stats    *GenerationStats `json:"stats,omitempty"`
+	Error    string           `json:"error,omitempty"`
 }
 
 // PluginManager handles dynamic loading of agents
@@ -94,6 +315,30 @@ type ConfigManager interface {
 type ServerConfig struct {
 	Host string `yaml:"host"`
 	Port int    `yaml:"port"`
+	// ReadTimeout, WriteTimeout, and IdleTimeout bound, in seconds, reading
+	// a request, writing its response, and waiting for the next request on
+	// a kept-alive connection
+	ReadTimeout  int `yaml:"read_timeout" mapstructure:"read_timeout"`
+	WriteTimeout int `yaml:"write_timeout" mapstructure:"write_timeout"`
+	IdleTimeout  int `yaml:"idle_timeout" mapstructure:"idle_timeout"`
+	// MaxRequestTimeout bounds, in seconds, a chat and the timeout a request
+	// may ask for
+	MaxRequestTimeout int `yaml:"max_request_timeout" mapstructure:"max_request_timeout"`
+	// MaxBodyBytes bounds the size of request bodies
+	MaxBodyBytes int64 `yaml:"max_body_bytes" mapstructure:"max_body_bytes"`
+	// RateLimit bounds how often each client may call the API
+	RateLimit RateLimitConfig `yaml:"rate_limit" mapstructure:"rate_limit"`
+	// AllowedOrigins are the browser origins that may call the API across
+	// origins; none may when it is empty
+	AllowedOrigins []string `yaml:"allowed_origins" mapstructure:"allowed_origins"`
+}
+
+// RateLimitConfig sets each API client's token bucket
+type RateLimitConfig struct {
+	// RequestsPerMinute refills the bucket; zero disables the limit
+	RequestsPerMinute int `yaml:"requests_per_minute" mapstructure:"requests_per_minute"`
+	// Burst is how many requests a client may make at once
+	Burst int `yaml:"burst" mapstructure:"burst"`
 }
 
 // AgentConfig represents agent configuration
@@ -107,6 +352,14 @@ type AgentConfig struct {
 	Config     map[string]interface{} `yaml:"config,omitempty"`
 }
 
+// ProviderConfig represents provider plugin configuration. A built plugin is
+// matched to its entry by the base name of Path or, failing that, by Name.
+type ProviderConfig struct {
+	Name   string                 `yaml:"name"`
+	Path   string                 `yaml:"path,omitempty"`
+	Config map[string]interface{} `yaml:"config,omitempty"`
+}
+
 // RecoveryConfig represents recovery configuration
 type RecoveryConfig struct {
 	HotReload   bool `yaml:"hot_reload"`
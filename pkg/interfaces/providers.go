@@ -10,3 +10,10 @@ type Provider interface {
 	HealthCheck() error
 	Shutdown() error
 }
+
+// StreamingProvider is implemented by providers that can emit text as it is
+// generated rather than returning it once the generation finishes
+type StreamingProvider interface {
+	Provider
+	GenerateStream(ctx context.Context, input GenerationRequest) (<-chan GenerationChunk, error)
+}
@@ -0,0 +1,36 @@
+// Package pathutil gives file agents a common way to report the paths they act on
+package pathutil
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Resolve returns path as an absolute, cleaned path with symlinks resolved.
+// Paths that do not exist (yet, or any more) are resolved through their
+// longest existing ancestor, so a file about to be created or just removed
+// still correlates with paths reported for its siblings.
+func Resolve(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+
+	// Walk up until an ancestor exists, then re-append the missing components
+	existing := abs
+	missing := ""
+	for {
+		if resolved, err := filepath.EvalSymlinks(existing); err == nil {
+			return filepath.Join(resolved, missing)
+		} else if !os.IsNotExist(err) {
+			return abs
+		}
+
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return abs
+		}
+		missing = filepath.Join(filepath.Base(existing), missing)
+		existing = parent
+	}
+}
@@ -0,0 +1,60 @@
+package pathutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	// Resolve the temp dir itself first; on some systems it sits behind a symlink
+	tmpDir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to resolve temp dir: %v", err)
+	}
+
+	realDir := filepath.Join(tmpDir, "real")
+	if err := os.Mkdir(realDir, 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(realDir, "file.txt"), []byte("x"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	link := filepath.Join(tmpDir, "link")
+	if err := os.Symlink(realDir, link); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{"cleaned", tmpDir + "/real/./sub/../file.txt", filepath.Join(realDir, "file.txt")},
+		{"trailing slash", realDir + "/", realDir},
+		{"symlink", filepath.Join(link, "file.txt"), filepath.Join(realDir, "file.txt")},
+		{"missing leaf behind symlink", filepath.Join(link, "new", "file.txt"), filepath.Join(realDir, "new", "file.txt")},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Resolve(tc.path)
+			if got != tc.expected {
+				t.Errorf("Resolve(%q) = %q, expected %q", tc.path, got, tc.expected)
+			}
+			if !filepath.IsAbs(got) || filepath.Clean(got) != got {
+				t.Errorf("Expected an absolute, cleaned path, got %q", got)
+			}
+		})
+	}
+}
+
+func TestResolve_Relative(t *testing.T) {
+	got := Resolve("some/../relative/path")
+	if !filepath.IsAbs(got) {
+		t.Errorf("Expected relative path to be made absolute, got %q", got)
+	}
+	if filepath.Base(got) != "path" || filepath.Base(filepath.Dir(got)) != "relative" {
+		t.Errorf("Expected cleaned path ending in relative/path, got %q", got)
+	}
+}
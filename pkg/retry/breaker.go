@@ -0,0 +1,129 @@
+package retry
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without calling the backend while a breaker is
+// open
+var ErrCircuitOpen = errors.New("circuit open")
+
+// Breaker stops calls to a backend after consecutive failures. Once Threshold
+// failures in a row are recorded it opens and rejects calls for Cooldown,
+// then lets a single trial call through: success closes it again, failure
+// reopens it. A nil *Breaker never opens.
+type Breaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	failures int
+	open     bool
+	openedAt time.Time
+	// trial is set while the call after a cooldown is in flight
+	trial bool
+}
+
+// NewBreaker returns a breaker that opens after threshold consecutive
+// failures and stays open for cooldown
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+// BreakerFromConfig reads circuit_failure_threshold (default 5) and
+// circuit_cooldown_seconds (default 30) from a provider's Initialize map. A
+// threshold of zero disables the breaker and returns nil.
+func BreakerFromConfig(config map[string]interface{}) *Breaker {
+	threshold, cooldown := 5, 30
+	if n, ok := getInt(config, "circuit_failure_threshold"); ok {
+		threshold = n
+	}
+	if n, ok := getInt(config, "circuit_cooldown_seconds"); ok && n >= 0 {
+		cooldown = n
+	}
+	if threshold <= 0 {
+		return nil
+	}
+	return NewBreaker(threshold, time.Duration(cooldown)*time.Second)
+}
+
+// Allow returns an error wrapping ErrCircuitOpen when a call must not be
+// made now
+func (b *Breaker) Allow() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.open {
+		return nil
+	}
+	if remaining := b.cooldown - b.now().Sub(b.openedAt); remaining > 0 {
+		return b.openError(remaining)
+	}
+	if b.trial {
+		return fmt.Errorf("%w: waiting for a trial call to finish", ErrCircuitOpen)
+	}
+	b.trial = true
+	return nil
+}
+
+// Record reports the outcome of an allowed call
+func (b *Breaker) Record(failed bool) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+	if !failed {
+		b.failures = 0
+		b.open = false
+		return
+	}
+
+	b.failures++
+	if b.open || b.failures >= b.threshold {
+		b.open = true
+		b.openedAt = b.now()
+	}
+}
+
+// Release ends an allowed call that had no outcome, such as one cancelled by
+// its caller, so another trial call may be made
+func (b *Breaker) Release() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+}
+
+// Err returns an error wrapping ErrCircuitOpen while the breaker rejects
+// calls, for use in health checks
+func (b *Breaker) Err() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.open {
+		return nil
+	}
+	if remaining := b.cooldown - b.now().Sub(b.openedAt); remaining > 0 {
+		return b.openError(remaining)
+	}
+	return nil
+}
+
+func (b *Breaker) openError(remaining time.Duration) error {
+	return fmt.Errorf("%w after %d consecutive failures, retrying in %v", ErrCircuitOpen, b.failures, remaining.Round(time.Millisecond))
+}
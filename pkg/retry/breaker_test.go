@@ -0,0 +1,108 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// newTestBreaker returns a breaker driven by a fake clock
+func newTestBreaker(threshold int, cooldown time.Duration) (*Breaker, *time.Time) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	breaker := NewBreaker(threshold, cooldown)
+	breaker.now = func() time.Time { return now }
+	return breaker, &now
+}
+
+func TestBreaker_OpensAfterConsecutiveFailures(t *testing.T) {
+	breaker, now := newTestBreaker(3, time.Minute)
+
+	// A success resets the count
+	breaker.Record(true)
+	breaker.Record(true)
+	breaker.Record(false)
+	breaker.Record(true)
+	breaker.Record(true)
+	if err := breaker.Allow(); err != nil {
+		t.Fatalf("Expected the breaker to stay closed, got %v", err)
+	}
+
+	breaker.Record(true)
+	if err := breaker.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected the breaker to open, got %v", err)
+	}
+	if err := breaker.Err(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected Err to report the open circuit, got %v", err)
+	}
+
+	// After the cooldown one trial call is let through
+	*now = now.Add(time.Minute)
+	if err := breaker.Allow(); err != nil {
+		t.Fatalf("Expected a trial call after the cooldown, got %v", err)
+	}
+	if err := breaker.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected other calls to wait for the trial, got %v", err)
+	}
+
+	// A failed trial reopens it; a successful one closes it
+	breaker.Record(true)
+	if err := breaker.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected a failed trial to reopen the breaker, got %v", err)
+	}
+	*now = now.Add(time.Minute)
+	breaker.Allow()
+	breaker.Record(false)
+	if err := breaker.Allow(); err != nil || breaker.Err() != nil {
+		t.Errorf("Expected a successful trial to close the breaker, got %v", err)
+	}
+}
+
+func TestDo_FailsFastWhenOpen(t *testing.T) {
+	breaker, _ := newTestBreaker(2, time.Minute)
+
+	calls := 0
+	err := fastPolicy.Do(context.Background(), breaker, func(ctx context.Context) error {
+		calls++
+		return &StatusError{StatusCode: 503}
+	})
+	if calls != 2 || !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected the breaker to stop retries after 2 calls, got %d calls, %v", calls, err)
+	}
+
+	calls = 0
+	err = fastPolicy.Do(context.Background(), breaker, func(ctx context.Context) error {
+		calls++
+		return nil
+	})
+	if calls != 0 || !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected an open breaker to fail without calling, got %d calls, %v", calls, err)
+	}
+
+	// Client errors do not count as backend failures
+	breaker, _ = newTestBreaker(1, time.Minute)
+	fastPolicy.Do(context.Background(), breaker, func(ctx context.Context) error {
+		return &StatusError{StatusCode: 404}
+	})
+	if err := breaker.Err(); err != nil {
+		t.Errorf("Expected a 4xx not to open the breaker, got %v", err)
+	}
+}
+
+func TestBreakerFromConfig(t *testing.T) {
+	if breaker := BreakerFromConfig(map[string]interface{}{"circuit_failure_threshold": 0}); breaker != nil {
+		t.Error("Expected a zero threshold to disable the breaker")
+	}
+
+	breaker := BreakerFromConfig(map[string]interface{}{"circuit_failure_threshold": float64(2), "circuit_cooldown_seconds": 10})
+	if breaker.threshold != 2 || breaker.cooldown != 10*time.Second {
+		t.Errorf("Unexpected breaker settings: threshold=%d cooldown=%v", breaker.threshold, breaker.cooldown)
+	}
+
+	// A nil breaker never opens
+	var disabled *Breaker
+	disabled.Record(true)
+	if disabled.Allow() != nil || disabled.Err() != nil {
+		t.Error("Expected a nil breaker to allow every call")
+	}
+}
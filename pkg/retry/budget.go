@@ -0,0 +1,70 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrBudgetExhausted is returned when a retry is refused because the request
+// it belongs to has used up its retry budget
+var ErrBudgetExhausted = errors.New("retry budget exhausted")
+
+// Budget bounds the retries made on behalf of one request, such as a chat
+// whose several agent and provider calls would otherwise each retry on their
+// own. It is safe for concurrent use. A nil budget allows every retry.
+type Budget struct {
+	mu    sync.Mutex
+	limit int
+	used  int
+}
+
+// BudgetReport is the state of a budget as reported to clients
+type BudgetReport struct {
+	Limit     int `json:"limit"`
+	Used      int `json:"used"`
+	Remaining int `json:"remaining"`
+}
+
+// NewBudget returns a budget allowing limit retries
+func NewBudget(limit int) *Budget {
+	if limit < 0 {
+		limit = 0
+	}
+	return &Budget{limit: limit}
+}
+
+// Take spends one retry and reports whether the budget allowed it
+func (b *Budget) Take() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.used >= b.limit {
+		return false
+	}
+	b.used++
+	return true
+}
+
+// Report returns how much of the budget has been spent
+func (b *Budget) Report() BudgetReport {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return BudgetReport{Limit: b.limit, Used: b.used, Remaining: b.limit - b.used}
+}
+
+type budgetKey struct{}
+
+// WithBudget returns a context whose retries are charged to budget
+func WithBudget(ctx context.Context, budget *Budget) context.Context {
+	return context.WithValue(ctx, budgetKey{}, budget)
+}
+
+// BudgetFromContext returns the budget retries under ctx are charged to, or
+// nil when they are not limited
+func BudgetFromContext(ctx context.Context) *Budget {
+	budget, _ := ctx.Value(budgetKey{}).(*Budget)
+	return budget
+}
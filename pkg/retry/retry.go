@@ -0,0 +1,174 @@
+// Package retry retries transient failures of provider calls with exponential
+// backoff and stops calling a backend that keeps failing with a circuit
+// breaker.
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"time"
+)
+
+// Policy controls how often and how quickly a failed call is retried
+type Policy struct {
+	// MaxAttempts is the total number of calls, including the first
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry; each later retry
+	// waits twice as long up to MaxBackoff
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Jitter randomizes each wait by up to this fraction in either direction
+	// so clients that failed together do not retry together
+	Jitter float64
+}
+
+// DefaultPolicy returns the policy used when a provider sets no retry options
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxAttempts:    3,
+		InitialBackoff: 200 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+		Jitter:         0.2,
+	}
+}
+
+// PolicyFromConfig reads max_attempts, retry_backoff_ms, and
+// retry_max_backoff_ms from a provider's Initialize map over the defaults.
+// max_retries, the retries after the first attempt, and retry_backoff, a
+// duration such as "500ms", are accepted as well and take precedence.
+func PolicyFromConfig(config map[string]interface{}) Policy {
+	policy := DefaultPolicy()
+	if n, ok := getInt(config, "max_attempts"); ok && n > 0 {
+		policy.MaxAttempts = n
+	}
+	if n, ok := getInt(config, "max_retries"); ok && n >= 0 {
+		policy.MaxAttempts = n + 1
+	}
+	if ms, ok := getInt(config, "retry_backoff_ms"); ok && ms >= 0 {
+		policy.InitialBackoff = time.Duration(ms) * time.Millisecond
+	}
+	if s, ok := config["retry_backoff"].(string); ok {
+		if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+			policy.InitialBackoff = d
+		}
+	}
+	if ms, ok := getInt(config, "retry_max_backoff_ms"); ok && ms >= 0 {
+		policy.MaxBackoff = time.Duration(ms) * time.Millisecond
+	}
+	return policy
+}
+
+// StatusError reports an HTTP response with an unsuccessful status. Server
+// errors are retried; client errors are not.
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("HTTP %d: %s", e.StatusCode, e.Body)
+}
+
+// Retryable reports whether err is worth retrying: connection failures,
+// timeouts, dropped connections, and 5xx responses. Cancellation and 4xx
+// responses are not retried, nor are errors of any other kind.
+func Retryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	return errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// Do calls fn until it succeeds, fails with an error that is not retryable,
+// or runs out of attempts, waiting between attempts. When breaker is not nil
+// every attempt must be allowed by it and backend failures are recorded on
+// it. Each retry is charged to the budget of ctx, if any, and once that is
+// spent Do returns the last error wrapped with ErrBudgetExhausted. Do gives up
+// as soon as ctx is done and returns the last error.
+func (p Policy) Do(ctx context.Context, breaker *Breaker, fn func(ctx context.Context) error) error {
+	attempts := p.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if openErr := breaker.Allow(); openErr != nil {
+			if err != nil {
+				return fmt.Errorf("%w (last error: %v)", openErr, err)
+			}
+			return openErr
+		}
+
+		err = fn(ctx)
+
+		// A cancelled caller is not a backend failure
+		if ctx.Err() != nil {
+			breaker.Release()
+			return err
+		}
+
+		// Only failures worth retrying mean the backend is unhealthy
+		retryable := Retryable(err)
+		breaker.Record(retryable)
+		if err == nil || !retryable || attempt >= attempts {
+			return err
+		}
+		if !BudgetFromContext(ctx).Take() {
+			return fmt.Errorf("%w (%w)", err, ErrBudgetExhausted)
+		}
+
+		select {
+		case <-time.After(p.backoff(attempt)):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+// backoff returns how long to wait after the given failed attempt
+func (p Policy) backoff(attempt int) time.Duration {
+	wait := p.InitialBackoff
+	for i := 1; i < attempt && wait < p.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if p.MaxBackoff > 0 && wait > p.MaxBackoff {
+		wait = p.MaxBackoff
+	}
+
+	if p.Jitter > 0 && wait > 0 {
+		spread := float64(wait) * p.Jitter
+		wait += time.Duration((rand.Float64()*2 - 1) * spread)
+	}
+	return wait
+}
+
+// getInt reads a number that may arrive as an int or as a JSON float64
+func getInt(values map[string]interface{}, key string) (int, bool) {
+	switch v := values[key].(type) {
+	case int:
+		return v, true
+	case int64:
+		return int(v), true
+	case float64:
+		return int(v), true
+	}
+	return 0, false
+}
@@ -0,0 +1,181 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// fastPolicy retries without waiting
+var fastPolicy = Policy{MaxAttempts: 4}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected bool
+	}{
+		{nil, false},
+		{&StatusError{StatusCode: 503}, true},
+		{fmt.Errorf("request failed: %w", &StatusError{StatusCode: 500}), true},
+		{&StatusError{StatusCode: 404}, false},
+		{&StatusError{StatusCode: 429}, false},
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, true},
+		{fmt.Errorf("read: %w", io.ErrUnexpectedEOF), true},
+		{context.DeadlineExceeded, true},
+		{context.Canceled, false},
+		{errors.New("failed to apply template"), false},
+	}
+
+	for _, test := range tests {
+		if got := Retryable(test.err); got != test.expected {
+			t.Errorf("Retryable(%v) = %v, want %v", test.err, got, test.expected)
+		}
+	}
+}
+
+func TestDo_RetriesUntilSuccess(t *testing.T) {
+	calls := 0
+	err := fastPolicy.Do(context.Background(), nil, func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			return &StatusError{StatusCode: 502}
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("Expected success on the third call, got %d calls, %v", calls, err)
+	}
+}
+
+func TestDo_StopsOnPermanentErrorsAndAttemptLimit(t *testing.T) {
+	calls := 0
+	err := fastPolicy.Do(context.Background(), nil, func(ctx context.Context) error {
+		calls++
+		return &StatusError{StatusCode: 400}
+	})
+	if calls != 1 || err == nil {
+		t.Errorf("Expected a 4xx to fail without retrying, got %d calls, %v", calls, err)
+	}
+
+	calls = 0
+	err = fastPolicy.Do(context.Background(), nil, func(ctx context.Context) error {
+		calls++
+		return &StatusError{StatusCode: 500}
+	})
+	var statusErr *StatusError
+	if calls != 4 || !errors.As(err, &statusErr) {
+		t.Errorf("Expected 4 attempts ending in the last error, got %d calls, %v", calls, err)
+	}
+}
+
+func TestDo_RespectsCancellationBetweenAttempts(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	policy := Policy{MaxAttempts: 5, InitialBackoff: time.Hour, MaxBackoff: time.Hour}
+
+	calls := 0
+	done := make(chan error)
+	go func() {
+		done <- policy.Do(ctx, nil, func(ctx context.Context) error {
+			calls++
+			return &StatusError{StatusCode: 503}
+		})
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		if calls != 1 || err == nil {
+			t.Errorf("Expected one call and its error, got %d calls, %v", calls, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Do kept waiting after cancellation")
+	}
+}
+
+func TestPolicy_Backoff(t *testing.T) {
+	policy := Policy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	expected := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second}
+	for i, want := range expected {
+		if got := policy.backoff(i + 1); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", i+1, got, want)
+		}
+	}
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if wait := policy.backoff(1); wait < 50*time.Millisecond || wait > 150*time.Millisecond {
+			t.Fatalf("Expected jittered backoff within 50%%, got %v", wait)
+		}
+	}
+}
+
+func TestPolicyFromConfig(t *testing.T) {
+	policy := PolicyFromConfig(map[string]interface{}{
+		"max_attempts":         float64(5),
+		"retry_backoff_ms":     50,
+		"retry_max_backoff_ms": float64(1000),
+	})
+	if policy.MaxAttempts != 5 || policy.InitialBackoff != 50*time.Millisecond || policy.MaxBackoff != time.Second {
+		t.Errorf("Unexpected policy: %+v", policy)
+	}
+
+	policy = PolicyFromConfig(map[string]interface{}{
+		"max_attempts":  float64(5),
+		"max_retries":   float64(0),
+		"retry_backoff": "750ms",
+	})
+	if policy.MaxAttempts != 1 || policy.InitialBackoff != 750*time.Millisecond {
+		t.Errorf("Expected max_retries and retry_backoff to win, got %+v", policy)
+	}
+
+	if policy := PolicyFromConfig(nil); policy != DefaultPolicy() {
+		t.Errorf("Expected defaults, got %+v", policy)
+	}
+}
+
+func TestDo_SharesRetryBudget(t *testing.T) {
+	budget := NewBudget(4)
+	ctx := WithBudget(context.Background(), budget)
+
+	// The first call spends three retries, the second the last one, and the
+	// third gets none
+	calls := 0
+	var errs []error
+	for i := 0; i < 3; i++ {
+		errs = append(errs, fastPolicy.Do(ctx, nil, func(ctx context.Context) error {
+			calls++
+			return &StatusError{StatusCode: 503}
+		}))
+	}
+
+	if calls != 4+2+1 {
+		t.Errorf("Expected 7 calls, got %d", calls)
+	}
+	if errors.Is(errs[0], ErrBudgetExhausted) {
+		t.Errorf("Expected the first call to stop at its attempt limit, got %v", errs[0])
+	}
+	for i, err := range errs[1:] {
+		if !errors.Is(err, ErrBudgetExhausted) || !Retryable(err) {
+			t.Errorf("call %d: expected the last error wrapped with ErrBudgetExhausted, got %v", i+2, err)
+		}
+	}
+	if report := budget.Report(); report != (BudgetReport{Limit: 4, Used: 4, Remaining: 0}) {
+		t.Errorf("Unexpected report %+v", report)
+	}
+
+	// Without a budget in the context retries are not limited
+	calls = 0
+	fastPolicy.Do(context.Background(), nil, func(ctx context.Context) error {
+		calls++
+		return &StatusError{StatusCode: 503}
+	})
+	if calls != fastPolicy.MaxAttempts {
+		t.Errorf("Expected %d attempts without a budget, got %d", fastPolicy.MaxAttempts, calls)
+	}
+}
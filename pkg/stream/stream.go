@@ -0,0 +1,43 @@
+// Package stream joins streamed generations back into single responses for
+// providers that generate by streaming.
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+// Collect joins the chunks of a stream into a single response for model. It
+// returns the error of a failed final chunk, or ctx's error when the stream
+// closes without a final chunk because ctx was cancelled.
+func Collect(ctx context.Context, chunks <-chan interfaces.GenerationChunk, model string) (*interfaces.GenerationResponse, error) {
+	var text strings.Builder
+
+	for chunk := range chunks {
+		text.WriteString(chunk.Delta)
+		if !chunk.Done {
+			continue
+		}
+		if chunk.Error != "" {
+			return nil, errors.New(chunk.Error)
+		}
+		return &interfaces.GenerationResponse{
+			Text:         text.String(),
+			Tokens:       chunk.Tokens,
+			PromptTokens: chunk.PromptTokens,
+			Finished:     true,
+			FinishReason: chunk.FinishReason,
+			Model:        model,
+			Stats:        chunk.Stats,
+		}, nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("stream ended without a final chunk")
+}
@@ -0,0 +1,49 @@
+package stream
+
+import (
+	"context"
+	"testing"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+// send returns a closed channel holding chunks
+func send(chunks ...interfaces.GenerationChunk) <-chan interfaces.GenerationChunk {
+	ch := make(chan interfaces.GenerationChunk, len(chunks))
+	for _, chunk := range chunks {
+		ch <- chunk
+	}
+	close(ch)
+	return ch
+}
+
+func TestCollect(t *testing.T) {
+	resp, err := Collect(context.Background(), send(
+		interfaces.GenerationChunk{Delta: "Hello, "},
+		interfaces.GenerationChunk{Delta: "world"},
+		interfaces.GenerationChunk{Done: true, Tokens: 3, PromptTokens: 5, FinishReason: "stop"},
+	), "test-model")
+	if err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	if resp.Text != "Hello, world" || resp.Tokens != 3 || resp.PromptTokens != 5 ||
+		resp.FinishReason != "stop" || !resp.Finished || resp.Model != "test-model" {
+		t.Errorf("Unexpected response: %+v", resp)
+	}
+}
+
+func TestCollect_Errors(t *testing.T) {
+	if _, err := Collect(context.Background(), send(interfaces.GenerationChunk{Done: true, Error: "boom"}), "m"); err == nil || err.Error() != "boom" {
+		t.Errorf("Expected the final chunk's error, got %v", err)
+	}
+
+	if _, err := Collect(context.Background(), send(interfaces.GenerationChunk{Delta: "partial"}), "m"); err == nil {
+		t.Error("Expected an error for a stream without a final chunk")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Collect(ctx, send(), "m"); err != context.Canceled {
+		t.Errorf("Expected the context's error, got %v", err)
+	}
+}
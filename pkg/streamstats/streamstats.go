@@ -0,0 +1,62 @@
+// Package streamstats measures the speed of streamed generations from the
+// arrival times of their chunks.
+package streamstats
+
+import (
+	"time"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+// Meter times one stream. It is not safe for concurrent use; the goroutine
+// reading the stream owns it.
+type Meter struct {
+	start time.Time
+	first time.Time
+	last  time.Time
+	now   func() time.Time
+}
+
+// NewMeter starts timing a stream whose request is being sent now
+func NewMeter() *Meter {
+	return newMeterWithClock(time.Now)
+}
+
+func newMeterWithClock(now func() time.Time) *Meter {
+	return &Meter{start: now(), now: now}
+}
+
+// Chunk records the arrival of a chunk of text
+func (m *Meter) Chunk() {
+	at := m.now()
+	if m.first.IsZero() {
+		m.first = at
+	}
+	m.last = at
+}
+
+// Stats reports the timing of the stream, which produced tokens tokens and
+// has just ended. The rate counts the tokens after the first over the time
+// between the first and last chunks, so it excludes prompt processing; a
+// stream with a single chunk falls back to the rate over its whole duration.
+func (m *Meter) Stats(tokens int) *interfaces.GenerationStats {
+	end := m.now()
+	stats := &interfaces.GenerationStats{
+		DurationMs: milliseconds(end.Sub(m.start)),
+	}
+	if m.first.IsZero() {
+		return stats
+	}
+	stats.TimeToFirstTokenMs = milliseconds(m.first.Sub(m.start))
+
+	if span := m.last.Sub(m.first); tokens > 1 && span > 0 {
+		stats.TokensPerSecond = float64(tokens-1) / span.Seconds()
+	} else if total := end.Sub(m.start); tokens > 0 && total > 0 {
+		stats.TokensPerSecond = float64(tokens) / total.Seconds()
+	}
+	return stats
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
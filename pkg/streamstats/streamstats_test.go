@@ -0,0 +1,64 @@
+package streamstats
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock returns the given times in order
+func fakeClock(start time.Time, offsets ...time.Duration) func() time.Time {
+	i := 0
+	return func() time.Time {
+		if i == 0 {
+			i++
+			return start
+		}
+		at := start.Add(offsets[i-1])
+		i++
+		return at
+	}
+}
+
+func TestMeter_Stats(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	// First chunk after 500ms, then four more 100ms apart, ending at 1s
+	m := newMeterWithClock(fakeClock(start,
+		500*time.Millisecond, 600*time.Millisecond, 700*time.Millisecond,
+		800*time.Millisecond, 900*time.Millisecond, time.Second))
+	for i := 0; i < 5; i++ {
+		m.Chunk()
+	}
+
+	stats := m.Stats(41)
+	if stats.TimeToFirstTokenMs != 500 {
+		t.Errorf("Expected 500ms to the first token, got %v", stats.TimeToFirstTokenMs)
+	}
+	if stats.DurationMs != 1000 {
+		t.Errorf("Expected a 1000ms duration, got %v", stats.DurationMs)
+	}
+	// 40 tokens after the first over 400ms
+	if stats.TokensPerSecond != 100 {
+		t.Errorf("Expected 100 tokens/s, got %v", stats.TokensPerSecond)
+	}
+}
+
+func TestMeter_SingleChunkUsesWholeDuration(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	m := newMeterWithClock(fakeClock(start, 200*time.Millisecond, 250*time.Millisecond))
+	m.Chunk()
+
+	stats := m.Stats(10)
+	if stats.TokensPerSecond != 40 {
+		t.Errorf("Expected 40 tokens/s, got %v", stats.TokensPerSecond)
+	}
+}
+
+func TestMeter_NoChunks(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	m := newMeterWithClock(fakeClock(start, time.Second))
+
+	stats := m.Stats(0)
+	if stats.TimeToFirstTokenMs != 0 || stats.TokensPerSecond != 0 || stats.DurationMs != 1000 {
+		t.Errorf("Expected only the duration, got %+v", stats)
+	}
+}
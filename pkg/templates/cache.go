@@ -6,55 +6,127 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 )
 
-// TemplateCache manages template caching
+// Renderer turns a conversation into a model prompt. Messages are maps with
+// role and content keys and, for assistant tool calls, a function_call map
+// with name and arguments.
+type Renderer interface {
+	Render(messages []map[string]interface{}) (string, error)
+}
+
+// cachedTemplate is a parsed template file and the file state it was parsed
+// from
+type cachedTemplate struct {
+	path     string
+	modTime  time.Time
+	size     int64
+	renderer Renderer
+}
+
+// TemplateCache parses template files once and keeps them until the file
+// changes. Names without a file fall back to templates registered on the
+// cache and then to the built-in variants.
 type TemplateCache struct {
-	cache map[string]*Jinja2Template
-	mutex sync.RWMutex
+	cache      map[string]*cachedTemplate
+	registered map[string]Renderer
+	mutex      sync.RWMutex
 }
 
 // NewTemplateCache creates a new template cache
 func NewTemplateCache() *TemplateCache {
 	return &TemplateCache{
-		cache: make(map[string]*Jinja2Template),
+		cache:      make(map[string]*cachedTemplate),
+		registered: make(map[string]Renderer),
 	}
 }
 
-// GetTemplate retrieves or loads a template
-func (tc *TemplateCache) GetTemplate(templatePath string) (*Jinja2Template, error) {
-	tc.mutex.RLock()
-	if tmpl, exists := tc.cache[templatePath]; exists {
-		tc.mutex.RUnlock()
-		return tmpl, nil
+// RegisterTemplate adds template source, such as a file embedded with
+// go:embed, used for name when no template file is found
+func (tc *TemplateCache) RegisterTemplate(name, content string) error {
+	renderer, err := parseTemplate(content)
+	if err != nil {
+		return fmt.Errorf("failed to register template %s: %w", name, err)
+	}
+
+	tc.mutex.Lock()
+	defer tc.mutex.Unlock()
+	tc.registered[templateName(name)] = renderer
+	return nil
+}
+
+// GetTemplate returns the template for name: the template file found by
+// FindTemplate, reparsed whenever its modification time or size changes, else
+// a registered template, else the built-in variant of that name
+func (tc *TemplateCache) GetTemplate(name string) (Renderer, error) {
+	path, info, err := findTemplateFile(name)
+	if err != nil {
+		key := templateName(name)
+		tc.mutex.Lock()
+		delete(tc.cache, name)
+		renderer, ok := tc.registered[key]
+		tc.mutex.Unlock()
+		if ok {
+			return renderer, nil
+		}
+		if renderer, ok := Variant(key); ok {
+			return renderer, nil
+		}
+		return nil, err
 	}
+
+	tc.mutex.RLock()
+	cached, exists := tc.cache[name]
 	tc.mutex.RUnlock()
+	if exists && cached.path == path && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		return cached.renderer, nil
+	}
 
 	// Load template from file
-	content, err := os.ReadFile(templatePath)
+	content, err := os.ReadFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read template file %s: %w", templatePath, err)
+		return nil, fmt.Errorf("failed to read template file %s: %w", path, err)
 	}
-
-	// Create new template
-	tmpl, err := NewJinja2Template(string(content))
+	renderer, err := parseTemplate(string(content))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create template from %s: %w", templatePath, err)
+		return nil, fmt.Errorf("failed to create template from %s: %w", path, err)
 	}
 
 	// Cache the template
 	tc.mutex.Lock()
-	tc.cache[templatePath] = tmpl
+	tc.cache[name] = &cachedTemplate{
+		path:     path,
+		modTime:  info.ModTime(),
+		size:     info.Size(),
+		renderer: renderer,
+	}
 	tc.mutex.Unlock()
 
-	return tmpl, nil
+	return renderer, nil
 }
 
-// ClearCache clears the template cache
+// ClearCache clears the template cache. Registered templates are kept.
 func (tc *TemplateCache) ClearCache() {
 	tc.mutex.Lock()
 	defer tc.mutex.Unlock()
-	tc.cache = make(map[string]*Jinja2Template)
+	tc.cache = make(map[string]*cachedTemplate)
+}
+
+// parseTemplate builds a renderer from template file source. Template files
+// follow the qwen3.j2 layout: a system block with a {{ system_prompt }}
+// placeholder and a message loop rendered as ChatML.
+func parseTemplate(content string) (Renderer, error) {
+	if strings.TrimSpace(content) == "" {
+		return nil, fmt.Errorf("template is empty")
+	}
+	return NewQwen3Template(content), nil
+}
+
+// templateName is the name registered and built-in templates are looked up
+// by: the file name without directory or .j2 extension
+func templateName(name string) string {
+	return strings.TrimSuffix(filepath.Base(name), ".j2")
 }
 
 // GetTemplatePaths returns common template paths
@@ -69,17 +141,30 @@ func GetTemplatePaths() []string {
 
 // FindTemplate finds a template file in common locations
 func FindTemplate(templateName string) (string, error) {
+	path, _, err := findTemplateFile(templateName)
+	return path, err
+}
+
+// findTemplateFile finds a template file and returns its path and file info
+func findTemplateFile(templateName string) (string, os.FileInfo, error) {
 	// Try with .j2 extension first
 	if !strings.HasSuffix(templateName, ".j2") {
 		templateName += ".j2"
 	}
 
+	// A path to a template file is used as is
+	if filepath.IsAbs(templateName) || strings.ContainsRune(templateName, filepath.Separator) {
+		if info, err := os.Stat(templateName); err == nil && !info.IsDir() {
+			return templateName, info, nil
+		}
+	}
+
 	for _, basePath := range GetTemplatePaths() {
 		fullPath := filepath.Join(basePath, templateName)
-		if _, err := os.Stat(fullPath); err == nil {
-			return fullPath, nil
+		if info, err := os.Stat(fullPath); err == nil && !info.IsDir() {
+			return fullPath, info, nil
 		}
 	}
 
-	return "", fmt.Errorf("template %s not found in any of the standard paths", templateName)
+	return "", nil, fmt.Errorf("template %s not found in any of the standard paths", templateName)
 }
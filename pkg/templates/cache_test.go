@@ -0,0 +1,107 @@
+package templates
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testTemplate follows the qwen3.j2 layout with a marker to tell versions apart
+func testTemplate(marker string) string {
+	return "<|im_start|>system\n" + marker + " {{ system_prompt }}\n<|im_end|>\n{% for msg in messages %}{% endfor %}<|im_start|>assistant\n"
+}
+
+func render(t *testing.T, renderer Renderer, messages []map[string]interface{}) string {
+	t.Helper()
+	prompt, err := renderer.Render(messages)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	return prompt
+}
+
+func TestTemplateCache_ReloadsChangedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "custom.j2")
+	if err := os.WriteFile(path, []byte(testTemplate("v1")), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cache := NewTemplateCache()
+	first, err := cache.GetTemplate(path)
+	if err != nil {
+		t.Fatalf("GetTemplate failed: %v", err)
+	}
+	second, _ := cache.GetTemplate(path)
+	if first != second {
+		t.Error("Expected an unchanged file to be served from the cache")
+	}
+
+	// Same size, later modification time
+	if err := os.WriteFile(path, []byte(testTemplate("v2")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := cache.GetTemplate(path)
+	if err != nil {
+		t.Fatalf("GetTemplate failed: %v", err)
+	}
+	if prompt := render(t, reloaded, nil); !strings.Contains(prompt, "v2") {
+		t.Errorf("Expected the edited template to be picked up, got %q", prompt)
+	}
+
+	cache.ClearCache()
+	if cleared, _ := cache.GetTemplate(path); cleared == reloaded {
+		t.Error("Expected ClearCache to drop parsed templates")
+	}
+}
+
+func TestTemplateCache_Fallbacks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shipped.j2")
+	if err := os.WriteFile(path, []byte(testTemplate("from-file")), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cache := NewTemplateCache()
+	if err := cache.RegisterTemplate("shipped", testTemplate("embedded")); err != nil {
+		t.Fatalf("RegisterTemplate failed: %v", err)
+	}
+
+	// A template file wins over the registered source
+	tmpl, err := cache.GetTemplate(path)
+	if err != nil {
+		t.Fatalf("GetTemplate failed: %v", err)
+	}
+	if prompt := render(t, tmpl, nil); !strings.Contains(prompt, "from-file") {
+		t.Errorf("Expected the template file, got %q", prompt)
+	}
+
+	// Without the file the registered template is used
+	os.Remove(path)
+	tmpl, err = cache.GetTemplate(path)
+	if err != nil {
+		t.Fatalf("GetTemplate failed: %v", err)
+	}
+	if prompt := render(t, tmpl, nil); !strings.Contains(prompt, "embedded") {
+		t.Errorf("Expected the registered template, got %q", prompt)
+	}
+
+	// Built-in variants are available by name
+	for _, name := range Variants() {
+		if _, err := cache.GetTemplate(name); err != nil {
+			t.Errorf("Expected built-in variant %s, got %v", name, err)
+		}
+	}
+
+	if _, err := cache.GetTemplate("no-such-template"); err == nil {
+		t.Error("Expected an unknown template to fail")
+	}
+	if err := cache.RegisterTemplate("empty", " \n"); err == nil {
+		t.Error("Expected an empty template to be rejected")
+	}
+}
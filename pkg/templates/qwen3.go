@@ -81,7 +81,7 @@ func (qt *Qwen3Template) Render(messages []map[string]interface{}) (string, erro
 
 	if start != -1 && end != -1 {
 		before := content[:start]
-		after := content[end+13:] // Length of "{% endfor %}"
+		after := content[end+len("{% endfor %}"):]
 		content = before + messageHistory.String() + after
 	}
 
@@ -140,3 +140,9 @@ func (qt *Qwen3Template) Render(messages []map[string]interface{}) (string, erro
 
 	return content, nil
 }
+
+// StopTokens returns the token that closes a turn in the qwen3 template's
+// ChatML message loop
+func (qt *Qwen3Template) StopTokens() []string {
+	return []string{"<|im_end|>"}
+}
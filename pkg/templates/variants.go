@@ -0,0 +1,143 @@
+package templates
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// variants are the built-in chat formats, selectable by name when no
+// template file or registered template exists
+var variants = map[string]Renderer{
+	"chatml":           ChatMLTemplate{},
+	"llama3":           Llama3Template{},
+	"mistral-instruct": MistralInstructTemplate{},
+}
+
+// Variant returns the built-in chat format called name
+func Variant(name string) (Renderer, bool) {
+	renderer, ok := variants[name]
+	return renderer, ok
+}
+
+// Variants lists the names of the built-in chat formats
+func Variants() []string {
+	names := make([]string, 0, len(variants))
+	for name := range variants {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// StopTokens returns the tokens that end an assistant turn in renderer's
+// format, or nil when the renderer does not declare any
+func StopTokens(renderer Renderer) []string {
+	if s, ok := renderer.(interface{ StopTokens() []string }); ok {
+		return s.StopTokens()
+	}
+	return nil
+}
+
+// messageText is the text of a message: its content, or for an assistant
+// tool call the <function_call> block the qwen3 template uses
+func messageText(msg map[string]interface{}) string {
+	if fc, ok := msg["function_call"].(map[string]interface{}); ok {
+		name, _ := fc["name"].(string)
+		args, _ := fc["arguments"].(string)
+		return fmt.Sprintf("<function_call name=\"%s\">\n%s\n</function_call>", name, args)
+	}
+	content, _ := msg["content"].(string)
+	return content
+}
+
+func messageRole(msg map[string]interface{}) string {
+	role, _ := msg["role"].(string)
+	return role
+}
+
+// ChatMLTemplate renders messages in the ChatML format used by Qwen and
+// many other instruction-tuned models
+type ChatMLTemplate struct{}
+
+// Render writes each message as an <|im_start|> block and opens the
+// assistant's turn
+func (ChatMLTemplate) Render(messages []map[string]interface{}) (string, error) {
+	var prompt strings.Builder
+	for _, msg := range messages {
+		fmt.Fprintf(&prompt, "<|im_start|>%s\n%s<|im_end|>\n", messageRole(msg), messageText(msg))
+	}
+	prompt.WriteString("<|im_start|>assistant\n")
+	return prompt.String(), nil
+}
+
+// StopTokens returns the token that closes a ChatML turn
+func (ChatMLTemplate) StopTokens() []string {
+	return []string{"<|im_end|>"}
+}
+
+// Llama3Template renders messages in the Llama 3 instruct format
+type Llama3Template struct{}
+
+// Render writes each message under a role header and opens the assistant's
+// turn. Tool results use the ipython role Llama 3 expects.
+func (Llama3Template) Render(messages []map[string]interface{}) (string, error) {
+	var prompt strings.Builder
+	prompt.WriteString("<|begin_of_text|>")
+	for _, msg := range messages {
+		role := messageRole(msg)
+		if role == "tool" || role == "function" {
+			role = "ipython"
+		}
+		fmt.Fprintf(&prompt, "<|start_header_id|>%s<|end_header_id|>\n\n%s<|eot_id|>", role, messageText(msg))
+	}
+	prompt.WriteString("<|start_header_id|>assistant<|end_header_id|>\n\n")
+	return prompt.String(), nil
+}
+
+// StopTokens returns the token that closes a Llama 3 turn
+func (Llama3Template) StopTokens() []string {
+	return []string{"<|eot_id|>"}
+}
+
+// MistralInstructTemplate renders messages in the Mistral instruct format.
+// Mistral has no system role, so system messages are prepended to the next
+// user message.
+type MistralInstructTemplate struct{}
+
+// Render wraps user turns in [INST] and closes each assistant turn with </s>
+func (MistralInstructTemplate) Render(messages []map[string]interface{}) (string, error) {
+	var prompt strings.Builder
+	var system []string
+	prompt.WriteString("<s>")
+	for _, msg := range messages {
+		text := messageText(msg)
+		switch messageRole(msg) {
+		case "system":
+			system = append(system, text)
+		case "user":
+			if len(system) > 0 {
+				text = strings.Join(append(system, text), "\n\n")
+				system = nil
+			}
+			fmt.Fprintf(&prompt, "[INST] %s [/INST]", text)
+		case "assistant":
+			fmt.Fprintf(&prompt, " %s</s>", text)
+		case "tool", "function":
+			fmt.Fprintf(&prompt, "[TOOL_RESULTS] %s [/TOOL_RESULTS]", text)
+		default:
+			return "", fmt.Errorf("unsupported role %q", messageRole(msg))
+		}
+	}
+	if len(system) > 0 {
+		// A trailing system message still has to reach the model
+		fmt.Fprintf(&prompt, "[INST] %s [/INST]", strings.Join(system, "\n\n"))
+	}
+	return prompt.String(), nil
+}
+
+// StopTokens returns the end-of-sequence token that closes a Mistral
+// assistant turn
+func (MistralInstructTemplate) StopTokens() []string {
+	return []string{"</s>"}
+}
@@ -0,0 +1,109 @@
+package templates
+
+import (
+	"strings"
+	"testing"
+)
+
+// conversation exercises every role, including an assistant tool call and
+// its result
+var conversation = []map[string]interface{}{
+	{"role": "system", "content": "Be brief."},
+	{"role": "user", "content": "List /tmp"},
+	{"role": "assistant", "content": "", "function_call": map[string]interface{}{
+		"name":      "ls",
+		"arguments": `{"path":"/tmp"}`,
+	}},
+	{"role": "tool", "content": "a.txt"},
+	{"role": "assistant", "content": "One file: a.txt"},
+	{"role": "user", "content": "Thanks"},
+}
+
+const functionCallBlock = "<function_call name=\"ls\">\n{\"path\":\"/tmp\"}\n</function_call>"
+
+func TestVariants_Render(t *testing.T) {
+	tests := map[string]string{
+		"chatml": "<|im_start|>system\nBe brief.<|im_end|>\n" +
+			"<|im_start|>user\nList /tmp<|im_end|>\n" +
+			"<|im_start|>assistant\n" + functionCallBlock + "<|im_end|>\n" +
+			"<|im_start|>tool\na.txt<|im_end|>\n" +
+			"<|im_start|>assistant\nOne file: a.txt<|im_end|>\n" +
+			"<|im_start|>user\nThanks<|im_end|>\n" +
+			"<|im_start|>assistant\n",
+		"llama3": "<|begin_of_text|>" +
+			"<|start_header_id|>system<|end_header_id|>\n\nBe brief.<|eot_id|>" +
+			"<|start_header_id|>user<|end_header_id|>\n\nList /tmp<|eot_id|>" +
+			"<|start_header_id|>assistant<|end_header_id|>\n\n" + functionCallBlock + "<|eot_id|>" +
+			"<|start_header_id|>ipython<|end_header_id|>\n\na.txt<|eot_id|>" +
+			"<|start_header_id|>assistant<|end_header_id|>\n\nOne file: a.txt<|eot_id|>" +
+			"<|start_header_id|>user<|end_header_id|>\n\nThanks<|eot_id|>" +
+			"<|start_header_id|>assistant<|end_header_id|>\n\n",
+		"mistral-instruct": "<s>[INST] Be brief.\n\nList /tmp [/INST] " + functionCallBlock + "</s>" +
+			"[TOOL_RESULTS] a.txt [/TOOL_RESULTS] One file: a.txt</s>" +
+			"[INST] Thanks [/INST]",
+	}
+
+	for name, expected := range tests {
+		renderer, ok := Variant(name)
+		if !ok {
+			t.Fatalf("Variant %s not found", name)
+		}
+		if prompt := render(t, renderer, conversation); prompt != expected {
+			t.Errorf("%s rendered:\n%q\nexpected:\n%q", name, prompt, expected)
+		}
+	}
+}
+
+func TestMistralInstruct_RejectsUnknownRoles(t *testing.T) {
+	_, err := MistralInstructTemplate{}.Render([]map[string]interface{}{{"role": "narrator", "content": "..."}})
+	if err == nil || !strings.Contains(err.Error(), "unsupported role") {
+		t.Errorf("Expected an unknown role to be rejected, got %v", err)
+	}
+}
+
+func TestQwen3Template_Render(t *testing.T) {
+	tmpl := NewQwen3Template(testTemplate("qwen3"))
+	prompt := render(t, tmpl, conversation)
+
+	turns := []string{
+		"qwen3 Be brief.",
+		"<|im_start|>user\nList /tmp\n<|im_end|>",
+		"<|im_start|>assistant\n" + functionCallBlock + "\n<|im_end|>",
+		"<|im_start|>assistant\nOne file: a.txt\n<|im_end|>",
+		"<|im_start|>user\nThanks\n<|im_end|>",
+	}
+	last := -1
+	for _, turn := range turns {
+		index := strings.Index(prompt, turn)
+		if index <= last {
+			t.Fatalf("Expected %q after the previous turn, got %q", turn, prompt)
+		}
+		last = index
+	}
+}
+
+// plainRenderer is a renderer that declares no stop tokens
+type plainRenderer struct{}
+
+func (plainRenderer) Render(messages []map[string]interface{}) (string, error) { return "", nil }
+
+func TestStopTokens(t *testing.T) {
+	tests := map[string]string{
+		"chatml":           "<|im_end|>",
+		"llama3":           "<|eot_id|>",
+		"mistral-instruct": "</s>",
+	}
+	for name, want := range tests {
+		renderer, _ := Variant(name)
+		if stop := StopTokens(renderer); len(stop) != 1 || stop[0] != want {
+			t.Errorf("%s: expected stop token %q, got %v", name, want, stop)
+		}
+	}
+
+	if stop := StopTokens(NewQwen3Template(testTemplate("qwen3"))); len(stop) != 1 || stop[0] != "<|im_end|>" {
+		t.Errorf("Expected the qwen3 template to stop at <|im_end|>, got %v", stop)
+	}
+	if stop := StopTokens(plainRenderer{}); stop != nil {
+		t.Errorf("Expected no stop tokens for a renderer that declares none, got %v", stop)
+	}
+}
@@ -0,0 +1,195 @@
+package tokenizer
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// pieceCacheSize bounds the per-tokenizer cache of merged pre-token pieces
+const pieceCacheSize = 65536
+
+// preTokenizer splits text into pieces before byte pair merging. It follows the
+// cl100k_base split pattern without the lookahead Go's regexp does not support.
+var preTokenizer = regexp.MustCompile(`(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+`)
+
+// BPE is a byte pair encoding tokenizer backed by a tiktoken-style vocab
+type BPE struct {
+	name  string
+	ranks map[string]int
+
+	cacheMu sync.RWMutex
+	cache   map[string][]int
+}
+
+// LoadBPEFile loads a tiktoken-style vocab file of "<base64 token> <rank>" lines
+func LoadBPEFile(name, path string) (*BPE, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open vocab file %s: %w", path, err)
+	}
+	defer file.Close()
+
+	bpe, err := LoadBPE(name, file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load vocab file %s: %w", path, err)
+	}
+	return bpe, nil
+}
+
+// LoadBPE reads a tiktoken-style vocab from r
+func LoadBPE(name string, r io.Reader) (*BPE, error) {
+	ranks := make(map[string]int)
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected \"<token> <rank>\"", lineNum)
+		}
+
+		token, err := base64.StdEncoding.DecodeString(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid base64 token: %w", lineNum, err)
+		}
+
+		rank, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid rank: %w", lineNum, err)
+		}
+
+		ranks[string(token)] = rank
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(ranks) == 0 {
+		return nil, fmt.Errorf("vocab is empty")
+	}
+
+	return &BPE{
+		name:  name,
+		ranks: ranks,
+		cache: make(map[string][]int),
+	}, nil
+}
+
+// Name returns the encoding name
+func (b *BPE) Name() string {
+	return b.name
+}
+
+// CountTokens returns the exact number of BPE tokens in text
+func (b *BPE) CountTokens(text string) int {
+	count := 0
+	for _, piece := range preTokenizer.FindAllString(text, -1) {
+		count += len(b.tokenBoundaries(piece))
+	}
+	return count
+}
+
+// Truncate returns the longest prefix of text that encodes to at most
+// maxTokens tokens, never splitting a UTF-8 sequence
+func (b *BPE) Truncate(text string, maxTokens int) string {
+	if maxTokens <= 0 {
+		return ""
+	}
+
+	// Scan piece by piece so only the prefix that is kept gets tokenized
+	count := 0
+	for pos := 0; pos < len(text); {
+		loc := preTokenizer.FindStringIndex(text[pos:])
+		if loc == nil {
+			break
+		}
+		start, stop := pos+loc[0], pos+loc[1]
+		pos = stop
+
+		boundaries := b.tokenBoundaries(text[start:stop])
+		if count+len(boundaries) <= maxTokens {
+			count += len(boundaries)
+			continue
+		}
+
+		// The budget ends inside this piece; keep the tokens that still fit
+		end := start
+		if remaining := maxTokens - count; remaining > 0 {
+			end += boundaries[remaining-1]
+		}
+		for end > 0 && !utf8.ValidString(text[:end]) {
+			end--
+		}
+		return text[:end]
+	}
+
+	return text
+}
+
+// tokenBoundaries returns the end offset of every token in piece
+func (b *BPE) tokenBoundaries(piece string) []int {
+	b.cacheMu.RLock()
+	cached, ok := b.cache[piece]
+	b.cacheMu.RUnlock()
+	if ok {
+		return cached
+	}
+
+	boundaries := b.merge(piece)
+
+	b.cacheMu.Lock()
+	if len(b.cache) >= pieceCacheSize {
+		b.cache = make(map[string][]int)
+	}
+	b.cache[piece] = boundaries
+	b.cacheMu.Unlock()
+
+	return boundaries
+}
+
+// merge applies byte pair merges to piece, always merging the adjacent pair
+// with the lowest rank first, and returns the resulting token end offsets
+func (b *BPE) merge(piece string) []int {
+	if _, ok := b.ranks[piece]; ok || len(piece) <= 1 {
+		return []int{len(piece)}
+	}
+
+	// starts holds the start offset of every current part plus the end of the piece
+	starts := make([]int, len(piece)+1)
+	for i := range starts {
+		starts[i] = i
+	}
+
+	for len(starts) > 2 {
+		best := -1
+		bestRank := math.MaxInt
+		for i := 0; i+2 < len(starts); i++ {
+			if rank, ok := b.ranks[piece[starts[i]:starts[i+2]]]; ok && rank < bestRank {
+				best = i
+				bestRank = rank
+			}
+		}
+		if best < 0 {
+			break
+		}
+		starts = append(starts[:best+1], starts[best+2:]...)
+	}
+
+	return starts[1:]
+}
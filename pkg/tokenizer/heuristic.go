@@ -0,0 +1,48 @@
+package tokenizer
+
+import "unicode/utf8"
+
+// Heuristic estimates tokens as a fixed number of characters per token. It is
+// cheap and needs no vocab, but over-counts CJK text and under-counts code.
+type Heuristic struct {
+	CharsPerToken int
+}
+
+// NewHeuristic creates the default 4 characters per token estimator
+func NewHeuristic() *Heuristic {
+	return &Heuristic{CharsPerToken: 4}
+}
+
+// Name returns the tokenizer name
+func (h *Heuristic) Name() string {
+	return HeuristicName
+}
+
+// CountTokens estimates the number of tokens in text
+func (h *Heuristic) CountTokens(text string) int {
+	return utf8.RuneCountInString(text) / h.charsPerToken()
+}
+
+// Truncate cuts text to at most maxTokens estimated tokens on a rune boundary
+func (h *Heuristic) Truncate(text string, maxTokens int) string {
+	if maxTokens <= 0 {
+		return ""
+	}
+
+	targetChars := maxTokens * h.charsPerToken()
+	count := 0
+	for i := range text {
+		if count == targetChars {
+			return text[:i]
+		}
+		count++
+	}
+	return text
+}
+
+func (h *Heuristic) charsPerToken() int {
+	if h.CharsPerToken <= 0 {
+		return 4
+	}
+	return h.CharsPerToken
+}
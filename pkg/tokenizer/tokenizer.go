@@ -0,0 +1,98 @@
+package tokenizer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Tokenizer counts and truncates text in model tokens
+type Tokenizer interface {
+	Name() string
+	CountTokens(text string) int
+	Truncate(text string, maxTokens int) string
+}
+
+// HeuristicName is the name of the built-in characters/4 tokenizer
+const HeuristicName = "heuristic"
+
+var (
+	loaded   = make(map[string]Tokenizer)
+	loadedMu sync.Mutex
+)
+
+// GetVocabPaths returns common locations for tiktoken-style vocab files
+func GetVocabPaths() []string {
+	paths := []string{
+		"./providers/models/tokenizers/",
+		"./tokenizers/",
+		"/etc/agentforge/tokenizers/",
+		"../../providers/models/tokenizers/",
+	}
+
+	if home, err := os.UserHomeDir(); err == nil {
+		paths = append(paths, filepath.Join(home, ".afe", "tokenizers"))
+	}
+
+	return paths
+}
+
+// FindVocab finds a vocab file for the named encoding in common locations
+func FindVocab(name string) (string, error) {
+	fileName := name
+	if !strings.HasSuffix(fileName, ".tiktoken") {
+		fileName += ".tiktoken"
+	}
+
+	for _, basePath := range GetVocabPaths() {
+		fullPath := filepath.Join(basePath, fileName)
+		if _, err := os.Stat(fullPath); err == nil {
+			return fullPath, nil
+		}
+	}
+
+	return "", fmt.Errorf("vocab %s not found in any of the standard paths", fileName)
+}
+
+// Get returns the tokenizer for name. An empty name or "heuristic" returns the
+// characters/4 estimator; any other name is loaded as a BPE vocab, either from
+// vocabPath when given or from the standard vocab locations. Loaded vocabs are
+// shared between callers.
+func Get(name, vocabPath string) (Tokenizer, error) {
+	if name == "" || name == HeuristicName {
+		return NewHeuristic(), nil
+	}
+
+	if vocabPath == "" {
+		found, err := FindVocab(name)
+		if err != nil {
+			return nil, err
+		}
+		vocabPath = found
+	}
+
+	loadedMu.Lock()
+	defer loadedMu.Unlock()
+
+	if tok, ok := loaded[vocabPath]; ok {
+		return tok, nil
+	}
+
+	tok, err := LoadBPEFile(name, vocabPath)
+	if err != nil {
+		return nil, err
+	}
+
+	loaded[vocabPath] = tok
+	return tok, nil
+}
+
+// FromConfig builds a tokenizer from the "tokenizer" and "tokenizer_path" keys
+// of an agent or provider config, falling back to the heuristic when unset
+func FromConfig(config map[string]interface{}) (Tokenizer, error) {
+	name, _ := config["tokenizer"].(string)
+	vocabPath, _ := config["tokenizer_path"].(string)
+	return Get(name, vocabPath)
+}
@@ -0,0 +1,197 @@
+package tokenizer
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// writeTestVocab writes a small tiktoken-style vocab: every single byte plus a
+// handful of common English merges
+func writeTestVocab(t testing.TB) string {
+	t.Helper()
+
+	var sb strings.Builder
+	rank := 0
+	for i := 0; i < 256; i++ {
+		fmt.Fprintf(&sb, "%s %d\n", base64.StdEncoding.EncodeToString([]byte{byte(i)}), rank)
+		rank++
+	}
+	for _, merge := range []string{"th", "he", "the", " the", "in", "ing", " t", "er", "func", " func"} {
+		fmt.Fprintf(&sb, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(merge)), rank)
+		rank++
+	}
+
+	path := filepath.Join(t.TempDir(), "test.tiktoken")
+	if err := os.WriteFile(path, []byte(sb.String()), 0644); err != nil {
+		t.Fatalf("Failed to write vocab: %v", err)
+	}
+	return path
+}
+
+func TestHeuristic(t *testing.T) {
+	tok := NewHeuristic()
+
+	if got := tok.CountTokens("abcdefgh"); got != 2 {
+		t.Errorf("Expected 2 tokens, got %d", got)
+	}
+
+	truncated := tok.Truncate("日本語のテキストです", 1)
+	if truncated != "日本語の" {
+		t.Errorf("Expected truncation on a rune boundary, got %q", truncated)
+	}
+
+	if got := tok.Truncate("short", 10); got != "short" {
+		t.Errorf("Expected short text to be unchanged, got %q", got)
+	}
+}
+
+func TestBPE_CountTokens(t *testing.T) {
+	tok, err := LoadBPEFile("test", writeTestVocab(t))
+	if err != nil {
+		t.Fatalf("Failed to load vocab: %v", err)
+	}
+
+	testCases := []struct {
+		text     string
+		expected int
+	}{
+		{"the", 1},
+		{"the the", 2},
+		{"thing", 2}, // th + ing
+		{"", 0},
+	}
+
+	for _, tc := range testCases {
+		if got := tok.CountTokens(tc.text); got != tc.expected {
+			t.Errorf("CountTokens(%q) = %d, expected %d", tc.text, got, tc.expected)
+		}
+	}
+
+	// Unmerged multi-byte runes cost one token per byte
+	if got := tok.CountTokens("日"); got != 3 {
+		t.Errorf("Expected 3 byte tokens for a CJK rune without merges, got %d", got)
+	}
+}
+
+func TestBPE_Truncate(t *testing.T) {
+	tok, err := LoadBPEFile("test", writeTestVocab(t))
+	if err != nil {
+		t.Fatalf("Failed to load vocab: %v", err)
+	}
+
+	text := "the the the 日本 the end"
+	for max := 0; max <= tok.CountTokens(text); max++ {
+		truncated := tok.Truncate(text, max)
+		if !strings.HasPrefix(text, truncated) {
+			t.Fatalf("Truncate(%d) = %q is not a prefix", max, truncated)
+		}
+		if !utf8.ValidString(truncated) {
+			t.Fatalf("Truncate(%d) = %q split a UTF-8 sequence", max, truncated)
+		}
+		if got := tok.CountTokens(truncated); got > max {
+			t.Fatalf("Truncate(%d) produced %d tokens", max, got)
+		}
+	}
+
+	if got := tok.Truncate(text, 1000); got != text {
+		t.Errorf("Expected text within budget to be unchanged, got %q", got)
+	}
+}
+
+func TestGet(t *testing.T) {
+	tok, err := Get("", "")
+	if err != nil || tok.Name() != HeuristicName {
+		t.Fatalf("Expected heuristic tokenizer, got %v (err: %v)", tok, err)
+	}
+
+	vocab := writeTestVocab(t)
+	tok, err = FromConfig(map[string]interface{}{
+		"tokenizer":      "cl100k_base",
+		"tokenizer_path": vocab,
+	})
+	if err != nil {
+		t.Fatalf("Failed to load tokenizer from config: %v", err)
+	}
+	if tok.Name() != "cl100k_base" {
+		t.Errorf("Expected cl100k_base, got %s", tok.Name())
+	}
+
+	again, _ := Get("cl100k_base", vocab)
+	if again != tok {
+		t.Error("Expected loaded vocab to be shared between callers")
+	}
+
+	if _, err := Get("does_not_exist", ""); err == nil {
+		t.Error("Expected error for missing vocab")
+	}
+}
+
+func TestLoadBPE_Invalid(t *testing.T) {
+	if _, err := LoadBPE("bad", strings.NewReader("not-base64! 1\n")); err == nil {
+		t.Error("Expected error for invalid base64")
+	}
+	if _, err := LoadBPE("bad", strings.NewReader("dGg= x\n")); err == nil {
+		t.Error("Expected error for invalid rank")
+	}
+	if _, err := LoadBPE("empty", strings.NewReader("")); err == nil {
+		t.Error("Expected error for empty vocab")
+	}
+}
+
+// mixedText builds roughly size bytes of English prose, Go code, and CJK text
+func mixedText(size int) string {
+	samples := []string{
+		"The quick brown fox jumps over the lazy dog while the engine is thinking. ",
+		"func (m *Manager) ResolveModel(name string) (string, error) { return name, nil }\n",
+		"日本語のテキストはトークン数の推定が難しいです。中文文本也是如此。",
+	}
+
+	var sb strings.Builder
+	for i := 0; sb.Len() < size; i++ {
+		sb.WriteString(samples[i%len(samples)])
+	}
+	return sb.String()
+}
+
+func BenchmarkHeuristic_CountTokens(b *testing.B) {
+	text := mixedText(4 << 20)
+	tok := NewHeuristic()
+	b.SetBytes(int64(len(text)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		tok.CountTokens(text)
+	}
+}
+
+func BenchmarkBPE_CountTokens(b *testing.B) {
+	text := mixedText(4 << 20)
+	tok, err := LoadBPEFile("test", writeTestVocab(b))
+	if err != nil {
+		b.Fatalf("Failed to load vocab: %v", err)
+	}
+	b.SetBytes(int64(len(text)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		tok.CountTokens(text)
+	}
+}
+
+func BenchmarkBPE_Truncate(b *testing.B) {
+	text := mixedText(4 << 20)
+	tok, err := LoadBPEFile("test", writeTestVocab(b))
+	if err != nil {
+		b.Fatalf("Failed to load vocab: %v", err)
+	}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		tok.Truncate(text, 8000)
+	}
+}
@@ -0,0 +1,469 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/retry"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// readTimeout bounds how long a request waits for the next message of
+	// its response
+	readTimeout = 120 * time.Second
+
+	// defaultKeepaliveInterval is how often an open connection is pinged;
+	// defaultKeepaliveTimeout how long after that the pong may take before
+	// the connection is dropped
+	defaultKeepaliveInterval = 30 * time.Second
+	defaultKeepaliveTimeout  = 10 * time.Second
+)
+
+const (
+	jsonrpcVersion = "2.0"
+
+	methodInitialize = "initialize"
+	methodGenerate   = "generate"
+	// methodPartial is the notification carrying a piece of generated text
+	methodPartial = "generate.partial"
+)
+
+// protocolVersions are the bridge protocol versions the provider speaks,
+// preferred first
+var protocolVersions = []string{"1.0"}
+
+var (
+	// errStaleConnection reports that the peer closed a reused connection
+	// before answering, so the request can be sent again on a fresh one
+	errStaleConnection = errors.New("connection closed by the bridge")
+	// errConnClosed reports that the provider closed the connection, as on
+	// shutdown
+	errConnClosed = errors.New("connection closed")
+)
+
+// bridgeConn is an open connection to the bridge. Its read loop hands each
+// message to the request holding the turn, and its keepalive loop pings the
+// bridge so a connection that went dead while idle is dropped before a
+// request is sent on it.
+type bridgeConn struct {
+	ws *websocket.Conn
+	// messages yields the messages the bridge sends
+	messages chan []byte
+	// closed is closed with the connection; err then says why
+	closed    chan struct{}
+	err       error
+	closeOnce sync.Once
+}
+
+// close closes the connection for the reason err, or errConnClosed. Only the
+// first call has an effect.
+func (c *bridgeConn) close(err error) {
+	c.closeOnce.Do(func() {
+		if err == nil {
+			err = errConnClosed
+		}
+		c.err = err
+		close(c.closed)
+		c.ws.Close()
+	})
+}
+
+// rpcRequest is a JSON-RPC 2.0 request sent to the bridge
+type rpcRequest struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      uint64      `json:"id"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// rpcMessage is a message from the bridge: a response when it has an ID, a
+// notification when it has a method and no ID
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+	Result  json.RawMessage `json:"result"`
+	Error   *rpcError       `json:"error"`
+}
+
+// rpcError is the error object of a JSON-RPC response
+type rpcError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *rpcError) Error() string {
+	if len(e.Data) > 0 {
+		return fmt.Sprintf("bridge error %d: %s (%s)", e.Code, e.Message, e.Data)
+	}
+	return fmt.Sprintf("bridge error %d: %s", e.Code, e.Message)
+}
+
+// initializeParams offers the protocol versions the provider speaks
+type initializeParams struct {
+	ProtocolVersions []string `json:"protocol_versions"`
+	Client           string   `json:"client"`
+}
+
+// initializeResult is the version the bridge picked
+type initializeResult struct {
+	ProtocolVersion string `json:"protocol_version"`
+}
+
+// generateParams are the params of a generate request: the model and the
+// request's prompt, messages, and sampling parameters under their JSON names
+type generateParams struct {
+	Model string `json:"model"`
+	interfaces.GenerationRequest
+}
+
+// partialParams are the params of a generate.partial notification
+type partialParams struct {
+	RequestID uint64 `json:"request_id"`
+	Delta     string `json:"delta"`
+}
+
+// generateResult is the result of a generate request. Text is the whole
+// reply; the part already sent in partials is not repeated to the caller.
+type generateResult struct {
+	Text             string `json:"text"`
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
+	FinishReason     string `json:"finish_reason"`
+}
+
+// acquire waits for the shared connection to be free. Requests hold it from
+// sending the prompt until the end of the response so that a bridge serving
+// one request at a time is never sent another meanwhile.
+func (p *JSONRPCBridgeProvider) acquire(ctx context.Context) error {
+	select {
+	case p.turn <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *JSONRPCBridgeProvider) release() {
+	<-p.turn
+}
+
+// connect returns the shared connection, dialing one with retries and
+// backoff and negotiating the protocol version when there is none. reused
+// reports whether the connection was already open. The caller holds the
+// turn.
+func (p *JSONRPCBridgeProvider) connect(ctx context.Context) (conn *bridgeConn, reused bool, err error) {
+	p.connMu.Lock()
+	conn = p.conn
+	p.connMu.Unlock()
+	if conn != nil {
+		return conn, true, nil
+	}
+
+	err = p.retryPolicy.Do(ctx, p.breaker, func(ctx context.Context) error {
+		dialer := websocket.Dialer{}
+		ws, resp, err := dialer.DialContext(ctx, p.endpoint, nil)
+		if err != nil {
+			if resp != nil {
+				return fmt.Errorf("WebSocket dial failed: %w", &retry.StatusError{StatusCode: resp.StatusCode, Body: err.Error()})
+			}
+			return fmt.Errorf("WebSocket dial failed: %w", err)
+		}
+		c := p.open(ws)
+		if err := p.handshake(ctx, c); err != nil {
+			c.close(err)
+			return err
+		}
+		conn = c
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	p.connMu.Lock()
+	p.conn = conn
+	p.connMu.Unlock()
+	return conn, false, nil
+}
+
+// open starts the read and keepalive loops of a new connection
+func (p *JSONRPCBridgeProvider) open(ws *websocket.Conn) *bridgeConn {
+	conn := &bridgeConn{
+		ws:       ws,
+		messages: make(chan []byte),
+		closed:   make(chan struct{}),
+	}
+	go p.readLoop(conn)
+	if p.keepaliveInterval > 0 {
+		go p.keepalive(conn)
+	}
+	return conn
+}
+
+// readLoop reads conn's messages until it fails, then drops it so the next
+// request reconnects. With keepalive on, a read that sees no frame, not even
+// a pong, within the keepalive interval and timeout fails.
+func (p *JSONRPCBridgeProvider) readLoop(conn *bridgeConn) {
+	wait := p.keepaliveInterval + p.keepaliveTimeout
+	if p.keepaliveInterval > 0 {
+		conn.ws.SetPongHandler(func(string) error {
+			return conn.ws.SetReadDeadline(time.Now().Add(wait))
+		})
+	}
+
+	for {
+		if p.keepaliveInterval > 0 {
+			if err := conn.ws.SetReadDeadline(time.Now().Add(wait)); err != nil {
+				conn.close(err)
+				p.dropConn(conn)
+				return
+			}
+		}
+		_, data, err := conn.ws.ReadMessage()
+		if err != nil {
+			conn.close(err)
+			p.dropConn(conn)
+			return
+		}
+		select {
+		case conn.messages <- data:
+		case <-conn.closed:
+			return
+		}
+	}
+}
+
+// keepalive pings conn every keepalive interval until it is closed
+func (p *JSONRPCBridgeProvider) keepalive(conn *bridgeConn) {
+	ticker := time.NewTicker(p.keepaliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-conn.closed:
+			return
+		case <-ticker.C:
+			deadline := time.Now().Add(p.keepaliveTimeout)
+			if err := conn.ws.WriteControl(websocket.PingMessage, nil, deadline); err != nil {
+				conn.close(fmt.Errorf("keepalive ping failed: %w", err))
+				p.dropConn(conn)
+				return
+			}
+		}
+	}
+}
+
+// handshake offers the provider's protocol versions on a new connection and
+// checks the one the bridge picked
+func (p *JSONRPCBridgeProvider) handshake(ctx context.Context, conn *bridgeConn) error {
+	id := atomic.AddUint64(&p.nextID, 1)
+	params := initializeParams{ProtocolVersions: protocolVersions, Client: "agentforgeengine"}
+	if err := p.write(conn, id, methodInitialize, params); err != nil {
+		return fmt.Errorf("version negotiation failed: %w", err)
+	}
+
+	raw, err := p.await(ctx, conn, id, nil)
+	if err != nil {
+		return fmt.Errorf("version negotiation failed: %w", err)
+	}
+	var result initializeResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return fmt.Errorf("version negotiation failed: invalid result: %w", err)
+	}
+	for _, version := range protocolVersions {
+		if result.ProtocolVersion == version {
+			return nil
+		}
+	}
+	return fmt.Errorf("version negotiation failed: bridge chose unsupported protocol version %q (supported: %s)",
+		result.ProtocolVersion, strings.Join(protocolVersions, ", "))
+}
+
+// dropConn closes conn and forgets it if it is still the shared connection,
+// so the next request reconnects
+func (p *JSONRPCBridgeProvider) dropConn(conn *bridgeConn) {
+	p.connMu.Lock()
+	if p.conn == conn {
+		p.conn = nil
+	}
+	p.connMu.Unlock()
+	conn.close(nil)
+}
+
+// write sends a JSON-RPC request on conn
+func (p *JSONRPCBridgeProvider) write(conn *bridgeConn, id uint64, method string, params interface{}) error {
+	jsonData, err := json.Marshal(rpcRequest{JSONRPC: jsonrpcVersion, ID: id, Method: method, Params: params})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	if err := conn.ws.SetWriteDeadline(time.Now().Add(p.timeout)); err != nil {
+		return err
+	}
+	return conn.ws.WriteMessage(websocket.TextMessage, jsonData)
+}
+
+// send writes a generate request for input on the shared connection and
+// returns the connection and the request's ID. A write that fails on a
+// reused connection is retried once on a fresh one. The caller holds the
+// turn.
+func (p *JSONRPCBridgeProvider) send(ctx context.Context, input interfaces.GenerationRequest) (*bridgeConn, uint64, bool, error) {
+	// Ask for partials; the result arrives either way
+	input.Stream = true
+	params := generateParams{Model: p.modelName, GenerationRequest: input}
+
+	for {
+		conn, reused, err := p.connect(ctx)
+		if err != nil {
+			return nil, 0, false, err
+		}
+
+		id := atomic.AddUint64(&p.nextID, 1)
+		err = p.write(conn, id, methodGenerate, params)
+		if err == nil {
+			return conn, id, reused, nil
+		}
+
+		p.dropConn(conn)
+		if !reused {
+			return nil, 0, false, fmt.Errorf("failed to send message: %w", err)
+		}
+	}
+}
+
+// readResponse reads the answer to generate request id, passing the text of
+// each of its partials to emit, and returns its result. The text of the
+// result that no partial carried is passed to emit before returning.
+func (p *JSONRPCBridgeProvider) readResponse(ctx context.Context, conn *bridgeConn, id uint64, emit func(delta string) bool) (generateResult, error) {
+	var streamed strings.Builder
+	cancelled := false
+	notify := func(message rpcMessage) bool {
+		if message.Method != methodPartial {
+			return true
+		}
+		var partial partialParams
+		if err := json.Unmarshal(message.Params, &partial); err != nil || partial.RequestID != id {
+			return true
+		}
+		if partial.Delta == "" {
+			return true
+		}
+		streamed.WriteString(partial.Delta)
+		if !emit(partial.Delta) {
+			cancelled = true
+			return false
+		}
+		return true
+	}
+
+	raw, err := p.await(ctx, conn, id, notify)
+	if cancelled {
+		p.dropConn(conn)
+		return generateResult{}, ctx.Err()
+	}
+	if err != nil {
+		return generateResult{}, err
+	}
+
+	var result generateResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return generateResult{}, fmt.Errorf("invalid generate result: %w", err)
+	}
+	if rest, ok := strings.CutPrefix(result.Text, streamed.String()); ok && rest != "" {
+		if !emit(rest) {
+			p.dropConn(conn)
+			return generateResult{}, ctx.Err()
+		}
+	}
+	return result, nil
+}
+
+// await reads messages from conn until the response to request id arrives
+// and returns its result, or its error object as an *rpcError. Notifications
+// are passed to notify, which stops the read by returning false. Responses to
+// other IDs are late answers to earlier requests and are skipped. Cancelling
+// ctx, or readTimeout passing without a message, ends the wait; the
+// connection is then dropped, since the rest of the response may still
+// arrive on it. It returns errStaleConnection when the peer closed the
+// connection before any message arrived.
+func (p *JSONRPCBridgeProvider) await(ctx context.Context, conn *bridgeConn, id uint64, notify func(rpcMessage) bool) (json.RawMessage, error) {
+	timer := time.NewTimer(readTimeout)
+	defer timer.Stop()
+
+	received := false
+	for {
+		var data []byte
+		select {
+		case data = <-conn.messages:
+		case <-conn.closed:
+			p.dropConn(conn)
+			if !received && peerClosed(conn.err) {
+				return nil, errStaleConnection
+			}
+			return nil, fmt.Errorf("failed to read message: %w", conn.err)
+		case <-ctx.Done():
+			p.dropConn(conn)
+			return nil, ctx.Err()
+		case <-timer.C:
+			p.dropConn(conn)
+			return nil, fmt.Errorf("failed to read message: no message from the bridge in %v", readTimeout)
+		}
+		received = true
+		timer.Reset(readTimeout)
+
+		var message rpcMessage
+		if err := json.Unmarshal(data, &message); err != nil || message.JSONRPC != jsonrpcVersion {
+			p.dropConn(conn)
+			return nil, fmt.Errorf("invalid JSON-RPC message from bridge: %.100s", data)
+		}
+
+		if isNullID(message.ID) {
+			if message.Error != nil {
+				// The bridge could not read a request's ID; only ours is
+				// in progress
+				return nil, message.Error
+			}
+			if message.Method != "" && notify != nil && !notify(message) {
+				return nil, ctx.Err()
+			}
+			continue
+		}
+
+		if responseID, err := strconv.ParseUint(string(message.ID), 10, 64); err != nil || responseID != id {
+			continue
+		}
+		if message.Error != nil {
+			return nil, message.Error
+		}
+		if message.Result == nil {
+			return nil, fmt.Errorf("response %d has neither result nor error", id)
+		}
+		return message.Result, nil
+	}
+}
+
+// isNullID reports whether a message has no ID or a null one
+func isNullID(id json.RawMessage) bool {
+	return len(id) == 0 || string(id) == "null"
+}
+
+// peerClosed reports whether a read failed because the bridge closed the
+// connection
+func peerClosed(err error) bool {
+	var closeErr *websocket.CloseError
+	return errors.As(err, &closeErr) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
@@ -1,12 +1,12 @@
 module json-rpc-bridge
 
-go 1.21
+go 1.24.0
 
-replace github.com/AgentForgeEngine/AgentForgeEngine => /home/audstanley/Documents/AgentForgeEngine
+replace github.com/AgentForgeEngine/AgentForgeEngine => ../..
 
 require (
 	github.com/AgentForgeEngine/AgentForgeEngine v0.0.0-00010101000000-000000000000
 	github.com/gorilla/websocket v1.5.1
 )
 
-require golang.org/x/net v0.19.0 // indirect
+require golang.org/x/net v0.48.0 // indirect
@@ -2,13 +2,17 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/retry"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/stream"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/tokenizer"
 	"github.com/gorilla/websocket"
 )
 
@@ -17,13 +21,36 @@ type JSONRPCBridgeProvider struct {
 	endpoint  string
 	modelName string
 	timeout   time.Duration
-	client    *websocket.Conn
+	tokenizer tokenizer.Tokenizer
+
+	// turn admits one request at a time to the shared connection
+	turn chan struct{}
+	// connMu guards conn, which is nil until the first request and after
+	// the connection fails
+	connMu sync.Mutex
+	conn   *bridgeConn
+	nextID uint64
+	// keepaliveInterval is how often the open connection is pinged, zero
+	// for never; keepaliveTimeout how long the pong may then take
+	keepaliveInterval time.Duration
+	keepaliveTimeout  time.Duration
+
+	retryPolicy retry.Policy
+	breaker     *retry.Breaker
 }
 
 func NewJSONRPCBridgeProvider() *JSONRPCBridgeProvider {
 	return &JSONRPCBridgeProvider{
-		name:    "json-rpc-bridge",
-		timeout: 60 * time.Second,
+		name:      "json-rpc-bridge",
+		timeout:   60 * time.Second,
+		tokenizer: tokenizer.NewHeuristic(),
+		turn:      make(chan struct{}, 1),
+
+		keepaliveInterval: defaultKeepaliveInterval,
+		keepaliveTimeout:  defaultKeepaliveTimeout,
+
+		retryPolicy: retry.DefaultPolicy(),
+		breaker:     retry.BreakerFromConfig(nil),
 	}
 }
 
@@ -45,6 +72,28 @@ func (p *JSONRPCBridgeProvider) Initialize(config map[string]interface{}) error
 		return fmt.Errorf("model_name not specified in config")
 	}
 
+	tok, err := tokenizer.FromConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to load tokenizer: %w", err)
+	}
+	p.tokenizer = tok
+
+	// Ping the open connection so one that went dead is replaced
+	if interval, ok, err := getSeconds(config, "keepalive_interval_seconds"); err != nil {
+		return err
+	} else if ok {
+		p.keepaliveInterval = interval
+	}
+	if timeout, ok, err := getSeconds(config, "keepalive_timeout_seconds"); err != nil {
+		return err
+	} else if ok && timeout > 0 {
+		p.keepaliveTimeout = timeout
+	}
+
+	// Retry failed connections and fail fast while the bridge is down
+	p.retryPolicy = retry.PolicyFromConfig(config)
+	p.breaker = retry.BreakerFromConfig(config)
+
 	// Ensure endpoint has /ws path
 	if !strings.HasSuffix(p.endpoint, "/ws") {
 		p.endpoint += "/ws"
@@ -55,87 +104,155 @@ func (p *JSONRPCBridgeProvider) Initialize(config map[string]interface{}) error
 }
 
 func (p *JSONRPCBridgeProvider) Generate(ctx context.Context, input interfaces.GenerationRequest) (*interfaces.GenerationResponse, error) {
-	// Connect to WebSocket
-	dialer := websocket.Dialer{}
-	c, _, err := dialer.Dial(p.endpoint, nil)
+	chunks, err := p.GenerateStream(ctx, input)
 	if err != nil {
-		return nil, fmt.Errorf("WebSocket dial failed: %w", err)
+		return nil, err
 	}
-	defer c.Close()
-	p.client = c
 
-	// Set deadlines
-	if err := c.SetWriteDeadline(time.Now().Add(p.timeout)); err != nil {
-		return nil, fmt.Errorf("failed to set write deadline: %w", err)
-	}
-	if err := c.SetReadDeadline(time.Now().Add(120 * time.Second)); err != nil {
-		return nil, fmt.Errorf("failed to set read deadline: %w", err)
-	}
+	return stream.Collect(ctx, chunks, p.modelName)
+}
 
-	// Create request message
-	request := map[string]interface{}{
-		"model":  p.modelName,
-		"prompt": input.Prompt,
+// GenerateStream sends a JSON-RPC generate request on the shared connection
+// and returns a channel that yields the text of each generate.partial
+// notification as the bridge sends it. The channel is closed after a final
+// chunk carrying the token counts and finish reason of the result, or the
+// bridge's error, or without one when ctx is cancelled.
+func (p *JSONRPCBridgeProvider) GenerateStream(ctx context.Context, input interfaces.GenerationRequest) (<-chan interfaces.GenerationChunk, error) {
+	if err := p.acquire(ctx); err != nil {
+		return nil, err
 	}
 
-	// Send request
-	jsonData, err := json.Marshal(request)
+	conn, id, reused, err := p.send(ctx, input)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+		p.release()
+		return nil, err
 	}
 
-	err = c.WriteMessage(websocket.TextMessage, jsonData)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send message: %w", err)
-	}
+	chunks := make(chan interfaces.GenerationChunk)
+	go func() {
+		defer close(chunks)
+		defer p.release()
 
-	// Read streaming response
-	var response strings.Builder
-	for {
-		_, message, err := c.ReadMessage()
-		if err != nil {
-			if websocket.IsCloseError(err) {
-				break // Normal closure
+		send := func(chunk interfaces.GenerationChunk) bool {
+			select {
+			case chunks <- chunk:
+				return true
+			case <-ctx.Done():
+				return false
 			}
-			return nil, fmt.Errorf("failed to read message: %w", err)
 		}
 
-		msgStr := string(message)
-		if msgStr == "[DONE]" {
-			break
+		var text strings.Builder
+		emit := func(delta string) bool {
+			text.WriteString(delta)
+			return send(interfaces.GenerationChunk{Delta: delta, Model: p.modelName})
 		}
-		response.WriteString(msgStr)
-	}
 
-	return &interfaces.GenerationResponse{
-		Text:     response.String(),
-		Finished: true,
-		Model:    p.modelName,
-	}, nil
+		result, err := p.readResponse(ctx, conn, id, emit)
+		if errors.Is(err, errStaleConnection) && reused {
+			// The bridge closed the idle connection; send again on a new one
+			if conn, id, _, err = p.send(ctx, input); err == nil {
+				result, err = p.readResponse(ctx, conn, id, emit)
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		final := interfaces.GenerationChunk{
+			Done:         true,
+			Tokens:       result.CompletionTokens,
+			PromptTokens: result.PromptTokens,
+			FinishReason: result.FinishReason,
+			Model:        p.modelName,
+		}
+		if final.Tokens == 0 {
+			final.Tokens = p.tokenizer.CountTokens(text.String())
+		}
+		if final.FinishReason == "" {
+			final.FinishReason = "stop"
+		}
+		if err != nil {
+			final = interfaces.GenerationChunk{Done: true, Error: err.Error(), Model: p.modelName}
+		}
+		send(final)
+	}()
+
+	return chunks, nil
 }
 
+// HealthCheck pings the bridge over the shared connection, connecting first
+// if there is none
 func (p *JSONRPCBridgeProvider) HealthCheck() error {
+	// Report the bridge as down while requests are failing fast
+	if err := p.breaker.Err(); err != nil {
+		return fmt.Errorf("health check failed: %w", err)
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	// Try to connect and test
-	dialer := websocket.Dialer{}
-	c, _, err := dialer.DialContext(ctx, p.endpoint, nil)
-	if err != nil {
+	// Wait for any request in progress rather than interleaving with it
+	if err := p.acquire(ctx); err != nil {
 		return fmt.Errorf("health check failed: %w", err)
 	}
-	defer c.Close()
+	defer p.release()
 
-	log.Printf("JSON-RPC Bridge health check passed")
-	return nil
+	for {
+		conn, reused, err := p.connect(ctx)
+		if err != nil {
+			return fmt.Errorf("health check failed: %w", err)
+		}
+
+		deadline, _ := ctx.Deadline()
+		err = conn.ws.WriteControl(websocket.PingMessage, nil, deadline)
+		if err == nil {
+			return nil
+		}
+
+		p.dropConn(conn)
+		if !reused {
+			return fmt.Errorf("health check failed: %w", err)
+		}
+	}
 }
 
+// Shutdown closes the shared connection, ending any request in progress
 func (p *JSONRPCBridgeProvider) Shutdown() error {
-	if p.client != nil {
-		return p.client.Close()
+	p.connMu.Lock()
+	conn := p.conn
+	p.conn = nil
+	p.connMu.Unlock()
+
+	if conn != nil {
+		conn.close(nil)
 	}
 	return nil
 }
 
+// getSeconds reads a non-negative number of seconds
+func getSeconds(config map[string]interface{}, key string) (time.Duration, bool, error) {
+	var seconds float64
+	switch raw := config[key].(type) {
+	case nil:
+		return 0, false, nil
+	case int:
+		seconds = float64(raw)
+	case int64:
+		seconds = float64(raw)
+	case float64:
+		seconds = raw
+	default:
+		return 0, false, fmt.Errorf("invalid %s: must be a number of seconds", key)
+	}
+	if seconds < 0 {
+		return 0, false, fmt.Errorf("invalid %s: must not be negative", key)
+	}
+	return time.Duration(seconds * float64(time.Second)), true, nil
+}
+
 // Export the provider for plugin loading
 var Provider interfaces.Provider = NewJSONRPCBridgeProvider()
+
+// JSONRPCBridgeProvider streams responses to callers that ask for them
+var _ interfaces.StreamingProvider = (*JSONRPCBridgeProvider)(nil)
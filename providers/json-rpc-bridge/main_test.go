@@ -0,0 +1,544 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/retry"
+	"github.com/gorilla/websocket"
+)
+
+// bridgeServer refuses the first failures handshakes with a 503 and answers
+// the first prompt on every later connection with a fixed reply before
+// closing it, counting every handshake
+func bridgeServer(failures int32) (*httptest.Server, *int32) {
+	var handshakes int32
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&handshakes, 1) <= failures {
+			http.Error(w, "bridge starting", http.StatusServiceUnavailable)
+			return
+		}
+		c, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		for {
+			var req bridgeRequest
+			if err := c.ReadJSON(&req); err != nil {
+				return
+			}
+			if req.Method == methodInitialize {
+				respondResult(c, req.ID, map[string]interface{}{"protocol_version": "1.0"})
+				continue
+			}
+			sendPartial(c, req.ID, "recovered")
+			respondResult(c, req.ID, map[string]interface{}{"text": "recovered"})
+			return
+		}
+	}))
+	return server, &handshakes
+}
+
+func newTestProvider(t *testing.T, config map[string]interface{}) *JSONRPCBridgeProvider {
+	t.Helper()
+	provider := NewJSONRPCBridgeProvider()
+	if err := provider.Initialize(config); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	return provider
+}
+
+func TestGenerate_RetriesHandshake(t *testing.T) {
+	server, handshakes := bridgeServer(2)
+	defer server.Close()
+
+	provider := newTestProvider(t, map[string]interface{}{
+		"endpoint":         wsURL(server),
+		"model_name":       "bridge",
+		"max_attempts":     3,
+		"retry_backoff_ms": 1,
+	})
+	response, err := provider.Generate(context.Background(), interfaces.GenerationRequest{Prompt: "Hi"})
+	if err != nil {
+		t.Fatalf("Expected the third handshake to succeed, got %v", err)
+	}
+	if response.Text != "recovered" || atomic.LoadInt32(handshakes) != 3 {
+		t.Errorf("Expected success after 3 handshakes, got %q after %d", response.Text, atomic.LoadInt32(handshakes))
+	}
+}
+
+func TestGenerate_CircuitBreakerOpens(t *testing.T) {
+	server, handshakes := bridgeServer(1000)
+	defer server.Close()
+
+	provider := newTestProvider(t, map[string]interface{}{
+		"endpoint":                  wsURL(server),
+		"model_name":                "bridge",
+		"max_attempts":              2,
+		"retry_backoff_ms":          1,
+		"circuit_failure_threshold": 2,
+		"circuit_cooldown_seconds":  60,
+	})
+	request := interfaces.GenerationRequest{Prompt: "Hi"}
+
+	if _, err := provider.Generate(context.Background(), request); err == nil {
+		t.Fatal("Expected the first request to fail")
+	}
+	if _, err := provider.Generate(context.Background(), request); !errors.Is(err, retry.ErrCircuitOpen) {
+		t.Fatalf("Expected the circuit to be open, got %v", err)
+	}
+	if got := atomic.LoadInt32(handshakes); got != 2 {
+		t.Errorf("Expected 2 handshakes before the circuit opened, got %d", got)
+	}
+	if err := provider.HealthCheck(); !errors.Is(err, retry.ErrCircuitOpen) {
+		t.Errorf("Expected HealthCheck to report the open circuit, got %v", err)
+	}
+}
+
+// bridgeRequest is a JSON-RPC request as the bridge reads it
+type bridgeRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      uint64          `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+// generate decodes the params of a generate request
+func (req bridgeRequest) generate() generateParams {
+	var params generateParams
+	json.Unmarshal(req.Params, &params)
+	return params
+}
+
+func respondResult(c *websocket.Conn, id uint64, result interface{}) {
+	c.WriteJSON(map[string]interface{}{"jsonrpc": "2.0", "id": id, "result": result})
+}
+
+func respondError(c *websocket.Conn, id uint64, code int, message string) {
+	c.WriteJSON(map[string]interface{}{"jsonrpc": "2.0", "id": id, "error": map[string]interface{}{"code": code, "message": message}})
+}
+
+func sendPartial(c *websocket.Conn, id uint64, delta string) {
+	c.WriteJSON(map[string]interface{}{"jsonrpc": "2.0", "method": methodPartial, "params": map[string]interface{}{"request_id": id, "delta": delta}})
+}
+
+// persistentServer negotiates version 1.0 and answers every generate request
+// on a connection with respond until the client goes away, counting
+// handshakes
+func persistentServer(respond func(c *websocket.Conn, req bridgeRequest)) (*httptest.Server, *int32) {
+	var handshakes int32
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		atomic.AddInt32(&handshakes, 1)
+
+		for {
+			var req bridgeRequest
+			if err := c.ReadJSON(&req); err != nil {
+				return
+			}
+			if req.Method == methodInitialize {
+				respondResult(c, req.ID, map[string]interface{}{"protocol_version": "1.0"})
+				continue
+			}
+			respond(c, req)
+		}
+	}))
+	return server, &handshakes
+}
+
+// echoWords answers with each word of the prompt in its own partial, then a
+// result counting the words
+func echoWords(c *websocket.Conn, req bridgeRequest) {
+	words := strings.Fields(req.generate().Prompt)
+	var text strings.Builder
+	for _, word := range words {
+		sendPartial(c, req.ID, word+" ")
+		text.WriteString(word + " ")
+	}
+	respondResult(c, req.ID, map[string]interface{}{"text": text.String(), "completion_tokens": len(words)})
+}
+
+func wsURL(server *httptest.Server) string {
+	return "ws" + server.URL[len("http"):]
+}
+
+func TestGenerate_ReusesConnection(t *testing.T) {
+	server, handshakes := persistentServer(echoWords)
+	defer server.Close()
+
+	provider := newTestProvider(t, map[string]interface{}{"endpoint": wsURL(server), "model_name": "bridge"})
+	defer provider.Shutdown()
+
+	for i := 0; i < 3; i++ {
+		response, err := provider.Generate(context.Background(), interfaces.GenerationRequest{Prompt: "hello there"})
+		if err != nil {
+			t.Fatalf("Generate %d failed: %v", i, err)
+		}
+		if response.Text != "hello there " {
+			t.Errorf("Unexpected response: %q", response.Text)
+		}
+	}
+	if err := provider.HealthCheck(); err != nil {
+		t.Errorf("HealthCheck failed: %v", err)
+	}
+
+	if got := atomic.LoadInt32(handshakes); got != 1 {
+		t.Errorf("Expected requests and health checks to share one connection, got %d handshakes", got)
+	}
+}
+
+func TestGenerate_ConcurrentRequestsDoNotInterleave(t *testing.T) {
+	var busy int32
+	server, _ := persistentServer(func(c *websocket.Conn, req bridgeRequest) {
+		// The bridge serves one request at a time
+		if !atomic.CompareAndSwapInt32(&busy, 0, 1) {
+			respondError(c, req.ID, -32000, "busy")
+			return
+		}
+		defer atomic.StoreInt32(&busy, 0)
+		for _, word := range strings.Fields(req.generate().Prompt) {
+			sendPartial(c, req.ID, word+" ")
+			time.Sleep(time.Millisecond)
+		}
+		respondResult(c, req.ID, map[string]interface{}{})
+	})
+	defer server.Close()
+
+	provider := newTestProvider(t, map[string]interface{}{"endpoint": wsURL(server), "model_name": "bridge"})
+	defer provider.Shutdown()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			prompt := fmt.Sprintf("request %d a b c", i)
+			response, err := provider.Generate(context.Background(), interfaces.GenerationRequest{Prompt: prompt})
+			if err != nil {
+				t.Errorf("Generate %d failed: %v", i, err)
+				return
+			}
+			if response.Text != prompt+" " {
+				t.Errorf("Expected %q, got %q", prompt+" ", response.Text)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestGenerate_SkipsLateMessagesFromEarlierRequests(t *testing.T) {
+	server, _ := persistentServer(func(c *websocket.Conn, req bridgeRequest) {
+		sendPartial(c, 0, "stale partial")
+		respondResult(c, 0, map[string]interface{}{"text": "stale answer"})
+		echoWords(c, req)
+	})
+	defer server.Close()
+
+	provider := newTestProvider(t, map[string]interface{}{"endpoint": wsURL(server), "model_name": "bridge"})
+	defer provider.Shutdown()
+
+	response, err := provider.Generate(context.Background(), interfaces.GenerationRequest{Prompt: "fresh"})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if response.Text != "fresh " {
+		t.Errorf("Expected only this request's messages, got %q", response.Text)
+	}
+}
+
+func TestGenerateStream_CancelUnblocksRead(t *testing.T) {
+	server, handshakes := persistentServer(func(c *websocket.Conn, req bridgeRequest) {
+		if req.generate().Prompt == "hang" {
+			sendPartial(c, req.ID, "partial")
+			return // never finish; the client must give up on its own
+		}
+		echoWords(c, req)
+	})
+	defer server.Close()
+
+	provider := newTestProvider(t, map[string]interface{}{"endpoint": wsURL(server), "model_name": "bridge"})
+	defer provider.Shutdown()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	chunks, err := provider.GenerateStream(ctx, interfaces.GenerationRequest{Prompt: "hang"})
+	if err != nil {
+		t.Fatalf("GenerateStream failed: %v", err)
+	}
+	if chunk := <-chunks; chunk.Delta != "partial" {
+		t.Fatalf("Expected the first frame, got %+v", chunk)
+	}
+	cancel()
+
+	select {
+	case chunk, ok := <-chunks:
+		if ok {
+			t.Errorf("Expected the stream to close on cancel, got %+v", chunk)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Read loop ignored cancellation")
+	}
+
+	// The abandoned connection is replaced so its late frames cannot leak
+	response, err := provider.Generate(context.Background(), interfaces.GenerationRequest{Prompt: "next"})
+	if err != nil || response.Text != "next " {
+		t.Fatalf("Expected the next request to succeed, got %q, %v", response.Text, err)
+	}
+	if got := atomic.LoadInt32(handshakes); got != 2 {
+		t.Errorf("Expected a reconnect after cancel, got %d handshakes", got)
+	}
+}
+
+func TestGenerate_ReconnectsAfterPeerCloses(t *testing.T) {
+	// bridgeServer closes each connection after one response
+	server, handshakes := bridgeServer(0)
+	defer server.Close()
+
+	provider := newTestProvider(t, map[string]interface{}{"endpoint": wsURL(server), "model_name": "bridge"})
+	defer provider.Shutdown()
+
+	for i := 0; i < 3; i++ {
+		response, err := provider.Generate(context.Background(), interfaces.GenerationRequest{Prompt: "Hi"})
+		if err != nil {
+			t.Fatalf("Generate %d failed: %v", i, err)
+		}
+		if response.Text != "recovered" {
+			t.Errorf("Unexpected response: %q", response.Text)
+		}
+	}
+	if got := atomic.LoadInt32(handshakes); got != 3 {
+		t.Errorf("Expected one connection per response, got %d handshakes", got)
+	}
+}
+
+func TestGenerate_SendsJSONRPCRequests(t *testing.T) {
+	requests := make(chan bridgeRequest, 1)
+	server, _ := persistentServer(func(c *websocket.Conn, req bridgeRequest) {
+		requests <- req
+		respondResult(c, req.ID, map[string]interface{}{
+			"text":              "Hello there",
+			"prompt_tokens":     7,
+			"completion_tokens": 2,
+			"finish_reason":     "length",
+		})
+	})
+	defer server.Close()
+
+	provider := newTestProvider(t, map[string]interface{}{"endpoint": wsURL(server), "model_name": "bridge"})
+	defer provider.Shutdown()
+
+	response, err := provider.Generate(context.Background(), interfaces.GenerationRequest{Prompt: "Hi", MaxTokens: 2, Temperature: 0.5})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	req := <-requests
+	params := req.generate()
+	if req.JSONRPC != "2.0" || req.Method != "generate" || req.ID == 0 {
+		t.Errorf("Expected a JSON-RPC 2.0 generate request with an ID, got %+v", req)
+	}
+	if params.Model != "bridge" || params.Prompt != "Hi" || params.MaxTokens != 2 || params.Temperature != 0.5 || !params.Stream {
+		t.Errorf("Unexpected params: %s", req.Params)
+	}
+
+	// A result without partials delivers its whole text
+	if response.Text != "Hello there" {
+		t.Errorf("Expected the result text, got %q", response.Text)
+	}
+	if response.PromptTokens != 7 || response.Tokens != 2 || response.FinishReason != "length" {
+		t.Errorf("Expected the result's token counts and finish reason, got %+v", response)
+	}
+}
+
+func TestGenerate_ReturnsBridgeErrors(t *testing.T) {
+	server, handshakes := persistentServer(func(c *websocket.Conn, req bridgeRequest) {
+		if req.generate().Prompt == "fail" {
+			sendPartial(c, req.ID, "partial ")
+			respondError(c, req.ID, -32602, "prompt too long")
+			return
+		}
+		echoWords(c, req)
+	})
+	defer server.Close()
+
+	provider := newTestProvider(t, map[string]interface{}{"endpoint": wsURL(server), "model_name": "bridge"})
+	defer provider.Shutdown()
+
+	_, err := provider.Generate(context.Background(), interfaces.GenerationRequest{Prompt: "fail"})
+	if err == nil || !strings.Contains(err.Error(), "-32602") || !strings.Contains(err.Error(), "prompt too long") {
+		t.Fatalf("Expected the bridge's error, got %v", err)
+	}
+
+	chunks, err := provider.GenerateStream(context.Background(), interfaces.GenerationRequest{Prompt: "fail"})
+	if err != nil {
+		t.Fatalf("GenerateStream failed: %v", err)
+	}
+	var final interfaces.GenerationChunk
+	for chunk := range chunks {
+		final = chunk
+	}
+	if !final.Done || !strings.Contains(final.Error, "prompt too long") {
+		t.Errorf("Expected a final chunk with the bridge's error, got %+v", final)
+	}
+
+	// An error response leaves the connection usable
+	response, err := provider.Generate(context.Background(), interfaces.GenerationRequest{Prompt: "after"})
+	if err != nil || response.Text != "after " {
+		t.Fatalf("Expected the next request to succeed, got %v", err)
+	}
+	if got := atomic.LoadInt32(handshakes); got != 1 {
+		t.Errorf("Expected one connection, got %d handshakes", got)
+	}
+}
+
+func TestConnect_NegotiatesProtocolVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		respond func(c *websocket.Conn, req bridgeRequest)
+		wantErr string
+	}{
+		{
+			name: "supported",
+			respond: func(c *websocket.Conn, req bridgeRequest) {
+				var params initializeParams
+				json.Unmarshal(req.Params, &params)
+				respondResult(c, req.ID, map[string]interface{}{"protocol_version": params.ProtocolVersions[0]})
+			},
+		},
+		{
+			name: "unsupported",
+			respond: func(c *websocket.Conn, req bridgeRequest) {
+				respondResult(c, req.ID, map[string]interface{}{"protocol_version": "0.1"})
+			},
+			wantErr: `unsupported protocol version "0.1"`,
+		},
+		{
+			name: "refused",
+			respond: func(c *websocket.Conn, req bridgeRequest) {
+				respondError(c, req.ID, -32601, "Method not found")
+			},
+			wantErr: "Method not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upgrader := websocket.Upgrader{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				c, err := upgrader.Upgrade(w, r, nil)
+				if err != nil {
+					return
+				}
+				defer c.Close()
+				for {
+					var req bridgeRequest
+					if err := c.ReadJSON(&req); err != nil {
+						return
+					}
+					if req.Method == methodInitialize {
+						tt.respond(c, req)
+						continue
+					}
+					echoWords(c, req)
+				}
+			}))
+			defer server.Close()
+
+			provider := newTestProvider(t, map[string]interface{}{"endpoint": wsURL(server), "model_name": "bridge"})
+			defer provider.Shutdown()
+
+			response, err := provider.Generate(context.Background(), interfaces.GenerationRequest{Prompt: "hello"})
+			if tt.wantErr == "" {
+				if err != nil || response.Text != "hello " {
+					t.Fatalf("Expected the request to succeed, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), "version negotiation failed") || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Expected version negotiation to fail with %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestKeepalive_DropsDeadConnection(t *testing.T) {
+	for _, answerPings := range []bool{true, false} {
+		t.Run(fmt.Sprintf("answer pings %v", answerPings), func(t *testing.T) {
+			var handshakes int32
+			upgrader := websocket.Upgrader{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				c, err := upgrader.Upgrade(w, r, nil)
+				if err != nil {
+					return
+				}
+				defer c.Close()
+				atomic.AddInt32(&handshakes, 1)
+				if !answerPings {
+					// A hung bridge: the socket stays open but nothing answers
+					c.SetPingHandler(func(string) error { return nil })
+				}
+				for {
+					var req bridgeRequest
+					if err := c.ReadJSON(&req); err != nil {
+						return
+					}
+					if req.Method == methodInitialize {
+						respondResult(c, req.ID, map[string]interface{}{"protocol_version": "1.0"})
+						continue
+					}
+					echoWords(c, req)
+				}
+			}))
+			defer server.Close()
+
+			provider := newTestProvider(t, map[string]interface{}{
+				"endpoint":                   wsURL(server),
+				"model_name":                 "bridge",
+				"keepalive_interval_seconds": 0.02,
+				"keepalive_timeout_seconds":  0.02,
+			})
+			defer provider.Shutdown()
+
+			if _, err := provider.Generate(context.Background(), interfaces.GenerationRequest{Prompt: "first"}); err != nil {
+				t.Fatalf("Generate failed: %v", err)
+			}
+			// Idle for several keepalive intervals
+			time.Sleep(200 * time.Millisecond)
+
+			provider.connMu.Lock()
+			dropped := provider.conn == nil
+			provider.connMu.Unlock()
+			if dropped == answerPings {
+				t.Errorf("Expected the connection to be dropped: %v, got %v", !answerPings, dropped)
+			}
+
+			response, err := provider.Generate(context.Background(), interfaces.GenerationRequest{Prompt: "second"})
+			if err != nil || response.Text != "second " {
+				t.Fatalf("Expected the next request to succeed, got %v", err)
+			}
+			expected := int32(1)
+			if !answerPings {
+				expected = 2
+			}
+			if got := atomic.LoadInt32(&handshakes); got != expected {
+				t.Errorf("Expected %d handshakes, got %d", expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,476 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/retry"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/stream"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/tokenizer"
+)
+
+// Ollama APIs the provider can use
+const (
+	apiChat     = "chat"
+	apiGenerate = "generate"
+)
+
+type OllamaProvider struct {
+	name      string
+	endpoint  string
+	modelName string
+	// api is the endpoint used for generation: /api/chat or /api/generate
+	api string
+	// keepAlive is passed through to Ollama as is, so both "10m" and a number
+	// of seconds work; nil leaves Ollama's default
+	keepAlive   interface{}
+	timeout     time.Duration
+	client      *http.Client
+	tokenizer   tokenizer.Tokenizer
+	retryPolicy retry.Policy
+	breaker     *retry.Breaker
+}
+
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+func NewOllamaProvider() *OllamaProvider {
+	return &OllamaProvider{
+		name:        "ollama",
+		endpoint:    "http://localhost:11434",
+		api:         apiChat,
+		timeout:     120 * time.Second,
+		tokenizer:   tokenizer.NewHeuristic(),
+		retryPolicy: retry.DefaultPolicy(),
+		breaker:     retry.BreakerFromConfig(nil),
+	}
+}
+
+func (p *OllamaProvider) Name() string {
+	return p.name
+}
+
+func (p *OllamaProvider) Initialize(config map[string]interface{}) error {
+	// Parse configuration
+	if endpoint, ok := config["endpoint"].(string); ok && endpoint != "" {
+		p.endpoint = strings.TrimSuffix(endpoint, "/")
+	}
+
+	if modelName, ok := config["model_name"].(string); ok && modelName != "" {
+		p.modelName = modelName
+	} else {
+		return fmt.Errorf("model_name not specified in config")
+	}
+
+	if api, ok := config["api"].(string); ok && api != "" {
+		if api != apiChat && api != apiGenerate {
+			return fmt.Errorf("invalid api %q: must be %q or %q", api, apiChat, apiGenerate)
+		}
+		p.api = api
+	}
+
+	p.keepAlive = config["keep_alive"]
+
+	if timeout, ok := getInt(config, "timeout"); ok && timeout > 0 {
+		p.timeout = time.Duration(timeout) * time.Second
+	}
+
+	// Tokenizer used to count tokens when Ollama does not report them
+	tok, err := tokenizer.FromConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to load tokenizer: %w", err)
+	}
+	p.tokenizer = tok
+
+	// Retry transient failures and fail fast while Ollama is down
+	p.retryPolicy = retry.PolicyFromConfig(config)
+	p.breaker = retry.BreakerFromConfig(config)
+
+	// Setup HTTP client
+	p.client = &http.Client{
+		Timeout: p.timeout,
+	}
+
+	log.Printf("Ollama provider initialized: endpoint=%s, model=%s, api=%s", p.endpoint, p.modelName, p.api)
+	return nil
+}
+
+func (p *OllamaProvider) Generate(ctx context.Context, input interfaces.GenerationRequest) (*interfaces.GenerationResponse, error) {
+	// Streaming requests are read chunk by chunk and joined
+	if input.Stream {
+		chunks, err := p.GenerateStream(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		return stream.Collect(ctx, chunks, p.modelName)
+	}
+
+	resp, err := p.send(ctx, input, false)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var response ollamaResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	text := response.text()
+	tokens := response.EvalCount
+	if tokens == 0 {
+		tokens = p.tokenizer.CountTokens(text)
+	}
+
+	return &interfaces.GenerationResponse{
+		Text:     text,
+		Tokens:   tokens,
+		Finished: response.Done,
+		Model:    p.modelName,
+	}, nil
+}
+
+// GenerateStream starts a streaming request and returns a channel that yields
+// each chunk of text as Ollama sends it. The channel is closed after a final
+// chunk carrying the token count and finish reason.
+func (p *OllamaProvider) GenerateStream(ctx context.Context, input interfaces.GenerationRequest) (<-chan interfaces.GenerationChunk, error) {
+	resp, err := p.send(ctx, input, true)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := make(chan interfaces.GenerationChunk)
+	go p.streamChunks(ctx, resp, chunks)
+	return chunks, nil
+}
+
+// send posts a request to the configured API, retrying connection failures
+// and server errors
+func (p *OllamaProvider) send(ctx context.Context, input interfaces.GenerationRequest, stream bool) (*http.Response, error) {
+	messages := parseMessages(input)
+
+	var payload map[string]interface{}
+	if p.api == apiGenerate {
+		payload = p.generatePayload(messages, input, stream)
+	} else {
+		payload = p.chatPayload(messages, input, stream)
+	}
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	var resp *http.Response
+	err = p.retryPolicy.Do(ctx, p.breaker, func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, "POST", p.endpoint+"/api/"+p.api, bytes.NewReader(jsonData))
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		attempt, err := p.client.Do(req)
+		if err != nil {
+			return fmt.Errorf("request failed: %w", err)
+		}
+
+		if attempt.StatusCode != http.StatusOK {
+			defer attempt.Body.Close()
+			body, _ := io.ReadAll(attempt.Body)
+			return &retry.StatusError{StatusCode: attempt.StatusCode, Body: string(body)}
+		}
+
+		resp = attempt
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// chatPayload builds an /api/chat request
+func (p *OllamaProvider) chatPayload(messages []Message, input interfaces.GenerationRequest, stream bool) map[string]interface{} {
+	payload := map[string]interface{}{
+		"model":    p.modelName,
+		"messages": messages,
+		"stream":   stream,
+		"options":  requestOptions(input),
+	}
+	if p.keepAlive != nil {
+		payload["keep_alive"] = p.keepAlive
+	}
+	return payload
+}
+
+// generatePayload builds an /api/generate request. System messages become the
+// system prompt and the other messages are joined into the prompt.
+func (p *OllamaProvider) generatePayload(messages []Message, input interfaces.GenerationRequest, stream bool) map[string]interface{} {
+	var system, prompt []string
+	for _, msg := range messages {
+		if msg.Role == "system" {
+			system = append(system, msg.Content)
+		} else {
+			prompt = append(prompt, msg.Content)
+		}
+	}
+
+	payload := map[string]interface{}{
+		"model":   p.modelName,
+		"prompt":  strings.Join(prompt, "\n\n"),
+		"stream":  stream,
+		"options": requestOptions(input),
+	}
+	if len(system) > 0 {
+		payload["system"] = strings.Join(system, "\n\n")
+	}
+	if p.keepAlive != nil {
+		payload["keep_alive"] = p.keepAlive
+	}
+	return payload
+}
+
+// requestOptions maps the sampling parameters of a request onto Ollama's
+// options block. Parameters left at zero are omitted so the model's defaults
+// apply; temperature is always sent, as the other providers do.
+func requestOptions(input interfaces.GenerationRequest) map[string]interface{} {
+	options := map[string]interface{}{
+		"temperature": input.Temperature,
+	}
+
+	if input.MaxTokens > 0 {
+		options["num_predict"] = input.MaxTokens
+	}
+	if len(input.StopTokens) > 0 {
+		options["stop"] = input.StopTokens
+	}
+	if input.TopP != 0 {
+		options["top_p"] = input.TopP
+	}
+	if input.TopK != 0 {
+		options["top_k"] = input.TopK
+	}
+	if input.RepeatPenalty != 0 {
+		options["repeat_penalty"] = input.RepeatPenalty
+	}
+	if input.PresencePenalty != 0 {
+		options["presence_penalty"] = input.PresencePenalty
+	}
+	if input.FrequencyPenalty != 0 {
+		options["frequency_penalty"] = input.FrequencyPenalty
+	}
+	if input.Seed != nil {
+		options["seed"] = *input.Seed
+	}
+
+	return options
+}
+
+// parseMessages returns the request's Messages when set. Otherwise it reads
+// a prompt holding a JSON list of messages and treats any other prompt as a
+// single user message.
+func parseMessages(input interfaces.GenerationRequest) []Message {
+	if len(input.Messages) > 0 {
+		messages := make([]Message, len(input.Messages))
+		for i, msg := range input.Messages {
+			messages[i] = Message{Role: msg.Role, Content: msg.Content}
+		}
+		return messages
+	}
+
+	var messages []Message
+	if err := json.Unmarshal([]byte(input.Prompt), &messages); err == nil && len(messages) > 0 {
+		return messages
+	}
+
+	return []Message{
+		{Role: "user", Content: input.Prompt},
+	}
+}
+
+// ollamaResponse is a response from /api/chat or /api/generate, or one line
+// of their NDJSON stream. The last line has done set and reports why
+// generation ended.
+type ollamaResponse struct {
+	Message    *Message `json:"message,omitempty"`
+	Response   string   `json:"response"`
+	Done       bool     `json:"done"`
+	DoneReason string   `json:"done_reason"`
+	EvalCount  int      `json:"eval_count"`
+	Error      string   `json:"error"`
+}
+
+// text returns the generated text of a chat or generate response
+func (r ollamaResponse) text() string {
+	if r.Message != nil {
+		return r.Message.Content
+	}
+	return r.Response
+}
+
+// streamChunks forwards each line of an NDJSON stream as soon as it is read,
+// then sends the final chunk and closes chunks. It gives up without a final
+// chunk when ctx is cancelled.
+func (p *OllamaProvider) streamChunks(ctx context.Context, resp *http.Response, chunks chan<- interfaces.GenerationChunk) {
+	defer close(chunks)
+	defer resp.Body.Close()
+
+	send := func(chunk interfaces.GenerationChunk) bool {
+		select {
+		case chunks <- chunk:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	var text strings.Builder
+	final := interfaces.GenerationChunk{Done: true, Model: p.modelName}
+	done := false
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var event ollamaResponse
+		if err := json.Unmarshal(line, &event); err != nil {
+			continue
+		}
+		if event.Error != "" {
+			final.Error = event.Error
+			break
+		}
+
+		if delta := event.text(); delta != "" {
+			text.WriteString(delta)
+			if !send(interfaces.GenerationChunk{Delta: delta, Model: p.modelName}) {
+				return
+			}
+		}
+
+		if event.Done {
+			done = true
+			final.Tokens = event.EvalCount
+			final.FinishReason = "stop"
+			if event.DoneReason == "length" {
+				final.FinishReason = "length"
+			}
+			break
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		final.Error = fmt.Sprintf("failed to read streaming response: %v", err)
+	} else if !done && final.Error == "" {
+		final.Error = "stream ended before Ollama reported done"
+	}
+
+	if final.Tokens == 0 {
+		final.Tokens = p.tokenizer.CountTokens(text.String())
+	}
+
+	send(final)
+}
+
+// HealthCheck lists the local models with /api/tags and fails when the
+// configured model has not been pulled
+func (p *OllamaProvider) HealthCheck() error {
+	// Report Ollama as down while requests are failing fast
+	if err := p.breaker.Err(); err != nil {
+		return fmt.Errorf("health check failed: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", p.endpoint+"/api/tags", nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("health check failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("health check failed with status: %d", resp.StatusCode)
+	}
+
+	var tags struct {
+		Models []struct {
+			Name  string `json:"name"`
+			Model string `json:"model"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return fmt.Errorf("health check failed: invalid /api/tags response: %w", err)
+	}
+
+	for _, model := range tags.Models {
+		if sameModel(model.Name, p.modelName) || sameModel(model.Model, p.modelName) {
+			return nil
+		}
+	}
+	return fmt.Errorf("model %s not pulled; run: ollama pull %s", p.modelName, p.modelName)
+}
+
+// sameModel compares model names, treating a name without a tag as :latest
+// the way Ollama does
+func sameModel(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	return withTag(a) == withTag(b)
+}
+
+func withTag(name string) string {
+	if strings.Contains(name, ":") {
+		return name
+	}
+	return name + ":latest"
+}
+
+// getInt reads a number that may arrive as an int or as a JSON float64
+func getInt(values map[string]interface{}, key string) (int, bool) {
+	switch v := values[key].(type) {
+	case int:
+		return v, true
+	case int64:
+		return int(v), true
+	case float64:
+		return int(v), true
+	}
+	return 0, false
+}
+
+func (p *OllamaProvider) Shutdown() error {
+	// No cleanup needed for HTTP client
+	return nil
+}
+
+// Export the provider for plugin loading
+var Provider interfaces.Provider = NewOllamaProvider()
+
+// OllamaProvider streams responses to callers that ask for them
+var _ interfaces.StreamingProvider = (*OllamaProvider)(nil)
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+func newTestProvider(t *testing.T, config map[string]interface{}) *OllamaProvider {
+	t.Helper()
+	provider := NewOllamaProvider()
+	if err := provider.Initialize(config); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	return provider
+}
+
+// writeLine sends one NDJSON line and flushes it to the client
+func writeLine(w http.ResponseWriter, line map[string]interface{}) {
+	data, _ := json.Marshal(line)
+	fmt.Fprintf(w, "%s\n", data)
+	w.(http.Flusher).Flush()
+}
+
+func TestGenerate_ChatRequest(t *testing.T) {
+	var payload map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("Expected /api/chat, got %s", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message":    map[string]interface{}{"role": "assistant", "content": "Hello!"},
+			"done":       true,
+			"eval_count": 3,
+		})
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(t, map[string]interface{}{
+		"endpoint":   server.URL,
+		"model_name": "qwen2.5:7b",
+		"keep_alive": "10m",
+	})
+	prompt := `[{"role":"system","content":"Be brief."},{"role":"user","content":"Hi"}]`
+	response, err := provider.Generate(context.Background(), interfaces.GenerationRequest{
+		Prompt:      prompt,
+		MaxTokens:   64,
+		Temperature: 0.2,
+		StopTokens:  []string{"</answer>"},
+	})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if response.Text != "Hello!" || response.Tokens != 3 || !response.Finished || response.Model != "qwen2.5:7b" {
+		t.Errorf("Unexpected response: %+v", response)
+	}
+
+	// Messages from the prompt are sent as chat messages
+	messages := payload["messages"].([]interface{})
+	if len(messages) != 2 || messages[0].(map[string]interface{})["role"] != "system" {
+		t.Errorf("Expected the prompt's messages, got %v", payload["messages"])
+	}
+	if payload["model"] != "qwen2.5:7b" || payload["stream"] != false || payload["keep_alive"] != "10m" {
+		t.Errorf("Unexpected request: %v", payload)
+	}
+
+	options := payload["options"].(map[string]interface{})
+	if options["num_predict"] != float64(64) || options["temperature"] != 0.2 {
+		t.Errorf("Expected num_predict and temperature in options, got %v", options)
+	}
+	if stop := options["stop"].([]interface{}); len(stop) != 1 || stop[0] != "</answer>" {
+		t.Errorf("Expected stop tokens in options, got %v", options["stop"])
+	}
+	if _, ok := options["top_k"]; ok {
+		t.Errorf("Expected unset parameters to be omitted, got %v", options)
+	}
+}
+
+func TestGenerate_StructuredMessages(t *testing.T) {
+	var payload map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&payload)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": map[string]interface{}{"role": "assistant", "content": "6"},
+			"done":    true,
+		})
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(t, map[string]interface{}{"endpoint": server.URL, "model_name": "qwen2.5:7b"})
+	conversation := []interfaces.ChatMessage{
+		{Role: "user", Content: "What is 2+2?"},
+		{Role: "assistant", Content: "4"},
+		{Role: "user", Content: "And 3+3?"},
+	}
+	if _, err := provider.Generate(context.Background(), interfaces.GenerationRequest{Prompt: "And 3+3?", Messages: conversation}); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	messages := payload["messages"].([]interface{})
+	if len(messages) != len(conversation) {
+		t.Fatalf("Expected the whole conversation, got %v", messages)
+	}
+	for i, msg := range conversation {
+		sent := messages[i].(map[string]interface{})
+		if sent["role"] != msg.Role || sent["content"] != msg.Content {
+			t.Errorf("Message %d: expected %+v, got %v", i, msg, sent)
+		}
+	}
+}
+
+func TestGenerate_GenerateAPI(t *testing.T) {
+	var payload map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/generate" {
+			t.Errorf("Expected /api/generate, got %s", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		json.NewEncoder(w).Encode(map[string]interface{}{"response": "The answer is 42.", "done": true})
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(t, map[string]interface{}{
+		"endpoint":   server.URL,
+		"model_name": "llama3",
+		"api":        "generate",
+	})
+	prompt := `[{"role":"system","content":"Answer with a number."},{"role":"user","content":"6*7?"}]`
+	response, err := provider.Generate(context.Background(), interfaces.GenerationRequest{Prompt: prompt})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if response.Text != "The answer is 42." || response.Tokens == 0 {
+		t.Errorf("Unexpected response: %+v", response)
+	}
+	if payload["system"] != "Answer with a number." || payload["prompt"] != "6*7?" {
+		t.Errorf("Expected system and prompt fields, got %v", payload)
+	}
+	if _, ok := payload["keep_alive"]; ok {
+		t.Errorf("Expected keep_alive to be left to Ollama, got %v", payload["keep_alive"])
+	}
+}
+
+func TestGenerateStream_NDJSON(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		if payload["stream"] != true {
+			t.Errorf("Expected a streaming request, got %v", payload)
+		}
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		writeLine(w, map[string]interface{}{"message": map[string]interface{}{"role": "assistant", "content": "Hel"}, "done": false})
+
+		// Hold the stream open until the client has seen the first chunk
+		select {
+		case <-release:
+		case <-r.Context().Done():
+			return
+		}
+
+		writeLine(w, map[string]interface{}{"message": map[string]interface{}{"role": "assistant", "content": "lo"}, "done": false})
+		writeLine(w, map[string]interface{}{"message": map[string]interface{}{"role": "assistant", "content": ""}, "done": true, "done_reason": "length", "eval_count": 2})
+	}))
+	defer server.Close()
+	defer close(release)
+
+	provider := newTestProvider(t, map[string]interface{}{"endpoint": server.URL, "model_name": "qwen2.5:7b"})
+	chunks, err := provider.GenerateStream(context.Background(), interfaces.GenerationRequest{Prompt: "Hi"})
+	if err != nil {
+		t.Fatalf("GenerateStream failed: %v", err)
+	}
+
+	receive := func() interfaces.GenerationChunk {
+		t.Helper()
+		select {
+		case chunk, ok := <-chunks:
+			if !ok {
+				t.Fatal("Stream closed early")
+			}
+			return chunk
+		case <-time.After(2 * time.Second):
+			t.Fatal("Timed out waiting for a chunk")
+		}
+		return interfaces.GenerationChunk{}
+	}
+
+	// The first chunk must arrive while Ollama is still generating
+	if chunk := receive(); chunk.Delta != "Hel" || chunk.Done {
+		t.Fatalf("Expected first delta before the stream ends, got %+v", chunk)
+	}
+	release <- struct{}{}
+
+	if chunk := receive(); chunk.Delta != "lo" {
+		t.Errorf("Expected second delta, got %+v", chunk)
+	}
+	final := receive()
+	if !final.Done || final.Tokens != 2 || final.FinishReason != "length" || final.Error != "" {
+		t.Errorf("Expected final chunk with token count and finish reason, got %+v", final)
+	}
+}
+
+func TestGenerate_StreamReportsErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeLine(w, map[string]interface{}{"error": "model runner crashed"})
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(t, map[string]interface{}{"endpoint": server.URL, "model_name": "qwen2.5:7b"})
+	_, err := provider.Generate(context.Background(), interfaces.GenerationRequest{Prompt: "Hi", Stream: true})
+	if err == nil || !strings.Contains(err.Error(), "model runner crashed") {
+		t.Errorf("Expected the stream error, got %v", err)
+	}
+}
+
+func TestHealthCheck_ModelPulled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/tags" {
+			t.Errorf("Expected /api/tags, got %s", r.URL.Path)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"models": []map[string]interface{}{
+				{"name": "llama3:latest", "model": "llama3:latest"},
+				{"name": "qwen2.5:7b", "model": "qwen2.5:7b"},
+			},
+		})
+	}))
+	defer server.Close()
+
+	for _, model := range []string{"qwen2.5:7b", "llama3"} {
+		provider := newTestProvider(t, map[string]interface{}{"endpoint": server.URL, "model_name": model})
+		if err := provider.HealthCheck(); err != nil {
+			t.Errorf("Expected %s to be found, got %v", model, err)
+		}
+	}
+
+	provider := newTestProvider(t, map[string]interface{}{"endpoint": server.URL, "model_name": "qwen2.5:14b"})
+	err := provider.HealthCheck()
+	if err == nil || !strings.Contains(err.Error(), "model qwen2.5:14b not pulled") {
+		t.Errorf("Expected a not pulled error, got %v", err)
+	}
+}
+
+func TestInitialize_RequiresModel(t *testing.T) {
+	if err := NewOllamaProvider().Initialize(map[string]interface{}{}); err == nil {
+		t.Error("Expected a missing model_name to be rejected")
+	}
+	if err := NewOllamaProvider().Initialize(map[string]interface{}{"model_name": "llama3", "api": "completions"}); err == nil {
+		t.Error("Expected an unknown api to be rejected")
+	}
+}
@@ -0,0 +1,7 @@
+module github.com/AgentForgeEngine/AgentForgeEngine/providers/openai-compat
+
+go 1.24.0
+
+require github.com/AgentForgeEngine/AgentForgeEngine v0.0.0
+
+replace github.com/AgentForgeEngine/AgentForgeEngine => ../..
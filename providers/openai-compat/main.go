@@ -0,0 +1,510 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/retry"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/stream"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/streamstats"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/tokenizer"
+)
+
+// OpenAICompatProvider serves models behind an OpenAI-compatible
+// /v1/chat/completions endpoint, such as vLLM, Ollama, or LM Studio
+type OpenAICompatProvider struct {
+	name string
+	// baseURL is the API root the /chat/completions and /models paths are
+	// added to, including any /v1 prefix
+	baseURL   string
+	modelName string
+	apiKey    string
+	headers   map[string]string
+	// streamUsage asks the server to report token usage at the end of a
+	// stream, which not every server supports
+	streamUsage bool
+	timeout     time.Duration
+	client      *http.Client
+	tokenizer   tokenizer.Tokenizer
+	retryPolicy retry.Policy
+	breaker     *retry.Breaker
+}
+
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+func NewOpenAICompatProvider() *OpenAICompatProvider {
+	return &OpenAICompatProvider{
+		name:        "openai-compat",
+		baseURL:     "http://localhost:8000/v1",
+		streamUsage: true,
+		timeout:     120 * time.Second,
+		tokenizer:   tokenizer.NewHeuristic(),
+		retryPolicy: retry.DefaultPolicy(),
+		breaker:     retry.BreakerFromConfig(nil),
+	}
+}
+
+func (p *OpenAICompatProvider) Name() string {
+	return p.name
+}
+
+func (p *OpenAICompatProvider) Initialize(config map[string]interface{}) error {
+	// Parse configuration
+	if baseURL, ok := config["base_url"].(string); ok && baseURL != "" {
+		p.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+
+	if modelName, ok := config["model_name"].(string); ok && modelName != "" {
+		p.modelName = modelName
+	} else {
+		return fmt.Errorf("model_name not specified in config")
+	}
+
+	if timeout, ok := getInt(config, "timeout"); ok && timeout > 0 {
+		p.timeout = time.Duration(timeout) * time.Second
+	}
+
+	if streamUsage, ok := config["stream_usage"].(bool); ok {
+		p.streamUsage = streamUsage
+	}
+
+	// Credentials and headers for hosted endpoints and gateways
+	headers, err := getHeaders(config, "headers")
+	if err != nil {
+		return err
+	}
+	p.headers = headers
+	p.apiKey, _ = config["api_key"].(string)
+
+	// Tokenizer used to count tokens when the server does not report usage
+	tok, err := tokenizer.FromConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to load tokenizer: %w", err)
+	}
+	p.tokenizer = tok
+
+	// Retry transient failures and fail fast while the server is down
+	p.retryPolicy = retry.PolicyFromConfig(config)
+	p.breaker = retry.BreakerFromConfig(config)
+
+	// Setup HTTP client
+	p.client = &http.Client{
+		Timeout: p.timeout,
+	}
+
+	log.Printf("OpenAI-compatible provider initialized: base_url=%s, model=%s, headers=%v, auth=%s",
+		p.baseURL, p.modelName, headerNames(p.headers), p.authSummary())
+	return nil
+}
+
+func (p *OpenAICompatProvider) Generate(ctx context.Context, input interfaces.GenerationRequest) (*interfaces.GenerationResponse, error) {
+	// Streaming requests are read chunk by chunk and joined
+	if input.Stream {
+		chunks, err := p.GenerateStream(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		return stream.Collect(ctx, chunks, p.modelName)
+	}
+
+	resp, err := p.send(ctx, input, false)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var response chatCompletion
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if response.Error != nil {
+		return nil, errors.New(response.Error.Message)
+	}
+	if len(response.Choices) == 0 {
+		return nil, fmt.Errorf("response has no choices")
+	}
+
+	choice := response.Choices[0]
+	text := ""
+	if choice.Message != nil {
+		text = choice.Message.Content
+	}
+
+	result := &interfaces.GenerationResponse{
+		Text:         text,
+		Finished:     choice.FinishReason != "",
+		FinishReason: finishReason(choice.FinishReason),
+		Model:        p.modelName,
+	}
+	if response.Usage != nil {
+		result.Tokens = response.Usage.CompletionTokens
+		result.PromptTokens = response.Usage.PromptTokens
+	}
+	if result.Tokens == 0 {
+		result.Tokens = p.tokenizer.CountTokens(text)
+	}
+	return result, nil
+}
+
+// GenerateStream starts a streaming request and returns a channel that yields
+// each chunk of text as the server sends it. The channel is closed after a
+// final chunk carrying the token counts and finish reason.
+func (p *OpenAICompatProvider) GenerateStream(ctx context.Context, input interfaces.GenerationRequest) (<-chan interfaces.GenerationChunk, error) {
+	meter := streamstats.NewMeter()
+	resp, err := p.send(ctx, input, true)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := make(chan interfaces.GenerationChunk)
+	go p.streamChunks(ctx, resp, meter, chunks)
+	return chunks, nil
+}
+
+// send posts a chat completion request, retrying connection failures and
+// server errors. A stream is only retried until the server accepts it, so no
+// output is ever repeated.
+func (p *OpenAICompatProvider) send(ctx context.Context, input interfaces.GenerationRequest, stream bool) (*http.Response, error) {
+	payload := p.chatPayload(parseMessages(input), input, stream)
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	var resp *http.Response
+	err = p.retryPolicy.Do(ctx, p.breaker, func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/chat/completions", bytes.NewReader(jsonData))
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if stream {
+			req.Header.Set("Accept", "text/event-stream")
+		}
+		p.setHeaders(req)
+
+		attempt, err := p.client.Do(req)
+		if err != nil {
+			return fmt.Errorf("request failed: %w", err)
+		}
+
+		if attempt.StatusCode != http.StatusOK {
+			defer attempt.Body.Close()
+			body, _ := io.ReadAll(attempt.Body)
+			return &retry.StatusError{StatusCode: attempt.StatusCode, Body: string(body)}
+		}
+
+		resp = attempt
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// chatPayload builds a /chat/completions request. Sampling parameters left at
+// zero are omitted so the server's defaults apply; temperature is always
+// sent, as the other providers do. The OpenAI schema has no top_k or repeat
+// penalty, so those are not sent.
+func (p *OpenAICompatProvider) chatPayload(messages []Message, input interfaces.GenerationRequest, stream bool) map[string]interface{} {
+	payload := map[string]interface{}{
+		"model":       p.modelName,
+		"messages":    messages,
+		"temperature": input.Temperature,
+		"stream":      stream,
+	}
+
+	if input.MaxTokens > 0 {
+		payload["max_tokens"] = input.MaxTokens
+	}
+	if len(input.StopTokens) > 0 {
+		payload["stop"] = input.StopTokens
+	}
+	if input.TopP != 0 {
+		payload["top_p"] = input.TopP
+	}
+	if input.PresencePenalty != 0 {
+		payload["presence_penalty"] = input.PresencePenalty
+	}
+	if input.FrequencyPenalty != 0 {
+		payload["frequency_penalty"] = input.FrequencyPenalty
+	}
+	if input.Seed != nil {
+		payload["seed"] = *input.Seed
+	}
+	if stream && p.streamUsage {
+		payload["stream_options"] = map[string]interface{}{"include_usage": true}
+	}
+
+	return payload
+}
+
+// parseMessages returns the request's Messages when set. Otherwise it reads
+// a prompt holding a JSON list of messages and treats any other prompt as a
+// single user message.
+func parseMessages(input interfaces.GenerationRequest) []Message {
+	if len(input.Messages) > 0 {
+		messages := make([]Message, len(input.Messages))
+		for i, msg := range input.Messages {
+			messages[i] = Message{Role: msg.Role, Content: msg.Content}
+		}
+		return messages
+	}
+
+	var messages []Message
+	if err := json.Unmarshal([]byte(input.Prompt), &messages); err == nil && len(messages) > 0 {
+		return messages
+	}
+
+	return []Message{
+		{Role: "user", Content: input.Prompt},
+	}
+}
+
+// chatCompletion is a /chat/completions response, or one event of its
+// stream, where each choice carries a delta instead of a message. Streams
+// that include usage end with an event holding usage and no choices.
+type chatCompletion struct {
+	Choices []struct {
+		Message      *Message `json:"message,omitempty"`
+		Delta        *Message `json:"delta,omitempty"`
+		FinishReason string   `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage,omitempty"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// finishReason maps an OpenAI finish reason onto "length" when generation hit
+// the token limit and "stop" otherwise
+func finishReason(reason string) string {
+	if reason == "length" {
+		return "length"
+	}
+	return "stop"
+}
+
+// streamChunks forwards each event of a server-sent event stream as soon as
+// it is read, then sends the final chunk and closes chunks. The final chunk
+// carries the time to first token and token rate measured by meter. It gives
+// up without a final chunk when ctx is cancelled.
+func (p *OpenAICompatProvider) streamChunks(ctx context.Context, resp *http.Response, meter *streamstats.Meter, chunks chan<- interfaces.GenerationChunk) {
+	defer close(chunks)
+	defer resp.Body.Close()
+
+	send := func(chunk interfaces.GenerationChunk) bool {
+		select {
+		case chunks <- chunk:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	var text strings.Builder
+	final := interfaces.GenerationChunk{Done: true, Model: p.modelName}
+	done := false
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			done = true
+			break
+		}
+
+		var event chatCompletion
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			continue
+		}
+		if event.Error != nil {
+			final.Error = event.Error.Message
+			break
+		}
+		if event.Usage != nil {
+			final.Tokens = event.Usage.CompletionTokens
+			final.PromptTokens = event.Usage.PromptTokens
+		}
+
+		for _, choice := range event.Choices {
+			if choice.Delta != nil && choice.Delta.Content != "" {
+				meter.Chunk()
+				text.WriteString(choice.Delta.Content)
+				if !send(interfaces.GenerationChunk{Delta: choice.Delta.Content, Model: p.modelName}) {
+					return
+				}
+			}
+			// Usage, when requested, follows the event that finishes the
+			// choice, so reading goes on until [DONE]
+			if choice.FinishReason != "" {
+				final.FinishReason = finishReason(choice.FinishReason)
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		final.Error = fmt.Sprintf("failed to read streaming response: %v", err)
+	} else if final.Error == "" && final.FinishReason == "" {
+		if !done {
+			final.Error = "stream ended before the server finished the response"
+		} else {
+			final.FinishReason = "stop"
+		}
+	}
+
+	if final.Tokens == 0 {
+		final.Tokens = p.tokenizer.CountTokens(text.String())
+	}
+	final.Stats = meter.Stats(final.Tokens)
+
+	send(final)
+}
+
+// HealthCheck lists the served models with /models and fails when the
+// configured model is not among them
+func (p *OpenAICompatProvider) HealthCheck() error {
+	// Report the server as down while requests are failing fast
+	if err := p.breaker.Err(); err != nil {
+		return fmt.Errorf("health check failed: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/models", nil)
+	if err != nil {
+		return err
+	}
+	p.setHeaders(req)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("health check failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("health check failed with status: %d", resp.StatusCode)
+	}
+
+	var models struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&models); err != nil {
+		return fmt.Errorf("health check failed: invalid /models response: %w", err)
+	}
+
+	for _, model := range models.Data {
+		if model.ID == p.modelName {
+			return nil
+		}
+	}
+	return fmt.Errorf("model %s is not served by %s", p.modelName, p.baseURL)
+}
+
+// setHeaders adds the configured headers and the API key, as a bearer token,
+// to a request
+func (p *OpenAICompatProvider) setHeaders(req *http.Request) {
+	for name, value := range p.headers {
+		req.Header.Set(name, value)
+	}
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+}
+
+// authSummary describes the credentials for logs without revealing the key
+func (p *OpenAICompatProvider) authSummary() string {
+	if p.apiKey == "" {
+		return "none"
+	}
+	return "bearer (key redacted)"
+}
+
+// getHeaders reads a map of header names to string values
+func getHeaders(config map[string]interface{}, key string) (map[string]string, error) {
+	headers := make(map[string]string)
+	switch raw := config[key].(type) {
+	case nil:
+	case map[string]string:
+		for name, value := range raw {
+			headers[name] = value
+		}
+	case map[string]interface{}:
+		for name, value := range raw {
+			s, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid %s: value of %s must be a string", key, name)
+			}
+			headers[name] = s
+		}
+	default:
+		return nil, fmt.Errorf("invalid %s: must be a map of header names to values", key)
+	}
+	return headers, nil
+}
+
+// headerNames lists header names, never values, which may hold secrets
+func headerNames(headers map[string]string) []string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// getInt reads a number that may arrive as an int or as a JSON float64
+func getInt(values map[string]interface{}, key string) (int, bool) {
+	switch v := values[key].(type) {
+	case int:
+		return v, true
+	case int64:
+		return int(v), true
+	case float64:
+		return int(v), true
+	}
+	return 0, false
+}
+
+func (p *OpenAICompatProvider) Shutdown() error {
+	// No cleanup needed for HTTP client
+	return nil
+}
+
+// Export the provider for plugin loading
+var Provider interfaces.Provider = NewOpenAICompatProvider()
+
+// OpenAICompatProvider streams responses to callers that ask for them
+var _ interfaces.StreamingProvider = (*OpenAICompatProvider)(nil)
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+func newTestProvider(t *testing.T, config map[string]interface{}) *OpenAICompatProvider {
+	t.Helper()
+	provider := NewOpenAICompatProvider()
+	if err := provider.Initialize(config); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	return provider
+}
+
+// writeEvent sends one server-sent event and flushes it to the client
+func writeEvent(w http.ResponseWriter, event interface{}) {
+	data, ok := event.(string)
+	if !ok {
+		encoded, _ := json.Marshal(event)
+		data = string(encoded)
+	}
+	fmt.Fprintf(w, "data: %s\n\n", data)
+	w.(http.Flusher).Flush()
+}
+
+func TestGenerate_ChatCompletion(t *testing.T) {
+	var payload map[string]interface{}
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("Expected /v1/chat/completions, got %s", r.URL.Path)
+		}
+		authorization = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&payload)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []interface{}{map[string]interface{}{
+				"message":       map[string]interface{}{"role": "assistant", "content": "Hello!"},
+				"finish_reason": "length",
+			}},
+			"usage": map[string]interface{}{"prompt_tokens": 12, "completion_tokens": 3},
+		})
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(t, map[string]interface{}{
+		"base_url":   server.URL + "/v1/",
+		"model_name": "Qwen/Qwen2.5-7B-Instruct",
+		"api_key":    "sk-test",
+	})
+	prompt := `[{"role":"system","content":"Be brief."},{"role":"user","content":"Hi"}]`
+	response, err := provider.Generate(context.Background(), interfaces.GenerationRequest{
+		Prompt:      prompt,
+		MaxTokens:   64,
+		Temperature: 0.2,
+		StopTokens:  []string{"</answer>"},
+		TopK:        40,
+	})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if response.Text != "Hello!" || response.Tokens != 3 || response.PromptTokens != 12 || response.FinishReason != "length" || !response.Finished {
+		t.Errorf("Unexpected response: %+v", response)
+	}
+	if authorization != "Bearer sk-test" {
+		t.Errorf("Expected the API key as a bearer token, got %q", authorization)
+	}
+
+	// Messages from the prompt are sent as chat messages
+	messages := payload["messages"].([]interface{})
+	if len(messages) != 2 || messages[0].(map[string]interface{})["role"] != "system" {
+		t.Errorf("Expected the prompt's messages, got %v", payload["messages"])
+	}
+	if payload["model"] != "Qwen/Qwen2.5-7B-Instruct" || payload["stream"] != false || payload["max_tokens"] != float64(64) || payload["temperature"] != 0.2 {
+		t.Errorf("Unexpected request: %v", payload)
+	}
+	if stop := payload["stop"].([]interface{}); len(stop) != 1 || stop[0] != "</answer>" {
+		t.Errorf("Expected stop tokens, got %v", payload["stop"])
+	}
+	for _, key := range []string{"top_k", "top_p", "stream_options"} {
+		if _, ok := payload[key]; ok {
+			t.Errorf("Expected %s to be omitted, got %v", key, payload)
+		}
+	}
+}
+
+func TestGenerate_StructuredMessages(t *testing.T) {
+	var payload map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&payload)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []interface{}{map[string]interface{}{
+				"message":       map[string]interface{}{"role": "assistant", "content": "Three plus three is six."},
+				"finish_reason": "stop",
+			}},
+		})
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(t, map[string]interface{}{"base_url": server.URL, "model_name": "local"})
+	conversation := []interfaces.ChatMessage{
+		{Role: "user", Content: "What is 2+2?"},
+		{Role: "assistant", Content: "4"},
+		{Role: "user", Content: "And 3+3?"},
+	}
+	response, err := provider.Generate(context.Background(), interfaces.GenerationRequest{Prompt: "And 3+3?", Messages: conversation})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	// Without usage the completion is counted locally
+	if response.Text != "Three plus three is six." || response.Tokens == 0 || response.FinishReason != "stop" {
+		t.Errorf("Unexpected response: %+v", response)
+	}
+
+	messages := payload["messages"].([]interface{})
+	if len(messages) != len(conversation) {
+		t.Fatalf("Expected the whole conversation, got %v", messages)
+	}
+	for i, msg := range conversation {
+		sent := messages[i].(map[string]interface{})
+		if sent["role"] != msg.Role || sent["content"] != msg.Content {
+			t.Errorf("Message %d: expected %+v, got %v", i, msg, sent)
+		}
+	}
+}
+
+func TestGenerateStream(t *testing.T) {
+	var payload map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&payload)
+		w.Header().Set("Content-Type", "text/event-stream")
+		delta := func(content, reason string) map[string]interface{} {
+			choice := map[string]interface{}{"delta": map[string]interface{}{"content": content}}
+			if reason != "" {
+				choice["finish_reason"] = reason
+			}
+			return map[string]interface{}{"choices": []interface{}{choice}}
+		}
+		writeEvent(w, delta("Hel", ""))
+		writeEvent(w, delta("lo", ""))
+		writeEvent(w, delta("", "stop"))
+		writeEvent(w, map[string]interface{}{
+			"choices": []interface{}{},
+			"usage":   map[string]interface{}{"prompt_tokens": 9, "completion_tokens": 2},
+		})
+		writeEvent(w, "[DONE]")
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(t, map[string]interface{}{"base_url": server.URL, "model_name": "local"})
+	chunks, err := provider.GenerateStream(context.Background(), interfaces.GenerationRequest{Prompt: "Hi", Stream: true})
+	if err != nil {
+		t.Fatalf("GenerateStream failed: %v", err)
+	}
+
+	var deltas []string
+	var final interfaces.GenerationChunk
+	for chunk := range chunks {
+		if chunk.Done {
+			final = chunk
+			continue
+		}
+		deltas = append(deltas, chunk.Delta)
+	}
+
+	if strings.Join(deltas, "|") != "Hel|lo" {
+		t.Errorf("Expected each delta as it arrived, got %v", deltas)
+	}
+	if final.Tokens != 2 || final.PromptTokens != 9 || final.FinishReason != "stop" || final.Error != "" || final.Stats == nil {
+		t.Errorf("Unexpected final chunk: %+v", final)
+	}
+	if options, _ := payload["stream_options"].(map[string]interface{}); options["include_usage"] != true || payload["stream"] != true {
+		t.Errorf("Expected a stream asking for usage, got %v", payload)
+	}
+}
+
+func TestGenerate_StreamErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		writeEvent(w, map[string]interface{}{"choices": []interface{}{map[string]interface{}{"delta": map[string]interface{}{"content": "par"}}}})
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(t, map[string]interface{}{"base_url": server.URL, "model_name": "local", "stream_usage": false})
+	if _, err := provider.Generate(context.Background(), interfaces.GenerationRequest{Prompt: "Hi", Stream: true}); err == nil || !strings.Contains(err.Error(), "stream ended") {
+		t.Errorf("Expected a truncated stream to fail, got %v", err)
+	}
+}
+
+func TestGenerate_ClientErrorsAreNotRetried(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error": {"message": "model not found"}}`)
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(t, map[string]interface{}{"base_url": server.URL, "model_name": "missing", "retry_backoff_ms": 1})
+	_, err := provider.Generate(context.Background(), interfaces.GenerationRequest{Prompt: "Hi"})
+	if err == nil || !strings.Contains(err.Error(), "model not found") {
+		t.Fatalf("Expected the server's error, got %v", err)
+	}
+	// The 503 is retried and the 400 that follows is not
+	if calls != 2 {
+		t.Errorf("Expected 2 calls, got %d", calls)
+	}
+}
+
+func TestHealthCheck(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/models" || r.Header.Get("Authorization") != "Bearer sk-test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": []interface{}{map[string]interface{}{"id": "local"}},
+		})
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(t, map[string]interface{}{"base_url": server.URL + "/v1", "model_name": "local", "api_key": "sk-test"})
+	if err := provider.HealthCheck(); err != nil {
+		t.Errorf("Expected a healthy server, got %v", err)
+	}
+
+	provider = newTestProvider(t, map[string]interface{}{"base_url": server.URL + "/v1", "model_name": "other", "api_key": "sk-test"})
+	if err := provider.HealthCheck(); err == nil || !strings.Contains(err.Error(), "not served") {
+		t.Errorf("Expected a missing model to fail, got %v", err)
+	}
+}
+
+func TestInitialize_RequiresModel(t *testing.T) {
+	if err := NewOpenAICompatProvider().Initialize(map[string]interface{}{}); err == nil {
+		t.Error("Expected an error without model_name")
+	}
+}
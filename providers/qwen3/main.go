@@ -4,19 +4,32 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	_ "embed"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
-	"os"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/retry"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/stream"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/streamstats"
 	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/templates"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/tokenizer"
 )
 
+// defaultTemplate is a ChatML template in the qwen3.j2 layout, used when the
+// configured template cannot be found so the provider works without template
+// files
+//
+//go:embed default.j2
+var defaultTemplate string
+
 type Qwen3Provider struct {
 	name          string
 	endpoint      string
@@ -24,6 +37,20 @@ type Qwen3Provider struct {
 	timeout       time.Duration
 	client        *http.Client
 	templateCache *templates.TemplateCache
+	// fallbackWarning logs once that the embedded template is in use
+	fallbackWarning sync.Once
+	tokenizer       tokenizer.Tokenizer
+	retryPolicy     retry.Policy
+	breaker         *retry.Breaker
+	// stopTokens, when configured, replace the stop tokens of the template's
+	// format; requests may add their own
+	stopTokens []string
+
+	// headers are added to every request; authHeader carries apiKey, as a
+	// bearer token when it is Authorization
+	headers    map[string]string
+	apiKey     string
+	authHeader string
 }
 
 type Message struct {
@@ -46,6 +73,9 @@ func NewQwen3Provider() *Qwen3Provider {
 		name:          "qwen3",
 		timeout:       120 * time.Second,
 		templateCache: templates.NewTemplateCache(),
+		tokenizer:     tokenizer.NewHeuristic(),
+		retryPolicy:   retry.DefaultPolicy(),
+		breaker:       retry.BreakerFromConfig(nil),
 	}
 }
 
@@ -69,108 +99,229 @@ func (p *Qwen3Provider) Initialize(config map[string]interface{}) error {
 		p.templatePath = "qwen3"
 	}
 
+	// Tokenizer used to count tokens when the server does not report them
+	tok, err := tokenizer.FromConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to load tokenizer: %w", err)
+	}
+	p.tokenizer = tok
+
+	// Retry transient failures and fail fast while the server is down
+	p.retryPolicy = retry.PolicyFromConfig(config)
+	p.breaker = retry.BreakerFromConfig(config)
+
+	// Stop tokens replace the ones the template's format ends a turn with;
+	// requests add to them
+	stopTokens, err := getStrings(config, "stop")
+	if err != nil {
+		return err
+	}
+	p.stopTokens = stopTokens
+
+	// Headers and credentials for endpoints behind a gateway
+	headers, err := getHeaders(config, "headers")
+	if err != nil {
+		return err
+	}
+	p.headers = headers
+	p.apiKey, _ = config["api_key"].(string)
+	p.authHeader = "Authorization"
+	if authHeader, ok := config["auth_header"].(string); ok && authHeader != "" {
+		p.authHeader = authHeader
+	}
+
 	// Setup HTTP client
 	p.client = &http.Client{
 		Timeout: p.timeout,
 	}
 
-	log.Printf("Qwen3 provider initialized: endpoint=%s, template=%s", p.endpoint, p.templatePath)
+	log.Printf("Qwen3 provider initialized: endpoint=%s, template=%s, tokenizer=%s, headers=%v, auth=%s",
+		p.endpoint, p.templatePath, p.tokenizer.Name(), headerNames(p.headers), p.authSummary())
 	return nil
 }
 
 func (p *Qwen3Provider) Generate(ctx context.Context, input interfaces.GenerationRequest) (*interfaces.GenerationResponse, error) {
-	// Parse messages from prompt
-	messages, err := p.parseMessages(input.Prompt)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse messages: %w", err)
+	// Streaming requests are read chunk by chunk and joined
+	if input.Stream {
+		chunks, err := p.GenerateStream(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		return stream.Collect(ctx, chunks, p.name)
 	}
 
-	// Apply template
-	renderedPrompt, err := p.applyTemplate(messages)
+	resp, err := p.sendCompletion(ctx, input, false)
 	if err != nil {
-		return nil, fmt.Errorf("failed to apply template: %w", err)
+		return nil, err
 	}
+	defer resp.Body.Close()
 
-	// Create llama.cpp request payload
-	payload := map[string]interface{}{
-		"prompt":      renderedPrompt,
-		"n_predict":   input.MaxTokens,
-		"temperature": input.Temperature,
-		"stop":        []string{"<|im_end|>"},
-		"stream":      input.Stream,
+	return p.handleNonStreamingResponse(resp)
+}
+
+// GenerateStream starts a streaming completion and returns a channel that
+// yields each chunk of text as llama.cpp sends it. The channel is closed after
+// a final chunk carrying the token count, finish reason, and timing.
+func (p *Qwen3Provider) GenerateStream(ctx context.Context, input interfaces.GenerationRequest) (<-chan interfaces.GenerationChunk, error) {
+	meter := streamstats.NewMeter()
+	resp, err := p.sendCompletion(ctx, input, true)
+	if err != nil {
+		return nil, err
 	}
 
-	// Add JSON system message header if needed
-	if p.hasJSONSystemMessage(messages) {
-		payload["system"] = p.getJSONSystemMessage(messages)
+	chunks := make(chan interfaces.GenerationChunk)
+	go p.streamChunks(ctx, resp, meter, chunks)
+	return chunks, nil
+}
+
+// sendCompletion renders the prompt and posts it to the llama.cpp completion
+// endpoint. The caller must close the response body.
+func (p *Qwen3Provider) sendCompletion(ctx context.Context, input interfaces.GenerationRequest, stream bool) (*http.Response, error) {
+	messages := parseMessages(input)
+
+	// Apply template
+	renderedPrompt, stopTokens, err := p.applyTemplate(messages)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply template: %w", err)
 	}
 
+	// Create llama.cpp request payload. System messages, including JSON
+	// ones, reach the model only through the rendered prompt.
+	payload := completionPayload(renderedPrompt, stopTokens, input, stream)
+
 	// Serialize payload
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	// Create HTTP request
-	req, err := http.NewRequestWithContext(ctx, "POST", p.endpoint+"/completion", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
+	// Send the request, retrying connection failures and server errors
+	var resp *http.Response
+	err = p.retryPolicy.Do(ctx, p.breaker, func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, "POST", p.endpoint+"/completion", bytes.NewReader(jsonData))
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
 
-	// Set headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "text/event-stream")
+		// Set headers
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "text/event-stream")
+		p.setHeaders(req)
 
-	// Send request
-	resp, err := p.client.Do(req)
+		attempt, err := p.client.Do(req)
+		if err != nil {
+			return fmt.Errorf("request failed: %w", err)
+		}
+
+		if attempt.StatusCode != http.StatusOK {
+			defer attempt.Body.Close()
+			body, _ := io.ReadAll(attempt.Body)
+			return &retry.StatusError{StatusCode: attempt.StatusCode, Body: string(body)}
+		}
+
+		resp = attempt
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
+	return resp, nil
+}
+
+// completionPayload builds a llama.cpp /completion request. Sampling
+// parameters left at zero are omitted so the server defaults apply, and
+// caller stop tokens are added to the provider's stop tokens.
+func completionPayload(prompt string, stop []string, input interfaces.GenerationRequest, stream bool) map[string]interface{} {
+	payload := map[string]interface{}{
+		"prompt":      prompt,
+		"temperature": input.Temperature,
+		"stop":        mergeStopTokens(stop, input.StopTokens),
+		"stream":      stream,
 	}
 
-	// Handle streaming response
-	if input.Stream {
-		return p.handleStreamingResponse(resp)
+	if input.MaxTokens > 0 {
+		payload["n_predict"] = input.MaxTokens
+	}
+	if input.TopP != 0 {
+		payload["top_p"] = input.TopP
+	}
+	if input.TopK != 0 {
+		payload["top_k"] = input.TopK
+	}
+	if input.RepeatPenalty != 0 {
+		payload["repeat_penalty"] = input.RepeatPenalty
+	}
+	if input.PresencePenalty != 0 {
+		payload["presence_penalty"] = input.PresencePenalty
+	}
+	if input.FrequencyPenalty != 0 {
+		payload["frequency_penalty"] = input.FrequencyPenalty
+	}
+	if input.Seed != nil {
+		payload["seed"] = *input.Seed
 	}
 
-	// Handle non-streaming response
-	return p.handleNonStreamingResponse(resp)
+	return payload
 }
 
-func (p *Qwen3Provider) parseMessages(prompt string) ([]Message, error) {
-	// Try to parse as JSON first
+// mergeStopTokens returns the defaults followed by any extra tokens not
+// already present
+func mergeStopTokens(defaults, extra []string) []string {
+	merged := append([]string{}, defaults...)
+	for _, token := range extra {
+		if token == "" {
+			continue
+		}
+		duplicate := false
+		for _, existing := range merged {
+			if existing == token {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			merged = append(merged, token)
+		}
+	}
+	return merged
+}
+
+// parseMessages returns the conversation to render: the request's Messages
+// when set, else a prompt holding a JSON list of messages, else the prompt as
+// a single user message
+func parseMessages(input interfaces.GenerationRequest) []Message {
+	if len(input.Messages) > 0 {
+		messages := make([]Message, len(input.Messages))
+		for i, msg := range input.Messages {
+			messages[i] = Message{Role: msg.Role, Content: msg.Content}
+		}
+		return messages
+	}
+
 	var messages []Message
-	if err := json.Unmarshal([]byte(prompt), &messages); err == nil {
-		return messages, nil
+	if err := json.Unmarshal([]byte(input.Prompt), &messages); err == nil && len(messages) > 0 {
+		return messages
 	}
 
-	// If not JSON, create a simple user message
 	return []Message{
-		{Role: "user", Content: prompt},
-	}, nil
-}
-
-func (p *Qwen3Provider) applyTemplate(messages []Message) (string, error) {
-	// Find template file
-	templateFile, err := templates.FindTemplate(p.templatePath)
-	if err != nil {
-		return "", fmt.Errorf("template not found: %w", err)
+		{Role: "user", Content: input.Prompt},
 	}
+}
 
-	// Read template content
-	content, err := os.ReadFile(templateFile)
+// applyTemplate renders messages with the configured template and returns the
+// prompt with the stop tokens that end the assistant's turn: the configured
+// ones, else those of the template's format
+func (p *Qwen3Provider) applyTemplate(messages []Message) (string, []string, error) {
+	// The cache rereads the template file only after it changes
+	tmpl, err := p.templateCache.GetTemplate(p.templatePath)
 	if err != nil {
-		return "", fmt.Errorf("failed to read template file: %w", err)
+		p.fallbackWarning.Do(func() {
+			log.Printf("Warning: qwen3 template %q unavailable, using the built-in default: %v", p.templatePath, err)
+		})
+		tmpl = templates.NewQwen3Template(defaultTemplate)
 	}
 
-	// Create qwen3 template processor
-	tmpl := templates.NewQwen3Template(string(content))
-
 	// Convert messages to map format
 	msgMaps := make([]map[string]interface{}, len(messages))
 	for i, msg := range messages {
@@ -188,62 +339,128 @@ func (p *Qwen3Provider) applyTemplate(messages []Message) (string, error) {
 	}
 
 	// Render template
-	return tmpl.Render(msgMaps)
-}
+	prompt, err := tmpl.Render(msgMaps)
+	if err != nil {
+		return "", nil, err
+	}
 
-func (p *Qwen3Provider) hasJSONSystemMessage(messages []Message) bool {
-	for _, msg := range messages {
-		if msg.Role == "system" && strings.HasPrefix(msg.Content, "{") {
-			return true
-		}
+	stopTokens := p.stopTokens
+	if len(stopTokens) == 0 {
+		stopTokens = templates.StopTokens(tmpl)
 	}
-	return false
+	return prompt, stopTokens, nil
 }
 
-func (p *Qwen3Provider) getJSONSystemMessage(messages []Message) string {
-	for _, msg := range messages {
-		if msg.Role == "system" && strings.HasPrefix(msg.Content, "{") {
-			return msg.Content
-		}
+// completionResult is a llama.cpp completion response, or one server-sent
+// event of a completion stream. The last event of a stream has stop set and
+// reports why generation ended and how many tokens it used.
+type completionResult struct {
+	Content         string `json:"content"`
+	Stop            bool   `json:"stop"`
+	Stopped         bool   `json:"stopped"`
+	StopType        string `json:"stop_type"`
+	StoppedEOS      bool   `json:"stopped_eos"`
+	StoppedWord     bool   `json:"stopped_word"`
+	StoppedLimit    bool   `json:"stopped_limit"`
+	TokensPredicted int    `json:"tokens_predicted"`
+	TokensEvaluated int    `json:"tokens_evaluated"`
+}
+
+// finished reports whether generation has ended
+func (r completionResult) finished() bool {
+	return r.Stop || r.Stopped || r.StoppedEOS || r.StoppedWord || r.StoppedLimit || r.StopType != ""
+}
+
+// finishReason maps llama.cpp's stop fields to "length" for a completion cut
+// off by the token limit and "stop" for one that ended on its own or on a
+// stop sequence. Newer servers report stop_type; older ones the stopped_*
+// flags.
+func (r completionResult) finishReason() string {
+	switch {
+	case r.StopType == "limit" || r.StoppedLimit:
+		return "length"
+	case r.finished():
+		return "stop"
 	}
 	return ""
 }
 
-func (p *Qwen3Provider) handleStreamingResponse(resp *http.Response) (*interfaces.GenerationResponse, error) {
-	var response strings.Builder
-	scanner := bufio.NewScanner(resp.Body)
+// streamChunks forwards each event of a completion stream as soon as it is
+// read, then sends the final chunk and closes chunks. The final chunk carries
+// the time to first token and token rate measured by meter. It gives up
+// without a final chunk when ctx is cancelled.
+func (p *Qwen3Provider) streamChunks(ctx context.Context, resp *http.Response, meter *streamstats.Meter, chunks chan<- interfaces.GenerationChunk) {
+	defer close(chunks)
+	defer resp.Body.Close()
+
+	send := func(chunk interfaces.GenerationChunk) bool {
+		select {
+		case chunks <- chunk:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
 
+	var text strings.Builder
+	streamed := 0
+	final := interfaces.GenerationChunk{Done: true, Model: p.name}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
 	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.HasPrefix(line, "data: ") {
-			data := strings.TrimPrefix(line, "data: ")
-			if data == "[DONE]" {
-				break
-			}
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			break
+		}
 
-			var chunk struct {
-				Content string `json:"content"`
-				Stopped bool   `json:"stopped"`
-			}
+		var event completionResult
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			continue
+		}
 
-			if err := json.Unmarshal([]byte(data), &chunk); err == nil {
-				response.WriteString(chunk.Content)
-				if chunk.Stopped {
-					break
-				}
+		if event.Content != "" {
+			meter.Chunk()
+			text.WriteString(event.Content)
+			if !send(interfaces.GenerationChunk{Delta: event.Content, Model: p.name}) {
+				return
 			}
 		}
+
+		if !event.finished() {
+			// Intermediate events count the tokens of their own delta
+			streamed += event.TokensPredicted
+			continue
+		}
+
+		// The last event counts the whole completion
+		final.Tokens = event.TokensPredicted
+		final.PromptTokens = event.TokensEvaluated
+		final.FinishReason = event.finishReason()
+		break
 	}
 
 	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("failed to read streaming response: %w", err)
+		if ctx.Err() != nil {
+			return
+		}
+		final.Error = fmt.Sprintf("failed to read streaming response: %v", err)
+	} else if final.FinishReason == "" {
+		final.FinishReason = "stop"
 	}
 
-	return &interfaces.GenerationResponse{
-		Text:     response.String(),
-		Finished: true,
-		Model:    p.name,
-	}, nil
+	if final.Tokens == 0 {
+		final.Tokens = streamed
+	}
+	if final.Tokens == 0 {
+		final.Tokens = p.tokenizer.CountTokens(text.String())
+	}
+	final.Stats = meter.Stats(final.Tokens)
+
+	send(final)
 }
 
 func (p *Qwen3Provider) handleNonStreamingResponse(resp *http.Response) (*interfaces.GenerationResponse, error) {
@@ -252,25 +469,32 @@ func (p *Qwen3Provider) handleNonStreamingResponse(resp *http.Response) (*interf
 		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
-	var response struct {
-		Content string `json:"content"`
-		Stopped bool   `json:"stopped"`
-		Tokens  int    `json:"tokens_predicted"`
-	}
-
+	var response completionResult
 	if err := json.Unmarshal(body, &response); err != nil {
 		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
 
+	tokens := response.TokensPredicted
+	if tokens == 0 {
+		tokens = p.tokenizer.CountTokens(response.Content)
+	}
+
 	return &interfaces.GenerationResponse{
-		Text:     response.Content,
-		Tokens:   response.Tokens,
-		Finished: response.Stopped,
-		Model:    p.name,
+		Text:         response.Content,
+		Tokens:       tokens,
+		PromptTokens: response.TokensEvaluated,
+		Finished:     response.finished(),
+		FinishReason: response.finishReason(),
+		Model:        p.name,
 	}, nil
 }
 
 func (p *Qwen3Provider) HealthCheck() error {
+	// Report the server as down while requests are failing fast
+	if err := p.breaker.Err(); err != nil {
+		return fmt.Errorf("health check failed: %w", err)
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
@@ -278,6 +502,7 @@ func (p *Qwen3Provider) HealthCheck() error {
 	if err != nil {
 		return err
 	}
+	p.setHeaders(req)
 
 	resp, err := p.client.Do(req)
 	if err != nil {
@@ -292,6 +517,83 @@ func (p *Qwen3Provider) HealthCheck() error {
 	return fmt.Errorf("health check failed with status: %d", resp.StatusCode)
 }
 
+// setHeaders adds the configured headers and credentials to a request
+func (p *Qwen3Provider) setHeaders(req *http.Request) {
+	for name, value := range p.headers {
+		req.Header.Set(name, value)
+	}
+	if p.apiKey == "" {
+		return
+	}
+	if strings.EqualFold(p.authHeader, "Authorization") {
+		req.Header.Set(p.authHeader, "Bearer "+p.apiKey)
+	} else {
+		req.Header.Set(p.authHeader, p.apiKey)
+	}
+}
+
+// authSummary describes the credentials for logs without revealing the key
+func (p *Qwen3Provider) authSummary() string {
+	if p.apiKey == "" {
+		return "none"
+	}
+	return p.authHeader + " (key redacted)"
+}
+
+// getHeaders reads a map of header names to string values
+func getHeaders(config map[string]interface{}, key string) (map[string]string, error) {
+	headers := make(map[string]string)
+	switch raw := config[key].(type) {
+	case nil:
+	case map[string]string:
+		for name, value := range raw {
+			headers[name] = value
+		}
+	case map[string]interface{}:
+		for name, value := range raw {
+			s, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid %s: value of %s must be a string", key, name)
+			}
+			headers[name] = s
+		}
+	default:
+		return nil, fmt.Errorf("invalid %s: must be a map of header names to values", key)
+	}
+	return headers, nil
+}
+
+// getStrings reads a list of strings
+func getStrings(config map[string]interface{}, key string) ([]string, error) {
+	switch raw := config[key].(type) {
+	case nil:
+		return nil, nil
+	case []string:
+		return raw, nil
+	case []interface{}:
+		values := make([]string, 0, len(raw))
+		for _, value := range raw {
+			s, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid %s: %v is not a string", key, value)
+			}
+			values = append(values, s)
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("invalid %s: must be a list of strings", key)
+}
+
+// headerNames lists header names, never values, which may hold secrets
+func headerNames(headers map[string]string) []string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 func (p *Qwen3Provider) Shutdown() error {
 	// No cleanup needed for HTTP client
 	return nil
@@ -299,3 +601,6 @@ func (p *Qwen3Provider) Shutdown() error {
 
 // Export the provider for plugin loading
 var Provider interfaces.Provider = NewQwen3Provider()
+
+// Qwen3Provider streams completions to callers that ask for them
+var _ interfaces.StreamingProvider = (*Qwen3Provider)(nil)
@@ -0,0 +1,636 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/retry"
+)
+
+func newTestProvider(t *testing.T, endpoint string) *Qwen3Provider {
+	t.Helper()
+	return newTestProviderWithConfig(t, map[string]interface{}{"endpoint": endpoint})
+}
+
+func newTestProviderWithConfig(t *testing.T, config map[string]interface{}) *Qwen3Provider {
+	t.Helper()
+	provider := NewQwen3Provider()
+	if err := provider.Initialize(config); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	return provider
+}
+
+// writeEvent sends one server-sent event and flushes it to the client
+func writeEvent(w http.ResponseWriter, event map[string]interface{}) {
+	data, _ := json.Marshal(event)
+	fmt.Fprintf(w, "data: %s\n\n", data)
+	w.(http.Flusher).Flush()
+}
+
+func receive(t *testing.T, chunks <-chan interfaces.GenerationChunk) interfaces.GenerationChunk {
+	t.Helper()
+	select {
+	case chunk, ok := <-chunks:
+		if !ok {
+			t.Fatal("Stream closed early")
+		}
+		return chunk
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for a chunk")
+	}
+	return interfaces.GenerationChunk{}
+}
+
+func TestGenerateStream_EmitsChunksIncrementally(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		if payload["stream"] != true {
+			t.Errorf("Expected a streaming request, got %v", payload)
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		writeEvent(w, map[string]interface{}{"content": "Hello", "stop": false})
+
+		// Hold the stream open until the client has seen the first chunk
+		select {
+		case <-release:
+		case <-r.Context().Done():
+			return
+		}
+
+		writeEvent(w, map[string]interface{}{"content": " world", "stop": false})
+		writeEvent(w, map[string]interface{}{"content": "", "stop": true, "stopped_eos": true, "tokens_predicted": 7})
+	}))
+	defer server.Close()
+	defer close(release)
+
+	provider := newTestProvider(t, server.URL)
+	chunks, err := provider.GenerateStream(context.Background(), interfaces.GenerationRequest{Prompt: "Hi", MaxTokens: 16})
+	if err != nil {
+		t.Fatalf("GenerateStream failed: %v", err)
+	}
+
+	// The first chunk must arrive while the server is still generating
+	if chunk := receive(t, chunks); chunk.Delta != "Hello" || chunk.Done {
+		t.Fatalf("Expected first delta before the stream ends, got %+v", chunk)
+	}
+	release <- struct{}{}
+
+	if chunk := receive(t, chunks); chunk.Delta != " world" || chunk.Done {
+		t.Errorf("Expected second delta, got %+v", chunk)
+	}
+	final := receive(t, chunks)
+	if !final.Done || final.Tokens != 7 || final.FinishReason != "stop" || final.Model != "qwen3" {
+		t.Errorf("Expected final chunk with token count and finish reason, got %+v", final)
+	}
+	if _, ok := <-chunks; ok {
+		t.Error("Expected the stream to be closed after the final chunk")
+	}
+}
+
+func TestGenerateStream_LengthLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeEvent(w, map[string]interface{}{"content": "one two", "stop": false})
+		writeEvent(w, map[string]interface{}{"content": "", "stop": true, "stopped_limit": true})
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(t, server.URL)
+	chunks, err := provider.GenerateStream(context.Background(), interfaces.GenerationRequest{Prompt: "Hi", MaxTokens: 2})
+	if err != nil {
+		t.Fatalf("GenerateStream failed: %v", err)
+	}
+
+	receive(t, chunks)
+	final := receive(t, chunks)
+	// Without tokens_predicted the count falls back to the tokenizer
+	if final.FinishReason != "length" || final.Tokens == 0 {
+		t.Errorf("Expected a length finish with counted tokens, got %+v", final)
+	}
+}
+
+func TestGenerateStream_AccumulatesTokens(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeEvent(w, map[string]interface{}{"content": "one", "stop": false, "tokens_predicted": 1})
+		writeEvent(w, map[string]interface{}{"content": " two three", "stop": false, "tokens_predicted": 2})
+		// The last event of an older server may leave the total out
+		writeEvent(w, map[string]interface{}{"content": "", "stop": true, "stop_type": "limit", "tokens_evaluated": 5})
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(t, server.URL)
+	response, err := provider.Generate(context.Background(), interfaces.GenerationRequest{Prompt: "Hi", Stream: true, MaxTokens: 3})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if response.Tokens != 3 || response.PromptTokens != 5 || response.FinishReason != "length" {
+		t.Errorf("Expected 3 completion and 5 prompt tokens cut off by length, got %+v", response)
+	}
+}
+
+func TestGenerate_StreamCollectsChunks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeEvent(w, map[string]interface{}{"content": "Hello", "stop": false})
+		writeEvent(w, map[string]interface{}{"content": " world", "stop": false})
+		writeEvent(w, map[string]interface{}{"content": "", "stop": true, "tokens_predicted": 2})
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(t, server.URL)
+	response, err := provider.Generate(context.Background(), interfaces.GenerationRequest{Prompt: "Hi", Stream: true})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if response.Text != "Hello world" || response.Tokens != 2 || !response.Finished {
+		t.Errorf("Unexpected response: %+v", response)
+	}
+}
+
+func TestGenerateStream_ReportsSpeed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The first token takes 100ms; then six chunks arrive 40ms apart,
+		// 20 tokens after the first over 200ms
+		time.Sleep(100 * time.Millisecond)
+		for i := 0; i < 6; i++ {
+			if i > 0 {
+				time.Sleep(40 * time.Millisecond)
+			}
+			writeEvent(w, map[string]interface{}{"content": "word ", "stop": false})
+		}
+		writeEvent(w, map[string]interface{}{"content": "", "stop": true, "tokens_predicted": 21})
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(t, server.URL)
+	response, err := provider.Generate(context.Background(), interfaces.GenerationRequest{Prompt: "Hi", Stream: true})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	stats := response.Stats
+	if stats == nil {
+		t.Fatal("Expected generation stats on a streamed response")
+	}
+	if stats.TimeToFirstTokenMs < 100 || stats.TimeToFirstTokenMs > 1000 {
+		t.Errorf("Expected about 100ms to the first token, got %vms", stats.TimeToFirstTokenMs)
+	}
+	// Leave room for scheduling delays, which can stretch or bunch chunks
+	if stats.TokensPerSecond < 50 || stats.TokensPerSecond > 150 {
+		t.Errorf("Expected about 100 tokens/s, got %v", stats.TokensPerSecond)
+	}
+	if stats.DurationMs < stats.TimeToFirstTokenMs+200 {
+		t.Errorf("Expected the duration to cover the whole stream, got %+v", stats)
+	}
+}
+
+func TestGenerateStream_Cancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeEvent(w, map[string]interface{}{"content": "Hello", "stop": false})
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(t, server.URL)
+	ctx, cancel := context.WithCancel(context.Background())
+	chunks, err := provider.GenerateStream(ctx, interfaces.GenerationRequest{Prompt: "Hi"})
+	if err != nil {
+		t.Fatalf("GenerateStream failed: %v", err)
+	}
+
+	receive(t, chunks)
+	cancel()
+
+	select {
+	case chunk, ok := <-chunks:
+		if ok {
+			t.Errorf("Expected the stream to close on cancel, got %+v", chunk)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Stream was not closed after cancel")
+	}
+}
+
+func TestCompletionPayload_SamplingParameters(t *testing.T) {
+	seed := 0
+	payload := completionPayload("prompt", []string{"<|im_end|>"}, interfaces.GenerationRequest{
+		MaxTokens:     64,
+		Temperature:   0.2,
+		TopP:          0.9,
+		TopK:          40,
+		RepeatPenalty: 1.1,
+		Seed:          &seed,
+		StopTokens:    []string{"</answer>", "<|im_end|>"},
+	}, false)
+
+	expected := map[string]interface{}{
+		"n_predict":      64,
+		"temperature":    0.2,
+		"top_p":          0.9,
+		"top_k":          40,
+		"repeat_penalty": 1.1,
+		"seed":           0,
+	}
+	for key, value := range expected {
+		if payload[key] != value {
+			t.Errorf("Expected %s=%v, got %v", key, value, payload[key])
+		}
+	}
+
+	// Caller stops are added to the template's stop token without duplicates
+	stop := payload["stop"].([]string)
+	if len(stop) != 2 || stop[0] != "<|im_end|>" || stop[1] != "</answer>" {
+		t.Errorf("Expected merged stop tokens, got %v", stop)
+	}
+
+	// Unset parameters are left to llama.cpp
+	payload = completionPayload("prompt", []string{"<|im_end|>"}, interfaces.GenerationRequest{}, false)
+	for _, key := range []string{"n_predict", "top_p", "top_k", "repeat_penalty", "presence_penalty", "frequency_penalty", "seed"} {
+		if _, ok := payload[key]; ok {
+			t.Errorf("Expected %s to be omitted, got %v", key, payload[key])
+		}
+	}
+}
+
+func TestInitialize_StopTokens(t *testing.T) {
+	provider := NewQwen3Provider()
+	if err := provider.Initialize(map[string]interface{}{"stop": []interface{}{"<|im_end|>", "<|endoftext|>"}}); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	payload := completionPayload("prompt", provider.stopTokens, interfaces.GenerationRequest{StopTokens: []string{"</answer>"}}, false)
+	if stop := payload["stop"].([]string); !reflect.DeepEqual(stop, []string{"<|im_end|>", "<|endoftext|>", "</answer>"}) {
+		t.Errorf("Expected the configured stop tokens and the request's, got %v", stop)
+	}
+
+	// Configured stop tokens replace those of the template's format
+	provider = newTestProviderWithConfig(t, map[string]interface{}{"template_path": "mistral-instruct", "stop": []string{"[/ANSWER]"}})
+	if _, stop, err := provider.applyTemplate([]Message{{Role: "user", Content: "Hi"}}); err != nil || !reflect.DeepEqual(stop, []string{"[/ANSWER]"}) {
+		t.Errorf("Expected the configured stop token, got %v, %v", stop, err)
+	}
+
+	if err := NewQwen3Provider().Initialize(map[string]interface{}{"stop": "<|im_end|>"}); err == nil {
+		t.Error("Expected a stop that is not a list to be rejected")
+	}
+}
+
+// failingServer answers the first failures requests with status and the rest
+// with a completion, counting every request
+func failingServer(failures int32, status int) (*httptest.Server, *int32) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) <= failures {
+			http.Error(w, "backend unavailable", status)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"content": "recovered", "stopped": true, "tokens_predicted": 1})
+	}))
+	return server, &requests
+}
+
+func TestGenerate_RetriesServerErrors(t *testing.T) {
+	server, requests := failingServer(2, http.StatusServiceUnavailable)
+	defer server.Close()
+
+	provider := newTestProviderWithConfig(t, map[string]interface{}{
+		"endpoint":         server.URL,
+		"max_attempts":     3,
+		"retry_backoff_ms": 1,
+	})
+	response, err := provider.Generate(context.Background(), interfaces.GenerationRequest{Prompt: "Hi"})
+	if err != nil {
+		t.Fatalf("Expected the third attempt to succeed, got %v", err)
+	}
+	if response.Text != "recovered" || atomic.LoadInt32(requests) != 3 {
+		t.Errorf("Expected success after 3 requests, got %q after %d", response.Text, atomic.LoadInt32(requests))
+	}
+
+	// Client errors are not retried
+	server, requests = failingServer(1, http.StatusBadRequest)
+	defer server.Close()
+	provider = newTestProviderWithConfig(t, map[string]interface{}{"endpoint": server.URL, "retry_backoff_ms": 1})
+	if _, err := provider.Generate(context.Background(), interfaces.GenerationRequest{Prompt: "Hi"}); err == nil || atomic.LoadInt32(requests) != 1 {
+		t.Errorf("Expected a 400 to fail after one request, got %d requests, %v", atomic.LoadInt32(requests), err)
+	}
+}
+
+func TestGenerate_CircuitBreakerOpens(t *testing.T) {
+	server, requests := failingServer(1000, http.StatusInternalServerError)
+	defer server.Close()
+
+	provider := newTestProviderWithConfig(t, map[string]interface{}{
+		"endpoint":                  server.URL,
+		"max_attempts":              2,
+		"retry_backoff_ms":          1,
+		"circuit_failure_threshold": 3,
+		"circuit_cooldown_seconds":  60,
+	})
+	request := interfaces.GenerationRequest{Prompt: "Hi"}
+
+	if _, err := provider.Generate(context.Background(), request); err == nil || errors.Is(err, retry.ErrCircuitOpen) {
+		t.Fatalf("Expected the first request to fail with the server error, got %v", err)
+	}
+	// The third consecutive failure opens the circuit mid-retry
+	if _, err := provider.Generate(context.Background(), request); !errors.Is(err, retry.ErrCircuitOpen) {
+		t.Fatalf("Expected the circuit to open, got %v", err)
+	}
+	if got := atomic.LoadInt32(requests); got != 3 {
+		t.Errorf("Expected 3 requests before the circuit opened, got %d", got)
+	}
+
+	// While open, requests fail without reaching the server
+	start := time.Now()
+	if _, err := provider.Generate(context.Background(), request); !errors.Is(err, retry.ErrCircuitOpen) {
+		t.Errorf("Expected to fail fast, got %v", err)
+	}
+	if atomic.LoadInt32(requests) != 3 || time.Since(start) > 100*time.Millisecond {
+		t.Errorf("Expected no request while the circuit is open")
+	}
+	if err := provider.HealthCheck(); !errors.Is(err, retry.ErrCircuitOpen) {
+		t.Errorf("Expected HealthCheck to report the open circuit, got %v", err)
+	}
+}
+
+func TestHeadersAndAuth(t *testing.T) {
+	var mu sync.Mutex
+	seen := make(map[string]http.Header)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		stream := strings.Contains(string(body), `"stream":true`)
+
+		mu.Lock()
+		seen[fmt.Sprintf("%s stream=%v", r.URL.Path, stream)] = r.Header.Clone()
+		mu.Unlock()
+
+		switch {
+		case r.URL.Path == "/health":
+			w.WriteHeader(http.StatusOK)
+		case stream:
+			writeEvent(w, map[string]interface{}{"content": "ok", "stop": true})
+		default:
+			json.NewEncoder(w).Encode(map[string]interface{}{"content": "ok", "stopped": true})
+		}
+	}))
+	defer server.Close()
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	provider := newTestProviderWithConfig(t, map[string]interface{}{
+		"endpoint": server.URL,
+		"api_key":  "sk-secret-key",
+		"headers":  map[string]interface{}{"X-Gateway-Tenant": "team-a"},
+	})
+	if strings.Contains(logs.String(), "sk-secret-key") {
+		t.Errorf("API key leaked into logs: %s", logs.String())
+	}
+
+	if _, err := provider.Generate(context.Background(), interfaces.GenerationRequest{Prompt: "Hi"}); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if _, err := provider.Generate(context.Background(), interfaces.GenerationRequest{Prompt: "Hi", Stream: true}); err != nil {
+		t.Fatalf("Streaming Generate failed: %v", err)
+	}
+	if err := provider.HealthCheck(); err != nil {
+		t.Fatalf("HealthCheck failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(seen) != 3 {
+		t.Fatalf("Expected completion, streaming, and health requests, got %d", len(seen))
+	}
+	for request, header := range seen {
+		if got := header.Get("Authorization"); got != "Bearer sk-secret-key" {
+			t.Errorf("%s: expected bearer token, got %q", request, got)
+		}
+		if got := header.Get("X-Gateway-Tenant"); got != "team-a" {
+			t.Errorf("%s: expected custom header, got %q", request, got)
+		}
+	}
+}
+
+func TestCustomAuthHeader(t *testing.T) {
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	provider := newTestProviderWithConfig(t, map[string]interface{}{
+		"endpoint":    server.URL,
+		"api_key":     "key-123",
+		"auth_header": "X-API-Key",
+	})
+	if err := provider.HealthCheck(); err != nil {
+		t.Fatalf("HealthCheck failed: %v", err)
+	}
+	if got.Get("X-API-Key") != "key-123" || got.Get("Authorization") != "" {
+		t.Errorf("Expected the raw key in X-API-Key only, got %v", got)
+	}
+
+	if err := NewQwen3Provider().Initialize(map[string]interface{}{"headers": map[string]interface{}{"X-Retries": 3}}); err == nil {
+		t.Error("Expected a non-string header value to be rejected")
+	}
+}
+
+func TestGenerate_SystemMessagesRenderedOnce(t *testing.T) {
+	var payload map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&payload)
+		json.NewEncoder(w).Encode(map[string]interface{}{"content": "ok", "stopped": true})
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(t, server.URL)
+	for _, system := range []string{
+		`{"response_format": "json", "schema": {"type": "object"}}`,
+		"You are a helpful assistant.",
+	} {
+		messages, _ := json.Marshal([]Message{
+			{Role: "system", Content: system},
+			{Role: "user", Content: "Hi"},
+		})
+		if _, err := provider.Generate(context.Background(), interfaces.GenerationRequest{Prompt: string(messages)}); err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+
+		prompt, _ := payload["prompt"].(string)
+		if strings.Count(prompt, system) != 1 {
+			t.Errorf("Expected the system message once in the rendered prompt, got %q", prompt)
+		}
+		if _, ok := payload["system"]; ok {
+			t.Errorf("Expected no separate system field, got %v", payload["system"])
+		}
+	}
+}
+
+func TestGenerate_StructuredMessages(t *testing.T) {
+	var payload map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&payload)
+		json.NewEncoder(w).Encode(map[string]interface{}{"content": "6", "stopped": true})
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(t, server.URL)
+	request := interfaces.GenerationRequest{
+		Prompt: "And 3+3?",
+		Messages: []interfaces.ChatMessage{
+			{Role: "system", Content: "Answer with a number."},
+			{Role: "user", Content: "What is 2+2?"},
+			{Role: "assistant", Content: "4"},
+			{Role: "user", Content: "And 3+3?"},
+		},
+	}
+	if _, err := provider.Generate(context.Background(), request); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	prompt, _ := payload["prompt"].(string)
+	turns := []string{
+		"Answer with a number.",
+		"<|im_start|>user\nWhat is 2+2?\n<|im_end|>",
+		"<|im_start|>assistant\n4\n<|im_end|>",
+		"<|im_start|>user\nAnd 3+3?\n<|im_end|>",
+	}
+	last := -1
+	for _, turn := range turns {
+		index := strings.Index(prompt, turn)
+		if index <= last {
+			t.Fatalf("Expected %q after the previous turn in the rendered prompt, got %q", turn, prompt)
+		}
+		last = index
+	}
+	if strings.Count(prompt, "And 3+3?") != 1 {
+		t.Errorf("Expected the prompt not to be rendered on top of the messages, got %q", prompt)
+	}
+}
+
+func TestApplyTemplate_ThroughCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "custom.j2")
+	write := func(marker string, modTime time.Time) {
+		content := "<|im_start|>system\n" + marker + " {{ system_prompt }}\n<|im_end|>\n{% for msg in messages %}{% endfor %}"
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("v1", time.Now())
+
+	provider := newTestProviderWithConfig(t, map[string]interface{}{"template_path": path})
+	messages := []Message{{Role: "user", Content: "Hi"}}
+	if prompt, _, err := provider.applyTemplate(messages); err != nil || !strings.Contains(prompt, "v1") {
+		t.Fatalf("Expected the template file to render, got %q, %v", prompt, err)
+	}
+
+	// Edits are picked up without restarting
+	write("v2", time.Now().Add(time.Minute))
+	if prompt, _, err := provider.applyTemplate(messages); err != nil || !strings.Contains(prompt, "v2") {
+		t.Errorf("Expected the edited template to render, got %q, %v", prompt, err)
+	}
+
+	// Built-in variants are selected by name
+	provider = newTestProviderWithConfig(t, map[string]interface{}{"template_path": "llama3"})
+	prompt, stop, err := provider.applyTemplate(messages)
+	if err != nil || !strings.HasPrefix(prompt, "<|begin_of_text|>") {
+		t.Errorf("Expected the llama3 format, got %q, %v", prompt, err)
+	}
+	if !reflect.DeepEqual(stop, []string{"<|eot_id|>"}) {
+		t.Errorf("Expected the llama3 stop token, got %v", stop)
+	}
+}
+
+func TestApplyTemplate_FallsBackToEmbeddedTemplate(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	provider := newTestProviderWithConfig(t, map[string]interface{}{"template_path": "no-such-template"})
+	messages := []Message{
+		{Role: "system", Content: "Answer in French."},
+		{Role: "user", Content: "Hi"},
+	}
+	for i := 0; i < 2; i++ {
+		prompt, stop, err := provider.applyTemplate(messages)
+		if err != nil {
+			t.Fatalf("Expected the embedded template to render, got %v", err)
+		}
+		if !reflect.DeepEqual(stop, []string{"<|im_end|>"}) {
+			t.Errorf("Expected the ChatML stop token, got %v", stop)
+		}
+		for _, part := range []string{"You are a helpful assistant.", "Answer in French.", "<|im_start|>user\nHi\n<|im_end|>"} {
+			if !strings.Contains(prompt, part) {
+				t.Errorf("Expected %q in the rendered prompt, got %q", part, prompt)
+			}
+		}
+		if !strings.HasSuffix(prompt, "<|im_start|>assistant\n") {
+			t.Errorf("Expected the prompt to open the assistant's turn, got %q", prompt)
+		}
+	}
+
+	if count := strings.Count(logs.String(), `qwen3 template "no-such-template" unavailable`); count != 1 {
+		t.Errorf("Expected one fallback warning, got %d in %q", count, logs.String())
+	}
+}
+
+func TestGenerate_UsageAndFinishReason(t *testing.T) {
+	tests := []struct {
+		name     string
+		response map[string]interface{}
+		reason   string
+	}{
+		{"end of sequence", map[string]interface{}{"stop": true, "stop_type": "eos"}, "stop"},
+		{"stop word", map[string]interface{}{"stop": true, "stop_type": "word"}, "stop"},
+		{"token limit", map[string]interface{}{"stop": true, "stop_type": "limit"}, "length"},
+		{"older eos flag", map[string]interface{}{"stopped_eos": true}, "stop"},
+		{"older limit flag", map[string]interface{}{"stopped_limit": true}, "length"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body := map[string]interface{}{"content": "done", "tokens_predicted": 4, "tokens_evaluated": 9}
+				for key, value := range tt.response {
+					body[key] = value
+				}
+				json.NewEncoder(w).Encode(body)
+			}))
+			defer server.Close()
+
+			provider := newTestProvider(t, server.URL)
+			response, err := provider.Generate(context.Background(), interfaces.GenerationRequest{Prompt: "Hi"})
+			if err != nil {
+				t.Fatalf("Generate failed: %v", err)
+			}
+			if response.FinishReason != tt.reason || !response.Finished {
+				t.Errorf("Expected finish reason %q, got %+v", tt.reason, response)
+			}
+			if response.Tokens != 4 || response.PromptTokens != 9 {
+				t.Errorf("Expected 4 completion and 9 prompt tokens, got %d and %d", response.Tokens, response.PromptTokens)
+			}
+		})
+	}
+}
@@ -1,15 +1,22 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"plugin"
+	"reflect"
+	"runtime"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
 	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/testing"
 )
 
@@ -35,17 +42,32 @@ type AgentInfo struct {
 // TestRunner manages testing of agents
 type TestRunner struct {
 	agentsDir string
+	parallel  int
 	results   []TestResult
 	logger    *log.Logger
+
+	// testAgent runs the tests for one agent; it defaults to TestAgent
+	testAgent func(agent AgentInfo) TestResult
 }
 
-// NewTestRunner creates a new test runner
+// NewTestRunner creates a new test runner that tests one agent at a time
 func NewTestRunner(agentsDir string) *TestRunner {
-	return &TestRunner{
+	tr := &TestRunner{
 		agentsDir: agentsDir,
+		parallel:  1,
 		results:   make([]TestResult, 0),
 		logger:    log.New(os.Stdout, "[TEST-RUNNER] ", log.LstdFlags),
 	}
+	tr.testAgent = tr.TestAgent
+	return tr
+}
+
+// SetParallel sets how many agents are tested concurrently
+func (tr *TestRunner) SetParallel(n int) {
+	if n < 1 {
+		n = 1
+	}
+	tr.parallel = n
 }
 
 // DiscoverAgents discovers all agents in the agents directory
@@ -104,12 +126,35 @@ func (tr *TestRunner) TestAllAgents() error {
 		return fmt.Errorf("failed to discover agents: %w", err)
 	}
 
-	tr.logger.Printf("Found %d agents to test", len(agents))
+	tr.logger.Printf("Found %d agents to test (parallel: %d)", len(agents), tr.parallel)
+
+	// Test agents on a bounded worker pool; each worker writes only its own
+	// slot so results need no locking
+	results := make([]TestResult, len(agents))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
 
-	for _, agent := range agents {
-		result := tr.TestAgent(agent)
-		tr.results = append(tr.results, result)
+	for w := 0; w < tr.parallel; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = tr.testAgent(agents[i])
+			}
+		}()
+	}
+
+	for i := range agents {
+		jobs <- i
 	}
+	close(jobs)
+	wg.Wait()
+
+	// Report in a stable order however the workers finished
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].AgentName < results[j].AgentName
+	})
+	tr.results = append(tr.results, results...)
 
 	return nil
 }
@@ -221,11 +266,9 @@ func (tr *TestRunner) testModelResponseParsing(agent AgentInfo) error {
 	return nil
 }
 
-// testInterfaceCompliance tests interface compliance
+// testInterfaceCompliance builds the agent as a plugin, opens it, and checks
+// that the exported Agent symbol satisfies interfaces.Agent
 func (tr *TestRunner) testInterfaceCompliance(agent AgentInfo) error {
-	// This is a basic compliance test
-	// In a real implementation, you might load the plugin and test the interface
-
 	if !agent.HasMain {
 		return fmt.Errorf("agent missing main.go")
 	}
@@ -234,31 +277,87 @@ func (tr *TestRunner) testInterfaceCompliance(agent AgentInfo) error {
 		return fmt.Errorf("agent missing go.mod")
 	}
 
-	// Check if main.go contains the required exports
-	mainFile := filepath.Join(agent.Path, "main.go")
-	content, err := ioutil.ReadFile(mainFile)
+	buildDir, err := os.MkdirTemp("", "afe-compliance-"+agent.Name+"-")
 	if err != nil {
-		return fmt.Errorf("failed to read main.go: %w", err)
+		return fmt.Errorf("failed to create build directory: %w", err)
 	}
+	defer os.RemoveAll(buildDir)
 
-	contentStr := string(content)
-	requiredExports := []string{
-		"var Agent interfaces.Agent",
-		"func New" + strings.Title(agent.Name) + "Agent",
-		"func (a *" + strings.Title(agent.Name) + "Agent) Name() string",
-		"func (a *" + strings.Title(agent.Name) + "Agent) Initialize",
-		"func (a *" + strings.Title(agent.Name) + "Agent) Process",
-		"func (a *" + strings.Title(agent.Name) + "Agent) HealthCheck",
-		"func (a *" + strings.Title(agent.Name) + "Agent) Shutdown",
+	// A wrongly typed Agent variable fails here, and the compiler output
+	// names the offending method
+	pluginPath := filepath.Join(buildDir, agent.Name+".so")
+	cmd := exec.Command("go", "build", "-buildmode=plugin", "-o", pluginPath, ".")
+	cmd.Dir = agent.Path
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("plugin build failed: %w\n%s", err, strings.TrimSpace(string(output)))
+	}
+
+	p, err := plugin.Open(pluginPath)
+	if err != nil {
+		return fmt.Errorf("failed to open plugin: %w", err)
 	}
 
-	for _, export := range requiredExports {
-		if !strings.Contains(contentStr, export) {
-			return fmt.Errorf("missing required export: %s", export)
+	symbol, err := p.Lookup("Agent")
+	if err != nil {
+		return fmt.Errorf("plugin does not export Agent: %w", err)
+	}
+
+	return checkAgentSymbol(symbol)
+}
+
+// checkAgentSymbol asserts that an exported Agent variable holds an
+// interfaces.Agent, listing every missing or mistyped method when it does not
+func checkAgentSymbol(symbol plugin.Symbol) error {
+	// Lookup returns a pointer to the exported variable
+	value := reflect.ValueOf(symbol)
+	if value.Kind() == reflect.Ptr && value.Elem().Kind() == reflect.Interface {
+		value = value.Elem()
+	}
+	if value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return fmt.Errorf("exported Agent is nil")
 		}
+		value = value.Elem()
 	}
 
-	return nil
+	if _, ok := value.Interface().(interfaces.Agent); ok {
+		return nil
+	}
+
+	return fmt.Errorf("exported Agent of type %s does not implement interfaces.Agent: %s",
+		value.Type(), strings.Join(methodMismatches(value.Type()), "; "))
+}
+
+// methodMismatches compares the method set of t with interfaces.Agent
+func methodMismatches(t reflect.Type) []string {
+	want := reflect.TypeOf((*interfaces.Agent)(nil)).Elem()
+
+	var problems []string
+	for i := 0; i < want.NumMethod(); i++ {
+		expected := want.Method(i)
+
+		method, ok := t.MethodByName(expected.Name)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("missing method %s %s", expected.Name, expected.Type))
+			continue
+		}
+
+		// Drop the receiver so the signature compares with the interface's
+		in := make([]reflect.Type, 0, method.Type.NumIn()-1)
+		for j := 1; j < method.Type.NumIn(); j++ {
+			in = append(in, method.Type.In(j))
+		}
+		out := make([]reflect.Type, 0, method.Type.NumOut())
+		for j := 0; j < method.Type.NumOut(); j++ {
+			out = append(out, method.Type.Out(j))
+		}
+		actual := reflect.FuncOf(in, out, method.Type.IsVariadic())
+
+		if actual != expected.Type {
+			problems = append(problems, fmt.Sprintf("method %s has signature %s, want %s", expected.Name, actual, expected.Type))
+		}
+	}
+	return problems
 }
 
 // testBuild tests if the agent can be built
@@ -461,12 +560,16 @@ func (tr *TestRunner) SaveResults(outputPath string) error {
 
 // main function
 func main() {
+	parallel := flag.Int("parallel", runtime.NumCPU(), "number of agents to test concurrently")
+	flag.Parse()
+
 	agentsDir := "./agents"
-	if len(os.Args) > 1 {
-		agentsDir = os.Args[1]
+	if flag.NArg() > 0 {
+		agentsDir = flag.Arg(0)
 	}
 
 	runner := NewTestRunner(agentsDir)
+	runner.SetParallel(*parallel)
 
 	// Run all tests
 	if err := runner.TestAllAgents(); err != nil {
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AgentForgeEngine/AgentForgeEngine/pkg/interfaces"
+)
+
+// createAgents writes count minimal agent directories named agent-00, agent-01, ...
+func createAgents(t *testing.T, count int) string {
+	t.Helper()
+	dir := t.TempDir()
+	for i := 0; i < count; i++ {
+		agentDir := filepath.Join(dir, fmt.Sprintf("agent-%02d", i))
+		if err := os.MkdirAll(agentDir, 0755); err != nil {
+			t.Fatalf("Failed to create agent dir: %v", err)
+		}
+		for _, name := range []string{"main.go", "go.mod"} {
+			if err := os.WriteFile(filepath.Join(agentDir, name), []byte("package main\n"), 0644); err != nil {
+				t.Fatalf("Failed to write %s: %v", name, err)
+			}
+		}
+	}
+	return dir
+}
+
+func TestTestAllAgents_CompleteAndOrderedAtAnyConcurrency(t *testing.T) {
+	const agentCount = 25
+	dir := createAgents(t, agentCount)
+
+	for _, parallel := range []int{1, 4, agentCount * 2} {
+		t.Run(fmt.Sprintf("parallel=%d", parallel), func(t *testing.T) {
+			runner := NewTestRunner(dir)
+			runner.logger = log.New(io.Discard, "", 0)
+			runner.SetParallel(parallel)
+
+			var running, peak int32
+			runner.testAgent = func(agent AgentInfo) TestResult {
+				current := atomic.AddInt32(&running, 1)
+				for {
+					seen := atomic.LoadInt32(&peak)
+					if current <= seen || atomic.CompareAndSwapInt32(&peak, seen, current) {
+						break
+					}
+				}
+				// Finish in a scrambled order
+				time.Sleep(time.Duration(rand.Intn(5)) * time.Millisecond)
+				atomic.AddInt32(&running, -1)
+				return TestResult{AgentName: agent.Name, Success: true}
+			}
+
+			if err := runner.TestAllAgents(); err != nil {
+				t.Fatalf("TestAllAgents failed: %v", err)
+			}
+
+			if len(runner.results) != agentCount {
+				t.Fatalf("Expected %d results, got %d", agentCount, len(runner.results))
+			}
+			for i, result := range runner.results {
+				if expected := fmt.Sprintf("agent-%02d", i); result.AgentName != expected {
+					t.Errorf("Result %d: expected %s, got %s", i, expected, result.AgentName)
+				}
+			}
+			if int(peak) > parallel {
+				t.Errorf("Expected at most %d concurrent tests, saw %d", parallel, peak)
+			}
+		})
+	}
+}
+
+type compliantAgent struct{}
+
+func (compliantAgent) Name() string                            { return "compliant" }
+func (compliantAgent) Initialize(map[string]interface{}) error { return nil }
+func (compliantAgent) HealthCheck() error                      { return nil }
+func (compliantAgent) Shutdown() error                         { return nil }
+func (compliantAgent) Process(context.Context, interfaces.AgentInput) (interfaces.AgentOutput, error) {
+	return interfaces.AgentOutput{}, nil
+}
+
+// legacyAgent uses an outdated Process signature and lacks Shutdown
+type legacyAgent struct{}
+
+func (legacyAgent) Name() string                            { return "legacy" }
+func (legacyAgent) Initialize(map[string]interface{}) error { return nil }
+func (legacyAgent) HealthCheck() error                      { return nil }
+func (legacyAgent) Process(context.Context, map[string]interface{}) (string, error) {
+	return "", nil
+}
+
+func TestCheckAgentSymbol(t *testing.T) {
+	// plugin.Lookup hands back a pointer to the exported variable
+	var agent interfaces.Agent = compliantAgent{}
+	if err := checkAgentSymbol(&agent); err != nil {
+		t.Errorf("Expected compliant agent to pass, got: %v", err)
+	}
+
+	var nilAgent interfaces.Agent
+	if err := checkAgentSymbol(&nilAgent); err == nil || !strings.Contains(err.Error(), "nil") {
+		t.Errorf("Expected nil agent to be reported, got: %v", err)
+	}
+
+	var legacy interface{} = legacyAgent{}
+	err := checkAgentSymbol(&legacy)
+	if err == nil {
+		t.Fatal("Expected legacy agent to fail")
+	}
+	for _, expected := range []string{
+		"missing method Shutdown",
+		"method Process has signature func(context.Context, map[string]interface {}) (string, error)",
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected error to contain %q, got: %v", expected, err)
+		}
+	}
+	if strings.Contains(err.Error(), "method Name") || strings.Contains(err.Error(), "method HealthCheck") {
+		t.Errorf("Expected matching methods not to be reported, got: %v", err)
+	}
+}